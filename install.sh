@@ -97,7 +97,7 @@ sudo chown -R $APP_USER:$APP_USER $LOG_DIR
 # Build application
 echo -e "${GREEN}[6/10] Building application...${NC}"
 cd "$(dirname "$0")"
-go build -o alliance-manager main.go
+go build -tags tesseract -o alliance-manager .
 sudo cp alliance-manager $APP_DIR/
 sudo cp -r static $APP_DIR/
 sudo chown -R $APP_USER:$APP_USER $APP_DIR
@@ -7,6 +7,7 @@ import (
 	"encoding/csv"
 	"encoding/hex"
 	"encoding/json"
+	"flag"
 	"fmt"
 	"image"
 	"image/color"
@@ -15,30 +16,42 @@ import (
 	_ "image/jpeg"
 	"image/png"
 	"io"
+	"io/fs"
 	"log"
+	"maps"
 	"math"
 	"math/big"
 	"net/http"
 	"os"
+	"path/filepath"
+	"reflect"
 	"regexp"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"text/template"
+	"text/template/parse"
 	"time"
+	_ "time/tzdata" // the game and member time zones must resolve on hosts without zoneinfo
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/gorilla/mux"
 	"github.com/gorilla/sessions"
-	gosseract "github.com/otiai10/gosseract/v2"
 	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/text/unicode/norm"
 	_ "modernc.org/sqlite"
 )
 
 type Member struct {
-	ID       int    `json:"id"`
-	Name     string `json:"name"`
-	Rank     string `json:"rank"`
-	Eligible bool   `json:"eligible"`
-	Power    *int64 `json:"power,omitempty"`
+	ID       int     `json:"id"`
+	Name     string  `json:"name"`
+	Rank     string  `json:"rank"`
+	Eligible bool    `json:"eligible"`
+	Power    *int64  `json:"power,omitempty"`
+	Timezone *string `json:"timezone,omitempty"` // IANA zone the member plays from, for times in personal messages
 }
 
 type MemberStats struct {
@@ -79,6 +92,62 @@ type TrainSchedule struct {
 	CreatedAt         string  `json:"created_at"`
 }
 
+// AllianceEventType is a recurring alliance role rotation (Marshal's Guard, Zombie Siege, ...)
+type AllianceEventType struct {
+	ID            int                 `json:"id"`
+	Name          string              `json:"name"`
+	Recurrence    string              `json:"recurrence"`
+	Weekdays      []string            `json:"weekdays"`
+	IntervalWeeks int                 `json:"interval_weeks"`
+	AnchorDate    string              `json:"anchor_date"`
+	StartTime     *string             `json:"start_time"`
+	Active        bool                `json:"active"`
+	SystemKey     string              `json:"system_key,omitempty"`
+	Slots         []AllianceEventSlot `json:"slots"`
+	CreatedAt     string              `json:"created_at"`
+}
+
+// AllianceEventSlot is a role filled by Count members on every occurrence
+type AllianceEventSlot struct {
+	ID        int      `json:"id"`
+	Name      string   `json:"name"`
+	Count     int      `json:"count"`
+	Ranks     []string `json:"ranks"`
+	Selection string   `json:"selection"`
+	SortOrder int      `json:"sort_order"`
+	SystemKey string   `json:"system_key,omitempty"`
+}
+
+type AllianceEventAssignment struct {
+	ID         int     `json:"id"`
+	Date       string  `json:"date"`
+	SlotID     int     `json:"slot_id"`
+	SlotName   string  `json:"slot_name"`
+	Position   int     `json:"position"`
+	MemberID   int     `json:"member_id"`
+	MemberName string  `json:"member_name"`
+	MemberRank string  `json:"member_rank"`
+	Score      *int    `json:"score"`
+	ShowedUp   *bool   `json:"showed_up"`
+	Notes      *string `json:"notes"`
+}
+
+type AllianceEventOccurrence struct {
+	Date        string                    `json:"date"`
+	Assignments []AllianceEventAssignment `json:"assignments"`
+}
+
+// MessageTemplate is one version of a named chat message template
+type MessageTemplate struct {
+	ID        int     `json:"id"`
+	Name      string  `json:"name"`
+	Kind      string  `json:"kind"`
+	Version   int     `json:"version"`
+	Body      string  `json:"body"`
+	CreatedBy *string `json:"created_by"`
+	CreatedAt string  `json:"created_at"`
+}
+
 type Award struct {
 	ID         int    `json:"id"`
 	WeekDate   string `json:"week_date"`
@@ -90,11 +159,12 @@ type Award struct {
 }
 
 type AwardType struct {
-	ID        int    `json:"id"`
-	Name      string `json:"name"`
-	Active    bool   `json:"active"`
-	SortOrder int    `json:"sort_order"`
-	CreatedAt string `json:"created_at"`
+	ID        int     `json:"id"`
+	Name      string  `json:"name"`
+	Active    bool    `json:"active"`
+	SortOrder int     `json:"sort_order"`
+	Metric    *string `json:"metric"` // ranking metric the award is decided by, if any
+	CreatedAt string  `json:"created_at"`
 }
 
 type Recommendation struct {
@@ -127,6 +197,42 @@ type WeekAwards struct {
 	Awards   map[string][]Award `json:"awards"`
 }
 
+// RankingMetric is one tab of the in-game strength ranking that can be read from screenshots
+type RankingMetric struct {
+	Name         string `json:"name"`
+	Label        string `json:"label"`
+	Table        string `json:"-"`
+	Column       string `json:"-"`
+	MinValue     int64  `json:"min_value"`
+	MaxValue     int64  `json:"max_value"`
+	ResetsWeekly bool   `json:"resets_weekly"` // donations count from zero every week, power and kills only grow
+}
+
+// RankingHistory is one recorded value of a ranking metric
+type RankingHistory struct {
+	ID         int    `json:"id"`
+	MemberID   int    `json:"member_id"`
+	Value      int64  `json:"value"`
+	RecordedAt string `json:"recorded_at"`
+}
+
+// RankingDelta is a member's change of a ranking metric over one week
+type RankingDelta struct {
+	MemberID   int    `json:"member_id"`
+	MemberName string `json:"member_name"`
+	Start      int64  `json:"start"`
+	End        int64  `json:"end"`
+	Delta      int64  `json:"delta"`
+	Partial    bool   `json:"partial"` // no value before the week, so the delta starts at its first value
+}
+
+// AwardSuggestion lists the top members for an award that is tied to a ranking metric
+type AwardSuggestion struct {
+	AwardType string         `json:"award_type"`
+	Metric    string         `json:"metric"`
+	Members   []RankingDelta `json:"members"`
+}
+
 type PowerHistory struct {
 	ID         int    `json:"id"`
 	MemberID   int    `json:"member_id"`
@@ -134,6 +240,85 @@ type PowerHistory struct {
 	RecordedAt string `json:"recorded_at"`
 }
 
+// PowerPoint is a member's recorded power on one day
+type PowerPoint struct {
+	RecordID int    `json:"record_id"`
+	Date     string `json:"date"`
+	Power    int64  `json:"power"`
+}
+
+// PowerAuditEntry is a power record that was replaced, merged, edited or voided
+type PowerAuditEntry struct {
+	ID             int     `json:"id"`
+	PowerHistoryID int     `json:"power_history_id"`
+	MemberID       int     `json:"member_id"`
+	MemberName     string  `json:"member_name"`
+	Action         string  `json:"action"`
+	OldPower       int64   `json:"old_power"`
+	NewPower       *int64  `json:"new_power"` // nil when the record was voided
+	RecordedAt     string  `json:"recorded_at"`
+	Reason         *string `json:"reason"`
+	ChangedBy      *int    `json:"changed_by"`
+	ChangedByName  *string `json:"changed_by_name"`
+	ChangedAt      string  `json:"changed_at"`
+}
+
+// PowerOutlier is a power snapshot far off the member's recent trend, usually an OCR misread
+type PowerOutlier struct {
+	RecordID      int     `json:"record_id"`
+	MemberID      int     `json:"member_id"`
+	MemberName    string  `json:"member_name"`
+	Date          string  `json:"date"`
+	Power         int64   `json:"power"`
+	Expected      int64   `json:"expected"` // median of the member's previous snapshots
+	ChangePercent float64 `json:"change_percent"`
+	Kind          string  `json:"kind"` // "drop" or "rise"
+}
+
+// PowerGrowthWindow is a member's power change over the trailing window of days
+type PowerGrowthWindow struct {
+	Days           int      `json:"days"`
+	StartDate      string   `json:"start_date"`
+	StartPower     int64    `json:"start_power"`
+	Delta          int64    `json:"delta"`
+	Percent        float64  `json:"percent"`
+	PerDay         float64  `json:"per_day"`
+	Partial        bool     `json:"partial"`         // no snapshot before the window, so it starts at the first one inside it
+	Percentile     *float64 `json:"percentile"`      // share of members with lower percentage growth
+	RankPercentile *float64 `json:"rank_percentile"` // the same among members of the same alliance rank
+}
+
+// PowerGrowth is a member's latest power with growth over 7, 30 and 90 days
+type PowerGrowth struct {
+	MemberID   int                 `json:"member_id"`
+	MemberName string              `json:"member_name"`
+	MemberRank string              `json:"member_rank"`
+	Power      int64               `json:"power"`
+	RecordedOn string              `json:"recorded_on"`
+	Windows    []PowerGrowthWindow `json:"windows"` // windows without two snapshots are left out
+}
+
+// PowerTotalPoint is the alliance's power on one snapshot day, counting each member's latest value
+type PowerTotalPoint struct {
+	Date    string  `json:"date"`
+	Members int     `json:"members"`
+	Total   int64   `json:"total"`
+	Average float64 `json:"average"`
+	Median  float64 `json:"median"`
+}
+
+// PowerStagnation is a member whose power hasn't changed across their latest snapshots
+type PowerStagnation struct {
+	MemberID   int    `json:"member_id"`
+	MemberName string `json:"member_name"`
+	MemberRank string `json:"member_rank"`
+	Power      int64  `json:"power"`
+	Snapshots  int    `json:"snapshots"` // latest days in a row recorded with this power
+	Since      string `json:"since"`
+	LastSeen   string `json:"last_seen"`
+	Days       int    `json:"days"` // days between the first and last unchanged snapshot
+}
+
 type LoginSession struct {
 	ID        int     `json:"id"`
 	UserID    int     `json:"user_id"`
@@ -193,9 +378,18 @@ type Settings struct {
 	AboveAverageConductorPenalty int    `json:"above_average_conductor_penalty"`
 	R4R5RankBoost                int    `json:"r4r5_rank_boost"`
 	FirstTimeConductorBoost      int    `json:"first_time_conductor_boost"`
-	ScheduleMessageTemplate      string `json:"schedule_message_template"`
-	DailyMessageTemplate         string `json:"daily_message_template"`
 	PowerTrackingEnabled         bool   `json:"power_tracking_enabled"`
+	VSPercentilePoints           int    `json:"vs_percentile_points"`
+	VSMinDailyPoints             int    `json:"vs_min_daily_points"`
+	VSConsistencyBonus           int    `json:"vs_consistency_bonus"`
+	VSZeroDayPenalty             int    `json:"vs_zero_day_penalty"`
+	OCRMinConfidence             int    `json:"ocr_min_confidence"`
+	OCRLanguages                 string `json:"ocr_languages"`
+	GameTimezone                 string `json:"game_timezone"`
+	GameResetTime                string `json:"game_reset_time"`
+	TrainConductorTime           string `json:"train_conductor_time"`
+	TrainBackupTime              string `json:"train_backup_time"`
+	DisplayTimezones             string `json:"display_timezones"`
 }
 
 type MemberRanking struct {
@@ -212,6 +406,12 @@ type MemberRanking struct {
 	DaysSinceLastConductor  *int          `json:"days_since_last_conductor"`
 	AwardDetails            []AwardDetail `json:"award_details"`
 	RecommendationCount     int           `json:"recommendation_count"`
+	VSWeeklyTotal           int           `json:"vs_weekly_total"`
+	VSPercentile            float64       `json:"vs_percentile"`
+	VSPercentilePoints      int           `json:"vs_percentile_points"`
+	VSConsistencyBonus      int           `json:"vs_consistency_bonus"`
+	VSZeroDays              int           `json:"vs_zero_days"`
+	VSZeroDayPenalty        int           `json:"vs_zero_day_penalty"`
 }
 
 type AwardDetail struct {
@@ -222,6 +422,17 @@ type AwardDetail struct {
 	Expired   bool   `json:"expired"`
 }
 
+type StormBuilding struct {
+	ID        string  `json:"id"`
+	Name      string  `json:"name"`
+	Stage     int     `json:"stage"`
+	Points    int     `json:"points"`
+	Priority  string  `json:"priority"`
+	Capacity  int     `json:"capacity"`
+	Boost     *string `json:"boost"`
+	SortOrder int     `json:"sort_order"`
+}
+
 type StormAssignment struct {
 	ID         int    `json:"id"`
 	TaskForce  string `json:"task_force"`
@@ -230,6 +441,71 @@ type StormAssignment struct {
 	Position   int    `json:"position"`
 }
 
+type StormEventResult struct {
+	TaskForce  string  `json:"task_force"`
+	Result     string  `json:"result"`
+	OurScore   *int    `json:"our_score"`
+	EnemyScore *int    `json:"enemy_score"`
+	Opponent   *string `json:"opponent"`
+}
+
+type StormEvent struct {
+	ID             int                `json:"id"`
+	EventDate      string             `json:"event_date"`
+	Notes          *string            `json:"notes"`
+	CreatedAt      string             `json:"created_at"`
+	SignupCounts   map[string]int     `json:"signup_counts"`
+	MyAvailability *string            `json:"my_availability,omitempty"`
+	Attended       int                `json:"attended"`
+	NoShows        int                `json:"no_shows"`
+	Results        []StormEventResult `json:"results"`
+}
+
+type StormSignup struct {
+	MemberID     int     `json:"member_id"`
+	MemberName   string  `json:"member_name"`
+	MemberRank   string  `json:"member_rank"`
+	Availability *string `json:"availability"`
+	Attended     *bool   `json:"attended"`
+	TaskForce    *string `json:"task_force"`
+	UpdatedAt    *string `json:"updated_at"`
+}
+
+type StormEventDetail struct {
+	StormEvent
+	Signups []StormSignup `json:"signups"`
+}
+
+type StormProposalMember struct {
+	MemberID int    `json:"member_id"`
+	Name     string `json:"name"`
+	Rank     string `json:"rank"`
+	Power    int64  `json:"power"`
+	Position int    `json:"position,omitempty"`
+}
+
+type StormProposalBuilding struct {
+	BuildingID string                `json:"building_id"`
+	Name       string                `json:"name"`
+	Stage      int                   `json:"stage"`
+	Priority   string                `json:"priority"`
+	TotalPower int64                 `json:"total_power"`
+	Members    []StormProposalMember `json:"members"`
+}
+
+type StormProposal struct {
+	TaskForce   string                  `json:"task_force"`
+	TotalPower  int64                   `json:"total_power"`
+	Members     int                     `json:"members"`
+	Buildings   []StormProposalBuilding `json:"buildings"`
+	Assignments []StormAssignment       `json:"assignments"`
+}
+
+type StormAutoAssignment struct {
+	TaskForces  []StormProposal       `json:"task_forces"`
+	Substitutes []StormProposalMember `json:"substitutes"`
+}
+
 type DetectedMember struct {
 	Name         string   `json:"name"`
 	Rank         string   `json:"rank"`
@@ -263,16 +539,19 @@ type ConfirmResult struct {
 	Removed   int `json:"removed"`
 }
 
+// VSPoints is a member's week of points for one event type, pivoted from the per-day vs_points rows
 type VSPoints struct {
 	ID        int    `json:"id"`
 	MemberID  int    `json:"member_id"`
 	WeekDate  string `json:"week_date"`
+	EventType string `json:"event_type"`
 	Monday    int    `json:"monday"`
 	Tuesday   int    `json:"tuesday"`
 	Wednesday int    `json:"wednesday"`
 	Thursday  int    `json:"thursday"`
 	Friday    int    `json:"friday"`
 	Saturday  int    `json:"saturday"`
+	Sunday    int    `json:"sunday"`
 	CreatedAt string `json:"created_at"`
 	UpdatedAt string `json:"updated_at"`
 }
@@ -283,13 +562,204 @@ type VSPointsWithMember struct {
 	MemberRank string `json:"member_rank"`
 }
 
+// OCRBox is a rectangle in the original screenshot's pixel coordinates
+type OCRBox struct {
+	X      int `json:"x"`
+	Y      int `json:"y"`
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+// OCRRecord is one row read from a screenshot or pasted text, before it is matched to a member
+type OCRRecord struct {
+	MemberName      string  `json:"member_name"`
+	Value           int64   `json:"value"`      // VS points or power
+	RawText         string  `json:"raw_text"`   // OCR text the row was parsed from
+	Confidence      float64 `json:"confidence"` // 0-100 from Tesseract (lowest field), 100 for typed input, ocrConfidenceUnknown if not measured
+	NameText        string  `json:"name_text"`
+	NameConfidence  float64 `json:"name_confidence"`
+	NameBox         *OCRBox `json:"name_box"`
+	ValueText       string  `json:"value_text"`
+	ValueConfidence float64 `json:"value_confidence"`
+	ValueBox        *OCRBox `json:"value_box"`
+	Rank            int     `json:"rank"` // in-game rank number column, 0 when not read
+	RankInferred    bool    `json:"rank_inferred"`
+	Page            int     `json:"page"` // screenshot index within a multi-image upload
+}
+
+// OCRBatch is a staged screenshot or text upload waiting for review before it is committed
+type OCRBatch struct {
+	ID             int           `json:"id"`
+	Kind           string        `json:"kind"`
+	Status         string        `json:"status"`
+	Source         string        `json:"source"`
+	Metric         *string       `json:"metric"` // strength ranking tab of a power batch: power, kills or donation
+	WeekDate       *string       `json:"week_date"`
+	Day            *string       `json:"day"`
+	HasImage       bool          `json:"has_image"`
+	PageCount      int           `json:"page_count"` // screenshots in a multi-image upload (0 for single uploads)
+	CreatedBy      *int          `json:"created_by"`
+	CreatedByName  *string       `json:"created_by_name"`
+	CreatedAt      string        `json:"created_at"`
+	ReviewedBy     *int          `json:"reviewed_by"`
+	ReviewedByName *string       `json:"reviewed_by_name"`
+	ReviewedAt     *string       `json:"reviewed_at"`
+	RowCount       int           `json:"row_count"`
+	MatchedCount   int           `json:"matched_count"`
+	LowConfidence  int           `json:"low_confidence_count"`
+	MinConfidence  int           `json:"min_confidence"` // current ocr_min_confidence setting
+	Rows           []OCRBatchRow `json:"rows,omitempty"`
+}
+
+// OCRBatchRow is one parsed row of a batch with its suggested member match
+type OCRBatchRow struct {
+	ID              int     `json:"id"`
+	RowIndex        int     `json:"row_index"`
+	RawText         string  `json:"raw_text"`
+	ParsedName      string  `json:"parsed_name"`
+	Value           int64   `json:"value"`
+	Confidence      float64 `json:"confidence"`
+	NameText        string  `json:"name_text"`
+	NameConfidence  float64 `json:"name_confidence"`
+	NameBox         *OCRBox `json:"name_box"`
+	ValueText       string  `json:"value_text"`
+	ValueConfidence float64 `json:"value_confidence"`
+	ValueBox        *OCRBox `json:"value_box"`
+	LowConfidence   bool    `json:"low_confidence"` // below ocr_min_confidence when staged, skipped unless the reviewer re-includes it
+	Rank            *int    `json:"rank"`
+	Page            int     `json:"page"`
+	MemberID        *int    `json:"member_id"`
+	MemberName      *string `json:"member_name"`
+	MatchScore      int     `json:"match_score"`
+	Corrected       bool    `json:"corrected"`
+	Skip            bool    `json:"skip"`
+}
+
+// vsDays lists the days VS points can be recorded for, in week order
+var vsDays = []string{"monday", "tuesday", "wednesday", "thursday", "friday", "saturday", "sunday"}
+
+// vsDuelDays are the Alliance Duel days, each with its own theme (Sunday is a rest day)
+var vsDuelDays = vsDays[:6]
+
+// vsEventAllianceDuel is the default event type for VS points
+const vsEventAllianceDuel = "alliance_duel"
+
+// VSRequirement is the minimum daily VS points for one day's theme
+type VSRequirement struct {
+	Day       string `json:"day"`
+	Theme     string `json:"theme"`
+	MinPoints int    `json:"min_points"`
+}
+
+// VSComplianceDay is one member's result against one day's requirement
+type VSComplianceDay struct {
+	Day       string `json:"day"`
+	Points    int    `json:"points"`
+	MinPoints int    `json:"min_points"`
+	Met       bool   `json:"met"`
+	Played    bool   `json:"played"`
+}
+
+// VSComplianceMember lists a member's daily results and missed-day streaks for a week
+type VSComplianceMember struct {
+	MemberID      int               `json:"member_id"`
+	MemberName    string            `json:"member_name"`
+	MemberRank    string            `json:"member_rank"`
+	Total         int               `json:"total"`
+	Days          []VSComplianceDay `json:"days"`
+	MissedDays    int               `json:"missed_days"`
+	LongestStreak int               `json:"longest_streak"`
+	CurrentStreak int               `json:"current_streak"`
+}
+
+// VSWeeklyTrend is one member's VS total for a week with movement against their previous week
+type VSWeeklyTrend struct {
+	MemberID      int     `json:"member_id"`
+	MemberName    string  `json:"member_name"`
+	MemberRank    string  `json:"member_rank"`
+	WeekDate      string  `json:"week_date"`
+	Total         int     `json:"total"`
+	WeekRank      int     `json:"week_rank"`
+	Percentile    float64 `json:"percentile"`
+	PreviousTotal *int    `json:"previous_total"`
+	Delta         *int    `json:"delta"`
+	RankChange    *int    `json:"rank_change"` // positive = moved up
+}
+
+// VSDayStat is the alliance average and median for one VS day
+type VSDayStat struct {
+	WeekDate     string  `json:"week_date"`
+	Day          string  `json:"day"`
+	Participants int     `json:"participants"`
+	Average      float64 `json:"average"`
+	Median       float64 `json:"median"`
+	Max          int     `json:"max"`
+	Total        int     `json:"total"`
+}
+
+// InactivitySettings weighs the signals of the inactivity report
+type InactivitySettings struct {
+	LookbackWeeks       int `json:"lookback_weeks"`
+	VSZeroDayPoints     int `json:"vs_zero_day_points"`    // per VS day without points
+	StagnantPowerPoints int `json:"stagnant_power_points"` // when power hasn't changed across StagnationSnapshots
+	StagnationSnapshots int `json:"stagnation_snapshots"`
+	NoTrainDutyPoints   int `json:"no_train_duty_points"` // when not conductor or backup in the lookback
+	NoShowPoints        int `json:"no_show_points"`       // per train the member conducted but didn't show up for
+	NoLoginPoints       int `json:"no_login_points"`      // when the last login is NoLoginDays or more ago
+	NoLoginDays         int `json:"no_login_days"`
+	FlagScore           int `json:"flag_score"` // members at or above this score are kick candidates
+}
+
+// InactivityReason is one signal that added to a member's inactivity score
+type InactivityReason struct {
+	Signal string `json:"signal"`
+	Detail string `json:"detail"`
+	Points int    `json:"points"`
+}
+
+// InactivityMember is one member's inactivity score with the signals behind it
+type InactivityMember struct {
+	MemberID       int                `json:"member_id"`
+	MemberName     string             `json:"member_name"`
+	MemberRank     string             `json:"member_rank"`
+	Score          int                `json:"score"`
+	Flagged        bool               `json:"flagged"`
+	Reasons        []InactivityReason `json:"reasons"`
+	VSZeroDays     int                `json:"vs_zero_days"`
+	VSDaysPlayed   int                `json:"vs_days_played"` // alliance VS days in the lookback
+	PowerUnchanged *PowerStagnation   `json:"power_unchanged"`
+	TrainDuties    int                `json:"train_duties"`
+	NoShows        int                `json:"no_shows"`
+	LastLogin      *string            `json:"last_login"`
+	HasAccount     bool               `json:"has_account"`
+}
+
+// InactivityReport ranks members by inactivity score over the lookback period
+type InactivityReport struct {
+	AsOf     string             `json:"as_of"`
+	From     string             `json:"from"`
+	Settings InactivitySettings `json:"settings"`
+	Flagged  int                `json:"flagged_count"`
+	Members  []InactivityMember `json:"members"`
+}
+
+// VSComplianceReport is the weekly VS minimum requirement report
+type VSComplianceReport struct {
+	WeekDate       string               `json:"week_date"`
+	Requirements   []VSRequirement      `json:"requirements"`
+	TotalMembers   int                  `json:"total_members"`
+	CompliantCount int                  `json:"compliant_count"`
+	Members        []VSComplianceMember `json:"members"`
+}
+
 var db *sql.DB
 var store *sessions.CookieStore
 
 // Calculate Levenshtein distance between two strings
 func levenshteinDistance(s1, s2 string) int {
-	s1Lower := strings.ToLower(s1)
-	s2Lower := strings.ToLower(s2)
+	// Compare characters, not bytes, so accented, Cyrillic and CJK names count one edit per character
+	s1Lower := []rune(strings.ToLower(s1))
+	s2Lower := []rune(strings.ToLower(s2))
 	len1 := len(s1Lower)
 	len2 := len(s2Lower)
 
@@ -428,6 +898,8 @@ type RankingContext struct {
 	AwardScoreMap     map[int]int // memberID -> total points
 	ConductorStats    map[int]ConductorStat
 	AvgConductorCount float64
+	VSStats           map[int]VSWeekStat // memberID -> last completed VS week
+	VSWeek            string
 	ReferenceDate     time.Time
 }
 
@@ -437,12 +909,24 @@ type ConductorStat struct {
 	LastBackupUsed *string
 }
 
+// VSWeekStat summarizes one member's VS points for a week (Monday-Saturday)
+type VSWeekStat struct {
+	Total      int
+	Percentile float64 // 0 = lowest weekly total in the alliance, 1 = highest
+	DaysAtMin  int     // duel days at or above the minimum daily threshold
+	ZeroDays   int     // days with no points (or no row) while the alliance has data for that day
+}
+
 // loadSettings loads the settings from the database
 func loadSettings() (Settings, error) {
 	var settings Settings
 	err := db.QueryRow(`SELECT id, award_first_points, award_second_points, award_third_points, 
 		recommendation_points, recent_conductor_penalty_days, above_average_conductor_penalty, r4r5_rank_boost,
-		first_time_conductor_boost, schedule_message_template, daily_message_template 
+		first_time_conductor_boost,
+		COALESCE(power_tracking_enabled, 0) as power_tracking_enabled,
+		vs_percentile_points, vs_min_daily_points, vs_consistency_bonus, vs_zero_day_penalty,
+		ocr_min_confidence, ocr_languages,
+		game_timezone, game_reset_time, train_conductor_time, train_backup_time, display_timezones
 		FROM settings WHERE id = 1`).Scan(
 		&settings.ID,
 		&settings.AwardFirstPoints,
@@ -453,25 +937,39 @@ func loadSettings() (Settings, error) {
 		&settings.AboveAverageConductorPenalty,
 		&settings.R4R5RankBoost,
 		&settings.FirstTimeConductorBoost,
-		&settings.ScheduleMessageTemplate,
-		&settings.DailyMessageTemplate,
+		&settings.PowerTrackingEnabled,
+		&settings.VSPercentilePoints,
+		&settings.VSMinDailyPoints,
+		&settings.VSConsistencyBonus,
+		&settings.VSZeroDayPenalty,
+		&settings.OCRMinConfidence,
+		&settings.OCRLanguages,
+		&settings.GameTimezone,
+		&settings.GameResetTime,
+		&settings.TrainConductorTime,
+		&settings.TrainBackupTime,
+		&settings.DisplayTimezones,
 	)
 	return settings, err
 }
 
-// loadRecommendations loads recommendation counts for all members (active only)
+// loadRecommendations loads recommendation counts for all members (active only) as of the given date
 // A recommendation is active if the member hasn't been assigned as conductor/backup after it was created
-func loadRecommendations() (map[int]int, error) {
+// and before the as-of date. Recommendations created after the as-of date are ignored.
+func loadRecommendations(asOf time.Time) (map[int]int, error) {
+	asOfStr := formatDateString(asOf)
 	rows, err := db.Query(`
 		SELECT r.member_id, COUNT(*) as rec_count
 		FROM recommendations r
-		WHERE NOT EXISTS (
+		WHERE date(r.created_at) <= ?
+		AND NOT EXISTS (
 			SELECT 1 FROM train_schedules ts
 			WHERE (ts.conductor_id = r.member_id OR (ts.backup_id = r.member_id AND ts.conductor_showed_up = 0))
 			AND ts.date >= date(r.created_at)
+			AND ts.date < ?
 		)
 		GROUP BY r.member_id
-	`)
+	`, asOfStr, asOfStr)
 	if err != nil {
 		return nil, err
 	}
@@ -488,18 +986,22 @@ func loadRecommendations() (map[int]int, error) {
 	return recommendationMap, nil
 }
 
-// loadAwards loads award scores for all members (active only)
-// An award is active if the member hasn't been assigned as conductor/backup after the award week
-func loadAwards(settings Settings) (map[int]int, error) {
+// loadAwards loads award scores for all members (active only) as of the given date
+// An award is active if the member hasn't been assigned as conductor/backup between the award week
+// and the as-of date. Awards for weeks after the as-of date are ignored.
+func loadAwards(settings Settings, asOf time.Time) (map[int]int, error) {
+	asOfStr := formatDateString(asOf)
 	rows, err := db.Query(`
 		SELECT a.member_id, a.rank
 		FROM awards a
-		WHERE NOT EXISTS (
+		WHERE a.week_date <= ?
+		AND NOT EXISTS (
 			SELECT 1 FROM train_schedules ts
 			WHERE (ts.conductor_id = a.member_id OR (ts.backup_id = a.member_id AND ts.conductor_showed_up = 0))
 			AND ts.date >= a.week_date
+			AND ts.date < ?
 		)
-	`)
+	`, asOfStr, asOfStr)
 	if err != nil {
 		return nil, err
 	}
@@ -523,13 +1025,15 @@ func loadAwards(settings Settings) (map[int]int, error) {
 	return awardScoreMap, nil
 }
 
-// loadConductorStats loads conductor statistics for all members
-func loadConductorStats() (map[int]ConductorStat, float64, error) {
+// loadConductorStats loads conductor statistics for all members from duties before the as-of date
+func loadConductorStats(asOf time.Time) (map[int]ConductorStat, float64, error) {
+	asOfStr := formatDateString(asOf)
 	rows, err := db.Query(`
 		SELECT conductor_id, COUNT(*) as conductor_count, MAX(date) as last_date
 		FROM train_schedules
+		WHERE date < ?
 		GROUP BY conductor_id
-	`)
+	`, asOfStr)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -561,9 +1065,9 @@ func loadConductorStats() (map[int]ConductorStat, float64, error) {
 	backupRows, err := db.Query(`
 		SELECT backup_id, MAX(date) as last_backup_used
 		FROM train_schedules
-		WHERE conductor_showed_up = 0
+		WHERE conductor_showed_up = 0 AND date < ?
 		GROUP BY backup_id
-	`)
+	`, asOfStr)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -601,25 +1105,151 @@ func loadConductorStats() (map[int]ConductorStat, float64, error) {
 	return conductorStats, avgConductorCount, nil
 }
 
-// buildRankingContext creates a complete ranking context for calculations
+// loadVSStats loads VS point statistics for the last completed VS week before the as-of date
+func loadVSStats(asOf time.Time, settings Settings) (map[int]VSWeekStat, string, error) {
+	weekDate := formatDateString(getMondayOfWeek(asOf).AddDate(0, 0, -7))
+	rows, err := db.Query(`
+		SELECT v.member_id, v.day, v.points, t.percentile
+		FROM vs_points v
+		JOIN (
+			SELECT member_id, PERCENT_RANK() OVER (ORDER BY SUM(points)) AS percentile
+			FROM vs_points
+			WHERE week_date = ? AND event_type = ?
+			GROUP BY member_id
+		) t ON t.member_id = v.member_id
+		WHERE v.week_date = ? AND v.event_type = ?
+	`, weekDate, vsEventAllianceDuel, weekDate, vsEventAllianceDuel)
+	if err != nil {
+		return nil, weekDate, err
+	}
+	defer rows.Close()
+
+	type memberWeek struct {
+		days       map[string]int
+		percentile float64
+	}
+	weeks := make(map[int]*memberWeek)
+	dayHasData := make(map[string]bool)
+	for rows.Next() {
+		var memberID, points int
+		var day string
+		var percentile float64
+		if err := rows.Scan(&memberID, &day, &points, &percentile); err != nil {
+			return nil, weekDate, err
+		}
+		mw, exists := weeks[memberID]
+		if !exists {
+			mw = &memberWeek{days: make(map[string]int), percentile: percentile}
+			weeks[memberID] = mw
+		}
+		mw.days[day] = points
+		if points > 0 {
+			dayHasData[day] = true
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, weekDate, err
+	}
+
+	// Eligible members with no points at all that week missed every day the alliance played
+	memberRows, err := db.Query("SELECT id FROM members WHERE eligible = 1")
+	if err != nil {
+		return nil, weekDate, err
+	}
+	defer memberRows.Close()
+	for memberRows.Next() {
+		var memberID int
+		if err := memberRows.Scan(&memberID); err != nil {
+			return nil, weekDate, err
+		}
+		if _, exists := weeks[memberID]; !exists {
+			weeks[memberID] = &memberWeek{days: make(map[string]int)}
+		}
+	}
+	if err := memberRows.Err(); err != nil {
+		return nil, weekDate, err
+	}
+
+	// A day only counts once it has been played, so require at least 1 point
+	minDaily := settings.VSMinDailyPoints
+	if minDaily < 1 {
+		minDaily = 1
+	}
+
+	vsStats := make(map[int]VSWeekStat)
+	for memberID, mw := range weeks {
+		stat := VSWeekStat{Percentile: mw.percentile}
+		for _, day := range vsDays {
+			points := mw.days[day]
+			stat.Total += points
+			if points == 0 && dayHasData[day] {
+				stat.ZeroDays++
+			}
+		}
+		for _, day := range vsDuelDays {
+			if mw.days[day] >= minDaily {
+				stat.DaysAtMin++
+			}
+		}
+		vsStats[memberID] = stat
+	}
+
+	return vsStats, weekDate, nil
+}
+
+// calculateVSScore returns the VS components of a member's score:
+// percentile points, consistency bonus and zero-day penalty
+func calculateVSScore(memberID int, ctx *RankingContext) (int, int, int) {
+	stat, exists := ctx.VSStats[memberID]
+	if !exists {
+		return 0, 0, 0
+	}
+
+	// Scale percentile points by the member's standing in the alliance
+	percentilePoints := int(math.Round(float64(ctx.Settings.VSPercentilePoints) * stat.Percentile))
+
+	// Consistency bonus for hitting the daily minimum every day Monday-Saturday
+	consistencyBonus := 0
+	if stat.DaysAtMin == len(vsDuelDays) {
+		consistencyBonus = ctx.Settings.VSConsistencyBonus
+	}
+
+	zeroDayPenalty := stat.ZeroDays * ctx.Settings.VSZeroDayPenalty
+
+	return percentilePoints, consistencyBonus, zeroDayPenalty
+}
+
+// buildRankingContext creates a complete ranking context for calculations.
+// Everything is evaluated as of referenceDate: duties on or after it don't count yet.
 func buildRankingContext(referenceDate time.Time) (*RankingContext, error) {
 	settings, err := loadSettings()
 	if err != nil {
 		return nil, err
 	}
 
-	recommendationMap, err := loadRecommendations()
+	return buildRankingContextWithSettings(referenceDate, settings)
+}
+
+// buildRankingContextWithSettings creates a ranking context using the given settings
+// instead of the stored ones (used for what-if simulations)
+func buildRankingContextWithSettings(referenceDate time.Time, settings Settings) (*RankingContext, error) {
+	recommendationMap, err := loadRecommendations(referenceDate)
 	if err != nil {
 		return nil, err
 	}
 
 	// Get all non-expired awards (stacks up over multiple weeks)
-	awardScoreMap, err := loadAwards(settings)
+	awardScoreMap, err := loadAwards(settings, referenceDate)
+	if err != nil {
+		return nil, err
+	}
+
+	conductorStats, avgConductorCount, err := loadConductorStats(referenceDate)
 	if err != nil {
 		return nil, err
 	}
 
-	conductorStats, avgConductorCount, err := loadConductorStats()
+	vsStats, vsWeek, err := loadVSStats(referenceDate, settings)
 	if err != nil {
 		return nil, err
 	}
@@ -630,10 +1260,37 @@ func buildRankingContext(referenceDate time.Time) (*RankingContext, error) {
 		AwardScoreMap:     awardScoreMap,
 		ConductorStats:    conductorStats,
 		AvgConductorCount: avgConductorCount,
+		VSStats:           vsStats,
+		VSWeek:            vsWeek,
 		ReferenceDate:     referenceDate,
 	}, nil
 }
 
+// ScoredMember pairs a member with their ranking score
+type ScoredMember struct {
+	Member Member
+	Score  int
+}
+
+// weeklyConductorSlots is the number of conductors picked by auto-schedule each week
+const weeklyConductorSlots = 7
+
+// scoreMembers scores members with the given context and sorts them by score (highest first).
+// Ties keep the input order, so members loaded ORDER BY name stay alphabetical.
+func scoreMembers(members []Member, ctx *RankingContext) []ScoredMember {
+	scored := make([]ScoredMember, 0, len(members))
+	for _, member := range members {
+		scored = append(scored, ScoredMember{
+			Member: member,
+			Score:  calculateMemberScore(member, ctx),
+		})
+	}
+	sort.SliceStable(scored, func(i, j int) bool {
+		return scored[i].Score > scored[j].Score
+	})
+	return scored
+}
+
 // calculateMemberScore calculates the ranking score for a member
 func calculateMemberScore(member Member, ctx *RankingContext) int {
 	score := 0
@@ -650,6 +1307,10 @@ func calculateMemberScore(member Member, ctx *RankingContext) int {
 	// Add award points
 	score += ctx.AwardScoreMap[member.ID]
 
+	// Add VS points from the last completed VS week
+	vsPercentilePoints, vsConsistencyBonus, vsZeroDayPenalty := calculateVSScore(member.ID, ctx)
+	score += vsPercentilePoints + vsConsistencyBonus
+
 	// Add rank boost for R4/R5 members (exponential based on days since last conductor)
 	if member.Rank == "R4" || member.Rank == "R5" {
 		baseBoost := float64(ctx.Settings.R4R5RankBoost)
@@ -728,6 +1389,9 @@ func calculateMemberScore(member Member, ctx *RankingContext) int {
 		}
 	}
 
+	// Penalize days without any VS points
+	score -= vsZeroDayPenalty
+
 	return score
 }
 
@@ -740,7 +1404,13 @@ func initDB() error {
 		dbPath = "./alliance.db"
 	}
 
-	db, err = sql.Open("sqlite", dbPath)
+	// Wait for a concurrent write instead of failing with "database is locked", so conditional
+	// updates (like claiming a pending OCR batch) can settle which request wins
+	separator := "?"
+	if strings.Contains(dbPath, "?") {
+		separator = "&"
+	}
+	db, err = sql.Open("sqlite", dbPath+separator+"_pragma=busy_timeout(5000)")
 	if err != nil {
 		return err
 	}
@@ -777,6 +1447,25 @@ func initDB() error {
 		log.Println("Database migration: Added eligible column to members table")
 	}
 
+	// Migrate existing members table to add timezone column if missing
+	var memberTimezoneColumnExists bool
+	err = db.QueryRow(`
+		SELECT COUNT(*) > 0
+		FROM pragma_table_info('members')
+		WHERE name = 'timezone'
+	`).Scan(&memberTimezoneColumnExists)
+	if err != nil {
+		return err
+	}
+
+	if !memberTimezoneColumnExists {
+		_, err = db.Exec(`ALTER TABLE members ADD COLUMN timezone TEXT`)
+		if err != nil {
+			return err
+		}
+		log.Println("Database migration: Added timezone column to members table")
+	}
+
 	// Create users table
 	createUsersTableSQL := `CREATE TABLE IF NOT EXISTS users (
 		id INTEGER PRIMARY KEY AUTOINCREMENT,
@@ -792,63 +1481,17 @@ func initDB() error {
 		return err
 	}
 
-	// Create train_schedules table
-	createTrainSchedulesSQL := `CREATE TABLE IF NOT EXISTS train_schedules (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		date TEXT NOT NULL UNIQUE,
-		conductor_id INTEGER NOT NULL,
-		backup_id INTEGER,
-		conductor_score INTEGER,
-		conductor_showed_up BOOLEAN,
-		notes TEXT,
-		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		FOREIGN KEY (conductor_id) REFERENCES members(id) ON DELETE CASCADE,
-		FOREIGN KEY (backup_id) REFERENCES members(id) ON DELETE CASCADE
-	);`
-
-	_, err = db.Exec(createTrainSchedulesSQL)
-	if err != nil {
-		return err
-	}
-
-	// Migrate existing train_schedules table to add conductor_score column if missing
-	var columnExists bool
-	err = db.QueryRow(`
-		SELECT COUNT(*) > 0
-		FROM pragma_table_info('train_schedules')
-		WHERE name = 'conductor_score'
-	`).Scan(&columnExists)
-	if err != nil {
+	// train_schedules is a view over the train's event assignments once the train has moved onto the
+	// event types (further down); until then it is the original table, brought up to date first
+	var trainSchedulesType string
+	err = db.QueryRow("SELECT type FROM sqlite_master WHERE name = 'train_schedules'").Scan(&trainSchedulesType)
+	if err != nil && err != sql.ErrNoRows {
 		return err
 	}
 
-	if !columnExists {
-		_, err = db.Exec(`ALTER TABLE train_schedules ADD COLUMN conductor_score INTEGER`)
-		if err != nil {
-			return err
-		}
-		log.Println("Database migration: Added conductor_score column to train_schedules table")
-	}
-
-	// Migrate train_schedules to make backup_id nullable (for existing databases)
-	// Check if the table structure needs migration by checking pragma
-	migrationNeeded := false
-	var backupIdNotnull int
-	err = db.QueryRow(`
-		SELECT "notnull"
-		FROM pragma_table_info('train_schedules')
-		WHERE name = 'backup_id'
-	`).Scan(&backupIdNotnull)
-
-	if err == nil && backupIdNotnull == 1 {
-		migrationNeeded = true
-	}
-
-	if migrationNeeded {
-		log.Println("Database migration: Making backup_id nullable in train_schedules table")
-
-		// Create new table with correct schema
-		_, err = db.Exec(`CREATE TABLE train_schedules_new (
+	if trainSchedulesType != "view" {
+		// Create train_schedules table
+		createTrainSchedulesSQL := `CREATE TABLE IF NOT EXISTS train_schedules (
 			id INTEGER PRIMARY KEY AUTOINCREMENT,
 			date TEXT NOT NULL UNIQUE,
 			conductor_id INTEGER NOT NULL,
@@ -859,32 +1502,88 @@ func initDB() error {
 			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
 			FOREIGN KEY (conductor_id) REFERENCES members(id) ON DELETE CASCADE,
 			FOREIGN KEY (backup_id) REFERENCES members(id) ON DELETE CASCADE
-		)`)
+		);`
+
+		_, err = db.Exec(createTrainSchedulesSQL)
 		if err != nil {
-			return fmt.Errorf("failed to create new train_schedules table: %v", err)
+			return err
 		}
 
-		// Copy data from old table
-		_, err = db.Exec(`INSERT INTO train_schedules_new (id, date, conductor_id, backup_id, conductor_score, conductor_showed_up, notes, created_at)
-			SELECT id, date, conductor_id, backup_id, conductor_score, conductor_showed_up, notes, created_at
-			FROM train_schedules`)
+		// Migrate existing train_schedules table to add conductor_score column if missing
+		var columnExists bool
+		err = db.QueryRow(`
+			SELECT COUNT(*) > 0
+			FROM pragma_table_info('train_schedules')
+			WHERE name = 'conductor_score'
+		`).Scan(&columnExists)
 		if err != nil {
-			return fmt.Errorf("failed to copy train_schedules data: %v", err)
+			return err
 		}
 
-		// Drop old table
-		_, err = db.Exec(`DROP TABLE train_schedules`)
-		if err != nil {
-			return fmt.Errorf("failed to drop old train_schedules table: %v", err)
+		if !columnExists {
+			_, err = db.Exec(`ALTER TABLE train_schedules ADD COLUMN conductor_score INTEGER`)
+			if err != nil {
+				return err
+			}
+			log.Println("Database migration: Added conductor_score column to train_schedules table")
 		}
 
-		// Rename new table
-		_, err = db.Exec(`ALTER TABLE train_schedules_new RENAME TO train_schedules`)
-		if err != nil {
-			return fmt.Errorf("failed to rename train_schedules_new table: %v", err)
+		// Migrate train_schedules to make backup_id nullable (for existing databases)
+		// Check if the table structure needs migration by checking pragma
+		migrationNeeded := false
+		var backupIdNotnull int
+		err = db.QueryRow(`
+			SELECT "notnull"
+			FROM pragma_table_info('train_schedules')
+			WHERE name = 'backup_id'
+		`).Scan(&backupIdNotnull)
+
+		if err == nil && backupIdNotnull == 1 {
+			migrationNeeded = true
 		}
 
-		log.Println("Database migration: Successfully made backup_id nullable")
+		if migrationNeeded {
+			log.Println("Database migration: Making backup_id nullable in train_schedules table")
+
+			// Create new table with correct schema
+			_, err = db.Exec(`CREATE TABLE train_schedules_new (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				date TEXT NOT NULL UNIQUE,
+				conductor_id INTEGER NOT NULL,
+				backup_id INTEGER,
+				conductor_score INTEGER,
+				conductor_showed_up BOOLEAN,
+				notes TEXT,
+				created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				FOREIGN KEY (conductor_id) REFERENCES members(id) ON DELETE CASCADE,
+				FOREIGN KEY (backup_id) REFERENCES members(id) ON DELETE CASCADE
+			)`)
+			if err != nil {
+				return fmt.Errorf("failed to create new train_schedules table: %v", err)
+			}
+
+			// Copy data from old table
+			_, err = db.Exec(`INSERT INTO train_schedules_new (id, date, conductor_id, backup_id, conductor_score, conductor_showed_up, notes, created_at)
+				SELECT id, date, conductor_id, backup_id, conductor_score, conductor_showed_up, notes, created_at
+				FROM train_schedules`)
+			if err != nil {
+				return fmt.Errorf("failed to copy train_schedules data: %v", err)
+			}
+
+			// Drop old table
+			_, err = db.Exec(`DROP TABLE train_schedules`)
+			if err != nil {
+				return fmt.Errorf("failed to drop old train_schedules table: %v", err)
+			}
+
+			// Rename new table
+			_, err = db.Exec(`ALTER TABLE train_schedules_new RENAME TO train_schedules`)
+			if err != nil {
+				return fmt.Errorf("failed to rename train_schedules_new table: %v", err)
+			}
+
+			log.Println("Database migration: Successfully made backup_id nullable")
+		}
 	}
 
 	// Create award_types table
@@ -955,6 +1654,32 @@ func initDB() error {
 		return err
 	}
 
+	// Migrate award_types table to add the ranking metric an award can be suggested from
+	var awardMetricColumnExists bool
+	err = db.QueryRow(`
+		SELECT COUNT(*) > 0
+		FROM pragma_table_info('award_types')
+		WHERE name = 'metric'
+	`).Scan(&awardMetricColumnExists)
+	if err != nil {
+		return err
+	}
+
+	if !awardMetricColumnExists {
+		_, err = db.Exec(`ALTER TABLE award_types ADD COLUMN metric TEXT CHECK(metric IN ('power', 'kills', 'donation'))`)
+		if err != nil {
+			return err
+		}
+		// The kill and donation awards are decided by those rankings
+		_, err = db.Exec(`UPDATE award_types SET metric = CASE name
+			WHEN 'Soldier Crusher' THEN 'kills' WHEN 'Alliance Sponsor' THEN 'donation' END
+			WHERE name IN ('Soldier Crusher', 'Alliance Sponsor')`)
+		if err != nil {
+			return err
+		}
+		log.Println("Database migration: Added metric column to award_types table")
+	}
+
 	// Create power_history table
 	createPowerHistorySQL := `CREATE TABLE IF NOT EXISTS power_history (
 		id INTEGER PRIMARY KEY AUTOINCREMENT,
@@ -975,31 +1700,120 @@ func initDB() error {
 		return err
 	}
 
-	// Create recommendations table
-	createRecommendationsSQL := `CREATE TABLE IF NOT EXISTS recommendations (
+	// Create power_history_audit table recording replaced, edited and voided power records
+	createPowerHistoryAuditSQL := `CREATE TABLE IF NOT EXISTS power_history_audit (
 		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		power_history_id INTEGER NOT NULL,
 		member_id INTEGER NOT NULL,
-		recommended_by_id INTEGER NOT NULL,
-		notes TEXT,
-		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		expired BOOLEAN DEFAULT 0,
+		action TEXT NOT NULL CHECK(action IN ('replace', 'merge', 'edit', 'void')),
+		old_power INTEGER NOT NULL,
+		new_power INTEGER,
+		recorded_at TEXT NOT NULL,
+		reason TEXT,
+		changed_by INTEGER,
+		changed_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
 		FOREIGN KEY (member_id) REFERENCES members(id) ON DELETE CASCADE,
-		FOREIGN KEY (recommended_by_id) REFERENCES users(id) ON DELETE CASCADE
+		FOREIGN KEY (changed_by) REFERENCES users(id) ON DELETE SET NULL
 	);`
 
-	_, err = db.Exec(createRecommendationsSQL)
+	_, err = db.Exec(createPowerHistoryAuditSQL)
 	if err != nil {
 		return err
 	}
 
-	// Create dyno recommendations table (informal feedback that expires after 1 week)
-	createDynoRecommendationsSQL := `CREATE TABLE IF NOT EXISTS dyno_recommendations (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		member_id INTEGER NOT NULL,
-		points INTEGER NOT NULL,
-		notes TEXT NOT NULL,
-		created_by_id INTEGER NOT NULL,
-		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	_, err = db.Exec("CREATE INDEX IF NOT EXISTS idx_power_history_audit_member ON power_history_audit(member_id, changed_at DESC)")
+	if err != nil {
+		return err
+	}
+
+	// Power is one snapshot per member per day: merge older same-day records into the latest one
+	result, err := db.Exec(`INSERT INTO power_history_audit (power_history_id, member_id, action, old_power, new_power, recorded_at, reason)
+		SELECT ph.id, ph.member_id, 'merge', ph.power, latest.power, ph.recorded_at, 'Duplicate snapshot on the same day'
+		FROM power_history ph
+		JOIN power_history latest ON latest.id = (
+			SELECT l.id FROM power_history l
+			WHERE l.member_id = ph.member_id AND DATE(l.recorded_at) = DATE(ph.recorded_at)
+			ORDER BY l.recorded_at DESC, l.id DESC LIMIT 1)
+		WHERE latest.id != ph.id`)
+	if err != nil {
+		return err
+	}
+	if merged, _ := result.RowsAffected(); merged > 0 {
+		_, err = db.Exec(`DELETE FROM power_history WHERE id IN (
+			SELECT power_history_id FROM power_history_audit WHERE action = 'merge')`)
+		if err != nil {
+			return err
+		}
+		log.Printf("Database migration: Merged %d same-day power records", merged)
+	}
+
+	_, err = db.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_power_history_member_day ON power_history(member_id, DATE(recorded_at))")
+	if err != nil {
+		return err
+	}
+
+	// Create kills_history and donation_history tables for the other strength ranking tabs
+	createKillsHistorySQL := `CREATE TABLE IF NOT EXISTS kills_history (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		member_id INTEGER NOT NULL,
+		kills INTEGER NOT NULL,
+		recorded_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (member_id) REFERENCES members(id) ON DELETE CASCADE
+	);`
+
+	_, err = db.Exec(createKillsHistorySQL)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec("CREATE INDEX IF NOT EXISTS idx_kills_history_member ON kills_history(member_id, recorded_at DESC)")
+	if err != nil {
+		return err
+	}
+
+	createDonationHistorySQL := `CREATE TABLE IF NOT EXISTS donation_history (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		member_id INTEGER NOT NULL,
+		donations INTEGER NOT NULL,
+		recorded_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (member_id) REFERENCES members(id) ON DELETE CASCADE
+	);`
+
+	_, err = db.Exec(createDonationHistorySQL)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec("CREATE INDEX IF NOT EXISTS idx_donation_history_member ON donation_history(member_id, recorded_at DESC)")
+	if err != nil {
+		return err
+	}
+
+	// Create recommendations table
+	createRecommendationsSQL := `CREATE TABLE IF NOT EXISTS recommendations (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		member_id INTEGER NOT NULL,
+		recommended_by_id INTEGER NOT NULL,
+		notes TEXT,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		expired BOOLEAN DEFAULT 0,
+		FOREIGN KEY (member_id) REFERENCES members(id) ON DELETE CASCADE,
+		FOREIGN KEY (recommended_by_id) REFERENCES users(id) ON DELETE CASCADE
+	);`
+
+	_, err = db.Exec(createRecommendationsSQL)
+	if err != nil {
+		return err
+	}
+
+	// Create dyno recommendations table (informal feedback that expires after 1 week)
+	createDynoRecommendationsSQL := `CREATE TABLE IF NOT EXISTS dyno_recommendations (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		member_id INTEGER NOT NULL,
+		points INTEGER NOT NULL,
+		notes TEXT NOT NULL,
+		created_by_id INTEGER NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
 		FOREIGN KEY (member_id) REFERENCES members(id) ON DELETE CASCADE,
 		FOREIGN KEY (created_by_id) REFERENCES users(id) ON DELETE CASCADE
 	);`
@@ -1028,1931 +1842,2003 @@ func initDB() error {
 		return err
 	}
 
-	// Create storm assignments table
-	createStormAssignmentsSQL := `CREATE TABLE IF NOT EXISTS storm_assignments (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		task_force TEXT NOT NULL CHECK (task_force IN ('A', 'B')),
-		building_id TEXT NOT NULL,
-		member_id INTEGER NOT NULL,
-		position INTEGER NOT NULL CHECK (position BETWEEN 1 AND 4),
-		FOREIGN KEY (member_id) REFERENCES members(id) ON DELETE CASCADE,
-		UNIQUE(task_force, building_id, position)
-	);`
-
-	_, err = db.Exec(createStormAssignmentsSQL)
+	// Create storm buildings table (the Desert Storm map catalogue)
+	var stormBuildingsTableExists bool
+	err = db.QueryRow(`
+		SELECT COUNT(*) > 0
+		FROM sqlite_master
+		WHERE type = 'table' AND name = 'storm_buildings'
+	`).Scan(&stormBuildingsTableExists)
 	if err != nil {
 		return err
 	}
 
-	// Create login_sessions table for tracking login history
-	createLoginSessionsSQL := `CREATE TABLE IF NOT EXISTS login_sessions (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		user_id INTEGER NOT NULL,
-		username TEXT NOT NULL,
-		ip_address TEXT,
-		user_agent TEXT,
-		country TEXT,
-		city TEXT,
-		isp TEXT,
-		login_time TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		success BOOLEAN DEFAULT 1,
-		FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+	createStormBuildingsSQL := `CREATE TABLE IF NOT EXISTS storm_buildings (
+		id TEXT PRIMARY KEY,
+		name TEXT NOT NULL,
+		stage INTEGER NOT NULL CHECK (stage >= 1),
+		points INTEGER NOT NULL DEFAULT 0,
+		priority TEXT NOT NULL CHECK (priority IN ('CRITICAL', 'HIGH', 'MEDIUM', 'LOW')),
+		capacity INTEGER NOT NULL DEFAULT 4 CHECK (capacity >= 1),
+		boost TEXT,
+		sort_order INTEGER DEFAULT 0
 	);`
 
-	_, err = db.Exec(createLoginSessionsSQL)
+	_, err = db.Exec(createStormBuildingsSQL)
 	if err != nil {
 		return err
 	}
 
-	// Create index for faster login history queries
-	_, err = db.Exec("CREATE INDEX IF NOT EXISTS idx_login_sessions_user ON login_sessions(user_id, login_time DESC)")
+	// Seed the storm buildings catalogue with the current map when the table is first created, so
+	// a catalogue the R5 emptied on purpose stays empty
+	if !stormBuildingsTableExists {
+		for i, b := range defaultStormBuildings {
+			_, err = db.Exec(`INSERT INTO storm_buildings (id, name, stage, points, priority, capacity, boost, sort_order)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?)`, b.ID, b.Name, b.Stage, b.Points, b.Priority, b.Capacity, b.Boost, i)
+			if err != nil {
+				return err
+			}
+		}
+		log.Println("Database migration: Seeded the storm buildings catalogue")
+	}
+
+	// Create storm events table (one Desert Storm per date)
+	createStormEventsSQL := `CREATE TABLE IF NOT EXISTS storm_events (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		event_date TEXT NOT NULL UNIQUE,
+		notes TEXT,
+		created_by INTEGER,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (created_by) REFERENCES users(id)
+	);`
+
+	_, err = db.Exec(createStormEventsSQL)
 	if err != nil {
 		return err
 	}
 
-	// Create vs_points table for tracking VS points Monday-Saturday
-	createVSPointsSQL := `CREATE TABLE IF NOT EXISTS vs_points (
+	// Create storm signups table (availability before the event, attendance after it)
+	createStormSignupsSQL := `CREATE TABLE IF NOT EXISTS storm_signups (
 		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		event_id INTEGER NOT NULL,
 		member_id INTEGER NOT NULL,
-		week_date TEXT NOT NULL,
-		monday INTEGER NOT NULL DEFAULT 0,
-		tuesday INTEGER NOT NULL DEFAULT 0,
-		wednesday INTEGER NOT NULL DEFAULT 0,
-		thursday INTEGER NOT NULL DEFAULT 0,
-		friday INTEGER NOT NULL DEFAULT 0,
-		saturday INTEGER NOT NULL DEFAULT 0,
-		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		availability TEXT CHECK (availability IN ('A', 'B', 'either', 'unavailable')),
+		attended BOOLEAN,
 		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (event_id) REFERENCES storm_events(id) ON DELETE CASCADE,
 		FOREIGN KEY (member_id) REFERENCES members(id) ON DELETE CASCADE,
-		UNIQUE(member_id, week_date)
+		UNIQUE(event_id, member_id)
 	);`
 
-	_, err = db.Exec(createVSPointsSQL)
+	_, err = db.Exec(createStormSignupsSQL)
 	if err != nil {
 		return err
 	}
 
-	// Create index for faster VS points queries
-	_, err = db.Exec("CREATE INDEX IF NOT EXISTS idx_vs_points_week ON vs_points(week_date)")
+	// Create storm event results table (one result per task force)
+	createStormEventResultsSQL := `CREATE TABLE IF NOT EXISTS storm_event_results (
+		event_id INTEGER NOT NULL,
+		task_force TEXT NOT NULL CHECK (task_force IN ('A', 'B')),
+		result TEXT NOT NULL CHECK (result IN ('win', 'loss', 'draw')),
+		our_score INTEGER,
+		enemy_score INTEGER,
+		opponent TEXT,
+		FOREIGN KEY (event_id) REFERENCES storm_events(id) ON DELETE CASCADE,
+		PRIMARY KEY (event_id, task_force)
+	);`
+
+	_, err = db.Exec(createStormEventResultsSQL)
 	if err != nil {
 		return err
 	}
 
-	// Initialize default settings if not exist
-	var settingsCount int
-	err = db.QueryRow("SELECT COUNT(*) FROM settings").Scan(&settingsCount)
+	// Create storm assignments table (event_id NULL is the undated plan)
+	createStormAssignmentsSQL := `CREATE TABLE IF NOT EXISTS storm_assignments (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		event_id INTEGER,
+		task_force TEXT NOT NULL CHECK (task_force IN ('A', 'B')),
+		building_id TEXT NOT NULL,
+		member_id INTEGER NOT NULL,
+		position INTEGER NOT NULL CHECK (position >= 1),
+		FOREIGN KEY (event_id) REFERENCES storm_events(id) ON DELETE CASCADE,
+		FOREIGN KEY (member_id) REFERENCES members(id) ON DELETE CASCADE
+	);`
+
+	_, err = db.Exec(createStormAssignmentsSQL)
 	if err != nil {
 		return err
 	}
 
-	if settingsCount == 0 {
-		_, err = db.Exec(`INSERT INTO settings (id, award_first_points, award_second_points, award_third_points, 
-			recommendation_points, recent_conductor_penalty_days, above_average_conductor_penalty, r4r5_rank_boost, 
-			first_time_conductor_boost, schedule_message_template) 
-			VALUES (1, 3, 2, 1, 10, 30, 10, 5, 5, 'Train Schedule - Week {WEEK}\n\n{SCHEDULES}\n\nNext in line:\n{NEXT_3}')`)
-		if err != nil {
-			return err
-		}
-		log.Println("Default settings initialized")
-	}
-
-	// Migrate settings table to add r4r5_rank_boost column if missing
-	var rankBoostColumnExists bool
+	// Migrate storm_assignments to per-event plans without the fixed 1..4 position check
+	// (capacity now comes from storm_buildings)
+	var stormEventColumnExists bool
 	err = db.QueryRow(`
 		SELECT COUNT(*) > 0
-		FROM pragma_table_info('settings')
-		WHERE name = 'r4r5_rank_boost'
-	`).Scan(&rankBoostColumnExists)
+		FROM pragma_table_info('storm_assignments')
+		WHERE name = 'event_id'
+	`).Scan(&stormEventColumnExists)
 	if err != nil {
 		return err
 	}
 
-	if !rankBoostColumnExists {
-		_, err = db.Exec(`ALTER TABLE settings ADD COLUMN r4r5_rank_boost INTEGER NOT NULL DEFAULT 5`)
+	if !stormEventColumnExists {
+		log.Println("Database migration: Adding events to storm_assignments table")
+
+		// Create new table with correct schema
+		_, err = db.Exec(`CREATE TABLE storm_assignments_new (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			event_id INTEGER,
+			task_force TEXT NOT NULL CHECK (task_force IN ('A', 'B')),
+			building_id TEXT NOT NULL,
+			member_id INTEGER NOT NULL,
+			position INTEGER NOT NULL CHECK (position >= 1),
+			FOREIGN KEY (event_id) REFERENCES storm_events(id) ON DELETE CASCADE,
+			FOREIGN KEY (member_id) REFERENCES members(id) ON DELETE CASCADE
+		)`)
 		if err != nil {
-			return err
+			return fmt.Errorf("failed to create new storm_assignments table: %v", err)
 		}
-		log.Println("Database migration: Added r4r5_rank_boost column to settings table")
+
+		// Copy data from old table
+		_, err = db.Exec(`INSERT INTO storm_assignments_new (id, task_force, building_id, member_id, position)
+			SELECT id, task_force, building_id, member_id, position FROM storm_assignments`)
+		if err != nil {
+			return fmt.Errorf("failed to copy storm_assignments data: %v", err)
+		}
+
+		// Drop old table
+		_, err = db.Exec(`DROP TABLE storm_assignments`)
+		if err != nil {
+			return fmt.Errorf("failed to drop old storm_assignments table: %v", err)
+		}
+
+		// Rename new table
+		_, err = db.Exec(`ALTER TABLE storm_assignments_new RENAME TO storm_assignments`)
+		if err != nil {
+			return fmt.Errorf("failed to rename storm_assignments_new table: %v", err)
+		}
+
+		log.Println("Database migration: Successfully added events to storm_assignments")
 	}
 
-	// Migrate settings table to add schedule_message_template column if missing
-	var scheduleTemplateColumnExists bool
-	err = db.QueryRow(`
-		SELECT COUNT(*) > 0
-		FROM pragma_table_info('settings')
-		WHERE name = 'schedule_message_template'
-	`).Scan(&scheduleTemplateColumnExists)
+	// One member per building slot in each event's plan (and in the undated plan)
+	_, err = db.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS idx_storm_assignments_slot
+		ON storm_assignments(COALESCE(event_id, 0), task_force, building_id, position)`)
 	if err != nil {
 		return err
 	}
 
-	if !scheduleTemplateColumnExists {
-		_, err = db.Exec(`ALTER TABLE settings ADD COLUMN schedule_message_template TEXT NOT NULL DEFAULT 'Train Schedule - Week {WEEK}
+	// Create alliance_event_types table (recurring role rotations, the train included)
+	createAllianceEventTypesSQL := `CREATE TABLE IF NOT EXISTS alliance_event_types (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL UNIQUE,
+		recurrence TEXT NOT NULL CHECK (recurrence IN ('daily', 'weekly')),
+		weekdays TEXT NOT NULL DEFAULT '',
+		interval_weeks INTEGER NOT NULL DEFAULT 1 CHECK (interval_weeks >= 1),
+		anchor_date TEXT NOT NULL,
+		start_time TEXT,
+		active BOOLEAN NOT NULL DEFAULT 1,
+		system_key TEXT,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);`
 
-{SCHEDULES}
+	_, err = db.Exec(createAllianceEventTypesSQL)
+	if err != nil {
+		return err
+	}
 
-Next in line:
-{NEXT_3}'`)
-		if err != nil {
-			return err
-		}
-		log.Println("Database migration: Added schedule_message_template column to settings table")
+	// Create alliance_event_slots table (roles filled on every occurrence)
+	createAllianceEventSlotsSQL := `CREATE TABLE IF NOT EXISTS alliance_event_slots (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		event_type_id INTEGER NOT NULL,
+		name TEXT NOT NULL,
+		count INTEGER NOT NULL DEFAULT 1 CHECK (count >= 1),
+		ranks TEXT NOT NULL DEFAULT '',
+		selection TEXT NOT NULL DEFAULT 'ranking' CHECK (selection IN ('ranking', 'random')),
+		sort_order INTEGER DEFAULT 0,
+		system_key TEXT,
+		FOREIGN KEY (event_type_id) REFERENCES alliance_event_types(id) ON DELETE CASCADE
+	);`
+
+	_, err = db.Exec(createAllianceEventSlotsSQL)
+	if err != nil {
+		return err
 	}
 
-	// Migrate settings table to add first_time_conductor_boost column if missing
-	var firstTimeBoostColumnExists bool
-	err = db.QueryRow(`
-		SELECT COUNT(*) > 0
-		FROM pragma_table_info('settings')
-		WHERE name = 'first_time_conductor_boost'
-	`).Scan(&firstTimeBoostColumnExists)
+	// Create alliance_event_assignments table
+	createAllianceEventAssignmentsSQL := `CREATE TABLE IF NOT EXISTS alliance_event_assignments (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		event_type_id INTEGER NOT NULL,
+		date TEXT NOT NULL,
+		slot_id INTEGER NOT NULL,
+		position INTEGER NOT NULL DEFAULT 1 CHECK (position >= 1),
+		member_id INTEGER NOT NULL,
+		score INTEGER,
+		showed_up BOOLEAN,
+		notes TEXT,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (event_type_id) REFERENCES alliance_event_types(id) ON DELETE CASCADE,
+		FOREIGN KEY (slot_id) REFERENCES alliance_event_slots(id) ON DELETE CASCADE,
+		FOREIGN KEY (member_id) REFERENCES members(id) ON DELETE CASCADE,
+		UNIQUE(event_type_id, date, slot_id, position)
+	);`
+
+	_, err = db.Exec(createAllianceEventAssignmentsSQL)
 	if err != nil {
 		return err
 	}
 
-	if !firstTimeBoostColumnExists {
-		_, err = db.Exec(`ALTER TABLE settings ADD COLUMN first_time_conductor_boost INTEGER NOT NULL DEFAULT 5`)
+	// system_key marks the built-in train event type and its conductor and backup slots
+	for _, table := range []string{"alliance_event_types", "alliance_event_slots"} {
+		var hasSystemKey bool
+		err = db.QueryRow(`SELECT COUNT(*) > 0 FROM pragma_table_info('` + table + `') WHERE name = 'system_key'`).Scan(&hasSystemKey)
 		if err != nil {
 			return err
 		}
-		log.Println("Database migration: Added first_time_conductor_boost column to settings table")
+		if !hasSystemKey {
+			if _, err = db.Exec(`ALTER TABLE ` + table + ` ADD COLUMN system_key TEXT`); err != nil {
+				return err
+			}
+			log.Printf("Database migration: Added system_key column to %s table", table)
+		}
 	}
 
-	// Migrate settings table to add daily_message_template column if missing
-	var dailyTemplateColumnExists bool
-	err = db.QueryRow(`
-		SELECT COUNT(*) > 0
-		FROM pragma_table_info('settings')
-		WHERE name = 'daily_message_template'
-	`).Scan(&dailyTemplateColumnExists)
+	_, err = db.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS idx_alliance_event_types_system_key ON alliance_event_types(system_key)`)
 	if err != nil {
 		return err
 	}
 
-	if !dailyTemplateColumnExists {
-		defaultDailyTemplate := `ALL ABOARD! Daily Train Assignment
-
-Date: {DATE}
-
-Today's Conductor: {CONDUCTOR_NAME} ({CONDUCTOR_RANK})
-Backup Engineer: {BACKUP_NAME} ({BACKUP_RANK})
-
-DEPARTURE SCHEDULE:
-- 15:00 ST (17:00 UK) - Conductor {CONDUCTOR_NAME}, please request train assignment in alliance chat
-- 16:30 ST (18:30 UK) - If conductor hasn't shown up, Backup {BACKUP_NAME} takes over and assigns train to themselves
-
-Remember: Communication is key! Let the alliance know if you can't make it.
+	// The train is an event type with a conductor slot picked by ranking and an R4/R5 backup picked at random.
+	// Its schedules used to live in the train_schedules table; they move to its assignments in the same
+	// transaction that replaces the table with a view, so a failed start leaves the table to retry from.
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
 
-All aboard for another successful run!`
-		// Add column without default first
-		_, err = db.Exec(`ALTER TABLE settings ADD COLUMN daily_message_template TEXT`)
+	var trainTypeID int
+	err = tx.QueryRow("SELECT id FROM alliance_event_types WHERE system_key = ?", trainEventKey).Scan(&trainTypeID)
+	if err == sql.ErrNoRows {
+		name := "Train"
+		var taken int
+		if tx.QueryRow("SELECT id FROM alliance_event_types WHERE name = ?", name).Scan(&taken) == nil {
+			name = "Train (built-in)"
+		}
+		result, err := tx.Exec(`INSERT INTO alliance_event_types (name, recurrence, weekdays, interval_weeks, anchor_date, active, system_key)
+			VALUES (?, 'daily', '', 1, ?, 1, ?)`, name, formatDateString(time.Now()), trainEventKey)
 		if err != nil {
 			return err
 		}
-		// Then update existing row with the default value
-		_, err = db.Exec(`UPDATE settings SET daily_message_template = ? WHERE id = 1`, defaultDailyTemplate)
+		id, _ := result.LastInsertId()
+		trainTypeID = int(id)
+
+		_, err = tx.Exec(`INSERT INTO alliance_event_slots (event_type_id, name, count, ranks, selection, sort_order, system_key)
+			VALUES (?, 'Conductor', 1, '', 'ranking', 0, ?), (?, 'Backup', 1, 'R4,R5', 'random', 1, ?)`,
+			trainTypeID, trainConductorSlotKey, trainTypeID, trainBackupSlotKey)
 		if err != nil {
 			return err
 		}
-		log.Println("Database migration: Added daily_message_template column to settings table")
+		log.Println("Database migration: Added the train event type")
+	} else if err != nil {
+		return err
 	}
 
-	// Migrate settings table to add power_tracking_enabled column if missing
-	var powerTrackingColumnExists bool
-	err = db.QueryRow(`
-		SELECT COUNT(*) FROM pragma_table_info('settings') 
-		WHERE name='power_tracking_enabled'
-	`).Scan(&powerTrackingColumnExists)
-	if err != nil || !powerTrackingColumnExists {
-		_, err = db.Exec(`ALTER TABLE settings ADD COLUMN power_tracking_enabled BOOLEAN DEFAULT 0`)
+	if trainSchedulesType != "view" {
+		var conductorSlotID, backupSlotID int
+		err = tx.QueryRow("SELECT id FROM alliance_event_slots WHERE event_type_id = ? AND system_key = ?", trainTypeID, trainConductorSlotKey).Scan(&conductorSlotID)
 		if err != nil {
 			return err
 		}
-		log.Println("Database migration: Added power_tracking_enabled column to settings table")
-	}
-
-	// Create default admin user if no users exist
-	var userCount int
-	err = db.QueryRow("SELECT COUNT(*) FROM users").Scan(&userCount)
-	if err != nil {
-		return err
-	}
-
-	if userCount == 0 {
-		// Default credentials: admin/admin123
-		hashedPassword, err := bcrypt.GenerateFromPassword([]byte("admin123"), bcrypt.DefaultCost)
+		err = tx.QueryRow("SELECT id FROM alliance_event_slots WHERE event_type_id = ? AND system_key = ?", trainTypeID, trainBackupSlotKey).Scan(&backupSlotID)
 		if err != nil {
 			return err
 		}
-		_, err = db.Exec("INSERT INTO users (username, password, is_admin) VALUES (?, ?, ?)", "admin", string(hashedPassword), true)
+
+		_, err = tx.Exec(`INSERT INTO alliance_event_assignments (event_type_id, date, slot_id, position, member_id, score, showed_up, notes, created_at)
+			SELECT ?, date, ?, 1, conductor_id, conductor_score, conductor_showed_up, notes, created_at
+			FROM train_schedules`, trainTypeID, conductorSlotID)
 		if err != nil {
-			return err
+			return fmt.Errorf("failed to copy train conductors: %v", err)
 		}
-		log.Println("Default admin user created - Username: admin, Password: admin123")
-	}
-
-	return nil
-}
-
-// Authentication middleware
-func authMiddleware(next http.HandlerFunc) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		session, _ := store.Get(r, "session")
-		if auth, ok := session.Values["authenticated"].(bool); !ok || !auth {
-			http.Error(w, "Unauthorized", http.StatusUnauthorized)
-			return
+		_, err = tx.Exec(`INSERT INTO alliance_event_assignments (event_type_id, date, slot_id, position, member_id, created_at)
+			SELECT ?, date, ?, 1, backup_id, created_at
+			FROM train_schedules
+			WHERE backup_id IS NOT NULL AND backup_id > 0`, trainTypeID, backupSlotID)
+		if err != nil {
+			return fmt.Errorf("failed to copy train backups: %v", err)
 		}
-		next(w, r)
-	}
-}
 
-// Permission middleware - only R4/R5 or admin can manage ranks
-func rankManagementMiddleware(next http.HandlerFunc) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		session, _ := store.Get(r, "session")
-
-		// Check if admin
-		if isAdmin, ok := session.Values["is_admin"].(bool); ok && isAdmin {
-			next(w, r)
-			return
+		if _, err = tx.Exec(`DROP TABLE train_schedules`); err != nil {
+			return fmt.Errorf("failed to drop train_schedules table: %v", err)
 		}
 
-		// Check if member has R4 or R5 rank
-		if memberID, ok := session.Values["member_id"].(int); ok {
-			var rank string
-			err := db.QueryRow("SELECT rank FROM members WHERE id = ?", memberID).Scan(&rank)
-			if err == nil && (rank == "R4" || rank == "R5") {
-				next(w, r)
-				return
-			}
+		// One row per day the train has a conductor; the ID is the conductor assignment's
+		_, err = tx.Exec(`CREATE VIEW train_schedules AS
+			SELECT c.id AS id, c.date AS date, c.member_id AS conductor_id, b.member_id AS backup_id,
+				c.score AS conductor_score, c.showed_up AS conductor_showed_up, c.notes AS notes, c.created_at AS created_at
+			FROM alliance_event_types t
+			JOIN alliance_event_slots cs ON cs.event_type_id = t.id AND cs.system_key = '` + trainConductorSlotKey + `'
+			JOIN alliance_event_assignments c ON c.slot_id = cs.id AND c.position = 1
+			LEFT JOIN alliance_event_slots bs ON bs.event_type_id = t.id AND bs.system_key = '` + trainBackupSlotKey + `'
+			LEFT JOIN alliance_event_assignments b ON b.slot_id = bs.id AND b.date = c.date AND b.position = 1
+			WHERE t.system_key = '` + trainEventKey + `'`)
+		if err != nil {
+			return fmt.Errorf("failed to create train_schedules view: %v", err)
 		}
-
-		http.Error(w, "Forbidden: Only R4/R5 members can manage ranks", http.StatusForbidden)
+		log.Println("Database migration: Moved train schedules onto the train event type")
 	}
-}
-
-// Permission middleware - only R5 or admin
-func adminR5Middleware(next http.HandlerFunc) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		session, _ := store.Get(r, "session")
-
-		// Check if admin
-		if isAdmin, ok := session.Values["is_admin"].(bool); ok && isAdmin {
-			next(w, r)
-			return
-		}
 
-		// Check if member has R5 rank
-		if memberID, ok := session.Values["member_id"].(int); ok {
-			var rank string
-			err := db.QueryRow("SELECT rank FROM members WHERE id = ?", memberID).Scan(&rank)
-			if err == nil && rank == "R5" {
-				next(w, r)
-				return
-			}
-		}
-
-		http.Error(w, "Forbidden: Only R5 members and admins can perform this action", http.StatusForbidden)
+	if err = tx.Commit(); err != nil {
+		return err
 	}
-}
-
-// Admin-only middleware
-func adminMiddleware(next http.HandlerFunc) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		session, _ := store.Get(r, "session")
 
-		if isAdmin, ok := session.Values["is_admin"].(bool); !ok || !isAdmin {
-			http.Error(w, "Forbidden: Admin access required", http.StatusForbidden)
-			return
-		}
+	// Create message_templates table (every saved version of each chat message template)
+	createMessageTemplatesSQL := `CREATE TABLE IF NOT EXISTS message_templates (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL,
+		kind TEXT NOT NULL,
+		version INTEGER NOT NULL,
+		body TEXT NOT NULL,
+		created_by TEXT,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE(name, version)
+	);`
 
-		next(w, r)
+	_, err = db.Exec(createMessageTemplatesSQL)
+	if err != nil {
+		return err
 	}
-}
 
-// Login handler
-func login(w http.ResponseWriter, r *http.Request) {
-	var creds Credentials
-	if err := json.NewDecoder(r.Body).Decode(&creds); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
+	// Create login_sessions table for tracking login history
+	createLoginSessionsSQL := `CREATE TABLE IF NOT EXISTS login_sessions (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL,
+		username TEXT NOT NULL,
+		ip_address TEXT,
+		user_agent TEXT,
+		country TEXT,
+		city TEXT,
+		isp TEXT,
+		login_time TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		success BOOLEAN DEFAULT 1,
+		FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+	);`
+
+	_, err = db.Exec(createLoginSessionsSQL)
+	if err != nil {
+		return err
 	}
 
-	var user User
-	var memberID sql.NullInt64
-	var isAdmin sql.NullBool
-	err := db.QueryRow("SELECT id, username, password, member_id, is_admin FROM users WHERE username = ?", creds.Username).Scan(&user.ID, &user.Username, &user.Password, &memberID, &isAdmin)
+	// Create index for faster login history queries
+	_, err = db.Exec("CREATE INDEX IF NOT EXISTS idx_login_sessions_user ON login_sessions(user_id, login_time DESC)")
 	if err != nil {
-		// Track failed login attempt
-		trackLogin(0, creds.Username, r, false)
-		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
-		return
+		return err
 	}
 
-	if memberID.Valid {
-		mid := int(memberID.Int64)
-		user.MemberID = &mid
+	// Create vs_points table for tracking VS points (one row per member, week, day and event type)
+	createVSPointsSQL := `CREATE TABLE IF NOT EXISTS vs_points (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		member_id INTEGER NOT NULL,
+		week_date TEXT NOT NULL,
+		day TEXT NOT NULL CHECK(day IN ('monday', 'tuesday', 'wednesday', 'thursday', 'friday', 'saturday', 'sunday')),
+		event_type TEXT NOT NULL DEFAULT 'alliance_duel',
+		points INTEGER NOT NULL DEFAULT 0,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (member_id) REFERENCES members(id) ON DELETE CASCADE,
+		UNIQUE(member_id, week_date, day, event_type)
+	);`
+
+	_, err = db.Exec(createVSPointsSQL)
+	if err != nil {
+		return err
 	}
-	user.IsAdmin = isAdmin.Valid && isAdmin.Bool
 
-	// Compare password
-	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(creds.Password))
+	// Migrate vs_points from one column per day to one row per day (for existing databases)
+	var vsDayColumnsExist bool
+	err = db.QueryRow(`
+		SELECT COUNT(*) > 0
+		FROM pragma_table_info('vs_points')
+		WHERE name = 'monday'
+	`).Scan(&vsDayColumnsExist)
 	if err != nil {
-		// Track failed login attempt
-		trackLogin(user.ID, user.Username, r, false)
-		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
-		return
+		return err
 	}
 
-	// Track successful login
-	trackLogin(user.ID, user.Username, r, true)
+	if vsDayColumnsExist {
+		log.Println("Database migration: Converting vs_points to one row per member, week and day")
 
-	// Create session
-	session, _ := store.Get(r, "session")
-	session.Values["authenticated"] = true
-	session.Values["username"] = user.Username
-	session.Values["user_id"] = user.ID
-	if user.MemberID != nil {
-		session.Values["member_id"] = *user.MemberID
-	}
-	session.Values["is_admin"] = user.IsAdmin
-	session.Save(r, w)
+		// Create new table with correct schema
+		_, err = db.Exec(`CREATE TABLE vs_points_new (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			member_id INTEGER NOT NULL,
+			week_date TEXT NOT NULL,
+			day TEXT NOT NULL CHECK(day IN ('monday', 'tuesday', 'wednesday', 'thursday', 'friday', 'saturday', 'sunday')),
+			event_type TEXT NOT NULL DEFAULT 'alliance_duel',
+			points INTEGER NOT NULL DEFAULT 0,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (member_id) REFERENCES members(id) ON DELETE CASCADE,
+			UNIQUE(member_id, week_date, day, event_type)
+		)`)
+		if err != nil {
+			return fmt.Errorf("failed to create new vs_points table: %v", err)
+		}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{"message": "Login successful", "username": user.Username})
-}
+		// Copy data from old table, one row per day
+		_, err = db.Exec(`INSERT INTO vs_points_new (member_id, week_date, day, event_type, points, created_at, updated_at)
+			SELECT member_id, week_date, 'monday', 'alliance_duel', monday, created_at, updated_at FROM vs_points
+			UNION ALL SELECT member_id, week_date, 'tuesday', 'alliance_duel', tuesday, created_at, updated_at FROM vs_points
+			UNION ALL SELECT member_id, week_date, 'wednesday', 'alliance_duel', wednesday, created_at, updated_at FROM vs_points
+			UNION ALL SELECT member_id, week_date, 'thursday', 'alliance_duel', thursday, created_at, updated_at FROM vs_points
+			UNION ALL SELECT member_id, week_date, 'friday', 'alliance_duel', friday, created_at, updated_at FROM vs_points
+			UNION ALL SELECT member_id, week_date, 'saturday', 'alliance_duel', saturday, created_at, updated_at FROM vs_points`)
+		if err != nil {
+			return fmt.Errorf("failed to copy vs_points data: %v", err)
+		}
 
-// Logout handler
-func logout(w http.ResponseWriter, r *http.Request) {
-	session, _ := store.Get(r, "session")
-	session.Values["authenticated"] = false
-	session.Options.MaxAge = -1
-	session.Save(r, w)
+		// Drop old table (its index goes with it)
+		_, err = db.Exec(`DROP TABLE vs_points`)
+		if err != nil {
+			return fmt.Errorf("failed to drop old vs_points table: %v", err)
+		}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{"message": "Logout successful"})
-}
+		// Rename new table
+		_, err = db.Exec(`ALTER TABLE vs_points_new RENAME TO vs_points`)
+		if err != nil {
+			return fmt.Errorf("failed to rename vs_points_new table: %v", err)
+		}
 
-// Change password handler
-func changePassword(w http.ResponseWriter, r *http.Request) {
-	session, _ := store.Get(r, "session")
-	userID, ok := session.Values["user_id"].(int)
-	if !ok {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
-		return
+		log.Println("Database migration: Successfully converted vs_points to one row per day")
 	}
 
-	var input struct {
-		CurrentPassword string `json:"current_password"`
-		NewPassword     string `json:"new_password"`
-	}
+	// Create inactivity_settings table (single row) weighing the inactivity report signals
+	createInactivitySettingsSQL := `CREATE TABLE IF NOT EXISTS inactivity_settings (
+		id INTEGER PRIMARY KEY CHECK (id = 1),
+		lookback_weeks INTEGER NOT NULL DEFAULT 4,
+		vs_zero_day_points INTEGER NOT NULL DEFAULT 3,
+		stagnant_power_points INTEGER NOT NULL DEFAULT 20,
+		stagnation_snapshots INTEGER NOT NULL DEFAULT 3,
+		no_train_duty_points INTEGER NOT NULL DEFAULT 5,
+		no_show_points INTEGER NOT NULL DEFAULT 15,
+		no_login_points INTEGER NOT NULL DEFAULT 20,
+		no_login_days INTEGER NOT NULL DEFAULT 7,
+		flag_score INTEGER NOT NULL DEFAULT 30,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);`
 
-	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
-		return
+	_, err = db.Exec(createInactivitySettingsSQL)
+	if err != nil {
+		return err
 	}
 
-	if len(input.NewPassword) < 6 {
-		http.Error(w, "New password must be at least 6 characters", http.StatusBadRequest)
-		return
+	_, err = db.Exec("INSERT OR IGNORE INTO inactivity_settings (id) VALUES (1)")
+	if err != nil {
+		return err
 	}
 
-	// Get current password hash
-	var currentHash string
-	err := db.QueryRow("SELECT password FROM users WHERE id = ?", userID).Scan(&currentHash)
+	// Create vs_requirements table for the minimum daily VS points per theme
+	createVSRequirementsSQL := `CREATE TABLE IF NOT EXISTS vs_requirements (
+		day TEXT PRIMARY KEY CHECK(day IN ('monday', 'tuesday', 'wednesday', 'thursday', 'friday', 'saturday')),
+		theme TEXT NOT NULL,
+		min_points INTEGER NOT NULL DEFAULT 0,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);`
+
+	_, err = db.Exec(createVSRequirementsSQL)
 	if err != nil {
-		http.Error(w, "User not found", http.StatusNotFound)
-		return
+		return err
 	}
 
-	// Verify current password
-	err = bcrypt.CompareHashAndPassword([]byte(currentHash), []byte(input.CurrentPassword))
+	// Seed the VS day themes (no minimum until configured)
+	_, err = db.Exec(`INSERT OR IGNORE INTO vs_requirements (day, theme, min_points) VALUES
+		('monday', 'Radar Training', 0),
+		('tuesday', 'Base Expansion', 0),
+		('wednesday', 'Age of Science', 0),
+		('thursday', 'Train Heroes', 0),
+		('friday', 'Total Mobilization', 0),
+		('saturday', 'Enemy Buster', 0)`)
 	if err != nil {
-		http.Error(w, "Current password is incorrect", http.StatusUnauthorized)
-		return
+		return err
 	}
 
-	// Hash new password
-	newHash, err := bcrypt.GenerateFromPassword([]byte(input.NewPassword), bcrypt.DefaultCost)
+	// Create index for faster VS points queries
+	_, err = db.Exec("CREATE INDEX IF NOT EXISTS idx_vs_points_week ON vs_points(week_date, event_type)")
 	if err != nil {
-		http.Error(w, "Failed to hash password", http.StatusInternalServerError)
-		return
+		return err
 	}
 
-	// Update password
-	_, err = db.Exec("UPDATE users SET password = ? WHERE id = ?", string(newHash), userID)
+	// Create ocr_batches table for screenshot uploads waiting for review
+	createOCRBatchesSQL := `CREATE TABLE IF NOT EXISTS ocr_batches (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		kind TEXT NOT NULL CHECK(kind IN ('vs_points', 'power')),
+		status TEXT NOT NULL DEFAULT 'pending' CHECK(status IN ('pending', 'approved', 'rejected')),
+		source TEXT NOT NULL CHECK(source IN ('image', 'text', 'records')),
+		week_date TEXT,
+		day TEXT,
+		metric TEXT,
+		image BLOB,
+		raw_text TEXT,
+		created_by INTEGER,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		reviewed_by INTEGER,
+		reviewed_at TIMESTAMP,
+		FOREIGN KEY (created_by) REFERENCES users(id) ON DELETE SET NULL,
+		FOREIGN KEY (reviewed_by) REFERENCES users(id) ON DELETE SET NULL
+	);`
+
+	_, err = db.Exec(createOCRBatchesSQL)
 	if err != nil {
-		http.Error(w, "Failed to update password", http.StatusInternalServerError)
-		return
+		return err
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{"message": "Password changed successfully"})
-}
+	// Migrate ocr_batches to add the strength ranking tab (power, kills, donation) of power batches
+	var ocrBatchMetricColumnExists bool
+	err = db.QueryRow(`
+		SELECT COUNT(*) > 0
+		FROM pragma_table_info('ocr_batches')
+		WHERE name = 'metric'
+	`).Scan(&ocrBatchMetricColumnExists)
+	if err != nil {
+		return err
+	}
 
-// Generate random alphanumeric password
-func generateRandomPassword(length int) (string, error) {
-	const charset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
-	password := make([]byte, length)
-	for i := range password {
-		num, err := rand.Int(rand.Reader, big.NewInt(int64(len(charset))))
+	if !ocrBatchMetricColumnExists {
+		_, err = db.Exec(`ALTER TABLE ocr_batches ADD COLUMN metric TEXT`)
 		if err != nil {
-			return "", err
+			return err
 		}
-		password[i] = charset[num.Int64()]
+		_, err = db.Exec(`UPDATE ocr_batches SET metric = 'power' WHERE kind = 'power'`)
+		if err != nil {
+			return err
+		}
+		log.Println("Database migration: Added metric column to ocr_batches table")
 	}
-	return string(password), nil
-}
 
-// Get client IP with X-Forwarded-For and X-Real-IP support
-func getClientIP(r *http.Request) string {
-	// Check X-Forwarded-For header (proxy/load balancer)
-	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
-		ips := strings.Split(xff, ",")
-		return strings.TrimSpace(ips[0])
-	}
+	// Create ocr_batch_rows table for the parsed rows of each batch
+	createOCRBatchRowsSQL := `CREATE TABLE IF NOT EXISTS ocr_batch_rows (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		batch_id INTEGER NOT NULL,
+		row_index INTEGER NOT NULL,
+		raw_text TEXT NOT NULL DEFAULT '',
+		parsed_name TEXT NOT NULL,
+		value INTEGER NOT NULL,
+		confidence REAL NOT NULL DEFAULT 0,
+		name_text TEXT NOT NULL DEFAULT '',
+		name_confidence REAL NOT NULL DEFAULT 0,
+		name_x INTEGER,
+		name_y INTEGER,
+		name_width INTEGER,
+		name_height INTEGER,
+		value_text TEXT NOT NULL DEFAULT '',
+		value_confidence REAL NOT NULL DEFAULT 0,
+		value_x INTEGER,
+		value_y INTEGER,
+		value_width INTEGER,
+		value_height INTEGER,
+		low_confidence BOOLEAN NOT NULL DEFAULT 0,
+		rank INTEGER,
+		page_index INTEGER NOT NULL DEFAULT 0,
+		member_id INTEGER,
+		match_score INTEGER NOT NULL DEFAULT 0,
+		corrected BOOLEAN NOT NULL DEFAULT 0,
+		skip BOOLEAN NOT NULL DEFAULT 0,
+		FOREIGN KEY (batch_id) REFERENCES ocr_batches(id) ON DELETE CASCADE,
+		FOREIGN KEY (member_id) REFERENCES members(id) ON DELETE SET NULL
+	);`
 
-	// Check X-Real-IP header (nginx)
-	if xri := r.Header.Get("X-Real-IP"); xri != "" {
-		return xri
+	_, err = db.Exec(createOCRBatchRowsSQL)
+	if err != nil {
+		return err
 	}
 
-	// Fall back to RemoteAddr
-	ip := r.RemoteAddr
-	if colonIndex := strings.LastIndex(ip, ":"); colonIndex != -1 {
-		ip = ip[:colonIndex]
-	}
-	return ip
-}
+	// Migrate ocr_batch_rows to add per-field text, confidence, bounding boxes and rank columns if missing
+	ocrBatchRowColumns := []struct {
+		name       string
+		definition string
+	}{
+		{"name_text", "TEXT NOT NULL DEFAULT ''"},
+		{"name_confidence", "REAL NOT NULL DEFAULT 0"},
+		{"name_x", "INTEGER"},
+		{"name_y", "INTEGER"},
+		{"name_width", "INTEGER"},
+		{"name_height", "INTEGER"},
+		{"value_text", "TEXT NOT NULL DEFAULT ''"},
+		{"value_confidence", "REAL NOT NULL DEFAULT 0"},
+		{"value_x", "INTEGER"},
+		{"value_y", "INTEGER"},
+		{"value_width", "INTEGER"},
+		{"value_height", "INTEGER"},
+		{"low_confidence", "BOOLEAN NOT NULL DEFAULT 0"},
+		{"rank", "INTEGER"},
+		{"page_index", "INTEGER NOT NULL DEFAULT 0"},
+	}
+	for _, column := range ocrBatchRowColumns {
+		var ocrColumnExists bool
+		err = db.QueryRow(`
+			SELECT COUNT(*) > 0
+			FROM pragma_table_info('ocr_batch_rows')
+			WHERE name = ?
+		`, column.name).Scan(&ocrColumnExists)
+		if err != nil {
+			return err
+		}
 
-// Get IP geolocation information using ip-api.com (free, no key required)
-func getIPGeolocation(ip string) (*IPGeolocation, error) {
-	// Skip localhost/private IPs
-	if ip == "127.0.0.1" || ip == "::1" || strings.HasPrefix(ip, "192.168.") || strings.HasPrefix(ip, "10.") {
-		return &IPGeolocation{
-			Status:  "success",
-			Country: "Local Network",
-			City:    "Localhost",
-			ISP:     "Private Network",
-			Query:   ip,
-		}, nil
+		if !ocrColumnExists {
+			_, err = db.Exec(`ALTER TABLE ocr_batch_rows ADD COLUMN ` + column.name + ` ` + column.definition)
+			if err != nil {
+				return err
+			}
+			log.Printf("Database migration: Added %s column to ocr_batch_rows table", column.name)
+		}
 	}
 
-	url := fmt.Sprintf("http://ip-api.com/json/%s?fields=status,country,countryCode,region,regionName,city,zip,lat,lon,timezone,isp,org,as,query", ip)
+	// Create ocr_batch_images table for the screenshots of multi-image uploads
+	createOCRBatchImagesSQL := `CREATE TABLE IF NOT EXISTS ocr_batch_images (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		batch_id INTEGER NOT NULL,
+		page_index INTEGER NOT NULL,
+		file_name TEXT NOT NULL DEFAULT '',
+		image BLOB NOT NULL,
+		FOREIGN KEY (batch_id) REFERENCES ocr_batches(id) ON DELETE CASCADE,
+		UNIQUE(batch_id, page_index)
+	);`
 
-	client := &http.Client{Timeout: 5 * time.Second}
-	resp, err := client.Get(url)
+	_, err = db.Exec(createOCRBatchImagesSQL)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	defer resp.Body.Close()
 
-	var geo IPGeolocation
-	if err := json.NewDecoder(resp.Body).Decode(&geo); err != nil {
-		return nil, err
+	_, err = db.Exec("CREATE INDEX IF NOT EXISTS idx_ocr_batches_status ON ocr_batches(status, created_at DESC)")
+	if err != nil {
+		return err
 	}
 
-	if geo.Status != "success" {
-		return nil, fmt.Errorf("geolocation lookup failed")
+	_, err = db.Exec("CREATE INDEX IF NOT EXISTS idx_ocr_batch_rows_batch ON ocr_batch_rows(batch_id, row_index)")
+	if err != nil {
+		return err
 	}
 
-	return &geo, nil
-}
+	// Create ocr_jobs table for screenshot recognition queued in the background
+	createOCRJobsSQL := `CREATE TABLE IF NOT EXISTS ocr_jobs (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		kind TEXT NOT NULL CHECK(kind IN ('vs_points', 'power')),
+		action TEXT NOT NULL CHECK(action IN ('upload', 'stitch', 'reprocess')),
+		status TEXT NOT NULL DEFAULT 'queued' CHECK(status IN ('queued', 'running', 'succeeded', 'failed')),
+		week_date TEXT,
+		metric TEXT,
+		batch_id INTEGER,
+		progress_done INTEGER NOT NULL DEFAULT 0,
+		progress_total INTEGER NOT NULL DEFAULT 0,
+		stage TEXT,
+		error TEXT,
+		result TEXT,
+		attempts INTEGER NOT NULL DEFAULT 0,
+		created_by INTEGER,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		started_at TIMESTAMP,
+		finished_at TIMESTAMP,
+		FOREIGN KEY (batch_id) REFERENCES ocr_batches(id) ON DELETE SET NULL,
+		FOREIGN KEY (created_by) REFERENCES users(id) ON DELETE SET NULL
+	);`
 
-// Track login attempt in database
-func trackLogin(userID int, username string, r *http.Request, success bool) {
-	ip := getClientIP(r)
-	userAgent := r.Header.Get("User-Agent")
+	_, err = db.Exec(createOCRJobsSQL)
+	if err != nil {
+		return err
+	}
 
-	var country, city, isp *string
+	// Migrate ocr_jobs to add the requested strength ranking tab
+	var ocrJobMetricColumnExists bool
+	err = db.QueryRow(`
+		SELECT COUNT(*) > 0
+		FROM pragma_table_info('ocr_jobs')
+		WHERE name = 'metric'
+	`).Scan(&ocrJobMetricColumnExists)
+	if err != nil {
+		return err
+	}
 
-	// Get geolocation data (non-blocking, log errors but don't fail)
-	if geo, err := getIPGeolocation(ip); err == nil {
-		country = &geo.Country
-		city = &geo.City
-		isp = &geo.ISP
+	if !ocrJobMetricColumnExists {
+		_, err = db.Exec(`ALTER TABLE ocr_jobs ADD COLUMN metric TEXT`)
+		if err != nil {
+			return err
+		}
+		log.Println("Database migration: Added metric column to ocr_jobs table")
 	}
 
-	_, err := db.Exec(`INSERT INTO login_sessions (user_id, username, ip_address, user_agent, country, city, isp, success) 
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
-		userID, username, ip, userAgent, country, city, isp, success)
+	// Create ocr_job_images table for the screenshots waiting in (or failed in) the OCR queue
+	createOCRJobImagesSQL := `CREATE TABLE IF NOT EXISTS ocr_job_images (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		job_id INTEGER NOT NULL,
+		page_index INTEGER NOT NULL,
+		file_name TEXT NOT NULL DEFAULT '',
+		image BLOB NOT NULL,
+		FOREIGN KEY (job_id) REFERENCES ocr_jobs(id) ON DELETE CASCADE,
+		UNIQUE(job_id, page_index)
+	);`
 
+	_, err = db.Exec(createOCRJobImagesSQL)
 	if err != nil {
-		log.Printf("Failed to track login: %v", err)
+		return err
 	}
-}
 
-// Create user for member
-func createUserForMember(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	memberID, err := strconv.Atoi(vars["id"])
+	_, err = db.Exec("CREATE INDEX IF NOT EXISTS idx_ocr_jobs_status ON ocr_jobs(status, id)")
 	if err != nil {
-		http.Error(w, "Invalid member ID", http.StatusBadRequest)
-		return
+		return err
 	}
 
-	// Check if member exists
-	var memberName string
-	err = db.QueryRow("SELECT name FROM members WHERE id = ?", memberID).Scan(&memberName)
+	// Create layout_profiles table for screenshot layouts of different devices and game screens
+	createLayoutProfilesSQL := `CREATE TABLE IF NOT EXISTS layout_profiles (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL UNIQUE,
+		screen TEXT NOT NULL DEFAULT 'any' CHECK(screen IN ('any', 'power', 'vs_points')),
+		aspect_ratio REAL NOT NULL DEFAULT 0,
+		aspect_tolerance REAL NOT NULL DEFAULT 0,
+		title_bottom REAL NOT NULL,
+		tabs_top REAL NOT NULL,
+		tabs_bottom REAL NOT NULL,
+		tabs_left REAL NOT NULL DEFAULT 0,
+		tabs_right REAL NOT NULL DEFAULT 1,
+		tab_count INTEGER NOT NULL,
+		tab_days TEXT NOT NULL,
+		selected_tab_color TEXT NOT NULL,
+		color_tolerance INTEGER NOT NULL,
+		data_top REAL NOT NULL,
+		data_bottom REAL NOT NULL,
+		visible_rows INTEGER NOT NULL,
+		rank_column_end REAL NOT NULL,
+		name_column_end REAL NOT NULL,
+		is_default BOOLEAN NOT NULL DEFAULT 0,
+		template BLOB,
+		created_by INTEGER,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (created_by) REFERENCES users(id) ON DELETE SET NULL
+	);`
+
+	_, err = db.Exec(createLayoutProfilesSQL)
 	if err != nil {
-		http.Error(w, "Member not found", http.StatusNotFound)
-		return
+		return err
 	}
 
-	// Check if user already exists for this member
-	var existingUserID int
-	err = db.QueryRow("SELECT id FROM users WHERE member_id = ?", memberID).Scan(&existingUserID)
-	if err == nil {
-		http.Error(w, "User already exists for this member", http.StatusConflict)
-		return
+	// Seed the built-in phone layout the recognizer was originally tuned on
+	var layoutProfileCount int
+	err = db.QueryRow("SELECT COUNT(*) FROM layout_profiles").Scan(&layoutProfileCount)
+	if err != nil {
+		return err
+	}
+	if layoutProfileCount == 0 {
+		if _, err := insertLayoutProfile(defaultLayoutProfile(), nil, nil); err != nil {
+			return err
+		}
+		log.Println("Default layout profile initialized")
 	}
 
-	// Generate random password
-	randomPassword, err := generateRandomPassword(10)
+	// Initialize default settings if not exist
+	var settingsCount int
+	err = db.QueryRow("SELECT COUNT(*) FROM settings").Scan(&settingsCount)
 	if err != nil {
-		http.Error(w, "Failed to generate password", http.StatusInternalServerError)
-		return
+		return err
 	}
 
-	// Hash password
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(randomPassword), bcrypt.DefaultCost)
-	if err != nil {
-		http.Error(w, "Failed to hash password", http.StatusInternalServerError)
-		return
+	if settingsCount == 0 {
+		_, err = db.Exec(`INSERT INTO settings (id, award_first_points, award_second_points, award_third_points, 
+			recommendation_points, recent_conductor_penalty_days, above_average_conductor_penalty, r4r5_rank_boost, 
+			first_time_conductor_boost, schedule_message_template) 
+			VALUES (1, 3, 2, 1, 10, 30, 10, 5, 5, 'Train Schedule - Week {WEEK}\n\n{SCHEDULES}\n\nNext in line:\n{NEXT_3}')`)
+		if err != nil {
+			return err
+		}
+		log.Println("Default settings initialized")
 	}
 
-	// Create username from member name (lowercase, no spaces)
-	username := strings.ToLower(strings.ReplaceAll(memberName, " ", ""))
+	// Migrate settings table to add r4r5_rank_boost column if missing
+	var rankBoostColumnExists bool
+	err = db.QueryRow(`
+		SELECT COUNT(*) > 0
+		FROM pragma_table_info('settings')
+		WHERE name = 'r4r5_rank_boost'
+	`).Scan(&rankBoostColumnExists)
+	if err != nil {
+		return err
+	}
 
-	// Check if username already exists, if so, append member ID
-	var existingUsername string
-	err = db.QueryRow("SELECT username FROM users WHERE username = ?", username).Scan(&existingUsername)
-	if err == nil {
-		username = username + strconv.Itoa(memberID)
+	if !rankBoostColumnExists {
+		_, err = db.Exec(`ALTER TABLE settings ADD COLUMN r4r5_rank_boost INTEGER NOT NULL DEFAULT 5`)
+		if err != nil {
+			return err
+		}
+		log.Println("Database migration: Added r4r5_rank_boost column to settings table")
 	}
 
-	// Insert user
-	_, err = db.Exec("INSERT INTO users (username, password, member_id, is_admin) VALUES (?, ?, ?, ?)",
-		username, string(hashedPassword), memberID, false)
+	// Migrate settings table to add schedule_message_template column if missing
+	var scheduleTemplateColumnExists bool
+	err = db.QueryRow(`
+		SELECT COUNT(*) > 0
+		FROM pragma_table_info('settings')
+		WHERE name = 'schedule_message_template'
+	`).Scan(&scheduleTemplateColumnExists)
 	if err != nil {
-		http.Error(w, "Failed to create user: "+err.Error(), http.StatusInternalServerError)
-		return
+		return err
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"message":  "User created successfully",
-		"username": username,
-		"password": randomPassword,
-	})
-}
+	if !scheduleTemplateColumnExists {
+		_, err = db.Exec(`ALTER TABLE settings ADD COLUMN schedule_message_template TEXT NOT NULL DEFAULT 'Train Schedule - Week {WEEK}
 
-// Check auth status
-func checkAuth(w http.ResponseWriter, r *http.Request) {
-	session, _ := store.Get(r, "session")
-	if auth, ok := session.Values["authenticated"].(bool); ok && auth {
-		username := session.Values["username"].(string)
-		isAdmin := false
-		if adminVal, ok := session.Values["is_admin"].(bool); ok {
-			isAdmin = adminVal
+{SCHEDULES}
+
+Next in line:
+{NEXT_3}'`)
+		if err != nil {
+			return err
 		}
+		log.Println("Database migration: Added schedule_message_template column to settings table")
+	}
 
-		var rank string
-		var canManageRanks bool
+	// Migrate settings table to add first_time_conductor_boost column if missing
+	var firstTimeBoostColumnExists bool
+	err = db.QueryRow(`
+		SELECT COUNT(*) > 0
+		FROM pragma_table_info('settings')
+		WHERE name = 'first_time_conductor_boost'
+	`).Scan(&firstTimeBoostColumnExists)
+	if err != nil {
+		return err
+	}
 
-		if isAdmin {
-			rank = "Admin"
-			canManageRanks = true
-		} else if memberID, ok := session.Values["member_id"].(int); ok {
-			// Get member's rank
-			err := db.QueryRow("SELECT rank FROM members WHERE id = ?", memberID).Scan(&rank)
-			if err == nil {
-				canManageRanks = (rank == "R4" || rank == "R5")
-			}
+	if !firstTimeBoostColumnExists {
+		_, err = db.Exec(`ALTER TABLE settings ADD COLUMN first_time_conductor_boost INTEGER NOT NULL DEFAULT 5`)
+		if err != nil {
+			return err
 		}
+		log.Println("Database migration: Added first_time_conductor_boost column to settings table")
+	}
 
-		// Check if user is R5 or admin (for more sensitive operations)
-		isR5OrAdmin := isAdmin
-		if !isR5OrAdmin && rank == "R5" {
-			isR5OrAdmin = true
-		}
+	// Migrate settings table to add daily_message_template column if missing
+	var dailyTemplateColumnExists bool
+	err = db.QueryRow(`
+		SELECT COUNT(*) > 0
+		FROM pragma_table_info('settings')
+		WHERE name = 'daily_message_template'
+	`).Scan(&dailyTemplateColumnExists)
+	if err != nil {
+		return err
+	}
 
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(map[string]interface{}{
-			"authenticated":    true,
-			"username":         username,
-			"rank":             rank,
-			"is_admin":         isAdmin,
-			"can_manage_ranks": canManageRanks,
-			"is_r5_or_admin":   isR5OrAdmin,
-		})
-	} else {
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(map[string]bool{"authenticated": false})
-	}
-}
+	if !dailyTemplateColumnExists {
+		defaultDailyTemplate := `ALL ABOARD! Daily Train Assignment
 
-// Admin: Get all users with login information
-func getAdminUsers(w http.ResponseWriter, r *http.Request) {
-	query := `
-		SELECT u.id, u.username, u.member_id, u.is_admin, 
-			   m.name as member_name,
-			   (SELECT login_time FROM login_sessions WHERE user_id = u.id AND success = 1 ORDER BY login_time DESC LIMIT 1) as last_login,
-			   (SELECT COUNT(*) FROM login_sessions WHERE user_id = u.id AND success = 1) as login_count
-		FROM users u
-		LEFT JOIN members m ON u.member_id = m.id
-		ORDER BY u.is_admin DESC, u.username ASC
-	`
+Date: {DATE}
 
-	rows, err := db.Query(query)
-	if err != nil {
-		http.Error(w, "Failed to fetch users", http.StatusInternalServerError)
-		return
-	}
-	defer rows.Close()
+Today's Conductor: {CONDUCTOR_NAME} ({CONDUCTOR_RANK})
+Backup Engineer: {BACKUP_NAME} ({BACKUP_RANK})
 
-	users := []AdminUserResponse{}
-	for rows.Next() {
-		var user AdminUserResponse
-		var memberID sql.NullInt64
-		var memberName sql.NullString
-		var lastLogin sql.NullString
+DEPARTURE SCHEDULE:
+- {CONDUCTOR_TIME} - Conductor {CONDUCTOR_NAME}, please request train assignment in alliance chat
+- {BACKUP_TIME} - If conductor hasn't shown up, Backup {BACKUP_NAME} takes over and assigns train to themselves
 
-		err := rows.Scan(&user.ID, &user.Username, &memberID, &user.IsAdmin,
-			&memberName, &lastLogin, &user.LoginCount)
-		if err != nil {
-			continue
-		}
+Remember: Communication is key! Let the alliance know if you can't make it.
 
-		if memberID.Valid {
-			mid := int(memberID.Int64)
-			user.MemberID = &mid
-		}
-		if memberName.Valid {
-			user.MemberName = &memberName.String
+All aboard for another successful run!`
+		// Add column without default first
+		_, err = db.Exec(`ALTER TABLE settings ADD COLUMN daily_message_template TEXT`)
+		if err != nil {
+			return err
 		}
-		if lastLogin.Valid {
-			user.LastLogin = &lastLogin.String
+		// Then update existing row with the default value
+		_, err = db.Exec(`UPDATE settings SET daily_message_template = ? WHERE id = 1`, defaultDailyTemplate)
+		if err != nil {
+			return err
 		}
+		log.Println("Database migration: Added daily_message_template column to settings table")
+	}
 
-		// Get recent logins (last 5)
-		loginRows, err := db.Query(`
-			SELECT id, user_id, username, ip_address, user_agent, country, city, isp, login_time, success
-			FROM login_sessions
-			WHERE user_id = ? AND success = 1
-			ORDER BY login_time DESC
-			LIMIT 5
-		`, user.ID)
-
-		if err == nil {
-			recentLogins := []LoginSession{}
-			for loginRows.Next() {
-				var login LoginSession
-				var ipAddr, userAgent, country, city, isp sql.NullString
-
-				loginRows.Scan(&login.ID, &login.UserID, &login.Username,
-					&ipAddr, &userAgent, &country, &city, &isp,
-					&login.LoginTime, &login.Success)
+	// Migrate settings table to add power_tracking_enabled column if missing
+	var powerTrackingColumnExists bool
+	err = db.QueryRow(`
+		SELECT COUNT(*) FROM pragma_table_info('settings') 
+		WHERE name='power_tracking_enabled'
+	`).Scan(&powerTrackingColumnExists)
+	if err != nil || !powerTrackingColumnExists {
+		_, err = db.Exec(`ALTER TABLE settings ADD COLUMN power_tracking_enabled BOOLEAN DEFAULT 0`)
+		if err != nil {
+			return err
+		}
+		log.Println("Database migration: Added power_tracking_enabled column to settings table")
+	}
 
-				if ipAddr.Valid {
-					login.IPAddress = &ipAddr.String
-				}
-				if userAgent.Valid {
-					login.UserAgent = &userAgent.String
-				}
-				if country.Valid {
-					login.Country = &country.String
-				}
-				if city.Valid {
-					login.City = &city.String
-				}
-				if isp.Valid {
-					login.ISP = &isp.String
-				}
+	// Migrate settings table to add VS points ranking columns if missing
+	for _, column := range []string{"vs_percentile_points", "vs_min_daily_points", "vs_consistency_bonus", "vs_zero_day_penalty"} {
+		var vsColumnExists bool
+		err = db.QueryRow(`
+			SELECT COUNT(*) > 0
+			FROM pragma_table_info('settings')
+			WHERE name = ?
+		`, column).Scan(&vsColumnExists)
+		if err != nil {
+			return err
+		}
 
-				recentLogins = append(recentLogins, login)
+		if !vsColumnExists {
+			_, err = db.Exec(`ALTER TABLE settings ADD COLUMN ` + column + ` INTEGER NOT NULL DEFAULT 0`)
+			if err != nil {
+				return err
 			}
-			loginRows.Close()
-			user.RecentLogins = recentLogins
+			log.Printf("Database migration: Added %s column to settings table", column)
 		}
-
-		users = append(users, user)
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(users)
-}
-
-// Admin: Create new user
-func createAdminUser(w http.ResponseWriter, r *http.Request) {
-	var req AdminUserRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
-		return
+	// Migrate settings table to add vs_warning_message_template column if missing
+	var vsWarningTemplateColumnExists bool
+	err = db.QueryRow(`
+		SELECT COUNT(*) > 0
+		FROM pragma_table_info('settings')
+		WHERE name = 'vs_warning_message_template'
+	`).Scan(&vsWarningTemplateColumnExists)
+	if err != nil {
+		return err
 	}
 
-	if req.Username == "" || req.Password == "" {
-		http.Error(w, "Username and password are required", http.StatusBadRequest)
-		return
-	}
+	if !vsWarningTemplateColumnExists {
+		defaultVSWarningTemplate := `VS Minimum Check - Week {WEEK}
 
-	if len(req.Password) < 6 {
-		http.Error(w, "Password must be at least 6 characters", http.StatusBadRequest)
-		return
-	}
+Daily minimums:
+{REQUIREMENTS}
 
-	// Check if username already exists
-	var existingID int
-	err := db.QueryRow("SELECT id FROM users WHERE username = ?", req.Username).Scan(&existingID)
-	if err == nil {
-		http.Error(w, "Username already exists", http.StatusConflict)
-		return
-	}
+{COUNT} members missed the minimum:
+{MEMBERS}
 
-	// Hash password
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
-	if err != nil {
-		http.Error(w, "Failed to hash password", http.StatusInternalServerError)
-		return
+Every point counts for the alliance - please hit the minimum every day!`
+		_, err = db.Exec(`ALTER TABLE settings ADD COLUMN vs_warning_message_template TEXT NOT NULL DEFAULT ''`)
+		if err != nil {
+			return err
+		}
+		_, err = db.Exec(`UPDATE settings SET vs_warning_message_template = ? WHERE id = 1`, defaultVSWarningTemplate)
+		if err != nil {
+			return err
+		}
+		log.Println("Database migration: Added vs_warning_message_template column to settings table")
 	}
 
-	// Insert user
-	result, err := db.Exec("INSERT INTO users (username, password, member_id, is_admin) VALUES (?, ?, ?, ?)",
-		req.Username, string(hashedPassword), req.MemberID, req.IsAdmin)
+	// Migrate settings table to add ocr_min_confidence column if missing
+	var ocrMinConfidenceColumnExists bool
+	err = db.QueryRow(`
+		SELECT COUNT(*) > 0
+		FROM pragma_table_info('settings')
+		WHERE name = 'ocr_min_confidence'
+	`).Scan(&ocrMinConfidenceColumnExists)
 	if err != nil {
-		http.Error(w, "Failed to create user: "+err.Error(), http.StatusInternalServerError)
-		return
+		return err
 	}
 
-	id, _ := result.LastInsertId()
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"message": "User created successfully",
-		"id":      id,
-	})
-}
+	if !ocrMinConfidenceColumnExists {
+		_, err = db.Exec(`ALTER TABLE settings ADD COLUMN ocr_min_confidence INTEGER NOT NULL DEFAULT 0`)
+		if err != nil {
+			return err
+		}
+		log.Println("Database migration: Added ocr_min_confidence column to settings table")
+	}
 
-// Admin: Update user
-func updateAdminUser(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	userID, err := strconv.Atoi(vars["id"])
+	// Migrate settings table to add ocr_languages column if missing
+	var ocrLanguagesColumnExists bool
+	err = db.QueryRow(`
+		SELECT COUNT(*) > 0
+		FROM pragma_table_info('settings')
+		WHERE name = 'ocr_languages'
+	`).Scan(&ocrLanguagesColumnExists)
 	if err != nil {
-		http.Error(w, "Invalid user ID", http.StatusBadRequest)
-		return
+		return err
 	}
 
-	var req AdminUserRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
-		return
+	if !ocrLanguagesColumnExists {
+		_, err = db.Exec(`ALTER TABLE settings ADD COLUMN ocr_languages TEXT NOT NULL DEFAULT 'eng'`)
+		if err != nil {
+			return err
+		}
+		log.Println("Database migration: Added ocr_languages column to settings table")
 	}
 
-	// Check if user exists
-	var existingUsername string
-	err = db.QueryRow("SELECT username FROM users WHERE id = ?", userID).Scan(&existingUsername)
+	// Migrate settings table to add the Desert Storm message template columns if missing
+	var stormTemplateColumnsExist bool
+	err = db.QueryRow(`
+		SELECT COUNT(*) > 0
+		FROM pragma_table_info('settings')
+		WHERE name = 'storm_plan_message_template'
+	`).Scan(&stormTemplateColumnsExist)
 	if err != nil {
-		http.Error(w, "User not found", http.StatusNotFound)
-		return
+		return err
 	}
 
-	// Check if new username already exists (if username is being changed)
-	if req.Username != "" && req.Username != existingUsername {
-		var otherID int
-		err = db.QueryRow("SELECT id FROM users WHERE username = ? AND id != ?", req.Username, userID).Scan(&otherID)
-		if err == nil {
-			http.Error(w, "Username already exists", http.StatusConflict)
-			return
-		}
-	}
+	if !stormTemplateColumnsExist {
+		defaultStormPlanTemplate := `DESERT STORM - TASK FORCE {TASK_FORCE}
+{DATE}
 
-	// Build update query
-	if req.Username != "" {
-		_, err = db.Exec("UPDATE users SET username = ?, member_id = ?, is_admin = ? WHERE id = ?",
-			req.Username, req.MemberID, req.IsAdmin, userID)
-	} else {
-		_, err = db.Exec("UPDATE users SET member_id = ?, is_admin = ? WHERE id = ?",
-			req.MemberID, req.IsAdmin, userID)
-	}
+Building assignments:
+{ASSIGNMENTS}
 
-	if err != nil {
-		http.Error(w, "Failed to update user: "+err.Error(), http.StatusInternalServerError)
-		return
+Teleport to your building as soon as the battle starts. Good luck everyone!`
+		defaultStormMemberTemplate := `Hi {NAME}! You're in Desert Storm Task Force {TASK_FORCE} on {DATE}.
+{ASSIGNMENT}
+Please teleport to your building as soon as the battle starts. Thanks!`
+		for _, column := range []string{"storm_plan_message_template", "storm_member_message_template"} {
+			_, err = db.Exec(`ALTER TABLE settings ADD COLUMN ` + column + ` TEXT NOT NULL DEFAULT ''`)
+			if err != nil {
+				return err
+			}
+		}
+		_, err = db.Exec(`UPDATE settings SET storm_plan_message_template = ?, storm_member_message_template = ? WHERE id = 1`,
+			defaultStormPlanTemplate, defaultStormMemberTemplate)
+		if err != nil {
+			return err
+		}
+		log.Println("Database migration: Added storm message template columns to settings table")
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{"message": "User updated successfully"})
-}
-
-// Admin: Delete user
-func deleteAdminUser(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	userID, err := strconv.Atoi(vars["id"])
+	// Migrate settings table to add the game time columns if missing
+	var gameTimeColumnsExist bool
+	err = db.QueryRow(`
+		SELECT COUNT(*) > 0
+		FROM pragma_table_info('settings')
+		WHERE name = 'game_timezone'
+	`).Scan(&gameTimeColumnsExist)
 	if err != nil {
-		http.Error(w, "Invalid user ID", http.StatusBadRequest)
-		return
+		return err
 	}
 
-	// Check if user exists
-	var username string
-	err = db.QueryRow("SELECT username FROM users WHERE id = ?", userID).Scan(&username)
-	if err != nil {
-		http.Error(w, "User not found", http.StatusNotFound)
-		return
-	}
+	if !gameTimeColumnsExist {
+		// The columns and the train time rewrite below are added together, so the rewrite runs once
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+
+		for column, value := range map[string]string{
+			"game_timezone":        defaultGameTimezone,
+			"game_reset_time":      "00:00",
+			"train_conductor_time": "15:00",
+			"train_backup_time":    "16:30",
+			"display_timezones":    "Europe/London,Europe/Berlin",
+		} {
+			_, err = tx.Exec(`ALTER TABLE settings ADD COLUMN ` + column + ` TEXT NOT NULL DEFAULT '` + value + `'`)
+			if err != nil {
+				return err
+			}
+		}
 
-	// Prevent deleting the last admin
-	var adminCount int
-	err = db.QueryRow("SELECT COUNT(*) FROM users WHERE is_admin = 1").Scan(&adminCount)
-	if err == nil && adminCount <= 1 {
-		var isAdmin bool
-		db.QueryRow("SELECT is_admin FROM users WHERE id = ?", userID).Scan(&isAdmin)
-		if isAdmin {
-			http.Error(w, "Cannot delete the last admin user", http.StatusForbidden)
-			return
+		// Daily templates from before the game time settings have the old default train times written out.
+		// Switch those lines to the time placeholders, so the message follows the configured times and zones:
+		// in the settings column that is converted below, and as a new version of daily templates that were
+		// already converted.
+		legacyTrainTimes := []struct{ old, setting, template string }{
+			{"- 15:00 ST (17:00 UK) - Conductor", "- {CONDUCTOR_TIME} - Conductor", "- {{.ConductorTime}} - Conductor"},
+			{"- 16:30 ST (18:30 UK) - If conductor", "- {BACKUP_TIME} - If conductor", "- {{$.BackupTime}} - If conductor"},
+		}
+		for _, times := range legacyTrainTimes {
+			_, err = tx.Exec(`UPDATE settings SET daily_message_template = REPLACE(daily_message_template, ?, ?)
+				WHERE id = 1 AND instr(daily_message_template, ?) > 0`, times.old, times.setting, times.old)
+			if err != nil {
+				return err
+			}
 		}
-	}
 
-	// Delete user
-	_, err = db.Exec("DELETE FROM users WHERE id = ?", userID)
-	if err != nil {
-		http.Error(w, "Failed to delete user: "+err.Error(), http.StatusInternalServerError)
-		return
-	}
+		rows, err := tx.Query(`
+			SELECT t.name, t.version, t.body FROM message_templates t
+			WHERE t.kind = 'train_daily' AND t.version = (SELECT MAX(version) FROM message_templates WHERE name = t.name)
+		`)
+		if err != nil {
+			return err
+		}
+		var latest []MessageTemplate
+		for rows.Next() {
+			var template MessageTemplate
+			if err := rows.Scan(&template.Name, &template.Version, &template.Body); err != nil {
+				rows.Close()
+				return err
+			}
+			latest = append(latest, template)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{"message": "User deleted successfully"})
-}
+		for _, template := range latest {
+			body := template.Body
+			for _, times := range legacyTrainTimes {
+				body = strings.ReplaceAll(body, times.old, times.template)
+			}
+			if body == template.Body {
+				continue
+			}
+			_, err = tx.Exec(`INSERT INTO message_templates (name, kind, version, body) VALUES (?, 'train_daily', ?, ?)`,
+				template.Name, template.Version+1, body)
+			if err != nil {
+				return err
+			}
+			log.Printf("Database migration: Saved %s with the train time placeholders as version %d", template.Name, template.Version+1)
+		}
 
-// Admin: Reset user password
-func resetUserPassword(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	userID, err := strconv.Atoi(vars["id"])
-	if err != nil {
-		http.Error(w, "Invalid user ID", http.StatusBadRequest)
-		return
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+		log.Println("Database migration: Added game time columns to settings table")
 	}
 
-	// Check if user exists
-	var username string
-	err = db.QueryRow("SELECT username FROM users WHERE id = ?", userID).Scan(&username)
+	// Move the message templates out of settings into versioned templates. The old settings
+	// columns are left in place (the migrations above would add them again) but are no longer read.
+	// The copy is one transaction, so a failed start leaves the table empty and the copy is retried.
+	tx, err = db.Begin()
 	if err != nil {
-		http.Error(w, "User not found", http.StatusNotFound)
-		return
+		return err
 	}
+	defer tx.Rollback()
 
-	// Generate random password
-	randomPassword, err := generateRandomPassword(10)
+	var messageTemplateCount int
+	err = tx.QueryRow("SELECT COUNT(*) FROM message_templates").Scan(&messageTemplateCount)
 	if err != nil {
-		http.Error(w, "Failed to generate password", http.StatusInternalServerError)
-		return
+		return err
 	}
 
-	// Hash password
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(randomPassword), bcrypt.DefaultCost)
-	if err != nil {
-		http.Error(w, "Failed to hash password", http.StatusInternalServerError)
-		return
+	if messageTemplateCount == 0 {
+		legacy := make(map[string]string)
+		var scheduleTemplate, dailyTemplate, vsWarningTemplate, stormPlanTemplate, stormMemberTemplate sql.NullString
+		err = tx.QueryRow(`SELECT schedule_message_template, daily_message_template, vs_warning_message_template,
+			storm_plan_message_template, storm_member_message_template
+			FROM settings WHERE id = 1`).Scan(&scheduleTemplate, &dailyTemplate, &vsWarningTemplate, &stormPlanTemplate, &stormMemberTemplate)
+		if err != nil {
+			return err
+		}
+		legacy["train_weekly"] = scheduleTemplate.String
+		legacy["train_daily"] = dailyTemplate.String
+		legacy["vs_warning"] = vsWarningTemplate.String
+		legacy["storm_plan"] = stormPlanTemplate.String
+		legacy["storm_member"] = stormMemberTemplate.String
+
+		for kind, body := range legacy {
+			// A template that doesn't convert cleanly (e.g. one with a literal "{{") is replaced by the
+			// default, so the message keeps working
+			converted := legacyMessageTemplate(kind, body)
+			if _, err := validateMessageTemplate(kind, converted); body == "" || err != nil {
+				if body != "" {
+					log.Printf("Database migration: %s message template could not be converted (%v), using the default", kind, err)
+				}
+				converted = defaultMessageTemplates[kind]
+			}
+			_, err = tx.Exec(`INSERT INTO message_templates (name, kind, version, body) VALUES (?, ?, 1, ?)`,
+				kind, kind, converted)
+			if err != nil {
+				return err
+			}
+		}
+		for i, body := range defaultConductorReminders {
+			_, err = tx.Exec(`INSERT INTO message_templates (name, kind, version, body) VALUES (?, 'conductor_reminder', 1, ?)`,
+				fmt.Sprintf("conductor_reminder_%d", i+1), body)
+			if err != nil {
+				return err
+			}
+		}
+		log.Println("Database migration: Moved message templates from settings to message_templates table")
+	}
+	if err = tx.Commit(); err != nil {
+		return err
 	}
 
-	// Update password
-	_, err = db.Exec("UPDATE users SET password = ? WHERE id = ?", string(hashedPassword), userID)
+	// Create default admin user if no users exist
+	var userCount int
+	err = db.QueryRow("SELECT COUNT(*) FROM users").Scan(&userCount)
 	if err != nil {
-		http.Error(w, "Failed to reset password: "+err.Error(), http.StatusInternalServerError)
-		return
+		return err
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"message":  "Password reset successfully",
-		"username": username,
-		"password": randomPassword,
-	})
+	if userCount == 0 {
+		// Default credentials: admin/admin123
+		hashedPassword, err := bcrypt.GenerateFromPassword([]byte("admin123"), bcrypt.DefaultCost)
+		if err != nil {
+			return err
+		}
+		_, err = db.Exec("INSERT INTO users (username, password, is_admin) VALUES (?, ?, ?)", "admin", string(hashedPassword), true)
+		if err != nil {
+			return err
+		}
+		log.Println("Default admin user created - Username: admin, Password: admin123")
+	}
+
+	return nil
 }
 
-// Admin: Get login history
-func getLoginHistory(w http.ResponseWriter, r *http.Request) {
-	// Get optional filters from query params
-	userIDParam := r.URL.Query().Get("user_id")
-	limit := r.URL.Query().Get("limit")
-	if limit == "" {
-		limit = "100"
+// Authentication middleware
+func authMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		session, _ := store.Get(r, "session")
+		if auth, ok := session.Values["authenticated"].(bool); !ok || !auth {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
 	}
+}
 
-	query := `
-		SELECT ls.id, ls.user_id, ls.username, ls.ip_address, ls.user_agent, 
-		       ls.country, ls.city, ls.isp, ls.login_time, ls.success
-		FROM login_sessions ls
-	`
+// Permission middleware - only R4/R5 or admin can manage ranks
+func rankManagementMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		session, _ := store.Get(r, "session")
 
-	if userIDParam != "" {
-		query += " WHERE ls.user_id = " + userIDParam
-	}
+		// Check if admin
+		if isAdmin, ok := session.Values["is_admin"].(bool); ok && isAdmin {
+			next(w, r)
+			return
+		}
 
-	query += " ORDER BY ls.login_time DESC LIMIT " + limit
+		// Check if member has R4 or R5 rank
+		if memberID, ok := session.Values["member_id"].(int); ok {
+			var rank string
+			err := db.QueryRow("SELECT rank FROM members WHERE id = ?", memberID).Scan(&rank)
+			if err == nil && (rank == "R4" || rank == "R5") {
+				next(w, r)
+				return
+			}
+		}
 
-	rows, err := db.Query(query)
-	if err != nil {
-		http.Error(w, "Failed to fetch login history", http.StatusInternalServerError)
-		return
+		http.Error(w, "Forbidden: Only R4/R5 members can manage ranks", http.StatusForbidden)
 	}
-	defer rows.Close()
+}
 
-	history := []LoginSession{}
-	for rows.Next() {
-		var login LoginSession
-		var ipAddr, userAgent, country, city, isp sql.NullString
+// Permission middleware - only R5 or admin
+func adminR5Middleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		session, _ := store.Get(r, "session")
 
-		err := rows.Scan(&login.ID, &login.UserID, &login.Username,
-			&ipAddr, &userAgent, &country, &city, &isp,
-			&login.LoginTime, &login.Success)
-		if err != nil {
-			continue
+		// Check if admin
+		if isAdmin, ok := session.Values["is_admin"].(bool); ok && isAdmin {
+			next(w, r)
+			return
 		}
 
-		if ipAddr.Valid {
-			login.IPAddress = &ipAddr.String
-		}
-		if userAgent.Valid {
-			login.UserAgent = &userAgent.String
-		}
-		if country.Valid {
-			login.Country = &country.String
-		}
-		if city.Valid {
-			login.City = &city.String
-		}
-		if isp.Valid {
-			login.ISP = &isp.String
+		// Check if member has R5 rank
+		if memberID, ok := session.Values["member_id"].(int); ok {
+			var rank string
+			err := db.QueryRow("SELECT rank FROM members WHERE id = ?", memberID).Scan(&rank)
+			if err == nil && rank == "R5" {
+				next(w, r)
+				return
+			}
 		}
 
-		history = append(history, login)
+		http.Error(w, "Forbidden: Only R5 members and admins can perform this action", http.StatusForbidden)
 	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(history)
 }
 
-// Get all members
-func getMembers(w http.ResponseWriter, r *http.Request) {
-	query := `
-		SELECT m.id, m.name, m.rank, COALESCE(m.eligible, 1),
-		       (SELECT ph.power 
-		        FROM power_history ph 
-		        WHERE ph.member_id = m.id 
-		        ORDER BY ph.recorded_at DESC 
-		        LIMIT 1) as latest_power
-		FROM members m
-		ORDER BY m.name
-	`
-	rows, err := db.Query(query)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-	defer rows.Close()
+// Admin-only middleware
+func adminMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		session, _ := store.Get(r, "session")
 
-	members := []Member{}
-	for rows.Next() {
-		var m Member
-		if err := rows.Scan(&m.ID, &m.Name, &m.Rank, &m.Eligible, &m.Power); err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+		if isAdmin, ok := session.Values["is_admin"].(bool); !ok || !isAdmin {
+			http.Error(w, "Forbidden: Admin access required", http.StatusForbidden)
 			return
 		}
-		members = append(members, m)
-	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(members)
+		next(w, r)
+	}
 }
 
-// Get member statistics for train scheduling
-func getMemberStats(w http.ResponseWriter, r *http.Request) {
-	rows, err := db.Query(`
-		SELECT 
-			m.id, 
-			m.name, 
-			m.rank,
-			COUNT(DISTINCT CASE WHEN ts.conductor_id = m.id THEN ts.date END) as conductor_count,
-			MAX(CASE WHEN ts.conductor_id = m.id THEN ts.date END) as last_conductor_date,
-			COUNT(DISTINCT CASE WHEN ts.backup_id = m.id THEN ts.date END) as backup_count,
-			COUNT(DISTINCT CASE WHEN ts.backup_id = m.id AND ts.conductor_showed_up = 0 THEN ts.date END) as backup_used_count,
-			COUNT(DISTINCT CASE WHEN ts.conductor_id = m.id AND ts.conductor_showed_up = 0 THEN ts.date END) as conductor_no_show_count
-		FROM members m
-		LEFT JOIN train_schedules ts ON ts.conductor_id = m.id OR ts.backup_id = m.id
-		GROUP BY m.id, m.name, m.rank
-		ORDER BY m.name
-	`)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+// Login handler
+func login(w http.ResponseWriter, r *http.Request) {
+	var creds Credentials
+	if err := json.NewDecoder(r.Body).Decode(&creds); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	defer rows.Close()
-
-	stats := []MemberStats{}
-	for rows.Next() {
-		var s MemberStats
-		var lastDate sql.NullString
-		if err := rows.Scan(&s.ID, &s.Name, &s.Rank, &s.ConductorCount, &lastDate, &s.BackupCount, &s.BackupUsedCount, &s.ConductorNoShowCount); err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-		if lastDate.Valid {
-			s.LastConductorDate = &lastDate.String
-		}
-		stats = append(stats, s)
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(stats)
-}
 
-// Create a new member
-func createMember(w http.ResponseWriter, r *http.Request) {
-	var m Member
-	if err := json.NewDecoder(r.Body).Decode(&m); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	var user User
+	var memberID sql.NullInt64
+	var isAdmin sql.NullBool
+	err := db.QueryRow("SELECT id, username, password, member_id, is_admin FROM users WHERE username = ?", creds.Username).Scan(&user.ID, &user.Username, &user.Password, &memberID, &isAdmin)
+	if err != nil {
+		// Track failed login attempt
+		trackLogin(0, creds.Username, r, false)
+		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
 		return
 	}
 
-	// Default to eligible if not specified
-	if !m.Eligible {
-		m.Eligible = true
+	if memberID.Valid {
+		mid := int(memberID.Int64)
+		user.MemberID = &mid
 	}
+	user.IsAdmin = isAdmin.Valid && isAdmin.Bool
 
-	result, err := db.Exec("INSERT INTO members (name, rank, eligible) VALUES (?, ?, ?)", m.Name, m.Rank, m.Eligible)
+	// Compare password
+	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(creds.Password))
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		// Track failed login attempt
+		trackLogin(user.ID, user.Username, r, false)
+		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
 		return
 	}
 
-	id, _ := result.LastInsertId()
-	m.ID = int(id)
+	// Track successful login
+	trackLogin(user.ID, user.Username, r, true)
+
+	// Create session
+	session, _ := store.Get(r, "session")
+	session.Values["authenticated"] = true
+	session.Values["username"] = user.Username
+	session.Values["user_id"] = user.ID
+	if user.MemberID != nil {
+		session.Values["member_id"] = *user.MemberID
+	}
+	session.Values["is_admin"] = user.IsAdmin
+	session.Save(r, w)
 
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(m)
+	json.NewEncoder(w).Encode(map[string]interface{}{"message": "Login successful", "username": user.Username})
 }
 
-// Update a member
-func updateMember(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	id, err := strconv.Atoi(vars["id"])
-	if err != nil {
-		http.Error(w, "Invalid member ID", http.StatusBadRequest)
+// Logout handler
+func logout(w http.ResponseWriter, r *http.Request) {
+	session, _ := store.Get(r, "session")
+	session.Values["authenticated"] = false
+	session.Options.MaxAge = -1
+	session.Save(r, w)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Logout successful"})
+}
+
+// Change password handler
+func changePassword(w http.ResponseWriter, r *http.Request) {
+	session, _ := store.Get(r, "session")
+	userID, ok := session.Values["user_id"].(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	var m Member
-	if err := json.NewDecoder(r.Body).Decode(&m); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
+	var input struct {
+		CurrentPassword string `json:"current_password"`
+		NewPassword     string `json:"new_password"`
 	}
 
-	_, err = db.Exec("UPDATE members SET name = ?, rank = ?, eligible = ? WHERE id = ?", m.Name, m.Rank, m.Eligible, id)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
-	m.ID = id
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(m)
-}
+	if len(input.NewPassword) < 6 {
+		http.Error(w, "New password must be at least 6 characters", http.StatusBadRequest)
+		return
+	}
 
-// Delete a member
-func deleteMember(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	id, err := strconv.Atoi(vars["id"])
+	// Get current password hash
+	var currentHash string
+	err := db.QueryRow("SELECT password FROM users WHERE id = ?", userID).Scan(&currentHash)
 	if err != nil {
-		http.Error(w, "Invalid member ID", http.StatusBadRequest)
+		http.Error(w, "User not found", http.StatusNotFound)
 		return
 	}
 
-	_, err = db.Exec("DELETE FROM members WHERE id = ?", id)
+	// Verify current password
+	err = bcrypt.CompareHashAndPassword([]byte(currentHash), []byte(input.CurrentPassword))
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, "Current password is incorrect", http.StatusUnauthorized)
 		return
 	}
 
-	w.WriteHeader(http.StatusNoContent)
-}
-
-// Get train schedules (optionally filtered by date range)
-func getTrainSchedules(w http.ResponseWriter, r *http.Request) {
-	startDate := r.URL.Query().Get("start")
-	endDate := r.URL.Query().Get("end")
-
-	query := `
-		SELECT 
-			ts.id, ts.date, ts.conductor_id, m1.name as conductor_name,
-			ts.conductor_score, ts.backup_id, m2.name as backup_name, m2.rank as backup_rank,
-			ts.conductor_showed_up, ts.notes, ts.created_at
-		FROM train_schedules ts
-		JOIN members m1 ON ts.conductor_id = m1.id
-		JOIN members m2 ON ts.backup_id = m2.id
-	`
-
-	var rows *sql.Rows
-	var err error
-
-	if startDate != "" && endDate != "" {
-		query += " WHERE ts.date BETWEEN ? AND ? ORDER BY ts.date, ts.conductor_score DESC"
-		rows, err = db.Query(query, startDate, endDate)
-	} else {
-		query += " ORDER BY ts.date, ts.conductor_score DESC"
-		rows, err = db.Query(query)
+	// Hash new password
+	newHash, err := bcrypt.GenerateFromPassword([]byte(input.NewPassword), bcrypt.DefaultCost)
+	if err != nil {
+		http.Error(w, "Failed to hash password", http.StatusInternalServerError)
+		return
 	}
 
+	// Update password
+	_, err = db.Exec("UPDATE users SET password = ? WHERE id = ?", string(newHash), userID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, "Failed to update password", http.StatusInternalServerError)
 		return
 	}
-	defer rows.Close()
-
-	schedules := []TrainSchedule{}
-	for rows.Next() {
-		var ts TrainSchedule
-		var showedUp sql.NullBool
-		var notes sql.NullString
-		var score sql.NullInt64
 
-		if err := rows.Scan(&ts.ID, &ts.Date, &ts.ConductorID, &ts.ConductorName,
-			&score, &ts.BackupID, &ts.BackupName, &ts.BackupRank, &showedUp, &notes, &ts.CreatedAt); err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Password changed successfully"})
+}
 
-		if showedUp.Valid {
-			ts.ConductorShowedUp = &showedUp.Bool
-		}
-		if notes.Valid {
-			ts.Notes = &notes.String
-		}
-		if score.Valid {
-			scoreInt := int(score.Int64)
-			ts.ConductorScore = &scoreInt
+// Generate random alphanumeric password
+func generateRandomPassword(length int) (string, error) {
+	const charset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+	password := make([]byte, length)
+	for i := range password {
+		num, err := rand.Int(rand.Reader, big.NewInt(int64(len(charset))))
+		if err != nil {
+			return "", err
 		}
-
-		schedules = append(schedules, ts)
+		password[i] = charset[num.Int64()]
 	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(schedules)
+	return string(password), nil
 }
 
-// Create a train schedule
-func createTrainSchedule(w http.ResponseWriter, r *http.Request) {
-	var ts TrainSchedule
-	if err := json.NewDecoder(r.Body).Decode(&ts); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
+// Get client IP with X-Forwarded-For and X-Real-IP support
+func getClientIP(r *http.Request) string {
+	// Check X-Forwarded-For header (proxy/load balancer)
+	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
+		ips := strings.Split(xff, ",")
+		return strings.TrimSpace(ips[0])
 	}
 
-	// Validate backup is R4 or R5
-	var backupRank string
-	err := db.QueryRow("SELECT rank FROM members WHERE id = ?", ts.BackupID).Scan(&backupRank)
-	if err != nil {
-		http.Error(w, "Backup member not found", http.StatusBadRequest)
-		return
+	// Check X-Real-IP header (nginx)
+	if xri := r.Header.Get("X-Real-IP"); xri != "" {
+		return xri
 	}
 
-	if backupRank != "R4" && backupRank != "R5" {
-		http.Error(w, "Backup must be an R4 or R5 member", http.StatusBadRequest)
-		return
+	// Fall back to RemoteAddr
+	ip := r.RemoteAddr
+	if colonIndex := strings.LastIndex(ip, ":"); colonIndex != -1 {
+		ip = ip[:colonIndex]
 	}
+	return ip
+}
 
-	// Use INSERT OR REPLACE to allow updating schedules created by auto-schedule
-	result, err := db.Exec(
-		"INSERT OR REPLACE INTO train_schedules (date, conductor_id, backup_id, conductor_score, conductor_showed_up, notes) VALUES (?, ?, ?, ?, ?, ?)",
-		ts.Date, ts.ConductorID, ts.BackupID, ts.ConductorScore, ts.ConductorShowedUp, ts.Notes)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+// Get IP geolocation information using ip-api.com (free, no key required)
+func getIPGeolocation(ip string) (*IPGeolocation, error) {
+	// Skip localhost/private IPs
+	if ip == "127.0.0.1" || ip == "::1" || strings.HasPrefix(ip, "192.168.") || strings.HasPrefix(ip, "10.") {
+		return &IPGeolocation{
+			Status:  "success",
+			Country: "Local Network",
+			City:    "Localhost",
+			ISP:     "Private Network",
+			Query:   ip,
+		}, nil
 	}
 
-	// Awards and recommendations automatically become inactive via on-the-fly calculation
-
-	id, _ := result.LastInsertId()
-	ts.ID = int(id)
-
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(ts)
-}
+	url := fmt.Sprintf("http://ip-api.com/json/%s?fields=status,country,countryCode,region,regionName,city,zip,lat,lon,timezone,isp,org,as,query", ip)
 
-// Update a train schedule
-func updateTrainSchedule(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	id, err := strconv.Atoi(vars["id"])
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Get(url)
 	if err != nil {
-		http.Error(w, "Invalid schedule ID", http.StatusBadRequest)
-		return
+		return nil, err
 	}
+	defer resp.Body.Close()
 
-	var ts TrainSchedule
-	if err := json.NewDecoder(r.Body).Decode(&ts); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
+	var geo IPGeolocation
+	if err := json.NewDecoder(resp.Body).Decode(&geo); err != nil {
+		return nil, err
 	}
 
-	// Get existing schedule to check if conductor or backup changed
-	var existingConductorID, existingBackupID int
-	err = db.QueryRow("SELECT conductor_id, backup_id FROM train_schedules WHERE id = ?", id).Scan(&existingConductorID, &existingBackupID)
-	if err != nil {
-		http.Error(w, "Schedule not found", http.StatusNotFound)
-		return
+	if geo.Status != "success" {
+		return nil, fmt.Errorf("geolocation lookup failed")
 	}
 
-	// Validate backup is R4 or R5 if backup is being updated
-	if ts.BackupID > 0 {
-		var backupRank string
-		err := db.QueryRow("SELECT rank FROM members WHERE id = ?", ts.BackupID).Scan(&backupRank)
-		if err != nil {
-			http.Error(w, "Backup member not found", http.StatusBadRequest)
-			return
-		}
+	return &geo, nil
+}
 
-		if backupRank != "R4" && backupRank != "R5" {
-			http.Error(w, "Backup must be an R4 or R5 member", http.StatusBadRequest)
-			return
-		}
-	}
+// Track login attempt in database
+func trackLogin(userID int, username string, r *http.Request, success bool) {
+	ip := getClientIP(r)
+	userAgent := r.Header.Get("User-Agent")
 
-	_, err = db.Exec(
-		"UPDATE train_schedules SET date = ?, conductor_id = ?, backup_id = ?, conductor_score = ?, conductor_showed_up = ?, notes = ? WHERE id = ?",
-		ts.Date, ts.ConductorID, ts.BackupID, ts.ConductorScore, ts.ConductorShowedUp, ts.Notes, id)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+	var country, city, isp *string
+
+	// Get geolocation data (non-blocking, log errors but don't fail)
+	if geo, err := getIPGeolocation(ip); err == nil {
+		country = &geo.Country
+		city = &geo.City
+		isp = &geo.ISP
 	}
 
-	// Awards and recommendations automatically become inactive via on-the-fly calculation
+	_, err := db.Exec(`INSERT INTO login_sessions (user_id, username, ip_address, user_agent, country, city, isp, success) 
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		userID, username, ip, userAgent, country, city, isp, success)
 
-	ts.ID = id
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(ts)
+	if err != nil {
+		log.Printf("Failed to track login: %v", err)
+	}
 }
 
-// Delete a train schedule
-func deleteTrainSchedule(w http.ResponseWriter, r *http.Request) {
+// Create user for member
+func createUserForMember(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
-	id, err := strconv.Atoi(vars["id"])
+	memberID, err := strconv.Atoi(vars["id"])
 	if err != nil {
-		http.Error(w, "Invalid schedule ID", http.StatusBadRequest)
+		http.Error(w, "Invalid member ID", http.StatusBadRequest)
 		return
 	}
 
-	_, err = db.Exec("DELETE FROM train_schedules WHERE id = ?", id)
+	// Check if member exists
+	var memberName string
+	err = db.QueryRow("SELECT name FROM members WHERE id = ?", memberID).Scan(&memberName)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, "Member not found", http.StatusNotFound)
 		return
 	}
 
-	w.WriteHeader(http.StatusNoContent)
-}
-
-// Auto-schedule train conductors and backups for a single day
-func autoSchedule(w http.ResponseWriter, r *http.Request) {
-	var input struct {
-		StartDate string `json:"start_date"`
-	}
-
-	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+	// Check if user already exists for this member
+	var existingUserID int
+	err = db.QueryRow("SELECT id FROM users WHERE member_id = ?", memberID).Scan(&existingUserID)
+	if err == nil {
+		http.Error(w, "User already exists for this member", http.StatusConflict)
 		return
 	}
 
-	// Parse the date and get the week start (Monday)
-	scheduleDate, err := parseDate(input.StartDate)
+	// Generate random password
+	randomPassword, err := generateRandomPassword(10)
 	if err != nil {
-		http.Error(w, "Invalid date format", http.StatusBadRequest)
+		http.Error(w, "Failed to generate password", http.StatusInternalServerError)
 		return
 	}
 
-	weekStart := getMondayOfWeek(scheduleDate)
-
-	// Build ranking context
-	ctx, err := buildRankingContext(weekStart)
+	// Hash password
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(randomPassword), bcrypt.DefaultCost)
 	if err != nil {
-		http.Error(w, "Failed to load ranking context: "+err.Error(), http.StatusInternalServerError)
+		http.Error(w, "Failed to hash password", http.StatusInternalServerError)
 		return
 	}
 
-	// Get all eligible members
-	rows, err := db.Query("SELECT id, name, rank, COALESCE(eligible, 1) FROM members WHERE COALESCE(eligible, 1) = 1 ORDER BY name")
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-	defer rows.Close()
+	// Create username from member name (lowercase, no spaces)
+	username := strings.ToLower(strings.ReplaceAll(memberName, " ", ""))
 
-	var candidates []Member
-	for rows.Next() {
-		var m Member
-		if err := rows.Scan(&m.ID, &m.Name, &m.Rank, &m.Eligible); err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-		candidates = append(candidates, m)
+	// Check if username already exists, if so, append member ID
+	var existingUsername string
+	err = db.QueryRow("SELECT username FROM users WHERE username = ?", username).Scan(&existingUsername)
+	if err == nil {
+		username = username + strconv.Itoa(memberID)
 	}
 
-	if len(candidates) < 7 {
-		http.Error(w, "Not enough members for weekly scheduling (need at least 7)", http.StatusBadRequest)
+	// Insert user
+	_, err = db.Exec("INSERT INTO users (username, password, member_id, is_admin) VALUES (?, ?, ?, ?)",
+		username, string(hashedPassword), memberID, false)
+	if err != nil {
+		http.Error(w, "Failed to create user: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	// Score each candidate using the abstracted ranking system
-	type ScoredMember struct {
-		Member Member
-		Score  int
-	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":  "User created successfully",
+		"username": username,
+		"password": randomPassword,
+	})
+}
 
-	var scoredCandidates []ScoredMember
-	for _, member := range candidates {
-		score := calculateMemberScore(member, ctx)
-		scoredCandidates = append(scoredCandidates, ScoredMember{
-			Member: member,
-			Score:  score,
-		})
-	}
+// Check auth status
+func checkAuth(w http.ResponseWriter, r *http.Request) {
+	session, _ := store.Get(r, "session")
+	if auth, ok := session.Values["authenticated"].(bool); ok && auth {
+		username := session.Values["username"].(string)
+		isAdmin := false
+		if adminVal, ok := session.Values["is_admin"].(bool); ok {
+			isAdmin = adminVal
+		}
 
-	// Sort by score (highest first)
-	for i := 0; i < len(scoredCandidates); i++ {
-		for j := i + 1; j < len(scoredCandidates); j++ {
-			if scoredCandidates[j].Score > scoredCandidates[i].Score {
-				scoredCandidates[i], scoredCandidates[j] = scoredCandidates[j], scoredCandidates[i]
+		var rank string
+		var timezone *string
+		var canManageRanks bool
+
+		if isAdmin {
+			rank = "Admin"
+			canManageRanks = true
+		} else if memberID, ok := session.Values["member_id"].(int); ok {
+			// Get member's rank
+			err := db.QueryRow("SELECT rank, timezone FROM members WHERE id = ?", memberID).Scan(&rank, &timezone)
+			if err == nil {
+				canManageRanks = (rank == "R4" || rank == "R5")
 			}
 		}
-	}
 
-	// Pre-select top 7 performers as conductors for the week
-	plannedConductors := make(map[int]bool)
-	for i := 0; i < 7 && i < len(scoredCandidates); i++ {
-		plannedConductors[scoredCandidates[i].Member.ID] = true
+		// Check if user is R5 or admin (for more sensitive operations)
+		isR5OrAdmin := isAdmin
+		if !isR5OrAdmin && rank == "R5" {
+			isR5OrAdmin = true
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"authenticated":    true,
+			"username":         username,
+			"rank":             rank,
+			"is_admin":         isAdmin,
+			"can_manage_ranks": canManageRanks,
+			"is_r5_or_admin":   isR5OrAdmin,
+			"timezone":         timezone,
+			"game_today":       formatDateString(gameToday()),
+		})
+	} else {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]bool{"authenticated": false})
 	}
+}
 
-	// Now schedule each day
-	var weekSchedules []TrainSchedule
-	usedConductors := make(map[int]bool)
-	usedBackups := make(map[int]bool)
+// Admin: Get all users with login information
+func getAdminUsers(w http.ResponseWriter, r *http.Request) {
+	query := `
+		SELECT u.id, u.username, u.member_id, u.is_admin, 
+			   m.name as member_name,
+			   (SELECT login_time FROM login_sessions WHERE user_id = u.id AND success = 1 ORDER BY login_time DESC LIMIT 1) as last_login,
+			   (SELECT COUNT(*) FROM login_sessions WHERE user_id = u.id AND success = 1) as login_count
+		FROM users u
+		LEFT JOIN members m ON u.member_id = m.id
+		ORDER BY u.is_admin DESC, u.username ASC
+	`
 
-	for day := 0; day < 7; day++ {
-		currentDate := weekStart.AddDate(0, 0, day)
-		dateStr := formatDateString(currentDate)
+	rows, err := db.Query(query)
+	if err != nil {
+		http.Error(w, "Failed to fetch users", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
 
-		// Select conductor from top 7 who hasn't been assigned yet
-		var conductorID int
-		var conductorScore int
+	users := []AdminUserResponse{}
+	for rows.Next() {
+		var user AdminUserResponse
+		var memberID sql.NullInt64
+		var memberName sql.NullString
+		var lastLogin sql.NullString
 
-		for _, sc := range scoredCandidates {
-			if plannedConductors[sc.Member.ID] && !usedConductors[sc.Member.ID] {
-				conductorID = sc.Member.ID
-				conductorScore = sc.Score
-				usedConductors[sc.Member.ID] = true
-				break
-			}
+		err := rows.Scan(&user.ID, &user.Username, &memberID, &user.IsAdmin,
+			&memberName, &lastLogin, &user.LoginCount)
+		if err != nil {
+			continue
 		}
 
-		if conductorID == 0 {
-			http.Error(w, "Unable to assign conductor for all days", http.StatusInternalServerError)
-			return
+		if memberID.Valid {
+			mid := int(memberID.Int64)
+			user.MemberID = &mid
+		}
+		if memberName.Valid {
+			user.MemberName = &memberName.String
+		}
+		if lastLogin.Valid {
+			user.LastLogin = &lastLogin.String
 		}
 
-		// Select backup (must be R4/R5, not a planned conductor, not already used as backup)
-		var availableBackups []Member
-		for _, sc := range scoredCandidates {
-			if !plannedConductors[sc.Member.ID] &&
-				!usedBackups[sc.Member.ID] &&
-				(sc.Member.Rank == "R4" || sc.Member.Rank == "R5") {
-				availableBackups = append(availableBackups, sc.Member)
-			}
-		}
-
-		var backupID int
-		if len(availableBackups) > 0 {
-			// Randomly select from available backups
-			randomIndex := time.Now().UnixNano() % int64(len(availableBackups))
-			backupID = availableBackups[randomIndex].ID
-			usedBackups[backupID] = true
-		}
-
-		// If backupID is 0, no backup available - continue anyway and allow manual assignment
-
-		// Insert schedule for this day
-		var result sql.Result
-		if backupID > 0 {
-			result, err = db.Exec(
-				"INSERT OR REPLACE INTO train_schedules (date, conductor_id, backup_id, conductor_score) VALUES (?, ?, ?, ?)",
-				dateStr, conductorID, backupID, conductorScore,
-			)
-		} else {
-			result, err = db.Exec(
-				"INSERT OR REPLACE INTO train_schedules (date, conductor_id, backup_id, conductor_score) VALUES (?, ?, NULL, ?)",
-				dateStr, conductorID, conductorScore,
-			)
-		}
-		if err != nil {
-			http.Error(w, "Failed to create schedule: "+err.Error(), http.StatusInternalServerError)
-			return
-		}
-
-		scheduleID, _ := result.LastInsertId()
+		// Get recent logins (last 5)
+		loginRows, err := db.Query(`
+			SELECT id, user_id, username, ip_address, user_agent, country, city, isp, login_time, success
+			FROM login_sessions
+			WHERE user_id = ? AND success = 1
+			ORDER BY login_time DESC
+			LIMIT 5
+		`, user.ID)
 
-		// Get the full schedule details
-		var schedule TrainSchedule
-		var score sql.NullInt64
-		var backupName sql.NullString
-		var backupRank sql.NullString
+		if err == nil {
+			recentLogins := []LoginSession{}
+			for loginRows.Next() {
+				var login LoginSession
+				var ipAddr, userAgent, country, city, isp sql.NullString
 
-		if backupID > 0 {
-			err = db.QueryRow(`
-			SELECT 
-				ts.id, ts.date, ts.conductor_id, 
-				mc.name, ts.conductor_score, ts.backup_id, mb.name, mb.rank,
-				ts.conductor_showed_up, ts.notes, ts.created_at
-			FROM train_schedules ts
-			JOIN members mc ON ts.conductor_id = mc.id
-			LEFT JOIN members mb ON ts.backup_id = mb.id
-			WHERE ts.id = ?
-		`, scheduleID).Scan(
-				&schedule.ID, &schedule.Date, &schedule.ConductorID,
-				&schedule.ConductorName, &score, &schedule.BackupID, &backupName,
-				&backupRank, &schedule.ConductorShowedUp, &schedule.Notes,
-				&schedule.CreatedAt,
-			)
-		} else {
-			err = db.QueryRow(`
-			SELECT 
-				ts.id, ts.date, ts.conductor_id, 
-				mc.name, ts.conductor_score,
-				ts.conductor_showed_up, ts.notes, ts.created_at
-			FROM train_schedules ts
-			JOIN members mc ON ts.conductor_id = mc.id
-			WHERE ts.id = ?
-		`, scheduleID).Scan(
-				&schedule.ID, &schedule.Date, &schedule.ConductorID,
-				&schedule.ConductorName, &score,
-				&schedule.ConductorShowedUp, &schedule.Notes,
-				&schedule.CreatedAt,
-			)
-			// Set backup fields to empty/zero
-			schedule.BackupID = 0
-			schedule.BackupName = ""
-			schedule.BackupRank = ""
-		}
+				loginRows.Scan(&login.ID, &login.UserID, &login.Username,
+					&ipAddr, &userAgent, &country, &city, &isp,
+					&login.LoginTime, &login.Success)
 
-		if err != nil {
-			http.Error(w, "Failed to retrieve schedule: "+err.Error(), http.StatusInternalServerError)
-			return
-		}
+				if ipAddr.Valid {
+					login.IPAddress = &ipAddr.String
+				}
+				if userAgent.Valid {
+					login.UserAgent = &userAgent.String
+				}
+				if country.Valid {
+					login.Country = &country.String
+				}
+				if city.Valid {
+					login.City = &city.String
+				}
+				if isp.Valid {
+					login.ISP = &isp.String
+				}
 
-		if backupID > 0 {
-			if backupName.Valid {
-				schedule.BackupName = backupName.String
-			}
-			if backupRank.Valid {
-				schedule.BackupRank = backupRank.String
+				recentLogins = append(recentLogins, login)
 			}
+			loginRows.Close()
+			user.RecentLogins = recentLogins
 		}
 
-		if score.Valid {
-			scoreInt := int(score.Int64)
-			schedule.ConductorScore = &scoreInt
-		}
-
-		weekSchedules = append(weekSchedules, schedule)
+		users = append(users, user)
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"message":   "Week scheduled successfully",
-		"schedules": weekSchedules,
-	})
+	json.NewEncoder(w).Encode(users)
 }
 
-// Helper function to parse date string
-func parseDate(dateStr string) (time.Time, error) {
-	return time.Parse("2006-01-02", dateStr)
-}
+// Admin: Create new user
+func createAdminUser(w http.ResponseWriter, r *http.Request) {
+	var req AdminUserRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
 
-// Helper function to format date to string
-func formatDateString(t time.Time) string {
-	return t.Format("2006-01-02")
-}
+	if req.Username == "" || req.Password == "" {
+		http.Error(w, "Username and password are required", http.StatusBadRequest)
+		return
+	}
 
-// Helper function to get Monday of a week
-func getMondayOfWeek(date time.Time) time.Time {
-	offset := int(time.Monday - date.Weekday())
-	if offset > 0 {
-		offset = -6
+	if len(req.Password) < 6 {
+		http.Error(w, "Password must be at least 6 characters", http.StatusBadRequest)
+		return
 	}
-	return date.AddDate(0, 0, offset)
-}
 
-// Import members from CSV
-func importCSV(w http.ResponseWriter, r *http.Request) {
-	// Parse multipart form (10MB max)
-	err := r.ParseMultipartForm(10 << 20)
+	// Check if username already exists
+	var existingID int
+	err := db.QueryRow("SELECT id FROM users WHERE username = ?", req.Username).Scan(&existingID)
+	if err == nil {
+		http.Error(w, "Username already exists", http.StatusConflict)
+		return
+	}
+
+	// Hash password
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
 	if err != nil {
-		http.Error(w, "Failed to parse form data", http.StatusBadRequest)
+		http.Error(w, "Failed to hash password", http.StatusInternalServerError)
 		return
 	}
 
-	file, _, err := r.FormFile("file")
+	// Insert user
+	result, err := db.Exec("INSERT INTO users (username, password, member_id, is_admin) VALUES (?, ?, ?, ?)",
+		req.Username, string(hashedPassword), req.MemberID, req.IsAdmin)
 	if err != nil {
-		http.Error(w, "Failed to read file", http.StatusBadRequest)
+		http.Error(w, "Failed to create user: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
-	defer file.Close()
 
-	// Parse CSV
-	reader := csv.NewReader(file)
-	reader.TrimLeadingSpace = true
-	reader.FieldsPerRecord = -1 // Allow variable number of fields
+	id, _ := result.LastInsertId()
 
-	records, err := reader.ReadAll()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "User created successfully",
+		"id":      id,
+	})
+}
+
+// Admin: Update user
+func updateAdminUser(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	userID, err := strconv.Atoi(vars["id"])
 	if err != nil {
-		http.Error(w, "Failed to parse CSV: "+err.Error(), http.StatusBadRequest)
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
 		return
 	}
 
-	if len(records) == 0 {
-		http.Error(w, "CSV file is empty", http.StatusBadRequest)
+	var req AdminUserRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
-	// Skip header row if it looks like a header
-	startIndex := 0
-	if len(records) > 0 {
-		firstRow := records[0]
-		if len(firstRow) > 0 {
-			firstCell := strings.ToLower(strings.TrimSpace(firstRow[0]))
-			// Check if first row is a header
-			if firstCell == "username" || firstCell == "name" || firstCell == "member" {
-				startIndex = 1
-			}
-		}
+	// Check if user exists
+	var existingUsername string
+	err = db.QueryRow("SELECT username FROM users WHERE id = ?", userID).Scan(&existingUsername)
+	if err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
 	}
 
-	validRanks := map[string]bool{"R1": true, "R2": true, "R3": true, "R4": true, "R5": true}
-	detectedMembers := []DetectedMember{}
-	errors := []string{}
-
-	// Get existing members
-	existingMembers := make(map[string]Member)
-	rows, err := db.Query("SELECT id, name, rank FROM members")
-	if err == nil {
-		defer rows.Close()
-		for rows.Next() {
-			var m Member
-			rows.Scan(&m.ID, &m.Name, &m.Rank)
-			existingMembers[m.Name] = m
+	// Check if new username already exists (if username is being changed)
+	if req.Username != "" && req.Username != existingUsername {
+		var otherID int
+		err = db.QueryRow("SELECT id FROM users WHERE username = ? AND id != ?", req.Username, userID).Scan(&otherID)
+		if err == nil {
+			http.Error(w, "Username already exists", http.StatusConflict)
+			return
 		}
 	}
 
-	for i := startIndex; i < len(records); i++ {
-		record := records[i]
-
-		// New format: Username,Rank,Power,Level,Status,Last_Active
-		// We only care about Username (index 0) and Rank (index 1)
-		if len(record) < 2 {
-			errors = append(errors, fmt.Sprintf("Line %d: Insufficient columns (need at least Username and Rank)", i+1))
-			continue
-		}
+	// Build update query
+	if req.Username != "" {
+		_, err = db.Exec("UPDATE users SET username = ?, member_id = ?, is_admin = ? WHERE id = ?",
+			req.Username, req.MemberID, req.IsAdmin, userID)
+	} else {
+		_, err = db.Exec("UPDATE users SET member_id = ?, is_admin = ? WHERE id = ?",
+			req.MemberID, req.IsAdmin, userID)
+	}
 
-		name := strings.TrimSpace(record[0])
-		rank := strings.TrimSpace(record[1])
+	if err != nil {
+		http.Error(w, "Failed to update user: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
 
-		if name == "" {
-			errors = append(errors, fmt.Sprintf("Line %d: Empty username", i+1))
-			continue
-		}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "User updated successfully"})
+}
 
-		if !validRanks[rank] {
-			errors = append(errors, fmt.Sprintf("Line %d: Invalid rank '%s' (must be R1-R5)", i+1, rank))
-			continue
-		}
+// Admin: Delete user
+func deleteAdminUser(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	userID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
 
-		detected := DetectedMember{
-			Name: name,
-			Rank: rank,
-		}
-
-		// Check if member exists
-		if existing, found := existingMembers[name]; found {
-			// Existing member - check for rank change
-			if existing.Rank != rank {
-				detected.RankChanged = true
-				detected.OldRank = existing.Rank
-			}
-		} else {
-			// New member - check for similar names in existing members
-			detected.IsNew = true
-			similarNames := []string{}
-			for existingName := range existingMembers {
-				if areSimilar(name, existingName) {
-					similarNames = append(similarNames, existingName)
-				}
-			}
-			if len(similarNames) > 0 {
-				detected.SimilarMatch = similarNames
-			}
-		}
-
-		detectedMembers = append(detectedMembers, detected)
+	// Check if user exists
+	var username string
+	err = db.QueryRow("SELECT username FROM users WHERE id = ?", userID).Scan(&username)
+	if err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
 	}
 
-	// Find members that would be removed (in database but not in CSV)
-	membersToRemove := []MemberToRemove{}
-	csvNames := make(map[string]bool)
-	for _, m := range detectedMembers {
-		csvNames[m.Name] = true
-	}
-	for _, existing := range existingMembers {
-		if !csvNames[existing.Name] {
-			membersToRemove = append(membersToRemove, MemberToRemove{
-				ID:   existing.ID,
-				Name: existing.Name,
-				Rank: existing.Rank,
-			})
+	// Prevent deleting the last admin
+	var adminCount int
+	err = db.QueryRow("SELECT COUNT(*) FROM users WHERE is_admin = 1").Scan(&adminCount)
+	if err == nil && adminCount <= 1 {
+		var isAdmin bool
+		db.QueryRow("SELECT is_admin FROM users WHERE id = ?", userID).Scan(&isAdmin)
+		if isAdmin {
+			http.Error(w, "Cannot delete the last admin user", http.StatusForbidden)
+			return
 		}
 	}
 
-	// Return preview data
-	result := map[string]interface{}{
-		"detected_members":  detectedMembers,
-		"members_to_remove": membersToRemove,
-		"errors":            errors,
-		"total_rows":        len(records) - startIndex,
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(result)
-}
-
-// Confirm CSV import (reuses the same confirmMemberUpdates function)
-// The route will be /api/members/import/confirm
-
-// Get awards for a specific week or all weeks
-func getAwards(w http.ResponseWriter, r *http.Request) {
-	weekDate := r.URL.Query().Get("week")
-
-	var query string
-	var rows *sql.Rows
-	var err error
-
-	if weekDate != "" {
-		query = `
-			SELECT a.id, a.week_date, a.award_type, a.rank, a.member_id, m.name, a.created_at
-			FROM awards a
-			JOIN members m ON a.member_id = m.id
-			WHERE a.week_date = ?
-			ORDER BY a.award_type, a.rank
-		`
-		rows, err = db.Query(query, weekDate)
-	} else {
-		query = `
-			SELECT a.id, a.week_date, a.award_type, a.rank, a.member_id, m.name, a.created_at
-			FROM awards a
-			JOIN members m ON a.member_id = m.id
-			ORDER BY a.week_date DESC, a.award_type, a.rank
-		`
-		rows, err = db.Query(query)
-	}
-
+	// Delete user
+	_, err = db.Exec("DELETE FROM users WHERE id = ?", userID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, "Failed to delete user: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
-	defer rows.Close()
-
-	awards := []Award{}
-	for rows.Next() {
-		var a Award
-		if err := rows.Scan(&a.ID, &a.WeekDate, &a.AwardType, &a.Rank, &a.MemberID, &a.MemberName, &a.CreatedAt); err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-		awards = append(awards, a)
-	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(awards)
+	json.NewEncoder(w).Encode(map[string]string{"message": "User deleted successfully"})
 }
 
-// Save awards for a week (bulk operation)
-func saveAwards(w http.ResponseWriter, r *http.Request) {
-	var data struct {
-		WeekDate string `json:"week_date"`
-		Awards   []struct {
-			AwardType string `json:"award_type"`
-			Rank      int    `json:"rank"`
-			MemberID  int    `json:"member_id"`
-		} `json:"awards"`
-	}
-
-	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+// Admin: Reset user password
+func resetUserPassword(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	userID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
 		return
 	}
 
-	// Begin transaction
-	tx, err := db.Begin()
+	// Check if user exists
+	var username string
+	err = db.QueryRow("SELECT username FROM users WHERE id = ?", userID).Scan(&username)
 	if err != nil {
-		http.Error(w, "Database error", http.StatusInternalServerError)
+		http.Error(w, "User not found", http.StatusNotFound)
 		return
 	}
 
-	// Delete existing awards for this week
-	_, err = tx.Exec("DELETE FROM awards WHERE week_date = ?", data.WeekDate)
+	// Generate random password
+	randomPassword, err := generateRandomPassword(10)
 	if err != nil {
-		tx.Rollback()
-		http.Error(w, "Failed to clear existing awards", http.StatusInternalServerError)
+		http.Error(w, "Failed to generate password", http.StatusInternalServerError)
 		return
 	}
 
-	// Insert new awards
-	for _, award := range data.Awards {
-		if award.MemberID > 0 { // Only insert if a member is selected
-			_, err = tx.Exec(
-				"INSERT INTO awards (week_date, award_type, rank, member_id) VALUES (?, ?, ?, ?)",
-				data.WeekDate, award.AwardType, award.Rank, award.MemberID)
-			if err != nil {
-				tx.Rollback()
-				http.Error(w, "Failed to save award", http.StatusInternalServerError)
-				return
-			}
-		}
-	}
-
-	// Commit transaction
-	err = tx.Commit()
+	// Hash password
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(randomPassword), bcrypt.DefaultCost)
 	if err != nil {
-		http.Error(w, "Failed to save changes", http.StatusInternalServerError)
+		http.Error(w, "Failed to hash password", http.StatusInternalServerError)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{"message": "Awards saved successfully"})
-}
-
-// Delete awards for a specific week
-func deleteWeekAwards(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	weekDate := vars["week"]
-
-	_, err := db.Exec("DELETE FROM awards WHERE week_date = ?", weekDate)
+	// Update password
+	_, err = db.Exec("UPDATE users SET password = ? WHERE id = ?", string(hashedPassword), userID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, "Failed to reset password: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	w.WriteHeader(http.StatusNoContent)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":  "Password reset successfully",
+		"username": username,
+		"password": randomPassword,
+	})
 }
 
-// Get VS points for a week or all weeks
-func getVSPoints(w http.ResponseWriter, r *http.Request) {
-	weekDate := r.URL.Query().Get("week")
+// Admin: Get login history
+func getLoginHistory(w http.ResponseWriter, r *http.Request) {
+	// Get optional filters from query params
+	userIDParam := r.URL.Query().Get("user_id")
+	limit := r.URL.Query().Get("limit")
+	if limit == "" {
+		limit = "100"
+	}
 
-	var query string
-	var rows *sql.Rows
-	var err error
+	query := `
+		SELECT ls.id, ls.user_id, ls.username, ls.ip_address, ls.user_agent, 
+		       ls.country, ls.city, ls.isp, ls.login_time, ls.success
+		FROM login_sessions ls
+	`
 
-	if weekDate != "" {
-		query = `
-			SELECT v.id, v.member_id, v.week_date, v.monday, v.tuesday, v.wednesday, 
-			       v.thursday, v.friday, v.saturday, v.created_at, v.updated_at,
-			       m.name, m.rank
-			FROM vs_points v
-			JOIN members m ON v.member_id = m.id
-			WHERE v.week_date = ?
-			ORDER BY m.name
-		`
-		rows, err = db.Query(query, weekDate)
-	} else {
-		query = `
-			SELECT v.id, v.member_id, v.week_date, v.monday, v.tuesday, v.wednesday, 
-			       v.thursday, v.friday, v.saturday, v.created_at, v.updated_at,
-			       m.name, m.rank
-			FROM vs_points v
-			JOIN members m ON v.member_id = m.id
-			ORDER BY v.week_date DESC, m.name
-		`
-		rows, err = db.Query(query)
+	if userIDParam != "" {
+		query += " WHERE ls.user_id = " + userIDParam
 	}
 
+	query += " ORDER BY ls.login_time DESC LIMIT " + limit
+
+	rows, err := db.Query(query)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, "Failed to fetch login history", http.StatusInternalServerError)
 		return
 	}
 	defer rows.Close()
 
-	vsPoints := []VSPointsWithMember{}
+	history := []LoginSession{}
 	for rows.Next() {
-		var v VSPointsWithMember
-		if err := rows.Scan(&v.ID, &v.MemberID, &v.WeekDate, &v.Monday, &v.Tuesday,
-			&v.Wednesday, &v.Thursday, &v.Friday, &v.Saturday, &v.CreatedAt, &v.UpdatedAt,
-			&v.MemberName, &v.MemberRank); err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
+		var login LoginSession
+		var ipAddr, userAgent, country, city, isp sql.NullString
+
+		err := rows.Scan(&login.ID, &login.UserID, &login.Username,
+			&ipAddr, &userAgent, &country, &city, &isp,
+			&login.LoginTime, &login.Success)
+		if err != nil {
+			continue
 		}
-		vsPoints = append(vsPoints, v)
+
+		if ipAddr.Valid {
+			login.IPAddress = &ipAddr.String
+		}
+		if userAgent.Valid {
+			login.UserAgent = &userAgent.String
+		}
+		if country.Valid {
+			login.Country = &country.String
+		}
+		if city.Valid {
+			login.City = &city.String
+		}
+		if isp.Valid {
+			login.ISP = &isp.String
+		}
+
+		history = append(history, login)
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(vsPoints)
+	json.NewEncoder(w).Encode(history)
 }
 
-// Save VS points for a week (bulk operation)
-func saveVSPoints(w http.ResponseWriter, r *http.Request) {
-	var data struct {
-		WeekDate string `json:"week_date"`
-		Points   []struct {
-			MemberID  int `json:"member_id"`
-			Monday    int `json:"monday"`
-			Tuesday   int `json:"tuesday"`
-			Wednesday int `json:"wednesday"`
-			Thursday  int `json:"thursday"`
-			Friday    int `json:"friday"`
-			Saturday  int `json:"saturday"`
-		} `json:"points"`
-	}
-
-	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
-	}
-
-	// Begin transaction
-	tx, err := db.Begin()
+// Get all members
+func getMembers(w http.ResponseWriter, r *http.Request) {
+	query := `
+		SELECT m.id, m.name, m.rank, COALESCE(m.eligible, 1),
+		       (SELECT ph.power 
+		        FROM power_history ph 
+		        WHERE ph.member_id = m.id 
+		        ORDER BY ph.recorded_at DESC 
+		        LIMIT 1) as latest_power,
+		       m.timezone
+		FROM members m
+		ORDER BY m.name
+	`
+	rows, err := db.Query(query)
 	if err != nil {
-		http.Error(w, "Database error", http.StatusInternalServerError)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	defer rows.Close()
 
-	// Upsert VS points for each member
-	for _, point := range data.Points {
-		// Check if record exists
-		var existingID int
-		err = tx.QueryRow("SELECT id FROM vs_points WHERE member_id = ? AND week_date = ?",
-			point.MemberID, data.WeekDate).Scan(&existingID)
-
-		if err == sql.ErrNoRows {
-			// Insert new record
-			_, err = tx.Exec(`
-				INSERT INTO vs_points (member_id, week_date, monday, tuesday, wednesday, thursday, friday, saturday, updated_at)
-				VALUES (?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)`,
-				point.MemberID, data.WeekDate, point.Monday, point.Tuesday, point.Wednesday,
-				point.Thursday, point.Friday, point.Saturday)
-		} else if err == nil {
-			// Update existing record
-			_, err = tx.Exec(`
-				UPDATE vs_points 
-				SET monday = ?, tuesday = ?, wednesday = ?, thursday = ?, friday = ?, saturday = ?, updated_at = CURRENT_TIMESTAMP
-				WHERE member_id = ? AND week_date = ?`,
-				point.Monday, point.Tuesday, point.Wednesday, point.Thursday, point.Friday, point.Saturday,
-				point.MemberID, data.WeekDate)
-		}
-
-		if err != nil {
-			tx.Rollback()
-			http.Error(w, "Failed to save VS points", http.StatusInternalServerError)
+	members := []Member{}
+	for rows.Next() {
+		var m Member
+		if err := rows.Scan(&m.ID, &m.Name, &m.Rank, &m.Eligible, &m.Power, &m.Timezone); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
-	}
-
-	// Commit transaction
-	err = tx.Commit()
-	if err != nil {
-		http.Error(w, "Failed to save changes", http.StatusInternalServerError)
-		return
+		members = append(members, m)
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{"message": "VS points saved successfully"})
-}
-
-// Delete VS points for a specific week
-func deleteWeekVSPoints(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	weekDate := vars["week"]
-
-	_, err := db.Exec("DELETE FROM vs_points WHERE week_date = ?", weekDate)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	w.WriteHeader(http.StatusNoContent)
+	json.NewEncoder(w).Encode(members)
 }
 
-// Get all award types
-func getAwardTypes(w http.ResponseWriter, r *http.Request) {
+// Get member statistics for train scheduling
+func getMemberStats(w http.ResponseWriter, r *http.Request) {
 	rows, err := db.Query(`
-		SELECT id, name, active, sort_order, created_at
-		FROM award_types
-		ORDER BY sort_order, name
+		SELECT 
+			m.id, 
+			m.name, 
+			m.rank,
+			COUNT(DISTINCT CASE WHEN ts.conductor_id = m.id THEN ts.date END) as conductor_count,
+			MAX(CASE WHEN ts.conductor_id = m.id THEN ts.date END) as last_conductor_date,
+			COUNT(DISTINCT CASE WHEN ts.backup_id = m.id THEN ts.date END) as backup_count,
+			COUNT(DISTINCT CASE WHEN ts.backup_id = m.id AND ts.conductor_showed_up = 0 THEN ts.date END) as backup_used_count,
+			COUNT(DISTINCT CASE WHEN ts.conductor_id = m.id AND ts.conductor_showed_up = 0 THEN ts.date END) as conductor_no_show_count
+		FROM members m
+		LEFT JOIN train_schedules ts ON ts.conductor_id = m.id OR ts.backup_id = m.id
+		GROUP BY m.id, m.name, m.rank
+		ORDER BY m.name
 	`)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -2960,460 +3846,408 @@ func getAwardTypes(w http.ResponseWriter, r *http.Request) {
 	}
 	defer rows.Close()
 
-	awardTypes := []AwardType{}
+	stats := []MemberStats{}
 	for rows.Next() {
-		var at AwardType
-		if err := rows.Scan(&at.ID, &at.Name, &at.Active, &at.SortOrder, &at.CreatedAt); err != nil {
+		var s MemberStats
+		var lastDate sql.NullString
+		if err := rows.Scan(&s.ID, &s.Name, &s.Rank, &s.ConductorCount, &lastDate, &s.BackupCount, &s.BackupUsedCount, &s.ConductorNoShowCount); err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
-		awardTypes = append(awardTypes, at)
+		if lastDate.Valid {
+			s.LastConductorDate = &lastDate.String
+		}
+		stats = append(stats, s)
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(awardTypes)
+	json.NewEncoder(w).Encode(stats)
 }
 
-// Create a new award type
-func createAwardType(w http.ResponseWriter, r *http.Request) {
-	var at AwardType
-	if err := json.NewDecoder(r.Body).Decode(&at); err != nil {
+// Create a new member
+func createMember(w http.ResponseWriter, r *http.Request) {
+	var m Member
+	if err := json.NewDecoder(r.Body).Decode(&m); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	// Validate name
-	if strings.TrimSpace(at.Name) == "" {
-		http.Error(w, "Award type name is required", http.StatusBadRequest)
-		return
-	}
-
-	// Check if award type already exists
-	var existingID int
-	err := db.QueryRow("SELECT id FROM award_types WHERE name = ?", at.Name).Scan(&existingID)
-	if err == nil {
-		http.Error(w, "Award type already exists", http.StatusConflict)
-		return
-	}
-
-	// Get max sort_order and add 1
-	var maxOrder int
-	err = db.QueryRow("SELECT COALESCE(MAX(sort_order), -1) FROM award_types").Scan(&maxOrder)
-	if err != nil {
-		maxOrder = -1
+	// Default to eligible if not specified
+	if !m.Eligible {
+		m.Eligible = true
 	}
 
-	result, err := db.Exec(
-		"INSERT INTO award_types (name, active, sort_order) VALUES (?, ?, ?)",
-		at.Name, true, maxOrder+1)
+	result, err := db.Exec("INSERT INTO members (name, rank, eligible) VALUES (?, ?, ?)", m.Name, m.Rank, m.Eligible)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
 	id, _ := result.LastInsertId()
-	at.ID = int(id)
-	at.Active = true
-	at.SortOrder = maxOrder + 1
-	at.CreatedAt = time.Now().Format(time.RFC3339)
+	m.ID = int(id)
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(at)
+	json.NewEncoder(w).Encode(m)
 }
 
-// Update award type (mainly for active status)
-func updateAwardType(w http.ResponseWriter, r *http.Request) {
+// Update a member
+func updateMember(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id, err := strconv.Atoi(vars["id"])
 	if err != nil {
-		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		http.Error(w, "Invalid member ID", http.StatusBadRequest)
 		return
 	}
 
-	var at AwardType
-	if err := json.NewDecoder(r.Body).Decode(&at); err != nil {
+	var m Member
+	if err := json.NewDecoder(r.Body).Decode(&m); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	_, err = db.Exec(
-		"UPDATE award_types SET active = ?, name = ? WHERE id = ?",
-		at.Active, at.Name, id)
+	_, err = db.Exec("UPDATE members SET name = ?, rank = ?, eligible = ? WHERE id = ?", m.Name, m.Rank, m.Eligible, id)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	m.ID = id
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{"message": "Award type updated"})
+	json.NewEncoder(w).Encode(m)
 }
 
-// Delete award type (supports force deletion)
-func deleteAwardType(w http.ResponseWriter, r *http.Request) {
+// Delete a member
+func deleteMember(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id, err := strconv.Atoi(vars["id"])
 	if err != nil {
-		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		http.Error(w, "Invalid member ID", http.StatusBadRequest)
 		return
 	}
 
-	// Check for force parameter
-	force := r.URL.Query().Get("force") == "true"
-
-	// Get award type name
-	var name string
-	err = db.QueryRow("SELECT name FROM award_types WHERE id = ?", id).Scan(&name)
+	_, err = db.Exec("DELETE FROM members WHERE id = ?", id)
 	if err != nil {
-		http.Error(w, "Award type not found", http.StatusNotFound)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	if force {
-		// Delete all awards of this type first
-		_, err = db.Exec("DELETE FROM awards WHERE award_type = ?", name)
-		if err != nil {
-			http.Error(w, "Failed to delete related awards", http.StatusInternalServerError)
-			return
-		}
-	} else {
-		// Check if award type is used in any awards
-		var count int
-		err = db.QueryRow("SELECT COUNT(*) FROM awards WHERE award_type = ?", name).Scan(&count)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
+	w.WriteHeader(http.StatusNoContent)
+}
 
-		if count > 0 {
-			http.Error(w, "Cannot delete award type that is used in awards. Use force=true to delete anyway.", http.StatusBadRequest)
-			return
-		}
+// Set the time zone a member plays from; members set their own, R4/R5 may pass a member_id
+func updateMemberTimezone(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		MemberID int    `json:"member_id"`
+		Timezone string `json:"timezone"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 
-	_, err = db.Exec("DELETE FROM award_types WHERE id = ?", id)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	session, _ := store.Get(r, "session")
+	ownMemberID, _ := session.Values["member_id"].(int)
+	if request.MemberID == 0 {
+		request.MemberID = ownMemberID
+	}
+	if request.MemberID == 0 {
+		http.Error(w, "Your account is not linked to a member", http.StatusBadRequest)
+		return
+	}
+	if request.MemberID != ownMemberID && !sessionCanManageRanks(r) {
+		http.Error(w, "Forbidden: Only R4/R5 members can set other members' time zones", http.StatusForbidden)
 		return
 	}
 
-	w.WriteHeader(http.StatusNoContent)
-}
+	// An empty time zone clears it
+	var timezone interface{}
+	if request.Timezone = strings.TrimSpace(request.Timezone); request.Timezone != "" {
+		if _, err := time.LoadLocation(request.Timezone); err != nil {
+			http.Error(w, fmt.Sprintf("Unknown time zone: %s", request.Timezone), http.StatusBadRequest)
+			return
+		}
+		timezone = request.Timezone
+	}
 
-// Get all recommendations
-func getRecommendations(w http.ResponseWriter, r *http.Request) {
-	rows, err := db.Query(`
-		SELECT 
-			rec.id, 
-			rec.member_id, 
-			m.name, 
-			m.rank,
-			u.username,
-			rec.recommended_by_id,
-			COALESCE(rec.notes, ''),
-			rec.created_at,
-			CASE 
-				WHEN EXISTS (
-					SELECT 1 FROM train_schedules ts
-					WHERE (ts.conductor_id = rec.member_id OR (ts.backup_id = rec.member_id AND ts.conductor_showed_up = 0))
-					AND ts.date >= date(rec.created_at)
-				) THEN 1
-				ELSE 0
-			END as expired
-		FROM recommendations rec
-		JOIN members m ON rec.member_id = m.id
-		JOIN users u ON rec.recommended_by_id = u.id
-		ORDER BY rec.created_at DESC
-	`)
+	result, err := db.Exec("UPDATE members SET timezone = ? WHERE id = ?", timezone, request.MemberID)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	defer rows.Close()
-
-	recommendations := []Recommendation{}
-	for rows.Next() {
-		var rec Recommendation
-		if err := rows.Scan(&rec.ID, &rec.MemberID, &rec.MemberName, &rec.MemberRank,
-			&rec.RecommendedBy, &rec.RecommendedByID, &rec.Notes, &rec.CreatedAt, &rec.Expired); err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-		recommendations = append(recommendations, rec)
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		http.Error(w, "Member not found", http.StatusNotFound)
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(recommendations)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"member_id": request.MemberID,
+		"timezone":  timezone,
+	})
 }
 
-// Create recommendation
-func createRecommendation(w http.ResponseWriter, r *http.Request) {
-	session, _ := store.Get(r, "session")
-	userID := session.Values["user_id"].(int)
+// trainEvent holds the IDs of the built-in train event type and its conductor and backup slots
+type trainEvent struct {
+	TypeID          int
+	ConductorSlotID int
+	BackupSlotID    int
+}
 
-	var input struct {
-		MemberID int    `json:"member_id"`
-		Notes    string `json:"notes"`
-	}
+// loadTrainEvent finds the train event type; train schedules are stored as its assignments
+func loadTrainEvent() (trainEvent, error) {
+	var te trainEvent
+	err := db.QueryRow(`
+		SELECT t.id, c.id, b.id
+		FROM alliance_event_types t
+		JOIN alliance_event_slots c ON c.event_type_id = t.id AND c.system_key = ?
+		JOIN alliance_event_slots b ON b.event_type_id = t.id AND b.system_key = ?
+		WHERE t.system_key = ?
+	`, trainConductorSlotKey, trainBackupSlotKey, trainEventKey).Scan(&te.TypeID, &te.ConductorSlotID, &te.BackupSlotID)
+	return te, err
+}
 
-	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
-		return
+// saveTrainDay writes a day's conductor and backup (no backup when backupID is 0) and returns the
+// conductor assignment's ID, which is the schedule's ID in train_schedules
+func saveTrainDay(tx *sql.Tx, te trainEvent, date string, conductorID, backupID int, score *int, showedUp *bool, notes *string) (int, error) {
+	_, err := tx.Exec(`
+		INSERT INTO alliance_event_assignments (event_type_id, date, slot_id, position, member_id, score, showed_up, notes)
+		VALUES (?, ?, ?, 1, ?, ?, ?, ?)
+		ON CONFLICT(event_type_id, date, slot_id, position) DO UPDATE SET
+			member_id = excluded.member_id,
+			score = excluded.score,
+			showed_up = excluded.showed_up,
+			notes = excluded.notes
+	`, te.TypeID, date, te.ConductorSlotID, conductorID, score, showedUp, notes)
+	if err != nil {
+		return 0, err
 	}
 
-	if input.MemberID == 0 {
-		http.Error(w, "Member ID is required", http.StatusBadRequest)
-		return
+	if backupID > 0 {
+		_, err = tx.Exec(`
+			INSERT INTO alliance_event_assignments (event_type_id, date, slot_id, position, member_id)
+			VALUES (?, ?, ?, 1, ?)
+			ON CONFLICT(event_type_id, date, slot_id, position) DO UPDATE SET member_id = excluded.member_id
+		`, te.TypeID, date, te.BackupSlotID, backupID)
+	} else {
+		_, err = tx.Exec("DELETE FROM alliance_event_assignments WHERE event_type_id = ? AND date = ? AND slot_id = ?",
+			te.TypeID, date, te.BackupSlotID)
+	}
+	if err != nil {
+		return 0, err
 	}
 
-	// Check if member exists
-	var exists bool
-	err := db.QueryRow("SELECT EXISTS(SELECT 1 FROM members WHERE id = ?)", input.MemberID).Scan(&exists)
-	if err != nil || !exists {
-		http.Error(w, "Member not found", http.StatusNotFound)
-		return
+	var id int
+	err = tx.QueryRow("SELECT id FROM alliance_event_assignments WHERE event_type_id = ? AND date = ? AND slot_id = ? AND position = 1",
+		te.TypeID, date, te.ConductorSlotID).Scan(&id)
+	return id, err
+}
+
+// Get train schedules (optionally filtered by date range)
+func getTrainSchedules(w http.ResponseWriter, r *http.Request) {
+	startDate := r.URL.Query().Get("start")
+	endDate := r.URL.Query().Get("end")
+
+	query := `
+		SELECT 
+			ts.id, ts.date, ts.conductor_id, m1.name as conductor_name,
+			ts.conductor_score, ts.backup_id, m2.name as backup_name, m2.rank as backup_rank,
+			ts.conductor_showed_up, ts.notes, ts.created_at
+		FROM train_schedules ts
+		JOIN members m1 ON ts.conductor_id = m1.id
+		JOIN members m2 ON ts.backup_id = m2.id
+	`
+
+	var rows *sql.Rows
+	var err error
+
+	if startDate != "" && endDate != "" {
+		query += " WHERE ts.date BETWEEN ? AND ? ORDER BY ts.date, ts.conductor_score DESC"
+		rows, err = db.Query(query, startDate, endDate)
+	} else {
+		query += " ORDER BY ts.date, ts.conductor_score DESC"
+		rows, err = db.Query(query)
 	}
 
-	result, err := db.Exec(
-		"INSERT INTO recommendations (member_id, recommended_by_id, notes) VALUES (?, ?, ?)",
-		input.MemberID, userID, input.Notes,
-	)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	defer rows.Close()
 
-	id, _ := result.LastInsertId()
+	schedules := []TrainSchedule{}
+	for rows.Next() {
+		var ts TrainSchedule
+		var showedUp sql.NullBool
+		var notes sql.NullString
+		var score sql.NullInt64
 
-	// Get the created recommendation
-	var rec Recommendation
-	err = db.QueryRow(`
-		SELECT 
-			rec.id, 
-			rec.member_id, 
-			m.name, 
-			m.rank,
-			u.username,
-			rec.recommended_by_id,
-			COALESCE(rec.notes, ''),
-			rec.created_at,
-			CASE 
-				WHEN EXISTS (
-					SELECT 1 FROM train_schedules ts
-					WHERE (ts.conductor_id = rec.member_id OR (ts.backup_id = rec.member_id AND ts.conductor_showed_up = 0))
-					AND ts.date >= date(rec.created_at)
-				) THEN 1
-				ELSE 0
-			END as expired
-		FROM recommendations rec
-		JOIN members m ON rec.member_id = m.id
-		JOIN users u ON rec.recommended_by_id = u.id
-		WHERE rec.id = ?
-	`, id).Scan(&rec.ID, &rec.MemberID, &rec.MemberName, &rec.MemberRank,
-		&rec.RecommendedBy, &rec.RecommendedByID, &rec.Notes, &rec.CreatedAt, &rec.Expired)
+		if err := rows.Scan(&ts.ID, &ts.Date, &ts.ConductorID, &ts.ConductorName,
+			&score, &ts.BackupID, &ts.BackupName, &ts.BackupRank, &showedUp, &notes, &ts.CreatedAt); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
 
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+		if showedUp.Valid {
+			ts.ConductorShowedUp = &showedUp.Bool
+		}
+		if notes.Valid {
+			ts.Notes = &notes.String
+		}
+		if score.Valid {
+			scoreInt := int(score.Int64)
+			ts.ConductorScore = &scoreInt
+		}
+
+		schedules = append(schedules, ts)
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(rec)
+	json.NewEncoder(w).Encode(schedules)
 }
 
-// Delete recommendation
-func deleteRecommendation(w http.ResponseWriter, r *http.Request) {
-	session, _ := store.Get(r, "session")
-	userID := session.Values["user_id"].(int)
-	isAdmin := session.Values["is_admin"].(bool)
-
-	vars := mux.Vars(r)
-	id, err := strconv.Atoi(vars["id"])
-	if err != nil {
-		http.Error(w, "Invalid ID", http.StatusBadRequest)
+// Create a train schedule
+func createTrainSchedule(w http.ResponseWriter, r *http.Request) {
+	var ts TrainSchedule
+	if err := json.NewDecoder(r.Body).Decode(&ts); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	// Check if user is the one who created the recommendation or is admin
-	var recommendedByID int
-	err = db.QueryRow("SELECT recommended_by_id FROM recommendations WHERE id = ?", id).Scan(&recommendedByID)
+	// Validate backup is R4 or R5
+	var backupRank string
+	err := db.QueryRow("SELECT rank FROM members WHERE id = ?", ts.BackupID).Scan(&backupRank)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			http.Error(w, "Recommendation not found", http.StatusNotFound)
-		} else {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-		}
+		http.Error(w, "Backup member not found", http.StatusBadRequest)
 		return
 	}
 
-	if recommendedByID != userID && !isAdmin {
-		http.Error(w, "You can only delete your own recommendations", http.StatusForbidden)
+	if backupRank != "R4" && backupRank != "R5" {
+		http.Error(w, "Backup must be an R4 or R5 member", http.StatusBadRequest)
 		return
 	}
 
-	_, err = db.Exec("DELETE FROM recommendations WHERE id = ?", id)
+	te, err := loadTrainEvent()
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	w.WriteHeader(http.StatusNoContent)
-}
+	tx, err := db.Begin()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
 
-// Get dyno recommendations (expires after 1 week)
-func getDynoRecommendations(w http.ResponseWriter, r *http.Request) {
-	rows, err := db.Query(`
-		SELECT 
-			dr.id, 
-			dr.member_id, 
-			m.name, 
-			m.rank,
-			dr.points,
-			dr.notes,
-			u.username,
-			dr.created_by_id,
-			dr.created_at,
-			CASE 
-				WHEN datetime(dr.created_at, '+7 days') < datetime('now') THEN 1
-				ELSE 0
-			END as expired
-		FROM dyno_recommendations dr
-		JOIN members m ON dr.member_id = m.id
-		JOIN users u ON dr.created_by_id = u.id
-		ORDER BY dr.created_at DESC
-	`)
+	// Saving over the date replaces schedules created by auto-schedule
+	ts.ID, err = saveTrainDay(tx, te, ts.Date, ts.ConductorID, ts.BackupID, ts.ConductorScore, ts.ConductorShowedUp, ts.Notes)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	defer rows.Close()
 
-	dynoRecs := []DynoRecommendation{}
-	for rows.Next() {
-		var dr DynoRecommendation
-		if err := rows.Scan(&dr.ID, &dr.MemberID, &dr.MemberName, &dr.MemberRank,
-			&dr.Points, &dr.Notes, &dr.CreatedBy, &dr.CreatedByID, &dr.CreatedAt, &dr.Expired); err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-		dynoRecs = append(dynoRecs, dr)
+	if err := tx.Commit(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
 	}
 
+	// Awards and recommendations automatically become inactive via on-the-fly calculation
+
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(dynoRecs)
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(ts)
 }
 
-// Create dyno recommendation
-func createDynoRecommendation(w http.ResponseWriter, r *http.Request) {
-	session, _ := store.Get(r, "session")
-	userID := session.Values["user_id"].(int)
-
-	var input struct {
-		MemberID int    `json:"member_id"`
-		Points   int    `json:"points"`
-		Notes    string `json:"notes"`
+// Update a train schedule
+func updateTrainSchedule(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "Invalid schedule ID", http.StatusBadRequest)
+		return
 	}
 
-	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+	var ts TrainSchedule
+	if err := json.NewDecoder(r.Body).Decode(&ts); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	if input.MemberID == 0 {
-		http.Error(w, "Member ID is required", http.StatusBadRequest)
+	var existingDate string
+	err = db.QueryRow("SELECT date FROM train_schedules WHERE id = ?", id).Scan(&existingDate)
+	if err != nil {
+		http.Error(w, "Schedule not found", http.StatusNotFound)
 		return
 	}
 
-	if input.Notes == "" {
-		http.Error(w, "Notes are required", http.StatusBadRequest)
-		return
+	// Validate backup is R4 or R5 if backup is being updated
+	if ts.BackupID > 0 {
+		var backupRank string
+		err := db.QueryRow("SELECT rank FROM members WHERE id = ?", ts.BackupID).Scan(&backupRank)
+		if err != nil {
+			http.Error(w, "Backup member not found", http.StatusBadRequest)
+			return
+		}
+
+		if backupRank != "R4" && backupRank != "R5" {
+			http.Error(w, "Backup must be an R4 or R5 member", http.StatusBadRequest)
+			return
+		}
 	}
 
-	// Check if member exists
-	var exists bool
-	err := db.QueryRow("SELECT EXISTS(SELECT 1 FROM members WHERE id = ?)", input.MemberID).Scan(&exists)
-	if err != nil || !exists {
-		http.Error(w, "Member not found", http.StatusNotFound)
+	te, err := loadTrainEvent()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	result, err := db.Exec(
-		"INSERT INTO dyno_recommendations (member_id, points, notes, created_by_id) VALUES (?, ?, ?, ?)",
-		input.MemberID, input.Points, input.Notes, userID,
-	)
+	tx, err := db.Begin()
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	defer tx.Rollback()
 
-	id, _ := result.LastInsertId()
+	// Move the day's conductor and backup together, then save over them
+	if ts.Date != existingDate {
+		_, err = tx.Exec("UPDATE alliance_event_assignments SET date = ? WHERE event_type_id = ? AND date = ?", ts.Date, te.TypeID, existingDate)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	ts.ID, err = saveTrainDay(tx, te, ts.Date, ts.ConductorID, ts.BackupID, ts.ConductorScore, ts.ConductorShowedUp, ts.Notes)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
 
-	// Get the created dyno recommendation
-	var dr DynoRecommendation
-	err = db.QueryRow(`
-		SELECT 
-			dr.id, 
-			dr.member_id, 
-			m.name, 
-			m.rank,
-			dr.points,
-			dr.notes,
-			u.username,
-			dr.created_by_id,
-			dr.created_at,
-			CASE 
-				WHEN datetime(dr.created_at, '+7 days') < datetime('now') THEN 1
-				ELSE 0
-			END as expired
-		FROM dyno_recommendations dr
-		JOIN members m ON dr.member_id = m.id
-		JOIN users u ON dr.created_by_id = u.id
-		WHERE dr.id = ?
-	`, id).Scan(&dr.ID, &dr.MemberID, &dr.MemberName, &dr.MemberRank,
-		&dr.Points, &dr.Notes, &dr.CreatedBy, &dr.CreatedByID, &dr.CreatedAt, &dr.Expired)
-
-	if err != nil {
+	if err := tx.Commit(); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	// Awards and recommendations automatically become inactive via on-the-fly calculation
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(dr)
+	json.NewEncoder(w).Encode(ts)
 }
 
-// Delete dyno recommendation
-func deleteDynoRecommendation(w http.ResponseWriter, r *http.Request) {
-	session, _ := store.Get(r, "session")
-	userID := session.Values["user_id"].(int)
-	isAdmin := session.Values["is_admin"].(bool)
-
+// Delete a train schedule
+func deleteTrainSchedule(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id, err := strconv.Atoi(vars["id"])
 	if err != nil {
-		http.Error(w, "Invalid ID", http.StatusBadRequest)
-		return
-	}
-
-	// Check if user is the one who created the recommendation or is admin
-	var createdByID int
-	err = db.QueryRow("SELECT created_by_id FROM dyno_recommendations WHERE id = ?", id).Scan(&createdByID)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			http.Error(w, "Dyno recommendation not found", http.StatusNotFound)
-		} else {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-		}
-		return
-	}
-
-	if createdByID != userID && !isAdmin {
-		http.Error(w, "You can only delete your own dyno recommendations", http.StatusForbidden)
+		http.Error(w, "Invalid schedule ID", http.StatusBadRequest)
 		return
 	}
 
-	_, err = db.Exec("DELETE FROM dyno_recommendations WHERE id = ?", id)
+	// The schedule is the day's conductor assignment; its backup goes with it
+	_, err = db.Exec(`
+		DELETE FROM alliance_event_assignments
+		WHERE event_type_id = (SELECT id FROM alliance_event_types WHERE system_key = ?)
+			AND date IN (SELECT date FROM train_schedules WHERE id = ?)
+	`, trainEventKey, id)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -3422,2767 +4256,12093 @@ func deleteDynoRecommendation(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
-// Get settings
-func getSettings(w http.ResponseWriter, r *http.Request) {
-	var settings Settings
-	err := db.QueryRow(`SELECT id, award_first_points, award_second_points, award_third_points, 
-		recommendation_points, recent_conductor_penalty_days, above_average_conductor_penalty, r4r5_rank_boost,
-		first_time_conductor_boost, schedule_message_template, daily_message_template, 
-		COALESCE(power_tracking_enabled, 0) as power_tracking_enabled
-		FROM settings WHERE id = 1`).Scan(
-		&settings.ID,
-		&settings.AwardFirstPoints,
-		&settings.AwardSecondPoints,
-		&settings.AwardThirdPoints,
-		&settings.RecommendationPoints,
-		&settings.RecentConductorPenaltyDays,
-		&settings.AboveAverageConductorPenalty,
-		&settings.R4R5RankBoost,
-		&settings.FirstTimeConductorBoost,
-		&settings.ScheduleMessageTemplate,
-		&settings.DailyMessageTemplate,
-		&settings.PowerTrackingEnabled,
-	)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+// Auto-schedule train conductors and backups for a single day
+func autoSchedule(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		StartDate string `json:"start_date"`
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(settings)
-}
-
-// Update settings (admin only)
-func updateSettings(w http.ResponseWriter, r *http.Request) {
-	var settings Settings
-	if err := json.NewDecoder(r.Body).Decode(&settings); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
-	_, err := db.Exec(`UPDATE settings SET 
-		award_first_points = ?, 
-		award_second_points = ?, 
-		award_third_points = ?, 
-		recommendation_points = ?, 
-		recent_conductor_penalty_days = ?, 
-		above_average_conductor_penalty = ?,
-		r4r5_rank_boost = ?,
-		first_time_conductor_boost = ?,
-		schedule_message_template = ?,
-		daily_message_template = ?,
-		power_tracking_enabled = ?
-		WHERE id = 1`,
-		settings.AwardFirstPoints,
-		settings.AwardSecondPoints,
-		settings.AwardThirdPoints,
-		settings.RecommendationPoints,
-		settings.RecentConductorPenaltyDays,
-		settings.AboveAverageConductorPenalty,
-		settings.R4R5RankBoost,
-		settings.FirstTimeConductorBoost,
-		settings.ScheduleMessageTemplate,
-		settings.DailyMessageTemplate,
-		settings.PowerTrackingEnabled,
-	)
+	// Parse the date and get the week start (Monday)
+	scheduleDate, err := parseDate(input.StartDate)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, "Invalid date format", http.StatusBadRequest)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{"message": "Settings updated successfully"})
-}
-
-// Get member rankings with detailed score breakdown
-func getMemberRankings(w http.ResponseWriter, r *http.Request) {
-	// Always include all awards (active and inactive) - filtering is done on client side
+	weekStart := getMondayOfWeek(scheduleDate)
 
-	// Build ranking context using current date
-	now := time.Now()
-	ctx, err := buildRankingContext(now)
+	// Build ranking context
+	ctx, err := buildRankingContext(weekStart)
 	if err != nil {
 		http.Error(w, "Failed to load ranking context: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	// Get all members
-	rows, err := db.Query("SELECT id, name, rank FROM members ORDER BY name")
+	// Get all eligible members
+	rows, err := db.Query("SELECT id, name, rank, COALESCE(eligible, 1) FROM members WHERE COALESCE(eligible, 1) = 1 ORDER BY name")
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 	defer rows.Close()
 
-	var members []Member
+	var candidates []Member
 	for rows.Next() {
 		var m Member
-		if err := rows.Scan(&m.ID, &m.Name, &m.Rank); err != nil {
+		if err := rows.Scan(&m.ID, &m.Name, &m.Rank, &m.Eligible); err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
-		members = append(members, m)
+		candidates = append(candidates, m)
 	}
 
-	// Load all award details with expired flag
-	awardQuery := `
-		SELECT 
-			a.member_id, 
-			a.award_type, 
-			a.rank, 
-			a.week_date,
-			CASE 
-				WHEN EXISTS (
-					SELECT 1 FROM train_schedules ts
-					WHERE (ts.conductor_id = a.member_id OR (ts.backup_id = a.member_id AND ts.conductor_showed_up = 0))
-					AND ts.date >= a.week_date
-				) THEN 1
-				ELSE 0
-			END as expired
-		FROM awards a
-		ORDER BY a.week_date DESC, a.rank ASC
-	`
+	if len(candidates) < weeklyConductorSlots {
+		http.Error(w, "Not enough members for weekly scheduling (need at least 7)", http.StatusBadRequest)
+		return
+	}
+
+	// Score each candidate using the abstracted ranking system (sorted highest first)
+	scoredCandidates := scoreMembers(candidates, ctx)
+
+	// Pre-select top 7 performers as conductors for the week
+	plannedConductors := make(map[int]bool)
+	for i := 0; i < weeklyConductorSlots && i < len(scoredCandidates); i++ {
+		plannedConductors[scoredCandidates[i].Member.ID] = true
+	}
 
-	awardRows, err := db.Query(awardQuery)
+	te, err := loadTrainEvent()
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	defer awardRows.Close()
 
-	memberAwards := make(map[int][]AwardDetail)
-	for awardRows.Next() {
-		var memberID, rank, expired int
-		var awardType, weekDate string
-		if err := awardRows.Scan(&memberID, &awardType, &rank, &weekDate, &expired); err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-		points := 0
-		switch rank {
-		case 1:
-			points = ctx.Settings.AwardFirstPoints
-		case 2:
-			points = ctx.Settings.AwardSecondPoints
-		case 3:
-			points = ctx.Settings.AwardThirdPoints
-		}
-		memberAwards[memberID] = append(memberAwards[memberID], AwardDetail{
-			AwardType: awardType,
-			Rank:      rank,
-			Points:    points,
-			WeekDate:  weekDate,
-			Expired:   expired == 1,
-		})
+	tx, err := db.Begin()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
 	}
+	defer tx.Rollback()
 
-	// Calculate rankings for each member
-	var rankings []MemberRanking
-	for _, member := range members {
-		ranking := MemberRanking{
-			Member:         member,
-			AwardDetails:   memberAwards[member.ID],
-			ConductorCount: 0,
-		}
-
-		// Calculate award points
-		ranking.AwardPoints = ctx.AwardScoreMap[member.ID]
-
-		// Calculate recommendation points
-		recCount := ctx.RecommendationMap[member.ID]
-		ranking.RecommendationCount = recCount
-		ranking.RecommendationPoints = recCount * ctx.Settings.RecommendationPoints
-
-		// Apply rank boost for R4/R5 members (exponential based on days since last conductor)
-		if member.Rank == "R4" || member.Rank == "R5" {
-			baseBoost := float64(ctx.Settings.R4R5RankBoost)
-
-			// Calculate days since last conductor/backup duty
-			var daysSinceLastDuty int = 0
-			if stats, exists := ctx.ConductorStats[member.ID]; exists {
-				var mostRecentDate *time.Time
+	// Now schedule each day
+	var scheduleIDs []int
+	var weekSchedules []TrainSchedule
+	usedConductors := make(map[int]bool)
+	usedBackups := make(map[int]bool)
 
-				if stats.LastDate != nil {
-					if lastDate, err := parseDate(*stats.LastDate); err == nil {
-						mostRecentDate = &lastDate
-					}
-				}
+	for day := 0; day < 7; day++ {
+		currentDate := weekStart.AddDate(0, 0, day)
+		dateStr := formatDateString(currentDate)
 
-				// Check if backup usage was more recent
-				if stats.LastBackupUsed != nil {
-					if backupDate, err := parseDate(*stats.LastBackupUsed); err == nil {
-						if mostRecentDate == nil || backupDate.After(*mostRecentDate) {
-							mostRecentDate = &backupDate
-						}
-					}
-				}
+		// Select conductor from top 7 who hasn't been assigned yet
+		var conductorID int
+		var conductorScore int
 
-				if mostRecentDate != nil {
-					daysSinceLastDuty = int(now.Sub(*mostRecentDate).Hours() / 24)
-				}
+		for _, sc := range scoredCandidates {
+			if plannedConductors[sc.Member.ID] && !usedConductors[sc.Member.ID] {
+				conductorID = sc.Member.ID
+				conductorScore = sc.Score
+				usedConductors[sc.Member.ID] = true
+				break
 			}
+		}
 
-			// Exponential formula: base_boost * 2^(days/7)
-			multiplier := math.Pow(2, float64(daysSinceLastDuty)/7.0)
-			ranking.RankBoost = int(math.Round(baseBoost * multiplier))
+		if conductorID == 0 {
+			http.Error(w, "Unable to assign conductor for all days", http.StatusInternalServerError)
+			return
 		}
 
-		// Apply first time conductor boost if member has never been conductor
-		if stats, exists := ctx.ConductorStats[member.ID]; !exists || stats.Count == 0 {
-			// Calculate base score without first time boost
-			baseScore := ranking.AwardPoints + ranking.RecommendationPoints + ranking.RankBoost
-			if baseScore > 0 {
-				ranking.FirstTimeConductorBoost = ctx.Settings.FirstTimeConductorBoost
+		// Select backup (must be R4/R5, not a planned conductor, not already used as backup)
+		var availableBackups []Member
+		for _, sc := range scoredCandidates {
+			if !plannedConductors[sc.Member.ID] &&
+				!usedBackups[sc.Member.ID] &&
+				(sc.Member.Rank == "R4" || sc.Member.Rank == "R5") {
+				availableBackups = append(availableBackups, sc.Member)
 			}
 		}
 
-		// Get conductor stats
-		if stats, exists := ctx.ConductorStats[member.ID]; exists {
-			ranking.ConductorCount = stats.Count
-			ranking.LastConductorDate = stats.LastDate
+		var backupID int
+		if len(availableBackups) > 0 {
+			// Randomly select from available backups
+			randomIndex := time.Now().UnixNano() % int64(len(availableBackups))
+			backupID = availableBackups[randomIndex].ID
+			usedBackups[backupID] = true
+		}
 
-			// Calculate above average penalty
-			if float64(stats.Count) > ctx.AvgConductorCount {
-				ranking.AboveAveragePenalty = ctx.Settings.AboveAverageConductorPenalty
-			}
+		// If backupID is 0, no backup available - continue anyway and allow manual assignment
 
-			// Calculate recent conductor penalty - check both conductor date and backup used date
-			var mostRecentDate *time.Time
+		// Save the day's conductor and backup
+		scheduleID, err := saveTrainDay(tx, te, dateStr, conductorID, backupID, &conductorScore, nil, nil)
+		if err != nil {
+			http.Error(w, "Failed to create schedule: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		scheduleIDs = append(scheduleIDs, scheduleID)
+	}
 
-			if stats.LastDate != nil {
-				if lastDate, err := parseDate(*stats.LastDate); err == nil {
-					mostRecentDate = &lastDate
-				}
-			}
+	if err := tx.Commit(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
 
-			// If they stepped in as backup, check if that's more recent
-			if stats.LastBackupUsed != nil {
-				if backupDate, err := parseDate(*stats.LastBackupUsed); err == nil {
-					if mostRecentDate == nil || backupDate.After(*mostRecentDate) {
-						mostRecentDate = &backupDate
-					}
-				}
-			}
+	for _, scheduleID := range scheduleIDs {
+		// Get the full schedule details; days without an available backup leave it for manual assignment
+		var schedule TrainSchedule
+		var score sql.NullInt64
+		var backupID sql.NullInt64
+		var backupName sql.NullString
+		var backupRank sql.NullString
 
-			// Apply penalty based on most recent duty (conductor or backup usage)
-			if mostRecentDate != nil {
-				daysSince := int(now.Sub(*mostRecentDate).Hours() / 24)
-				ranking.DaysSinceLastConductor = &daysSince
-				penalty := ctx.Settings.RecentConductorPenaltyDays - daysSince
-				if penalty > 0 {
-					ranking.RecentConductorPenalty = penalty
-				}
-			}
+		err = db.QueryRow(`
+			SELECT 
+				ts.id, ts.date, ts.conductor_id, 
+				mc.name, ts.conductor_score, ts.backup_id, mb.name, mb.rank,
+				ts.conductor_showed_up, ts.notes, ts.created_at
+			FROM train_schedules ts
+			JOIN members mc ON ts.conductor_id = mc.id
+			LEFT JOIN members mb ON ts.backup_id = mb.id
+			WHERE ts.id = ?
+		`, scheduleID).Scan(
+			&schedule.ID, &schedule.Date, &schedule.ConductorID,
+			&schedule.ConductorName, &score, &backupID, &backupName,
+			&backupRank, &schedule.ConductorShowedUp, &schedule.Notes,
+			&schedule.CreatedAt,
+		)
+		if err != nil {
+			http.Error(w, "Failed to retrieve schedule: "+err.Error(), http.StatusInternalServerError)
+			return
 		}
 
-		// Calculate total score using the same abstracted function
-		ranking.TotalScore = calculateMemberScore(member, ctx)
-
-		rankings = append(rankings, ranking)
-	}
+		if backupID.Valid {
+			schedule.BackupID = int(backupID.Int64)
+			schedule.BackupName = backupName.String
+			schedule.BackupRank = backupRank.String
+		}
 
-	// Sort by total score (highest first)
-	for i := 0; i < len(rankings); i++ {
-		for j := i + 1; j < len(rankings); j++ {
-			if rankings[j].TotalScore > rankings[i].TotalScore {
-				rankings[i], rankings[j] = rankings[j], rankings[i]
-			}
+		if score.Valid {
+			scoreInt := int(score.Int64)
+			schedule.ConductorScore = &scoreInt
 		}
+
+		weekSchedules = append(weekSchedules, schedule)
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"rankings":                rankings,
-		"settings":                ctx.Settings,
-		"average_conductor_count": ctx.AvgConductorCount,
+		"message":   "Week scheduled successfully",
+		"schedules": weekSchedules,
 	})
 }
 
-// Get member point accumulation timelines over specified months
-func getMemberTimelines(w http.ResponseWriter, r *http.Request) {
-	// Parse months parameter (default to 3)
-	monthsParam := r.URL.Query().Get("months")
-	months := 3
-	if monthsParam != "" {
-		if m, err := strconv.Atoi(monthsParam); err == nil && m > 0 {
-			months = m
+// Helper function to parse date string
+func parseDate(dateStr string) (time.Time, error) {
+	return time.Parse("2006-01-02", dateStr)
+}
+
+// Helper function to format date to string
+func formatDateString(t time.Time) string {
+	return t.Format("2006-01-02")
+}
+
+// Helper function to get Monday of a week
+func getMondayOfWeek(date time.Time) time.Time {
+	offset := int(time.Monday - date.Weekday())
+	if offset > 0 {
+		offset = -6
+	}
+	return date.AddDate(0, 0, offset)
+}
+
+// defaultGameTimezone is Last War's server time (ST), UTC-2 all year
+const defaultGameTimezone = "Etc/GMT+2"
+
+// gameClock loads the game server's time zone and daily reset time (as an offset from midnight),
+// falling back to the defaults when the settings can't be read
+func gameClock() (*time.Location, time.Duration) {
+	timezone, resetTime := defaultGameTimezone, "00:00"
+	if db != nil {
+		if err := db.QueryRow("SELECT game_timezone, game_reset_time FROM settings WHERE id = 1").Scan(&timezone, &resetTime); err != nil {
+			log.Printf("Failed to load game time settings, using %s: %v", defaultGameTimezone, err)
 		}
 	}
 
-	// Calculate start date (N months ago from today)
-	now := time.Now()
-	startDate := now.AddDate(0, -months, 0)
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		log.Printf("Unknown game time zone %q, using %s: %v", timezone, defaultGameTimezone, err)
+		location, _ = time.LoadLocation(defaultGameTimezone)
+	}
+	reset, err := parseClock(resetTime)
+	if err != nil {
+		log.Printf("Invalid game reset time %q, using 00:00", resetTime)
+		reset = 0
+	}
+	return location, reset
+}
 
-	// Get all members
-	memberRows, err := db.Query("SELECT id, name, rank FROM members ORDER BY name")
+// gameToday returns the current game day as a date (like parseDate). The game day starts at the
+// daily reset in server time, not at midnight on the host running the app.
+func gameToday() time.Time {
+	location, reset := gameClock()
+	return gameDay(time.Now(), location, reset)
+}
+
+// gameDay returns the game day a moment falls on, for a server time zone and daily reset offset
+func gameDay(moment time.Time, location *time.Location, reset time.Duration) time.Time {
+	local := moment.In(location).Add(-reset)
+	return time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// parseClock parses a HH:MM time of day into the offset from midnight
+func parseClock(value string) (time.Duration, error) {
+	clock, err := time.Parse("15:04", value)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+		return 0, err
 	}
-	defer memberRows.Close()
+	return time.Duration(clock.Hour())*time.Hour + time.Duration(clock.Minute())*time.Minute, nil
+}
 
-	var members []Member
-	for memberRows.Next() {
-		var m Member
-		if err := memberRows.Scan(&m.ID, &m.Name, &m.Rank); err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
+// loadTimezones resolves a comma separated list of IANA time zones, skipping the ones that don't resolve
+func loadTimezones(value string) []*time.Location {
+	var locations []*time.Location
+	for _, name := range splitList(value) {
+		if location, err := time.LoadLocation(name); err == nil {
+			locations = append(locations, location)
 		}
-		members = append(members, m)
 	}
+	return locations
+}
 
-	// Get settings for point calculations
-	var settings Settings
-	err = db.QueryRow(`SELECT award_first_points, award_second_points, award_third_points, 
-		recommendation_points, r4r5_rank_boost, first_time_conductor_boost,
-		recent_conductor_penalty_days, above_average_conductor_penalty 
-		FROM settings WHERE id = 1`).Scan(
-		&settings.AwardFirstPoints, &settings.AwardSecondPoints,
-		&settings.AwardThirdPoints, &settings.RecommendationPoints,
-		&settings.R4R5RankBoost, &settings.FirstTimeConductorBoost,
-		&settings.RecentConductorPenaltyDays, &settings.AboveAverageConductorPenalty)
-	if err != nil {
-		http.Error(w, "Failed to load settings: "+err.Error(), http.StatusInternalServerError)
-		return
+// zoneLabel names the zone of a moment in messages: ST for Last War's server time, otherwise the
+// zone's abbreviation on that date (CET, BST), or its UTC offset when it has none
+func zoneLabel(moment time.Time) string {
+	if moment.Location().String() == defaultGameTimezone {
+		return "ST"
 	}
-
-	// Calculate average conductor count for above average penalty
-	var totalConductors, memberCount int
-	db.QueryRow(`
-		SELECT COUNT(DISTINCT conductor_id), 
-		       (SELECT COUNT(*) FROM members WHERE eligible = 1)
-		FROM train_schedules
-	`).Scan(&totalConductors, &memberCount)
-	avgConductorCount := 0.0
-	if memberCount > 0 {
-		avgConductorCount = float64(totalConductors) / float64(memberCount)
+	label := moment.Format("MST")
+	if strings.HasPrefix(label, "+") || strings.HasPrefix(label, "-") {
+		label = "UTC" + label
 	}
+	return label
+}
 
-	// Build timeline data for each member
-	timelines := make(map[int]map[string]interface{})
+// formatGameTime renders a server time on a game date followed by the same moment in each zone,
+// e.g. "15:00 ST / 17:00 GMT / 18:00 CET". Zone abbreviations follow daylight saving on that date,
+// and a zone where the moment falls on another day gets the weekday added. Server time is labelled
+// ST on the default game time zone and with its own abbreviation on any other.
+func formatGameTime(location *time.Location, date time.Time, clock string, zones []*time.Location) string {
+	offset, err := parseClock(clock)
+	if err != nil {
+		return clock
+	}
+	moment := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, location).Add(offset)
 
-	for _, member := range members {
-		// Get train schedules where member was conductor (to track resets)
-		conductorDates := []string{}
-		conductorRows, err := db.Query(`
-			SELECT date FROM train_schedules 
-			WHERE (conductor_id = ? OR (backup_id = ? AND conductor_showed_up = 0))
-			AND date >= ?
-			ORDER BY date ASC
-		`, member.ID, member.ID, formatDateString(startDate))
-		if err != nil {
+	parts := []string{moment.Format("15:04") + " " + zoneLabel(moment)}
+	for _, zone := range zones {
+		if zone.String() == location.String() {
 			continue
 		}
-		for conductorRows.Next() {
-			var dateStr string
-			if err := conductorRows.Scan(&dateStr); err == nil {
-				conductorDates = append(conductorDates, dateStr)
-			}
+		local := moment.In(zone)
+		part := local.Format("15:04") + " " + zoneLabel(local)
+		if local.Day() != moment.Day() {
+			part += " " + local.Format("Mon")
 		}
-		conductorRows.Close()
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, " / ")
+}
 
-		// Get all awards and recommendations since start date (tracked separately)
-		type PointEvent struct {
-			Date   string
-			Awards int
-			Recs   int
+// validateGameTimeSettings fills in the game time settings left empty and returns a message
+// when one of them is not valid
+func validateGameTimeSettings(settings *Settings) string {
+	if settings.GameTimezone == "" {
+		settings.GameTimezone = defaultGameTimezone
+	}
+	if _, err := time.LoadLocation(settings.GameTimezone); err != nil {
+		return fmt.Sprintf("Unknown game_timezone: %s", settings.GameTimezone)
+	}
+
+	clocks := []struct {
+		field string
+		value *string
+		def   string
+	}{
+		{"game_reset_time", &settings.GameResetTime, "00:00"},
+		{"train_conductor_time", &settings.TrainConductorTime, "15:00"},
+		{"train_backup_time", &settings.TrainBackupTime, "16:30"},
+	}
+	for _, clock := range clocks {
+		if *clock.value == "" {
+			*clock.value = clock.def
 		}
-		eventMap := make(map[string]*PointEvent)
+		if _, err := parseClock(*clock.value); err != nil {
+			return fmt.Sprintf("Invalid %s (expected HH:MM)", clock.field)
+		}
+	}
 
-		// Get awards
-		awardRows, err := db.Query(`
-			SELECT week_date, rank FROM awards 
-			WHERE member_id = ? AND week_date >= ?
-			ORDER BY week_date ASC
-		`, member.ID, formatDateString(startDate))
-		if err == nil {
-			for awardRows.Next() {
-				var weekDate string
-				var rank int
-				if err := awardRows.Scan(&weekDate, &rank); err == nil {
-					points := 0
-					switch rank {
-					case 1:
-						points = settings.AwardFirstPoints
-					case 2:
-						points = settings.AwardSecondPoints
-					case 3:
-						points = settings.AwardThirdPoints
-					}
-					if eventMap[weekDate] == nil {
-						eventMap[weekDate] = &PointEvent{Date: weekDate}
-					}
-					eventMap[weekDate].Awards += points
-				}
-			}
-			awardRows.Close()
+	zones := splitList(settings.DisplayTimezones)
+	for _, zone := range zones {
+		if _, err := time.LoadLocation(zone); err != nil {
+			return fmt.Sprintf("Unknown time zone in display_timezones: %s", zone)
 		}
+	}
+	settings.DisplayTimezones = strings.Join(zones, ",")
+	return ""
+}
 
-		// Get recommendations (calculate non-linear points: 5*sqrt(n))
-		recRows, err := db.Query(`
-			SELECT DATE(created_at) as rec_date, COUNT(*) as count
-			FROM recommendations 
-			WHERE member_id = ? AND DATE(created_at) >= ?
-			GROUP BY DATE(created_at)
-			ORDER BY rec_date ASC
-		`, member.ID, formatDateString(startDate))
-		if err == nil {
-			for recRows.Next() {
-				var recDate string
-				var count int
-				if err := recRows.Scan(&recDate, &count); err == nil {
-					// Non-linear formula: 5*sqrt(count)
+// Import members from CSV
+func importCSV(w http.ResponseWriter, r *http.Request) {
+	// Parse multipart form (10MB max)
+	err := r.ParseMultipartForm(10 << 20)
+	if err != nil {
+		http.Error(w, "Failed to parse form data", http.StatusBadRequest)
+		return
+	}
+
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, "Failed to read file", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	// Parse CSV
+	reader := csv.NewReader(file)
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1 // Allow variable number of fields
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		http.Error(w, "Failed to parse CSV: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if len(records) == 0 {
+		http.Error(w, "CSV file is empty", http.StatusBadRequest)
+		return
+	}
+
+	// Skip header row if it looks like a header
+	startIndex := 0
+	if len(records) > 0 {
+		firstRow := records[0]
+		if len(firstRow) > 0 {
+			firstCell := strings.ToLower(strings.TrimSpace(firstRow[0]))
+			// Check if first row is a header
+			if firstCell == "username" || firstCell == "name" || firstCell == "member" {
+				startIndex = 1
+			}
+		}
+	}
+
+	validRanks := map[string]bool{"R1": true, "R2": true, "R3": true, "R4": true, "R5": true}
+	detectedMembers := []DetectedMember{}
+	errors := []string{}
+
+	// Get existing members
+	existingMembers := make(map[string]Member)
+	rows, err := db.Query("SELECT id, name, rank FROM members")
+	if err == nil {
+		defer rows.Close()
+		for rows.Next() {
+			var m Member
+			rows.Scan(&m.ID, &m.Name, &m.Rank)
+			existingMembers[m.Name] = m
+		}
+	}
+
+	for i := startIndex; i < len(records); i++ {
+		record := records[i]
+
+		// New format: Username,Rank,Power,Level,Status,Last_Active
+		// We only care about Username (index 0) and Rank (index 1)
+		if len(record) < 2 {
+			errors = append(errors, fmt.Sprintf("Line %d: Insufficient columns (need at least Username and Rank)", i+1))
+			continue
+		}
+
+		name := strings.TrimSpace(record[0])
+		rank := strings.TrimSpace(record[1])
+
+		if name == "" {
+			errors = append(errors, fmt.Sprintf("Line %d: Empty username", i+1))
+			continue
+		}
+
+		if !validRanks[rank] {
+			errors = append(errors, fmt.Sprintf("Line %d: Invalid rank '%s' (must be R1-R5)", i+1, rank))
+			continue
+		}
+
+		detected := DetectedMember{
+			Name: name,
+			Rank: rank,
+		}
+
+		// Check if member exists
+		if existing, found := existingMembers[name]; found {
+			// Existing member - check for rank change
+			if existing.Rank != rank {
+				detected.RankChanged = true
+				detected.OldRank = existing.Rank
+			}
+		} else {
+			// New member - check for similar names in existing members
+			detected.IsNew = true
+			similarNames := []string{}
+			for existingName := range existingMembers {
+				if areSimilar(name, existingName) {
+					similarNames = append(similarNames, existingName)
+				}
+			}
+			if len(similarNames) > 0 {
+				detected.SimilarMatch = similarNames
+			}
+		}
+
+		detectedMembers = append(detectedMembers, detected)
+	}
+
+	// Find members that would be removed (in database but not in CSV)
+	membersToRemove := []MemberToRemove{}
+	csvNames := make(map[string]bool)
+	for _, m := range detectedMembers {
+		csvNames[m.Name] = true
+	}
+	for _, existing := range existingMembers {
+		if !csvNames[existing.Name] {
+			membersToRemove = append(membersToRemove, MemberToRemove{
+				ID:   existing.ID,
+				Name: existing.Name,
+				Rank: existing.Rank,
+			})
+		}
+	}
+
+	// Return preview data
+	result := map[string]interface{}{
+		"detected_members":  detectedMembers,
+		"members_to_remove": membersToRemove,
+		"errors":            errors,
+		"total_rows":        len(records) - startIndex,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// Confirm CSV import (reuses the same confirmMemberUpdates function)
+// The route will be /api/members/import/confirm
+
+// Get awards for a specific week or all weeks
+func getAwards(w http.ResponseWriter, r *http.Request) {
+	weekDate := r.URL.Query().Get("week")
+
+	var query string
+	var rows *sql.Rows
+	var err error
+
+	if weekDate != "" {
+		query = `
+			SELECT a.id, a.week_date, a.award_type, a.rank, a.member_id, m.name, a.created_at
+			FROM awards a
+			JOIN members m ON a.member_id = m.id
+			WHERE a.week_date = ?
+			ORDER BY a.award_type, a.rank
+		`
+		rows, err = db.Query(query, weekDate)
+	} else {
+		query = `
+			SELECT a.id, a.week_date, a.award_type, a.rank, a.member_id, m.name, a.created_at
+			FROM awards a
+			JOIN members m ON a.member_id = m.id
+			ORDER BY a.week_date DESC, a.award_type, a.rank
+		`
+		rows, err = db.Query(query)
+	}
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	awards := []Award{}
+	for rows.Next() {
+		var a Award
+		if err := rows.Scan(&a.ID, &a.WeekDate, &a.AwardType, &a.Rank, &a.MemberID, &a.MemberName, &a.CreatedAt); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		awards = append(awards, a)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(awards)
+}
+
+// Save awards for a week (bulk operation)
+func saveAwards(w http.ResponseWriter, r *http.Request) {
+	var data struct {
+		WeekDate string `json:"week_date"`
+		Awards   []struct {
+			AwardType string `json:"award_type"`
+			Rank      int    `json:"rank"`
+			MemberID  int    `json:"member_id"`
+		} `json:"awards"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Begin transaction
+	tx, err := db.Begin()
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	// Delete existing awards for this week
+	_, err = tx.Exec("DELETE FROM awards WHERE week_date = ?", data.WeekDate)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, "Failed to clear existing awards", http.StatusInternalServerError)
+		return
+	}
+
+	// Insert new awards
+	for _, award := range data.Awards {
+		if award.MemberID > 0 { // Only insert if a member is selected
+			_, err = tx.Exec(
+				"INSERT INTO awards (week_date, award_type, rank, member_id) VALUES (?, ?, ?, ?)",
+				data.WeekDate, award.AwardType, award.Rank, award.MemberID)
+			if err != nil {
+				tx.Rollback()
+				http.Error(w, "Failed to save award", http.StatusInternalServerError)
+				return
+			}
+		}
+	}
+
+	// Commit transaction
+	err = tx.Commit()
+	if err != nil {
+		http.Error(w, "Failed to save changes", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Awards saved successfully"})
+}
+
+// Delete awards for a specific week
+func deleteWeekAwards(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	weekDate := vars["week"]
+
+	_, err := db.Exec("DELETE FROM awards WHERE week_date = ?", weekDate)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Get VS points for a week or all weeks
+func getVSPoints(w http.ResponseWriter, r *http.Request) {
+	weekDate := r.URL.Query().Get("week")
+	eventType, err := parseVSEventType(r.URL.Query().Get("event_type"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Pivot the per-day rows back into one row per member and week
+	query := `
+		SELECT MIN(v.id), v.member_id, v.week_date, v.event_type,
+		       SUM(CASE WHEN v.day = 'monday' THEN v.points ELSE 0 END),
+		       SUM(CASE WHEN v.day = 'tuesday' THEN v.points ELSE 0 END),
+		       SUM(CASE WHEN v.day = 'wednesday' THEN v.points ELSE 0 END),
+		       SUM(CASE WHEN v.day = 'thursday' THEN v.points ELSE 0 END),
+		       SUM(CASE WHEN v.day = 'friday' THEN v.points ELSE 0 END),
+		       SUM(CASE WHEN v.day = 'saturday' THEN v.points ELSE 0 END),
+		       SUM(CASE WHEN v.day = 'sunday' THEN v.points ELSE 0 END),
+		       MIN(v.created_at), MAX(v.updated_at),
+		       m.name, m.rank
+		FROM vs_points v
+		JOIN members m ON v.member_id = m.id
+		WHERE v.event_type = ? AND (? = '' OR v.week_date = ?)
+		GROUP BY v.member_id, v.week_date, v.event_type
+		ORDER BY v.week_date DESC, m.name
+	`
+	rows, err := db.Query(query, eventType, weekDate, weekDate)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	vsPoints := []VSPointsWithMember{}
+	for rows.Next() {
+		var v VSPointsWithMember
+		if err := rows.Scan(&v.ID, &v.MemberID, &v.WeekDate, &v.EventType, &v.Monday, &v.Tuesday,
+			&v.Wednesday, &v.Thursday, &v.Friday, &v.Saturday, &v.Sunday, &v.CreatedAt, &v.UpdatedAt,
+			&v.MemberName, &v.MemberRank); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		vsPoints = append(vsPoints, v)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(vsPoints)
+}
+
+// parseVSEventType validates an event type name, defaulting to the Alliance Duel
+func parseVSEventType(eventType string) (string, error) {
+	eventType = strings.ToLower(strings.TrimSpace(eventType))
+	if eventType == "" {
+		return vsEventAllianceDuel, nil
+	}
+	if len(eventType) > 50 {
+		return "", fmt.Errorf("event_type is too long")
+	}
+	for _, c := range eventType {
+		if (c < 'a' || c > 'z') && (c < '0' || c > '9') && c != '_' {
+			return "", fmt.Errorf("event_type may only contain letters, numbers and underscores")
+		}
+	}
+	return eventType, nil
+}
+
+// upsertVSPoints stores one member's points for one day of an event.
+// Alliance Duel days are always stored so zero days are on record; other days only once they have points.
+func upsertVSPoints(tx *sql.Tx, memberID int, weekDate, day, eventType string, points int64) error {
+	isDuelDay := false
+	for _, d := range vsDuelDays {
+		if d == day {
+			isDuelDay = true
+			break
+		}
+	}
+
+	if points == 0 && !(isDuelDay && eventType == vsEventAllianceDuel) {
+		_, err := tx.Exec(`
+			UPDATE vs_points SET points = 0, updated_at = CURRENT_TIMESTAMP
+			WHERE member_id = ? AND week_date = ? AND day = ? AND event_type = ?`,
+			memberID, weekDate, day, eventType)
+		return err
+	}
+
+	_, err := tx.Exec(`
+		INSERT INTO vs_points (member_id, week_date, day, event_type, points, updated_at)
+		VALUES (?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT(member_id, week_date, day, event_type)
+		DO UPDATE SET points = excluded.points, updated_at = CURRENT_TIMESTAMP`,
+		memberID, weekDate, day, eventType, points)
+	return err
+}
+
+// Save VS points for a week (bulk operation)
+func saveVSPoints(w http.ResponseWriter, r *http.Request) {
+	var data struct {
+		WeekDate  string `json:"week_date"`
+		EventType string `json:"event_type"`
+		Points    []struct {
+			MemberID  int   `json:"member_id"`
+			Monday    int64 `json:"monday"`
+			Tuesday   int64 `json:"tuesday"`
+			Wednesday int64 `json:"wednesday"`
+			Thursday  int64 `json:"thursday"`
+			Friday    int64 `json:"friday"`
+			Saturday  int64 `json:"saturday"`
+			Sunday    int64 `json:"sunday"`
+		} `json:"points"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	eventType, err := parseVSEventType(data.EventType)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Begin transaction
+	tx, err := db.Begin()
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	// Upsert VS points for each member and day
+	for _, point := range data.Points {
+		dailyPoints := []int64{point.Monday, point.Tuesday, point.Wednesday, point.Thursday,
+			point.Friday, point.Saturday, point.Sunday}
+		for i, day := range vsDays {
+			err = upsertVSPoints(tx, point.MemberID, data.WeekDate, day, eventType, dailyPoints[i])
+			if err != nil {
+				tx.Rollback()
+				http.Error(w, "Failed to save VS points", http.StatusInternalServerError)
+				return
+			}
+		}
+	}
+
+	// Commit transaction
+	err = tx.Commit()
+	if err != nil {
+		http.Error(w, "Failed to save changes", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "VS points saved successfully"})
+}
+
+// Delete VS points for a specific week
+func deleteWeekVSPoints(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	weekDate := vars["week"]
+	eventType, err := parseVSEventType(r.URL.Query().Get("event_type"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, err = db.Exec("DELETE FROM vs_points WHERE week_date = ? AND event_type = ?", weekDate, eventType)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// loadVSRequirements loads the minimum daily VS points in day order
+func loadVSRequirements() ([]VSRequirement, error) {
+	rows, err := db.Query("SELECT day, theme, min_points FROM vs_requirements")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	byDay := make(map[string]VSRequirement)
+	for rows.Next() {
+		var req VSRequirement
+		if err := rows.Scan(&req.Day, &req.Theme, &req.MinPoints); err != nil {
+			return nil, err
+		}
+		byDay[req.Day] = req
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	requirements := make([]VSRequirement, 0, len(vsDuelDays))
+	for _, day := range vsDuelDays {
+		if req, exists := byDay[day]; exists {
+			requirements = append(requirements, req)
+		}
+	}
+	return requirements, nil
+}
+
+// vsDayIndex returns the position of a day in the VS week (0 = Monday)
+func vsDayIndex(day string) int {
+	for i, d := range vsDays {
+		if d == day {
+			return i
+		}
+	}
+	return len(vsDays)
+}
+
+// vsDayName returns the display name for a vs_points day (e.g. "monday" -> "Monday")
+func vsDayName(day string) string {
+	if i := vsDayIndex(day); i < len(vsDays) {
+		return time.Weekday((int(time.Monday) + i) % 7).String()
+	}
+	return day
+}
+
+// Get VS daily requirements
+func getVSRequirements(w http.ResponseWriter, r *http.Request) {
+	requirements, err := loadVSRequirements()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(requirements)
+}
+
+// Update VS daily requirements (R5/Admin only)
+func updateVSRequirements(w http.ResponseWriter, r *http.Request) {
+	var requirements []VSRequirement
+	if err := json.NewDecoder(r.Body).Decode(&requirements); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	for _, req := range requirements {
+		valid := false
+		for _, day := range vsDuelDays {
+			if req.Day == day {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			http.Error(w, fmt.Sprintf("Invalid day: %s. Must be monday-saturday", req.Day), http.StatusBadRequest)
+			return
+		}
+		if strings.TrimSpace(req.Theme) == "" {
+			http.Error(w, "Theme is required for "+req.Day, http.StatusBadRequest)
+			return
+		}
+		if req.MinPoints < 0 {
+			http.Error(w, "Minimum points cannot be negative", http.StatusBadRequest)
+			return
+		}
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	for _, req := range requirements {
+		_, err = tx.Exec(`
+			INSERT INTO vs_requirements (day, theme, min_points, updated_at)
+			VALUES (?, ?, ?, CURRENT_TIMESTAMP)
+			ON CONFLICT(day) DO UPDATE SET theme = excluded.theme, min_points = excluded.min_points, updated_at = CURRENT_TIMESTAMP`,
+			req.Day, strings.TrimSpace(req.Theme), req.MinPoints)
+		if err != nil {
+			tx.Rollback()
+			http.Error(w, "Failed to save VS requirements", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Failed to save changes", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "VS requirements updated successfully"})
+}
+
+// vsComplianceLookbackWeeks is how many earlier weeks are checked to continue missed-day streaks
+const vsComplianceLookbackWeeks = 8
+
+// buildVSComplianceReport checks every member against the daily minimums for a week.
+// Only days the alliance has points for count, so a week in progress isn't flagged for days not yet played.
+func buildVSComplianceReport(weekStart time.Time) (*VSComplianceReport, error) {
+	requirements, err := loadVSRequirements()
+	if err != nil {
+		return nil, err
+	}
+	minPoints := make(map[string]int)
+	for _, req := range requirements {
+		minPoints[req.Day] = req.MinPoints
+	}
+
+	members, err := db.Query("SELECT id, name, rank FROM members ORDER BY name")
+	if err != nil {
+		return nil, err
+	}
+	var allMembers []Member
+	for members.Next() {
+		var m Member
+		if err := members.Scan(&m.ID, &m.Name, &m.Rank); err != nil {
+			members.Close()
+			return nil, err
+		}
+		allMembers = append(allMembers, m)
+	}
+	members.Close()
+
+	// Load the target week plus earlier weeks for streaks
+	firstWeek := weekStart.AddDate(0, 0, -7*vsComplianceLookbackWeeks)
+	rows, err := db.Query(`
+		SELECT member_id, week_date, day, points
+		FROM vs_points
+		WHERE event_type = ? AND week_date >= ? AND week_date <= ?
+	`, vsEventAllianceDuel, formatDateString(firstWeek), formatDateString(weekStart))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	weekPoints := make(map[string]map[int]map[string]int) // week -> memberID -> day -> points
+	dayPlayed := make(map[string]map[string]bool)         // week -> days the alliance has points for
+	for rows.Next() {
+		var memberID, points int
+		var weekDate, day string
+		if err := rows.Scan(&memberID, &weekDate, &day, &points); err != nil {
+			return nil, err
+		}
+		if weekPoints[weekDate] == nil {
+			weekPoints[weekDate] = make(map[int]map[string]int)
+			dayPlayed[weekDate] = make(map[string]bool)
+		}
+		if weekPoints[weekDate][memberID] == nil {
+			weekPoints[weekDate][memberID] = make(map[string]int)
+		}
+		weekPoints[weekDate][memberID][day] = points
+		if points > 0 {
+			dayPlayed[weekDate][day] = true
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	weekDate := formatDateString(weekStart)
+	report := &VSComplianceReport{
+		WeekDate:     weekDate,
+		Requirements: requirements,
+		TotalMembers: len(allMembers),
+		Members:      []VSComplianceMember{},
+	}
+
+	for _, member := range allMembers {
+		result := VSComplianceMember{
+			MemberID:   member.ID,
+			MemberName: member.Name,
+			MemberRank: member.Rank,
+		}
+
+		// Walk all played days oldest first; a streak is consecutive days below the minimum
+		streak := 0
+		for week := firstWeek; !week.After(weekStart); week = week.AddDate(0, 0, 7) {
+			wd := formatDateString(week)
+			days := weekPoints[wd][member.ID]
+			played := dayPlayed[wd]
+			for _, day := range vsDuelDays {
+				min := minPoints[day]
+				if !played[day] || min == 0 {
+					continue
+				}
+				met := days[day] >= min
+				if met {
+					streak = 0
+				} else {
+					streak++
+				}
+
+				if wd == weekDate {
+					if !met {
+						result.MissedDays++
+					}
+					if streak > result.LongestStreak && !met {
+						result.LongestStreak = streak
+					}
+				}
+			}
+		}
+		result.CurrentStreak = streak
+
+		days := weekPoints[weekDate][member.ID]
+		played := dayPlayed[weekDate]
+		for _, day := range vsDuelDays {
+			min := minPoints[day]
+			result.Total += days[day]
+			result.Days = append(result.Days, VSComplianceDay{
+				Day:       day,
+				Points:    days[day],
+				MinPoints: min,
+				Met:       !played[day] || days[day] >= min,
+				Played:    played[day],
+			})
+		}
+
+		if result.MissedDays == 0 {
+			report.CompliantCount++
+			continue
+		}
+		report.Members = append(report.Members, result)
+	}
+
+	// Worst offenders first
+	sort.SliceStable(report.Members, func(i, j int) bool {
+		if report.Members[i].MissedDays != report.Members[j].MissedDays {
+			return report.Members[i].MissedDays > report.Members[j].MissedDays
+		}
+		return report.Members[i].CurrentStreak > report.Members[j].CurrentStreak
+	})
+
+	return report, nil
+}
+
+// parseVSWeekParam reads ?week=YYYY-MM-DD (defaults to the current week) and returns its Monday
+func parseVSWeekParam(r *http.Request) (time.Time, error) {
+	weekParam := r.URL.Query().Get("week")
+	if weekParam == "" {
+		return getMondayOfWeek(gameToday()), nil
+	}
+	weekDate, err := parseDate(weekParam)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return getMondayOfWeek(weekDate), nil
+}
+
+// Get VS minimum requirement compliance for a week
+func getVSCompliance(w http.ResponseWriter, r *http.Request) {
+	weekStart, err := parseVSWeekParam(r)
+	if err != nil {
+		http.Error(w, "Invalid week date format (expected YYYY-MM-DD)", http.StatusBadRequest)
+		return
+	}
+
+	report, err := buildVSComplianceReport(weekStart)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// Generate VS minimum warning message for a week
+func generateVSWarningMessage(w http.ResponseWriter, r *http.Request) {
+	weekStart, err := parseVSWeekParam(r)
+	if err != nil {
+		http.Error(w, "Invalid week date format (expected YYYY-MM-DD)", http.StatusBadRequest)
+		return
+	}
+
+	report, err := buildVSComplianceReport(weekStart)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	data := vsWarningMessageData{
+		Week:         formatDateString(weekStart),
+		Requirements: []vsWarningRequirement{},
+		Members:      []vsWarningMember{},
+		Count:        len(report.Members),
+	}
+
+	// Build requirements list
+	for _, req := range report.Requirements {
+		if req.MinPoints == 0 {
+			continue
+		}
+		data.Requirements = append(data.Requirements, vsWarningRequirement{
+			Day:       vsDayName(req.Day),
+			Theme:     req.Theme,
+			MinPoints: req.MinPoints,
+		})
+	}
+
+	// Build members list
+	for _, member := range report.Members {
+		missed := []string{}
+		for _, day := range member.Days {
+			if !day.Met {
+				missed = append(missed, vsDayName(day.Day)[:3])
+			}
+		}
+		data.Members = append(data.Members, vsWarningMember{
+			Name:   member.MemberName,
+			Rank:   member.MemberRank,
+			Missed: missed,
+			Streak: member.CurrentStreak,
+		})
+	}
+
+	message, err := renderKindMessage("vs_warning", data)
+	if err != nil {
+		http.Error(w, "Failed to render template: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": message,
+		"count":   len(report.Members),
+	})
+}
+
+// parseVSAnalyticsParams reads ?weeks=N (default 8, max 52) and ?end=YYYY-MM-DD (defaults to the latest week with data)
+func parseVSAnalyticsParams(r *http.Request) (string, int, error) {
+	weeks := 8
+	if weeksParam := r.URL.Query().Get("weeks"); weeksParam != "" {
+		n, err := strconv.Atoi(weeksParam)
+		if err != nil || n < 1 {
+			return "", 0, fmt.Errorf("weeks must be a positive number")
+		}
+		if n > 52 {
+			n = 52
+		}
+		weeks = n
+	}
+
+	endParam := r.URL.Query().Get("end")
+	if endParam == "" {
+		var latest sql.NullString
+		if err := db.QueryRow("SELECT MAX(week_date) FROM vs_points WHERE event_type = ?", vsEventAllianceDuel).Scan(&latest); err != nil {
+			return "", 0, err
+		}
+		return latest.String, weeks, nil
+	}
+	endDate, err := parseDate(endParam)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid end date format (expected YYYY-MM-DD)")
+	}
+	return formatDateString(getMondayOfWeek(endDate)), weeks, nil
+}
+
+// loadVSWeeklyTrends aggregates weekly VS totals, ranks and percentiles for the last N weeks with data
+// up to endWeek. Movement is measured against the previous week with data, so the first week of the
+// range still gets a delta.
+func loadVSWeeklyTrends(endWeek string, weeks int) ([]VSWeeklyTrend, error) {
+	rows, err := db.Query(`
+		WITH weeks AS (
+			SELECT week_date, ROW_NUMBER() OVER (ORDER BY week_date DESC) AS week_num
+			FROM (SELECT DISTINCT week_date FROM vs_points WHERE event_type = ? AND week_date <= ?)
+		),
+		totals AS (
+			SELECT v.member_id, v.week_date, w.week_num, SUM(v.points) AS total
+			FROM vs_points v
+			JOIN weeks w ON w.week_date = v.week_date
+			WHERE v.event_type = ? AND w.week_num <= ? + 1
+			GROUP BY v.member_id, v.week_date
+		),
+		ranked AS (
+			SELECT member_id, week_date, week_num, total,
+				RANK() OVER (PARTITION BY week_date ORDER BY total DESC) AS week_rank,
+				PERCENT_RANK() OVER (PARTITION BY week_date ORDER BY total) AS percentile
+			FROM totals
+		),
+		moves AS (
+			SELECT *,
+				LAG(week_num) OVER (PARTITION BY member_id ORDER BY week_date) AS prev_week_num,
+				LAG(total) OVER (PARTITION BY member_id ORDER BY week_date) AS prev_total,
+				LAG(week_rank) OVER (PARTITION BY member_id ORDER BY week_date) AS prev_rank
+			FROM ranked
+		)
+		SELECT mv.member_id, m.name, m.rank, mv.week_date, mv.total, mv.week_rank, mv.percentile,
+			CASE WHEN mv.prev_week_num = mv.week_num + 1 THEN mv.prev_total END,
+			CASE WHEN mv.prev_week_num = mv.week_num + 1 THEN mv.prev_rank END
+		FROM moves mv
+		JOIN members m ON m.id = mv.member_id
+		WHERE mv.week_num <= ?
+		ORDER BY mv.week_date, mv.week_rank, m.name
+	`, vsEventAllianceDuel, endWeek, vsEventAllianceDuel, weeks, weeks)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	trends := []VSWeeklyTrend{}
+	for rows.Next() {
+		var t VSWeeklyTrend
+		var prevTotal, prevRank sql.NullInt64
+		if err := rows.Scan(&t.MemberID, &t.MemberName, &t.MemberRank, &t.WeekDate, &t.Total,
+			&t.WeekRank, &t.Percentile, &prevTotal, &prevRank); err != nil {
+			return nil, err
+		}
+		if prevTotal.Valid {
+			previous := int(prevTotal.Int64)
+			delta := t.Total - previous
+			rankChange := int(prevRank.Int64) - t.WeekRank
+			t.PreviousTotal = &previous
+			t.Delta = &delta
+			t.RankChange = &rankChange
+		}
+		trends = append(trends, t)
+	}
+	return trends, rows.Err()
+}
+
+// Get per-member weekly VS totals, ranks and percentile history
+func getVSMemberTrends(w http.ResponseWriter, r *http.Request) {
+	endWeek, weeks, err := parseVSAnalyticsParams(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	trends, err := loadVSWeeklyTrends(endWeek, weeks)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	type memberTrend struct {
+		MemberID     int             `json:"member_id"`
+		MemberName   string          `json:"member_name"`
+		MemberRank   string          `json:"member_rank"`
+		AverageTotal float64         `json:"average_total"`
+		Weeks        []VSWeeklyTrend `json:"weeks"`
+	}
+
+	// Group by member, keeping the best current performers first
+	weekDates := []string{}
+	seenWeeks := make(map[string]bool)
+	byMember := make(map[int]*memberTrend)
+	var order []int
+	for _, t := range trends {
+		if !seenWeeks[t.WeekDate] {
+			seenWeeks[t.WeekDate] = true
+			weekDates = append(weekDates, t.WeekDate)
+		}
+		mt, exists := byMember[t.MemberID]
+		if !exists {
+			mt = &memberTrend{MemberID: t.MemberID, MemberName: t.MemberName, MemberRank: t.MemberRank}
+			byMember[t.MemberID] = mt
+			order = append(order, t.MemberID)
+		}
+		mt.Weeks = append(mt.Weeks, t)
+	}
+
+	members := make([]memberTrend, 0, len(order))
+	for _, id := range order {
+		mt := byMember[id]
+		sum := 0
+		for _, t := range mt.Weeks {
+			sum += t.Total
+		}
+		mt.AverageTotal = float64(sum) / float64(len(mt.Weeks))
+		members = append(members, *mt)
+	}
+	sort.SliceStable(members, func(i, j int) bool {
+		return members[i].AverageTotal > members[j].AverageTotal
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"end_week": endWeek,
+		"weeks":    weekDates,
+		"members":  members,
+	})
+}
+
+// Get alliance average and median VS points per day.
+// Only members with points for a day count, so unplayed days don't drag the numbers down.
+func getVSDayStats(w http.ResponseWriter, r *http.Request) {
+	endWeek, weeks, err := parseVSAnalyticsParams(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	rows, err := db.Query(`
+		WITH weeks AS (
+			SELECT DISTINCT week_date FROM vs_points
+			WHERE event_type = ? AND week_date <= ?
+			ORDER BY week_date DESC LIMIT ?
+		),
+		ordered AS (
+			SELECT v.week_date, v.day, v.points,
+				ROW_NUMBER() OVER (PARTITION BY v.week_date, v.day ORDER BY v.points) AS rn,
+				COUNT(*) OVER (PARTITION BY v.week_date, v.day) AS cnt
+			FROM vs_points v
+			JOIN weeks w ON w.week_date = v.week_date
+			WHERE v.event_type = ? AND v.points > 0
+		)
+		SELECT week_date, day, COUNT(*), AVG(points),
+			AVG(CASE WHEN rn IN ((cnt + 1) / 2, (cnt + 2) / 2) THEN points END),
+			MAX(points), SUM(points)
+		FROM ordered
+		GROUP BY week_date, day
+	`, vsEventAllianceDuel, endWeek, weeks, vsEventAllianceDuel)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	stats := []VSDayStat{}
+	for rows.Next() {
+		var stat VSDayStat
+		if err := rows.Scan(&stat.WeekDate, &stat.Day, &stat.Participants, &stat.Average,
+			&stat.Median, &stat.Max, &stat.Total); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		stats = append(stats, stat)
+	}
+
+	// Order by week, then by day of the week
+	sort.SliceStable(stats, func(i, j int) bool {
+		if stats[i].WeekDate != stats[j].WeekDate {
+			return stats[i].WeekDate < stats[j].WeekDate
+		}
+		return vsDayIndex(stats[i].Day) < vsDayIndex(stats[j].Day)
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"end_week": endWeek,
+		"days":     stats,
+	})
+}
+
+// Get the top VS risers and fallers week-over-week
+func getVSMovers(w http.ResponseWriter, r *http.Request) {
+	endWeek, _, err := parseVSAnalyticsParams(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	limit := 5
+	if limitParam := r.URL.Query().Get("limit"); limitParam != "" {
+		if n, err := strconv.Atoi(limitParam); err == nil && n > 0 {
+			limit = n
+		}
+	}
+
+	trends, err := loadVSWeeklyTrends(endWeek, 1)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Only members with a previous week can move
+	var moved []VSWeeklyTrend
+	for _, t := range trends {
+		if t.Delta != nil {
+			moved = append(moved, t)
+		}
+	}
+	sort.SliceStable(moved, func(i, j int) bool {
+		return *moved[i].Delta > *moved[j].Delta
+	})
+
+	risers := []VSWeeklyTrend{}
+	for _, t := range moved {
+		if *t.Delta <= 0 || len(risers) == limit {
+			break
+		}
+		risers = append(risers, t)
+	}
+	fallers := []VSWeeklyTrend{}
+	for i := len(moved) - 1; i >= 0; i-- {
+		if *moved[i].Delta >= 0 || len(fallers) == limit {
+			break
+		}
+		fallers = append(fallers, moved[i])
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"week_date": endWeek,
+		"risers":    risers,
+		"fallers":   fallers,
+	})
+}
+
+// loadInactivitySettings loads the inactivity report weights
+func loadInactivitySettings() (InactivitySettings, error) {
+	var settings InactivitySettings
+	err := db.QueryRow(`SELECT lookback_weeks, vs_zero_day_points, stagnant_power_points, stagnation_snapshots,
+		no_train_duty_points, no_show_points, no_login_points, no_login_days, flag_score
+		FROM inactivity_settings WHERE id = 1`).Scan(
+		&settings.LookbackWeeks,
+		&settings.VSZeroDayPoints,
+		&settings.StagnantPowerPoints,
+		&settings.StagnationSnapshots,
+		&settings.NoTrainDutyPoints,
+		&settings.NoShowPoints,
+		&settings.NoLoginPoints,
+		&settings.NoLoginDays,
+		&settings.FlagScore,
+	)
+	return settings, err
+}
+
+// Get the inactivity report weights
+func getInactivitySettings(w http.ResponseWriter, r *http.Request) {
+	settings, err := loadInactivitySettings()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(settings)
+}
+
+// Update the inactivity report weights (R5/Admin only)
+func updateInactivitySettings(w http.ResponseWriter, r *http.Request) {
+	var settings InactivitySettings
+	if err := json.NewDecoder(r.Body).Decode(&settings); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if settings.LookbackWeeks < 1 || settings.LookbackWeeks > 26 {
+		http.Error(w, "lookback_weeks must be between 1 and 26", http.StatusBadRequest)
+		return
+	}
+	if settings.StagnationSnapshots < 2 {
+		http.Error(w, "stagnation_snapshots must be at least 2", http.StatusBadRequest)
+		return
+	}
+	if settings.NoLoginDays < 1 {
+		http.Error(w, "no_login_days must be at least 1", http.StatusBadRequest)
+		return
+	}
+	if settings.VSZeroDayPoints < 0 || settings.StagnantPowerPoints < 0 || settings.NoTrainDutyPoints < 0 ||
+		settings.NoShowPoints < 0 || settings.NoLoginPoints < 0 || settings.FlagScore < 0 {
+		http.Error(w, "Points cannot be negative", http.StatusBadRequest)
+		return
+	}
+
+	_, err := db.Exec(`UPDATE inactivity_settings SET
+		lookback_weeks = ?,
+		vs_zero_day_points = ?,
+		stagnant_power_points = ?,
+		stagnation_snapshots = ?,
+		no_train_duty_points = ?,
+		no_show_points = ?,
+		no_login_points = ?,
+		no_login_days = ?,
+		flag_score = ?,
+		updated_at = CURRENT_TIMESTAMP
+		WHERE id = 1`,
+		settings.LookbackWeeks,
+		settings.VSZeroDayPoints,
+		settings.StagnantPowerPoints,
+		settings.StagnationSnapshots,
+		settings.NoTrainDutyPoints,
+		settings.NoShowPoints,
+		settings.NoLoginPoints,
+		settings.NoLoginDays,
+		settings.FlagScore,
+	)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Inactivity settings updated"})
+}
+
+// buildInactivityReport scores every member on VS zero days, unchanged power, train duty,
+// train no-shows and last login over the lookback weeks up to asOf, highest score first
+func buildInactivityReport(asOf time.Time) (InactivityReport, error) {
+	settings, err := loadInactivitySettings()
+	if err != nil {
+		return InactivityReport{}, err
+	}
+
+	from := getMondayOfWeek(asOf).AddDate(0, 0, -7*(settings.LookbackWeeks-1))
+	fromStr := formatDateString(from)
+	asOfStr := formatDateString(asOf)
+	report := InactivityReport{AsOf: asOfStr, From: fromStr, Settings: settings, Members: []InactivityMember{}}
+
+	rows, err := db.Query(`
+		SELECT m.id, m.name, m.rank,
+			EXISTS (SELECT 1 FROM users u WHERE u.member_id = m.id),
+			(SELECT MAX(ls.login_time) FROM login_sessions ls
+				JOIN users u ON u.id = ls.user_id
+				WHERE u.member_id = m.id AND ls.success = 1 AND DATE(ls.login_time) <= ?),
+			(SELECT COUNT(*) FROM train_schedules ts
+				WHERE (ts.conductor_id = m.id OR ts.backup_id = m.id) AND ts.date BETWEEN ? AND ?),
+			(SELECT COUNT(*) FROM train_schedules ts
+				WHERE ts.conductor_id = m.id AND ts.conductor_showed_up = 0 AND ts.date BETWEEN ? AND ?)
+		FROM members m
+		ORDER BY m.name
+	`, asOfStr, fromStr, asOfStr, fromStr, asOfStr)
+	if err != nil {
+		return report, err
+	}
+	for rows.Next() {
+		var m InactivityMember
+		var lastLogin sql.NullString
+		if err := rows.Scan(&m.MemberID, &m.MemberName, &m.MemberRank, &m.HasAccount, &lastLogin,
+			&m.TrainDuties, &m.NoShows); err != nil {
+			rows.Close()
+			return report, err
+		}
+		if lastLogin.Valid {
+			m.LastLogin = &lastLogin.String
+		}
+		report.Members = append(report.Members, m)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return report, err
+	}
+
+	// VS days count once the alliance has points for them, so unplayed days are not held against anyone.
+	// In the week of asOf only the days up to asOf count.
+	asOfDays := vsDays[:vsDayIndex(strings.ToLower(asOf.Weekday().String()))+1]
+	args := []interface{}{vsEventAllianceDuel, fromStr, asOfStr, formatDateString(getMondayOfWeek(asOf))}
+	placeholders := []string{}
+	for _, day := range asOfDays {
+		args = append(args, day)
+		placeholders = append(placeholders, "?")
+	}
+	args = append(args, vsEventAllianceDuel)
+	vsDayRows, err := db.Query(`
+		WITH played AS (
+			SELECT DISTINCT week_date, day FROM vs_points
+			WHERE event_type = ? AND points > 0 AND week_date BETWEEN ? AND ?
+				AND (week_date < ? OR day IN (`+strings.Join(placeholders, ", ")+`))
+		)
+		SELECT m.id, COUNT(p.day),
+			COUNT(CASE WHEN COALESCE(v.points, 0) = 0 THEN 1 END)
+		FROM members m
+		CROSS JOIN played p
+		LEFT JOIN vs_points v ON v.member_id = m.id AND v.week_date = p.week_date AND v.day = p.day AND v.event_type = ?
+		GROUP BY m.id
+	`, args...)
+	if err != nil {
+		return report, err
+	}
+	type vsDayCounts struct{ played, zero int }
+	vsByMember := make(map[int]vsDayCounts)
+	for vsDayRows.Next() {
+		var memberID int
+		var days vsDayCounts
+		if err := vsDayRows.Scan(&memberID, &days.played, &days.zero); err != nil {
+			vsDayRows.Close()
+			return report, err
+		}
+		vsByMember[memberID] = days
+	}
+	vsDayRows.Close()
+
+	stagnant, err := loadPowerStagnation(asOf, settings.StagnationSnapshots)
+	if err != nil {
+		return report, err
+	}
+	stagnantByMember := make(map[int]PowerStagnation)
+	for _, s := range stagnant {
+		stagnantByMember[s.MemberID] = s
+	}
+
+	for i := range report.Members {
+		m := &report.Members[i]
+		m.Reasons = []InactivityReason{}
+		addReason := func(signal, detail string, points int) {
+			if points > 0 {
+				m.Reasons = append(m.Reasons, InactivityReason{Signal: signal, Detail: detail, Points: points})
+				m.Score += points
+			}
+		}
+
+		days := vsByMember[m.MemberID]
+		m.VSDaysPlayed, m.VSZeroDays = days.played, days.zero
+		if m.VSZeroDays > 0 {
+			addReason("vs_zero_days", fmt.Sprintf("No VS points on %d of %d days", m.VSZeroDays, m.VSDaysPlayed),
+				m.VSZeroDays*settings.VSZeroDayPoints)
+		}
+
+		if s, found := stagnantByMember[m.MemberID]; found {
+			m.PowerUnchanged = &s
+			addReason("stagnant_power", fmt.Sprintf("Power unchanged at %d across %d snapshots since %s", s.Power, s.Snapshots, s.Since),
+				settings.StagnantPowerPoints)
+		}
+
+		if m.TrainDuties == 0 {
+			addReason("no_train_duty", "No train duty as conductor or backup", settings.NoTrainDutyPoints)
+		}
+		if m.NoShows > 0 {
+			addReason("train_no_show", fmt.Sprintf("Didn't show up for %d train(s) as conductor", m.NoShows),
+				m.NoShows*settings.NoShowPoints)
+		}
+
+		// Members without an account can't log in, so login is only a signal for those with one
+		if m.HasAccount {
+			if m.LastLogin == nil {
+				addReason("no_login", "Never logged in", settings.NoLoginPoints)
+			} else if lastLogin, err := parseDate((*m.LastLogin)[:min(10, len(*m.LastLogin))]); err == nil {
+				if daysAgo := int(asOf.Sub(lastLogin).Hours() / 24); daysAgo >= settings.NoLoginDays {
+					addReason("no_login", fmt.Sprintf("Last login %d days ago", daysAgo), settings.NoLoginPoints)
+				}
+			}
+		}
+
+		m.Flagged = m.Score > 0 && m.Score >= settings.FlagScore
+		if m.Flagged {
+			report.Flagged++
+		}
+	}
+
+	sort.SliceStable(report.Members, func(i, j int) bool {
+		return report.Members[i].Score > report.Members[j].Score
+	})
+	return report, nil
+}
+
+// Get the inactivity report ranking members by inactivity score (optional ?as_of=YYYY-MM-DD)
+func getInactivityReport(w http.ResponseWriter, r *http.Request) {
+	asOf, err := parsePowerAsOf(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	report, err := buildInactivityReport(asOf)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// Export the inactivity report as CSV for R4 discussion (optional ?as_of=, ?flagged=1 for kick candidates only)
+func exportInactivityReport(w http.ResponseWriter, r *http.Request) {
+	asOf, err := parsePowerAsOf(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	report, err := buildInactivityReport(asOf)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	flaggedOnly := r.URL.Query().Get("flagged") == "1"
+
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"inactivity-%s.csv\"", report.AsOf))
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"Position", "Member", "Rank", "Score", "Kick Candidate", "VS Zero Days", "VS Days",
+		"Power Unchanged Since", "Train Duties", "No-Shows", "Last Login", "Reasons"})
+	for i, m := range report.Members {
+		if flaggedOnly && !m.Flagged {
+			continue
+		}
+		flagged := "no"
+		if m.Flagged {
+			flagged = "yes"
+		}
+		unchangedSince, lastLogin := "", ""
+		if m.PowerUnchanged != nil {
+			unchangedSince = m.PowerUnchanged.Since
+		}
+		if m.LastLogin != nil {
+			lastLogin = *m.LastLogin
+		} else if !m.HasAccount {
+			lastLogin = "no account"
+		}
+		reasons := make([]string, len(m.Reasons))
+		for j, reason := range m.Reasons {
+			reasons[j] = fmt.Sprintf("%s (+%d)", reason.Detail, reason.Points)
+		}
+		writer.Write([]string{strconv.Itoa(i + 1), m.MemberName, m.MemberRank, strconv.Itoa(m.Score), flagged,
+			strconv.Itoa(m.VSZeroDays), strconv.Itoa(m.VSDaysPlayed), unchangedSince, strconv.Itoa(m.TrainDuties),
+			strconv.Itoa(m.NoShows), lastLogin, strings.Join(reasons, "; ")})
+	}
+	writer.Flush()
+}
+
+// Get all award types
+func getAwardTypes(w http.ResponseWriter, r *http.Request) {
+	rows, err := db.Query(`
+		SELECT id, name, active, sort_order, metric, created_at
+		FROM award_types
+		ORDER BY sort_order, name
+	`)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	awardTypes := []AwardType{}
+	for rows.Next() {
+		var at AwardType
+		if err := rows.Scan(&at.ID, &at.Name, &at.Active, &at.SortOrder, &at.Metric, &at.CreatedAt); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		awardTypes = append(awardTypes, at)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(awardTypes)
+}
+
+// Create a new award type
+func createAwardType(w http.ResponseWriter, r *http.Request) {
+	var at AwardType
+	if err := json.NewDecoder(r.Body).Decode(&at); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Validate name
+	if strings.TrimSpace(at.Name) == "" {
+		http.Error(w, "Award type name is required", http.StatusBadRequest)
+		return
+	}
+
+	// Check if award type already exists
+	var existingID int
+	err := db.QueryRow("SELECT id FROM award_types WHERE name = ?", at.Name).Scan(&existingID)
+	if err == nil {
+		http.Error(w, "Award type already exists", http.StatusConflict)
+		return
+	}
+
+	// Get max sort_order and add 1
+	var maxOrder int
+	err = db.QueryRow("SELECT COALESCE(MAX(sort_order), -1) FROM award_types").Scan(&maxOrder)
+	if err != nil {
+		maxOrder = -1
+	}
+
+	result, err := db.Exec(
+		"INSERT INTO award_types (name, active, sort_order) VALUES (?, ?, ?)",
+		at.Name, true, maxOrder+1)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	id, _ := result.LastInsertId()
+	at.ID = int(id)
+	at.Active = true
+	at.SortOrder = maxOrder + 1
+	at.CreatedAt = time.Now().Format(time.RFC3339)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(at)
+}
+
+// Update award type (mainly for active status)
+func updateAwardType(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	var at AwardType
+	if err := json.NewDecoder(r.Body).Decode(&at); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, err = db.Exec(
+		"UPDATE award_types SET active = ?, name = ? WHERE id = ?",
+		at.Active, at.Name, id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// The ranking metric is only changed when sent; "" unlinks it
+	if at.Metric != nil {
+		var metric interface{}
+		if *at.Metric != "" {
+			if _, found := findRankingMetric(*at.Metric); !found {
+				http.Error(w, "Invalid metric - must be power, kills or donation", http.StatusBadRequest)
+				return
+			}
+			metric = *at.Metric
+		}
+		if _, err = db.Exec("UPDATE award_types SET metric = ? WHERE id = ?", metric, id); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Award type updated"})
+}
+
+// Delete award type (supports force deletion)
+func deleteAwardType(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	// Check for force parameter
+	force := r.URL.Query().Get("force") == "true"
+
+	// Get award type name
+	var name string
+	err = db.QueryRow("SELECT name FROM award_types WHERE id = ?", id).Scan(&name)
+	if err != nil {
+		http.Error(w, "Award type not found", http.StatusNotFound)
+		return
+	}
+
+	if force {
+		// Delete all awards of this type first
+		_, err = db.Exec("DELETE FROM awards WHERE award_type = ?", name)
+		if err != nil {
+			http.Error(w, "Failed to delete related awards", http.StatusInternalServerError)
+			return
+		}
+	} else {
+		// Check if award type is used in any awards
+		var count int
+		err = db.QueryRow("SELECT COUNT(*) FROM awards WHERE award_type = ?", name).Scan(&count)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if count > 0 {
+			http.Error(w, "Cannot delete award type that is used in awards. Use force=true to delete anyway.", http.StatusBadRequest)
+			return
+		}
+	}
+
+	_, err = db.Exec("DELETE FROM award_types WHERE id = ?", id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Get all recommendations
+func getRecommendations(w http.ResponseWriter, r *http.Request) {
+	rows, err := db.Query(`
+		SELECT 
+			rec.id, 
+			rec.member_id, 
+			m.name, 
+			m.rank,
+			u.username,
+			rec.recommended_by_id,
+			COALESCE(rec.notes, ''),
+			rec.created_at,
+			CASE 
+				WHEN EXISTS (
+					SELECT 1 FROM train_schedules ts
+					WHERE (ts.conductor_id = rec.member_id OR (ts.backup_id = rec.member_id AND ts.conductor_showed_up = 0))
+					AND ts.date >= date(rec.created_at)
+				) THEN 1
+				ELSE 0
+			END as expired
+		FROM recommendations rec
+		JOIN members m ON rec.member_id = m.id
+		JOIN users u ON rec.recommended_by_id = u.id
+		ORDER BY rec.created_at DESC
+	`)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	recommendations := []Recommendation{}
+	for rows.Next() {
+		var rec Recommendation
+		if err := rows.Scan(&rec.ID, &rec.MemberID, &rec.MemberName, &rec.MemberRank,
+			&rec.RecommendedBy, &rec.RecommendedByID, &rec.Notes, &rec.CreatedAt, &rec.Expired); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		recommendations = append(recommendations, rec)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(recommendations)
+}
+
+// Create recommendation
+func createRecommendation(w http.ResponseWriter, r *http.Request) {
+	session, _ := store.Get(r, "session")
+	userID := session.Values["user_id"].(int)
+
+	var input struct {
+		MemberID int    `json:"member_id"`
+		Notes    string `json:"notes"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if input.MemberID == 0 {
+		http.Error(w, "Member ID is required", http.StatusBadRequest)
+		return
+	}
+
+	// Check if member exists
+	var exists bool
+	err := db.QueryRow("SELECT EXISTS(SELECT 1 FROM members WHERE id = ?)", input.MemberID).Scan(&exists)
+	if err != nil || !exists {
+		http.Error(w, "Member not found", http.StatusNotFound)
+		return
+	}
+
+	result, err := db.Exec(
+		"INSERT INTO recommendations (member_id, recommended_by_id, notes) VALUES (?, ?, ?)",
+		input.MemberID, userID, input.Notes,
+	)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	id, _ := result.LastInsertId()
+
+	// Get the created recommendation
+	var rec Recommendation
+	err = db.QueryRow(`
+		SELECT 
+			rec.id, 
+			rec.member_id, 
+			m.name, 
+			m.rank,
+			u.username,
+			rec.recommended_by_id,
+			COALESCE(rec.notes, ''),
+			rec.created_at,
+			CASE 
+				WHEN EXISTS (
+					SELECT 1 FROM train_schedules ts
+					WHERE (ts.conductor_id = rec.member_id OR (ts.backup_id = rec.member_id AND ts.conductor_showed_up = 0))
+					AND ts.date >= date(rec.created_at)
+				) THEN 1
+				ELSE 0
+			END as expired
+		FROM recommendations rec
+		JOIN members m ON rec.member_id = m.id
+		JOIN users u ON rec.recommended_by_id = u.id
+		WHERE rec.id = ?
+	`, id).Scan(&rec.ID, &rec.MemberID, &rec.MemberName, &rec.MemberRank,
+		&rec.RecommendedBy, &rec.RecommendedByID, &rec.Notes, &rec.CreatedAt, &rec.Expired)
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(rec)
+}
+
+// Delete recommendation
+func deleteRecommendation(w http.ResponseWriter, r *http.Request) {
+	session, _ := store.Get(r, "session")
+	userID := session.Values["user_id"].(int)
+	isAdmin := session.Values["is_admin"].(bool)
+
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	// Check if user is the one who created the recommendation or is admin
+	var recommendedByID int
+	err = db.QueryRow("SELECT recommended_by_id FROM recommendations WHERE id = ?", id).Scan(&recommendedByID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			http.Error(w, "Recommendation not found", http.StatusNotFound)
+		} else {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if recommendedByID != userID && !isAdmin {
+		http.Error(w, "You can only delete your own recommendations", http.StatusForbidden)
+		return
+	}
+
+	_, err = db.Exec("DELETE FROM recommendations WHERE id = ?", id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Get dyno recommendations (expires after 1 week)
+func getDynoRecommendations(w http.ResponseWriter, r *http.Request) {
+	rows, err := db.Query(`
+		SELECT 
+			dr.id, 
+			dr.member_id, 
+			m.name, 
+			m.rank,
+			dr.points,
+			dr.notes,
+			u.username,
+			dr.created_by_id,
+			dr.created_at,
+			CASE 
+				WHEN datetime(dr.created_at, '+7 days') < datetime('now') THEN 1
+				ELSE 0
+			END as expired
+		FROM dyno_recommendations dr
+		JOIN members m ON dr.member_id = m.id
+		JOIN users u ON dr.created_by_id = u.id
+		ORDER BY dr.created_at DESC
+	`)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	dynoRecs := []DynoRecommendation{}
+	for rows.Next() {
+		var dr DynoRecommendation
+		if err := rows.Scan(&dr.ID, &dr.MemberID, &dr.MemberName, &dr.MemberRank,
+			&dr.Points, &dr.Notes, &dr.CreatedBy, &dr.CreatedByID, &dr.CreatedAt, &dr.Expired); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		dynoRecs = append(dynoRecs, dr)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(dynoRecs)
+}
+
+// Create dyno recommendation
+func createDynoRecommendation(w http.ResponseWriter, r *http.Request) {
+	session, _ := store.Get(r, "session")
+	userID := session.Values["user_id"].(int)
+
+	var input struct {
+		MemberID int    `json:"member_id"`
+		Points   int    `json:"points"`
+		Notes    string `json:"notes"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if input.MemberID == 0 {
+		http.Error(w, "Member ID is required", http.StatusBadRequest)
+		return
+	}
+
+	if input.Notes == "" {
+		http.Error(w, "Notes are required", http.StatusBadRequest)
+		return
+	}
+
+	// Check if member exists
+	var exists bool
+	err := db.QueryRow("SELECT EXISTS(SELECT 1 FROM members WHERE id = ?)", input.MemberID).Scan(&exists)
+	if err != nil || !exists {
+		http.Error(w, "Member not found", http.StatusNotFound)
+		return
+	}
+
+	result, err := db.Exec(
+		"INSERT INTO dyno_recommendations (member_id, points, notes, created_by_id) VALUES (?, ?, ?, ?)",
+		input.MemberID, input.Points, input.Notes, userID,
+	)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	id, _ := result.LastInsertId()
+
+	// Get the created dyno recommendation
+	var dr DynoRecommendation
+	err = db.QueryRow(`
+		SELECT 
+			dr.id, 
+			dr.member_id, 
+			m.name, 
+			m.rank,
+			dr.points,
+			dr.notes,
+			u.username,
+			dr.created_by_id,
+			dr.created_at,
+			CASE 
+				WHEN datetime(dr.created_at, '+7 days') < datetime('now') THEN 1
+				ELSE 0
+			END as expired
+		FROM dyno_recommendations dr
+		JOIN members m ON dr.member_id = m.id
+		JOIN users u ON dr.created_by_id = u.id
+		WHERE dr.id = ?
+	`, id).Scan(&dr.ID, &dr.MemberID, &dr.MemberName, &dr.MemberRank,
+		&dr.Points, &dr.Notes, &dr.CreatedBy, &dr.CreatedByID, &dr.CreatedAt, &dr.Expired)
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(dr)
+}
+
+// Delete dyno recommendation
+func deleteDynoRecommendation(w http.ResponseWriter, r *http.Request) {
+	session, _ := store.Get(r, "session")
+	userID := session.Values["user_id"].(int)
+	isAdmin := session.Values["is_admin"].(bool)
+
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	// Check if user is the one who created the recommendation or is admin
+	var createdByID int
+	err = db.QueryRow("SELECT created_by_id FROM dyno_recommendations WHERE id = ?", id).Scan(&createdByID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			http.Error(w, "Dyno recommendation not found", http.StatusNotFound)
+		} else {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if createdByID != userID && !isAdmin {
+		http.Error(w, "You can only delete your own dyno recommendations", http.StatusForbidden)
+		return
+	}
+
+	_, err = db.Exec("DELETE FROM dyno_recommendations WHERE id = ?", id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Get settings
+func getSettings(w http.ResponseWriter, r *http.Request) {
+	settings, err := loadSettings()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(settings)
+}
+
+// OCRLanguagesResponse lists the configured and installed Tesseract language packs
+type OCRLanguagesResponse struct {
+	Engine     string   `json:"engine"`
+	Configured []string `json:"configured"`
+	Installed  []string `json:"installed"`
+	Error      string   `json:"error,omitempty"` // why the installed packs could not be listed
+}
+
+// Get the OCR language packs for the settings page
+func getOCRLanguages(w http.ResponseWriter, r *http.Request) {
+	response := OCRLanguagesResponse{
+		Engine:     ocrEngine.Name(),
+		Configured: ocrLanguages(),
+		Installed:  []string{},
+	}
+	installed, err := ocrEngine.Languages()
+	if err != nil {
+		response.Error = err.Error()
+	} else {
+		response.Installed = installed
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// Update settings (admin only)
+func updateSettings(w http.ResponseWriter, r *http.Request) {
+	var settings Settings
+	if err := json.NewDecoder(r.Body).Decode(&settings); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if settings.OCRMinConfidence < 0 || settings.OCRMinConfidence > 100 {
+		http.Error(w, "ocr_min_confidence must be between 0 and 100", http.StatusBadRequest)
+		return
+	}
+
+	if settings.OCRLanguages == "" {
+		settings.OCRLanguages = defaultOCRLanguages
+	}
+	languages, err := parseOCRLanguages(settings.OCRLanguages)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	// Only reject missing language packs when the engine can tell which ones are installed
+	if installed, err := ocrEngine.Languages(); err == nil {
+		for _, language := range languages {
+			if !slices.Contains(installed, language) {
+				http.Error(w, fmt.Sprintf("OCR language pack %q is not installed (installed: %s)",
+					language, strings.Join(installed, ", ")), http.StatusBadRequest)
+				return
+			}
+		}
+	}
+	settings.OCRLanguages = strings.Join(languages, "+")
+
+	if msg := validateGameTimeSettings(&settings); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	_, err = db.Exec(`UPDATE settings SET 
+		award_first_points = ?, 
+		award_second_points = ?, 
+		award_third_points = ?, 
+		recommendation_points = ?, 
+		recent_conductor_penalty_days = ?, 
+		above_average_conductor_penalty = ?,
+		r4r5_rank_boost = ?,
+		first_time_conductor_boost = ?,
+		power_tracking_enabled = ?,
+		vs_percentile_points = ?,
+		vs_min_daily_points = ?,
+		vs_consistency_bonus = ?,
+		vs_zero_day_penalty = ?,
+		ocr_min_confidence = ?,
+		ocr_languages = ?,
+		game_timezone = ?,
+		game_reset_time = ?,
+		train_conductor_time = ?,
+		train_backup_time = ?,
+		display_timezones = ?
+		WHERE id = 1`,
+		settings.AwardFirstPoints,
+		settings.AwardSecondPoints,
+		settings.AwardThirdPoints,
+		settings.RecommendationPoints,
+		settings.RecentConductorPenaltyDays,
+		settings.AboveAverageConductorPenalty,
+		settings.R4R5RankBoost,
+		settings.FirstTimeConductorBoost,
+		settings.PowerTrackingEnabled,
+		settings.VSPercentilePoints,
+		settings.VSMinDailyPoints,
+		settings.VSConsistencyBonus,
+		settings.VSZeroDayPenalty,
+		settings.OCRMinConfidence,
+		settings.OCRLanguages,
+		settings.GameTimezone,
+		settings.GameResetTime,
+		settings.TrainConductorTime,
+		settings.TrainBackupTime,
+		settings.DisplayTimezones,
+	)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Settings updated successfully"})
+}
+
+// Get member rankings with detailed score breakdown
+func getMemberRankings(w http.ResponseWriter, r *http.Request) {
+	// Always include all awards (active and inactive) - filtering is done on client side
+
+	// Build ranking context using the current game day, or replay a past date with ?as_of=YYYY-MM-DD
+	now := gameToday()
+	if asOfParam := r.URL.Query().Get("as_of"); asOfParam != "" {
+		asOf, err := parseDate(asOfParam)
+		if err != nil {
+			http.Error(w, "Invalid as_of date format (expected YYYY-MM-DD)", http.StatusBadRequest)
+			return
+		}
+		now = asOf
+	}
+	ctx, err := buildRankingContext(now)
+	if err != nil {
+		http.Error(w, "Failed to load ranking context: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Get all members
+	rows, err := db.Query("SELECT id, name, rank FROM members ORDER BY name")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	var members []Member
+	for rows.Next() {
+		var m Member
+		if err := rows.Scan(&m.ID, &m.Name, &m.Rank); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		members = append(members, m)
+	}
+
+	// Load all award details with expired flag (as of the reference date)
+	awardQuery := `
+		SELECT 
+			a.member_id, 
+			a.award_type, 
+			a.rank, 
+			a.week_date,
+			CASE 
+				WHEN EXISTS (
+					SELECT 1 FROM train_schedules ts
+					WHERE (ts.conductor_id = a.member_id OR (ts.backup_id = a.member_id AND ts.conductor_showed_up = 0))
+					AND ts.date >= a.week_date
+					AND ts.date < ?
+				) THEN 1
+				ELSE 0
+			END as expired
+		FROM awards a
+		WHERE a.week_date <= ?
+		ORDER BY a.week_date DESC, a.rank ASC
+	`
+
+	asOfStr := formatDateString(now)
+	awardRows, err := db.Query(awardQuery, asOfStr, asOfStr)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer awardRows.Close()
+
+	memberAwards := make(map[int][]AwardDetail)
+	for awardRows.Next() {
+		var memberID, rank, expired int
+		var awardType, weekDate string
+		if err := awardRows.Scan(&memberID, &awardType, &rank, &weekDate, &expired); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		points := 0
+		switch rank {
+		case 1:
+			points = ctx.Settings.AwardFirstPoints
+		case 2:
+			points = ctx.Settings.AwardSecondPoints
+		case 3:
+			points = ctx.Settings.AwardThirdPoints
+		}
+		memberAwards[memberID] = append(memberAwards[memberID], AwardDetail{
+			AwardType: awardType,
+			Rank:      rank,
+			Points:    points,
+			WeekDate:  weekDate,
+			Expired:   expired == 1,
+		})
+	}
+
+	// Calculate rankings for each member
+	var rankings []MemberRanking
+	for _, member := range members {
+		ranking := MemberRanking{
+			Member:         member,
+			AwardDetails:   memberAwards[member.ID],
+			ConductorCount: 0,
+		}
+
+		// Calculate award points
+		ranking.AwardPoints = ctx.AwardScoreMap[member.ID]
+
+		// Calculate recommendation points
+		recCount := ctx.RecommendationMap[member.ID]
+		ranking.RecommendationCount = recCount
+		ranking.RecommendationPoints = recCount * ctx.Settings.RecommendationPoints
+
+		// Calculate VS points from the last completed VS week
+		if vsStat, exists := ctx.VSStats[member.ID]; exists {
+			ranking.VSWeeklyTotal = vsStat.Total
+			ranking.VSPercentile = vsStat.Percentile
+			ranking.VSZeroDays = vsStat.ZeroDays
+		}
+		ranking.VSPercentilePoints, ranking.VSConsistencyBonus, ranking.VSZeroDayPenalty = calculateVSScore(member.ID, ctx)
+
+		// Apply rank boost for R4/R5 members (exponential based on days since last conductor)
+		if member.Rank == "R4" || member.Rank == "R5" {
+			baseBoost := float64(ctx.Settings.R4R5RankBoost)
+
+			// Calculate days since last conductor/backup duty
+			var daysSinceLastDuty int = 0
+			if stats, exists := ctx.ConductorStats[member.ID]; exists {
+				var mostRecentDate *time.Time
+
+				if stats.LastDate != nil {
+					if lastDate, err := parseDate(*stats.LastDate); err == nil {
+						mostRecentDate = &lastDate
+					}
+				}
+
+				// Check if backup usage was more recent
+				if stats.LastBackupUsed != nil {
+					if backupDate, err := parseDate(*stats.LastBackupUsed); err == nil {
+						if mostRecentDate == nil || backupDate.After(*mostRecentDate) {
+							mostRecentDate = &backupDate
+						}
+					}
+				}
+
+				if mostRecentDate != nil {
+					daysSinceLastDuty = int(now.Sub(*mostRecentDate).Hours() / 24)
+				}
+			}
+
+			// Exponential formula: base_boost * 2^(days/7)
+			multiplier := math.Pow(2, float64(daysSinceLastDuty)/7.0)
+			ranking.RankBoost = int(math.Round(baseBoost * multiplier))
+		}
+
+		// Apply first time conductor boost if member has never been conductor
+		if stats, exists := ctx.ConductorStats[member.ID]; !exists || stats.Count == 0 {
+			// Calculate base score without first time boost
+			baseScore := ranking.AwardPoints + ranking.RecommendationPoints + ranking.RankBoost +
+				ranking.VSPercentilePoints + ranking.VSConsistencyBonus
+			if baseScore > 0 {
+				ranking.FirstTimeConductorBoost = ctx.Settings.FirstTimeConductorBoost
+			}
+		}
+
+		// Get conductor stats
+		if stats, exists := ctx.ConductorStats[member.ID]; exists {
+			ranking.ConductorCount = stats.Count
+			ranking.LastConductorDate = stats.LastDate
+
+			// Calculate above average penalty
+			if float64(stats.Count) > ctx.AvgConductorCount {
+				ranking.AboveAveragePenalty = ctx.Settings.AboveAverageConductorPenalty
+			}
+
+			// Calculate recent conductor penalty - check both conductor date and backup used date
+			var mostRecentDate *time.Time
+
+			if stats.LastDate != nil {
+				if lastDate, err := parseDate(*stats.LastDate); err == nil {
+					mostRecentDate = &lastDate
+				}
+			}
+
+			// If they stepped in as backup, check if that's more recent
+			if stats.LastBackupUsed != nil {
+				if backupDate, err := parseDate(*stats.LastBackupUsed); err == nil {
+					if mostRecentDate == nil || backupDate.After(*mostRecentDate) {
+						mostRecentDate = &backupDate
+					}
+				}
+			}
+
+			// Apply penalty based on most recent duty (conductor or backup usage)
+			if mostRecentDate != nil {
+				daysSince := int(now.Sub(*mostRecentDate).Hours() / 24)
+				ranking.DaysSinceLastConductor = &daysSince
+				penalty := ctx.Settings.RecentConductorPenaltyDays - daysSince
+				if penalty > 0 {
+					ranking.RecentConductorPenalty = penalty
+				}
+			}
+		}
+
+		// Calculate total score using the same abstracted function
+		ranking.TotalScore = calculateMemberScore(member, ctx)
+
+		rankings = append(rankings, ranking)
+	}
+
+	// Sort by total score (highest first)
+	for i := 0; i < len(rankings); i++ {
+		for j := i + 1; j < len(rankings); j++ {
+			if rankings[j].TotalScore > rankings[i].TotalScore {
+				rankings[i], rankings[j] = rankings[j], rankings[i]
+			}
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"rankings":                rankings,
+		"settings":                ctx.Settings,
+		"average_conductor_count": ctx.AvgConductorCount,
+		"as_of":                   asOfStr,
+		"vs_week":                 ctx.VSWeek,
+	})
+}
+
+// RankingSimulationEntry describes how one member's score and rank change under candidate settings
+type RankingSimulationEntry struct {
+	Member         Member `json:"member"`
+	CurrentScore   int    `json:"current_score"`
+	SimulatedScore int    `json:"simulated_score"`
+	ScoreChange    int    `json:"score_change"`
+	CurrentRank    int    `json:"current_rank"`
+	SimulatedRank  int    `json:"simulated_rank"`
+	RankChange     int    `json:"rank_change"` // positive = moved up
+}
+
+// Simulate rankings with candidate settings without persisting anything (R5/admin only)
+func simulateRankings(w http.ResponseWriter, r *http.Request) {
+	currentSettings, err := loadSettings()
+	if err != nil {
+		http.Error(w, "Failed to load settings: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Start from the stored settings so fields omitted from the request keep their current values
+	candidateSettings := currentSettings
+	if err := json.NewDecoder(r.Body).Decode(&candidateSettings); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	candidateSettings.ID = currentSettings.ID
+
+	// Simulate the next auto-scheduled week unless a week is specified
+	weekStart := getMondayOfWeek(gameToday()).AddDate(0, 0, 7)
+	if startParam := r.URL.Query().Get("start"); startParam != "" {
+		startDate, err := parseDate(startParam)
+		if err != nil {
+			http.Error(w, "Invalid date format", http.StatusBadRequest)
+			return
+		}
+		weekStart = getMondayOfWeek(startDate)
+	}
+
+	currentCtx, err := buildRankingContextWithSettings(weekStart, currentSettings)
+	if err != nil {
+		http.Error(w, "Failed to load ranking context: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	simulatedCtx, err := buildRankingContextWithSettings(weekStart, candidateSettings)
+	if err != nil {
+		http.Error(w, "Failed to load ranking context: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	rows, err := db.Query("SELECT id, name, rank, COALESCE(eligible, 1) FROM members ORDER BY name")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	var members []Member
+	for rows.Next() {
+		var m Member
+		if err := rows.Scan(&m.ID, &m.Name, &m.Rank, &m.Eligible); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		members = append(members, m)
+	}
+
+	currentScored := scoreMembers(members, currentCtx)
+	simulatedScored := scoreMembers(members, simulatedCtx)
+
+	// Rank positions (1-based) and the members auto-schedule would pick under each settings set
+	currentRanks := make(map[int]int)
+	currentScores := make(map[int]int)
+	currentSelected := make(map[int]bool)
+	for i, sm := range currentScored {
+		currentRanks[sm.Member.ID] = i + 1
+		currentScores[sm.Member.ID] = sm.Score
+		if sm.Member.Eligible && len(currentSelected) < weeklyConductorSlots {
+			currentSelected[sm.Member.ID] = true
+		}
+	}
+
+	changes := []RankingSimulationEntry{}
+	simulatedSelection := []Member{}
+	entering := []Member{}
+	for i, sm := range simulatedScored {
+		currentRank := currentRanks[sm.Member.ID]
+		changes = append(changes, RankingSimulationEntry{
+			Member:         sm.Member,
+			CurrentScore:   currentScores[sm.Member.ID],
+			SimulatedScore: sm.Score,
+			ScoreChange:    sm.Score - currentScores[sm.Member.ID],
+			CurrentRank:    currentRank,
+			SimulatedRank:  i + 1,
+			RankChange:     currentRank - (i + 1),
+		})
+
+		if sm.Member.Eligible && len(simulatedSelection) < weeklyConductorSlots {
+			simulatedSelection = append(simulatedSelection, sm.Member)
+			if !currentSelected[sm.Member.ID] {
+				entering = append(entering, sm.Member)
+			}
+		}
+	}
+
+	simulatedSelected := make(map[int]bool)
+	for _, m := range simulatedSelection {
+		simulatedSelected[m.ID] = true
+	}
+
+	currentSelection := []Member{}
+	leaving := []Member{}
+	for _, sm := range currentScored {
+		if !currentSelected[sm.Member.ID] {
+			continue
+		}
+		currentSelection = append(currentSelection, sm.Member)
+		if !simulatedSelected[sm.Member.ID] {
+			leaving = append(leaving, sm.Member)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"week_start":          formatDateString(weekStart),
+		"current_settings":    currentSettings,
+		"simulated_settings":  candidateSettings,
+		"changes":             changes,
+		"current_selection":   currentSelection,
+		"simulated_selection": simulatedSelection,
+		"entering":            entering,
+		"leaving":             leaving,
+	})
+}
+
+// Get member point accumulation timelines over specified months
+func getMemberTimelines(w http.ResponseWriter, r *http.Request) {
+	// Parse months parameter (default to 3)
+	monthsParam := r.URL.Query().Get("months")
+	months := 3
+	if monthsParam != "" {
+		if m, err := strconv.Atoi(monthsParam); err == nil && m > 0 {
+			months = m
+		}
+	}
+
+	// Calculate start date (N months ago from today)
+	now := gameToday()
+	startDate := now.AddDate(0, -months, 0)
+
+	// Get all members
+	memberRows, err := db.Query("SELECT id, name, rank FROM members ORDER BY name")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer memberRows.Close()
+
+	var members []Member
+	for memberRows.Next() {
+		var m Member
+		if err := memberRows.Scan(&m.ID, &m.Name, &m.Rank); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		members = append(members, m)
+	}
+
+	// Get settings for point calculations
+	var settings Settings
+	err = db.QueryRow(`SELECT award_first_points, award_second_points, award_third_points, 
+		recommendation_points, r4r5_rank_boost, first_time_conductor_boost,
+		recent_conductor_penalty_days, above_average_conductor_penalty 
+		FROM settings WHERE id = 1`).Scan(
+		&settings.AwardFirstPoints, &settings.AwardSecondPoints,
+		&settings.AwardThirdPoints, &settings.RecommendationPoints,
+		&settings.R4R5RankBoost, &settings.FirstTimeConductorBoost,
+		&settings.RecentConductorPenaltyDays, &settings.AboveAverageConductorPenalty)
+	if err != nil {
+		http.Error(w, "Failed to load settings: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Calculate average conductor count for above average penalty
+	var totalConductors, memberCount int
+	db.QueryRow(`
+		SELECT COUNT(DISTINCT conductor_id), 
+		       (SELECT COUNT(*) FROM members WHERE eligible = 1)
+		FROM train_schedules
+	`).Scan(&totalConductors, &memberCount)
+	avgConductorCount := 0.0
+	if memberCount > 0 {
+		avgConductorCount = float64(totalConductors) / float64(memberCount)
+	}
+
+	// Build timeline data for each member
+	timelines := make(map[int]map[string]interface{})
+
+	for _, member := range members {
+		// Get train schedules where member was conductor (to track resets)
+		conductorDates := []string{}
+		conductorRows, err := db.Query(`
+			SELECT date FROM train_schedules 
+			WHERE (conductor_id = ? OR (backup_id = ? AND conductor_showed_up = 0))
+			AND date >= ?
+			ORDER BY date ASC
+		`, member.ID, member.ID, formatDateString(startDate))
+		if err != nil {
+			continue
+		}
+		for conductorRows.Next() {
+			var dateStr string
+			if err := conductorRows.Scan(&dateStr); err == nil {
+				conductorDates = append(conductorDates, dateStr)
+			}
+		}
+		conductorRows.Close()
+
+		// Get all awards and recommendations since start date (tracked separately)
+		type PointEvent struct {
+			Date   string
+			Awards int
+			Recs   int
+		}
+		eventMap := make(map[string]*PointEvent)
+
+		// Get awards
+		awardRows, err := db.Query(`
+			SELECT week_date, rank FROM awards 
+			WHERE member_id = ? AND week_date >= ?
+			ORDER BY week_date ASC
+		`, member.ID, formatDateString(startDate))
+		if err == nil {
+			for awardRows.Next() {
+				var weekDate string
+				var rank int
+				if err := awardRows.Scan(&weekDate, &rank); err == nil {
+					points := 0
+					switch rank {
+					case 1:
+						points = settings.AwardFirstPoints
+					case 2:
+						points = settings.AwardSecondPoints
+					case 3:
+						points = settings.AwardThirdPoints
+					}
+					if eventMap[weekDate] == nil {
+						eventMap[weekDate] = &PointEvent{Date: weekDate}
+					}
+					eventMap[weekDate].Awards += points
+				}
+			}
+			awardRows.Close()
+		}
+
+		// Get recommendations (calculate non-linear points: 5*sqrt(n))
+		recRows, err := db.Query(`
+			SELECT DATE(created_at) as rec_date, COUNT(*) as count
+			FROM recommendations 
+			WHERE member_id = ? AND DATE(created_at) >= ?
+			GROUP BY DATE(created_at)
+			ORDER BY rec_date ASC
+		`, member.ID, formatDateString(startDate))
+		if err == nil {
+			for recRows.Next() {
+				var recDate string
+				var count int
+				if err := recRows.Scan(&recDate, &count); err == nil {
+					// Non-linear formula: 5*sqrt(count)
 					points := int(5 * math.Sqrt(float64(count)))
 					if eventMap[recDate] == nil {
 						eventMap[recDate] = &PointEvent{Date: recDate}
 					}
-					eventMap[recDate].Recs += points
+					eventMap[recDate].Recs += points
+				}
+			}
+			recRows.Close()
+		}
+
+		// Convert map to sorted slice
+		var events []PointEvent
+		for _, event := range eventMap {
+			events = append(events, *event)
+		}
+		sort.Slice(events, func(i, j int) bool {
+			return events[i].Date < events[j].Date
+		})
+
+		// Get power history for this member
+		powerHistoryMap := make(map[string]int)
+		powerRows, err := db.Query(`
+			SELECT DATE(recorded_at) as power_date, 
+			       MAX(power) as max_power
+			FROM power_history 
+			WHERE member_id = ? AND DATE(recorded_at) >= ?
+			GROUP BY DATE(recorded_at)
+			ORDER BY power_date ASC
+		`, member.ID, formatDateString(startDate))
+		if err == nil {
+			for powerRows.Next() {
+				var powerDate string
+				var maxPower int
+				if err := powerRows.Scan(&powerDate, &maxPower); err == nil {
+					powerHistoryMap[powerDate] = maxPower
+				}
+			}
+			powerRows.Close()
+		}
+
+		// Build weekly timeline arrays
+		weekLabels := []string{}
+		pointsWithReset := []int{}
+		pointsCumulative := []int{}
+		awardsWithReset := []int{}
+		awardsCumulative := []int{}
+		recsWithReset := []int{}
+		recsCumulative := []int{}
+		rankBoostWithReset := []int{}
+		rankBoostCumulative := []int{}
+		firstTimeBoostWithReset := []int{}
+		firstTimeBoostCumulative := []int{}
+		recentPenaltyWithReset := []int{}
+		recentPenaltyCumulative := []int{}
+		aboveAvgPenaltyWithReset := []int{}
+		aboveAvgPenaltyCumulative := []int{}
+		powerValues := []int{}
+
+		currentPoints := 0
+		cumulativePoints := 0
+		currentAwards := 0
+		cumulativeAwards := 0
+		currentRecs := 0
+		cumulativeRecs := 0
+		currentRankBoost := 0
+		cumulativeRankBoost := 0
+		currentFirstTimeBoost := 0
+		cumulativeFirstTimeBoost := 0
+		currentRecentPenalty := 0
+		cumulativeRecentPenalty := 0
+		currentAboveAvgPenalty := 0
+		cumulativeAboveAvgPenalty := 0
+		conductorIdx := 0
+		conductorCountSoFar := 0
+
+		// Generate week range from start to now (by Monday of each week)
+		currentDate := getMondayOfWeek(startDate)
+		for currentDate.Before(now) || currentDate.Equal(now) {
+			weekStart := currentDate
+			weekEnd := currentDate.AddDate(0, 0, 6)
+			weekStartStr := formatDateString(weekStart)
+			weekEndStr := formatDateString(weekEnd)
+
+			// Format: "Jan 1 - Jan 7"
+			weekLabel := fmt.Sprintf("%s - %s",
+				weekStart.Format("Jan 2"),
+				weekEnd.Format("Jan 2"))
+			weekLabels = append(weekLabels, weekLabel)
+
+			// Check if this week has a conductor event (train reset)
+			weekHasReset := false
+			for conductorIdx < len(conductorDates) && conductorDates[conductorIdx] <= weekEndStr {
+				if conductorDates[conductorIdx] >= weekStartStr {
+					weekHasReset = true
+					conductorCountSoFar++
+				}
+				conductorIdx++
+			}
+
+			// Add points from events in this week
+			weekAwards := 0
+			weekRecs := 0
+			for _, event := range events {
+				if event.Date >= weekStartStr && event.Date <= weekEndStr {
+					weekAwards += event.Awards
+					weekRecs += event.Recs
+				}
+			}
+			weekPoints := weekAwards + weekRecs
+
+			currentPoints += weekPoints
+			cumulativePoints += weekPoints
+			currentAwards += weekAwards
+			cumulativeAwards += weekAwards
+			currentRecs += weekRecs
+			cumulativeRecs += weekRecs
+
+			// Calculate Rank Boost (R4/R5 exponential)
+			weekRankBoost := 0
+			if member.Rank == "R4" || member.Rank == "R5" {
+				baseBoost := float64(settings.R4R5RankBoost)
+				// Find most recent conductor date before this week
+				daysSinceLastDuty := 0
+				for i := conductorIdx - 1; i >= 0; i-- {
+					if i < len(conductorDates) {
+						if lastConductorDate, err := time.Parse("2006-01-02", conductorDates[i]); err == nil {
+							daysSinceLastDuty = int(weekEnd.Sub(lastConductorDate).Hours() / 24)
+							break
+						}
+					}
+				}
+				multiplier := math.Pow(2, float64(daysSinceLastDuty)/7.0)
+				weekRankBoost = int(math.Round(baseBoost * multiplier))
+			}
+			currentRankBoost += weekRankBoost
+			cumulativeRankBoost += weekRankBoost
+
+			// Calculate First Time Conductor Boost
+			weekFirstTimeBoost := 0
+			if conductorCountSoFar == 0 {
+				// Only if they have other points (awards, recs, or rank boost)
+				if currentAwards > 0 || currentRecs > 0 || currentRankBoost > 0 {
+					weekFirstTimeBoost = settings.FirstTimeConductorBoost
+				}
+			}
+			currentFirstTimeBoost += weekFirstTimeBoost
+			cumulativeFirstTimeBoost += weekFirstTimeBoost
+
+			// Calculate Recent Conductor Penalty
+			weekRecentPenalty := 0
+			if conductorCountSoFar > 0 {
+				// Find most recent conductor date
+				for i := conductorIdx - 1; i >= 0; i-- {
+					if i < len(conductorDates) {
+						if lastConductorDate, err := time.Parse("2006-01-02", conductorDates[i]); err == nil {
+							daysSince := int(weekEnd.Sub(lastConductorDate).Hours() / 24)
+							penalty := settings.RecentConductorPenaltyDays - daysSince
+							if penalty > 0 {
+								weekRecentPenalty = penalty
+							}
+							break
+						}
+					}
+				}
+			}
+			currentRecentPenalty += weekRecentPenalty
+			cumulativeRecentPenalty += weekRecentPenalty
+
+			// Calculate Above Average Penalty
+			weekAboveAvgPenalty := 0
+			if float64(conductorCountSoFar) > avgConductorCount {
+				weekAboveAvgPenalty = settings.AboveAverageConductorPenalty
+			}
+			currentAboveAvgPenalty += weekAboveAvgPenalty
+			cumulativeAboveAvgPenalty += weekAboveAvgPenalty
+
+			// Apply reset at end of week if conductor event occurred
+			if weekHasReset {
+				currentPoints = 0
+				currentAwards = 0
+				currentRecs = 0
+				currentRankBoost = 0
+				currentFirstTimeBoost = 0
+				currentRecentPenalty = 0
+				currentAboveAvgPenalty = 0
+			}
+
+			// Find max power value for this week
+			weekMaxPower := 0
+			for powerDate, power := range powerHistoryMap {
+				if powerDate >= weekStartStr && powerDate <= weekEndStr {
+					if power > weekMaxPower {
+						weekMaxPower = power
+					}
+				}
+			}
+
+			pointsWithReset = append(pointsWithReset, currentPoints)
+			pointsCumulative = append(pointsCumulative, cumulativePoints)
+			awardsWithReset = append(awardsWithReset, currentAwards)
+			awardsCumulative = append(awardsCumulative, cumulativeAwards)
+			recsWithReset = append(recsWithReset, currentRecs)
+			recsCumulative = append(recsCumulative, cumulativeRecs)
+			rankBoostWithReset = append(rankBoostWithReset, currentRankBoost)
+			rankBoostCumulative = append(rankBoostCumulative, cumulativeRankBoost)
+			firstTimeBoostWithReset = append(firstTimeBoostWithReset, currentFirstTimeBoost)
+			firstTimeBoostCumulative = append(firstTimeBoostCumulative, cumulativeFirstTimeBoost)
+			recentPenaltyWithReset = append(recentPenaltyWithReset, currentRecentPenalty)
+			recentPenaltyCumulative = append(recentPenaltyCumulative, cumulativeRecentPenalty)
+			aboveAvgPenaltyWithReset = append(aboveAvgPenaltyWithReset, currentAboveAvgPenalty)
+			aboveAvgPenaltyCumulative = append(aboveAvgPenaltyCumulative, cumulativeAboveAvgPenalty)
+			powerValues = append(powerValues, weekMaxPower)
+
+			currentDate = currentDate.AddDate(0, 0, 7)
+		}
+
+		// Format conductor dates for display (convert to week labels)
+		conductorWeekLabels := []string{}
+		for _, condDate := range conductorDates {
+			if parsedDate, err := time.Parse("2006-01-02", condDate); err == nil {
+				monday := getMondayOfWeek(parsedDate)
+				weekEnd := monday.AddDate(0, 0, 6)
+				weekLabel := fmt.Sprintf("%s - %s",
+					monday.Format("Jan 2"),
+					weekEnd.Format("Jan 2"))
+				conductorWeekLabels = append(conductorWeekLabels, weekLabel)
+			}
+		}
+
+		timelines[member.ID] = map[string]interface{}{
+			"dates":                        weekLabels,
+			"points_with_reset":            pointsWithReset,
+			"points_cumulative":            pointsCumulative,
+			"awards_with_reset":            awardsWithReset,
+			"awards_cumulative":            awardsCumulative,
+			"recommendations_with_reset":   recsWithReset,
+			"recommendations_cumulative":   recsCumulative,
+			"rank_boost_with_reset":        rankBoostWithReset,
+			"rank_boost_cumulative":        rankBoostCumulative,
+			"first_time_boost_with_reset":  firstTimeBoostWithReset,
+			"first_time_boost_cumulative":  firstTimeBoostCumulative,
+			"recent_penalty_with_reset":    recentPenaltyWithReset,
+			"recent_penalty_cumulative":    recentPenaltyCumulative,
+			"above_avg_penalty_with_reset": aboveAvgPenaltyWithReset,
+			"above_avg_penalty_cumulative": aboveAvgPenaltyCumulative,
+			"conductor_dates":              conductorWeekLabels,
+			"power":                        powerValues,
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(timelines)
+}
+
+// templateMember is a member as message templates see them
+type templateMember struct {
+	Name string
+	Rank string
+}
+
+// trainMessageDay is one day of the train schedule in a message
+type trainMessageDay struct {
+	Date      string // YYYY-MM-DD
+	Conductor templateMember
+	Backup    *templateMember // nil when the day has no backup
+}
+
+// trainWeeklyMessageData is rendered by the train_weekly template
+type trainWeeklyMessageData struct {
+	Week       string // Monday of the week
+	Schedules  []trainMessageDay
+	NextInLine []templateMember // the 3 best ranked members for the following week
+}
+
+// trainDailyMessageData is rendered by the train_daily template
+type trainDailyMessageData struct {
+	Date          string
+	Conductor     templateMember
+	Backup        *templateMember
+	ConductorTime string // train request time in ST and the chat time zones
+	BackupTime    string // backup takeover time in ST and the chat time zones
+}
+
+// conductorReminderData is rendered by the conductor_reminder templates, one message per conductor
+type conductorReminderData struct {
+	Name   string
+	Rank   string
+	Date   string
+	Time   string // train request time in ST and the conductor's own time zone
+	Backup *templateMember
+}
+
+// vsWarningMessageData is rendered by the vs_warning template
+type vsWarningMessageData struct {
+	Week         string
+	Requirements []vsWarningRequirement // days with a minimum
+	Members      []vsWarningMember      // members who missed a minimum
+	Count        int
+}
+
+type vsWarningRequirement struct {
+	Day       string // e.g. Monday
+	Theme     string
+	MinPoints int
+}
+
+type vsWarningMember struct {
+	Name   string
+	Rank   string
+	Missed []string // days missed, e.g. Mon
+	Streak int      // missed days in a row up to the end of the week
+}
+
+// stormPlanMessageData is rendered by the storm_plan template
+type stormPlanMessageData struct {
+	TaskForce string
+	Date      string // empty for the undated plan
+	Count     int    // assigned members
+	Stages    []stormMessageStage
+}
+
+type stormMessageStage struct {
+	Stage     int
+	Buildings []stormMessageBuilding
+}
+
+type stormMessageBuilding struct {
+	Name    string
+	Members []string
+}
+
+// stormMemberMessageData is rendered by the storm_member template, one message per assigned member
+type stormMemberMessageData struct {
+	Name      string
+	Rank      string
+	TaskForce string
+	Date      string
+	Slots     []stormMessageSlot
+}
+
+type stormMessageSlot struct {
+	Stage    int
+	Building string
+	Position int
+}
+
+// messageTemplateKind describes a kind of message template
+type messageTemplateKind struct {
+	Single  bool                 // one template named after the kind; otherwise variants used in turn
+	Samples func() []interface{} // data every template of the kind must render, covering the optional parts
+	Legacy  *strings.Replacer    // converts the {PLACEHOLDER} templates from before the template engine
+}
+
+var messageTemplateKinds = map[string]messageTemplateKind{
+	"train_weekly": {
+		Single: true,
+		Samples: func() []interface{} {
+			return []interface{}{trainWeeklyMessageData{
+				Week: "2025-01-06",
+				Schedules: []trainMessageDay{
+					{Date: "2025-01-06", Conductor: templateMember{"Alice", "R3"}, Backup: &templateMember{"Bob", "R4"}},
+					{Date: "2025-01-07", Conductor: templateMember{"Carol", "R2"}},
+				},
+				NextInLine: []templateMember{{"Dave", "R3"}, {"Erin", "R1"}, {"Frank", "R5"}},
+			}}
+		},
+		Legacy: strings.NewReplacer(
+			"{WEEK}", `{{date .Week "medium"}}`,
+			"{SCHEDULES}", "{{range .Schedules}}{{date .Date \"day\"}}: {{.Conductor.Name}}{{with .Backup}} (Backup: {{.Name}}){{end}}\n{{end}}",
+			"{NEXT_3}", "{{range .NextInLine}}{{.Name}}\n{{end}}",
+		),
+	},
+	"train_daily": {
+		Single: true,
+		Samples: func() []interface{} {
+			data := trainDailyMessageData{
+				Date:          "2025-01-06",
+				Conductor:     templateMember{"Alice", "R3"},
+				Backup:        &templateMember{"Bob", "R4"},
+				ConductorTime: "15:00 ST / 17:00 GMT / 18:00 CET",
+				BackupTime:    "16:30 ST / 18:30 GMT / 19:30 CET",
+			}
+			withoutBackup := data
+			withoutBackup.Backup = nil
+			return []interface{}{data, withoutBackup}
+		},
+		Legacy: strings.NewReplacer(
+			"{DATE}", `{{date .Date "long"}}`,
+			"{CONDUCTOR_NAME}", "{{.Conductor.Name}}",
+			"{CONDUCTOR_RANK}", "{{.Conductor.Rank}}",
+			"{BACKUP_NAME}", "{{with .Backup}}{{.Name}}{{else}}-{{end}}",
+			"{BACKUP_RANK}", "{{with .Backup}}{{.Rank}}{{else}}-{{end}}",
+			"{CONDUCTOR_TIME}", "{{.ConductorTime}}",
+			"{BACKUP_TIME}", "{{.BackupTime}}",
+		),
+	},
+	"conductor_reminder": {
+		Samples: func() []interface{} {
+			data := conductorReminderData{
+				Name:   "Alice",
+				Rank:   "R3",
+				Date:   "2025-01-06",
+				Time:   "15:00 ST / 16:00 CET",
+				Backup: &templateMember{"Bob", "R4"},
+			}
+			withoutBackup := data
+			withoutBackup.Backup = nil
+			return []interface{}{data, withoutBackup}
+		},
+	},
+	"vs_warning": {
+		Single: true,
+		Samples: func() []interface{} {
+			data := vsWarningMessageData{
+				Week: "2025-01-06",
+				Requirements: []vsWarningRequirement{
+					{"Monday", "Radar Training", 1500000},
+					{"Saturday", "Enemy Buster", 2000000},
+				},
+				Members: []vsWarningMember{
+					{Name: "Alice", Rank: "R3", Missed: []string{"Mon"}, Streak: 0},
+					{Name: "Bob", Rank: "R2", Missed: []string{"Mon", "Sat"}, Streak: 3},
+				},
+				Count: 2,
+			}
+			return []interface{}{data, vsWarningMessageData{Week: "2025-01-13"}}
+		},
+		Legacy: strings.NewReplacer(
+			"{WEEK}", `{{date .Week "medium"}}`,
+			"{REQUIREMENTS}", "{{range $i, $r := .Requirements}}{{if $i}}\n{{end}}{{.Day}} ({{.Theme}}): {{.MinPoints}}{{end}}",
+			"{MEMBERS}", "{{range $i, $m := .Members}}{{if $i}}\n{{end}}{{.Name}} - missed {{join .Missed \", \"}}{{if gt .Streak (len .Missed)}} ({{.Streak}} days in a row){{end}}{{end}}",
+			"{COUNT}", "{{.Count}}",
+		),
+	},
+	"storm_plan": {
+		Single: true,
+		Samples: func() []interface{} {
+			data := stormPlanMessageData{
+				TaskForce: "A",
+				Date:      "2025-01-10",
+				Count:     3,
+				Stages: []stormMessageStage{
+					{Stage: 1, Buildings: []stormMessageBuilding{{"Field Hospital I", []string{"Alice", "Bob"}}}},
+					{Stage: 2, Buildings: []stormMessageBuilding{{"Nuclear Silo", []string{"Carol"}}}},
+				},
+			}
+			undated := data
+			undated.Date = ""
+			return []interface{}{data, undated}
+		},
+		Legacy: strings.NewReplacer(
+			"{TASK_FORCE}", "{{.TaskForce}}",
+			"{DATE}", `{{if .Date}}{{date .Date "long"}}{{else}}TBD{{end}}`,
+			"{ASSIGNMENTS}", "{{range $i, $s := .Stages}}{{if $i}}\n\n{{end}}Stage {{.Stage}}:{{range .Buildings}}\n- {{.Name}}: {{join .Members \", \"}}{{end}}{{end}}",
+			"{COUNT}", "{{.Count}}",
+		),
+	},
+	"storm_member": {
+		Single: true,
+		Samples: func() []interface{} {
+			data := stormMemberMessageData{
+				Name:      "Alice",
+				Rank:      "R3",
+				TaskForce: "A",
+				Date:      "2025-01-10",
+				Slots:     []stormMessageSlot{{1, "Field Hospital I", 1}, {2, "Nuclear Silo", 2}},
+			}
+			undated := data
+			undated.Date = ""
+			return []interface{}{data, undated}
+		},
+		Legacy: strings.NewReplacer(
+			"{NAME}", "{{.Name}}",
+			"{TASK_FORCE}", "{{.TaskForce}}",
+			"{DATE}", `{{if .Date}}{{date .Date "long"}}{{else}}TBD{{end}}`,
+			"{ASSIGNMENT}", "{{range $i, $s := .Slots}}{{if $i}}\n{{end}}Stage {{.Stage}}: {{.Building}}, position {{.Position}}{{end}}",
+		),
+	},
+}
+
+// defaultMessageTemplates are the templates of the single-template kinds on a new install, and the
+// fallback when a template from before the template engine can't be converted
+var defaultMessageTemplates = map[string]string{
+	"train_weekly": `Train Schedule - Week {{date .Week "medium"}}
+
+{{range .Schedules}}{{date .Date "day"}}: {{.Conductor.Name}}{{with .Backup}} (Backup: {{.Name}}){{end}}
+{{end}}
+Next in line:
+{{range .NextInLine}}{{.Name}}
+{{end}}`,
+	"train_daily": `ALL ABOARD! Daily Train Assignment
+
+Date: {{date .Date "long"}}
+
+Today's Conductor: {{.Conductor.Name}} ({{.Conductor.Rank}})
+{{with .Backup}}Backup Engineer: {{.Name}} ({{.Rank}}){{else}}Backup Engineer: none today{{end}}
+
+DEPARTURE SCHEDULE:
+- {{.ConductorTime}} - Conductor {{.Conductor.Name}}, please request train assignment in alliance chat
+{{with .Backup}}- {{$.BackupTime}} - If conductor hasn't shown up, Backup {{.Name}} takes over and assigns train to themselves
+{{end}}
+Remember: Communication is key! Let the alliance know if you can't make it.
+
+All aboard for another successful run!`,
+	"vs_warning": `VS Minimum Check - Week {{date .Week "medium"}}
+
+Daily minimums:
+{{range .Requirements}}{{.Day}} ({{.Theme}}): {{number .MinPoints}}
+{{end}}
+{{.Count}} {{plural .Count "member" "members"}} missed the minimum:
+{{range .Members}}{{.Name}} - missed {{join .Missed ", "}}{{if gt .Streak (len .Missed)}} ({{.Streak}} days in a row){{end}}
+{{end}}
+Every point counts for the alliance - please hit the minimum every day!`,
+	"storm_plan": `DESERT STORM - TASK FORCE {{.TaskForce}}
+{{if .Date}}{{date .Date "long"}}{{else}}TBD{{end}}
+
+Building assignments:
+{{range .Stages}}Stage {{.Stage}}:
+{{range .Buildings}}- {{.Name}}: {{join .Members ", "}}
+{{end}}
+{{end}}Teleport to your building as soon as the battle starts. Good luck everyone!`,
+	"storm_member": `Hi {{.Name}}! You're in Desert Storm Task Force {{.TaskForce}} on {{if .Date}}{{date .Date "long"}}{{else}}TBD{{end}}.
+{{range .Slots}}Stage {{.Stage}}: {{.Building}}, position {{.Position}}
+{{end}}Please teleport to your building as soon as the battle starts. Thanks!`,
+}
+
+// defaultConductorReminders are the conductor_reminder variants, used in turn for natural variety
+var defaultConductorReminders = []string{
+	`Hi {{.Name}}! Just a reminder that you're the train conductor on {{date .Date "day"}}, {{date .Date "short"}}. Please be online around {{.Time}} and ask in alliance chat for the train to be assigned to you. If anything comes up, let us know early so we can coordinate with the backup. Please add a reminder in your phone so you don't forget. Thanks for helping keep the train golden!`,
+	`Hi {{.Name}}! You're scheduled as train conductor on {{date .Date "day"}}, {{date .Date "short"}}. Please be online at {{.Time}} and request the train in alliance chat. If your schedule changes, let us know in advance so we can coordinate with the backup. Add a reminder in your phone to make sure you're on time. Appreciate your support!`,
+	`Hi {{.Name}}! Just a heads-up that you're the train conductor on {{date .Date "day"}}, {{date .Date "short"}}. Please be online around {{.Time}} and ask for the train in alliance chat. If you need help or need to swap, reach out early. Set a phone reminder so you don't miss it. Thanks a lot!`,
+	`Hi {{.Name}}! You're assigned as train conductor on {{date .Date "day"}}, {{date .Date "short"}}. Please be online at {{.Time}} and request the train in alliance chat. If there are any timing issues, let us know so we can plan with the backup. Don't forget to add a reminder in your phone. Thanks for stepping up!`,
+	`Hi {{.Name}}! Reminder that you're the train conductor on {{date .Date "day"}}, {{date .Date "short"}}. Please be online around {{.Time}} and ask in alliance chat for the train assignment. Let us know early if anything changes. Make sure to set a phone reminder. Much appreciated!`,
+	`Hi {{.Name}}! You're scheduled as train conductor on {{date .Date "day"}}, {{date .Date "short"}}. Please be online at {{.Time}} and request the train in alliance chat. If you need assistance or a timing adjustment, just let us know. Add a phone reminder to help you remember. Thanks!`,
+	`Hi {{.Name}}! Just a reminder that you're the train conductor on {{date .Date "day"}}, {{date .Date "short"}}. Please be online around {{.Time}} and ask in alliance chat for the train to be assigned. If anything comes up, please reach out early. Set a reminder in your phone so you're prepared. Thanks for helping the alliance!`,
+}
+
+const (
+	maxMessageTemplateLength = 8000     // characters in a template body
+	maxMessageLength         = 16 << 10 // bytes in a rendered message
+	maxMessageTemplateLoops  = 3        // nested {{range}}s
+)
+
+var messageTemplateNamePattern = regexp.MustCompile(`^[a-z0-9_]{1,50}$`)
+
+// messageTemplateSaves serializes saving template versions
+var messageTemplateSaves sync.Mutex
+
+// messageDateLayouts are the styles of the date helper
+var messageDateLayouts = map[string]string{
+	"day":      "Monday",
+	"dayShort": "Mon",
+	"short":    "2 Jan",
+	"medium":   "Jan 2, 2006",
+	"long":     "Monday, Jan 2, 2006",
+	"iso":      "2006-01-02",
+}
+
+// messageTemplateFuncs are the helpers message templates can call
+var messageTemplateFuncs = template.FuncMap{
+	// date formats a YYYY-MM-DD date in one of messageDateLayouts; other values are kept as they are
+	"date": func(value, style string) (string, error) {
+		layout, ok := messageDateLayouts[style]
+		if !ok {
+			return "", fmt.Errorf("unknown date style %q", style)
+		}
+		date, err := parseDate(value)
+		if err != nil {
+			return value, nil
+		}
+		return date.Format(layout), nil
+	},
+	"join":  strings.Join,
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	"add":   func(a, b int) int { return a + b },
+	// plural picks the singular or plural word for a count
+	"plural": func(count int, singular, plural string) string {
+		if count == 1 {
+			return singular
+		}
+		return plural
+	},
+	// number formats a whole number with thousands separators
+	"number": func(value interface{}) (string, error) {
+		var n int64
+		switch v := value.(type) {
+		case int:
+			n = int64(v)
+		case int64:
+			n = v
+		default:
+			return "", fmt.Errorf("number expects a whole number, got %T", value)
+		}
+		digits := strconv.FormatInt(n, 10)
+		sign := ""
+		if n < 0 {
+			sign, digits = "-", digits[1:]
+		}
+		for i := len(digits) - 3; i > 0; i -= 3 {
+			digits = digits[:i] + "," + digits[i:]
+		}
+		return sign + digits, nil
+	},
+}
+
+// messageTemplateBuiltins are the text/template builtins message templates may use. call, printf
+// and the escapers are left out: templates only read and compare the data they are given.
+var messageTemplateBuiltins = []string{"and", "or", "not", "eq", "ne", "lt", "le", "gt", "ge", "len", "index", "slice", "print"}
+
+// parseMessageTemplate parses a message template and checks it stays inside the sandbox: only the
+// helpers above, no {{define}}/{{template}}/{{block}}, and loops only over lists in the kind's message
+// data (never over numbers), nested at most maxMessageTemplateLoops deep
+func parseMessageTemplate(kind, body string) (*template.Template, error) {
+	templateKind, ok := messageTemplateKinds[kind]
+	if !ok {
+		return nil, fmt.Errorf("unknown template kind %q", kind)
+	}
+	if len(body) > maxMessageTemplateLength {
+		return nil, fmt.Errorf("template is longer than %d characters", maxMessageTemplateLength)
+	}
+
+	tmpl, err := template.New("message").Funcs(messageTemplateFuncs).Parse(body)
+	if err != nil {
+		return nil, err
+	}
+	if len(tmpl.Templates()) > 1 {
+		return nil, fmt.Errorf("{{define}} and {{block}} are not supported")
+	}
+	if tmpl.Tree == nil {
+		return tmpl, nil
+	}
+
+	dataType := reflect.TypeOf(templateKind.Samples()[0])
+	scope := &messageTemplateScope{dot: dataType, vars: map[string]reflect.Type{"$": dataType}}
+	if err := checkMessageTemplateNode(tmpl.Tree.Root, scope); err != nil {
+		return nil, err
+	}
+	return tmpl, nil
+}
+
+// messageTemplateScope is what checkMessageTemplateNode knows at a point of the template: the type
+// of dot and of each variable (nil when it can't tell, e.g. a helper's result) and the loop depth
+type messageTemplateScope struct {
+	dot   reflect.Type
+	vars  map[string]reflect.Type
+	loops int
+}
+
+// enter returns the scope inside an {{if}}, {{with}} or {{range}}, whose variables end with it
+func (s *messageTemplateScope) enter(dot reflect.Type, loops int) *messageTemplateScope {
+	return &messageTemplateScope{dot: dot, vars: maps.Clone(s.vars), loops: loops}
+}
+
+// checkMessageTemplateNode walks a template's parse tree for parseMessageTemplate
+func checkMessageTemplateNode(node parse.Node, scope *messageTemplateScope) error {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return nil
+		}
+		for _, child := range n.Nodes {
+			if err := checkMessageTemplateNode(child, scope); err != nil {
+				return err
+			}
+		}
+	case *parse.ActionNode:
+		return checkMessageTemplatePipe(n.Pipe, scope, scope)
+	case *parse.IfNode:
+		if err := checkMessageTemplatePipe(n.Pipe, scope, scope); err != nil {
+			return err
+		}
+		return checkMessageTemplateBranches(&n.BranchNode, scope.enter(scope.dot, scope.loops), scope)
+	case *parse.WithNode:
+		inner := scope.enter(messageTemplatePipeType(n.Pipe, scope), scope.loops)
+		if err := checkMessageTemplatePipe(n.Pipe, scope, inner); err != nil {
+			return err
+		}
+		return checkMessageTemplateBranches(&n.BranchNode, inner, scope)
+	case *parse.RangeNode:
+		if scope.loops >= maxMessageTemplateLoops {
+			return fmt.Errorf("line %d: {{range}} can be nested at most %d deep", n.Line, maxMessageTemplateLoops)
+		}
+		list := messageTemplatePipeType(n.Pipe, scope)
+		for list != nil && list.Kind() == reflect.Pointer {
+			list = list.Elem()
+		}
+		if list == nil || (list.Kind() != reflect.Slice && list.Kind() != reflect.Array) {
+			return fmt.Errorf("line %d: {{range}} only works over lists in the message data, like .Schedules", n.Line)
+		}
+
+		inner := scope.enter(list.Elem(), scope.loops+1)
+		if err := checkMessageTemplatePipe(n.Pipe, scope, nil); err != nil {
+			return err
+		}
+		// {{range $x := .List}} binds the item, {{range $i, $x := .List}} the index and the item
+		switch len(n.Pipe.Decl) {
+		case 1:
+			inner.vars[n.Pipe.Decl[0].Ident[0]] = list.Elem()
+		case 2:
+			inner.vars[n.Pipe.Decl[0].Ident[0]] = reflect.TypeOf(0)
+			inner.vars[n.Pipe.Decl[1].Ident[0]] = list.Elem()
+		}
+		return checkMessageTemplateBranches(&n.BranchNode, inner, scope)
+	case *parse.TemplateNode:
+		return fmt.Errorf("line %d: {{template}} is not supported", n.Line)
+	}
+	return nil
+}
+
+// checkMessageTemplateBranches checks the body of an {{if}}, {{with}} or {{range}} with its own
+// scope, and its {{else}} with the enclosing dot
+func checkMessageTemplateBranches(branch *parse.BranchNode, inner, outer *messageTemplateScope) error {
+	if err := checkMessageTemplateNode(branch.List, inner); err != nil {
+		return err
+	}
+	return checkMessageTemplateNode(branch.ElseList, outer.enter(outer.dot, outer.loops))
+}
+
+// checkMessageTemplatePipe checks the functions a pipeline calls and records the type of the
+// variables it declares in declScope
+func checkMessageTemplatePipe(pipe *parse.PipeNode, scope, declScope *messageTemplateScope) error {
+	if pipe == nil {
+		return nil
+	}
+	var check func(node parse.Node) error
+	check = func(node parse.Node) error {
+		switch n := node.(type) {
+		case *parse.PipeNode:
+			for _, cmd := range n.Cmds {
+				if err := check(cmd); err != nil {
+					return err
+				}
+			}
+		case *parse.CommandNode:
+			for _, arg := range n.Args {
+				if err := check(arg); err != nil {
+					return err
+				}
+			}
+		case *parse.ChainNode:
+			return check(n.Node)
+		case *parse.IdentifierNode:
+			if _, ok := messageTemplateFuncs[n.Ident]; !ok && !slices.Contains(messageTemplateBuiltins, n.Ident) {
+				return fmt.Errorf("function %q is not allowed in message templates", n.Ident)
+			}
+		}
+		return nil
+	}
+	if err := check(pipe); err != nil {
+		return err
+	}
+
+	if declScope != nil && len(pipe.Decl) == 1 {
+		declScope.vars[pipe.Decl[0].Ident[0]] = messageTemplatePipeType(pipe, scope)
+	}
+	return nil
+}
+
+// messageTemplatePipeType is the type a pipeline evaluates to when it reads the message data
+// (., .Schedules, $.Members, $day.Slots), or nil for anything else
+func messageTemplatePipeType(pipe *parse.PipeNode, scope *messageTemplateScope) reflect.Type {
+	if len(pipe.Cmds) != 1 || len(pipe.Cmds[0].Args) != 1 {
+		return nil
+	}
+	switch arg := pipe.Cmds[0].Args[0].(type) {
+	case *parse.DotNode:
+		return scope.dot
+	case *parse.FieldNode:
+		return messageTemplateFieldType(scope.dot, arg.Ident)
+	case *parse.VariableNode:
+		return messageTemplateFieldType(scope.vars[arg.Ident[0]], arg.Ident[1:])
+	}
+	return nil
+}
+
+// messageTemplateFieldType follows a chain of struct fields from t, or returns nil if one doesn't exist
+func messageTemplateFieldType(t reflect.Type, fields []string) reflect.Type {
+	for _, field := range fields {
+		for t != nil && t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		if t == nil || t.Kind() != reflect.Struct {
+			return nil
+		}
+		f, ok := t.FieldByName(field)
+		if !ok {
+			return nil
+		}
+		t = f.Type
+	}
+	return t
+}
+
+var errMessageTooLong = fmt.Errorf("message is longer than %d bytes", maxMessageLength)
+
+// messageWriter collects a rendered message, failing once it grows past maxMessageLength
+type messageWriter struct {
+	strings.Builder
+}
+
+func (w *messageWriter) Write(p []byte) (int, error) {
+	if w.Len()+len(p) > maxMessageLength {
+		return 0, errMessageTooLong
+	}
+	return w.Builder.Write(p)
+}
+
+// renderMessageTemplate renders a parsed message template with data
+func renderMessageTemplate(tmpl *template.Template, data interface{}) (string, error) {
+	var out messageWriter
+	if err := tmpl.Execute(&out, data); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+// validateMessageTemplate parses a template and renders it with every sample of its kind, so
+// mistyped fields and unguarded optional parts (like a missing backup) are caught before saving
+func validateMessageTemplate(kind, body string) ([]string, error) {
+	tmpl, err := parseMessageTemplate(kind, body)
+	if err != nil {
+		return nil, err
+	}
+
+	outputs := []string{}
+	for _, sample := range messageTemplateKinds[kind].Samples() {
+		output, err := renderMessageTemplate(tmpl, sample)
+		if err != nil {
+			return nil, err
+		}
+		outputs = append(outputs, output)
+	}
+	return outputs, nil
+}
+
+// legacyMessageTemplate converts a settings template from before the template engine: {PLACEHOLDER}s
+// become template actions and literal \n sequences become line breaks
+func legacyMessageTemplate(kind, body string) string {
+	body = strings.ReplaceAll(body, `\n`, "\n")
+	if legacy := messageTemplateKinds[kind].Legacy; legacy != nil {
+		body = legacy.Replace(body)
+	}
+	return body
+}
+
+// loadMessageTemplates loads the current version of each message template, ordered by kind and name
+func loadMessageTemplates(where string, args ...interface{}) ([]MessageTemplate, error) {
+	rows, err := db.Query(`
+		SELECT t.id, t.name, t.kind, t.version, t.body, t.created_by, t.created_at
+		FROM message_templates t
+		WHERE t.version = (SELECT MAX(version) FROM message_templates WHERE name = t.name)
+		`+where+`
+		ORDER BY t.kind, t.name
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	templates := []MessageTemplate{}
+	for rows.Next() {
+		var t MessageTemplate
+		if err := rows.Scan(&t.ID, &t.Name, &t.Kind, &t.Version, &t.Body, &t.CreatedBy, &t.CreatedAt); err != nil {
+			return nil, err
+		}
+		templates = append(templates, t)
+	}
+	return templates, rows.Err()
+}
+
+// loadKindTemplates loads and parses the current templates of a kind, ordered by name
+func loadKindTemplates(kind string) ([]*template.Template, error) {
+	stored, err := loadMessageTemplates("AND t.kind = ?", kind)
+	if err != nil {
+		return nil, err
+	}
+	if len(stored) == 0 {
+		return nil, fmt.Errorf("no %s message template", kind)
+	}
+
+	templates := []*template.Template{}
+	for _, t := range stored {
+		tmpl, err := parseMessageTemplate(kind, t.Body)
+		if err != nil {
+			return nil, fmt.Errorf("template %s: %v", t.Name, err)
+		}
+		templates = append(templates, tmpl.Option("missingkey=error"))
+	}
+	return templates, nil
+}
+
+// renderKindMessage renders the template of a single-template kind with data
+func renderKindMessage(kind string, data interface{}) (string, error) {
+	templates, err := loadKindTemplates(kind)
+	if err != nil {
+		return "", err
+	}
+	message, err := renderMessageTemplate(templates[0], data)
+	if err != nil {
+		return "", fmt.Errorf("template %s: %v", kind, err)
+	}
+	return message, nil
+}
+
+// Get the current version of every message template
+func getMessageTemplates(w http.ResponseWriter, r *http.Request) {
+	templates, err := loadMessageTemplates("")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(templates)
+}
+
+// Get every version of a message template, newest first
+func getMessageTemplateVersions(w http.ResponseWriter, r *http.Request) {
+	rows, err := db.Query(`
+		SELECT id, name, kind, version, body, created_by, created_at
+		FROM message_templates
+		WHERE name = ?
+		ORDER BY version DESC
+	`, mux.Vars(r)["name"])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	versions := []MessageTemplate{}
+	for rows.Next() {
+		var t MessageTemplate
+		if err := rows.Scan(&t.ID, &t.Name, &t.Kind, &t.Version, &t.Body, &t.CreatedBy, &t.CreatedAt); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		versions = append(versions, t)
+	}
+	if len(versions) == 0 {
+		http.Error(w, "Template not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(versions)
+}
+
+// Save a new version of a message template; a new conductor reminder variant needs its kind
+func saveMessageTemplate(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+	var input struct {
+		Kind string `json:"kind"`
+		Body string `json:"body"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Read the current version and add the next one in one transaction, one save at a time, so
+	// concurrent saves can't both pick the same version number
+	messageTemplateSaves.Lock()
+	defer messageTemplateSaves.Unlock()
+	tx, err := db.Begin()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	current := MessageTemplate{Name: name, Kind: input.Kind}
+	err = tx.QueryRow(`
+		SELECT kind, version, body FROM message_templates WHERE name = ? ORDER BY version DESC LIMIT 1
+	`, name).Scan(&current.Kind, &current.Version, &current.Body)
+	exists := err == nil
+	if err != nil && err != sql.ErrNoRows {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if exists {
+		if input.Kind != "" && input.Kind != current.Kind {
+			http.Error(w, "A template's kind can't be changed", http.StatusBadRequest)
+			return
+		}
+	} else {
+		if !messageTemplateNamePattern.MatchString(name) {
+			http.Error(w, "Template names use lowercase letters, digits and underscores", http.StatusBadRequest)
+			return
+		}
+		if kind, ok := messageTemplateKinds[input.Kind]; !ok || kind.Single {
+			http.Error(w, "New templates can only be conductor_reminder variants", http.StatusBadRequest)
+			return
+		}
+	}
+
+	if _, err := validateMessageTemplate(current.Kind, input.Body); err != nil {
+		http.Error(w, "Invalid template: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Saving the current text again doesn't make a new version
+	if !exists || current.Body != input.Body {
+		session, _ := store.Get(r, "session")
+		username, _ := session.Values["username"].(string)
+		_, err = tx.Exec(`INSERT INTO message_templates (name, kind, version, body, created_by) VALUES (?, ?, ?, ?, ?)`,
+			name, current.Kind, current.Version+1, input.Body, username)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	saved, err := loadMessageTemplates("AND t.name = ?", name)
+	if err != nil || len(saved) == 0 {
+		http.Error(w, "Failed to load the saved template", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(saved[0])
+}
+
+// Delete a conductor reminder variant with its versions; the last variant of a kind is kept
+func deleteMessageTemplate(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+	existing, err := loadMessageTemplates("AND t.name = ?", name)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if len(existing) == 0 {
+		http.Error(w, "Template not found", http.StatusNotFound)
+		return
+	}
+
+	var variants int
+	if err := db.QueryRow("SELECT COUNT(DISTINCT name) FROM message_templates WHERE kind = ?", existing[0].Kind).Scan(&variants); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if messageTemplateKinds[existing[0].Kind].Single || variants <= 1 {
+		http.Error(w, "This template is required and can't be deleted", http.StatusConflict)
+		return
+	}
+
+	if _, err := db.Exec("DELETE FROM message_templates WHERE name = ?", name); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Validate a template and preview it with sample data, without saving it
+func previewMessageTemplate(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Kind string `json:"kind"`
+		Body string `json:"body"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if _, ok := messageTemplateKinds[input.Kind]; !ok {
+		http.Error(w, "Unknown template kind", http.StatusBadRequest)
+		return
+	}
+
+	response := map[string]interface{}{"valid": true}
+	outputs, err := validateMessageTemplate(input.Kind, input.Body)
+	if err != nil {
+		response["valid"] = false
+		response["error"] = err.Error()
+	} else {
+		response["outputs"] = outputs
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// Generate weekly schedule message
+func generateWeeklyMessage(w http.ResponseWriter, r *http.Request) {
+	startDate := r.URL.Query().Get("start")
+	if startDate == "" {
+		http.Error(w, "start date is required", http.StatusBadRequest)
+		return
+	}
+
+	// Parse start date
+	weekStart, err := parseDate(startDate)
+	if err != nil {
+		http.Error(w, "Invalid date format", http.StatusBadRequest)
+		return
+	}
+
+	data := trainWeeklyMessageData{Week: formatDateString(weekStart), Schedules: []trainMessageDay{}, NextInLine: []templateMember{}}
+
+	// Get schedules for the week
+	weekEnd := weekStart.AddDate(0, 0, 6)
+	rows, err := db.Query(`
+		SELECT 
+			ts.date, m1.name as conductor_name, m1.rank as conductor_rank,
+			m2.name as backup_name, m2.rank as backup_rank
+		FROM train_schedules ts
+		JOIN members m1 ON ts.conductor_id = m1.id
+		LEFT JOIN members m2 ON ts.backup_id = m2.id
+		WHERE ts.date >= ? AND ts.date <= ?
+		ORDER BY ts.date
+	`, formatDateString(weekStart), formatDateString(weekEnd))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var day trainMessageDay
+		var backupName, backupRank sql.NullString
+		if err := rows.Scan(&day.Date, &day.Conductor.Name, &day.Conductor.Rank, &backupName, &backupRank); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if backupName.Valid {
+			day.Backup = &templateMember{Name: backupName.String, Rank: backupRank.String}
+		}
+		data.Schedules = append(data.Schedules, day)
+	}
+
+	// Build ranking context as of the following week to get the next 3 candidates
+	// (this week's duties must count, otherwise this week's conductors show up as "next")
+	ctx, err := buildRankingContext(weekStart.AddDate(0, 0, 7))
+	if err != nil {
+		http.Error(w, "Failed to load ranking context: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Get all eligible members and score them
+	memberRows, err := db.Query("SELECT id, name, rank FROM members WHERE eligible = 1 ORDER BY name")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer memberRows.Close()
+
+	type ScoredMember struct {
+		Name  string
+		Rank  string
+		Score int
+	}
+
+	var scoredMembers []ScoredMember
+	for memberRows.Next() {
+		var m Member
+		if err := memberRows.Scan(&m.ID, &m.Name, &m.Rank); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		score := calculateMemberScore(m, ctx)
+		scoredMembers = append(scoredMembers, ScoredMember{
+			Name:  m.Name,
+			Rank:  m.Rank,
+			Score: score,
+		})
+	}
+
+	// Sort by score (highest first)
+	for i := 0; i < len(scoredMembers); i++ {
+		for j := i + 1; j < len(scoredMembers); j++ {
+			if scoredMembers[j].Score > scoredMembers[i].Score {
+				scoredMembers[i], scoredMembers[j] = scoredMembers[j], scoredMembers[i]
+			}
+		}
+	}
+
+	// Get top 3
+	limit := 3
+	if len(scoredMembers) < 3 {
+		limit = len(scoredMembers)
+	}
+	for i := 0; i < limit; i++ {
+		data.NextInLine = append(data.NextInLine, templateMember{Name: scoredMembers[i].Name, Rank: scoredMembers[i].Rank})
+	}
+
+	message, err := renderKindMessage("train_weekly", data)
+	if err != nil {
+		http.Error(w, "Failed to render template: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": message,
+	})
+}
+
+// Generate daily message with conductor and backup for a specific date
+func generateDailyMessage(w http.ResponseWriter, r *http.Request) {
+	dateParam := r.URL.Query().Get("date")
+	if dateParam == "" {
+		http.Error(w, "date is required", http.StatusBadRequest)
+		return
+	}
+
+	// Parse date
+	date, err := parseDate(dateParam)
+	if err != nil {
+		http.Error(w, "Invalid date format", http.StatusBadRequest)
+		return
+	}
+
+	// Get settings
+	settings, err := loadSettings()
+	if err != nil {
+		http.Error(w, "Failed to load settings: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Get schedule for the specific date
+	data := trainDailyMessageData{Date: formatDateString(date)}
+	var backupName, backupRank sql.NullString
+	err = db.QueryRow(`
+		SELECT 
+			m1.name as conductor_name, m1.rank as conductor_rank,
+			m2.name as backup_name, m2.rank as backup_rank
+		FROM train_schedules ts
+		JOIN members m1 ON ts.conductor_id = m1.id
+		LEFT JOIN members m2 ON ts.backup_id = m2.id
+		WHERE ts.date = ?
+	`, data.Date).Scan(&data.Conductor.Name, &data.Conductor.Rank, &backupName, &backupRank)
+
+	if err != nil {
+		if err.Error() == "sql: no rows in result set" {
+			http.Error(w, "No schedule found for this date", http.StatusNotFound)
+		} else {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if backupName.Valid {
+		data.Backup = &templateMember{Name: backupName.String, Rank: backupRank.String}
+	}
+
+	// Times in server time and the alliance's display time zones
+	location, _ := gameClock()
+	zones := loadTimezones(settings.DisplayTimezones)
+	data.ConductorTime = formatGameTime(location, date, settings.TrainConductorTime, zones)
+	data.BackupTime = formatGameTime(location, date, settings.TrainBackupTime, zones)
+
+	message, err := renderKindMessage("train_daily", data)
+	if err != nil {
+		http.Error(w, "Failed to render template: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": message,
+	})
+}
+
+// Generate individual conductor reminder messages for the week
+func generateConductorMessages(w http.ResponseWriter, r *http.Request) {
+	startDate := r.URL.Query().Get("start")
+	if startDate == "" {
+		http.Error(w, "start date is required", http.StatusBadRequest)
+		return
+	}
+
+	// Parse start date
+	weekStart, err := parseDate(startDate)
+	if err != nil {
+		http.Error(w, "Invalid date format", http.StatusBadRequest)
+		return
+	}
+
+	settings, err := loadSettings()
+	if err != nil {
+		http.Error(w, "Failed to load settings: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	location, _ := gameClock()
+	displayZones := loadTimezones(settings.DisplayTimezones)
+
+	// Get schedules for the week
+	weekEnd := weekStart.AddDate(0, 0, 6)
+	rows, err := db.Query(`
+		SELECT 
+			ts.date, m1.name as conductor_name, m1.rank as conductor_rank, m1.timezone,
+			m2.name as backup_name, m2.rank as backup_rank
+		FROM train_schedules ts
+		JOIN members m1 ON ts.conductor_id = m1.id
+		LEFT JOIN members m2 ON ts.backup_id = m2.id
+		WHERE ts.date >= ? AND ts.date <= ?
+		ORDER BY ts.date
+	`, formatDateString(weekStart), formatDateString(weekEnd))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	// Reminder variants, used in turn for natural variety
+	messageTemplates, err := loadKindTemplates("conductor_reminder")
+	if err != nil {
+		http.Error(w, "Failed to load templates: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	type DayMessage struct {
+		Day     string `json:"day"`
+		Name    string `json:"name"`
+		Message string `json:"message"`
+	}
+
+	var messages []DayMessage
+	templateIndex := 0
+
+	for rows.Next() {
+		var data conductorReminderData
+		var timezone, backupName, backupRank sql.NullString
+		if err := rows.Scan(&data.Date, &data.Name, &data.Rank, &timezone, &backupName, &backupRank); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if backupName.Valid {
+			data.Backup = &templateMember{Name: backupName.String, Rank: backupRank.String}
+		}
+		dateObj, _ := parseDate(data.Date)
+
+		// Server time plus the conductor's own time zone, or the alliance's display zones if it isn't known
+		zones := displayZones
+		if timezone.Valid {
+			if memberZones := loadTimezones(timezone.String); len(memberZones) > 0 {
+				zones = memberZones
+			}
+		}
+		data.Time = formatGameTime(location, dateObj, settings.TrainConductorTime, zones)
+
+		// Get template and cycle through them
+		message, err := renderMessageTemplate(messageTemplates[templateIndex], data)
+		if err != nil {
+			http.Error(w, "Failed to render template: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		templateIndex = (templateIndex + 1) % len(messageTemplates)
+
+		messages = append(messages, DayMessage{
+			Day:     dateObj.Format("Monday"),
+			Name:    data.Name,
+			Message: message,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"messages": messages,
+	})
+}
+
+var allianceEventWeekdays = []string{"monday", "tuesday", "wednesday", "thursday", "friday", "saturday", "sunday"}
+
+// maxAllianceEventScheduleDays caps the range a schedule is loaded or auto-scheduled for
+const maxAllianceEventScheduleDays = 92
+
+// The train is the built-in event type: a conductor picked by ranking and an R4/R5 backup picked at random
+const (
+	trainEventKey         = "train"
+	trainConductorSlotKey = "conductor"
+	trainBackupSlotKey    = "backup"
+)
+
+// splitList splits a comma separated column, dropping empty entries
+func splitList(value string) []string {
+	items := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// loadAllianceEventTypes loads event types with their slots, ordered by name
+func loadAllianceEventTypes(where string, args ...interface{}) ([]AllianceEventType, error) {
+	rows, err := db.Query(`
+		SELECT id, name, recurrence, weekdays, interval_weeks, anchor_date, start_time, active, COALESCE(system_key, ''), created_at
+		FROM alliance_event_types
+		`+where+`
+		ORDER BY name
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	eventTypes := []AllianceEventType{}
+	typeIndex := make(map[int]int)
+	for rows.Next() {
+		var et AllianceEventType
+		var weekdays string
+		if err := rows.Scan(&et.ID, &et.Name, &et.Recurrence, &weekdays, &et.IntervalWeeks, &et.AnchorDate,
+			&et.StartTime, &et.Active, &et.SystemKey, &et.CreatedAt); err != nil {
+			return nil, err
+		}
+		et.Weekdays = splitList(weekdays)
+		et.Slots = []AllianceEventSlot{}
+		typeIndex[et.ID] = len(eventTypes)
+		eventTypes = append(eventTypes, et)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	slotRows, err := db.Query(`
+		SELECT id, event_type_id, name, count, ranks, selection, sort_order, COALESCE(system_key, '')
+		FROM alliance_event_slots
+		ORDER BY sort_order, id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer slotRows.Close()
+
+	for slotRows.Next() {
+		var slot AllianceEventSlot
+		var eventTypeID int
+		var ranks string
+		if err := slotRows.Scan(&slot.ID, &eventTypeID, &slot.Name, &slot.Count, &ranks, &slot.Selection, &slot.SortOrder, &slot.SystemKey); err != nil {
+			return nil, err
+		}
+		slot.Ranks = splitList(ranks)
+		if i, found := typeIndex[eventTypeID]; found {
+			eventTypes[i].Slots = append(eventTypes[i].Slots, slot)
+		}
+	}
+	return eventTypes, slotRows.Err()
+}
+
+// loadAllianceEventTypeFromURL loads the event type in the URL, writing the error response when it can't
+func loadAllianceEventTypeFromURL(w http.ResponseWriter, r *http.Request) (AllianceEventType, bool) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return AllianceEventType{}, false
+	}
+
+	eventTypes, err := loadAllianceEventTypes("WHERE id = ?", id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return AllianceEventType{}, false
+	}
+	if len(eventTypes) == 0 {
+		http.Error(w, "Event type not found", http.StatusNotFound)
+		return AllianceEventType{}, false
+	}
+	return eventTypes[0], true
+}
+
+// validateAllianceEventType normalises an event type and returns a message when it is not valid
+func validateAllianceEventType(et *AllianceEventType) string {
+	et.Name = strings.TrimSpace(et.Name)
+	if et.Name == "" {
+		return "Event type name is required"
+	}
+
+	switch et.Recurrence {
+	case "daily":
+		et.Weekdays = []string{}
+	case "weekly":
+		if len(et.Weekdays) == 0 {
+			return "weekly events need at least one weekday"
+		}
+		for i, day := range et.Weekdays {
+			et.Weekdays[i] = strings.ToLower(strings.TrimSpace(day))
+			if !slices.Contains(allianceEventWeekdays, et.Weekdays[i]) {
+				return fmt.Sprintf("Invalid weekday: %s", day)
+			}
+		}
+	default:
+		return "Invalid recurrence - must be daily or weekly"
+	}
+
+	if et.IntervalWeeks == 0 {
+		et.IntervalWeeks = 1
+	}
+	if et.IntervalWeeks < 1 || et.IntervalWeeks > 8 {
+		return "interval_weeks must be between 1 and 8"
+	}
+
+	if et.AnchorDate == "" {
+		et.AnchorDate = formatDateString(gameToday())
+	}
+	if _, err := parseDate(et.AnchorDate); err != nil {
+		return "Invalid anchor_date format (expected YYYY-MM-DD)"
+	}
+
+	if et.StartTime != nil && strings.TrimSpace(*et.StartTime) == "" {
+		et.StartTime = nil
+	}
+	if et.StartTime != nil {
+		if _, err := time.Parse("15:04", *et.StartTime); err != nil {
+			return "Invalid start_time format (expected HH:MM)"
+		}
+	}
+
+	if len(et.Slots) == 0 {
+		return "An event type needs at least one slot"
+	}
+	slotIDs := make(map[int]bool)
+	for i := range et.Slots {
+		slot := &et.Slots[i]
+		if slot.ID > 0 {
+			if slotIDs[slot.ID] {
+				return fmt.Sprintf("Slot %d is listed more than once", slot.ID)
+			}
+			slotIDs[slot.ID] = true
+		}
+		slot.Name = strings.TrimSpace(slot.Name)
+		if slot.Name == "" {
+			return "Slot name is required"
+		}
+		if slot.Count < 1 || slot.Count > 50 {
+			return "Slot count must be between 1 and 50"
+		}
+		if slot.Selection == "" {
+			slot.Selection = "ranking"
+		}
+		if slot.Selection != "ranking" && slot.Selection != "random" {
+			return "Invalid slot selection - must be ranking or random"
+		}
+		for j, rank := range slot.Ranks {
+			slot.Ranks[j] = strings.ToUpper(strings.TrimSpace(rank))
+			if !slices.Contains([]string{"R1", "R2", "R3", "R4", "R5"}, slot.Ranks[j]) {
+				return fmt.Sprintf("Invalid rank: %s", rank)
+			}
+		}
+		slot.SortOrder = i
+	}
+	return ""
+}
+
+// validateTrainEventType returns a message when an update would change more of the train than its
+// names, start time or anchor: the train scheduler relies on a daily conductor and R4/R5 backup
+func validateTrainEventType(existing, et AllianceEventType) string {
+	if et.Recurrence != "daily" {
+		return "The train runs daily"
+	}
+	if len(et.Slots) != len(existing.Slots) {
+		return "The train's conductor and backup slots can't be added to or removed"
+	}
+	for _, slot := range et.Slots {
+		i := slices.IndexFunc(existing.Slots, func(s AllianceEventSlot) bool { return s.ID == slot.ID })
+		if i < 0 {
+			return "The train's conductor and backup slots can't be added to or removed"
+		}
+		current := existing.Slots[i]
+		if slot.Count != current.Count || slot.Selection != current.Selection || !slices.Equal(slot.Ranks, current.Ranks) {
+			return fmt.Sprintf("The train's %s slot can only be renamed", current.Name)
+		}
+	}
+	return ""
+}
+
+// saveAllianceEventSlots updates slots sent with an ID, adds the new ones and removes the slots
+// (and their assignments) that are no longer listed
+func saveAllianceEventSlots(tx *sql.Tx, et AllianceEventType) error {
+	keep := []interface{}{et.ID}
+	placeholders := []string{}
+	saved := make(map[int]bool)
+	for _, slot := range et.Slots {
+		ranks := strings.Join(slot.Ranks, ",")
+		if slot.ID > 0 {
+			if saved[slot.ID] {
+				return fmt.Errorf("slot %d is listed more than once", slot.ID)
+			}
+			saved[slot.ID] = true
+			result, err := tx.Exec(`UPDATE alliance_event_slots SET name = ?, count = ?, ranks = ?, selection = ?, sort_order = ?
+				WHERE id = ? AND event_type_id = ?`, slot.Name, slot.Count, ranks, slot.Selection, slot.SortOrder, slot.ID, et.ID)
+			if err != nil {
+				return err
+			}
+			if affected, _ := result.RowsAffected(); affected > 0 {
+				keep = append(keep, slot.ID)
+				placeholders = append(placeholders, "?")
+				continue
+			}
+		}
+		result, err := tx.Exec(`INSERT INTO alliance_event_slots (event_type_id, name, count, ranks, selection, sort_order)
+			VALUES (?, ?, ?, ?, ?, ?)`, et.ID, slot.Name, slot.Count, ranks, slot.Selection, slot.SortOrder)
+		if err != nil {
+			return err
+		}
+		id, _ := result.LastInsertId()
+		keep = append(keep, id)
+		placeholders = append(placeholders, "?")
+	}
+
+	notKept := "event_type_id = ? AND id NOT IN (" + strings.Join(placeholders, ", ") + ")"
+	if _, err := tx.Exec("DELETE FROM alliance_event_assignments WHERE slot_id IN (SELECT id FROM alliance_event_slots WHERE "+notKept+")", keep...); err != nil {
+		return err
+	}
+	_, err := tx.Exec("DELETE FROM alliance_event_slots WHERE "+notKept, keep...)
+	return err
+}
+
+// Get all alliance event types
+func getAllianceEventTypes(w http.ResponseWriter, r *http.Request) {
+	eventTypes, err := loadAllianceEventTypes("")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(eventTypes)
+}
+
+// Create an alliance event type with its slots
+func createAllianceEventType(w http.ResponseWriter, r *http.Request) {
+	et := AllianceEventType{Active: true}
+	if err := json.NewDecoder(r.Body).Decode(&et); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	et.SystemKey = ""
+
+	if msg := validateAllianceEventType(&et); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	var existingID int
+	if err := db.QueryRow("SELECT id FROM alliance_event_types WHERE name = ?", et.Name).Scan(&existingID); err == nil {
+		http.Error(w, "Event type already exists", http.StatusConflict)
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`INSERT INTO alliance_event_types (name, recurrence, weekdays, interval_weeks, anchor_date, start_time, active)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`, et.Name, et.Recurrence, strings.Join(et.Weekdays, ","), et.IntervalWeeks, et.AnchorDate, et.StartTime, et.Active)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	id, _ := result.LastInsertId()
+	et.ID = int(id)
+
+	for i := range et.Slots {
+		et.Slots[i].ID = 0
+	}
+	if err := saveAllianceEventSlots(tx, et); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	eventTypes, err := loadAllianceEventTypes("WHERE id = ?", et.ID)
+	if err != nil || len(eventTypes) == 0 {
+		http.Error(w, "Failed to load the created event type", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(eventTypes[0])
+}
+
+// Update an alliance event type; slots left out are removed with their assignments
+func updateAllianceEventType(w http.ResponseWriter, r *http.Request) {
+	existing, ok := loadAllianceEventTypeFromURL(w, r)
+	if !ok {
+		return
+	}
+
+	// Fields left out of the body keep their current values. Slots are decoded fresh, since decoding
+	// into the current ones would leave a new slot with the ID and fields of the one at its index.
+	et := existing
+	et.Slots = nil
+	if err := json.NewDecoder(r.Body).Decode(&et); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if et.Slots == nil {
+		et.Slots = existing.Slots
+	}
+	et.ID = existing.ID
+	et.SystemKey = existing.SystemKey
+
+	if msg := validateAllianceEventType(&et); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+	if et.SystemKey == trainEventKey {
+		if msg := validateTrainEventType(existing, et); msg != "" {
+			http.Error(w, msg, http.StatusBadRequest)
+			return
+		}
+	}
+
+	var existingID int
+	if err := db.QueryRow("SELECT id FROM alliance_event_types WHERE name = ? AND id != ?", et.Name, et.ID).Scan(&existingID); err == nil {
+		http.Error(w, "Event type already exists", http.StatusConflict)
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`UPDATE alliance_event_types
+		SET name = ?, recurrence = ?, weekdays = ?, interval_weeks = ?, anchor_date = ?, start_time = ?, active = ?
+		WHERE id = ?`, et.Name, et.Recurrence, strings.Join(et.Weekdays, ","), et.IntervalWeeks, et.AnchorDate, et.StartTime, et.Active, et.ID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := saveAllianceEventSlots(tx, et); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	eventTypes, err := loadAllianceEventTypes("WHERE id = ?", et.ID)
+	if err != nil || len(eventTypes) == 0 {
+		http.Error(w, "Failed to load the updated event type", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(eventTypes[0])
+}
+
+// Delete an alliance event type with its slots and assignments
+func deleteAllianceEventType(w http.ResponseWriter, r *http.Request) {
+	et, ok := loadAllianceEventTypeFromURL(w, r)
+	if !ok {
+		return
+	}
+	if et.SystemKey != "" {
+		http.Error(w, "The train is built in and can't be deleted", http.StatusConflict)
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	for _, table := range []string{"alliance_event_assignments", "alliance_event_slots"} {
+		if _, err := tx.Exec("DELETE FROM "+table+" WHERE event_type_id = ?", et.ID); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	if _, err := tx.Exec("DELETE FROM alliance_event_types WHERE id = ?", et.ID); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// allianceEventDates lists the dates from start to end (inclusive) the event type occurs on.
+// Weekly events count every interval_weeks from the week of their anchor date.
+func allianceEventDates(et AllianceEventType, start, end time.Time) []time.Time {
+	anchor, _ := parseDate(et.AnchorDate)
+	anchorWeek := getMondayOfWeek(anchor)
+
+	dates := []time.Time{}
+	for date := start; !date.After(end); date = date.AddDate(0, 0, 1) {
+		if et.Recurrence == "weekly" {
+			if !slices.Contains(et.Weekdays, strings.ToLower(date.Weekday().String())) {
+				continue
+			}
+			weeks := daysBetween(formatDateString(anchorWeek), formatDateString(getMondayOfWeek(date))) / 7
+			if ((weeks%et.IntervalWeeks)+et.IntervalWeeks)%et.IntervalWeeks != 0 {
+				continue
+			}
+		}
+		dates = append(dates, date)
+	}
+	return dates
+}
+
+// parseAllianceEventRange reads start/end dates (YYYY-MM-DD); without an end the range is the start's
+// week, Monday to Sunday, like the train schedule
+func parseAllianceEventRange(startParam, endParam string) (time.Time, time.Time, string) {
+	start := getMondayOfWeek(gameToday())
+	if startParam != "" {
+		date, err := parseDate(startParam)
+		if err != nil {
+			return time.Time{}, time.Time{}, "Invalid start date format (expected YYYY-MM-DD)"
+		}
+		start = date
+	}
+
+	if endParam == "" {
+		start = getMondayOfWeek(start)
+		return start, start.AddDate(0, 0, 6), ""
+	}
+	end, err := parseDate(endParam)
+	if err != nil {
+		return time.Time{}, time.Time{}, "Invalid end date format (expected YYYY-MM-DD)"
+	}
+	if end.Before(start) {
+		return time.Time{}, time.Time{}, "end date must not be before start date"
+	}
+	if daysBetween(formatDateString(start), formatDateString(end)) >= maxAllianceEventScheduleDays {
+		return time.Time{}, time.Time{}, fmt.Sprintf("Date range must be shorter than %d days", maxAllianceEventScheduleDays)
+	}
+	return start, end, ""
+}
+
+// loadAllianceEventSchedule lists the occurrences from start to end with their assignments;
+// dates that still hold assignments are kept even if the recurrence changed since
+func loadAllianceEventSchedule(et AllianceEventType, start, end time.Time) ([]AllianceEventOccurrence, error) {
+	rows, err := db.Query(`
+		SELECT a.id, a.date, a.slot_id, s.name, a.position, a.member_id, m.name, m.rank, a.score, a.showed_up, a.notes
+		FROM alliance_event_assignments a
+		JOIN alliance_event_slots s ON s.id = a.slot_id
+		JOIN members m ON m.id = a.member_id
+		WHERE a.event_type_id = ? AND a.date >= ? AND a.date <= ?
+		ORDER BY a.date, s.sort_order, s.id, a.position
+	`, et.ID, formatDateString(start), formatDateString(end))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	byDate := make(map[string][]AllianceEventAssignment)
+	for rows.Next() {
+		var a AllianceEventAssignment
+		if err := rows.Scan(&a.ID, &a.Date, &a.SlotID, &a.SlotName, &a.Position, &a.MemberID, &a.MemberName,
+			&a.MemberRank, &a.Score, &a.ShowedUp, &a.Notes); err != nil {
+			return nil, err
+		}
+		byDate[a.Date] = append(byDate[a.Date], a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	dates := []string{}
+	for _, date := range allianceEventDates(et, start, end) {
+		dates = append(dates, formatDateString(date))
+	}
+	for date := range byDate {
+		if !slices.Contains(dates, date) {
+			dates = append(dates, date)
+		}
+	}
+	sort.Strings(dates)
+
+	occurrences := []AllianceEventOccurrence{}
+	for _, date := range dates {
+		assignments := byDate[date]
+		if assignments == nil {
+			assignments = []AllianceEventAssignment{}
+		}
+		occurrences = append(occurrences, AllianceEventOccurrence{Date: date, Assignments: assignments})
+	}
+	return occurrences, nil
+}
+
+// Get an event type's schedule (?start, optional ?end; defaults to the current week)
+func getAllianceEventSchedule(w http.ResponseWriter, r *http.Request) {
+	et, ok := loadAllianceEventTypeFromURL(w, r)
+	if !ok {
+		return
+	}
+
+	start, end, msg := parseAllianceEventRange(r.URL.Query().Get("start"), r.URL.Query().Get("end"))
+	if msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	occurrences, err := loadAllianceEventSchedule(et, start, end)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"event_type":  et,
+		"start":       formatDateString(start),
+		"end":         formatDateString(end),
+		"occurrences": occurrences,
+	})
+}
+
+// loadAllianceEventDutyStats summarises each member's duties for an event type before asOf in the
+// shape of the train conductor stats, so the ranking penalties apply per event type
+func loadAllianceEventDutyStats(eventTypeID int, asOf time.Time) (map[int]ConductorStat, float64, error) {
+	rows, err := db.Query(`
+		SELECT member_id, COUNT(*), MAX(date)
+		FROM alliance_event_assignments
+		WHERE event_type_id = ? AND date < ?
+		GROUP BY member_id
+	`, eventTypeID, formatDateString(asOf))
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	stats := make(map[int]ConductorStat)
+	total := 0
+	for rows.Next() {
+		var memberID, count int
+		var lastDate string
+		if err := rows.Scan(&memberID, &count, &lastDate); err != nil {
+			return nil, 0, err
+		}
+		stats[memberID] = ConductorStat{Count: count, LastDate: &lastDate}
+		total += count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	var avg float64
+	if len(stats) > 0 {
+		avg = float64(total) / float64(len(stats))
+	}
+	return stats, avg, nil
+}
+
+// Auto-schedule an event type from start to end: every slot of every occurrence is filled from the
+// eligible members of its ranks, spreading duties so nobody repeats before everyone in the pool had a turn.
+// "ranking" slots take the best ranked member (scored like train conductors, with this event type's duty
+// history in place of conductor stats); "random" slots pick at random, like train backups.
+func autoScheduleAllianceEvent(w http.ResponseWriter, r *http.Request) {
+	et, ok := loadAllianceEventTypeFromURL(w, r)
+	if !ok {
+		return
+	}
+	if et.SystemKey == trainEventKey {
+		http.Error(w, "The train is auto-scheduled a week at a time from the train schedule, which keeps its weekly conductor rotation", http.StatusBadRequest)
+		return
+	}
+
+	var input struct {
+		StartDate string `json:"start_date"`
+		EndDate   string `json:"end_date"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if input.StartDate == "" {
+		http.Error(w, "start_date is required", http.StatusBadRequest)
+		return
+	}
+
+	start, end, msg := parseAllianceEventRange(input.StartDate, input.EndDate)
+	if msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	dates := allianceEventDates(et, start, end)
+	if len(dates) == 0 {
+		http.Error(w, "The event does not occur in this date range", http.StatusBadRequest)
+		return
+	}
+
+	ctx, err := buildRankingContext(start)
+	if err != nil {
+		http.Error(w, "Failed to load ranking context: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	ctx.ConductorStats, ctx.AvgConductorCount, err = loadAllianceEventDutyStats(et.ID, start)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	rows, err := db.Query("SELECT id, name, rank, COALESCE(eligible, 1) FROM members WHERE COALESCE(eligible, 1) = 1 ORDER BY name")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	var candidates []Member
+	for rows.Next() {
+		var m Member
+		if err := rows.Scan(&m.ID, &m.Name, &m.Rank, &m.Eligible); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		candidates = append(candidates, m)
+	}
+	scoredCandidates := scoreMembers(candidates, ctx)
+
+	type plannedAssignment struct {
+		Date     string
+		SlotID   int
+		Position int
+		MemberID int
+		Score    int
+	}
+
+	var planned []plannedAssignment
+	runDuties := make(map[int]int)
+	unfilled := 0
+	for _, date := range dates {
+		dateStr := formatDateString(date)
+		taken := make(map[int]bool)
+
+		for _, slot := range et.Slots {
+			for position := 1; position <= slot.Count; position++ {
+				// Members of the slot's ranks not yet on duty this day, with the fewest duties in this run
+				var pool []ScoredMember
+				fewest := -1
+				for _, sc := range scoredCandidates {
+					if taken[sc.Member.ID] || (len(slot.Ranks) > 0 && !slices.Contains(slot.Ranks, sc.Member.Rank)) {
+						continue
+					}
+					duties := runDuties[sc.Member.ID]
+					if fewest < 0 || duties < fewest {
+						pool = nil
+						fewest = duties
+					}
+					if duties == fewest {
+						pool = append(pool, sc)
+					}
+				}
+
+				if len(pool) == 0 {
+					unfilled++
+					continue
+				}
+
+				pick := pool[0]
+				if slot.Selection == "random" {
+					pick = pool[time.Now().UnixNano()%int64(len(pool))]
+				}
+				taken[pick.Member.ID] = true
+				runDuties[pick.Member.ID]++
+				planned = append(planned, plannedAssignment{
+					Date:     dateStr,
+					SlotID:   slot.ID,
+					Position: position,
+					MemberID: pick.Member.ID,
+					Score:    pick.Score,
+				})
+			}
+		}
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec("DELETE FROM alliance_event_assignments WHERE event_type_id = ? AND date >= ? AND date <= ?",
+		et.ID, formatDateString(start), formatDateString(end))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	for _, p := range planned {
+		_, err = tx.Exec(`INSERT INTO alliance_event_assignments (event_type_id, date, slot_id, position, member_id, score)
+			VALUES (?, ?, ?, ?, ?, ?)`, et.ID, p.Date, p.SlotID, p.Position, p.MemberID, p.Score)
+		if err != nil {
+			http.Error(w, "Failed to create schedule: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	occurrences, err := loadAllianceEventSchedule(et, start, end)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":     fmt.Sprintf("%s scheduled successfully", et.Name),
+		"occurrences": occurrences,
+		"unfilled":    unfilled,
+	})
+}
+
+// Assign a member to an event slot by hand, or record whether they showed up
+func saveAllianceEventAssignment(w http.ResponseWriter, r *http.Request) {
+	et, ok := loadAllianceEventTypeFromURL(w, r)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Date     string  `json:"date"`
+		SlotID   int     `json:"slot_id"`
+		Position int     `json:"position"`
+		MemberID int     `json:"member_id"`
+		ShowedUp *bool   `json:"showed_up"`
+		Notes    *string `json:"notes"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if _, err := parseDate(input.Date); err != nil {
+		http.Error(w, "Invalid date format (expected YYYY-MM-DD)", http.StatusBadRequest)
+		return
+	}
+	if input.Position == 0 {
+		input.Position = 1
+	}
+
+	slotIndex := slices.IndexFunc(et.Slots, func(slot AllianceEventSlot) bool { return slot.ID == input.SlotID })
+	if slotIndex < 0 {
+		http.Error(w, "Slot not found for this event type", http.StatusBadRequest)
+		return
+	}
+	slot := et.Slots[slotIndex]
+	if input.Position < 1 || input.Position > slot.Count {
+		http.Error(w, fmt.Sprintf("Invalid position - %s has %d place(s)", slot.Name, slot.Count), http.StatusBadRequest)
+		return
+	}
+
+	var rank string
+	if err := db.QueryRow("SELECT rank FROM members WHERE id = ?", input.MemberID).Scan(&rank); err != nil {
+		http.Error(w, "Member not found", http.StatusBadRequest)
+		return
+	}
+	if len(slot.Ranks) > 0 && !slices.Contains(slot.Ranks, rank) {
+		http.Error(w, fmt.Sprintf("%s is limited to %s", slot.Name, strings.Join(slot.Ranks, ", ")), http.StatusBadRequest)
+		return
+	}
+
+	var otherSlot string
+	err := db.QueryRow(`
+		SELECT s.name FROM alliance_event_assignments a
+		JOIN alliance_event_slots s ON s.id = a.slot_id
+		WHERE a.event_type_id = ? AND a.date = ? AND a.member_id = ? AND NOT (a.slot_id = ? AND a.position = ?)
+	`, et.ID, input.Date, input.MemberID, input.SlotID, input.Position).Scan(&otherSlot)
+	if err == nil {
+		http.Error(w, fmt.Sprintf("Member is already assigned to %s on this date", otherSlot), http.StatusConflict)
+		return
+	}
+
+	_, err = db.Exec(`
+		INSERT INTO alliance_event_assignments (event_type_id, date, slot_id, position, member_id, showed_up, notes)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(event_type_id, date, slot_id, position) DO UPDATE SET
+			member_id = excluded.member_id,
+			score = CASE WHEN alliance_event_assignments.member_id = excluded.member_id THEN alliance_event_assignments.score END,
+			showed_up = excluded.showed_up,
+			notes = excluded.notes
+	`, et.ID, input.Date, input.SlotID, input.Position, input.MemberID, input.ShowedUp, input.Notes)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Assignment saved"})
+}
+
+// Remove an event assignment
+func deleteAllianceEventAssignment(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	result, err := db.Exec("DELETE FROM alliance_event_assignments WHERE id = ?", id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		http.Error(w, "Assignment not found", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// R4/R5/Admin middleware - checks if user has R4, R5 rank or is admin
+func r4r5Middleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		session, _ := store.Get(r, "session")
+
+		// Check if user is admin
+		if isAdmin, ok := session.Values["is_admin"].(bool); ok && isAdmin {
+			next(w, r)
+			return
+		}
+
+		memberID, ok := session.Values["member_id"].(int)
+		if !ok {
+			http.Error(w, "Access denied - R4, R5 rank or admin privileges required", http.StatusForbidden)
+			return
+		}
+
+		// Get member rank
+		var rank string
+		err := db.QueryRow("SELECT rank FROM members WHERE id = ?", memberID).Scan(&rank)
+		if err != nil {
+			http.Error(w, "Member not found", http.StatusNotFound)
+			return
+		}
+
+		if rank != "R4" && rank != "R5" {
+			http.Error(w, "Access denied - R4, R5 rank or admin privileges required", http.StatusForbidden)
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+// stormBoost returns the optional boost text of a seeded building
+func stormBoost(text string) *string {
+	return &text
+}
+
+// defaultStormBuildings seeds the catalogue on first start; points are per second
+var defaultStormBuildings = []StormBuilding{
+	{ID: "field_hospital_1", Name: "Field Hospital I", Stage: 1, Points: 30, Priority: "CRITICAL", Capacity: 4, Boost: stormBoost("Heal 15 troops/10s")},
+	{ID: "field_hospital_2", Name: "Field Hospital II", Stage: 1, Points: 30, Priority: "CRITICAL", Capacity: 4, Boost: stormBoost("Heal 15 troops/10s")},
+	{ID: "field_hospital_3", Name: "Field Hospital III", Stage: 1, Points: 30, Priority: "CRITICAL", Capacity: 4, Boost: stormBoost("Heal 15 troops/10s")},
+	{ID: "field_hospital_4", Name: "Field Hospital IV", Stage: 1, Points: 30, Priority: "CRITICAL", Capacity: 4, Boost: stormBoost("Heal 15 troops/10s")},
+	{ID: "oil_refinery_1", Name: "Oil Refinery I", Stage: 1, Points: 50, Priority: "HIGH", Capacity: 4},
+	{ID: "oil_refinery_2", Name: "Oil Refinery II", Stage: 1, Points: 50, Priority: "HIGH", Capacity: 4},
+	{ID: "science_hub", Name: "Science Hub", Stage: 1, Points: 10, Priority: "MEDIUM", Capacity: 4, Boost: stormBoost("Teleport cooldown -50%")},
+	{ID: "info_center", Name: "Info Center", Stage: 1, Points: 10, Priority: "LOW", Capacity: 4, Boost: stormBoost("+10% all points")},
+	{ID: "nuclear_silo", Name: "Nuclear Silo", Stage: 2, Points: 80, Priority: "CRITICAL", Capacity: 4, Boost: stormBoost("HIGHEST POINTS!")},
+	{ID: "arsenal", Name: "Arsenal", Stage: 2, Points: 10, Priority: "MEDIUM", Capacity: 4, Boost: stormBoost("+15% ATK/DEF/HP")},
+	{ID: "mercenary_factory", Name: "Mercenary Factory", Stage: 2, Points: 10, Priority: "MEDIUM", Capacity: 4, Boost: stormBoost("-15% enemy stats")},
+}
+
+var stormPriorities = []string{"CRITICAL", "HIGH", "MEDIUM", "LOW"}
+
+var stormBuildingIDPattern = regexp.MustCompile(`[^a-z0-9]+`)
+
+// loadStormBuildings returns the building catalogue ordered by stage and sort order
+func loadStormBuildings() ([]StormBuilding, error) {
+	rows, err := db.Query(`
+		SELECT id, name, stage, points, priority, capacity, boost, sort_order
+		FROM storm_buildings
+		ORDER BY stage, sort_order, name
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	buildings := []StormBuilding{}
+	for rows.Next() {
+		var b StormBuilding
+		if err := rows.Scan(&b.ID, &b.Name, &b.Stage, &b.Points, &b.Priority, &b.Capacity, &b.Boost, &b.SortOrder); err != nil {
+			return nil, err
+		}
+		buildings = append(buildings, b)
+	}
+	return buildings, rows.Err()
+}
+
+// validateStormBuilding normalises a building and returns a message when it is not valid
+func validateStormBuilding(b *StormBuilding) string {
+	b.Name = strings.TrimSpace(b.Name)
+	b.Priority = strings.ToUpper(strings.TrimSpace(b.Priority))
+	if b.Boost != nil && strings.TrimSpace(*b.Boost) == "" {
+		b.Boost = nil
+	}
+
+	if b.Name == "" {
+		return "Building name is required"
+	}
+	if b.Stage < 1 {
+		return "stage must be at least 1"
+	}
+	if b.Points < 0 {
+		return "points cannot be negative"
+	}
+	if b.Capacity < 1 || b.Capacity > 20 {
+		return "capacity must be between 1 and 20"
+	}
+	for _, priority := range stormPriorities {
+		if b.Priority == priority {
+			return ""
+		}
+	}
+	return "Invalid priority - must be CRITICAL, HIGH, MEDIUM or LOW"
+}
+
+// Get storm buildings catalogue
+func getStormBuildings(w http.ResponseWriter, r *http.Request) {
+	buildings, err := loadStormBuildings()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(buildings)
+}
+
+// Create a storm building
+func createStormBuilding(w http.ResponseWriter, r *http.Request) {
+	var b StormBuilding
+	if err := json.NewDecoder(r.Body).Decode(&b); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if msg := validateStormBuilding(&b); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	// Derive the ID from the name unless one was given
+	if strings.TrimSpace(b.ID) == "" {
+		b.ID = b.Name
+	}
+	b.ID = strings.Trim(stormBuildingIDPattern.ReplaceAllString(strings.ToLower(b.ID), "_"), "_")
+	if b.ID == "" {
+		http.Error(w, "Building ID must contain letters or digits", http.StatusBadRequest)
+		return
+	}
+
+	var existingID string
+	err := db.QueryRow("SELECT id FROM storm_buildings WHERE id = ?", b.ID).Scan(&existingID)
+	if err == nil {
+		http.Error(w, "Storm building already exists", http.StatusConflict)
+		return
+	}
+
+	// Append to the end of its stage unless a sort order was given
+	if b.SortOrder == 0 {
+		err = db.QueryRow("SELECT COALESCE(MAX(sort_order), -1) + 1 FROM storm_buildings WHERE stage = ?", b.Stage).Scan(&b.SortOrder)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	_, err = db.Exec(`INSERT INTO storm_buildings (id, name, stage, points, priority, capacity, boost, sort_order)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`, b.ID, b.Name, b.Stage, b.Points, b.Priority, b.Capacity, b.Boost, b.SortOrder)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(b)
+}
+
+// Update a storm building; slots beyond a reduced capacity are unassigned
+func updateStormBuilding(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	var b StormBuilding
+	if err := json.NewDecoder(r.Body).Decode(&b); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	b.ID = id
+
+	if msg := validateStormBuilding(&b); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`UPDATE storm_buildings
+		SET name = ?, stage = ?, points = ?, priority = ?, capacity = ?, boost = ?, sort_order = ?
+		WHERE id = ?`, b.Name, b.Stage, b.Points, b.Priority, b.Capacity, b.Boost, b.SortOrder, id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		http.Error(w, "Storm building not found", http.StatusNotFound)
+		return
+	}
+
+	result, err = tx.Exec("DELETE FROM storm_assignments WHERE building_id = ? AND position > ?", id, b.Capacity)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	removed, _ := result.RowsAffected()
+
+	// A member holds one building per stage, so after a stage change the assignments here of members
+	// already placed elsewhere in the new stage (same plan and task force) are removed
+	result, err = tx.Exec(`DELETE FROM storm_assignments WHERE building_id = ? AND id IN (
+			SELECT sa.id FROM storm_assignments sa
+			JOIN storm_assignments other ON other.event_id IS sa.event_id AND other.task_force = sa.task_force
+				AND other.member_id = sa.member_id AND other.building_id != sa.building_id
+			JOIN storm_buildings ob ON ob.id = other.building_id
+			WHERE sa.building_id = ? AND ob.stage = ?
+		)`, id, id, b.Stage)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	stageConflicts, _ := result.RowsAffected()
+	removed += stageConflicts
+
+	if err := tx.Commit(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":             "Storm building updated",
+		"removed_assignments": removed,
+	})
+}
+
+// Delete a storm building together with its assignments
+func deleteStormBuilding(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	tx, err := db.Begin()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec("DELETE FROM storm_buildings WHERE id = ?", id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		http.Error(w, "Storm building not found", http.StatusNotFound)
+		return
+	}
+
+	if _, err := tx.Exec("DELETE FROM storm_assignments WHERE building_id = ?", id); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// stormEventScope checks the storm event an assignment request is for and returns it as a query
+// argument; 0 is the undated plan (nil). Past events are history, so editing them is refused.
+func stormEventScope(w http.ResponseWriter, eventID int, editing bool) (interface{}, bool) {
+	if eventID == 0 {
+		return nil, true
+	}
+
+	var eventDate string
+	err := db.QueryRow("SELECT event_date FROM storm_events WHERE id = ?", eventID).Scan(&eventDate)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Storm event not found", http.StatusNotFound)
+		return nil, false
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return nil, false
+	}
+	if editing && eventDate < formatDateString(gameToday()) {
+		http.Error(w, "Assignments of past storm events are kept as history", http.StatusConflict)
+		return nil, false
+	}
+	return eventID, true
+}
+
+// stormEventIDParam reads the optional ?event_id query parameter (0 when absent)
+func stormEventIDParam(w http.ResponseWriter, r *http.Request) (int, bool) {
+	param := r.URL.Query().Get("event_id")
+	if param == "" {
+		return 0, true
+	}
+	eventID, err := strconv.Atoi(param)
+	if err != nil || eventID < 0 {
+		http.Error(w, "Invalid event ID", http.StatusBadRequest)
+		return 0, false
+	}
+	return eventID, true
+}
+
+// Get storm assignments (optional ?event_id, otherwise the undated plan)
+func getStormAssignments(w http.ResponseWriter, r *http.Request) {
+	taskForce := r.URL.Query().Get("task_force")
+	if taskForce == "" {
+		taskForce = "A"
+	}
+
+	eventID, ok := stormEventIDParam(w, r)
+	if !ok {
+		return
+	}
+	event, ok := stormEventScope(w, eventID, false)
+	if !ok {
+		return
+	}
+
+	rows, err := db.Query(`
+		SELECT id, task_force, building_id, member_id, position
+		FROM storm_assignments
+		WHERE task_force = ? AND event_id IS ?
+		ORDER BY building_id, position
+	`, taskForce, event)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	assignments := []StormAssignment{}
+	for rows.Next() {
+		var a StormAssignment
+		if err := rows.Scan(&a.ID, &a.TaskForce, &a.BuildingID, &a.MemberID, &a.Position); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		assignments = append(assignments, a)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(assignments)
+}
+
+// Save storm assignments, replacing the task force's plan for the event (or the undated plan)
+func saveStormAssignments(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		EventID     int    `json:"event_id"`
+		TaskForce   string `json:"task_force"`
+		Assignments []struct {
+			BuildingID string `json:"building_id"`
+			MemberID   int    `json:"member_id"`
+			Position   int    `json:"position"`
+		} `json:"assignments"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Validate task force
+	if request.TaskForce != "A" && request.TaskForce != "B" {
+		http.Error(w, "Invalid task force - must be A or B", http.StatusBadRequest)
+		return
+	}
+
+	event, ok := stormEventScope(w, request.EventID, true)
+	if !ok {
+		return
+	}
+
+	// Validate against the building catalogue
+	buildings, err := loadStormBuildings()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	buildingByID := make(map[string]StormBuilding)
+	for _, b := range buildings {
+		buildingByID[b.ID] = b
+	}
+
+	takenSlots := make(map[string]bool)
+	stageOfMember := make(map[int]map[int]string)
+	for _, assignment := range request.Assignments {
+		building, found := buildingByID[assignment.BuildingID]
+		if !found {
+			http.Error(w, fmt.Sprintf("Unknown storm building: %s", assignment.BuildingID), http.StatusBadRequest)
+			return
+		}
+		if assignment.Position < 1 || assignment.Position > building.Capacity {
+			http.Error(w, fmt.Sprintf("Invalid position %d for %s - must be between 1 and %d", assignment.Position, building.Name, building.Capacity), http.StatusBadRequest)
+			return
+		}
+
+		slot := fmt.Sprintf("%s/%d", building.ID, assignment.Position)
+		if takenSlots[slot] {
+			http.Error(w, fmt.Sprintf("Position %d of %s is assigned more than once", assignment.Position, building.Name), http.StatusBadRequest)
+			return
+		}
+		takenSlots[slot] = true
+
+		if stageOfMember[building.Stage] == nil {
+			stageOfMember[building.Stage] = make(map[int]string)
+		}
+		if other, assigned := stageOfMember[building.Stage][assignment.MemberID]; assigned {
+			http.Error(w, fmt.Sprintf("Member %d is assigned to both %s and %s in stage %d", assignment.MemberID, buildingByID[other].Name, building.Name, building.Stage), http.StatusBadRequest)
+			return
+		}
+		stageOfMember[building.Stage][assignment.MemberID] = building.ID
+
+		var memberExists bool
+		if err := db.QueryRow("SELECT COUNT(*) > 0 FROM members WHERE id = ?", assignment.MemberID).Scan(&memberExists); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !memberExists {
+			http.Error(w, fmt.Sprintf("Member %d not found", assignment.MemberID), http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Start transaction
+	tx, err := db.Begin()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	// Delete existing assignments for this task force
+	_, err = tx.Exec("DELETE FROM storm_assignments WHERE task_force = ? AND event_id IS ?", request.TaskForce, event)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Insert new assignments
+	for _, assignment := range request.Assignments {
+		_, err = tx.Exec(`
+			INSERT INTO storm_assignments (event_id, task_force, building_id, member_id, position)
+			VALUES (?, ?, ?, ?, ?)
+		`, event, request.TaskForce, assignment.BuildingID, assignment.MemberID, assignment.Position)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "Assignments saved successfully",
+	})
+}
+
+// Delete storm assignments for a task force (optional ?event_id, otherwise the undated plan)
+func deleteStormAssignments(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	taskForce := vars["taskForce"]
+
+	if taskForce != "A" && taskForce != "B" {
+		http.Error(w, "Invalid task force - must be A or B", http.StatusBadRequest)
+		return
+	}
+
+	eventID, ok := stormEventIDParam(w, r)
+	if !ok {
+		return
+	}
+	event, ok := stormEventScope(w, eventID, true)
+	if !ok {
+		return
+	}
+
+	_, err := db.Exec("DELETE FROM storm_assignments WHERE task_force = ? AND event_id IS ?", taskForce, event)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+var stormAvailabilities = []string{"A", "B", "either", "unavailable"}
+
+// loadStormEvents loads events with their signup counts, attendance and results, newest first
+func loadStormEvents(where string, args ...interface{}) ([]StormEvent, error) {
+	rows, err := db.Query(`
+		SELECT e.id, e.event_date, e.notes, e.created_at,
+		       (SELECT COUNT(*) FROM storm_signups s WHERE s.event_id = e.id AND s.availability = 'A'),
+		       (SELECT COUNT(*) FROM storm_signups s WHERE s.event_id = e.id AND s.availability = 'B'),
+		       (SELECT COUNT(*) FROM storm_signups s WHERE s.event_id = e.id AND s.availability = 'either'),
+		       (SELECT COUNT(*) FROM storm_signups s WHERE s.event_id = e.id AND s.availability = 'unavailable'),
+		       (SELECT COUNT(*) FROM storm_signups s WHERE s.event_id = e.id AND s.attended = 1),
+		       (SELECT COUNT(*) FROM storm_signups s WHERE s.event_id = e.id AND s.attended = 0)
+		FROM storm_events e
+		`+where+`
+		ORDER BY e.event_date DESC
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []StormEvent{}
+	eventIndex := make(map[int]int)
+	for rows.Next() {
+		var e StormEvent
+		var forA, forB, either, unavailable int
+		if err := rows.Scan(&e.ID, &e.EventDate, &e.Notes, &e.CreatedAt, &forA, &forB, &either, &unavailable, &e.Attended, &e.NoShows); err != nil {
+			return nil, err
+		}
+		e.SignupCounts = map[string]int{"A": forA, "B": forB, "either": either, "unavailable": unavailable}
+		e.Results = []StormEventResult{}
+		eventIndex[e.ID] = len(events)
+		events = append(events, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	resultRows, err := db.Query(`
+		SELECT event_id, task_force, result, our_score, enemy_score, opponent
+		FROM storm_event_results
+		ORDER BY event_id, task_force
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer resultRows.Close()
+
+	for resultRows.Next() {
+		var eventID int
+		var result StormEventResult
+		if err := resultRows.Scan(&eventID, &result.TaskForce, &result.Result, &result.OurScore, &result.EnemyScore, &result.Opponent); err != nil {
+			return nil, err
+		}
+		if i, found := eventIndex[eventID]; found {
+			events[i].Results = append(events[i].Results, result)
+		}
+	}
+	return events, resultRows.Err()
+}
+
+// loadStormEventFromURL loads the event in the URL, writing the error response when it can't
+func loadStormEventFromURL(w http.ResponseWriter, r *http.Request) (StormEvent, bool) {
+	eventID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid event ID", http.StatusBadRequest)
+		return StormEvent{}, false
+	}
+
+	events, err := loadStormEvents("WHERE e.id = ?", eventID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return StormEvent{}, false
+	}
+	if len(events) == 0 {
+		http.Error(w, "Storm event not found", http.StatusNotFound)
+		return StormEvent{}, false
+	}
+	return events[0], true
+}
+
+// Get all storm events with the caller's own signup
+func getStormEvents(w http.ResponseWriter, r *http.Request) {
+	events, err := loadStormEvents("")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	session, _ := store.Get(r, "session")
+	if memberID, ok := session.Values["member_id"].(int); ok {
+		for i := range events {
+			var availability *string
+			err := db.QueryRow("SELECT availability FROM storm_signups WHERE event_id = ? AND member_id = ?",
+				events[i].ID, memberID).Scan(&availability)
+			if err != nil && err != sql.ErrNoRows {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			events[i].MyAvailability = availability
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(events)
+}
+
+// Get a storm event with every member's signup, assigned task force and attendance
+func getStormEvent(w http.ResponseWriter, r *http.Request) {
+	event, ok := loadStormEventFromURL(w, r)
+	if !ok {
+		return
+	}
+
+	rows, err := db.Query(`
+		SELECT m.id, m.name, m.rank, s.availability, s.attended,
+		       (SELECT MIN(sa.task_force) FROM storm_assignments sa WHERE sa.event_id = ? AND sa.member_id = m.id),
+		       s.updated_at
+		FROM members m
+		LEFT JOIN storm_signups s ON s.member_id = m.id AND s.event_id = ?
+		ORDER BY m.name
+	`, event.ID, event.ID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	detail := StormEventDetail{StormEvent: event, Signups: []StormSignup{}}
+	for rows.Next() {
+		var signup StormSignup
+		if err := rows.Scan(&signup.MemberID, &signup.MemberName, &signup.MemberRank, &signup.Availability,
+			&signup.Attended, &signup.TaskForce, &signup.UpdatedAt); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		detail.Signups = append(detail.Signups, signup)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(detail)
+}
+
+// Create a storm event
+func createStormEvent(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		EventDate string  `json:"event_date"`
+		Notes     *string `json:"notes"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	eventDate, err := parseDate(request.EventDate)
+	if err != nil {
+		http.Error(w, "Invalid event_date format (expected YYYY-MM-DD)", http.StatusBadRequest)
+		return
+	}
+
+	var existingID int
+	err = db.QueryRow("SELECT id FROM storm_events WHERE event_date = ?", formatDateString(eventDate)).Scan(&existingID)
+	if err == nil {
+		http.Error(w, "A storm event already exists on this date", http.StatusConflict)
+		return
+	}
+
+	session, _ := store.Get(r, "session")
+	userID, _ := session.Values["user_id"].(int)
+	var createdBy interface{}
+	if userID > 0 {
+		createdBy = userID
+	}
+
+	result, err := db.Exec("INSERT INTO storm_events (event_date, notes, created_by) VALUES (?, ?, ?)",
+		formatDateString(eventDate), request.Notes, createdBy)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	id, _ := result.LastInsertId()
+	events, err := loadStormEvents("WHERE e.id = ?", id)
+	if err != nil || len(events) == 0 {
+		http.Error(w, "Failed to load the created storm event", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(events[0])
+}
+
+// Update a storm event's date and notes
+func updateStormEvent(w http.ResponseWriter, r *http.Request) {
+	event, ok := loadStormEventFromURL(w, r)
+	if !ok {
+		return
+	}
+
+	var request struct {
+		EventDate string  `json:"event_date"`
+		Notes     *string `json:"notes"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	eventDate, err := parseDate(request.EventDate)
+	if err != nil {
+		http.Error(w, "Invalid event_date format (expected YYYY-MM-DD)", http.StatusBadRequest)
+		return
+	}
+
+	// A past event has its signups, attendance and results recorded against that day, and an
+	// upcoming one can't be moved into the past where its assignments would be history
+	today := formatDateString(gameToday())
+	if formatDateString(eventDate) != event.EventDate {
+		if event.EventDate < today {
+			http.Error(w, "The date of a past storm event can't be changed", http.StatusConflict)
+			return
+		}
+		if formatDateString(eventDate) < today {
+			http.Error(w, "A storm event can't be moved to a past date", http.StatusConflict)
+			return
+		}
+	}
+
+	var existingID int
+	err = db.QueryRow("SELECT id FROM storm_events WHERE event_date = ? AND id != ?", formatDateString(eventDate), event.ID).Scan(&existingID)
+	if err == nil {
+		http.Error(w, "A storm event already exists on this date", http.StatusConflict)
+		return
+	}
+
+	_, err = db.Exec("UPDATE storm_events SET event_date = ?, notes = ? WHERE id = ?", formatDateString(eventDate), request.Notes, event.ID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Storm event updated"})
+}
+
+// Delete a storm event with its signups, results and assignments
+func deleteStormEvent(w http.ResponseWriter, r *http.Request) {
+	event, ok := loadStormEventFromURL(w, r)
+	if !ok {
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	for _, table := range []string{"storm_assignments", "storm_signups", "storm_event_results"} {
+		if _, err := tx.Exec("DELETE FROM "+table+" WHERE event_id = ?", event.ID); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	if _, err := tx.Exec("DELETE FROM storm_events WHERE id = ?", event.ID); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Sign up for a storm event; members sign themselves up, R4/R5 may sign up anyone
+func signupStormEvent(w http.ResponseWriter, r *http.Request) {
+	event, ok := loadStormEventFromURL(w, r)
+	if !ok {
+		return
+	}
+
+	var request struct {
+		MemberID     int    `json:"member_id"`
+		Availability string `json:"availability"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if !slices.Contains(stormAvailabilities, request.Availability) {
+		http.Error(w, "Invalid availability - must be A, B, either or unavailable", http.StatusBadRequest)
+		return
+	}
+
+	session, _ := store.Get(r, "session")
+	ownMemberID, _ := session.Values["member_id"].(int)
+	if request.MemberID == 0 {
+		request.MemberID = ownMemberID
+	}
+	if request.MemberID == 0 {
+		http.Error(w, "Your account is not linked to a member", http.StatusBadRequest)
+		return
+	}
+	if request.MemberID != ownMemberID && !sessionCanManageRanks(r) {
+		http.Error(w, "Forbidden: Only R4/R5 members can sign up other members", http.StatusForbidden)
+		return
+	}
+
+	if event.EventDate < formatDateString(gameToday()) {
+		http.Error(w, "Signups for past storm events are closed", http.StatusConflict)
+		return
+	}
+
+	var memberExists bool
+	if err := db.QueryRow("SELECT COUNT(*) > 0 FROM members WHERE id = ?", request.MemberID).Scan(&memberExists); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !memberExists {
+		http.Error(w, "Member not found", http.StatusNotFound)
+		return
+	}
+
+	_, err := db.Exec(`
+		INSERT INTO storm_signups (event_id, member_id, availability)
+		VALUES (?, ?, ?)
+		ON CONFLICT(event_id, member_id) DO UPDATE SET availability = excluded.availability, updated_at = CURRENT_TIMESTAMP
+	`, event.ID, request.MemberID, request.Availability)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Signup saved"})
+}
+
+// Record who showed up to a storm event; attended null clears a member's attendance
+func recordStormAttendance(w http.ResponseWriter, r *http.Request) {
+	event, ok := loadStormEventFromURL(w, r)
+	if !ok {
+		return
+	}
+
+	var request struct {
+		Attendance []struct {
+			MemberID int   `json:"member_id"`
+			Attended *bool `json:"attended"`
+		} `json:"attendance"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if event.EventDate > formatDateString(gameToday()) {
+		http.Error(w, "Attendance can only be recorded once the storm event has taken place", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	for _, entry := range request.Attendance {
+		var memberExists bool
+		if err := tx.QueryRow("SELECT COUNT(*) > 0 FROM members WHERE id = ?", entry.MemberID).Scan(&memberExists); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !memberExists {
+			http.Error(w, fmt.Sprintf("Member %d not found", entry.MemberID), http.StatusBadRequest)
+			return
+		}
+
+		_, err = tx.Exec(`
+			INSERT INTO storm_signups (event_id, member_id, attended)
+			VALUES (?, ?, ?)
+			ON CONFLICT(event_id, member_id) DO UPDATE SET attended = excluded.attended, updated_at = CURRENT_TIMESTAMP
+		`, event.ID, entry.MemberID, entry.Attended)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "Attendance saved",
+		"updated": len(request.Attendance),
+	})
+}
+
+// Record a storm event's result per task force
+func recordStormResults(w http.ResponseWriter, r *http.Request) {
+	event, ok := loadStormEventFromURL(w, r)
+	if !ok {
+		return
+	}
+
+	var request struct {
+		Results []StormEventResult `json:"results"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if event.EventDate > formatDateString(gameToday()) {
+		http.Error(w, "Results can only be recorded once the storm event has taken place", http.StatusBadRequest)
+		return
+	}
+
+	for _, result := range request.Results {
+		if result.TaskForce != "A" && result.TaskForce != "B" {
+			http.Error(w, "Invalid task force - must be A or B", http.StatusBadRequest)
+			return
+		}
+		if result.Result != "win" && result.Result != "loss" && result.Result != "draw" {
+			http.Error(w, "Invalid result - must be win, loss or draw", http.StatusBadRequest)
+			return
+		}
+		if (result.OurScore != nil && *result.OurScore < 0) || (result.EnemyScore != nil && *result.EnemyScore < 0) {
+			http.Error(w, "Scores cannot be negative", http.StatusBadRequest)
+			return
+		}
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	for _, result := range request.Results {
+		_, err = tx.Exec(`
+			INSERT INTO storm_event_results (event_id, task_force, result, our_score, enemy_score, opponent)
+			VALUES (?, ?, ?, ?, ?, ?)
+			ON CONFLICT(event_id, task_force) DO UPDATE SET
+				result = excluded.result,
+				our_score = excluded.our_score,
+				enemy_score = excluded.enemy_score,
+				opponent = excluded.opponent
+		`, event.ID, result.TaskForce, result.Result, result.OurScore, result.EnemyScore, result.Opponent)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Results saved"})
+}
+
+// stormPlanSlot is one saved storm assignment with its building and member names
+type stormPlanSlot struct {
+	Stage      int
+	BuildingID string
+	Building   string
+	Position   int
+	MemberID   int
+	MemberName string
+	MemberRank string
+}
+
+// loadStormPlan reads the message parameters (?task_force, optional ?event_id) and loads the saved
+// assignments in catalogue order, with the event date ("" for the undated plan)
+func loadStormPlan(w http.ResponseWriter, r *http.Request) (string, string, []stormPlanSlot, bool) {
+	taskForce := r.URL.Query().Get("task_force")
+	if taskForce == "" {
+		taskForce = "A"
+	}
+	if taskForce != "A" && taskForce != "B" {
+		http.Error(w, "Invalid task force - must be A or B", http.StatusBadRequest)
+		return "", "", nil, false
+	}
+
+	eventID, ok := stormEventIDParam(w, r)
+	if !ok {
+		return "", "", nil, false
+	}
+	event, ok := stormEventScope(w, eventID, false)
+	if !ok {
+		return "", "", nil, false
+	}
+
+	eventDate := ""
+	if eventID > 0 {
+		if err := db.QueryRow("SELECT event_date FROM storm_events WHERE id = ?", eventID).Scan(&eventDate); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return "", "", nil, false
+		}
+	}
+
+	rows, err := db.Query(`
+		SELECT b.stage, b.id, b.name, sa.position, m.id, m.name, m.rank
+		FROM storm_assignments sa
+		JOIN storm_buildings b ON b.id = sa.building_id
+		JOIN members m ON m.id = sa.member_id
+		WHERE sa.task_force = ? AND sa.event_id IS ?
+		ORDER BY b.stage, b.sort_order, b.name, b.id, sa.position
+	`, taskForce, event)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return "", "", nil, false
+	}
+	defer rows.Close()
+
+	slots := []stormPlanSlot{}
+	for rows.Next() {
+		var slot stormPlanSlot
+		if err := rows.Scan(&slot.Stage, &slot.BuildingID, &slot.Building, &slot.Position, &slot.MemberID, &slot.MemberName, &slot.MemberRank); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return "", "", nil, false
+		}
+		slots = append(slots, slot)
+	}
+	return taskForce, eventDate, slots, true
+}
+
+// Generate the chat message with a task force's storm plan per stage and building
+func generateStormPlanMessage(w http.ResponseWriter, r *http.Request) {
+	taskForce, eventDate, slots, ok := loadStormPlan(w, r)
+	if !ok {
+		return
+	}
+
+	// Group the assignments by stage, then building
+	data := stormPlanMessageData{TaskForce: taskForce, Date: eventDate, Stages: []stormMessageStage{}}
+	members := make(map[int]bool)
+	for i := 0; i < len(slots); {
+		slot := slots[i]
+		if i == 0 || slot.Stage != slots[i-1].Stage {
+			data.Stages = append(data.Stages, stormMessageStage{Stage: slot.Stage, Buildings: []stormMessageBuilding{}})
+		}
+
+		building := stormMessageBuilding{Name: slot.Building, Members: []string{}}
+		for ; i < len(slots) && slots[i].Stage == slot.Stage && slots[i].BuildingID == slot.BuildingID; i++ {
+			building.Members = append(building.Members, slots[i].MemberName)
+			members[slots[i].MemberID] = true
+		}
+		stage := &data.Stages[len(data.Stages)-1]
+		stage.Buildings = append(stage.Buildings, building)
+	}
+	data.Count = len(members)
+
+	message, err := renderKindMessage("storm_plan", data)
+	if err != nil {
+		http.Error(w, "Failed to render template: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": message,
+		"count":   len(members),
+	})
+}
+
+// Generate personal storm messages telling each assigned member their buildings and positions
+func generateStormMemberMessages(w http.ResponseWriter, r *http.Request) {
+	taskForce, eventDate, slots, ok := loadStormPlan(w, r)
+	if !ok {
+		return
+	}
+
+	messageTemplates, err := loadKindTemplates("storm_member")
+	if err != nil {
+		http.Error(w, "Failed to load templates: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	type MemberMessage struct {
+		MemberID int    `json:"member_id"`
+		Name     string `json:"name"`
+		Message  string `json:"message"`
+	}
+
+	// Group each member's slots, keeping stage order
+	order := []int{}
+	data := make(map[int]*stormMemberMessageData)
+	for _, slot := range slots {
+		if _, seen := data[slot.MemberID]; !seen {
+			order = append(order, slot.MemberID)
+			data[slot.MemberID] = &stormMemberMessageData{
+				Name:      slot.MemberName,
+				Rank:      slot.MemberRank,
+				TaskForce: taskForce,
+				Date:      eventDate,
+			}
+		}
+		data[slot.MemberID].Slots = append(data[slot.MemberID].Slots,
+			stormMessageSlot{Stage: slot.Stage, Building: slot.Building, Position: slot.Position})
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return strings.ToLower(data[order[i]].Name) < strings.ToLower(data[order[j]].Name)
+	})
+
+	messages := []MemberMessage{}
+	for _, memberID := range order {
+		message, err := renderMessageTemplate(messageTemplates[0], data[memberID])
+		if err != nil {
+			http.Error(w, "Failed to render template: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		messages = append(messages, MemberMessage{
+			MemberID: memberID,
+			Name:     data[memberID].Name,
+			Message:  message,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"messages": messages,
+	})
+}
+
+// stormCandidate is a member offered to the auto-assignment with the task force they asked for ("" for either)
+type stormCandidate struct {
+	StormProposalMember
+	TaskForce string
+}
+
+// stormPriorityOrder ranks a building priority, CRITICAL first
+func stormPriorityOrder(priority string) int {
+	for i, p := range stormPriorities {
+		if p == priority {
+			return i
+		}
+	}
+	return len(stormPriorities)
+}
+
+// proposeStormAssignments splits the candidates over task forces A and B with balanced power, then fills
+// each stage's buildings strongest first in priority order, balancing power between buildings of equal priority.
+// Members that don't fit a task force's largest stage are returned as substitutes.
+func proposeStormAssignments(buildings []StormBuilding, candidates []stormCandidate) StormAutoAssignment {
+	stageCapacity := make(map[int]int)
+	rosterSize := 0
+	for _, b := range buildings {
+		stageCapacity[b.Stage] += b.Capacity
+		rosterSize = max(rosterSize, stageCapacity[b.Stage])
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].Power != candidates[j].Power {
+			return candidates[i].Power > candidates[j].Power
+		}
+		return strings.ToLower(candidates[i].Name) < strings.ToLower(candidates[j].Name)
+	})
+
+	result := StormAutoAssignment{TaskForces: []StormProposal{}, Substitutes: []StormProposalMember{}}
+	rosters := make(map[string][]StormProposalMember)
+	totals := make(map[string]int64)
+	for _, c := range candidates {
+		options := []string{"A", "B"}
+		if c.TaskForce != "" {
+			options = []string{c.TaskForce}
+		}
+
+		best := ""
+		for _, tf := range options {
+			if len(rosters[tf]) >= rosterSize {
+				continue
+			}
+			if best == "" || totals[tf] < totals[best] || (totals[tf] == totals[best] && len(rosters[tf]) < len(rosters[best])) {
+				best = tf
+			}
+		}
+		if best == "" {
+			result.Substitutes = append(result.Substitutes, c.StormProposalMember)
+			continue
+		}
+		rosters[best] = append(rosters[best], c.StormProposalMember)
+		totals[best] += c.Power
+	}
+
+	stages := []int{}
+	for stage := range stageCapacity {
+		stages = append(stages, stage)
+	}
+	sort.Ints(stages)
+
+	for _, tf := range []string{"A", "B"} {
+		roster := rosters[tf]
+		proposal := StormProposal{
+			TaskForce:   tf,
+			TotalPower:  totals[tf],
+			Members:     len(roster),
+			Buildings:   []StormProposalBuilding{},
+			Assignments: []StormAssignment{},
+		}
+
+		for _, stage := range stages {
+			stageBuildings := []StormBuilding{}
+			for _, b := range buildings {
+				if b.Stage == stage {
+					stageBuildings = append(stageBuildings, b)
+				}
+			}
+			sort.SliceStable(stageBuildings, func(i, j int) bool {
+				return stormPriorityOrder(stageBuildings[i].Priority) < stormPriorityOrder(stageBuildings[j].Priority)
+			})
+
+			planned := make([]StormProposalBuilding, len(stageBuildings))
+			for i, b := range stageBuildings {
+				planned[i] = StormProposalBuilding{BuildingID: b.ID, Name: b.Name, Stage: b.Stage, Priority: b.Priority, Members: []StormProposalMember{}}
+			}
+
+			// Each priority tier takes the strongest members left, each member going to the tier's weakest building
+			next := 0
+			for i := 0; i < len(stageBuildings); {
+				j, tierCapacity := i, 0
+				for j < len(stageBuildings) && stageBuildings[j].Priority == stageBuildings[i].Priority {
+					tierCapacity += stageBuildings[j].Capacity
+					j++
+				}
+
+				end := min(next+tierCapacity, len(roster))
+				for _, m := range roster[next:end] {
+					best := -1
+					for k := i; k < j; k++ {
+						if len(planned[k].Members) >= stageBuildings[k].Capacity {
+							continue
+						}
+						if best < 0 || planned[k].TotalPower < planned[best].TotalPower ||
+							(planned[k].TotalPower == planned[best].TotalPower && len(planned[k].Members) < len(planned[best].Members)) {
+							best = k
+						}
+					}
+					m.Position = len(planned[best].Members) + 1
+					planned[best].Members = append(planned[best].Members, m)
+					planned[best].TotalPower += m.Power
+				}
+				next = end
+				i = j
+			}
+
+			for _, b := range planned {
+				proposal.Buildings = append(proposal.Buildings, b)
+				for _, m := range b.Members {
+					proposal.Assignments = append(proposal.Assignments, StormAssignment{
+						TaskForce:  tf,
+						BuildingID: b.BuildingID,
+						MemberID:   m.MemberID,
+						Position:   m.Position,
+					})
+				}
+			}
+		}
+
+		result.TaskForces = append(result.TaskForces, proposal)
+	}
+
+	return result
+}
+
+// Propose storm assignments for both task forces from latest power; nothing is saved
+func autoAssignStorm(w http.ResponseWriter, r *http.Request) {
+	type memberRequest struct {
+		MemberID  int    `json:"member_id"`
+		TaskForce string `json:"task_force"`
+	}
+	var request struct {
+		EventID int             `json:"event_id"`
+		Members []memberRequest `json:"members"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil && err != io.EOF {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	buildings, err := loadStormBuildings()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if len(buildings) == 0 {
+		http.Error(w, "No storm buildings in the catalogue", http.StatusBadRequest)
+		return
+	}
+
+	rows, err := db.Query(`
+		SELECT m.id, m.name, m.rank,
+		       COALESCE((SELECT ph.power
+		        FROM power_history ph
+		        WHERE ph.member_id = m.id
+		        ORDER BY ph.recorded_at DESC
+		        LIMIT 1), 0) as latest_power
+		FROM members m
+		ORDER BY m.name
+	`)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	members := make(map[int]StormProposalMember)
+	allCandidates := []stormCandidate{}
+	for rows.Next() {
+		var m StormProposalMember
+		if err := rows.Scan(&m.MemberID, &m.Name, &m.Rank, &m.Power); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		members[m.MemberID] = m
+		allCandidates = append(allCandidates, stormCandidate{StormProposalMember: m})
+	}
+
+	// An event's signups are the candidates unless a member list is given
+	if request.EventID > 0 && len(request.Members) == 0 {
+		if _, ok := stormEventScope(w, request.EventID, false); !ok {
+			return
+		}
+
+		signupRows, err := db.Query(`
+			SELECT member_id, availability
+			FROM storm_signups
+			WHERE event_id = ? AND availability IN ('A', 'B', 'either')
+		`, request.EventID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		defer signupRows.Close()
+
+		for signupRows.Next() {
+			var member memberRequest
+			if err := signupRows.Scan(&member.MemberID, &member.TaskForce); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			if member.TaskForce == "either" {
+				member.TaskForce = ""
+			}
+			request.Members = append(request.Members, member)
+		}
+		if len(request.Members) == 0 {
+			http.Error(w, "No members have signed up for this storm event", http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Without a member list every member is a candidate for either task force
+	candidates := allCandidates
+	if len(request.Members) > 0 {
+		candidates = []stormCandidate{}
+		seen := make(map[int]bool)
+		for _, requested := range request.Members {
+			m, found := members[requested.MemberID]
+			if !found {
+				http.Error(w, fmt.Sprintf("Member %d not found", requested.MemberID), http.StatusBadRequest)
+				return
+			}
+			if requested.TaskForce != "" && requested.TaskForce != "A" && requested.TaskForce != "B" {
+				http.Error(w, "Invalid task force - must be A or B", http.StatusBadRequest)
+				return
+			}
+			if seen[requested.MemberID] {
+				continue
+			}
+			seen[requested.MemberID] = true
+			candidates = append(candidates, stormCandidate{StormProposalMember: m, TaskForce: requested.TaskForce})
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(proposeStormAssignments(buildings, candidates))
+}
+
+// Confirm and update members in database
+func confirmMemberUpdates(w http.ResponseWriter, r *http.Request) {
+	var request ConfirmRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result := ConfirmResult{}
+
+	// Process renames first
+	for _, rename := range request.Renames {
+		_, err := db.Exec("UPDATE members SET name = ? WHERE name = ?", rename.NewName, rename.OldName)
+		if err != nil {
+			log.Printf("Error renaming member %s to %s: %v", rename.OldName, rename.NewName, err)
+			continue
+		}
+		log.Printf("Renamed member %s to %s", rename.OldName, rename.NewName)
+	}
+
+	// Create a set of member names from the request
+	memberNames := make(map[string]bool)
+	for _, member := range request.Members {
+		memberNames[member.Name] = true
+	}
+
+	for _, member := range request.Members {
+		// Check if member exists
+		var existingID int
+		var existingRank string
+		err := db.QueryRow("SELECT id, rank FROM members WHERE name = ?", member.Name).Scan(&existingID, &existingRank)
+
+		if err == sql.ErrNoRows {
+			// Add new member
+			_, err = db.Exec("INSERT INTO members (name, rank) VALUES (?, ?)", member.Name, member.Rank)
+			if err != nil {
+				log.Printf("Error adding member %s: %v", member.Name, err)
+				continue
+			}
+			result.Added++
+		} else if err == nil {
+			// Update existing member if rank changed
+			if existingRank != member.Rank {
+				_, err = db.Exec("UPDATE members SET rank = ? WHERE id = ?", member.Rank, existingID)
+				if err != nil {
+					log.Printf("Error updating member %s: %v", member.Name, err)
+					continue
+				}
+				result.Updated++
+			} else {
+				result.Unchanged++
+			}
+		}
+	}
+
+	// Remove specific members by ID if requested
+	if len(request.RemoveMemberIDs) > 0 {
+		for _, id := range request.RemoveMemberIDs {
+			_, err := db.Exec("DELETE FROM members WHERE id = ?", id)
+			if err != nil {
+				log.Printf("Error removing member with id %d: %v", id, err)
+				continue
+			}
+			result.Removed++
+		}
+		log.Printf("Removed %d selected members", result.Removed)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// Get power history for a specific member or all members
+func getPowerHistory(w http.ResponseWriter, r *http.Request) {
+	memberID := r.URL.Query().Get("member_id")
+	limit := r.URL.Query().Get("limit")
+
+	if limit == "" {
+		limit = "30" // Default to last 30 records
+	}
+
+	var rows *sql.Rows
+	var err error
+
+	if memberID != "" {
+		rows, err = db.Query(`
+			SELECT ph.id, ph.member_id, ph.power, ph.recorded_at
+			FROM power_history ph
+			WHERE ph.member_id = ?
+			ORDER BY ph.recorded_at DESC
+			LIMIT ?
+		`, memberID, limit)
+	} else {
+		rows, err = db.Query(`
+			SELECT ph.id, ph.member_id, ph.power, ph.recorded_at
+			FROM power_history ph
+			ORDER BY ph.recorded_at DESC
+			LIMIT ?
+		`, limit)
+	}
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	history := []PowerHistory{}
+	for rows.Next() {
+		var ph PowerHistory
+		if err := rows.Scan(&ph.ID, &ph.MemberID, &ph.Power, &ph.RecordedAt); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		history = append(history, ph)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(history)
+}
+
+// Add power record manually
+func addPowerRecord(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		MemberID int   `json:"member_id"`
+		Power    int64 `json:"power"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Check if member exists
+	var exists int
+	err := db.QueryRow("SELECT COUNT(*) FROM members WHERE id = ?", request.MemberID).Scan(&exists)
+	if err != nil || exists == 0 {
+		http.Error(w, "Member not found", http.StatusNotFound)
+		return
+	}
+
+	session, _ := store.Get(r, "session")
+	userID, _ := session.Values["user_id"].(int)
+
+	tx, err := db.Begin()
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	id, replaced, err := recordPowerSnapshot(tx, request.MemberID, request.Power, nil, userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Failed to save changes", http.StatusInternalServerError)
+		return
+	}
+
+	message := "Power record added successfully"
+	if replaced {
+		message = "Power record replaced today's earlier record"
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":  message,
+		"id":       id,
+		"replaced": replaced,
+	})
+}
+
+// recordPowerSnapshot stores a member's power for the day of recordedAt (nil for now). Power is one
+// snapshot per member per day, latest wins: a later reading replaces the day's earlier one, which is
+// kept in the audit log, and a reading older than the one already stored is ignored. It returns the
+// ID of the day's record and whether an earlier value was replaced.
+func recordPowerSnapshot(tx *sql.Tx, memberID int, power int64, recordedAt interface{}, changedBy int) (int64, bool, error) {
+	var at string
+	if err := tx.QueryRow("SELECT datetime(COALESCE(?, CURRENT_TIMESTAMP))", recordedAt).Scan(&at); err != nil {
+		return 0, false, err
+	}
+
+	var existingID, existingPower int64
+	var existingAt string
+	err := tx.QueryRow(`SELECT id, power, datetime(recorded_at) FROM power_history
+		WHERE member_id = ? AND DATE(recorded_at) = DATE(?)`, memberID, at).Scan(&existingID, &existingPower, &existingAt)
+	if err == sql.ErrNoRows {
+		result, err := tx.Exec("INSERT INTO power_history (member_id, power, recorded_at) VALUES (?, ?, ?)", memberID, power, at)
+		if err != nil {
+			return 0, false, err
+		}
+		id, err := result.LastInsertId()
+		return id, false, err
+	} else if err != nil {
+		return 0, false, err
+	}
+
+	// A newer reading of the same day is already stored
+	if existingAt > at {
+		return existingID, false, nil
+	}
+
+	replaced := existingPower != power
+	if replaced {
+		var changedByID interface{}
+		if changedBy > 0 {
+			changedByID = changedBy
+		}
+		_, err = tx.Exec(`INSERT INTO power_history_audit (power_history_id, member_id, action, old_power, new_power, recorded_at, reason, changed_by)
+			VALUES (?, ?, 'replace', ?, ?, ?, 'Newer snapshot on the same day', ?)`,
+			existingID, memberID, existingPower, power, existingAt, changedByID)
+		if err != nil {
+			return 0, false, err
+		}
+	}
+	_, err = tx.Exec("UPDATE power_history SET power = ?, recorded_at = ? WHERE id = ?", power, at, existingID)
+	return existingID, replaced, err
+}
+
+// auditPowerRecord loads a power record and logs a manual edit (newPower set) or void (nil) of it
+func auditPowerRecord(tx *sql.Tx, recordID int, action string, newPower interface{}, reason string, changedBy int) error {
+	var changedByID interface{}
+	if changedBy > 0 {
+		changedByID = changedBy
+	}
+	result, err := tx.Exec(`INSERT INTO power_history_audit (power_history_id, member_id, action, old_power, new_power, recorded_at, reason, changed_by)
+		SELECT id, member_id, ?, power, ?, datetime(recorded_at), ?, ? FROM power_history WHERE id = ?`,
+		action, newPower, sql.NullString{String: reason, Valid: reason != ""}, changedByID, recordID)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// Correct the value of a power record, e.g. an OCR misread (R4/R5 only)
+func updatePowerRecord(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid record ID", http.StatusBadRequest)
+		return
+	}
+
+	var request struct {
+		Power  int64  `json:"power"`
+		Reason string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	powerMetric, _ := findRankingMetric("power")
+	if request.Power < powerMetric.MinValue || request.Power > powerMetric.MaxValue {
+		http.Error(w, fmt.Sprintf("Power must be between %d and %d", powerMetric.MinValue, powerMetric.MaxValue), http.StatusBadRequest)
+		return
+	}
+
+	session, _ := store.Get(r, "session")
+	userID, _ := session.Values["user_id"].(int)
+
+	tx, err := db.Begin()
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	err = auditPowerRecord(tx, id, "edit", request.Power, request.Reason, userID)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Power record not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if _, err := tx.Exec("UPDATE power_history SET power = ? WHERE id = ?", request.Power, id); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Failed to save changes", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Power record updated"})
+}
+
+// Void a power record, keeping its value in the audit log (R4/R5 only, optional ?reason=)
+func voidPowerRecord(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid record ID", http.StatusBadRequest)
+		return
+	}
+
+	session, _ := store.Get(r, "session")
+	userID, _ := session.Values["user_id"].(int)
+
+	tx, err := db.Begin()
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	err = auditPowerRecord(tx, id, "void", nil, r.URL.Query().Get("reason"), userID)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Power record not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if _, err := tx.Exec("DELETE FROM power_history WHERE id = ?", id); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Failed to save changes", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Get the audit log of replaced, merged, edited and voided power records (optional ?member_id=&limit=)
+func getPowerAudit(w http.ResponseWriter, r *http.Request) {
+	limit := r.URL.Query().Get("limit")
+	if limit == "" {
+		limit = "100"
+	}
+
+	query := `
+		SELECT a.id, a.power_history_id, a.member_id, m.name, a.action, a.old_power, a.new_power,
+			a.recorded_at, a.reason, a.changed_by, u.username, a.changed_at
+		FROM power_history_audit a
+		JOIN members m ON m.id = a.member_id
+		LEFT JOIN users u ON u.id = a.changed_by`
+	args := []interface{}{}
+	if memberID := r.URL.Query().Get("member_id"); memberID != "" {
+		query += " WHERE a.member_id = ?"
+		args = append(args, memberID)
+	}
+	query += " ORDER BY a.changed_at DESC, a.id DESC LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	entries := []PowerAuditEntry{}
+	for rows.Next() {
+		var e PowerAuditEntry
+		if err := rows.Scan(&e.ID, &e.PowerHistoryID, &e.MemberID, &e.MemberName, &e.Action, &e.OldPower, &e.NewPower,
+			&e.RecordedAt, &e.Reason, &e.ChangedBy, &e.ChangedByName, &e.ChangedAt); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		entries = append(entries, e)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entries)
+}
+
+// powerGrowthWindows are the trailing windows, in days, power growth is reported for
+var powerGrowthWindows = []int{7, 30, 90}
+
+// defaultPowerStagnationSnapshots is how many unchanged snapshots in a row make a member stagnant
+const defaultPowerStagnationSnapshots = 3
+
+// powerAnalyticsMember is a member with their daily power snapshots, oldest first
+type powerAnalyticsMember struct {
+	ID     int
+	Name   string
+	Rank   string
+	Points []PowerPoint
+}
+
+// loadDailyPower loads every member's power snapshots up to asOf (one per day, see recordPowerSnapshot)
+func loadDailyPower(asOf time.Time) ([]powerAnalyticsMember, error) {
+	rows, err := db.Query(`
+		SELECT m.id, m.name, m.rank, ph.id, DATE(ph.recorded_at), ph.power
+		FROM members m
+		JOIN power_history ph ON ph.member_id = m.id
+		WHERE DATE(ph.recorded_at) <= ?
+		ORDER BY m.name, m.id, ph.recorded_at
+	`, formatDateString(asOf))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := []powerAnalyticsMember{}
+	for rows.Next() {
+		var id int
+		var name, rank string
+		var point PowerPoint
+		if err := rows.Scan(&id, &name, &rank, &point.RecordID, &point.Date, &point.Power); err != nil {
+			return nil, err
+		}
+		if n := len(members); n == 0 || members[n-1].ID != id {
+			members = append(members, powerAnalyticsMember{ID: id, Name: name, Rank: rank})
+		}
+		members[len(members)-1].Points = append(members[len(members)-1].Points, point)
+	}
+	return members, rows.Err()
+}
+
+// daysBetween counts whole days between two YYYY-MM-DD dates
+func daysBetween(from, to string) int {
+	fromDate, err1 := parseDate(from)
+	toDate, err2 := parseDate(to)
+	if err1 != nil || err2 != nil {
+		return 0
+	}
+	return int(math.Round(toDate.Sub(fromDate).Hours() / 24))
+}
+
+// powerGrowthWindow measures growth from the last snapshot on or before the window start to the
+// latest snapshot. It returns nil when there aren't two different snapshots to compare.
+func powerGrowthWindow(points []PowerPoint, asOf time.Time, days int) *PowerGrowthWindow {
+	if len(points) < 2 {
+		return nil
+	}
+	windowStart := formatDateString(asOf.AddDate(0, 0, -days))
+	current := points[len(points)-1]
+
+	startIndex, partial := -1, false
+	for i, point := range points {
+		if point.Date <= windowStart {
+			startIndex = i
+		}
+	}
+	if startIndex < 0 {
+		startIndex, partial = 0, true
+	}
+	if startIndex == len(points)-1 {
+		return nil
+	}
+
+	start := points[startIndex]
+	window := &PowerGrowthWindow{
+		Days:       days,
+		StartDate:  start.Date,
+		StartPower: start.Power,
+		Delta:      current.Power - start.Power,
+		Partial:    partial,
+	}
+	if start.Power > 0 {
+		window.Percent = math.Round(float64(window.Delta)/float64(start.Power)*10000) / 100
+	}
+	if elapsed := daysBetween(start.Date, current.Date); elapsed > 0 {
+		window.PerDay = math.Round(float64(window.Delta) / float64(elapsed))
+	}
+	return window
+}
+
+// percentRank is the share of values below value, like SQL PERCENT_RANK
+func percentRank(values []float64, value float64) float64 {
+	if len(values) < 2 {
+		return 1
+	}
+	below := 0
+	for _, v := range values {
+		if v < value {
+			below++
+		}
+	}
+	return math.Round(float64(below)/float64(len(values)-1)*1000) / 1000
+}
+
+// loadPowerGrowth works out each member's growth windows as of a date, with percentiles of their
+// percentage growth against the whole alliance and against members of the same rank
+func loadPowerGrowth(asOf time.Time) ([]PowerGrowth, error) {
+	members, err := loadDailyPower(asOf)
+	if err != nil {
+		return nil, err
+	}
+
+	growth := make([]PowerGrowth, 0, len(members))
+	for _, member := range members {
+		current := member.Points[len(member.Points)-1]
+		g := PowerGrowth{
+			MemberID:   member.ID,
+			MemberName: member.Name,
+			MemberRank: member.Rank,
+			Power:      current.Power,
+			RecordedOn: current.Date,
+			Windows:    []PowerGrowthWindow{},
+		}
+		for _, days := range powerGrowthWindows {
+			if window := powerGrowthWindow(member.Points, asOf, days); window != nil {
+				g.Windows = append(g.Windows, *window)
+			}
+		}
+		growth = append(growth, g)
+	}
+
+	for _, days := range powerGrowthWindows {
+		all := []float64{}
+		byRank := make(map[string][]float64)
+		for _, g := range growth {
+			for _, window := range g.Windows {
+				if window.Days == days {
+					all = append(all, window.Percent)
+					byRank[g.MemberRank] = append(byRank[g.MemberRank], window.Percent)
+				}
+			}
+		}
+		for i := range growth {
+			for j := range growth[i].Windows {
+				window := &growth[i].Windows[j]
+				if window.Days != days {
+					continue
+				}
+				percentile := percentRank(all, window.Percent)
+				rankPercentile := percentRank(byRank[growth[i].MemberRank], window.Percent)
+				window.Percentile = &percentile
+				window.RankPercentile = &rankPercentile
+			}
+		}
+	}
+	return growth, nil
+}
+
+// loadPowerStagnation finds members whose latest snapshots (at least minSnapshots days) all
+// have the same power, longest unchanged first. Power that doesn't move usually means the
+// player has stopped logging in.
+func loadPowerStagnation(asOf time.Time, minSnapshots int) ([]PowerStagnation, error) {
+	members, err := loadDailyPower(asOf)
+	if err != nil {
+		return nil, err
+	}
+
+	stagnant := []PowerStagnation{}
+	for _, member := range members {
+		last := len(member.Points) - 1
+		first := last
+		for first > 0 && member.Points[first-1].Power == member.Points[last].Power {
+			first--
+		}
+		run := last - first + 1
+		if run < minSnapshots {
+			continue
+		}
+		stagnant = append(stagnant, PowerStagnation{
+			MemberID:   member.ID,
+			MemberName: member.Name,
+			MemberRank: member.Rank,
+			Power:      member.Points[last].Power,
+			Snapshots:  run,
+			Since:      member.Points[first].Date,
+			LastSeen:   member.Points[last].Date,
+			Days:       daysBetween(member.Points[first].Date, member.Points[last].Date),
+		})
+	}
+
+	sort.SliceStable(stagnant, func(i, j int) bool {
+		if stagnant[i].Days != stagnant[j].Days {
+			return stagnant[i].Days > stagnant[j].Days
+		}
+		return stagnant[i].Snapshots > stagnant[j].Snapshots
+	})
+	return stagnant, nil
+}
+
+// findPowerOutliers flags snapshots that dropped more than dropPercent or rose more than risePercent
+// against the median of the member's previous three trusted snapshots. A dropped digit shows up as a
+// ~90% drop and a doubled digit as a big rise; flagged snapshots are left out of later baselines.
+func findPowerOutliers(members []powerAnalyticsMember, dropPercent, risePercent float64) []PowerOutlier {
+	outliers := []PowerOutlier{}
+	for _, member := range members {
+		trusted := []int64{}
+		for _, point := range member.Points {
+			if len(trusted) == 0 {
+				trusted = append(trusted, point.Power)
+				continue
+			}
+			recent := append([]int64{}, trusted[max(0, len(trusted)-3):]...)
+			sort.Slice(recent, func(i, j int) bool { return recent[i] < recent[j] })
+			expected := (recent[(len(recent)-1)/2] + recent[len(recent)/2]) / 2
+			if expected <= 0 {
+				trusted = append(trusted, point.Power)
+				continue
+			}
+
+			change := float64(point.Power-expected) / float64(expected) * 100
+			kind := ""
+			if change <= -dropPercent {
+				kind = "drop"
+			} else if change >= risePercent {
+				kind = "rise"
+			}
+			if kind == "" {
+				trusted = append(trusted, point.Power)
+				continue
+			}
+			outliers = append(outliers, PowerOutlier{
+				RecordID:      point.RecordID,
+				MemberID:      member.ID,
+				MemberName:    member.Name,
+				Date:          point.Date,
+				Power:         point.Power,
+				Expected:      expected,
+				ChangePercent: math.Round(change*10) / 10,
+				Kind:          kind,
+			})
+		}
+	}
+
+	sort.SliceStable(outliers, func(i, j int) bool { return outliers[i].Date > outliers[j].Date })
+	return outliers
+}
+
+// Get power snapshots that are far off the member's trend (optional ?drop=50&rise=100 in percent)
+func getPowerOutliers(w http.ResponseWriter, r *http.Request) {
+	asOf, err := parsePowerAsOf(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	thresholds := map[string]float64{"drop": 50, "rise": 100}
+	for name := range thresholds {
+		if param := r.URL.Query().Get(name); param != "" {
+			value, err := strconv.ParseFloat(param, 64)
+			if err != nil || value <= 0 {
+				http.Error(w, name+" must be a positive percentage", http.StatusBadRequest)
+				return
+			}
+			thresholds[name] = value
+		}
+	}
+
+	members, err := loadDailyPower(asOf)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"as_of":        formatDateString(asOf),
+		"drop_percent": thresholds["drop"],
+		"rise_percent": thresholds["rise"],
+		"outliers":     findPowerOutliers(members, thresholds["drop"], thresholds["rise"]),
+	})
+}
+
+// parsePowerAsOf reads ?as_of=YYYY-MM-DD, defaulting to the current game day
+func parsePowerAsOf(r *http.Request) (time.Time, error) {
+	asOfParam := r.URL.Query().Get("as_of")
+	if asOfParam == "" {
+		return gameToday(), nil
+	}
+	asOf, err := parseDate(asOfParam)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid as_of date format (expected YYYY-MM-DD)")
+	}
+	return asOf, nil
+}
+
+// Get each member's power growth over 7, 30 and 90 days with growth percentiles
+func getPowerGrowth(w http.ResponseWriter, r *http.Request) {
+	asOf, err := parsePowerAsOf(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	growth, err := loadPowerGrowth(asOf)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"as_of":   formatDateString(asOf),
+		"windows": powerGrowthWindows,
+		"members": growth,
+	})
+}
+
+// Get alliance total, average and median power on each snapshot day.
+// Members count with their latest value until they are recorded again.
+func getPowerTotals(w http.ResponseWriter, r *http.Request) {
+	asOf, err := parsePowerAsOf(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	days := 90
+	if daysParam := r.URL.Query().Get("days"); daysParam != "" {
+		n, err := strconv.Atoi(daysParam)
+		if err != nil || n < 1 {
+			http.Error(w, "days must be a positive number", http.StatusBadRequest)
+			return
+		}
+		days = min(n, 730)
+	}
+	from := formatDateString(asOf.AddDate(0, 0, -days))
+
+	members, err := loadDailyPower(asOf)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	dates := []string{}
+	seenDates := make(map[string]bool)
+	for _, member := range members {
+		for _, point := range member.Points {
+			if point.Date >= from && !seenDates[point.Date] {
+				seenDates[point.Date] = true
+				dates = append(dates, point.Date)
+			}
+		}
+	}
+	sort.Strings(dates)
+
+	series := []PowerTotalPoint{}
+	for _, date := range dates {
+		values := []int64{}
+		for _, member := range members {
+			// Latest snapshot on or before this day
+			idx := sort.Search(len(member.Points), func(i int) bool { return member.Points[i].Date > date }) - 1
+			if idx >= 0 {
+				values = append(values, member.Points[idx].Power)
+			}
+		}
+		sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
+
+		point := PowerTotalPoint{Date: date, Members: len(values)}
+		for _, value := range values {
+			point.Total += value
+		}
+		if n := len(values); n > 0 {
+			point.Average = math.Round(float64(point.Total) / float64(n))
+			point.Median = float64(values[(n-1)/2]+values[n/2]) / 2
+		}
+		series = append(series, point)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"as_of":  formatDateString(asOf),
+		"days":   days,
+		"series": series,
+	})
+}
+
+// Get members whose power hasn't changed across their last N snapshots (?snapshots=, default 3)
+func getStagnantPower(w http.ResponseWriter, r *http.Request) {
+	asOf, err := parsePowerAsOf(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	snapshots := defaultPowerStagnationSnapshots
+	if snapshotsParam := r.URL.Query().Get("snapshots"); snapshotsParam != "" {
+		n, err := strconv.Atoi(snapshotsParam)
+		if err != nil || n < 2 {
+			http.Error(w, "snapshots must be a number of at least 2", http.StatusBadRequest)
+			return
+		}
+		snapshots = n
+	}
+
+	stagnant, err := loadPowerStagnation(asOf, snapshots)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"as_of":     formatDateString(asOf),
+		"snapshots": snapshots,
+		"members":   stagnant,
+	})
+}
+
+// Strength ranking tabs in the order they appear on screen
+var rankingMetrics = []RankingMetric{
+	{Name: "power", Label: "Power", Table: "power_history", Column: "power", MinValue: 1000000, MaxValue: 9999999999},
+	{Name: "kills", Label: "Kills", Table: "kills_history", Column: "kills", MinValue: 0, MaxValue: 9999999999},
+	{Name: "donation", Label: "Donation", Table: "donation_history", Column: "donations", MinValue: 0, MaxValue: 999999999, ResetsWeekly: true},
+}
+
+func findRankingMetric(name string) (RankingMetric, bool) {
+	for _, metric := range rankingMetrics {
+		if metric.Name == name {
+			return metric, true
+		}
+	}
+	return RankingMetric{}, false
+}
+
+// rankingMetricFromRequest reads the {metric} route variable
+func rankingMetricFromRequest(w http.ResponseWriter, r *http.Request) (RankingMetric, bool) {
+	metric, found := findRankingMetric(mux.Vars(r)["metric"])
+	if !found {
+		http.Error(w, "Unknown metric - must be power, kills or donation", http.StatusNotFound)
+	}
+	return metric, found
+}
+
+// Get the history of a ranking metric for a specific member or all members
+func getRankingHistory(w http.ResponseWriter, r *http.Request) {
+	metric, ok := rankingMetricFromRequest(w, r)
+	if !ok {
+		return
+	}
+	memberID := r.URL.Query().Get("member_id")
+	limit := r.URL.Query().Get("limit")
+
+	if limit == "" {
+		limit = "30" // Default to last 30 records
+	}
+
+	query := fmt.Sprintf("SELECT id, member_id, %s, recorded_at FROM %s", metric.Column, metric.Table)
+	args := []interface{}{}
+	if memberID != "" {
+		query += " WHERE member_id = ?"
+		args = append(args, memberID)
+	}
+	query += " ORDER BY recorded_at DESC LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	history := []RankingHistory{}
+	for rows.Next() {
+		var rh RankingHistory
+		if err := rows.Scan(&rh.ID, &rh.MemberID, &rh.Value, &rh.RecordedAt); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		history = append(history, rh)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(history)
+}
+
+// Add a ranking metric record manually
+func addRankingRecord(w http.ResponseWriter, r *http.Request) {
+	metric, ok := rankingMetricFromRequest(w, r)
+	if !ok {
+		return
+	}
+	var request struct {
+		MemberID int   `json:"member_id"`
+		Value    int64 `json:"value"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if request.Value < metric.MinValue || request.Value > metric.MaxValue {
+		http.Error(w, fmt.Sprintf("%s must be between %d and %d", metric.Label, metric.MinValue, metric.MaxValue), http.StatusBadRequest)
+		return
+	}
+
+	// Check if member exists
+	var exists int
+	err := db.QueryRow("SELECT COUNT(*) FROM members WHERE id = ?", request.MemberID).Scan(&exists)
+	if err != nil || exists == 0 {
+		http.Error(w, "Member not found", http.StatusNotFound)
+		return
+	}
+
+	var id int64
+	if metric.Name == "power" {
+		session, _ := store.Get(r, "session")
+		userID, _ := session.Values["user_id"].(int)
+		tx, err := db.Begin()
+		if err != nil {
+			http.Error(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		defer tx.Rollback()
+		if id, _, err = recordPowerSnapshot(tx, request.MemberID, request.Value, nil, userID); err == nil {
+			err = tx.Commit()
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	} else {
+		result, err := db.Exec(fmt.Sprintf("INSERT INTO %s (member_id, %s) VALUES (?, ?)", metric.Table, metric.Column),
+			request.MemberID, request.Value)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		id, _ = result.LastInsertId()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": metric.Label + " record added successfully",
+		"id":      id,
+	})
+}
+
+// loadRankingDeltas works out each member's change of a metric over the week starting weekStart,
+// biggest first. Power and kills only grow, so the delta is the last value of the week minus the
+// last value before it; donations restart every week, so the delta is the week's highest value.
+func loadRankingDeltas(metric RankingMetric, weekStart time.Time) ([]RankingDelta, error) {
+	weekFrom := formatDateString(weekStart)
+	weekTo := formatDateString(weekStart.AddDate(0, 0, 7))
+
+	var query string
+	if metric.ResetsWeekly {
+		query = fmt.Sprintf(`
+			SELECT m.id, m.name, 0, MAX(h.%[1]s), 0
+			FROM %[2]s h
+			JOIN members m ON m.id = h.member_id
+			WHERE h.recorded_at >= ? AND h.recorded_at < ?
+			GROUP BY m.id, m.name
+		`, metric.Column, metric.Table)
+	} else {
+		// Start is the latest value before the week, or the first value in the week when there is none
+		query = fmt.Sprintf(`
+			WITH in_week AS (
+				SELECT member_id, %[1]s AS value, recorded_at,
+					ROW_NUMBER() OVER (PARTITION BY member_id ORDER BY recorded_at DESC, id DESC) AS newest,
+					ROW_NUMBER() OVER (PARTITION BY member_id ORDER BY recorded_at, id) AS oldest
+				FROM %[2]s
+				WHERE recorded_at >= ?1 AND recorded_at < ?2
+			)
+			SELECT m.id, m.name,
+				COALESCE(
+					(SELECT b.%[1]s FROM %[2]s b WHERE b.member_id = m.id AND b.recorded_at < ?1
+						ORDER BY b.recorded_at DESC, b.id DESC LIMIT 1),
+					first.value),
+				last.value,
+				(SELECT COUNT(*) FROM %[2]s b WHERE b.member_id = m.id AND b.recorded_at < ?1) = 0
+			FROM members m
+			JOIN in_week last ON last.member_id = m.id AND last.newest = 1
+			JOIN in_week first ON first.member_id = m.id AND first.oldest = 1
+		`, metric.Column, metric.Table)
+	}
+
+	rows, err := db.Query(query, weekFrom, weekTo)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deltas := []RankingDelta{}
+	for rows.Next() {
+		var d RankingDelta
+		if err := rows.Scan(&d.MemberID, &d.MemberName, &d.Start, &d.End, &d.Partial); err != nil {
+			return nil, err
+		}
+		d.Delta = d.End - d.Start
+		deltas = append(deltas, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(deltas, func(i, j int) bool {
+		if deltas[i].Delta != deltas[j].Delta {
+			return deltas[i].Delta > deltas[j].Delta
+		}
+		return deltas[i].MemberName < deltas[j].MemberName
+	})
+	return deltas, nil
+}
+
+// Get each member's weekly change of a ranking metric
+func getRankingWeeklyDeltas(w http.ResponseWriter, r *http.Request) {
+	metric, ok := rankingMetricFromRequest(w, r)
+	if !ok {
+		return
+	}
+	weekStart, err := parseVSWeekParam(r)
+	if err != nil {
+		http.Error(w, "Invalid week date format (expected YYYY-MM-DD)", http.StatusBadRequest)
+		return
+	}
+
+	deltas, err := loadRankingDeltas(metric, weekStart)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"metric":    metric.Name,
+		"week_date": formatDateString(weekStart),
+		"members":   deltas,
+	})
+}
+
+// Suggest winners for award types that are tied to a ranking metric: the top 3 by weekly change
+func getAwardSuggestions(w http.ResponseWriter, r *http.Request) {
+	weekStart, err := parseVSWeekParam(r)
+	if err != nil {
+		http.Error(w, "Invalid week date format (expected YYYY-MM-DD)", http.StatusBadRequest)
+		return
+	}
+
+	rows, err := db.Query(`
+		SELECT name, metric FROM award_types
+		WHERE active = 1 AND metric IS NOT NULL
+		ORDER BY sort_order, name
+	`)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	suggestions := []AwardSuggestion{}
+	for rows.Next() {
+		var s AwardSuggestion
+		if err := rows.Scan(&s.AwardType, &s.Metric); err != nil {
+			rows.Close()
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		suggestions = append(suggestions, s)
+	}
+	rows.Close()
+
+	deltasByMetric := make(map[string][]RankingDelta)
+	for i := range suggestions {
+		metric, found := findRankingMetric(suggestions[i].Metric)
+		if !found {
+			continue
+		}
+		deltas, loaded := deltasByMetric[metric.Name]
+		if !loaded {
+			deltas, err = loadRankingDeltas(metric, weekStart)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			deltasByMetric[metric.Name] = deltas
+		}
+		suggestions[i].Members = []RankingDelta{}
+		for _, d := range deltas {
+			if len(suggestions[i].Members) == 3 {
+				break
+			}
+			if d.Delta > 0 {
+				suggestions[i].Members = append(suggestions[i].Members, d)
+			}
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"week_date":   formatDateString(weekStart),
+		"suggestions": suggestions,
+	})
+}
+
+// ImageRegion represents a detected region in the screenshot
+type ImageRegion struct {
+	Name   string
+	Top    int
+	Bottom int
+	Left   int
+	Right  int
+}
+
+// ScreenshotAttributes contains analyzed attributes from the screenshot
+type ScreenshotAttributes struct {
+	Width          int
+	Height         int
+	TitleBarRegion *ImageRegion
+	TabsRegion     *ImageRegion
+	HeaderRegion   *ImageRegion
+	DataRegion     *ImageRegion
+	ButtonRegion   *ImageRegion
+	RowHeight      int
+	EstimatedRows  int
+	Profile        *LayoutProfile
+}
+
+// LayoutProfile describes where things are on one kind of screenshot (device shape, game screen, UI language).
+// Vertical positions are fractions of the screenshot height and horizontal ones fractions of its width,
+// so one profile covers every resolution with the same proportions.
+type LayoutProfile struct {
+	ID               int      `json:"id"`
+	Name             string   `json:"name"`
+	Screen           string   `json:"screen"`       // "any", "power" or "vs_points"
+	AspectRatio      float64  `json:"aspect_ratio"` // width / height, 0 matches any shape
+	AspectTolerance  float64  `json:"aspect_tolerance"`
+	TitleBottom      float64  `json:"title_bottom"`
+	TabsTop          float64  `json:"tabs_top"`
+	TabsBottom       float64  `json:"tabs_bottom"`
+	TabsLeft         float64  `json:"tabs_left"`
+	TabsRight        float64  `json:"tabs_right"`
+	TabCount         int      `json:"tab_count"`
+	TabDays          []string `json:"tab_days"`           // day shown on each tab, left to right
+	SelectedTabColor string   `json:"selected_tab_color"` // background of the selected day tab, e.g. "#ffffff"
+	ColorTolerance   int      `json:"color_tolerance"`    // max per-channel difference from SelectedTabColor
+	DataTop          float64  `json:"data_top"`
+	DataBottom       float64  `json:"data_bottom"`
+	VisibleRows      int      `json:"visible_rows"`
+	RankColumnEnd    float64  `json:"rank_column_end"`
+	NameColumnEnd    float64  `json:"name_column_end"`
+	IsDefault        bool     `json:"is_default"`
+	HasTemplate      bool     `json:"has_template"`
+	CreatedAt        string   `json:"created_at"`
+
+	template *image.Gray
+}
+
+const (
+	layoutTemplateWidth    = 64
+	layoutTemplateHeight   = 24
+	layoutTemplateMinScore = 0.6  // minimum correlation for a template to count as a match
+	layoutAspectTolerance  = 0.05 // used when a profile doesn't set its own
+)
+
+// defaultLayoutProfile is the phone layout the recognizer was originally tuned on
+func defaultLayoutProfile() LayoutProfile {
+	return LayoutProfile{
+		Name:             "Default phone",
+		Screen:           "any",
+		TitleBottom:      1.0 / 15,
+		TabsTop:          0.08,
+		TabsBottom:       0.105,
+		TabsLeft:         0,
+		TabsRight:        1,
+		TabCount:         6,
+		TabDays:          vsDays[:6],
+		SelectedTabColor: "#ffffff",
+		ColorTolerance:   54, // RGB > 200
+		DataTop:          2.0/15 + 1.0/20,
+		DataBottom:       0.9,
+		VisibleRows:      10,
+		RankColumnEnd:    0.15,
+		NameColumnEnd:    0.65,
+		IsDefault:        true,
+	}
+}
+
+// validate checks that the regions of a profile are inside the image and in order
+func (p LayoutProfile) validate() error {
+	if strings.TrimSpace(p.Name) == "" {
+		return fmt.Errorf("name is required")
+	}
+	if p.Screen != "any" && p.Screen != "power" && p.Screen != "vs_points" {
+		return fmt.Errorf("screen must be any, power or vs_points")
+	}
+	fractions := []float64{p.TitleBottom, p.TabsTop, p.TabsBottom, p.TabsLeft, p.TabsRight,
+		p.DataTop, p.DataBottom, p.RankColumnEnd, p.NameColumnEnd}
+	for _, f := range fractions {
+		if f < 0 || f > 1 {
+			return fmt.Errorf("regions must lie within the image")
+		}
+	}
+	if p.TabsBottom <= p.TabsTop || p.TabsRight <= p.TabsLeft {
+		return fmt.Errorf("tab region is empty")
+	}
+	if p.DataBottom <= p.DataTop {
+		return fmt.Errorf("data region is empty")
+	}
+	if p.NameColumnEnd <= p.RankColumnEnd {
+		return fmt.Errorf("name column must end after the rank column")
+	}
+	if p.TabCount < 1 || p.TabCount > len(vsDays) {
+		return fmt.Errorf("tab_count must be between 1 and %d", len(vsDays))
+	}
+	if len(p.TabDays) != p.TabCount {
+		return fmt.Errorf("tab_days must list one day per tab")
+	}
+	for _, day := range p.TabDays {
+		if vsDayIndex(day) == len(vsDays) {
+			return fmt.Errorf("invalid day in tab_days: %s", day)
+		}
+	}
+	if p.VisibleRows < 1 {
+		return fmt.Errorf("visible_rows must be at least 1")
+	}
+	if _, err := parseHexColor(p.SelectedTabColor); err != nil {
+		return err
+	}
+	if p.ColorTolerance < 0 || p.ColorTolerance > 255 {
+		return fmt.Errorf("color_tolerance must be between 0 and 255")
+	}
+	if p.AspectRatio < 0 || p.AspectTolerance < 0 {
+		return fmt.Errorf("aspect ratio and tolerance cannot be negative")
+	}
+	return nil
+}
+
+// parseHexColor parses "#rrggbb"
+func parseHexColor(s string) (color.RGBA, error) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "#")
+	if len(s) != 6 {
+		return color.RGBA{}, fmt.Errorf("invalid color %q, expected #rrggbb", s)
+	}
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return color.RGBA{}, fmt.Errorf("invalid color %q, expected #rrggbb", s)
+	}
+	return color.RGBA{R: b[0], G: b[1], B: b[2], A: 255}, nil
+}
+
+// matchesSelectedTab reports whether a pixel has the selected tab's background color
+func (p *LayoutProfile) matchesSelectedTab(c color.Color, selected color.RGBA) bool {
+	r, g, b, _ := c.RGBA()
+	within := func(v uint32, target uint8) bool {
+		diff := int(v>>8) - int(target)
+		return diff <= p.ColorTolerance && -diff <= p.ColorTolerance
+	}
+	return within(r, selected.R) && within(g, selected.G) && within(b, selected.B)
+}
+
+// layoutTemplate shrinks the part of the screenshot above the data rows (title, tabs, column headers)
+// to a small grayscale thumbnail used to tell screens apart
+func layoutTemplate(img image.Image, dataTop float64) *image.Gray {
+	bounds := img.Bounds()
+	regionHeight := int(float64(bounds.Dy()) * dataTop)
+	if regionHeight < 1 || bounds.Dx() < 1 {
+		return nil
+	}
+
+	thumb := image.NewGray(image.Rect(0, 0, layoutTemplateWidth, layoutTemplateHeight))
+	for ty := 0; ty < layoutTemplateHeight; ty++ {
+		y0 := bounds.Min.Y + ty*regionHeight/layoutTemplateHeight
+		y1 := max(bounds.Min.Y+(ty+1)*regionHeight/layoutTemplateHeight, y0+1)
+		for tx := 0; tx < layoutTemplateWidth; tx++ {
+			x0 := bounds.Min.X + tx*bounds.Dx()/layoutTemplateWidth
+			x1 := max(bounds.Min.X+(tx+1)*bounds.Dx()/layoutTemplateWidth, x0+1)
+			sum, count := 0, 0
+			for y := y0; y < y1; y++ {
+				for x := x0; x < x1; x++ {
+					sum += int(color.GrayModel.Convert(img.At(x, y)).(color.Gray).Y)
+					count++
+				}
+			}
+			thumb.SetGray(tx, ty, color.Gray{Y: uint8(sum / count)})
+		}
+	}
+	return thumb
+}
+
+// templateSimilarity is the normalized cross-correlation of two thumbnails (-1 to 1),
+// which ignores overall brightness and contrast differences between devices
+func templateSimilarity(a, b *image.Gray) float64 {
+	if a == nil || b == nil || a.Bounds() != b.Bounds() {
+		return 0
+	}
+	n := float64(len(a.Pix))
+	meanA, meanB := 0.0, 0.0
+	for i := range a.Pix {
+		meanA += float64(a.Pix[i])
+		meanB += float64(b.Pix[i])
+	}
+	meanA /= n
+	meanB /= n
+
+	cov, varA, varB := 0.0, 0.0, 0.0
+	for i := range a.Pix {
+		da := float64(a.Pix[i]) - meanA
+		db := float64(b.Pix[i]) - meanB
+		cov += da * db
+		varA += da * da
+		varB += db * db
+	}
+	if varA == 0 || varB == 0 {
+		return 0
+	}
+	return cov / math.Sqrt(varA*varB)
+}
+
+const layoutProfileSelectSQL = `SELECT id, name, screen, aspect_ratio, aspect_tolerance, title_bottom,
+	tabs_top, tabs_bottom, tabs_left, tabs_right, tab_count, tab_days, selected_tab_color, color_tolerance,
+	data_top, data_bottom, visible_rows, rank_column_end, name_column_end, is_default, template, created_at
+	FROM layout_profiles`
+
+func scanLayoutProfile(row interface{ Scan(...interface{}) error }) (LayoutProfile, error) {
+	var p LayoutProfile
+	var tabDays string
+	var template []byte
+	err := row.Scan(&p.ID, &p.Name, &p.Screen, &p.AspectRatio, &p.AspectTolerance, &p.TitleBottom,
+		&p.TabsTop, &p.TabsBottom, &p.TabsLeft, &p.TabsRight, &p.TabCount, &tabDays, &p.SelectedTabColor,
+		&p.ColorTolerance, &p.DataTop, &p.DataBottom, &p.VisibleRows, &p.RankColumnEnd, &p.NameColumnEnd,
+		&p.IsDefault, &template, &p.CreatedAt)
+	if err != nil {
+		return p, err
+	}
+	p.TabDays = strings.Split(tabDays, ",")
+	if len(template) > 0 {
+		if img, err := png.Decode(bytes.NewReader(template)); err == nil {
+			if gray, ok := img.(*image.Gray); ok {
+				p.template = gray
+				p.HasTemplate = true
+			}
+		}
+	}
+	return p, nil
+}
+
+// loadLayoutProfiles returns the stored profiles, oldest first
+func loadLayoutProfiles() ([]LayoutProfile, error) {
+	rows, err := db.Query(layoutProfileSelectSQL + " ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	profiles := []LayoutProfile{}
+	for rows.Next() {
+		p, err := scanLayoutProfile(rows)
+		if err != nil {
+			return nil, err
+		}
+		profiles = append(profiles, p)
+	}
+	return profiles, rows.Err()
+}
+
+// insertLayoutProfile stores a profile with its optional template thumbnail
+func insertLayoutProfile(p LayoutProfile, template *image.Gray, createdBy interface{}) (int64, error) {
+	var templateData []byte
+	if template != nil {
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, template); err != nil {
+			return 0, err
+		}
+		templateData = buf.Bytes()
+	}
+	result, err := db.Exec(`INSERT INTO layout_profiles (name, screen, aspect_ratio, aspect_tolerance, title_bottom,
+		tabs_top, tabs_bottom, tabs_left, tabs_right, tab_count, tab_days, selected_tab_color, color_tolerance,
+		data_top, data_bottom, visible_rows, rank_column_end, name_column_end, is_default, template, created_by)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		p.Name, p.Screen, p.AspectRatio, p.AspectTolerance, p.TitleBottom,
+		p.TabsTop, p.TabsBottom, p.TabsLeft, p.TabsRight, p.TabCount, strings.Join(p.TabDays, ","),
+		strings.ToLower(p.SelectedTabColor), p.ColorTolerance, p.DataTop, p.DataBottom, p.VisibleRows,
+		p.RankColumnEnd, p.NameColumnEnd, p.IsDefault, templateData, createdBy)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+// LayoutProfileMatch is how well a stored profile fits a screenshot
+type LayoutProfileMatch struct {
+	ProfileID   int     `json:"profile_id"`
+	Name        string  `json:"name"`
+	AspectMatch bool    `json:"aspect_match"`
+	Template    float64 `json:"template_score"`
+	Score       float64 `json:"score"`
+}
+
+// rankLayoutProfiles scores each profile against a screenshot. A profile must fit the screen kind and the
+// image's aspect ratio; among those, a matching template (title, tabs and headers look the same) scores highest,
+// then profiles made for this aspect ratio, then generic ones.
+func rankLayoutProfiles(img image.Image, screen string, profiles []LayoutProfile) []LayoutProfileMatch {
+	bounds := img.Bounds()
+	aspect := 0.0
+	if bounds.Dy() > 0 {
+		aspect = float64(bounds.Dx()) / float64(bounds.Dy())
+	}
+
+	matches := []LayoutProfileMatch{}
+	for _, p := range profiles {
+		if p.Screen != "any" && screen != "" && p.Screen != screen {
+			continue
+		}
+		match := LayoutProfileMatch{ProfileID: p.ID, Name: p.Name}
+		if p.AspectRatio > 0 {
+			tolerance := p.AspectTolerance
+			if tolerance == 0 {
+				tolerance = layoutAspectTolerance
+			}
+			if math.Abs(aspect-p.AspectRatio) > tolerance {
+				continue
+			}
+			match.AspectMatch = true
+		}
+
+		switch {
+		case p.template != nil:
+			match.Template = templateSimilarity(p.template, layoutTemplate(img, p.DataTop))
+			if match.Template < layoutTemplateMinScore {
+				continue
+			}
+			match.Score = 1 + match.Template
+		case match.AspectMatch:
+			match.Score = 1
+		default:
+			match.Score = 0.5
+		}
+		matches = append(matches, match)
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].Score > matches[j].Score })
+	return matches
+}
+
+// selectLayoutProfile picks the stored profile that best fits a screenshot, falling back to the default layout
+func selectLayoutProfile(img image.Image, screen string) *LayoutProfile {
+	fallback := defaultLayoutProfile()
+	if db == nil {
+		return &fallback
+	}
+
+	profiles, err := loadLayoutProfiles()
+	if err != nil {
+		log.Printf("Failed to load layout profiles: %v", err)
+		return &fallback
+	}
+	matches := rankLayoutProfiles(img, screen, profiles)
+	if len(matches) == 0 {
+		return &fallback
+	}
+	for i := range profiles {
+		if profiles[i].ID == matches[0].ProfileID {
+			log.Printf("Layout profile: %s (score %.2f)", profiles[i].Name, matches[0].Score)
+			return &profiles[i]
+		}
+	}
+	return &fallback
+}
+
+// layoutRegionMark is a rectangle marked on a sample screenshot, in pixels
+type layoutRegionMark struct {
+	Top    int `json:"top"`
+	Bottom int `json:"bottom"`
+	Left   int `json:"left"`
+	Right  int `json:"right"`
+}
+
+// layoutProfileMarks is what an admin marks on a sample screenshot to define a new layout profile
+type layoutProfileMarks struct {
+	Name            string           `json:"name"`
+	Screen          string           `json:"screen"`
+	AspectTolerance float64          `json:"aspect_tolerance"`
+	TitleBottom     int              `json:"title_bottom"`
+	Tabs            layoutRegionMark `json:"tabs"`
+	TabCount        int              `json:"tab_count"`
+	TabDays         []string         `json:"tab_days"`
+	SelectedTab     *int             `json:"selected_tab"` // tab selected in the sample, its color is sampled
+	ColorTolerance  int              `json:"color_tolerance"`
+	Data            layoutRegionMark `json:"data"` // top and bottom of the ranking rows
+	VisibleRows     int              `json:"visible_rows"`
+	RankColumnEnd   int              `json:"rank_column_end"` // x where the rank column ends
+	NameColumnEnd   int              `json:"name_column_end"` // x where the name column ends
+}
+
+// layoutProfileFromMarks converts regions marked on a sample screenshot into a resolution-independent profile
+func layoutProfileFromMarks(img image.Image, marks layoutProfileMarks) (LayoutProfile, error) {
+	bounds := img.Bounds()
+	width, height := float64(bounds.Dx()), float64(bounds.Dy())
+	defaults := defaultLayoutProfile()
+
+	p := LayoutProfile{
+		Name:            strings.TrimSpace(marks.Name),
+		Screen:          marks.Screen,
+		AspectRatio:     math.Round(width/height*1000) / 1000,
+		AspectTolerance: marks.AspectTolerance,
+		TitleBottom:     float64(marks.TitleBottom) / height,
+		TabsTop:         float64(marks.Tabs.Top) / height,
+		TabsBottom:      float64(marks.Tabs.Bottom) / height,
+		TabsLeft:        float64(marks.Tabs.Left) / width,
+		TabsRight:       float64(marks.Tabs.Right) / width,
+		TabCount:        marks.TabCount,
+		TabDays:         marks.TabDays,
+		ColorTolerance:  marks.ColorTolerance,
+		DataTop:         float64(marks.Data.Top) / height,
+		DataBottom:      float64(marks.Data.Bottom) / height,
+		VisibleRows:     marks.VisibleRows,
+		RankColumnEnd:   float64(marks.RankColumnEnd) / width,
+		NameColumnEnd:   float64(marks.NameColumnEnd) / width,
+	}
+	if p.Screen == "" {
+		p.Screen = "any"
+	}
+	if marks.Tabs.Right == 0 {
+		p.TabsRight = 1
+	}
+	if p.TabCount == 0 {
+		p.TabCount = defaults.TabCount
+	}
+	for i, day := range p.TabDays {
+		p.TabDays[i] = strings.ToLower(strings.TrimSpace(day))
+	}
+	if len(p.TabDays) == 0 && p.TabCount <= len(vsDays) {
+		p.TabDays = vsDays[:p.TabCount]
+	}
+	if p.ColorTolerance == 0 {
+		p.ColorTolerance = defaults.ColorTolerance
+	}
+	if p.VisibleRows == 0 {
+		p.VisibleRows = defaults.VisibleRows
+	}
+
+	// Take the selected tab color from the sample rather than asking for it
+	p.SelectedTabColor = defaults.SelectedTabColor
+	if marks.SelectedTab != nil {
+		if *marks.SelectedTab < 0 || *marks.SelectedTab >= p.TabCount {
+			return p, fmt.Errorf("selected_tab must be between 0 and %d", p.TabCount-1)
+		}
+		tabWidth := (marks.Tabs.Right - marks.Tabs.Left) / p.TabCount
+		if marks.Tabs.Right == 0 {
+			tabWidth = bounds.Dx() / p.TabCount
+		}
+		left := bounds.Min.X + marks.Tabs.Left + *marks.SelectedTab*tabWidth + tabWidth/4
+		right := left + tabWidth/2
+		top := bounds.Min.Y + marks.Tabs.Top + (marks.Tabs.Bottom-marks.Tabs.Top)/4
+		bottom := top + (marks.Tabs.Bottom-marks.Tabs.Top)/2
+		var sumR, sumG, sumB, count uint64
+		for y := top; y < bottom; y++ {
+			for x := left; x < right; x++ {
+				r, g, b, _ := img.At(x, y).RGBA()
+				sumR += uint64(r >> 8)
+				sumG += uint64(g >> 8)
+				sumB += uint64(b >> 8)
+				count++
+			}
+		}
+		if count > 0 {
+			p.SelectedTabColor = fmt.Sprintf("#%02x%02x%02x", sumR/count, sumG/count, sumB/count)
+		}
+	}
+
+	return p, p.validate()
+}
+
+// Get all screenshot layout profiles
+func getLayoutProfiles(w http.ResponseWriter, r *http.Request) {
+	profiles, err := loadLayoutProfiles()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(profiles)
+}
+
+// Create a layout profile from regions marked on a sample screenshot.
+// Expects multipart form data with the sample as "image" and the marks as JSON in "profile".
+func createLayoutProfile(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseMultipartForm(10 << 20); err != nil {
+		http.Error(w, "Failed to parse form", http.StatusBadRequest)
+		return
+	}
+
+	file, _, err := r.FormFile("image")
+	if err != nil {
+		http.Error(w, "No sample image provided", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	img, _, err := image.Decode(file)
+	if err != nil {
+		http.Error(w, "Failed to decode sample image", http.StatusBadRequest)
+		return
+	}
+
+	var marks layoutProfileMarks
+	if err := json.Unmarshal([]byte(r.FormValue("profile")), &marks); err != nil {
+		http.Error(w, "Invalid profile marks: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	profile, err := layoutProfileFromMarks(img, marks)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var exists bool
+	if err := db.QueryRow("SELECT COUNT(*) > 0 FROM layout_profiles WHERE name = ?", profile.Name).Scan(&exists); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if exists {
+		http.Error(w, "A layout profile with this name already exists", http.StatusConflict)
+		return
+	}
+
+	session, _ := store.Get(r, "session")
+	userID, _ := session.Values["user_id"].(int)
+	var createdBy interface{}
+	if userID > 0 {
+		createdBy = userID
+	}
+
+	id, err := insertLayoutProfile(profile, layoutTemplate(img, profile.DataTop), createdBy)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	saved, err := scanLayoutProfile(db.QueryRow(layoutProfileSelectSQL+" WHERE id = ?", id))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(saved)
+}
+
+// Update a layout profile's regions, tabs and colors; fields left out keep their current values.
+// Moving data_top drops the template thumbnail, which was taken from the area above it.
+func updateLayoutProfile(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "Invalid profile ID", http.StatusBadRequest)
+		return
+	}
+
+	existing, err := scanLayoutProfile(db.QueryRow(layoutProfileSelectSQL+" WHERE id = ?", id))
+	if err == sql.ErrNoRows {
+		http.Error(w, "Layout profile not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	profile := existing
+	profile.TabDays = nil
+	if err := json.NewDecoder(r.Body).Decode(&profile); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if profile.TabDays == nil {
+		profile.TabDays = existing.TabDays
+	}
+	profile.ID = existing.ID
+	profile.IsDefault = existing.IsDefault
+	profile.Name = strings.TrimSpace(profile.Name)
+	profile.SelectedTabColor = strings.ToLower(profile.SelectedTabColor)
+	for i, day := range profile.TabDays {
+		profile.TabDays[i] = strings.ToLower(strings.TrimSpace(day))
+	}
+	if err := profile.validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var exists bool
+	if err := db.QueryRow("SELECT COUNT(*) > 0 FROM layout_profiles WHERE name = ? AND id != ?", profile.Name, profile.ID).Scan(&exists); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if exists {
+		http.Error(w, "A layout profile with this name already exists", http.StatusConflict)
+		return
+	}
+
+	_, err = db.Exec(`UPDATE layout_profiles SET name = ?, screen = ?, aspect_ratio = ?, aspect_tolerance = ?,
+		title_bottom = ?, tabs_top = ?, tabs_bottom = ?, tabs_left = ?, tabs_right = ?, tab_count = ?, tab_days = ?,
+		selected_tab_color = ?, color_tolerance = ?, data_top = ?, data_bottom = ?, visible_rows = ?,
+		rank_column_end = ?, name_column_end = ?,
+		template = CASE WHEN data_top = ? THEN template ELSE NULL END
+		WHERE id = ?`,
+		profile.Name, profile.Screen, profile.AspectRatio, profile.AspectTolerance,
+		profile.TitleBottom, profile.TabsTop, profile.TabsBottom, profile.TabsLeft, profile.TabsRight,
+		profile.TabCount, strings.Join(profile.TabDays, ","), profile.SelectedTabColor, profile.ColorTolerance,
+		profile.DataTop, profile.DataBottom, profile.VisibleRows, profile.RankColumnEnd, profile.NameColumnEnd,
+		profile.DataTop, profile.ID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	saved, err := scanLayoutProfile(db.QueryRow(layoutProfileSelectSQL+" WHERE id = ?", id))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(saved)
+}
+
+// Delete a layout profile (the built-in default cannot be deleted)
+func deleteLayoutProfile(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "Invalid profile ID", http.StatusBadRequest)
+		return
+	}
+
+	var isDefault bool
+	err = db.QueryRow("SELECT is_default FROM layout_profiles WHERE id = ?", id).Scan(&isDefault)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Layout profile not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if isDefault {
+		http.Error(w, "The default layout profile cannot be deleted", http.StatusBadRequest)
+		return
+	}
+
+	if _, err := db.Exec("DELETE FROM layout_profiles WHERE id = ?", id); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Show which layout profile would be used for a screenshot and how each profile scored
+func detectLayoutProfile(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseMultipartForm(10 << 20); err != nil {
+		http.Error(w, "Failed to parse form", http.StatusBadRequest)
+		return
+	}
+
+	file, _, err := r.FormFile("image")
+	if err != nil {
+		http.Error(w, "No image file provided", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	img, _, err := image.Decode(file)
+	if err != nil {
+		http.Error(w, "Failed to decode image", http.StatusBadRequest)
+		return
+	}
+
+	profiles, err := loadLayoutProfiles()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	screen := r.FormValue("screen")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"selected": selectLayoutProfile(img, screen),
+		"matches":  rankLayoutProfiles(img, screen, profiles),
+	})
+}
+
+// Analyze screenshot to detect distinct regions and attributes
+func analyzeScreenshot(img image.Image, profile *LayoutProfile) *ScreenshotAttributes {
+	bounds := img.Bounds()
+	width := bounds.Dx()
+	height := bounds.Dy()
+
+	attrs := &ScreenshotAttributes{
+		Width:   width,
+		Height:  height,
+		Profile: profile,
+	}
+
+	// Title bar at top (dark colored)
+	titleBarHeight := int(float64(height) * profile.TitleBottom)
+	attrs.TitleBarRegion = &ImageRegion{
+		Name:   "TitleBar",
+		Top:    0,
+		Bottom: titleBarHeight,
+		Left:   0,
+		Right:  width,
+	}
+
+	// Day tabs (VS screens) or category tabs (rankings)
+	attrs.TabsRegion = &ImageRegion{
+		Name:   "Tabs",
+		Top:    int(float64(height) * profile.TabsTop),
+		Bottom: int(float64(height) * profile.TabsBottom),
+		Left:   int(float64(width) * profile.TabsLeft),
+		Right:  int(float64(width) * profile.TabsRight),
+	}
+
+	// Column headers sit between the tabs and the first data row
+	dataTop := int(float64(height) * profile.DataTop)
+	attrs.HeaderRegion = &ImageRegion{
+		Name:   "Headers",
+		Top:    max(attrs.TabsRegion.Bottom, titleBarHeight),
+		Bottom: dataTop,
+		Left:   0,
+		Right:  width,
+	}
+
+	// Bottom button region is everything below the data rows
+	dataBottom := int(float64(height) * profile.DataBottom)
+	attrs.ButtonRegion = &ImageRegion{
+		Name:   "BottomButton",
+		Top:    dataBottom,
+		Bottom: height,
+		Left:   0,
+		Right:  width,
+	}
+
+	// Data region holds the ranking rows
+	attrs.DataRegion = &ImageRegion{
+		Name:   "DataRows",
+		Top:    dataTop,
+		Bottom: dataBottom,
+		Left:   0,
+		Right:  width,
+	}
+
+	// Estimate row height and count
+	dataHeight := dataBottom - dataTop
+	attrs.RowHeight = dataHeight / profile.VisibleRows
+	if attrs.RowHeight < 40 {
+		attrs.RowHeight = 40
+	}
+	attrs.EstimatedRows = dataHeight / attrs.RowHeight
+
+	log.Printf("Screenshot Analysis: %dx%d, DataRegion: (%d,%d) to (%d,%d), Est. Rows: %d",
+		width, height, attrs.DataRegion.Left, attrs.DataRegion.Top,
+		attrs.DataRegion.Right, attrs.DataRegion.Bottom, attrs.EstimatedRows)
+
+	return attrs
+}
+
+// Crop image to data region only
+func cropToDataRegion(img image.Image, region *ImageRegion) image.Image {
+	bounds := img.Bounds()
+	top := region.Top
+	bottom := region.Bottom
+	left := region.Left
+	right := region.Right
+
+	// Ensure bounds are valid
+	if top < bounds.Min.Y {
+		top = bounds.Min.Y
+	}
+	if bottom > bounds.Max.Y {
+		bottom = bounds.Max.Y
+	}
+	if left < bounds.Min.X {
+		left = bounds.Min.X
+	}
+	if right > bounds.Max.X {
+		right = bounds.Max.X
+	}
+
+	croppedImg := image.NewRGBA(image.Rect(0, 0, right-left, bottom-top))
+	draw.Draw(croppedImg, croppedImg.Bounds(), img, image.Point{left, top}, draw.Src)
+
+	log.Printf("Cropped image from %v to %v", bounds, croppedImg.Bounds())
+	return croppedImg
+}
+
+// Convert image to grayscale
+func convertToGrayscale(img image.Image) *image.Gray {
+	bounds := img.Bounds()
+	gray := image.NewGray(bounds)
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			gray.Set(x, y, img.At(x, y))
+		}
+	}
+
+	return gray
+}
+
+// Enhance contrast using histogram equalization (simplified)
+func enhanceContrast(img *image.Gray) *image.Gray {
+	bounds := img.Bounds()
+	enhanced := image.NewGray(bounds)
+
+	// Calculate histogram
+	histogram := make([]int, 256)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			grayVal := img.GrayAt(x, y).Y
+			histogram[grayVal]++
+		}
+	}
+
+	// Calculate cumulative distribution
+	totalPixels := bounds.Dx() * bounds.Dy()
+	cdf := make([]float64, 256)
+	cdf[0] = float64(histogram[0]) / float64(totalPixels)
+	for i := 1; i < 256; i++ {
+		cdf[i] = cdf[i-1] + float64(histogram[i])/float64(totalPixels)
+	}
+
+	// Apply equalization
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			grayVal := img.GrayAt(x, y).Y
+			newVal := uint8(cdf[grayVal] * 255)
+			enhanced.SetGray(x, y, color.Gray{Y: newVal})
+		}
+	}
+
+	return enhanced
+}
+
+// Apply adaptive thresholding to enhance text
+func applyAdaptiveThreshold(img *image.Gray, blockSize int) *image.Gray {
+	bounds := img.Bounds()
+	thresholded := image.NewGray(bounds)
+
+	halfBlock := blockSize / 2
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			// Calculate local mean in block
+			sum := 0
+			count := 0
+			for by := y - halfBlock; by <= y+halfBlock; by++ {
+				for bx := x - halfBlock; bx <= x+halfBlock; bx++ {
+					if bx >= bounds.Min.X && bx < bounds.Max.X && by >= bounds.Min.Y && by < bounds.Max.Y {
+						sum += int(img.GrayAt(bx, by).Y)
+						count++
+					}
 				}
 			}
-			recRows.Close()
+			mean := uint8(sum / count)
+
+			// Threshold: if pixel is darker than local mean, make it black, else white
+			pixel := img.GrayAt(x, y).Y
+			if pixel < mean-10 { // -10 for bias towards text
+				thresholded.SetGray(x, y, color.Gray{Y: 0}) // Black (text)
+			} else {
+				thresholded.SetGray(x, y, color.Gray{Y: 255}) // White (background)
+			}
+		}
+	}
+
+	return thresholded
+}
+
+// Invert image (make text black on white background)
+func invertImage(img *image.Gray) *image.Gray {
+	bounds := img.Bounds()
+	inverted := image.NewGray(bounds)
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			grayVal := img.GrayAt(x, y).Y
+			inverted.SetGray(x, y, color.Gray{Y: 255 - grayVal})
+		}
+	}
+
+	return inverted
+}
+
+// Scale image up for better OCR
+func scaleImage(img image.Image, factor int) image.Image {
+	bounds := img.Bounds()
+	newWidth := bounds.Dx() * factor
+	newHeight := bounds.Dy() * factor
+
+	scaled := image.NewRGBA(image.Rect(0, 0, newWidth, newHeight))
+
+	// Simple nearest-neighbor scaling
+	for y := 0; y < newHeight; y++ {
+		for x := 0; x < newWidth; x++ {
+			origX := x / factor
+			origY := y / factor
+			scaled.Set(x, y, img.At(origX, origY))
+		}
+	}
+
+	return scaled
+}
+
+// Preprocess image for better OCR; screen ("power" or "vs_points") narrows the layout profiles considered
+func preprocessImageForOCR(imageData []byte, screen string) ([]byte, ocrTransform, error) {
+	transform := ocrTransform{Scale: 1}
+
+	// Decode image
+	img, format, err := image.Decode(bytes.NewReader(imageData))
+	if err != nil {
+		return nil, transform, fmt.Errorf("failed to decode image: %v", err)
+	}
+
+	log.Printf("Original image: %dx%d, format: %s", img.Bounds().Dx(), img.Bounds().Dy(), format)
+
+	// Analyze screenshot to detect regions
+	attrs := analyzeScreenshot(img, selectLayoutProfile(img, screen))
+
+	// Crop to data region only (remove UI elements)
+	// For narrow screenshots or when power values might be cut off, use less aggressive cropping
+	croppedImg := img
+	if attrs.DataRegion != nil && attrs.Width > 600 {
+		croppedImg = cropToDataRegion(img, attrs.DataRegion)
+		transform.Offset = image.Point{
+			X: max(attrs.DataRegion.Left, img.Bounds().Min.X),
+			Y: max(attrs.DataRegion.Top, img.Bounds().Min.Y),
+		}
+	} else {
+		log.Printf("Skipping crop for narrow image to preserve power values")
+	}
+
+	// Scale up 2x for better OCR (small text is hard to read)
+	scaledImg := scaleImage(croppedImg, 2)
+	transform.Scale = 2
+
+	// Convert to grayscale
+	grayImg := convertToGrayscale(scaledImg)
+
+	// For small images, skip contrast enhancement (can make things worse)
+	processedImg := grayImg
+
+	// Encode back to bytes
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, processedImg); err != nil {
+		return nil, transform, fmt.Errorf("failed to encode processed image: %v", err)
+	}
+
+	log.Printf("Image preprocessed: %dx%d -> %dx%d (2x scaled grayscale)",
+		img.Bounds().Dx(), img.Bounds().Dy(),
+		processedImg.Bounds().Dx(), processedImg.Bounds().Dy())
+
+	return buf.Bytes(), transform, nil
+}
+
+// Extract power data from image using OCR with preprocessing
+func extractPowerDataFromImage(imageData []byte) ([]OCRRecord, error) {
+	_, records, err := extractRankingDataFromImage(imageData, "power")
+	return records, err
+}
+
+// extractRankingDataFromImage reads a strength ranking screenshot. When metric is empty the selected
+// tab (power, kills or donation) is detected, failing when it can't be; the metric that was read is
+// returned with the rows.
+func extractRankingDataFromImage(imageData []byte, metric string) (string, []OCRRecord, error) {
+	if metric == "" {
+		metric = detectRankingMetric(imageData)
+		if metric == "" {
+			return "", nil, fmt.Errorf("could not tell which ranking tab is selected; choose power, kills or donations and upload again")
+		}
+	}
+	rankingMetric, found := findRankingMetric(metric)
+	if !found {
+		return "", nil, fmt.Errorf("unknown ranking metric: %s", metric)
+	}
+
+	// Preprocess image to filter and enhance relevant regions
+	processedData, transform, err := preprocessImageForOCR(imageData, "power")
+	if err != nil {
+		log.Printf("Warning: Image preprocessing failed: %v. Using original image.", err)
+		processedData = imageData // Fallback to original
+		transform = ocrTransform{Scale: 1}
+	}
+
+	// Try different PSM modes for better recognition
+	var text string
+	var words []OCRWord
+	languages := ocrLanguages()
+	psmModes := []OCRPageSegMode{
+		OCRPageAuto,
+		OCRPageSingleBlock,
+		OCRPageSparseText,
+	}
+
+	for i, mode := range psmModes {
+		result, err := ocrEngine.Recognize(processedData, mode, languages)
+		if err == nil && len(strings.TrimSpace(result.Text)) > 0 {
+			text = result.Text
+			words = result.Words
+			log.Printf("OCR successful with PSM mode %d (attempt %d)", mode, i+1)
+			break
+		}
+		if err != nil {
+			log.Printf("OCR attempt %d with PSM mode %d failed: %v", i+1, mode, err)
+		} else {
+			log.Printf("OCR attempt %d with PSM mode %d returned no text", i+1, mode)
+		}
+	}
+
+	if len(strings.TrimSpace(text)) == 0 {
+		return metric, nil, fmt.Errorf("OCR failed: no text extracted after trying multiple modes")
+	}
+
+	// Log the extracted text for debugging
+	log.Printf("OCR extracted text:\n%s\n---END OCR---", text)
+
+	// Parse the OCR text
+	records := parseRankingText(text, rankingMetric)
+	applyOCRWordFields(words, records, transform)
+
+	if len(records) == 0 {
+		return metric, nil, fmt.Errorf("no valid records found in extracted text (see server logs for OCR output)")
+	}
+
+	return metric, records, nil
+}
+
+// leadingRankPattern matches the in-game rank number at the start of a ranking line, e.g. "7 dvdAlbert91" or "B 25) Nutty Tx"
+var leadingRankPattern = regexp.MustCompile(`^(?:[A-Z]{1,3}\s+)?([0-9]{1,3})[\s).:]`)
+
+// parseLeadingRank returns the rank number a ranking line starts with, or 0 if there is none
+func parseLeadingRank(line string) int {
+	matches := leadingRankPattern.FindStringSubmatch(strings.TrimSpace(line))
+	if len(matches) < 2 {
+		return 0
+	}
+	rank, err := strconv.Atoi(matches[1])
+	if err != nil || rank < 1 {
+		return 0
+	}
+	return rank
+}
+
+// Player names may use any script, accents, emoji and game symbols. A name starts with a letter or
+// symbol, and may contain spaces; callers add the quantifier to playerNamePattern.
+const (
+	playerNameStart   = `[\p{L}\p{So}]`
+	playerNameChars   = `\p{L}\p{M}\p{N}\p{So}\p{Sk}\p{Pc}\p{Pd}\x{200D}`
+	playerNamePattern = playerNameStart + `[` + playerNameChars + `\s]`
+)
+
+// validPlayerNameLength reports whether a parsed name has a plausible length (3-30 characters).
+// Chinese, Japanese and Korean names are often only two characters long.
+func validPlayerNameLength(name string) bool {
+	length := utf8.RuneCountInString(name)
+	minLength := 3
+	for _, r := range name {
+		if unicode.In(r, unicode.Han, unicode.Hangul, unicode.Hiragana, unicode.Katakana) {
+			minLength = 2
+			break
+		}
+	}
+	return length >= minLength && length <= 30
+}
+
+// rankingValuePattern matches a word with at least three digits, the value column of a ranking row
+// even when OCR misread some of its digits as letters ("s1926102")
+var rankingValuePattern = regexp.MustCompile(`(?:[^0-9]*[0-9]){3}`)
+
+// isRankingHeaderLine reports whether a line of ranking text is the screen's title, tabs or column
+// headers. Those name the screen's words and carry no value, so a row whose member name contains one
+// ("PowerRanger 63785308") is still read.
+func isRankingHeaderLine(line string) bool {
+	fields := strings.Fields(line)
+	if len(fields) > 0 && rankingValuePattern.MatchString(fields[len(fields)-1]) {
+		return false
+	}
+	lowerLine := strings.ToLower(line)
+	for _, word := range []string{"ranking", "commander", "power", "kills", "donation"} {
+		if strings.Contains(lowerLine, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// trailingRankBadgePattern matches an alliance rank badge (R1-R5) read at the end of a member name
+var trailingRankBadgePattern = regexp.MustCompile(`\s+R[1-5]$`)
+
+// Parse power rankings text (from OCR or manual input)
+func parsePowerRankingsText(text string) []OCRRecord {
+	var records []OCRRecord
+
+	lines := strings.Split(text, "\n")
+
+	// Pattern specifically for Last War rankings format
+	// Matches: optional rank badge (R4, R3), name (can have spaces), then large power number
+	// Examples: "R4 Gary6126 77421000", "Nutty Tx 61926102", "R3 DYNOSUR 63785308"
+	// Updated to better handle multi-word names
+	rankPattern := regexp.MustCompile(`(?:R[0-9]\s+)?(` + playerNamePattern + `+?)\s+([0-9]{7,})`)
+
+	// Alternative simpler pattern: captures name with spaces followed by 7+ digit number
+	simplePattern := regexp.MustCompile(`(` + playerNamePattern + `+?)\s+([0-9]{7,})`)
+
+	// Pattern for lines with rank number prefix: "1 Gary6126 R4 77421000" or "1 ileesu R4 66715876"
+	rankPrefixPattern := regexp.MustCompile(`^[0-9]{1,3}\s+(` + playerNamePattern + `+?)\s+(?:R[0-9]\s+)?([0-9]{7,})`)
+
+	// Flexible pattern that allows letters in power (for OCR errors): "B 25) Nutty Tx s1926102"
+	// This captures name followed by 7+ chars that may contain letters misread as digits
+	flexiblePattern := regexp.MustCompile(`(?:[A-Z]{1,3}\s+)?(?:\d+\)?\s+)?(` + playerNamePattern + `+?)\s+([A-Za-z0-9]{7,})`)
+
+	// Track seen names to avoid duplicates from multi-line OCR
+	seenNames := make(map[string]bool)
+
+	for _, rawLine := range lines {
+		rawLine = strings.TrimSpace(rawLine)
+		if rawLine == "" {
+			continue
+		}
+		// Full-width digits and letters become plain ones, so "１２３" parses as a number
+		line := norm.NFKC.String(rawLine)
+
+		// Skip lines that are clearly UI elements or rank numbers
+		if len(line) < 5 || regexp.MustCompile(`^[0-9]{1,2}$`).MatchString(line) {
+			continue
+		}
+
+		// Skip the title, tabs and column headers
+		if isRankingHeaderLine(line) {
+			continue
+		}
+
+		// Try rank pattern first (for lines with R4, R3, etc.)
+		matches := rankPattern.FindStringSubmatch(line)
+		if len(matches) == 0 {
+			// Try pattern with rank number prefix
+			matches = rankPrefixPattern.FindStringSubmatch(line)
+		}
+		if len(matches) == 0 {
+			// Try simple pattern
+			matches = simplePattern.FindStringSubmatch(line)
+		}
+		if len(matches) == 0 {
+			// Try flexible pattern (allows letters in power number for OCR errors)
+			matches = flexiblePattern.FindStringSubmatch(line)
+		}
+
+		if len(matches) >= 3 {
+			name := strings.TrimSpace(matches[1])
+			// Clean up extra whitespace in names
+			name = regexp.MustCompile(`\s+`).ReplaceAllString(name, " ")
+			// The rank badge after the name ("Gary6126 R4") isn't part of it
+			name = strings.TrimSpace(trailingRankBadgePattern.ReplaceAllString(name, ""))
+
+			powerStr := strings.ReplaceAll(matches[2], ",", "")
+			powerStr = strings.ReplaceAll(powerStr, " ", "")
+			powerStr = strings.ReplaceAll(powerStr, ".", "") // Remove periods OCR might insert
+			// Common OCR character misreads for digits
+			powerStr = strings.ReplaceAll(powerStr, "O", "0")
+			powerStr = strings.ReplaceAll(powerStr, "o", "0")
+			powerStr = strings.ReplaceAll(powerStr, "s", "6") // s often misread as 6
+			powerStr = strings.ReplaceAll(powerStr, "S", "5") // S often misread as 5
+			powerStr = strings.ReplaceAll(powerStr, "l", "1") // l often misread as 1
+			powerStr = strings.ReplaceAll(powerStr, "I", "1") // I often misread as 1
+			powerStr = strings.ReplaceAll(powerStr, "Z", "2") // Z sometimes misread as 2
+			powerStr = strings.ReplaceAll(powerStr, "B", "8") // B sometimes misread as 8
+			powerStr = strings.ReplaceAll(powerStr, "e", "6") // e sometimes misread as 6
+			powerStr = strings.ReplaceAll(powerStr, "g", "9") // g sometimes misread as 9
+			powerStr = strings.ReplaceAll(powerStr, "G", "6") // G sometimes misread as 6
+			// Remove any remaining non-digit characters
+			powerStr = regexp.MustCompile(`[^0-9]`).ReplaceAllString(powerStr, "")
+
+			power, err := strconv.ParseInt(powerStr, 10, 64)
+
+			// Validate: power should be realistic (1M to 1B range), name should be reasonable
+			if err == nil && power >= 1000000 && power <= 9999999999 &&
+				validPlayerNameLength(name) && !seenNames[name] {
+				records = append(records, OCRRecord{
+					MemberName: name,
+					Value:      power,
+					RawText:    rawLine,
+					Rank:       parseLeadingRank(line),
+				})
+				seenNames[name] = true
+				log.Printf("Parsed: %s -> %d", name, power)
+			} else if err != nil {
+				log.Printf("Failed to parse power for %s: %s (error: %v)", name, powerStr, err)
+			}
 		}
+	}
 
-		// Convert map to sorted slice
-		var events []PointEvent
-		for _, event := range eventMap {
-			events = append(events, *event)
+	return records
+}
+
+// parseRankingText parses strength ranking text for one metric. Power keeps its own parser, which
+// also repairs letters misread in its 7+ digit values; kills and donations can be short numbers,
+// so for them the value has to be the last thing on the line.
+func parseRankingText(text string, metric RankingMetric) []OCRRecord {
+	if metric.Name == "power" {
+		return parsePowerRankingsText(text)
+	}
+
+	// "3 Nutty Tx 125,400", "R4 Gary6126 98211" or "12 ileesu R4 4500"
+	rowPattern := regexp.MustCompile(`^(?:[0-9]{1,3}\)?\s+)?(?:R[0-9]\s+)?(` + playerNamePattern + `*?)\s+(?:R[0-9]\s+)?([0-9][0-9,.]*)$`)
+
+	records := []OCRRecord{}
+	seenNames := make(map[string]bool)
+	for _, rawLine := range strings.Split(text, "\n") {
+		rawLine = strings.TrimSpace(rawLine)
+		if rawLine == "" {
+			continue
 		}
-		sort.Slice(events, func(i, j int) bool {
-			return events[i].Date < events[j].Date
+		line := norm.NFKC.String(rawLine)
+
+		// Skip the tabs and column headers
+		if isRankingHeaderLine(line) {
+			continue
+		}
+
+		matches := rowPattern.FindStringSubmatch(line)
+		if len(matches) < 3 {
+			continue
+		}
+		name := regexp.MustCompile(`\s+`).ReplaceAllString(strings.TrimSpace(matches[1]), " ")
+		value, err := strconv.ParseInt(strings.NewReplacer(",", "", ".", "").Replace(matches[2]), 10, 64)
+		if err != nil || value < metric.MinValue || value > metric.MaxValue ||
+			!validPlayerNameLength(name) || seenNames[name] {
+			continue
+		}
+		records = append(records, OCRRecord{
+			MemberName: name,
+			Value:      value,
+			RawText:    rawLine,
+			Rank:       parseLeadingRank(line),
 		})
+		seenNames[name] = true
+		log.Printf("Parsed %s: %s -> %d", metric.Name, name, value)
+	}
+	return records
+}
 
-		// Get power history for this member
-		powerHistoryMap := make(map[string]int)
-		powerRows, err := db.Query(`
-			SELECT DATE(recorded_at) as power_date, 
-			       MAX(power) as max_power
-			FROM power_history 
-			WHERE member_id = ? AND DATE(recorded_at) >= ?
-			GROUP BY DATE(recorded_at)
-			ORDER BY power_date ASC
-		`, member.ID, formatDateString(startDate))
-		if err == nil {
-			for powerRows.Next() {
-				var powerDate string
-				var maxPower int
-				if err := powerRows.Scan(&powerDate, &maxPower); err == nil {
-					powerHistoryMap[powerDate] = maxPower
-				}
+// detectRankingMetricFromText finds the metric of pasted ranking text from its column header
+// ("Ranking Commander Kills"); it returns "" when there is no header
+func detectRankingMetricFromText(text string) string {
+	for _, line := range strings.Split(strings.ToLower(text), "\n") {
+		if !strings.Contains(line, "commander") {
+			continue
+		}
+		for _, metric := range rankingMetrics {
+			if strings.Contains(line, strings.ToLower(metric.Label)) {
+				return metric.Name
+			}
+		}
+	}
+	return ""
+}
+
+// OCRPageSegMode is a Tesseract page segmentation mode
+type OCRPageSegMode int
+
+// Page segmentation modes used by the recognizer (same numbers as tesseract --psm)
+const (
+	OCRPageAuto        OCRPageSegMode = 3
+	OCRPageSingleBlock OCRPageSegMode = 6
+	OCRPageSingleLine  OCRPageSegMode = 7
+	OCRPageSparseText  OCRPageSegMode = 11
+)
+
+// OCRWord is a recognized word and its position on the OCR image.
+// Block, Paragraph and Line identify the text line the word belongs to.
+type OCRWord struct {
+	Text       string
+	Box        image.Rectangle
+	Confidence float64
+	Block      int
+	Paragraph  int
+	Line       int
+}
+
+// OCRResult is the text and words recognized in one image
+type OCRResult struct {
+	Text  string
+	Words []OCRWord
+}
+
+// OCREngine recognizes text in images. The implementation is picked at build time:
+// ocr_tesseract.go (-tags tesseract, in-process via CGO and libtesseract), ocr_cli.go (default,
+// runs the tesseract command) or ocr_none.go (-tags noocr, screenshot recognition disabled).
+type OCREngine interface {
+	// Name identifies the engine in logs and API responses
+	Name() string
+	// Available returns why the engine cannot run, or nil if it can
+	Available() error
+	// Languages lists the installed Tesseract language packs
+	Languages() ([]string, error)
+	// Recognize runs OCR on an encoded image with the given language packs (e.g. eng, rus, kor)
+	Recognize(imageData []byte, mode OCRPageSegMode, languages []string) (OCRResult, error)
+}
+
+// ocrEngine is the OCR engine built into this binary
+var ocrEngine = newOCREngine()
+
+// requireOCR responds with 501 Not Implemented and returns false when this build cannot read screenshots
+func requireOCR(w http.ResponseWriter) bool {
+	if err := ocrEngine.Available(); err != nil {
+		http.Error(w, fmt.Sprintf("Screenshot recognition is not available (%s): %v", ocrEngine.Name(), err), http.StatusNotImplemented)
+		return false
+	}
+	return true
+}
+
+// defaultOCRLanguages is used until an R5 configures other language packs
+const defaultOCRLanguages = "eng"
+
+// ocrLanguagePattern matches a Tesseract language pack name (eng, chi_sim, script/Cyrillic)
+var ocrLanguagePattern = regexp.MustCompile(`^[A-Za-z]+(?:[_/][A-Za-z]+)*$`)
+
+// parseOCRLanguages splits an ocr_languages setting ("eng+rus+kor", commas and spaces also work)
+func parseOCRLanguages(value string) ([]string, error) {
+	languages := strings.FieldsFunc(value, func(r rune) bool {
+		return r == '+' || r == ',' || unicode.IsSpace(r)
+	})
+	if len(languages) == 0 {
+		return nil, fmt.Errorf("at least one OCR language is required")
+	}
+	for _, language := range languages {
+		if !ocrLanguagePattern.MatchString(language) {
+			return nil, fmt.Errorf("invalid OCR language %q", language)
+		}
+	}
+	return languages, nil
+}
+
+// ocrLanguages returns the configured Tesseract language packs, English when none are set
+func ocrLanguages() []string {
+	value := defaultOCRLanguages
+	if db != nil {
+		if err := db.QueryRow("SELECT ocr_languages FROM settings WHERE id = 1").Scan(&value); err != nil {
+			log.Printf("Failed to load OCR languages, using %s: %v", defaultOCRLanguages, err)
+			value = defaultOCRLanguages
+		}
+	}
+	languages, err := parseOCRLanguages(value)
+	if err != nil {
+		return []string{defaultOCRLanguages}
+	}
+	return languages
+}
+
+// ocrTransform maps positions on a preprocessed OCR image back to the original screenshot
+type ocrTransform struct {
+	Offset image.Point // top-left of the OCR image in the original screenshot
+	Scale  int         // upscale factor applied before OCR
+}
+
+// toOriginal converts a rectangle on the OCR image to original screenshot coordinates
+func (t ocrTransform) toOriginal(r image.Rectangle) *OCRBox {
+	scale := t.Scale
+	if scale < 1 {
+		scale = 1
+	}
+	return &OCRBox{
+		X:      t.Offset.X + r.Min.X/scale,
+		Y:      t.Offset.Y + r.Min.Y/scale,
+		Width:  r.Dx() / scale,
+		Height: r.Dy() / scale,
+	}
+}
+
+// summarizeOCRWords returns the mean confidence and enclosing box of a set of recognized words
+func summarizeOCRWords(words []OCRWord, transform ocrTransform) (float64, *OCRBox) {
+	if len(words) == 0 {
+		return 0, nil
+	}
+	total := 0.0
+	bounds := words[0].Box
+	for _, word := range words {
+		total += word.Confidence
+		bounds = bounds.Union(word.Box)
+	}
+	return total / float64(len(words)), transform.toOriginal(bounds)
+}
+
+// ocrConfidenceUnknown marks a record whose text line wasn't found among the OCR words, so its
+// confidence wasn't measured; such rows aren't held to ocr_min_confidence
+const ocrConfidenceUnknown = -1
+
+// applyOCRWordFields fills in per-field text, confidence and boxes for records parsed from full-image text.
+// Each record is tied back to its text line; the word holding the value is the value field and the
+// words of the member name are the name field.
+func applyOCRWordFields(words []OCRWord, records []OCRRecord, transform ocrTransform) {
+	// Group words into text lines, keyed by their whitespace-normalized text
+	type lineKey struct{ block, par, line int }
+	lineOrder := []lineKey{}
+	lineWords := make(map[lineKey][]OCRWord)
+	for _, word := range words {
+		if strings.TrimSpace(word.Text) == "" {
+			continue
+		}
+		key := lineKey{word.Block, word.Paragraph, word.Line}
+		if _, seen := lineWords[key]; !seen {
+			lineOrder = append(lineOrder, key)
+		}
+		lineWords[key] = append(lineWords[key], word)
+	}
+	linesByText := make(map[string][]OCRWord)
+	for _, key := range lineOrder {
+		texts := []string{}
+		for _, word := range lineWords[key] {
+			texts = append(texts, strings.TrimSpace(word.Text))
+		}
+		linesByText[strings.Join(texts, " ")] = lineWords[key]
+	}
+
+	for i := range records {
+		record := &records[i]
+		line, found := linesByText[strings.Join(strings.Fields(record.RawText), " ")]
+		if !found {
+			record.Confidence = ocrConfidenceUnknown
+			record.NameConfidence = ocrConfidenceUnknown
+			record.ValueConfidence = ocrConfidenceUnknown
+			continue
+		}
+
+		valueDigits := strconv.FormatInt(record.Value, 10)
+		valueIndex := len(line) - 1
+		for j := len(line) - 1; j >= 0; j-- {
+			if strings.Contains(strings.NewReplacer(",", "", ".", "").Replace(line[j].Text), valueDigits) {
+				valueIndex = j
+				break
 			}
-			powerRows.Close()
 		}
 
-		// Build weekly timeline arrays
-		weekLabels := []string{}
-		pointsWithReset := []int{}
-		pointsCumulative := []int{}
-		awardsWithReset := []int{}
-		awardsCumulative := []int{}
-		recsWithReset := []int{}
-		recsCumulative := []int{}
-		rankBoostWithReset := []int{}
-		rankBoostCumulative := []int{}
-		firstTimeBoostWithReset := []int{}
-		firstTimeBoostCumulative := []int{}
-		recentPenaltyWithReset := []int{}
-		recentPenaltyCumulative := []int{}
-		aboveAvgPenaltyWithReset := []int{}
-		aboveAvgPenaltyCumulative := []int{}
-		powerValues := []int{}
+		nameWords := []OCRWord{}
+		nameTexts := []string{}
+		for _, word := range line[:valueIndex] {
+			text := strings.Trim(strings.TrimSpace(word.Text), ".,;:|()[]")
+			if text != "" && strings.Contains(record.MemberName, norm.NFKC.String(text)) {
+				nameWords = append(nameWords, word)
+				nameTexts = append(nameTexts, strings.TrimSpace(word.Text))
+			}
+		}
 
-		currentPoints := 0
-		cumulativePoints := 0
-		currentAwards := 0
-		cumulativeAwards := 0
-		currentRecs := 0
-		cumulativeRecs := 0
-		currentRankBoost := 0
-		cumulativeRankBoost := 0
-		currentFirstTimeBoost := 0
-		cumulativeFirstTimeBoost := 0
-		currentRecentPenalty := 0
-		cumulativeRecentPenalty := 0
-		currentAboveAvgPenalty := 0
-		cumulativeAboveAvgPenalty := 0
-		conductorIdx := 0
-		conductorCountSoFar := 0
+		record.NameText = strings.Join(nameTexts, " ")
+		record.NameConfidence, record.NameBox = summarizeOCRWords(nameWords, transform)
+		record.ValueText = strings.TrimSpace(line[valueIndex].Text)
+		record.ValueConfidence, record.ValueBox = summarizeOCRWords(line[valueIndex:valueIndex+1], transform)
+		record.Confidence = math.Min(record.NameConfidence, record.ValueConfidence)
+	}
+}
 
-		// Generate week range from start to now (by Monday of each week)
-		currentDate := getMondayOfWeek(startDate)
-		for currentDate.Before(now) || currentDate.Equal(now) {
-			weekStart := currentDate
-			weekEnd := currentDate.AddDate(0, 0, 6)
-			weekStartStr := formatDateString(weekStart)
-			weekEndStr := formatDateString(weekEnd)
+// confusableLetters folds Cyrillic and Greek letters that look like Latin ones, so a name typed with
+// a Cyrillic "а" matches the Latin "a" OCR reads (and the other way round)
+var confusableLetters = strings.NewReplacer(
+	"а", "a", "в", "b", "е", "e", "ё", "e", "к", "k", "м", "m", "н", "h", "о", "o", "р", "p",
+	"с", "c", "т", "t", "у", "y", "х", "x", "і", "i", "ї", "i", "ј", "j", "ѕ", "s", "һ", "h", "ԁ", "d",
+	"α", "a", "β", "b", "ε", "e", "η", "n", "ι", "i", "κ", "k", "ν", "v", "ο", "o", "ρ", "p",
+	"τ", "t", "υ", "u", "χ", "x", "ω", "w",
+)
 
-			// Format: "Jan 1 - Jan 7"
-			weekLabel := fmt.Sprintf("%s - %s",
-				weekStart.Format("Jan 2"),
-				weekEnd.Format("Jan 2"))
-			weekLabels = append(weekLabels, weekLabel)
+// Normalize name for matching (remove common prefixes, spaces, special chars).
+// Names are NFKC-normalized (full-width and styled letters become plain ones), accents on Latin
+// letters are dropped, look-alike letters are folded to Latin and anything else that isn't a letter,
+// digit or mark (spaces, punctuation, emoji) is removed.
+func normalizeName(name string) string {
+	name = strings.ToLower(norm.NFKC.String(name))
+	// Remove common prefixes
+	name = strings.TrimPrefix(name, "the ")
+	name = strings.TrimPrefix(name, "a ")
+	name = strings.TrimPrefix(name, "an ")
 
-			// Check if this week has a conductor event (train reset)
-			weekHasReset := false
-			for conductorIdx < len(conductorDates) && conductorDates[conductorIdx] <= weekEndStr {
-				if conductorDates[conductorIdx] >= weekStartStr {
-					weekHasReset = true
-					conductorCountSoFar++
-				}
-				conductorIdx++
+	var b strings.Builder
+	var base rune
+	for _, r := range norm.NFD.String(confusableLetters.Replace(name)) {
+		switch {
+		case unicode.IsMark(r):
+			// "é" matches "e", but marks that change the letter in other scripts are kept
+			if base != 0 && !unicode.Is(unicode.Latin, base) {
+				b.WriteRune(r)
 			}
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			base = r
+			b.WriteRune(r)
+		default:
+			base = 0
+		}
+	}
+	return norm.NFC.String(b.String())
+}
 
-			// Add points from events in this week
-			weekAwards := 0
-			weekRecs := 0
-			for _, event := range events {
-				if event.Date >= weekStartStr && event.Date <= weekEndStr {
-					weekAwards += event.Awards
-					weekRecs += event.Recs
-				}
-			}
-			weekPoints := weekAwards + weekRecs
+// Calculate string similarity (0-100) using improved algorithm
+func calculateSimilarity(s1, s2 string) int {
+	// Normalize both strings
+	n1 := normalizeName(s1)
+	n2 := normalizeName(s2)
 
-			currentPoints += weekPoints
-			cumulativePoints += weekPoints
-			currentAwards += weekAwards
-			cumulativeAwards += weekAwards
-			currentRecs += weekRecs
-			cumulativeRecs += weekRecs
+	// If normalized strings are identical, perfect match
+	if n1 == n2 {
+		return 100
+	}
 
-			// Calculate Rank Boost (R4/R5 exponential)
-			weekRankBoost := 0
-			if member.Rank == "R4" || member.Rank == "R5" {
-				baseBoost := float64(settings.R4R5RankBoost)
-				// Find most recent conductor date before this week
-				daysSinceLastDuty := 0
-				for i := conductorIdx - 1; i >= 0; i-- {
-					if i < len(conductorDates) {
-						if lastConductorDate, err := time.Parse("2006-01-02", conductorDates[i]); err == nil {
-							daysSinceLastDuty = int(weekEnd.Sub(lastConductorDate).Hours() / 24)
-							break
-						}
-					}
-				}
-				multiplier := math.Pow(2, float64(daysSinceLastDuty)/7.0)
-				weekRankBoost = int(math.Round(baseBoost * multiplier))
-			}
-			currentRankBoost += weekRankBoost
-			cumulativeRankBoost += weekRankBoost
+	// If one contains the other after normalization, very high score
+	if strings.Contains(n1, n2) || strings.Contains(n2, n1) {
+		return 90
+	}
 
-			// Calculate First Time Conductor Boost
-			weekFirstTimeBoost := 0
-			if conductorCountSoFar == 0 {
-				// Only if they have other points (awards, recs, or rank boost)
-				if currentAwards > 0 || currentRecs > 0 || currentRankBoost > 0 {
-					weekFirstTimeBoost = settings.FirstTimeConductorBoost
-				}
-			}
-			currentFirstTimeBoost += weekFirstTimeBoost
-			cumulativeFirstTimeBoost += weekFirstTimeBoost
+	// Calculate Levenshtein distance using existing function
+	distance := levenshteinDistance(n1, n2)
+	maxLen := utf8.RuneCountInString(n1)
+	if n := utf8.RuneCountInString(n2); n > maxLen {
+		maxLen = n
+	}
 
-			// Calculate Recent Conductor Penalty
-			weekRecentPenalty := 0
-			if conductorCountSoFar > 0 {
-				// Find most recent conductor date
-				for i := conductorIdx - 1; i >= 0; i-- {
-					if i < len(conductorDates) {
-						if lastConductorDate, err := time.Parse("2006-01-02", conductorDates[i]); err == nil {
-							daysSince := int(weekEnd.Sub(lastConductorDate).Hours() / 24)
-							penalty := settings.RecentConductorPenaltyDays - daysSince
-							if penalty > 0 {
-								weekRecentPenalty = penalty
-							}
-							break
-						}
-					}
-				}
-			}
-			currentRecentPenalty += weekRecentPenalty
-			cumulativeRecentPenalty += weekRecentPenalty
+	if maxLen == 0 {
+		return 0
+	}
 
-			// Calculate Above Average Penalty
-			weekAboveAvgPenalty := 0
-			if float64(conductorCountSoFar) > avgConductorCount {
-				weekAboveAvgPenalty = settings.AboveAverageConductorPenalty
-			}
-			currentAboveAvgPenalty += weekAboveAvgPenalty
-			cumulativeAboveAvgPenalty += weekAboveAvgPenalty
+	// Convert distance to similarity percentage
+	similarity := ((maxLen - distance) * 100) / maxLen
 
-			// Apply reset at end of week if conductor event occurred
-			if weekHasReset {
-				currentPoints = 0
-				currentAwards = 0
-				currentRecs = 0
-				currentRankBoost = 0
-				currentFirstTimeBoost = 0
-				currentRecentPenalty = 0
-				currentAboveAvgPenalty = 0
-			}
+	return similarity
+}
 
-			// Find max power value for this week
-			weekMaxPower := 0
-			for powerDate, power := range powerHistoryMap {
-				if powerDate >= weekStartStr && powerDate <= weekEndStr {
-					if power > weekMaxPower {
-						weekMaxPower = power
-					}
-				}
-			}
+// Detect selected day tab by color (the profile's selected tab color, white/light by default)
+func detectDayByColor(img image.Image, profile *LayoutProfile) string {
+	bounds := img.Bounds()
+	width := bounds.Dx()
 
-			pointsWithReset = append(pointsWithReset, currentPoints)
-			pointsCumulative = append(pointsCumulative, cumulativePoints)
-			awardsWithReset = append(awardsWithReset, currentAwards)
-			awardsCumulative = append(awardsCumulative, cumulativeAwards)
-			recsWithReset = append(recsWithReset, currentRecs)
-			recsCumulative = append(recsCumulative, cumulativeRecs)
-			rankBoostWithReset = append(rankBoostWithReset, currentRankBoost)
-			rankBoostCumulative = append(rankBoostCumulative, cumulativeRankBoost)
-			firstTimeBoostWithReset = append(firstTimeBoostWithReset, currentFirstTimeBoost)
-			firstTimeBoostCumulative = append(firstTimeBoostCumulative, cumulativeFirstTimeBoost)
-			recentPenaltyWithReset = append(recentPenaltyWithReset, currentRecentPenalty)
-			recentPenaltyCumulative = append(recentPenaltyCumulative, cumulativeRecentPenalty)
-			aboveAvgPenaltyWithReset = append(aboveAvgPenaltyWithReset, currentAboveAvgPenalty)
-			aboveAvgPenaltyCumulative = append(aboveAvgPenaltyCumulative, cumulativeAboveAvgPenalty)
-			powerValues = append(powerValues, weekMaxPower)
+	// Days are arranged horizontally as listed in the profile (Mon, Tues, Wed, Thur, Fri, Sat by default)
+	days := profile.TabDays
+	tabCount := len(days)
+	tabWidth := width / tabCount // Tabs share the width equally
+	selectedColor, err := parseHexColor(profile.SelectedTabColor)
+	if err != nil {
+		log.Printf("Layout profile %s: %v", profile.Name, err)
+		return ""
+	}
 
-			currentDate = currentDate.AddDate(0, 0, 7)
+	// Count pixels with the selected tab color in each tab region
+	// Selected tab has a light background, unselected tabs are gray
+	lightCounts := make([]int, tabCount)
+
+	for dayIdx := 0; dayIdx < tabCount; dayIdx++ {
+		// Define the region for this day tab
+		startX := bounds.Min.X + dayIdx*tabWidth
+		endX := startX + tabWidth
+		if dayIdx == tabCount-1 {
+			endX = bounds.Max.X // Last tab goes to the end
 		}
 
-		// Format conductor dates for display (convert to week labels)
-		conductorWeekLabels := []string{}
-		for _, condDate := range conductorDates {
-			if parsedDate, err := time.Parse("2006-01-02", condDate); err == nil {
-				monday := getMondayOfWeek(parsedDate)
-				weekEnd := monday.AddDate(0, 0, 6)
-				weekLabel := fmt.Sprintf("%s - %s",
-					monday.Format("Jan 2"),
-					weekEnd.Format("Jan 2"))
-				conductorWeekLabels = append(conductorWeekLabels, weekLabel)
+		// Sample the center 70% of each tab to avoid edge overlap issues
+		// This prevents bleeding from adjacent tabs
+		tabCenter := startX + tabWidth/2
+		sampleWidth := int(float64(tabWidth) * 0.70)
+		sampleStartX := tabCenter - sampleWidth/2
+		sampleEndX := tabCenter + sampleWidth/2
+
+		// Ensure we stay within bounds
+		if sampleStartX < startX {
+			sampleStartX = startX
+		}
+		if sampleEndX > endX {
+			sampleEndX = endX
+		}
+
+		// Count selected-color pixels in this region
+		// With the default profile the selected tab has a white/cream background (RGB > 200)
+		// and unselected tabs are gray/dark (RGB < 180)
+		lightCount := 0
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			for x := sampleStartX; x < sampleEndX; x++ {
+				if profile.matchesSelectedTab(img.At(x, y), selectedColor) {
+					lightCount++
+				}
 			}
 		}
+		lightCounts[dayIdx] = lightCount
+	}
+
+	// Find the day with the most light pixels
+	maxLight := 0
+	selectedDay := -1
+	for i, count := range lightCounts {
+		if count > maxLight {
+			maxLight = count
+			selectedDay = i
+		}
+	}
 
-		timelines[member.ID] = map[string]interface{}{
-			"dates":                        weekLabels,
-			"points_with_reset":            pointsWithReset,
-			"points_cumulative":            pointsCumulative,
-			"awards_with_reset":            awardsWithReset,
-			"awards_cumulative":            awardsCumulative,
-			"recommendations_with_reset":   recsWithReset,
-			"recommendations_cumulative":   recsCumulative,
-			"rank_boost_with_reset":        rankBoostWithReset,
-			"rank_boost_cumulative":        rankBoostCumulative,
-			"first_time_boost_with_reset":  firstTimeBoostWithReset,
-			"first_time_boost_cumulative":  firstTimeBoostCumulative,
-			"recent_penalty_with_reset":    recentPenaltyWithReset,
-			"recent_penalty_cumulative":    recentPenaltyCumulative,
-			"above_avg_penalty_with_reset": aboveAvgPenaltyWithReset,
-			"above_avg_penalty_cumulative": aboveAvgPenaltyCumulative,
-			"conductor_dates":              conductorWeekLabels,
-			"power":                        powerValues,
-		}
+	// Require a minimum threshold to avoid false positives
+	minThreshold := 100 // At least 100 light pixels
+	if selectedDay >= 0 && maxLight > minThreshold {
+		log.Printf("Day detected by color: %s (light pixel count: %d)", days[selectedDay], maxLight)
+		log.Printf("Color counts per day (%s): %v", strings.Join(days, ", "), lightCounts)
+		return days[selectedDay]
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(timelines)
+	log.Printf("Color detection failed: max light count %d below threshold %d", maxLight, minThreshold)
+	log.Printf("Color counts per day (%s): %v", strings.Join(days, ", "), lightCounts)
+	return ""
 }
 
-// Generate weekly schedule message
-func generateWeeklyMessage(w http.ResponseWriter, r *http.Request) {
-	startDate := r.URL.Query().Get("start")
-	if startDate == "" {
-		http.Error(w, "start date is required", http.StatusBadRequest)
-		return
+// rankingTabColor is the mean color of one tab of a strength ranking screenshot
+func rankingTabColor(img image.Image, region image.Rectangle) [3]float64 {
+	var sum [3]float64
+	count := 0
+	for y := region.Min.Y; y < region.Max.Y; y += 2 {
+		for x := region.Min.X; x < region.Max.X; x += 2 {
+			r, g, b, _ := img.At(x, y).RGBA()
+			sum[0] += float64(r >> 8)
+			sum[1] += float64(g >> 8)
+			sum[2] += float64(b >> 8)
+			count++
+		}
+	}
+	if count > 0 {
+		for i := range sum {
+			sum[i] /= float64(count)
+		}
 	}
+	return sum
+}
 
-	// Parse start date
-	weekStart, err := parseDate(startDate)
+// detectRankingMetric finds the selected tab of a strength ranking screenshot (Power, Kills or
+// Donation). The tab strip of the layout profile is split into one tab per metric and the tab whose
+// color stands out from the others is the selected one; if none does, the column header is read
+// with OCR. It returns "" when the tab can't be told.
+func detectRankingMetric(imageData []byte) string {
+	img, _, err := image.Decode(bytes.NewReader(imageData))
 	if err != nil {
-		http.Error(w, "Invalid date format", http.StatusBadRequest)
-		return
+		log.Printf("Failed to decode image for ranking tab detection: %v", err)
+		return ""
+	}
+	bounds := img.Bounds()
+	attrs := analyzeScreenshot(img, selectLayoutProfile(img, "power"))
+
+	tabs := attrs.TabsRegion
+	tabWidth := (tabs.Right - tabs.Left) / len(rankingMetrics)
+	if tabWidth > 0 && tabs.Bottom > tabs.Top {
+		colors := make([][3]float64, len(rankingMetrics))
+		for i := range rankingMetrics {
+			colors[i] = rankingTabColor(img, image.Rect(
+				bounds.Min.X+tabs.Left+i*tabWidth, bounds.Min.Y+tabs.Top,
+				bounds.Min.X+tabs.Left+(i+1)*tabWidth, bounds.Min.Y+tabs.Bottom))
+		}
+
+		// Distance of each tab from the average of the others
+		distances := make([]float64, len(colors))
+		for i := range colors {
+			var others [3]float64
+			for j := range colors {
+				if j != i {
+					for c := range others {
+						others[c] += colors[j][c] / float64(len(colors)-1)
+					}
+				}
+			}
+			distances[i] = math.Sqrt(math.Pow(colors[i][0]-others[0], 2) +
+				math.Pow(colors[i][1]-others[1], 2) + math.Pow(colors[i][2]-others[2], 2))
+		}
+		best, second := 0, 1
+		if distances[second] > distances[best] {
+			best, second = second, best
+		}
+		for i := 2; i < len(distances); i++ {
+			if distances[i] > distances[best] {
+				best, second = i, best
+			} else if distances[i] > distances[second] {
+				second = i
+			}
+		}
+		if distances[best] >= 30 && distances[best] >= 1.5*distances[second] {
+			log.Printf("Detected ranking tab by color: %s (distance %.0f vs %.0f)",
+				rankingMetrics[best].Name, distances[best], distances[second])
+			return rankingMetrics[best].Name
+		}
 	}
 
-	// Get settings
-	settings, err := loadSettings()
+	// Fallback: the last column header names the metric ("Ranking Commander Kills")
+	header := attrs.HeaderRegion
+	if header.Bottom <= header.Top || ocrEngine.Available() != nil {
+		return ""
+	}
+	headerImg := image.NewRGBA(image.Rect(0, 0, header.Right-header.Left, header.Bottom-header.Top))
+	draw.Draw(headerImg, headerImg.Bounds(), img, image.Point{bounds.Min.X + header.Left, bounds.Min.Y + header.Top}, draw.Src)
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, convertToGrayscale(scaleImage(headerImg, 2))); err != nil {
+		return ""
+	}
+	result, err := ocrEngine.Recognize(buf.Bytes(), OCRPageSingleLine, ocrLanguages())
 	if err != nil {
-		http.Error(w, "Failed to load settings: "+err.Error(), http.StatusInternalServerError)
-		return
+		log.Printf("Ranking header OCR failed: %v", err)
+		return ""
 	}
+	metric := detectRankingMetricFromText("commander " + result.Text)
+	log.Printf("Ranking header OCR text: %q -> %q", strings.TrimSpace(result.Text), metric)
+	return metric
+}
 
-	// Get schedules for the week
-	weekEnd := weekStart.AddDate(0, 0, 6)
-	rows, err := db.Query(`
-		SELECT 
-			ts.date, m1.name as conductor_name, m2.name as backup_name
-		FROM train_schedules ts
-		JOIN members m1 ON ts.conductor_id = m1.id
-		JOIN members m2 ON ts.backup_id = m2.id
-		WHERE ts.date >= ? AND ts.date <= ?
-		ORDER BY ts.date
-	`, formatDateString(weekStart), formatDateString(weekEnd))
+// Extract just the day tab region and detect selected day by color
+func detectDayFromTabRegion(imageData []byte) string {
+	// Decode the image
+	img, format, err := image.Decode(bytes.NewReader(imageData))
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+		log.Printf("Failed to decode image for tab detection: %v", err)
+		return ""
 	}
-	defer rows.Close()
+	log.Printf("Image format for tab detection: %s, bounds: %v", format, img.Bounds())
 
-	var schedulesText strings.Builder
-	for rows.Next() {
-		var date, conductor, backup string
-		if err := rows.Scan(&date, &conductor, &backup); err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
+	bounds := img.Bounds()
+	width := bounds.Dx()
+	height := bounds.Dy()
 
-		// Parse date to get day name
-		dateObj, _ := parseDate(date)
-		dayName := dateObj.Format("Monday")
+	// The tab region comes from the layout profile (~8% to ~10.5% from the top on the default phone layout)
+	profile := selectLayoutProfile(img, "vs_points")
+	tabTop := int(float64(height) * profile.TabsTop)
+	tabBottom := int(float64(height) * profile.TabsBottom)
+	tabLeft := int(float64(width) * profile.TabsLeft)
+	tabRight := int(float64(width) * profile.TabsRight)
 
-		schedulesText.WriteString(dayName + ": " + conductor + " (Backup: " + backup + ")\n")
+	// Ensure we don't go out of bounds
+	if tabTop < 0 {
+		tabTop = 0
+	}
+	if tabBottom > height {
+		tabBottom = height
+	}
+	if tabBottom <= tabTop {
+		tabBottom = tabTop + 100 // minimum 100px height
 	}
 
-	// Build ranking context to get next 3 candidates
-	ctx, err := buildRankingContext(weekStart)
-	if err != nil {
-		http.Error(w, "Failed to load ranking context: "+err.Error(), http.StatusInternalServerError)
-		return
+	log.Printf("Extracting tab region: x=%d to x=%d, y=%d to y=%d (full image: %dx%d)", tabLeft, tabRight, tabTop, tabBottom, width, height)
+
+	// Create a new image with just the tab region
+	tabRegion := image.NewRGBA(image.Rect(0, 0, tabRight-tabLeft, tabBottom-tabTop))
+	draw.Draw(tabRegion, tabRegion.Bounds(), img, image.Point{bounds.Min.X + tabLeft, bounds.Min.Y + tabTop}, draw.Src)
+
+	// First try: Detect by color (most reliable for this UI)
+	dayByColor := detectDayByColor(tabRegion, profile)
+	if dayByColor != "" {
+		return dayByColor
 	}
 
-	// Get all eligible members and score them
-	memberRows, err := db.Query("SELECT id, name, rank FROM members WHERE eligible = 1 ORDER BY name")
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+	log.Printf("Color detection failed, falling back to OCR")
+
+	// Fallback: Try OCR detection
+
+	// Simple preprocessing for tab region: scale 2x and convert to grayscale
+	// Don't use preprocessImageForOCR() as it tries to detect data regions (fails on small images)
+	scaledTab := scaleImage(tabRegion, 2)
+	grayTab := convertToGrayscale(scaledTab)
+
+	// Convert to PNG bytes
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, grayTab); err != nil {
+		log.Printf("Failed to encode tab region: %v", err)
+		return ""
 	}
-	defer memberRows.Close()
 
-	type ScoredMember struct {
-		Name  string
-		Score int
+	log.Printf("Tab region preprocessed: %dx%d -> %dx%d (scaled 2x, grayscale)",
+		tabRegion.Bounds().Dx(), tabRegion.Bounds().Dy(),
+		grayTab.Bounds().Dx(), grayTab.Bounds().Dy())
+
+	// Run OCR on the tab region
+	result, err := ocrEngine.Recognize(buf.Bytes(), OCRPageSingleLine, ocrLanguages())
+	text := result.Text
+	if err != nil || len(strings.TrimSpace(text)) == 0 {
+		log.Printf("Tab region OCR failed or empty")
+		return ""
 	}
 
-	var scoredMembers []ScoredMember
-	for memberRows.Next() {
-		var m Member
-		if err := memberRows.Scan(&m.ID, &m.Name, &m.Rank); err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-		score := calculateMemberScore(m, ctx)
-		scoredMembers = append(scoredMembers, ScoredMember{
-			Name:  m.Name,
-			Score: score,
-		})
+	log.Printf("Tab region OCR text: %s", text)
+
+	// Look for day names in the tab text
+	textLower := strings.ToLower(text)
+	days := []struct {
+		name     string
+		patterns []string
+	}{
+		{"monday", []string{"monday", "mon.", "mon"}},
+		{"tuesday", []string{"tuesday", "tues.", "tues", "tue"}},
+		{"wednesday", []string{"wednesday", "wed.", "wed"}},
+		{"thursday", []string{"thursday", "thur.", "thur", "thu"}},
+		{"friday", []string{"friday", "fri.", "fri"}},
+		{"saturday", []string{"saturday", "sat.", "sat"}},
 	}
 
-	// Sort by score (highest first)
-	for i := 0; i < len(scoredMembers); i++ {
-		for j := i + 1; j < len(scoredMembers); j++ {
-			if scoredMembers[j].Score > scoredMembers[i].Score {
-				scoredMembers[i], scoredMembers[j] = scoredMembers[j], scoredMembers[i]
+	// Find which day patterns appear in the text
+	// The selected tab usually appears first or more prominently
+	for _, day := range days {
+		for _, pattern := range day.patterns {
+			if strings.Contains(textLower, pattern) {
+				// Check if this appears early in the text (likely the selected/highlighted tab)
+				idx := strings.Index(textLower, pattern)
+				if idx < 100 { // Within first 100 chars suggests it's prominent
+					log.Printf("Detected day '%s' from tab region (pattern: '%s' at position %d)", day.name, pattern, idx)
+					return day.name
+				}
 			}
 		}
 	}
 
-	// Get top 3
-	var next3Text strings.Builder
-	limit := 3
-	if len(scoredMembers) < 3 {
-		limit = len(scoredMembers)
-	}
-	for i := 0; i < limit; i++ {
-		next3Text.WriteString(scoredMembers[i].Name + "\n")
-	}
-
-	// Format the message using template
-	message := settings.ScheduleMessageTemplate
-	message = strings.ReplaceAll(message, "{WEEK}", weekStart.Format("Jan 2, 2006"))
-	message = strings.ReplaceAll(message, "{SCHEDULES}", schedulesText.String())
-	message = strings.ReplaceAll(message, "{NEXT_3}", next3Text.String())
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"message": message,
-	})
+	return ""
 }
 
-// Generate daily message with conductor and backup for a specific date
-func generateDailyMessage(w http.ResponseWriter, r *http.Request) {
-	dateParam := r.URL.Query().Get("date")
-	if dateParam == "" {
-		http.Error(w, "date is required", http.StatusBadRequest)
-		return
-	}
+// Extract VS points data from image and detect which day
+func extractVSPointsDataFromImage(imageData []byte) (day string, records []OCRRecord, error error) {
+	// First try to detect the day from the tab region specifically
+	detectedDay := detectDayFromTabRegion(imageData)
 
-	// Parse date
-	date, err := parseDate(dateParam)
-	if err != nil {
-		http.Error(w, "Invalid date format", http.StatusBadRequest)
-		return
+	// If day detection failed, try text-based detection
+	if detectedDay == "" {
+		log.Printf("Tab region day detection failed, will try OCR fallback")
 	}
 
-	// Get settings
-	settings, err := loadSettings()
+	// Decode image for row segmentation
+	img, _, err := image.Decode(bytes.NewReader(imageData))
 	if err != nil {
-		http.Error(w, "Failed to load settings: "+err.Error(), http.StatusInternalServerError)
-		return
+		return "", nil, fmt.Errorf("failed to decode image: %v", err)
 	}
 
-	// Get schedule for the specific date
-	var conductorName, conductorRank, backupName, backupRank string
-	err = db.QueryRow(`
-		SELECT 
-			m1.name as conductor_name, m1.rank as conductor_rank,
-			m2.name as backup_name, m2.rank as backup_rank
-		FROM train_schedules ts
-		JOIN members m1 ON ts.conductor_id = m1.id
-		JOIN members m2 ON ts.backup_id = m2.id
-		WHERE ts.date = ?
-	`, formatDateString(date)).Scan(&conductorName, &conductorRank, &backupName, &backupRank)
+	// Analyze screenshot to get regions
+	attrs := analyzeScreenshot(img, selectLayoutProfile(img, "vs_points"))
 
-	if err != nil {
-		if err.Error() == "sql: no rows in result set" {
-			http.Error(w, "No schedule found for this date", http.StatusNotFound)
-		} else {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+	// Try segmented OCR approach: extract and process individual rows
+	log.Printf("Attempting row-by-row segmented OCR...")
+	records, err = extractVSPointsByRows(img, attrs)
+
+	if err != nil || len(records) == 0 {
+		log.Printf("Segmented OCR failed (%v), falling back to full image OCR", err)
+		// Fallback to original full-image OCR approach
+		records, err = extractVSPointsFullImage(imageData, attrs)
+		if err != nil {
+			return detectedDay, nil, err
+		}
+	}
+
+	// If we didn't detect day from tab region, try text-based detection as fallback
+	if detectedDay == "" {
+		// Use the current game day as last resort
+		now := gameToday()
+		weekday := now.Weekday()
+		switch weekday {
+		case time.Monday:
+			detectedDay = "monday"
+		case time.Tuesday:
+			detectedDay = "tuesday"
+		case time.Wednesday:
+			detectedDay = "wednesday"
+		case time.Thursday:
+			detectedDay = "thursday"
+		case time.Friday:
+			detectedDay = "friday"
+		case time.Saturday:
+			detectedDay = "saturday"
+		default:
+			// Sunday - default to Monday
+			detectedDay = "monday"
 		}
-		return
+		log.Printf("Warning: Could not detect day from screenshot, using current system day: %s", detectedDay)
 	}
 
-	// Format the message using template
-	message := settings.DailyMessageTemplate
-	message = strings.ReplaceAll(message, "{DATE}", date.Format("Monday, Jan 2, 2006"))
-	message = strings.ReplaceAll(message, "{CONDUCTOR_NAME}", conductorName)
-	message = strings.ReplaceAll(message, "{CONDUCTOR_RANK}", conductorRank)
-	message = strings.ReplaceAll(message, "{BACKUP_NAME}", backupName)
-	message = strings.ReplaceAll(message, "{BACKUP_RANK}", backupRank)
+	if len(records) == 0 {
+		return "", nil, fmt.Errorf("no valid VS point records found in extracted text")
+	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"message": message,
-	})
+	return detectedDay, records, nil
 }
 
-// Generate individual conductor reminder messages for the week
-func generateConductorMessages(w http.ResponseWriter, r *http.Request) {
-	startDate := r.URL.Query().Get("start")
-	if startDate == "" {
-		http.Error(w, "start date is required", http.StatusBadRequest)
-		return
-	}
-
-	// Parse start date
-	weekStart, err := parseDate(startDate)
-	if err != nil {
-		http.Error(w, "Invalid date format", http.StatusBadRequest)
-		return
-	}
+// Extract VS points by segmenting image into rows and OCR each row independently
+func extractVSPointsByRows(img image.Image, attrs *ScreenshotAttributes) ([]OCRRecord, error) {
+	bounds := img.Bounds()
+	dataRegion := attrs.DataRegion
+	rowHeight := attrs.RowHeight
+	estimatedRows := attrs.EstimatedRows
 
-	// Get schedules for the week
-	weekEnd := weekStart.AddDate(0, 0, 6)
-	rows, err := db.Query(`
-		SELECT 
-			ts.date, m1.name as conductor_name
-		FROM train_schedules ts
-		JOIN members m1 ON ts.conductor_id = m1.id
-		WHERE ts.date >= ? AND ts.date <= ?
-		ORDER BY ts.date
-	`, formatDateString(weekStart), formatDateString(weekEnd))
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+	if estimatedRows < 1 {
+		estimatedRows = 10
 	}
-	defer rows.Close()
 
-	// Message variations for natural variety
-	messageTemplates := []string{
-		"Hi {NAME}! Just a reminder that you're the train conductor on {DAY}, {DATE}. Please be online around 15:00 ST / 17:00 UK / 18:00 CET and ask in alliance chat for the train to be assigned to you. If anything comes up, let us know early so we can coordinate with the backup. Please add a reminder in your phone so you don't forget. Thanks for helping keep the train golden!",
-		"Hi {NAME}! You're scheduled as train conductor on {DAY}, {DATE}. Please be online at 15:00 ST / 17:00 UK / 18:00 CET and request the train in alliance chat. If your schedule changes, let us know in advance so we can coordinate with the backup. Add a reminder in your phone to make sure you're on time. Appreciate your support!",
-		"Hi {NAME}! Just a heads-up that you're the train conductor on {DAY}, {DATE}. Please be online around 15:00 ST / 17:00 UK / 18:00 CET and ask for the train in alliance chat. If you need help or need to swap, reach out early. Set a phone reminder so you don't miss it. Thanks a lot!",
-		"Hi {NAME}! You're assigned as train conductor on {DAY}, {DATE}. Please be online at 15:00 ST / 17:00 UK / 18:00 CET and request the train in alliance chat. If there are any timing issues, let us know so we can plan with the backup. Don't forget to add a reminder in your phone. Thanks for stepping up!",
-		"Hi {NAME}! Reminder that you're the train conductor on {DAY}, {DATE}. Please be online around 15:00 ST / 17:00 UK / 18:00 CET and ask in alliance chat for the train assignment. Let us know early if anything changes. Make sure to set a phone reminder. Much appreciated!",
-		"Hi {NAME}! You're scheduled as train conductor on {DAY}, {DATE}. Please be online at 15:00 ST / 17:00 UK / 18:00 CET and request the train in alliance chat. If you need assistance or a timing adjustment, just let us know. Add a phone reminder to help you remember. Thanks!",
-		"Hi {NAME}! Just a reminder that you're the train conductor on {DAY}, {DATE}. Please be online around 15:00 ST / 17:00 UK / 18:00 CET and ask in alliance chat for the train to be assigned. If anything comes up, please reach out early. Set a reminder in your phone so you're prepared. Thanks for helping the alliance!",
-	}
+	records := []OCRRecord{}
+	languages := ocrLanguages()
 
-	type DayMessage struct {
-		Day     string `json:"day"`
-		Name    string `json:"name"`
-		Message string `json:"message"`
-	}
+	log.Printf("Processing %d estimated rows with height %d", estimatedRows, rowHeight)
 
-	var messages []DayMessage
-	templateIndex := 0
+	// Extract and OCR each row
+	for i := 0; i < estimatedRows; i++ {
+		rowTop := dataRegion.Top + (i * rowHeight)
+		rowBottom := rowTop + rowHeight
 
-	for rows.Next() {
-		var date, conductor string
-		if err := rows.Scan(&date, &conductor); err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
+		// Ensure we don't go out of bounds
+		if rowBottom > dataRegion.Bottom {
+			rowBottom = dataRegion.Bottom
+		}
+		if rowTop >= rowBottom {
+			break
 		}
 
-		// Parse date to get day name and formatted date
-		dateObj, _ := parseDate(date)
-		dayName := dateObj.Format("Monday")
-		dateFormatted := dateObj.Format("2 Jan") // e.g., "3 Jan" - unambiguous date format
+		// Extract this row as a separate image
+		rowImg := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), rowBottom-rowTop))
+		draw.Draw(rowImg, rowImg.Bounds(), img, image.Point{0, rowTop}, draw.Src)
 
-		// Get template and cycle through them
-		template := messageTemplates[templateIndex]
-		templateIndex = (templateIndex + 1) % len(messageTemplates)
+		// Process this row
+		// Split row into segments at the profile's column positions: rank (15%), name (50%), points (35%) by default
+		nameStart := int(float64(bounds.Dx()) * attrs.Profile.RankColumnEnd)
+		pointsStart := int(float64(bounds.Dx()) * attrs.Profile.NameColumnEnd)
+		nameWidth := pointsStart - nameStart
 
-		// Replace placeholders
-		message := strings.ReplaceAll(template, "{NAME}", conductor)
-		message = strings.ReplaceAll(message, "{DAY}", dayName)
-		message = strings.ReplaceAll(message, "{DATE}", dateFormatted)
+		// Extract name segment
+		nameImg := image.NewRGBA(image.Rect(0, 0, nameWidth, rowBottom-rowTop))
+		draw.Draw(nameImg, nameImg.Bounds(), rowImg, image.Point{nameStart, 0}, draw.Src)
 
-		messages = append(messages, DayMessage{
-			Day:     dayName,
-			Name:    conductor,
-			Message: message,
-		})
-	}
+		// Extract points segment
+		pointsWidth := bounds.Dx() - pointsStart
+		pointsImg := image.NewRGBA(image.Rect(0, 0, pointsWidth, rowBottom-rowTop))
+		draw.Draw(pointsImg, pointsImg.Bounds(), rowImg, image.Point{pointsStart, 0}, draw.Src)
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"messages": messages,
-	})
-}
+		// Scale 2x and convert to grayscale for better OCR
+		scaledName := scaleImage(nameImg, 2)
+		grayName := convertToGrayscale(scaledName)
 
-// R4/R5/Admin middleware - checks if user has R4, R5 rank or is admin
-func r4r5Middleware(next http.HandlerFunc) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		session, _ := store.Get(r, "session-name")
-		memberID, ok := session.Values["member_id"].(int)
-		if !ok {
-			http.Error(w, "Not authenticated", http.StatusUnauthorized)
-			return
-		}
+		scaledPoints := scaleImage(pointsImg, 2)
+		grayPoints := convertToGrayscale(scaledPoints)
 
-		// Check if user is admin
-		var isAdmin bool
-		err := db.QueryRow("SELECT is_admin FROM users WHERE member_id = ?", memberID).Scan(&isAdmin)
-		if err == nil && isAdmin {
-			next(w, r)
-			return
+		// OCR the name segment
+		var nameBuf bytes.Buffer
+		if err := png.Encode(&nameBuf, grayName); err != nil {
+			log.Printf("Row %d: Failed to encode name segment: %v", i+1, err)
+			continue
 		}
 
-		// Get member rank
-		var rank string
-		err = db.QueryRow("SELECT rank FROM members WHERE id = ?", memberID).Scan(&rank)
-		if err != nil {
-			http.Error(w, "Member not found", http.StatusNotFound)
-			return
+		nameResult, err := ocrEngine.Recognize(nameBuf.Bytes(), OCRPageSingleLine, languages)
+		nameText := nameResult.Text
+		if err != nil || len(strings.TrimSpace(nameText)) == 0 {
+			continue // Skip empty rows
 		}
+		nameConfidence, nameBox := summarizeOCRWords(nameResult.Words, ocrTransform{
+			Offset: image.Point{X: bounds.Min.X + nameStart, Y: rowTop},
+			Scale:  2,
+		})
 
-		if rank != "R4" && rank != "R5" {
-			http.Error(w, "Access denied - R4, R5 rank or admin privileges required", http.StatusForbidden)
-			return
+		// OCR the points segment
+		var pointsBuf bytes.Buffer
+		if err := png.Encode(&pointsBuf, grayPoints); err != nil {
+			log.Printf("Row %d: Failed to encode points segment: %v", i+1, err)
+			continue
 		}
 
-		next(w, r)
-	}
-}
+		pointsResult, err := ocrEngine.Recognize(pointsBuf.Bytes(), OCRPageSingleLine, languages)
+		pointsText := pointsResult.Text
+		if err != nil || len(strings.TrimSpace(pointsText)) == 0 {
+			log.Printf("Row %d: Name='%s', but no points found", i+1, strings.TrimSpace(nameText))
+			continue
+		}
+		pointsConfidence, pointsBox := summarizeOCRWords(pointsResult.Words, ocrTransform{
+			Offset: image.Point{X: bounds.Min.X + pointsStart, Y: rowTop},
+			Scale:  2,
+		})
 
-// Get storm assignments
-func getStormAssignments(w http.ResponseWriter, r *http.Request) {
-	taskForce := r.URL.Query().Get("task_force")
-	if taskForce == "" {
-		taskForce = "A"
-	}
+		// Parse the extracted text
+		name := strings.TrimSpace(nameText)
+		// Clean up name (remove alliance tags, rank numbers, etc.)
+		name = cleanPlayerName(name)
 
-	rows, err := db.Query(`
-		SELECT id, task_force, building_id, member_id, position
-		FROM storm_assignments
-		WHERE task_force = ?
-		ORDER BY building_id, position
-	`, taskForce)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-	defer rows.Close()
+		// Parse points
+		pointsStr := strings.TrimSpace(pointsText)
+		pointsStr = strings.ReplaceAll(pointsStr, ",", "")
+		pointsStr = strings.ReplaceAll(pointsStr, ".", "")
+		pointsStr = strings.ReplaceAll(pointsStr, " ", "")
 
-	assignments := []StormAssignment{}
-	for rows.Next() {
-		var a StormAssignment
-		if err := rows.Scan(&a.ID, &a.TaskForce, &a.BuildingID, &a.MemberID, &a.Position); err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
+		points, err := strconv.ParseInt(pointsStr, 10, 64)
+		if err != nil {
+			log.Printf("Row %d: Failed to parse points '%s': %v", i+1, pointsStr, err)
+			continue
 		}
-		assignments = append(assignments, a)
-	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(assignments)
-}
+		if points < 100 { // Sanity check
+			continue
+		}
 
-// Save storm assignments
-func saveStormAssignments(w http.ResponseWriter, r *http.Request) {
-	var request struct {
-		TaskForce   string `json:"task_force"`
-		Assignments []struct {
-			BuildingID string `json:"building_id"`
-			MemberID   int    `json:"member_id"`
-			Position   int    `json:"position"`
-		} `json:"assignments"`
-	}
+		log.Printf("Row %d: Name='%s', Points=%d", i+1, name, points)
 
-	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
+		// A row is only as reliable as its weakest field
+		records = append(records, OCRRecord{
+			MemberName:      name,
+			Value:           points,
+			RawText:         strings.TrimSpace(nameText) + " " + strings.TrimSpace(pointsText),
+			Confidence:      math.Min(nameConfidence, pointsConfidence),
+			NameText:        strings.TrimSpace(nameText),
+			NameConfidence:  nameConfidence,
+			NameBox:         nameBox,
+			ValueText:       strings.TrimSpace(pointsText),
+			ValueConfidence: pointsConfidence,
+			ValueBox:        pointsBox,
+		})
 	}
 
-	// Validate task force
-	if request.TaskForce != "A" && request.TaskForce != "B" {
-		http.Error(w, "Invalid task force - must be A or B", http.StatusBadRequest)
-		return
-	}
+	return records, nil
+}
 
-	// Start transaction
-	tx, err := db.Begin()
+// Fallback: Extract VS points from full image (original method)
+func extractVSPointsFullImage(imageData []byte, attrs *ScreenshotAttributes) ([]OCRRecord, error) {
+	// Preprocess image to filter and enhance relevant regions
+	processedData, transform, err := preprocessImageForOCR(imageData, "vs_points")
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+		log.Printf("Warning: Image preprocessing failed: %v. Using original image.", err)
+		processedData = imageData // Fallback to original
+		transform = ocrTransform{Scale: 1}
 	}
-	defer tx.Rollback()
 
-	// Delete existing assignments for this task force
-	_, err = tx.Exec("DELETE FROM storm_assignments WHERE task_force = ?", request.TaskForce)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+	// Try different PSM modes for better recognition
+	var text string
+	var words []OCRWord
+	languages := ocrLanguages()
+	psmModes := []OCRPageSegMode{
+		OCRPageAuto,
+		OCRPageSingleBlock,
+		OCRPageSparseText,
 	}
 
-	// Insert new assignments
-	for _, assignment := range request.Assignments {
-		_, err = tx.Exec(`
-			INSERT INTO storm_assignments (task_force, building_id, member_id, position)
-			VALUES (?, ?, ?, ?)
-		`, request.TaskForce, assignment.BuildingID, assignment.MemberID, assignment.Position)
+	for i, mode := range psmModes {
+		result, err := ocrEngine.Recognize(processedData, mode, languages)
+		if err == nil && len(strings.TrimSpace(result.Text)) > 0 {
+			text = result.Text
+			words = result.Words
+			log.Printf("OCR successful with PSM mode %d (attempt %d)", mode, i+1)
+			break
+		}
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
+			log.Printf("OCR attempt %d with PSM mode %d failed: %v", i+1, mode, err)
+		} else {
+			log.Printf("OCR attempt %d with PSM mode %d returned no text", i+1, mode)
 		}
 	}
 
-	if err := tx.Commit(); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+	if len(strings.TrimSpace(text)) == 0 {
+		return nil, fmt.Errorf("OCR failed: no text extracted after trying multiple modes")
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"message": "Assignments saved successfully",
-	})
+	// Log the extracted text for debugging
+	log.Printf("OCR extracted text:\n%s\n---END OCR---", text)
+
+	// Parse the OCR text for VS points
+	records := parseVSPointsText(text)
+	applyOCRWordFields(words, records, transform)
+
+	return records, nil
 }
 
-// Delete storm assignments for a task force
-func deleteStormAssignments(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	taskForce := vars["taskForce"]
+// Clean player name by removing alliance tags, special characters, etc
+func cleanPlayerName(name string) string {
+	// Full-width brackets and letters become plain ones; drop invisible control characters
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || r == utf8.RuneError {
+			return -1
+		}
+		return r
+	}, norm.NFKC.String(name))
+
+	// Remove common OCR artifacts
+	name = strings.ReplaceAll(name, "|", "I")
+	name = strings.ReplaceAll(name, "~", "")
+	name = strings.ReplaceAll(name, "`", "")
 
-	if taskForce != "A" && taskForce != "B" {
-		http.Error(w, "Invalid task force - must be A or B", http.StatusBadRequest)
-		return
-	}
+	// Remove alliance tags like [NTMs], (NTMs), etc.
+	re := regexp.MustCompile(`\[.*?\]|\(.*?\)`)
+	name = re.ReplaceAllString(name, "")
 
-	_, err := db.Exec("DELETE FROM storm_assignments WHERE task_force = ?", taskForce)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
+	// Remove rank numbers at the start (1), (2), etc.
+	re = regexp.MustCompile(`^\d+\)?\s*`)
+	name = re.ReplaceAllString(name, "")
 
-	w.WriteHeader(http.StatusNoContent)
+	// Clean up whitespace
+	name = strings.TrimSpace(name)
+
+	return name
 }
 
-// Confirm and update members in database
-func confirmMemberUpdates(w http.ResponseWriter, r *http.Request) {
-	var request ConfirmRequest
+// Detect which day is selected from OCR text
+// Looks for day names and context clues to determine the active tab
+func detectSelectedDay(text string) string {
+	textLower := strings.ToLower(text)
 
-	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
+	// Check for presence of day names and "Daily Rank" which indicates VS points screen
+	if !strings.Contains(textLower, "daily") && !strings.Contains(textLower, "rank") {
+		return "" // Not a daily rank screen
 	}
 
-	result := ConfirmResult{}
-
-	// Process renames first
-	for _, rename := range request.Renames {
-		_, err := db.Exec("UPDATE members SET name = ? WHERE name = ?", rename.NewName, rename.OldName)
-		if err != nil {
-			log.Printf("Error renaming member %s to %s: %v", rename.OldName, rename.NewName, err)
-			continue
-		}
-		log.Printf("Renamed member %s to %s", rename.OldName, rename.NewName)
+	// Count occurrences of each day name (the selected day often appears more prominently)
+	days := map[string][]string{
+		"monday":    {"monday", "mon.", "mon"},
+		"tuesday":   {"tuesday", "tues.", "tues"},
+		"wednesday": {"wednesday", "wed.", "wed"},
+		"thursday":  {"thursday", "thur.", "thur"},
+		"friday":    {"friday", "fri.", "fri"},
+		"saturday":  {"saturday", "sat.", "sat"},
 	}
 
-	// Create a set of member names from the request
-	memberNames := make(map[string]bool)
-	for _, member := range request.Members {
-		memberNames[member.Name] = true
-	}
+	dayScores := make(map[string]int)
 
-	for _, member := range request.Members {
-		// Check if member exists
-		var existingID int
-		var existingRank string
-		err := db.QueryRow("SELECT id, rank FROM members WHERE name = ?", member.Name).Scan(&existingID, &existingRank)
+	for standardDay, variants := range days {
+		for _, variant := range variants {
+			// Count occurrences
+			count := strings.Count(textLower, variant)
+			dayScores[standardDay] += count
 
-		if err == sql.ErrNoRows {
-			// Add new member
-			_, err = db.Exec("INSERT INTO members (name, rank) VALUES (?, ?)", member.Name, member.Rank)
-			if err != nil {
-				log.Printf("Error adding member %s: %v", member.Name, err)
-				continue
-			}
-			result.Added++
-		} else if err == nil {
-			// Update existing member if rank changed
-			if existingRank != member.Rank {
-				_, err = db.Exec("UPDATE members SET rank = ? WHERE id = ?", member.Rank, existingID)
-				if err != nil {
-					log.Printf("Error updating member %s: %v", member.Name, err)
-					continue
-				}
-				result.Updated++
-			} else {
-				result.Unchanged++
+			// Higher weight if it appears near the beginning (likely the tab)
+			if idx := strings.Index(textLower, variant); idx >= 0 && idx < 200 {
+				dayScores[standardDay] += 2
 			}
 		}
 	}
 
-	// Remove specific members by ID if requested
-	if len(request.RemoveMemberIDs) > 0 {
-		for _, id := range request.RemoveMemberIDs {
-			_, err := db.Exec("DELETE FROM members WHERE id = ?", id)
-			if err != nil {
-				log.Printf("Error removing member with id %d: %v", id, err)
-				continue
-			}
-			result.Removed++
+	// Find the day with the highest score
+	maxScore := 0
+	selectedDay := ""
+	for day, score := range dayScores {
+		if score > maxScore {
+			maxScore = score
+			selectedDay = day
 		}
-		log.Printf("Removed %d selected members", result.Removed)
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(result)
-}
-
-// Get power history for a specific member or all members
-func getPowerHistory(w http.ResponseWriter, r *http.Request) {
-	memberID := r.URL.Query().Get("member_id")
-	limit := r.URL.Query().Get("limit")
-
-	if limit == "" {
-		limit = "30" // Default to last 30 records
+	// Only return if we have strong confidence (score >= 3)
+	if maxScore >= 3 {
+		log.Printf("Detected day from full OCR text: %s (score: %d)", selectedDay, maxScore)
+		return selectedDay
 	}
 
-	var rows *sql.Rows
-	var err error
-
-	if memberID != "" {
-		rows, err = db.Query(`
-			SELECT ph.id, ph.member_id, ph.power, ph.recorded_at
-			FROM power_history ph
-			WHERE ph.member_id = ?
-			ORDER BY ph.recorded_at DESC
-			LIMIT ?
-		`, memberID, limit)
-	} else {
-		rows, err = db.Query(`
-			SELECT ph.id, ph.member_id, ph.power, ph.recorded_at
-			FROM power_history ph
-			ORDER BY ph.recorded_at DESC
-			LIMIT ?
-		`, limit)
+	// Low confidence or no detection
+	if maxScore > 0 {
+		log.Printf("Low confidence day detection from OCR: %s (score: %d)", selectedDay, maxScore)
 	}
+	return ""
+}
 
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-	defer rows.Close()
+// dayTabWordPattern matches a whole day name or tab abbreviation ("Mon", "Tues", "Saturday"), so names
+// that merely contain one ("Satchmo", "Simon") aren't mistaken for the day tabs
+var dayTabWordPattern = regexp.MustCompile(`\b(?:mon|tues?|wed(?:nes)?|thu(?:rs?)?|fri|sat(?:ur)?|sun)(?:day)?\b`)
 
-	history := []PowerHistory{}
-	for rows.Next() {
-		var ph PowerHistory
-		if err := rows.Scan(&ph.ID, &ph.MemberID, &ph.Power, &ph.RecordedAt); err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-		history = append(history, ph)
-	}
+// Parse VS points text(from OCR or manual input)
+func parseVSPointsText(text string) []OCRRecord {
+	var records []OCRRecord
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(history)
-}
+	lines := strings.Split(text, "\n")
 
-// Add power record manually
-func addPowerRecord(w http.ResponseWriter, r *http.Request) {
-	var request struct {
-		MemberID int   `json:"member_id"`
-		Power    int64 `json:"power"`
-	}
+	// Pattern for VS points - similar to power rankings but with different number ranges
+	// VS points are typically 6-9 digits (100k to 999M range)
+	// Examples: "Gary6126 30598466", "Gargoland 23660312"
+	rankPattern := regexp.MustCompile(`(?:R[0-9]\s+)?(` + playerNamePattern + `*?)\s+([0-9]{6,})`)
+	simplePattern := regexp.MustCompile(`(` + playerNameStart + `[` + playerNameChars + `]+)\s+([0-9]{6,})`)
 
-	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
-	}
+	// Track seen names to avoid duplicates
+	seenNames := make(map[string]bool)
 
-	// Check if member exists
-	var exists int
-	err := db.QueryRow("SELECT COUNT(*) FROM members WHERE id = ?", request.MemberID).Scan(&exists)
-	if err != nil || exists == 0 {
-		http.Error(w, "Member not found", http.StatusNotFound)
-		return
-	}
+	for _, rawLine := range lines {
+		rawLine = strings.TrimSpace(rawLine)
+		if rawLine == "" {
+			continue
+		}
+		line := norm.NFKC.String(rawLine)
 
-	result, err := db.Exec("INSERT INTO power_history (member_id, power) VALUES (?, ?)",
-		request.MemberID, request.Power)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
+		// Skip lines that are clearly UI elements
+		if len(line) < 5 {
+			continue
+		}
 
-	id, _ := result.LastInsertId()
+		// Skip common UI text
+		lowerLine := strings.ToLower(line)
+		if strings.Contains(lowerLine, "ranking") ||
+			strings.Contains(lowerLine, "commander") ||
+			strings.Contains(lowerLine, "points") ||
+			strings.Contains(lowerLine, "daily") ||
+			strings.Contains(lowerLine, "weekly") ||
+			dayTabWordPattern.MatchString(lowerLine) ||
+			strings.Contains(lowerLine, "alliance") ||
+			strings.Contains(lowerLine, "your alliance") {
+			continue
+		}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"message": "Power record added successfully",
-		"id":      id,
-	})
-}
+		// Try patterns
+		matches := rankPattern.FindStringSubmatch(line)
+		if len(matches) == 0 {
+			matches = simplePattern.FindStringSubmatch(line)
+		}
 
-// ImageRegion represents a detected region in the screenshot
-type ImageRegion struct {
-	Name   string
-	Top    int
-	Bottom int
-	Left   int
-	Right  int
-}
+		if len(matches) >= 3 {
+			name := strings.TrimSpace(matches[1])
+			pointsStr := strings.ReplaceAll(matches[2], ",", "")
+			pointsStr = strings.ReplaceAll(pointsStr, " ", "")
 
-// ScreenshotAttributes contains analyzed attributes from the screenshot
-type ScreenshotAttributes struct {
-	Width          int
-	Height         int
-	TitleBarRegion *ImageRegion
-	TabsRegion     *ImageRegion
-	HeaderRegion   *ImageRegion
-	DataRegion     *ImageRegion
-	ButtonRegion   *ImageRegion
-	RowHeight      int
-	EstimatedRows  int
+			points, err := strconv.ParseInt(pointsStr, 10, 64)
+
+			// Validate: points should be realistic (10k to 999M range), name should be reasonable
+			if err == nil && points >= 10000 && points <= 999999999 &&
+				validPlayerNameLength(name) && !seenNames[name] {
+				records = append(records, OCRRecord{
+					MemberName: name,
+					Value:      points,
+					RawText:    rawLine,
+				})
+				seenNames[name] = true
+				log.Printf("Parsed VS points: %s -> %d", name, points)
+			}
+		}
+	}
+
+	return records
 }
 
-// Analyze screenshot to detect distinct regions and attributes
-func analyzeScreenshot(img image.Image) *ScreenshotAttributes {
-	bounds := img.Bounds()
-	width := bounds.Dx()
-	height := bounds.Dy()
+// Minimum similarity for a parsed name to be proposed as a member match
+const (
+	vsMatchThreshold    = 70
+	powerMatchThreshold = 50
+)
 
-	attrs := &ScreenshotAttributes{
-		Width:  width,
-		Height: height,
-	}
+// Most screenshots accepted in one stitched upload (a full alliance list takes 8-10)
+const maxStitchedScreenshots = 25
 
-	// Detect dark title bar at top (typically 5-10% of height)
-	// Title bars are usually dark colored
-	titleBarHeight := height / 15 // ~6-7%
-	if titleBarHeight < 30 {
-		titleBarHeight = 30
-	}
-	attrs.TitleBarRegion = &ImageRegion{
-		Name:   "TitleBar",
-		Top:    0,
-		Bottom: titleBarHeight,
-		Left:   0,
-		Right:  width,
+// ocrMatchThreshold returns the minimum match score for a batch kind
+func ocrMatchThreshold(kind string) int {
+	if kind == "power" {
+		return powerMatchThreshold
 	}
+	return vsMatchThreshold
+}
 
-	// Detect tabs region (typically right below title bar, ~5-8% of height)
-	tabsHeight := height / 15
-	if tabsHeight < 40 {
-		tabsHeight = 40
-	}
-	attrs.TabsRegion = &ImageRegion{
-		Name:   "Tabs",
-		Top:    titleBarHeight,
-		Bottom: titleBarHeight + tabsHeight,
-		Left:   0,
-		Right:  width,
+// matchMemberName finds the member a parsed name most likely refers to.
+// Returns the member ID and a 0-100 score (100 for a case-insensitive exact match).
+func matchMemberName(name string, members []Member) (int, int) {
+	for _, m := range members {
+		if strings.EqualFold(m.Name, name) {
+			return m.ID, 100
+		}
 	}
 
-	// Detect column headers (below tabs, ~5% of height)
-	headerHeight := height / 20
-	if headerHeight < 30 {
-		headerHeight = 30
+	bestID := 0
+	bestScore := 0
+	for _, m := range members {
+		score := calculateSimilarity(name, m.Name)
+		if score > bestScore {
+			bestScore = score
+			bestID = m.ID
+		}
 	}
-	haederTop := titleBarHeight + tabsHeight
-	attrs.HeaderRegion = &ImageRegion{
-		Name:   "Headers",
-		Top:    haederTop,
-		Bottom: haederTop + headerHeight,
-		Left:   0,
-		Right:  width,
+	return bestID, bestScore
+}
+
+// uploadWeekDate returns the Monday of the current or last week for an upload
+func uploadWeekDate(weekParam string) string {
+	now := gameToday()
+	if weekParam == "last" {
+		// Subtract 7 days to get last week
+		now = now.AddDate(0, 0, -7)
 	}
+	return getMondayOfWeek(now).Format("2006-01-02")
+}
 
-	// Detect bottom button region (typically last 8-10% of height)
-	buttonHeight := height / 10
-	if buttonHeight < 50 {
-		buttonHeight = 50
+// insertOCRBatchRows stores parsed records for a batch with their best member match
+func insertOCRBatchRows(tx *sql.Tx, batchID int64, kind string, records []OCRRecord) error {
+	rows, err := tx.Query("SELECT id, name FROM members")
+	if err != nil {
+		return err
 	}
-	attrs.ButtonRegion = &ImageRegion{
-		Name:   "BottomButton",
-		Top:    height - buttonHeight,
-		Bottom: height,
-		Left:   0,
-		Right:  width,
+	members := []Member{}
+	for rows.Next() {
+		var m Member
+		if err := rows.Scan(&m.ID, &m.Name); err != nil {
+			rows.Close()
+			return err
+		}
+		members = append(members, m)
 	}
+	rows.Close()
 
-	// Data region is everything between headers and bottom button
-	dataTop := haederTop + headerHeight
-	dataBottom := height - buttonHeight
-	attrs.DataRegion = &ImageRegion{
-		Name:   "DataRows",
-		Top:    dataTop,
-		Bottom: dataBottom,
-		Left:   0,
-		Right:  width,
+	var minConfidence int
+	if err := tx.QueryRow("SELECT COALESCE(ocr_min_confidence, 0) FROM settings WHERE id = 1").Scan(&minConfidence); err != nil {
+		return err
 	}
 
-	// Estimate row height and count
-	dataHeight := dataBottom - dataTop
-	attrs.RowHeight = dataHeight / 10 // Assume ~10 visible rows
-	if attrs.RowHeight < 40 {
-		attrs.RowHeight = 40
+	threshold := ocrMatchThreshold(kind)
+	for i, record := range records {
+		bestID, score := matchMemberName(record.MemberName, members)
+
+		// Weak matches are still scored, but left unassigned for the reviewer
+		var memberID interface{}
+		if bestID > 0 && score >= threshold {
+			memberID = bestID
+		}
+
+		// Rows below the minimum confidence are rejected (skipped) unless a reviewer re-includes them;
+		// rows whose confidence wasn't measured are left to the reviewer
+		lowConfidence := minConfidence > 0 && record.Confidence != ocrConfidenceUnknown && record.Confidence < float64(minConfidence)
+
+		args := []interface{}{batchID, i, record.RawText, record.MemberName, record.Value, record.Confidence,
+			record.NameText, record.NameConfidence}
+		args = append(args, ocrBoxColumns(record.NameBox)...)
+		args = append(args, record.ValueText, record.ValueConfidence)
+		args = append(args, ocrBoxColumns(record.ValueBox)...)
+		var rank interface{}
+		if record.Rank > 0 {
+			rank = record.Rank
+		}
+		args = append(args, lowConfidence, lowConfidence, rank, record.Page, memberID, score)
+
+		_, err := tx.Exec(`INSERT INTO ocr_batch_rows (batch_id, row_index, raw_text, parsed_name, value, confidence,
+				name_text, name_confidence, name_x, name_y, name_width, name_height,
+				value_text, value_confidence, value_x, value_y, value_width, value_height,
+				low_confidence, skip, rank, page_index, member_id, match_score)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`, args...)
+		if err != nil {
+			return err
+		}
 	}
-	attrs.EstimatedRows = dataHeight / attrs.RowHeight
+	return nil
+}
 
-	log.Printf("Screenshot Analysis: %dx%d, DataRegion: (%d,%d) to (%d,%d), Est. Rows: %d",
-		width, height, attrs.DataRegion.Left, attrs.DataRegion.Top,
-		attrs.DataRegion.Right, attrs.DataRegion.Bottom, attrs.EstimatedRows)
+// ocrBoxColumns returns the x, y, width and height column values for a box (NULL when there is none)
+func ocrBoxColumns(box *OCRBox) []interface{} {
+	if box == nil {
+		return []interface{}{nil, nil, nil, nil}
+	}
+	return []interface{}{box.X, box.Y, box.Width, box.Height}
+}
 
-	return attrs
+// ocrBoxFromColumns rebuilds a box from its nullable x, y, width and height columns
+func ocrBoxFromColumns(x, y, width, height sql.NullInt64) *OCRBox {
+	if !x.Valid || !y.Valid || !width.Valid || !height.Valid {
+		return nil
+	}
+	return &OCRBox{X: int(x.Int64), Y: int(y.Int64), Width: int(width.Int64), Height: int(height.Int64)}
 }
 
-// Crop image to data region only
-func cropToDataRegion(img image.Image, region *ImageRegion) image.Image {
-	bounds := img.Bounds()
-	top := region.Top
-	bottom := region.Bottom
-	left := region.Left
-	right := region.Right
+// createOCRBatch stages parsed records (and the original image, if any) for review
+func createOCRBatch(kind, source, metric, weekDate, day string, imageData []byte, pages []ocrPageImage, rawText string, records []OCRRecord, createdBy int) (int64, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
 
-	// Ensure bounds are valid
-	if top < bounds.Min.Y {
-		top = bounds.Min.Y
+	var createdByID interface{}
+	if createdBy > 0 {
+		createdByID = createdBy
 	}
-	if bottom > bounds.Max.Y {
-		bottom = bounds.Max.Y
+
+	result, err := tx.Exec(`INSERT INTO ocr_batches (kind, source, metric, week_date, day, image, raw_text, created_by)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		kind, source,
+		sql.NullString{String: metric, Valid: metric != ""},
+		sql.NullString{String: weekDate, Valid: weekDate != ""},
+		sql.NullString{String: day, Valid: day != ""},
+		imageData,
+		sql.NullString{String: rawText, Valid: rawText != ""},
+		createdByID)
+	if err != nil {
+		return 0, err
 	}
-	if left < bounds.Min.X {
-		left = bounds.Min.X
+
+	batchID, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
 	}
-	if right > bounds.Max.X {
-		right = bounds.Max.X
+
+	for i, page := range pages {
+		_, err := tx.Exec("INSERT INTO ocr_batch_images (batch_id, page_index, file_name, image) VALUES (?, ?, ?, ?)",
+			batchID, i, page.FileName, page.Data)
+		if err != nil {
+			return 0, err
+		}
 	}
 
-	croppedImg := image.NewRGBA(image.Rect(0, 0, right-left, bottom-top))
-	draw.Draw(croppedImg, croppedImg.Bounds(), img, image.Point{left, top}, draw.Src)
+	if err := insertOCRBatchRows(tx, batchID, kind, records); err != nil {
+		return 0, err
+	}
 
-	log.Printf("Cropped image from %v to %v", bounds, croppedImg.Bounds())
-	return croppedImg
+	return batchID, tx.Commit()
 }
 
-// Convert image to grayscale
-func convertToGrayscale(img image.Image) *image.Gray {
-	bounds := img.Bounds()
-	gray := image.NewGray(bounds)
+const ocrBatchSelectSQL = `
+	SELECT b.id, b.kind, b.status, b.source, b.metric, b.week_date, b.day,
+		b.image IS NOT NULL OR EXISTS (SELECT 1 FROM ocr_batch_images WHERE batch_id = b.id),
+		(SELECT COUNT(*) FROM ocr_batch_images WHERE batch_id = b.id),
+		b.created_by, cu.username, b.created_at, b.reviewed_by, ru.username, b.reviewed_at,
+		(SELECT COUNT(*) FROM ocr_batch_rows WHERE batch_id = b.id),
+		(SELECT COUNT(*) FROM ocr_batch_rows WHERE batch_id = b.id AND member_id IS NOT NULL AND skip = 0),
+		(SELECT COUNT(*) FROM ocr_batch_rows WHERE batch_id = b.id AND low_confidence = 1)
+	FROM ocr_batches b
+	LEFT JOIN users cu ON b.created_by = cu.id
+	LEFT JOIN users ru ON b.reviewed_by = ru.id`
+
+// scanOCRBatch reads one row selected with ocrBatchSelectSQL
+func scanOCRBatch(row interface{ Scan(...interface{}) error }) (OCRBatch, error) {
+	var b OCRBatch
+	err := row.Scan(&b.ID, &b.Kind, &b.Status, &b.Source, &b.Metric, &b.WeekDate, &b.Day, &b.HasImage, &b.PageCount,
+		&b.CreatedBy, &b.CreatedByName, &b.CreatedAt, &b.ReviewedBy, &b.ReviewedByName, &b.ReviewedAt,
+		&b.RowCount, &b.MatchedCount, &b.LowConfidence)
+	return b, err
+}
 
-	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
-		for x := bounds.Min.X; x < bounds.Max.X; x++ {
-			gray.Set(x, y, img.At(x, y))
-		}
-	}
+// sqlQuerier is satisfied by both db and a transaction
+type sqlQuerier interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}
 
-	return gray
+// loadOCRBatch loads a batch with all of its rows
+func loadOCRBatch(batchID int) (*OCRBatch, error) {
+	return loadOCRBatchFrom(db, batchID)
 }
 
-// Enhance contrast using histogram equalization (simplified)
-func enhanceContrast(img *image.Gray) *image.Gray {
-	bounds := img.Bounds()
-	enhanced := image.NewGray(bounds)
+// loadOCRBatchFrom loads a batch with all of its rows through q, e.g. inside the transaction reviewing it
+func loadOCRBatchFrom(q sqlQuerier, batchID int) (*OCRBatch, error) {
+	batch, err := scanOCRBatch(q.QueryRow(ocrBatchSelectSQL+" WHERE b.id = ?", batchID))
+	if err != nil {
+		return nil, err
+	}
 
-	// Calculate histogram
-	histogram := make([]int, 256)
-	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
-		for x := bounds.Min.X; x < bounds.Max.X; x++ {
-			grayVal := img.GrayAt(x, y).Y
-			histogram[grayVal]++
-		}
+	err = q.QueryRow("SELECT COALESCE(ocr_min_confidence, 0) FROM settings WHERE id = 1").Scan(&batch.MinConfidence)
+	if err != nil {
+		return nil, err
 	}
 
-	// Calculate cumulative distribution
-	totalPixels := bounds.Dx() * bounds.Dy()
-	cdf := make([]float64, 256)
-	cdf[0] = float64(histogram[0]) / float64(totalPixels)
-	for i := 1; i < 256; i++ {
-		cdf[i] = cdf[i-1] + float64(histogram[i])/float64(totalPixels)
+	rows, err := q.Query(`
+		SELECT r.id, r.row_index, r.raw_text, r.parsed_name, r.value, r.confidence,
+			r.name_text, r.name_confidence, r.name_x, r.name_y, r.name_width, r.name_height,
+			r.value_text, r.value_confidence, r.value_x, r.value_y, r.value_width, r.value_height,
+			r.low_confidence, r.rank, r.page_index, r.member_id, m.name, r.match_score, r.corrected, r.skip
+		FROM ocr_batch_rows r
+		LEFT JOIN members m ON r.member_id = m.id
+		WHERE r.batch_id = ?
+		ORDER BY r.row_index`, batchID)
+	if err != nil {
+		return nil, err
 	}
+	defer rows.Close()
 
-	// Apply equalization
-	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
-		for x := bounds.Min.X; x < bounds.Max.X; x++ {
-			grayVal := img.GrayAt(x, y).Y
-			newVal := uint8(cdf[grayVal] * 255)
-			enhanced.SetGray(x, y, color.Gray{Y: newVal})
+	batch.Rows = []OCRBatchRow{}
+	for rows.Next() {
+		var row OCRBatchRow
+		var nameX, nameY, nameWidth, nameHeight sql.NullInt64
+		var valueX, valueY, valueWidth, valueHeight sql.NullInt64
+		if err := rows.Scan(&row.ID, &row.RowIndex, &row.RawText, &row.ParsedName, &row.Value, &row.Confidence,
+			&row.NameText, &row.NameConfidence, &nameX, &nameY, &nameWidth, &nameHeight,
+			&row.ValueText, &row.ValueConfidence, &valueX, &valueY, &valueWidth, &valueHeight,
+			&row.LowConfidence, &row.Rank, &row.Page, &row.MemberID, &row.MemberName, &row.MatchScore, &row.Corrected, &row.Skip); err != nil {
+			return nil, err
 		}
+		row.NameBox = ocrBoxFromColumns(nameX, nameY, nameWidth, nameHeight)
+		row.ValueBox = ocrBoxFromColumns(valueX, valueY, valueWidth, valueHeight)
+		batch.Rows = append(batch.Rows, row)
 	}
-
-	return enhanced
+	return &batch, rows.Err()
 }
 
-// Apply adaptive thresholding to enhance text
-func applyAdaptiveThreshold(img *image.Gray, blockSize int) *image.Gray {
-	bounds := img.Bounds()
-	thresholded := image.NewGray(bounds)
-
-	halfBlock := blockSize / 2
+// writeStagedBatch responds to an upload with the batch that is now waiting for review
+func writeStagedBatch(w http.ResponseWriter, batchID int64) {
+	batch, err := loadOCRBatch(int(batchID))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
 
-	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
-		for x := bounds.Min.X; x < bounds.Max.X; x++ {
-			// Calculate local mean in block
-			sum := 0
-			count := 0
-			for by := y - halfBlock; by <= y+halfBlock; by++ {
-				for bx := x - halfBlock; bx <= x+halfBlock; bx++ {
-					if bx >= bounds.Min.X && bx < bounds.Max.X && by >= bounds.Min.Y && by < bounds.Max.Y {
-						sum += int(img.GrayAt(bx, by).Y)
-						count++
-					}
-				}
-			}
-			mean := uint8(sum / count)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":  stagedBatchMessage(batch),
+		"batch_id": batch.ID,
+		"batch":    batch,
+	})
+}
 
-			// Threshold: if pixel is darker than local mean, make it black, else white
-			pixel := img.GrayAt(x, y).Y
-			if pixel < mean-10 { // -10 for bias towards text
-				thresholded.SetGray(x, y, color.Gray{Y: 0}) // Black (text)
-			} else {
-				thresholded.SetGray(x, y, color.Gray{Y: 255}) // White (background)
-			}
-		}
+// stagedBatchMessage summarizes a newly staged batch for the uploader
+func stagedBatchMessage(batch *OCRBatch) string {
+	message := fmt.Sprintf("Staged %d rows for review (%d matched to members)", batch.RowCount, batch.MatchedCount)
+	if batch.Day != nil {
+		message = fmt.Sprintf("Staged %d rows for %s for review (%d matched to members)", batch.RowCount, *batch.Day, batch.MatchedCount)
 	}
+	if batch.LowConfidence > 0 {
+		message += fmt.Sprintf(", %d rejected below %d%% confidence", batch.LowConfidence, batch.MinConfidence)
+	}
+	return message
+}
 
-	return thresholded
+// stagedBatchResult is the stored result of an OCR job that staged a batch
+func stagedBatchResult(batchID int64) (map[string]interface{}, error) {
+	batch, err := loadOCRBatch(int(batchID))
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"message": stagedBatchMessage(batch)}, nil
 }
 
-// Invert image (make text black on white background)
-func invertImage(img *image.Gray) *image.Gray {
-	bounds := img.Bounds()
-	inverted := image.NewGray(bounds)
+// HTTP handler to process VS points screenshot.
+// Parsed rows are staged in an OCR batch and only written to vs_points once approved.
+// Screenshots are queued as an OCR job (202 with the job ID); pasted text is staged straight away.
+func processVSPointsScreenshot(w http.ResponseWriter, r *http.Request) {
+	var records []OCRRecord
+	var detectedDay string
+	var weekDate string
+	var rawText string
+	var source string
 
-	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
-		for x := bounds.Min.X; x < bounds.Max.X; x++ {
-			grayVal := img.GrayAt(x, y).Y
-			inverted.SetGray(x, y, color.Gray{Y: 255 - grayVal})
+	// Check if this is a multipart form (image upload) or JSON (manual text)
+	contentType := r.Header.Get("Content-Type")
+	if strings.HasPrefix(contentType, "multipart/form-data") {
+		// Handle image upload
+		if !requireOCR(w) {
+			return
 		}
-	}
 
-	return inverted
-}
+		err := r.ParseMultipartForm(10 << 20) // 10 MB max
+		if err != nil {
+			http.Error(w, "Failed to parse form", http.StatusBadRequest)
+			return
+		}
 
-// Scale image up for better OCR
-func scaleImage(img image.Image, factor int) image.Image {
-	bounds := img.Bounds()
-	newWidth := bounds.Dx() * factor
-	newHeight := bounds.Dy() * factor
+		file, header, err := r.FormFile("image")
+		if err != nil {
+			http.Error(w, "No image file provided", http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
 
-	scaled := image.NewRGBA(image.Rect(0, 0, newWidth, newHeight))
+		imageData, err := io.ReadAll(file)
+		if err != nil {
+			http.Error(w, "Failed to read image", http.StatusInternalServerError)
+			return
+		}
 
-	// Simple nearest-neighbor scaling
-	for y := 0; y < newHeight; y++ {
-		for x := 0; x < newWidth; x++ {
-			origX := x / factor
-			origY := y / factor
-			scaled.Set(x, y, img.At(origX, origY))
+		// OCR runs in the background; the client polls the job for the staged batch
+		// Week parameter is optional and defaults to "current"
+		session, _ := store.Get(r, "session")
+		userID, _ := session.Values["user_id"].(int)
+		jobID, err := enqueueOCRJob("vs_points", "upload", "", uploadWeekDate(r.FormValue("week")), 0,
+			[]ocrPageImage{{FileName: header.Filename, Data: imageData}}, userID)
+		writeQueuedJob(w, jobID, err)
+		return
+	} else {
+		// Handle JSON (manual text or pre-parsed data)
+		var request struct {
+			Records []struct {
+				MemberName string `json:"member_name"`
+				Points     int64  `json:"points"`
+			} `json:"records"`
+			Text string `json:"text"` // Raw text to parse
+			Day  string `json:"day"`  // Optional: specify the day
+			Week string `json:"week"` // Optional: "current" or "last"
 		}
-	}
 
-	return scaled
-}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 
-// Preprocess image for better OCR
-func preprocessImageForOCR(imageData []byte) ([]byte, error) {
-	// Decode image
-	img, format, err := image.Decode(bytes.NewReader(imageData))
-	if err != nil {
-		return nil, fmt.Errorf("failed to decode image: %v", err)
-	}
+		if request.Text != "" {
+			// Parse raw text
+			source = "text"
+			rawText = request.Text
+			records = parseVSPointsText(request.Text)
+			detectedDay = detectSelectedDay(request.Text)
+		} else {
+			source = "records"
+			for _, rec := range request.Records {
+				records = append(records, OCRRecord{MemberName: rec.MemberName, Value: rec.Points})
+			}
+		}
 
-	log.Printf("Original image: %dx%d, format: %s", img.Bounds().Dx(), img.Bounds().Dy(), format)
+		// Typed input has no OCR uncertainty
+		for i := range records {
+			records[i].Confidence = 100
+			records[i].NameText = records[i].MemberName
+			records[i].NameConfidence = 100
+			records[i].ValueText = strconv.FormatInt(records[i].Value, 10)
+			records[i].ValueConfidence = 100
+		}
 
-	// Analyze screenshot to detect regions
-	attrs := analyzeScreenshot(img)
+		// Use provided day if available, otherwise use detected day
+		if request.Day != "" {
+			detectedDay = strings.ToLower(request.Day)
+		}
 
-	// Crop to data region only (remove UI elements)
-	// For narrow screenshots or when power values might be cut off, use less aggressive cropping
-	croppedImg := img
-	if attrs.DataRegion != nil && attrs.Width > 600 {
-		croppedImg = cropToDataRegion(img, attrs.DataRegion)
-	} else {
-		log.Printf("Skipping crop for narrow image to preserve power values")
+		weekDate = uploadWeekDate(request.Week)
 	}
 
-	// Scale up 2x for better OCR (small text is hard to read)
-	scaledImg := scaleImage(croppedImg, 2)
-
-	// Convert to grayscale
-	grayImg := convertToGrayscale(scaledImg)
-
-	// For small images, skip contrast enhancement (can make things worse)
-	processedImg := grayImg
-
-	// Encode back to bytes
-	var buf bytes.Buffer
-	if err := png.Encode(&buf, processedImg); err != nil {
-		return nil, fmt.Errorf("failed to encode processed image: %v", err)
+	if len(records) == 0 {
+		http.Error(w, "No valid VS point records found", http.StatusBadRequest)
+		return
 	}
 
-	log.Printf("Image preprocessed: %dx%d -> %dx%d (2x scaled grayscale)",
-		img.Bounds().Dx(), img.Bounds().Dy(),
-		processedImg.Bounds().Dx(), processedImg.Bounds().Dy())
-
-	return buf.Bytes(), nil
-}
-
-// Extract power data from image using OCR with preprocessing
-func extractPowerDataFromImage(imageData []byte) ([]struct {
-	MemberName string `json:"member_name"`
-	Power      int64  `json:"power"`
-}, error) {
-	// Preprocess image to filter and enhance relevant regions
-	processedData, err := preprocessImageForOCR(imageData)
-	if err != nil {
-		log.Printf("Warning: Image preprocessing failed: %v. Using original image.", err)
-		processedData = imageData // Fallback to original
+	// An undetected day can be set by the reviewer before approval
+	if detectedDay != "" && vsDayIndex(detectedDay) == len(vsDays) {
+		http.Error(w, fmt.Sprintf("Invalid day: %s. Must be monday-sunday", detectedDay), http.StatusBadRequest)
+		return
 	}
 
-	client := gosseract.NewClient()
-	defer client.Close()
+	session, _ := store.Get(r, "session")
+	userID, _ := session.Values["user_id"].(int)
 
-	err = client.SetImageFromBytes(processedData)
+	batchID, err := createOCRBatch("vs_points", source, "", weekDate, detectedDay, nil, nil, rawText, records, userID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to load image: %v", err)
-	}
-
-	// Try different PSM modes for better recognition
-	var text string
-	psmModes := []gosseract.PageSegMode{
-		gosseract.PSM_AUTO,
-		gosseract.PSM_SINGLE_BLOCK,
-		gosseract.PSM_SPARSE_TEXT,
+		http.Error(w, fmt.Sprintf("Failed to stage records: %v", err), http.StatusInternalServerError)
+		return
 	}
 
-	for i, mode := range psmModes {
-		client.SetPageSegMode(mode)
-		extractedText, err := client.Text()
-		if err == nil && len(strings.TrimSpace(extractedText)) > 0 {
-			text = extractedText
-			log.Printf("OCR successful with PSM mode %d (attempt %d)", mode, i+1)
-			break
-		}
-		log.Printf("OCR attempt %d with PSM mode %d failed or empty", i+1, mode)
-	}
+	writeStagedBatch(w, batchID)
+}
 
-	if len(strings.TrimSpace(text)) == 0 {
-		return nil, fmt.Errorf("OCR failed: no text extracted after trying multiple modes")
+// validRankingMetricParam checks an optional metric parameter of a ranking upload ("" means detect it)
+func validRankingMetricParam(w http.ResponseWriter, metric string) bool {
+	if metric == "" {
+		return true
 	}
-
-	// Log the extracted text for debugging
-	log.Printf("OCR extracted text:\n%s\n---END OCR---", text)
-
-	// Parse the OCR text
-	records := parsePowerRankingsText(text)
-
-	if len(records) == 0 {
-		return nil, fmt.Errorf("no valid records found in extracted text (see server logs for OCR output)")
+	if _, found := findRankingMetric(metric); !found {
+		http.Error(w, "Invalid metric - must be power, kills or donation", http.StatusBadRequest)
+		return false
 	}
-
-	return records, nil
+	return true
 }
 
-// Parse power rankings text (from OCR or manual input)
-func parsePowerRankingsText(text string) []struct {
-	MemberName string `json:"member_name"`
-	Power      int64  `json:"power"`
-} {
-	var records []struct {
-		MemberName string `json:"member_name"`
-		Power      int64  `json:"power"`
+// Process screenshot data with OCR support.
+// Parsed rows are staged in an OCR batch and only written to power_history (or kills_history or
+// donation_history for those ranking tabs) once approved. The tab is taken from the metric
+// parameter, or detected from the screenshot or pasted text when it is not given.
+// Screenshots are queued as an OCR job (202 with the job ID); pasted text is staged straight away.
+func processPowerScreenshot(w http.ResponseWriter, r *http.Request) {
+	// Check if power tracking is enabled
+	var powerTrackingEnabled bool
+	err := db.QueryRow("SELECT COALESCE(power_tracking_enabled, 0) FROM settings WHERE id = 1").Scan(&powerTrackingEnabled)
+	if err != nil || !powerTrackingEnabled {
+		http.Error(w, "Power tracking is not enabled", http.StatusForbidden)
+		return
 	}
 
-	lines := strings.Split(text, "\n")
-
-	// Pattern specifically for Last War rankings format
-	// Matches: optional rank badge (R4, R3), name (can have spaces), then large power number
-	// Examples: "R4 Gary6126 77421000", "Nutty Tx 61926102", "R3 DYNOSUR 63785308"
-	// Updated to better handle multi-word names
-	rankPattern := regexp.MustCompile(`(?:R[0-9]\s+)?([A-Za-z][A-Za-z0-9_\s]+?)\s+([0-9]{7,})`)
-
-	// Alternative simpler pattern: captures name with spaces followed by 7+ digit number
-	simplePattern := regexp.MustCompile(`([A-Za-z][A-Za-z0-9_\s]+?)\s+([0-9]{7,})`)
+	var records []OCRRecord
+	var rawText string
+	var source string
+	var metric string
 
-	// Pattern for lines with rank number prefix: "1 Gary6126 R4 77421000" or "1 ileesu R4 66715876"
-	rankPrefixPattern := regexp.MustCompile(`^[0-9]{1,3}\s+([A-Za-z][A-Za-z0-9_\s]+?)\s+(?:R[0-9]\s+)?([0-9]{7,})`)
+	// Check if this is a multipart form (image upload) or JSON (manual text)
+	contentType := r.Header.Get("Content-Type")
+	if strings.HasPrefix(contentType, "multipart/form-data") {
+		// Handle image upload
+		if !requireOCR(w) {
+			return
+		}
 
-	// Flexible pattern that allows letters in power (for OCR errors): "B 25) Nutty Tx s1926102"
-	// This captures name followed by 7+ chars that may contain letters misread as digits
-	flexiblePattern := regexp.MustCompile(`(?:[A-Z]{1,3}\s+)?(?:\d+\)?\s+)?([A-Za-z][A-Za-z0-9_\s]+?)\s+([A-Za-z0-9]{7,})`)
+		err := r.ParseMultipartForm(10 << 20) // 10 MB max
+		if err != nil {
+			http.Error(w, "Failed to parse form", http.StatusBadRequest)
+			return
+		}
 
-	// Track seen names to avoid duplicates from multi-line OCR
-	seenNames := make(map[string]bool)
+		metric = r.FormValue("metric")
+		if !validRankingMetricParam(w, metric) {
+			return
+		}
 
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line == "" {
-			continue
+		file, header, err := r.FormFile("image")
+		if err != nil {
+			http.Error(w, "No image file provided", http.StatusBadRequest)
+			return
 		}
+		defer file.Close()
 
-		// Skip lines that are clearly UI elements or rank numbers
-		if len(line) < 5 || regexp.MustCompile(`^[0-9]{1,2}$`).MatchString(line) {
-			continue
+		imageData, err := io.ReadAll(file)
+		if err != nil {
+			http.Error(w, "Failed to read image", http.StatusInternalServerError)
+			return
 		}
 
-		// Skip common UI text
-		lowerLine := strings.ToLower(line)
-		if strings.Contains(lowerLine, "ranking") ||
-			strings.Contains(lowerLine, "commander") ||
-			strings.Contains(lowerLine, "power") ||
-			strings.Contains(lowerLine, "kills") ||
-			strings.Contains(lowerLine, "donation") {
-			continue
+		// OCR runs in the background; the client polls the job for the staged batch
+		session, _ := store.Get(r, "session")
+		userID, _ := session.Values["user_id"].(int)
+		jobID, err := enqueueOCRJob("power", "upload", metric, "", 0,
+			[]ocrPageImage{{FileName: header.Filename, Data: imageData}}, userID)
+		writeQueuedJob(w, jobID, err)
+		return
+	} else {
+		// Handle JSON (manual text or pre-parsed data)
+		var request struct {
+			Metric  string `json:"metric"`
+			Records []struct {
+				MemberName string `json:"member_name"`
+				Power      int64  `json:"power"`
+				Value      int64  `json:"value"` // kills or donations
+			} `json:"records"`
+			Text string `json:"text"` // Raw text to parse
 		}
 
-		// Try rank pattern first (for lines with R4, R3, etc.)
-		matches := rankPattern.FindStringSubmatch(line)
-		if len(matches) == 0 {
-			// Try pattern with rank number prefix
-			matches = rankPrefixPattern.FindStringSubmatch(line)
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
 		}
-		if len(matches) == 0 {
-			// Try simple pattern
-			matches = simplePattern.FindStringSubmatch(line)
+		if !validRankingMetricParam(w, request.Metric) {
+			return
 		}
-		if len(matches) == 0 {
-			// Try flexible pattern (allows letters in power number for OCR errors)
-			matches = flexiblePattern.FindStringSubmatch(line)
+		metric = request.Metric
+
+		if request.Text != "" {
+			// Parse raw text
+			source = "text"
+			rawText = request.Text
+			if metric == "" {
+				metric = detectRankingMetricFromText(request.Text)
+			}
+			if metric == "" {
+				metric = "power"
+			}
+			rankingMetric, _ := findRankingMetric(metric)
+			records = parseRankingText(request.Text, rankingMetric)
+		} else {
+			source = "records"
+			if metric == "" {
+				metric = "power"
+			}
+			for _, rec := range request.Records {
+				value := rec.Value
+				if metric == "power" && rec.Power != 0 {
+					value = rec.Power
+				}
+				records = append(records, OCRRecord{MemberName: rec.MemberName, Value: value})
+			}
 		}
 
-		if len(matches) >= 3 {
-			name := strings.TrimSpace(matches[1])
-			// Clean up extra whitespace in names
-			name = regexp.MustCompile(`\s+`).ReplaceAllString(name, " ")
+		// Typed input has no OCR uncertainty
+		for i := range records {
+			records[i].Confidence = 100
+			records[i].NameText = records[i].MemberName
+			records[i].NameConfidence = 100
+			records[i].ValueText = strconv.FormatInt(records[i].Value, 10)
+			records[i].ValueConfidence = 100
+		}
+	}
 
-			powerStr := strings.ReplaceAll(matches[2], ",", "")
-			powerStr = strings.ReplaceAll(powerStr, " ", "")
-			powerStr = strings.ReplaceAll(powerStr, ".", "") // Remove periods OCR might insert
-			// Common OCR character misreads for digits
-			powerStr = strings.ReplaceAll(powerStr, "O", "0")
-			powerStr = strings.ReplaceAll(powerStr, "o", "0")
-			powerStr = strings.ReplaceAll(powerStr, "s", "6") // s often misread as 6
-			powerStr = strings.ReplaceAll(powerStr, "S", "5") // S often misread as 5
-			powerStr = strings.ReplaceAll(powerStr, "l", "1") // l often misread as 1
-			powerStr = strings.ReplaceAll(powerStr, "I", "1") // I often misread as 1
-			powerStr = strings.ReplaceAll(powerStr, "Z", "2") // Z sometimes misread as 2
-			powerStr = strings.ReplaceAll(powerStr, "B", "8") // B sometimes misread as 8
-			powerStr = strings.ReplaceAll(powerStr, "e", "6") // e sometimes misread as 6
-			powerStr = strings.ReplaceAll(powerStr, "g", "9") // g sometimes misread as 9
-			powerStr = strings.ReplaceAll(powerStr, "G", "6") // G sometimes misread as 6
-			// Remove any remaining non-digit characters
-			powerStr = regexp.MustCompile(`[^0-9]`).ReplaceAllString(powerStr, "")
+	if len(records) == 0 {
+		http.Error(w, "No valid records found", http.StatusBadRequest)
+		return
+	}
 
-			power, err := strconv.ParseInt(powerStr, 10, 64)
+	session, _ := store.Get(r, "session")
+	userID, _ := session.Values["user_id"].(int)
 
-			// Validate: power should be realistic (1M to 1B range), name should be reasonable
-			if err == nil && power >= 1000000 && power <= 9999999999 &&
-				len(name) >= 3 && len(name) <= 30 && !seenNames[name] {
-				records = append(records, struct {
-					MemberName string `json:"member_name"`
-					Power      int64  `json:"power"`
-				}{
-					MemberName: name,
-					Power:      power,
-				})
-				seenNames[name] = true
-				log.Printf("Parsed: %s -> %d", name, power)
-			} else if err != nil {
-				log.Printf("Failed to parse power for %s: %s (error: %v)", name, powerStr, err)
-			}
-		}
+	batchID, err := createOCRBatch("power", source, metric, "", "", nil, nil, rawText, records, userID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to stage records: %v", err), http.StatusInternalServerError)
+		return
 	}
 
-	return records
+	writeStagedBatch(w, batchID)
 }
 
-// Normalize name for matching (remove common prefixes, spaces, special chars)
-func normalizeName(name string) string {
-	name = strings.ToLower(name)
-	// Remove common prefixes
-	name = strings.TrimPrefix(name, "the ")
-	name = strings.TrimPrefix(name, "a ")
-	name = strings.TrimPrefix(name, "an ")
-	// Remove spaces and special characters
-	name = strings.ReplaceAll(name, " ", "")
-	name = strings.ReplaceAll(name, "_", "")
-	name = strings.ReplaceAll(name, "-", "")
-	return name
+// ocrPageImage is one screenshot of a multi-image upload
+type ocrPageImage struct {
+	FileName string
+	Data     []byte
 }
 
-// Calculate string similarity (0-100) using improved algorithm
-func calculateSimilarity(s1, s2 string) int {
-	// Normalize both strings
-	n1 := normalizeName(s1)
-	n2 := normalizeName(s2)
+// StitchPageReport summarizes the rows read from one screenshot of a multi-image upload
+type StitchPageReport struct {
+	Page     int    `json:"page"`
+	FileName string `json:"file_name"`
+	RowCount int    `json:"row_count"`
+	MinRank  int    `json:"min_rank"`
+	MaxRank  int    `json:"max_rank"`
+	Error    string `json:"error,omitempty"`
+}
 
-	// If normalized strings are identical, perfect match
-	if n1 == n2 {
-		return 100
-	}
+// RankRange is an inclusive range of in-game rank numbers
+type RankRange struct {
+	From int `json:"from"`
+	To   int `json:"to"`
+}
 
-	// If one contains the other after normalization, very high score
-	if strings.Contains(n1, n2) || strings.Contains(n2, n1) {
-		return 90
-	}
+// StitchOverlap is a rank range that appears on two neighbouring screenshots
+type StitchOverlap struct {
+	Pages []int `json:"pages"`
+	RankRange
+}
 
-	// Calculate Levenshtein distance using existing function
-	distance := levenshteinDistance(n1, n2)
-	maxLen := len(n1)
-	if len(n2) > maxLen {
-		maxLen = len(n2)
-	}
+// StitchConflict records two different rows read for the same rank or member
+type StitchConflict struct {
+	Rank      int       `json:"rank"`
+	Reason    string    `json:"reason"`
+	Kept      OCRRecord `json:"kept"`
+	Discarded OCRRecord `json:"discarded"`
+}
 
-	if maxLen == 0 {
-		return 0
-	}
+// StitchReport describes how screenshots were merged into one ranking list
+type StitchReport struct {
+	Pages           []StitchPageReport `json:"pages"`
+	TotalRows       int                `json:"total_rows"`     // rows read across all screenshots
+	UniqueRows      int                `json:"unique_rows"`    // rows in the consolidated list
+	DuplicateRows   int                `json:"duplicate_rows"` // identical rows read again where screenshots overlap
+	InferredRanks   int                `json:"inferred_ranks"` // rank numbers filled in from neighbouring rows
+	UnrankedRows    int                `json:"unranked_rows"`  // rows whose rank could not be read or inferred
+	HighestRank     int                `json:"highest_rank"`
+	Overlaps        []StitchOverlap    `json:"overlaps"`
+	Conflicts       []StitchConflict   `json:"conflicts"`
+	MissingRanks    []int              `json:"missing_ranks"`
+	Gaps            []RankRange        `json:"gaps"`
+	CoveragePercent float64            `json:"coverage_percent"` // share of ranks 1..highest_rank that were read
+}
 
-	// Convert distance to similarity percentage
-	similarity := ((maxLen - distance) * 100) / maxLen
+// inferPageRanks fills in unread rank numbers from the rows around them.
+// Rows on one screenshot are consecutive, so a row after rank N is N+1 as long as that
+// stays below the next rank that was read.
+func inferPageRanks(records []OCRRecord) int {
+	inferred := 0
+	for i := range records {
+		if records[i].Rank > 0 || i == 0 || records[i-1].Rank == 0 {
+			continue
+		}
+		candidate := records[i-1].Rank + 1
+		nextRank := 0
+		for j := i + 1; j < len(records); j++ {
+			if records[j].Rank > 0 && !records[j].RankInferred {
+				nextRank = records[j].Rank
+				break
+			}
+		}
+		if nextRank == 0 || candidate < nextRank {
+			records[i].Rank = candidate
+			records[i].RankInferred = true
+			inferred++
+		}
+	}
+	return inferred
+}
 
-	return similarity
+// preferOCRRecord picks which of two readings of the same row to keep
+func preferOCRRecord(a, b OCRRecord) (OCRRecord, OCRRecord) {
+	if b.Confidence > a.Confidence || (a.RankInferred && !b.RankInferred) {
+		return b, a
+	}
+	return a, b
 }
 
-// Detect selected day tab by color (white/light background indicates selected tab)
-func detectDayByColor(img image.Image) string {
-	bounds := img.Bounds()
-	width := bounds.Dx()
+// stitchRankedPages merges the rows of scrolled ranking screenshots into one list ordered by rank.
+// Rows read again where screenshots overlap are de-duplicated, disagreeing rows are reported as
+// conflicts and rank numbers that were never read are reported as gaps.
+func stitchRankedPages(pages [][]OCRRecord, fileNames []string) ([]OCRRecord, StitchReport) {
+	report := StitchReport{
+		Pages:        []StitchPageReport{},
+		Overlaps:     []StitchOverlap{},
+		Conflicts:    []StitchConflict{},
+		MissingRanks: []int{},
+		Gaps:         []RankRange{},
+	}
+
+	byRank := make(map[int]OCRRecord)
+	unranked := []OCRRecord{}
+
+	for page, records := range pages {
+		pageReport := StitchPageReport{Page: page, RowCount: len(records)}
+		if page < len(fileNames) {
+			pageReport.FileName = fileNames[page]
+		}
+		report.TotalRows += len(records)
+		report.InferredRanks += inferPageRanks(records)
 
-	// Days are arranged horizontally: Mon, Tues, Wed, Thur, Fri, Sat
-	days := []string{"monday", "tuesday", "wednesday", "thursday", "friday", "saturday"}
-	tabWidth := width / 6 // Each tab takes ~1/6 of the width
+		for _, record := range records {
+			record.Page = page
+			if record.Rank == 0 {
+				unranked = append(unranked, record)
+				continue
+			}
+			if pageReport.MinRank == 0 || record.Rank < pageReport.MinRank {
+				pageReport.MinRank = record.Rank
+			}
+			if record.Rank > pageReport.MaxRank {
+				pageReport.MaxRank = record.Rank
+			}
 
-	// Count white/light pixels in each tab region
-	// Selected tab has white background, unselected tabs are gray
-	lightCounts := make([]int, 6)
+			existing, found := byRank[record.Rank]
+			if !found {
+				byRank[record.Rank] = record
+				continue
+			}
 
-	for dayIdx := 0; dayIdx < 6; dayIdx++ {
-		// Define the region for this day tab
-		startX := dayIdx * tabWidth
-		endX := startX + tabWidth
-		if dayIdx == 5 {
-			endX = width // Last tab goes to the end
+			kept, discarded := preferOCRRecord(existing, record)
+			byRank[record.Rank] = kept
+			switch {
+			case normalizeName(existing.MemberName) != normalizeName(record.MemberName):
+				report.Conflicts = append(report.Conflicts, StitchConflict{Rank: record.Rank, Reason: "different names for the same rank", Kept: kept, Discarded: discarded})
+			case existing.Value != record.Value:
+				report.Conflicts = append(report.Conflicts, StitchConflict{Rank: record.Rank, Reason: "different values for the same member", Kept: kept, Discarded: discarded})
+			default:
+				report.DuplicateRows++
+			}
 		}
+		report.Pages = append(report.Pages, pageReport)
+	}
 
-		// Sample the center 70% of each tab to avoid edge overlap issues
-		// This prevents bleeding from adjacent tabs
-		tabCenter := startX + tabWidth/2
-		sampleWidth := int(float64(tabWidth) * 0.70)
-		sampleStartX := tabCenter - sampleWidth/2
-		sampleEndX := tabCenter + sampleWidth/2
+	// A member read at two ranks means one rank number was misread; keep the better reading
+	rankByName := make(map[string]int)
+	ranks := make([]int, 0, len(byRank))
+	for rank := range byRank {
+		ranks = append(ranks, rank)
+	}
+	sort.Ints(ranks)
+	for _, rank := range ranks {
+		record, found := byRank[rank]
+		if !found {
+			continue
+		}
+		name := normalizeName(record.MemberName)
+		otherRank, seen := rankByName[name]
+		if !seen {
+			rankByName[name] = rank
+			continue
+		}
+		kept, discarded := preferOCRRecord(byRank[otherRank], record)
+		delete(byRank, discarded.Rank)
+		rankByName[name] = kept.Rank
+		report.Conflicts = append(report.Conflicts, StitchConflict{
+			Rank:      discarded.Rank,
+			Reason:    fmt.Sprintf("same member also read at rank %d", kept.Rank),
+			Kept:      kept,
+			Discarded: discarded,
+		})
+	}
 
-		// Ensure we stay within bounds
-		if sampleStartX < startX {
-			sampleStartX = startX
+	// Screenshots are scrolled in order, so overlaps are between neighbours by rank range
+	sortedPages := []StitchPageReport{}
+	for _, pageReport := range report.Pages {
+		if pageReport.MaxRank > 0 {
+			sortedPages = append(sortedPages, pageReport)
 		}
-		if sampleEndX > endX {
-			sampleEndX = endX
+	}
+	sort.Slice(sortedPages, func(i, j int) bool { return sortedPages[i].MinRank < sortedPages[j].MinRank })
+	for i := 1; i < len(sortedPages); i++ {
+		prev, cur := sortedPages[i-1], sortedPages[i]
+		if cur.MinRank <= prev.MaxRank {
+			report.Overlaps = append(report.Overlaps, StitchOverlap{
+				Pages:     []int{prev.Page, cur.Page},
+				RankRange: RankRange{From: cur.MinRank, To: min(prev.MaxRank, cur.MaxRank)},
+			})
 		}
+	}
 
-		// Count white/light pixels in this region
-		// Selected tab has white/cream background (high RGB values)
-		lightCount := 0
-		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
-			for x := sampleStartX; x < sampleEndX; x++ {
-				r, g, b, _ := img.At(x, y).RGBA()
-				// Convert from 16-bit to 8-bit color
-				r8, g8, b8 := uint8(r>>8), uint8(g>>8), uint8(b>>8)
+	highestRank := 0
+	for rank := range byRank {
+		highestRank = max(highestRank, rank)
+	}
 
-				// White/cream/light background detection
-				// Selected tab has light background (RGB > 200)
-				// Unselected tabs are gray/dark (RGB < 180)
-				if r8 > 200 && g8 > 200 && b8 > 200 {
-					lightCount++
-				}
+	merged := []OCRRecord{}
+	for rank := 1; rank <= highestRank; rank++ {
+		record, found := byRank[rank]
+		if !found {
+			report.MissingRanks = append(report.MissingRanks, rank)
+			if n := len(report.Gaps); n > 0 && report.Gaps[n-1].To == rank-1 {
+				report.Gaps[n-1].To = rank
+			} else {
+				report.Gaps = append(report.Gaps, RankRange{From: rank, To: rank})
 			}
+			continue
 		}
-		lightCounts[dayIdx] = lightCount
+		merged = append(merged, record)
 	}
+	report.HighestRank = highestRank
 
-	// Find the day with the most light pixels
-	maxLight := 0
-	selectedDay := -1
-	for i, count := range lightCounts {
-		if count > maxLight {
-			maxLight = count
-			selectedDay = i
+	// Rows without a rank go last, unless the member is already in the list
+	for _, record := range unranked {
+		name := normalizeName(record.MemberName)
+		if _, seen := rankByName[name]; seen {
+			report.DuplicateRows++
+			continue
 		}
+		rankByName[name] = 0
+		merged = append(merged, record)
+		report.UnrankedRows++
 	}
 
-	// Require a minimum threshold to avoid false positives
-	minThreshold := 100 // At least 100 light pixels
-	if selectedDay >= 0 && maxLight > minThreshold {
-		log.Printf("Day detected by color: %s (light pixel count: %d)", days[selectedDay], maxLight)
-		log.Printf("Color counts per day: Mon=%d, Tue=%d, Wed=%d, Thu=%d, Fri=%d, Sat=%d",
-			lightCounts[0], lightCounts[1], lightCounts[2], lightCounts[3], lightCounts[4], lightCounts[5])
-		return days[selectedDay]
+	report.UniqueRows = len(merged)
+	if report.HighestRank > 0 {
+		report.CoveragePercent = math.Round(float64(report.HighestRank-len(report.MissingRanks))/float64(report.HighestRank)*1000) / 10
 	}
+	return merged, report
+}
 
-	log.Printf("Color detection failed: max light count %d below threshold %d", maxLight, minThreshold)
-	log.Printf("Color counts per day: Mon=%d, Tue=%d, Wed=%d, Thu=%d, Fri=%d, Sat=%d",
-		lightCounts[0], lightCounts[1], lightCounts[2], lightCounts[3], lightCounts[4], lightCounts[5])
-	return ""
+// extractStitchedPowerData runs OCR on each screenshot and merges the results.
+// Screenshots that fail are listed in the report rather than failing the whole upload.
+// All screenshots are read as one ranking tab: metric, or the first tab detected when it is empty
+// (screenshots before the first one it is recognized on fail); the metric that was read is returned with the rows.
+// progress, if set, is called with the number of screenshots done after each one.
+func extractStitchedPowerData(images []ocrPageImage, metric string, progress func(done int)) (string, []OCRRecord, StitchReport) {
+	pages := make([][]OCRRecord, len(images))
+	fileNames := make([]string, len(images))
+	pageErrors := make(map[int]string)
+	for i, img := range images {
+		fileNames[i] = img.FileName
+		pageMetric, records, err := extractRankingDataFromImage(img.Data, metric)
+		if metric == "" {
+			metric = pageMetric
+		}
+		if progress != nil {
+			progress(i + 1)
+		}
+		if err != nil {
+			log.Printf("Page %d (%s): %v", i+1, img.FileName, err)
+			pageErrors[i] = err.Error()
+			continue
+		}
+		pages[i] = records
+	}
+
+	merged, report := stitchRankedPages(pages, fileNames)
+	for i := range report.Pages {
+		report.Pages[i].Error = pageErrors[report.Pages[i].Page]
+	}
+	return metric, merged, report
 }
 
-// Extract just the day tab region and detect selected day by color
-func detectDayFromTabRegion(imageData []byte) string {
-	// Decode the image
-	img, format, err := image.Decode(bytes.NewReader(imageData))
-	if err != nil {
-		log.Printf("Failed to decode image for tab detection: %v", err)
-		return ""
+// Process a scrolled set of power ranking screenshots as one consolidated list
+func processPowerScreenshots(w http.ResponseWriter, r *http.Request) {
+	// Check if power tracking is enabled
+	var powerTrackingEnabled bool
+	err := db.QueryRow("SELECT COALESCE(power_tracking_enabled, 0) FROM settings WHERE id = 1").Scan(&powerTrackingEnabled)
+	if err != nil || !powerTrackingEnabled {
+		http.Error(w, "Power tracking is not enabled", http.StatusForbidden)
+		return
 	}
-	log.Printf("Image format for tab detection: %s, bounds: %v", format, img.Bounds())
 
-	bounds := img.Bounds()
-	width := bounds.Dx()
-	height := bounds.Dy()
+	if !requireOCR(w) {
+		return
+	}
 
-	// The tab region is approximately at y=220-290 pixels from the top
-	// Adjust based on image size
-	tabTop := int(float64(height) * 0.08)     // ~8% from top
-	tabBottom := int(float64(height) * 0.105) // ~10.5% from top
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		http.Error(w, "Failed to parse form", http.StatusBadRequest)
+		return
+	}
 
-	// Ensure we don't go out of bounds
-	if tabTop < 0 {
-		tabTop = 0
+	metric := r.FormValue("metric")
+	if !validRankingMetricParam(w, metric) {
+		return
 	}
-	if tabBottom > height {
-		tabBottom = height
+
+	files := r.MultipartForm.File["images"]
+	if len(files) == 0 {
+		http.Error(w, "No image files provided", http.StatusBadRequest)
+		return
 	}
-	if tabBottom <= tabTop {
-		tabBottom = tabTop + 100 // minimum 100px height
+	if len(files) > maxStitchedScreenshots {
+		http.Error(w, fmt.Sprintf("Too many images (maximum %d)", maxStitchedScreenshots), http.StatusBadRequest)
+		return
 	}
 
-	log.Printf("Extracting tab region: y=%d to y=%d (full image: %dx%d)", tabTop, tabBottom, width, height)
+	images := []ocrPageImage{}
+	for _, header := range files {
+		file, err := header.Open()
+		if err != nil {
+			http.Error(w, "Failed to read image", http.StatusBadRequest)
+			return
+		}
+		data, err := io.ReadAll(file)
+		file.Close()
+		if err != nil {
+			http.Error(w, "Failed to read image", http.StatusInternalServerError)
+			return
+		}
+		images = append(images, ocrPageImage{FileName: header.Filename, Data: data})
+	}
 
-	// Create a new image with just the tab region
-	tabRegion := image.NewRGBA(image.Rect(0, 0, width, tabBottom-tabTop))
-	draw.Draw(tabRegion, tabRegion.Bounds(), img, image.Point{0, tabTop}, draw.Src)
+	// OCR runs in the background; the client polls the job for the staged batch and coverage report
+	session, _ := store.Get(r, "session")
+	userID, _ := session.Values["user_id"].(int)
+	jobID, err := enqueueOCRJob("power", "stitch", metric, "", 0, images, userID)
+	writeQueuedJob(w, jobID, err)
+}
 
-	// First try: Detect by color (most reliable for this UI)
-	dayByColor := detectDayByColor(tabRegion)
-	if dayByColor != "" {
-		return dayByColor
+// stitchedBatchMessage summarizes a stitched upload for the uploader
+func stitchedBatchMessage(screenshots int, report StitchReport) string {
+	message := fmt.Sprintf("Stitched %d screenshots into %d rows for review (%d%% of ranks 1-%d covered)",
+		screenshots, report.UniqueRows, int(report.CoveragePercent), report.HighestRank)
+	if len(report.MissingRanks) > 0 {
+		message += fmt.Sprintf(", %d ranks missing", len(report.MissingRanks))
 	}
+	return message
+}
 
-	log.Printf("Color detection failed, falling back to OCR")
+// Get OCR batches, newest first (optionally filtered by ?status= and ?kind=)
+func getOCRBatches(w http.ResponseWriter, r *http.Request) {
+	query := ocrBatchSelectSQL + " WHERE 1=1"
+	args := []interface{}{}
 
-	// Fallback: Try OCR detection
+	if status := r.URL.Query().Get("status"); status != "" {
+		query += " AND b.status = ?"
+		args = append(args, status)
+	}
+	if kind := r.URL.Query().Get("kind"); kind != "" {
+		query += " AND b.kind = ?"
+		args = append(args, kind)
+	}
+	query += " ORDER BY b.created_at DESC, b.id DESC LIMIT 100"
 
-	// Simple preprocessing for tab region: scale 2x and convert to grayscale
-	// Don't use preprocessImageForOCR() as it tries to detect data regions (fails on small images)
-	scaledTab := scaleImage(tabRegion, 2)
-	grayTab := convertToGrayscale(scaledTab)
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
 
-	// Convert to PNG bytes
-	var buf bytes.Buffer
-	if err := png.Encode(&buf, grayTab); err != nil {
-		log.Printf("Failed to encode tab region: %v", err)
-		return ""
+	batches := []OCRBatch{}
+	for rows.Next() {
+		batch, err := scanOCRBatch(rows)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		batches = append(batches, batch)
 	}
 
-	log.Printf("Tab region preprocessed: %dx%d -> %dx%d (scaled 2x, grayscale)",
-		tabRegion.Bounds().Dx(), tabRegion.Bounds().Dy(),
-		grayTab.Bounds().Dx(), grayTab.Bounds().Dy())
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(batches)
+}
 
-	// Run OCR on the tab region
-	client := gosseract.NewClient()
-	defer client.Close()
+// parseOCRBatchID reads the {id} route variable
+func parseOCRBatchID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	batchID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid batch ID", http.StatusBadRequest)
+		return 0, false
+	}
+	return batchID, true
+}
 
-	if err := client.SetImageFromBytes(buf.Bytes()); err != nil {
-		log.Printf("Failed to load tab region for OCR: %v", err)
-		return ""
+// loadPendingOCRBatch loads a batch and rejects requests for batches that were already reviewed
+func loadPendingOCRBatch(w http.ResponseWriter, batchID int) (*OCRBatch, bool) {
+	batch, err := loadOCRBatch(batchID)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Batch not found", http.StatusNotFound)
+		return nil, false
 	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return nil, false
+	}
+	if batch.Status != "pending" {
+		http.Error(w, fmt.Sprintf("Batch has already been %s", batch.Status), http.StatusConflict)
+		return nil, false
+	}
+	return batch, true
+}
 
-	client.SetPageSegMode(gosseract.PSM_SINGLE_LINE)
-	text, err := client.Text()
-	if err != nil || len(strings.TrimSpace(text)) == 0 {
-		log.Printf("Tab region OCR failed or empty")
-		return ""
+// Get a single OCR batch with its rows
+func getOCRBatch(w http.ResponseWriter, r *http.Request) {
+	batchID, ok := parseOCRBatchID(w, r)
+	if !ok {
+		return
 	}
 
-	log.Printf("Tab region OCR text: %s", text)
+	batch, err := loadOCRBatch(batchID)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Batch not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
 
-	// Look for day names in the tab text
-	textLower := strings.ToLower(text)
-	days := []struct {
-		name     string
-		patterns []string
-	}{
-		{"monday", []string{"monday", "mon.", "mon"}},
-		{"tuesday", []string{"tuesday", "tues.", "tues", "tue"}},
-		{"wednesday", []string{"wednesday", "wed.", "wed"}},
-		{"thursday", []string{"thursday", "thur.", "thur", "thu"}},
-		{"friday", []string{"friday", "fri.", "fri"}},
-		{"saturday", []string{"saturday", "sat.", "sat"}},
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(batch)
+}
+
+// Get the original screenshot of an OCR batch
+func getOCRBatchImage(w http.ResponseWriter, r *http.Request) {
+	batchID, ok := parseOCRBatchID(w, r)
+	if !ok {
+		return
 	}
 
-	// Find which day patterns appear in the text
-	// The selected tab usually appears first or more prominently
-	for _, day := range days {
-		for _, pattern := range day.patterns {
-			if strings.Contains(textLower, pattern) {
-				// Check if this appears early in the text (likely the selected/highlighted tab)
-				idx := strings.Index(textLower, pattern)
-				if idx < 100 { // Within first 100 chars suggests it's prominent
-					log.Printf("Detected day '%s' from tab region (pattern: '%s' at position %d)", day.name, pattern, idx)
-					return day.name
-				}
-			}
+	// Multi-image uploads store each screenshot as a page (?page=N, default 0)
+	var imageData []byte
+	var err error
+	if pageParam := r.URL.Query().Get("page"); pageParam != "" {
+		page, convErr := strconv.Atoi(pageParam)
+		if convErr != nil {
+			http.Error(w, "Invalid page", http.StatusBadRequest)
+			return
 		}
+		err = db.QueryRow("SELECT image FROM ocr_batch_images WHERE batch_id = ? AND page_index = ?", batchID, page).Scan(&imageData)
+	} else {
+		err = db.QueryRow(`SELECT COALESCE(b.image, (SELECT image FROM ocr_batch_images WHERE batch_id = b.id ORDER BY page_index LIMIT 1))
+			FROM ocr_batches b WHERE b.id = ?`, batchID).Scan(&imageData)
+	}
+	if err == sql.ErrNoRows || (err == nil && len(imageData) == 0) {
+		http.Error(w, "Image not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
 	}
 
-	return ""
+	w.Header().Set("Content-Type", http.DetectContentType(imageData))
+	w.Write(imageData)
 }
 
-// Extract VS points data from image and detect which day
-func extractVSPointsDataFromImage(imageData []byte) (day string, records []struct {
-	MemberName string `json:"member_name"`
-	Points     int64  `json:"points"`
-}, error error) {
-	// First try to detect the day from the tab region specifically
-	detectedDay := detectDayFromTabRegion(imageData)
+// Update a pending OCR batch: week/day and per-row member, value and skip corrections
+func updateOCRBatch(w http.ResponseWriter, r *http.Request) {
+	batchID, ok := parseOCRBatchID(w, r)
+	if !ok {
+		return
+	}
 
-	// If day detection failed, try text-based detection
-	if detectedDay == "" {
-		log.Printf("Tab region day detection failed, will try OCR fallback")
+	batch, ok := loadPendingOCRBatch(w, batchID)
+	if !ok {
+		return
 	}
 
-	// Decode image for row segmentation
-	img, _, err := image.Decode(bytes.NewReader(imageData))
-	if err != nil {
-		return "", nil, fmt.Errorf("failed to decode image: %v", err)
+	var input struct {
+		WeekDate *string `json:"week_date"`
+		Day      *string `json:"day"`
+		Metric   *string `json:"metric"`
+		Rows     []struct {
+			ID       int   `json:"id"`
+			MemberID *int  `json:"member_id"`
+			Value    int64 `json:"value"`
+			Skip     bool  `json:"skip"`
+		} `json:"rows"`
 	}
 
-	// Analyze screenshot to get regions
-	attrs := analyzeScreenshot(img)
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
-	// Try segmented OCR approach: extract and process individual rows
-	log.Printf("Attempting row-by-row segmented OCR...")
-	records, err = extractVSPointsByRows(img, attrs)
+	tx, err := db.Begin()
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
 
-	if err != nil || len(records) == 0 {
-		log.Printf("Segmented OCR failed (%v), falling back to full image OCR", err)
-		// Fallback to original full-image OCR approach
-		records, err = extractVSPointsFullImage(imageData, attrs)
-		if err != nil {
-			return detectedDay, nil, err
+	if batch.Kind == "vs_points" {
+		if input.WeekDate != nil {
+			weekDate, err := parseDate(*input.WeekDate)
+			if err != nil {
+				http.Error(w, "Invalid week date format (expected YYYY-MM-DD)", http.StatusBadRequest)
+				return
+			}
+			_, err = tx.Exec("UPDATE ocr_batches SET week_date = ? WHERE id = ?",
+				getMondayOfWeek(weekDate).Format("2006-01-02"), batchID)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+		if input.Day != nil {
+			day := strings.ToLower(*input.Day)
+			if vsDayIndex(day) == len(vsDays) {
+				http.Error(w, fmt.Sprintf("Invalid day: %s. Must be monday-sunday", day), http.StatusBadRequest)
+				return
+			}
+			if _, err := tx.Exec("UPDATE ocr_batches SET day = ? WHERE id = ?", day, batchID); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
 		}
 	}
 
-	// If we didn't detect day from tab region, try text-based detection as fallback
-	if detectedDay == "" {
-		// Use current system day as last resort
-		now := time.Now()
-		weekday := now.Weekday()
-		switch weekday {
-		case time.Monday:
-			detectedDay = "monday"
-		case time.Tuesday:
-			detectedDay = "tuesday"
-		case time.Wednesday:
-			detectedDay = "wednesday"
-		case time.Thursday:
-			detectedDay = "thursday"
-		case time.Friday:
-			detectedDay = "friday"
-		case time.Saturday:
-			detectedDay = "saturday"
-		default:
-			// Sunday - default to Monday
-			detectedDay = "monday"
+	// A misdetected ranking tab can be corrected before approving
+	if batch.Kind == "power" && input.Metric != nil {
+		if _, found := findRankingMetric(*input.Metric); !found {
+			http.Error(w, "Invalid metric - must be power, kills or donation", http.StatusBadRequest)
+			return
+		}
+		if _, err := tx.Exec("UPDATE ocr_batches SET metric = ? WHERE id = ?", *input.Metric, batchID); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
 		}
-		log.Printf("Warning: Could not detect day from screenshot, using current system day: %s", detectedDay)
 	}
 
-	if len(records) == 0 {
-		return "", nil, fmt.Errorf("no valid VS point records found in extracted text")
+	current := make(map[int]OCRBatchRow)
+	for _, row := range batch.Rows {
+		current[row.ID] = row
 	}
 
-	return detectedDay, records, nil
-}
+	for _, row := range input.Rows {
+		existing, found := current[row.ID]
+		if !found {
+			http.Error(w, fmt.Sprintf("Row %d does not belong to this batch", row.ID), http.StatusBadRequest)
+			return
+		}
+		if row.MemberID != nil {
+			var memberExists bool
+			if err := tx.QueryRow("SELECT COUNT(*) > 0 FROM members WHERE id = ?", *row.MemberID).Scan(&memberExists); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			if !memberExists {
+				http.Error(w, fmt.Sprintf("Row %d: member %d not found", row.ID, *row.MemberID), http.StatusBadRequest)
+				return
+			}
+		}
 
-// Extract VS points by segmenting image into rows and OCR each row independently
-func extractVSPointsByRows(img image.Image, attrs *ScreenshotAttributes) ([]struct {
-	MemberName string `json:"member_name"`
-	Points     int64  `json:"points"`
-}, error) {
-	bounds := img.Bounds()
-	dataRegion := attrs.DataRegion
-	rowHeight := attrs.RowHeight
-	estimatedRows := attrs.EstimatedRows
+		// Remember which rows a reviewer changed so the matcher can be audited later
+		corrected := existing.Corrected || existing.Value != row.Value ||
+			(existing.MemberID == nil) != (row.MemberID == nil) ||
+			(existing.MemberID != nil && row.MemberID != nil && *existing.MemberID != *row.MemberID)
 
-	if estimatedRows < 1 {
-		estimatedRows = 10
+		_, err := tx.Exec("UPDATE ocr_batch_rows SET member_id = ?, value = ?, skip = ?, corrected = ? WHERE id = ?",
+			row.MemberID, row.Value, row.Skip, corrected, row.ID)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to update row %d: %v", row.ID, err), http.StatusBadRequest)
+			return
+		}
 	}
 
-	records := []struct {
-		MemberName string `json:"member_name"`
-		Points     int64  `json:"points"`
-	}{}
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Failed to save changes", http.StatusInternalServerError)
+		return
+	}
 
-	log.Printf("Processing %d estimated rows with height %d", estimatedRows, rowHeight)
+	getOCRBatch(w, r)
+}
 
-	// Extract and OCR each row
-	for i := 0; i < estimatedRows; i++ {
-		rowTop := dataRegion.Top + (i * rowHeight)
-		rowBottom := rowTop + rowHeight
+// Approve a pending OCR batch and commit its matched rows
+func approveOCRBatch(w http.ResponseWriter, r *http.Request) {
+	batchID, ok := parseOCRBatchID(w, r)
+	if !ok {
+		return
+	}
 
-		// Ensure we don't go out of bounds
-		if rowBottom > dataRegion.Bottom {
-			rowBottom = dataRegion.Bottom
-		}
-		if rowTop >= rowBottom {
-			break
-		}
+	batch, ok := loadPendingOCRBatch(w, batchID)
+	if !ok {
+		return
+	}
 
-		// Extract this row as a separate image
-		rowImg := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), rowBottom-rowTop))
-		draw.Draw(rowImg, rowImg.Bounds(), img, image.Point{0, rowTop}, draw.Src)
+	if batch.Kind == "vs_points" && (batch.WeekDate == nil || batch.Day == nil) {
+		http.Error(w, "Set the week and day for this batch before approving", http.StatusBadRequest)
+		return
+	}
 
-		// Process this row
-		// Split row into segments: rank (15%), name (50%), points (35%)
-		rankWidth := bounds.Dx() * 15 / 100
-		nameStart := rankWidth
-		nameWidth := bounds.Dx() * 50 / 100
-		pointsStart := nameStart + nameWidth
+	// Power batches from before kills and donations were read are power rankings
+	metric, _ := findRankingMetric("power")
+	if batch.Metric != nil {
+		metric, _ = findRankingMetric(*batch.Metric)
+	}
 
-		// Extract name segment
-		nameImg := image.NewRGBA(image.Rect(0, 0, nameWidth, rowBottom-rowTop))
-		draw.Draw(nameImg, nameImg.Bounds(), rowImg, image.Point{nameStart, 0}, draw.Src)
+	session, _ := store.Get(r, "session")
+	userID, _ := session.Values["user_id"].(int)
 
-		// Extract points segment
-		pointsWidth := bounds.Dx() - pointsStart
-		pointsImg := image.NewRGBA(image.Rect(0, 0, pointsWidth, rowBottom-rowTop))
-		draw.Draw(pointsImg, pointsImg.Bounds(), rowImg, image.Point{pointsStart, 0}, draw.Src)
+	var reviewedBy interface{}
+	if userID > 0 {
+		reviewedBy = userID
+	}
 
-		// Scale 2x and convert to grayscale for better OCR
-		scaledName := scaleImage(nameImg, 2)
-		grayName := convertToGrayscale(scaledName)
+	tx, err := db.Begin()
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
 
-		scaledPoints := scaleImage(pointsImg, 2)
-		grayPoints := convertToGrayscale(scaledPoints)
+	// Claim the batch first, so a concurrent approval or rejection can't also commit it
+	result, err := tx.Exec(`UPDATE ocr_batches SET status = 'approved', reviewed_by = ?, reviewed_at = CURRENT_TIMESTAMP
+		WHERE id = ? AND status = 'pending'`, reviewedBy, batchID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if claimed, _ := result.RowsAffected(); claimed == 0 {
+		http.Error(w, "Batch has already been reviewed", http.StatusConflict)
+		return
+	}
 
-		// OCR the name segment
-		var nameBuf bytes.Buffer
-		if err := png.Encode(&nameBuf, grayName); err != nil {
-			log.Printf("Row %d: Failed to encode name segment: %v", i+1, err)
-			continue
-		}
+	// Reload the batch inside the transaction in case it was corrected since it was first read
+	batch, err = loadOCRBatchFrom(tx, batchID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if batch.Kind == "vs_points" && (batch.WeekDate == nil || batch.Day == nil) {
+		http.Error(w, "Set the week and day for this batch before approving", http.StatusBadRequest)
+		return
+	}
 
-		nameClient := gosseract.NewClient()
-		defer nameClient.Close()
-		nameClient.SetImageFromBytes(nameBuf.Bytes())
-		nameClient.SetPageSegMode(gosseract.PSM_SINGLE_LINE)
-		nameText, err := nameClient.Text()
-		if err != nil || len(strings.TrimSpace(nameText)) == 0 {
-			continue // Skip empty rows
+	// Every row must be matched to a member or skipped, so nothing is dropped without a decision
+	unmatched := []string{}
+	for _, row := range batch.Rows {
+		if !row.Skip && row.MemberID == nil {
+			unmatched = append(unmatched, fmt.Sprintf("row %d (%s)", row.RowIndex+1, row.ParsedName))
 		}
+	}
+	if len(unmatched) > 0 {
+		http.Error(w, "Match these rows to a member or skip them before approving: "+strings.Join(unmatched, ", "), http.StatusBadRequest)
+		return
+	}
 
-		// OCR the points segment
-		var pointsBuf bytes.Buffer
-		if err := png.Encode(&pointsBuf, grayPoints); err != nil {
-			log.Printf("Row %d: Failed to encode points segment: %v", i+1, err)
-			continue
-		}
+	// Record values as of the upload, not the review
+	var uploadedAt string
+	if err := tx.QueryRow("SELECT datetime(created_at) FROM ocr_batches WHERE id = ?", batchID).Scan(&uploadedAt); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
 
-		pointsClient := gosseract.NewClient()
-		defer pointsClient.Close()
-		pointsClient.SetImageFromBytes(pointsBuf.Bytes())
-		pointsClient.SetPageSegMode(gosseract.PSM_SINGLE_LINE)
-		pointsText, err := pointsClient.Text()
-		if err != nil || len(strings.TrimSpace(pointsText)) == 0 {
-			log.Printf("Row %d: Name='%s', but no points found", i+1, strings.TrimSpace(nameText))
+	successCount := 0
+	for _, row := range batch.Rows {
+		if row.Skip {
 			continue
 		}
 
-		// Parse the extracted text
-		name := strings.TrimSpace(nameText)
-		// Clean up name (remove alliance tags, rank numbers, etc.)
-		name = cleanPlayerName(name)
-
-		// Parse points
-		pointsStr := strings.TrimSpace(pointsText)
-		pointsStr = strings.ReplaceAll(pointsStr, ",", "")
-		pointsStr = strings.ReplaceAll(pointsStr, ".", "")
-		pointsStr = strings.ReplaceAll(pointsStr, " ", "")
-
-		points, err := strconv.ParseInt(pointsStr, 10, 64)
+		if batch.Kind == "vs_points" {
+			err = upsertVSPoints(tx, *row.MemberID, *batch.WeekDate, *batch.Day, vsEventAllianceDuel, row.Value)
+		} else if metric.Name == "power" {
+			_, _, err = recordPowerSnapshot(tx, *row.MemberID, row.Value, uploadedAt, userID)
+		} else {
+			_, err = tx.Exec(fmt.Sprintf("INSERT INTO %s (member_id, %s, recorded_at) VALUES (?, ?, ?)",
+				metric.Table, metric.Column), *row.MemberID, row.Value, uploadedAt)
+		}
 		if err != nil {
-			log.Printf("Row %d: Failed to parse points '%s': %v", i+1, pointsStr, err)
-			continue
+			http.Error(w, fmt.Sprintf("Failed to save '%s': %v", row.ParsedName, err), http.StatusInternalServerError)
+			return
 		}
+		successCount++
+	}
 
-		if points < 100 { // Sanity check
-			continue
-		}
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Failed to save changes", http.StatusInternalServerError)
+		return
+	}
 
-		log.Printf("Row %d: Name='%s', Points=%d", i+1, name, points)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":       fmt.Sprintf("Approved batch %d: saved %d records", batchID, successCount),
+		"success_count": successCount,
+	})
+}
 
-		records = append(records, struct {
-			MemberName string `json:"member_name"`
-			Points     int64  `json:"points"`
-		}{
-			MemberName: name,
-			Points:     points,
-		})
+// Reject a pending OCR batch without committing anything
+func rejectOCRBatch(w http.ResponseWriter, r *http.Request) {
+	batchID, ok := parseOCRBatchID(w, r)
+	if !ok {
+		return
 	}
 
-	return records, nil
-}
+	if _, ok := loadPendingOCRBatch(w, batchID); !ok {
+		return
+	}
 
-// Fallback: Extract VS points from full image (original method)
-func extractVSPointsFullImage(imageData []byte, attrs *ScreenshotAttributes) ([]struct {
-	MemberName string `json:"member_name"`
-	Points     int64  `json:"points"`
-}, error) {
-	// Preprocess image to filter and enhance relevant regions
-	processedData, err := preprocessImageForOCR(imageData)
+	session, _ := store.Get(r, "session")
+	userID, _ := session.Values["user_id"].(int)
+	var reviewedBy interface{}
+	if userID > 0 {
+		reviewedBy = userID
+	}
+
+	result, err := db.Exec(`UPDATE ocr_batches SET status = 'rejected', reviewed_by = ?, reviewed_at = CURRENT_TIMESTAMP
+		WHERE id = ? AND status = 'pending'`, reviewedBy, batchID)
 	if err != nil {
-		log.Printf("Warning: Image preprocessing failed: %v. Using original image.", err)
-		processedData = imageData // Fallback to original
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if rejected, _ := result.RowsAffected(); rejected == 0 {
+		http.Error(w, "Batch has already been reviewed", http.StatusConflict)
+		return
 	}
 
-	client := gosseract.NewClient()
-	defer client.Close()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Batch rejected"})
+}
 
-	err = client.SetImageFromBytes(processedData)
+// loadOCRBatchPages loads the screenshots of a multi-image upload in page order
+func loadOCRBatchPages(batchID int) ([]ocrPageImage, error) {
+	rows, err := db.Query("SELECT file_name, image FROM ocr_batch_images WHERE batch_id = ? ORDER BY page_index", batchID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to load image: %v", err)
+		return nil, err
 	}
+	defer rows.Close()
 
-	// Try different PSM modes for better recognition
-	var text string
-	psmModes := []gosseract.PageSegMode{
-		gosseract.PSM_AUTO,
-		gosseract.PSM_SINGLE_BLOCK,
-		gosseract.PSM_SPARSE_TEXT,
+	pages := []ocrPageImage{}
+	for rows.Next() {
+		var page ocrPageImage
+		if err := rows.Scan(&page.FileName, &page.Data); err != nil {
+			return nil, err
+		}
+		pages = append(pages, page)
 	}
+	return pages, rows.Err()
+}
 
-	for i, mode := range psmModes {
-		client.SetPageSegMode(mode)
-		extractedText, err := client.Text()
-		if err == nil && len(strings.TrimSpace(extractedText)) > 0 {
-			text = extractedText
-			log.Printf("OCR successful with PSM mode %d (attempt %d)", mode, i+1)
-			break
-		}
-		log.Printf("OCR attempt %d with PSM mode %d failed or empty", i+1, mode)
+// Re-run OCR on a pending batch's stored screenshot(s) in the background, replacing its rows
+func reprocessOCRBatch(w http.ResponseWriter, r *http.Request) {
+	batchID, ok := parseOCRBatchID(w, r)
+	if !ok {
+		return
 	}
 
-	if len(strings.TrimSpace(text)) == 0 {
-		return nil, fmt.Errorf("OCR failed: no text extracted after trying multiple modes")
+	batch, ok := loadPendingOCRBatch(w, batchID)
+	if !ok {
+		return
+	}
+	if !batch.HasImage {
+		http.Error(w, "Batch has no stored image to reprocess", http.StatusBadRequest)
+		return
+	}
+	if !requireOCR(w) {
+		return
 	}
 
-	// Log the extracted text for debugging
-	log.Printf("OCR extracted text:\n%s\n---END OCR---", text)
+	session, _ := store.Get(r, "session")
+	userID, _ := session.Values["user_id"].(int)
+	jobID, err := enqueueOCRJob(batch.Kind, "reprocess", "", "", batchID, nil, userID)
+	writeQueuedJob(w, jobID, err)
+}
 
-	// Parse the OCR text for VS points
-	records := parseVSPointsText(text)
+// reprocessOCRBatchRows re-runs OCR on a pending batch's stored screenshot(s) and replaces its rows.
+// progress, if set, is called with screenshots done and total.
+func reprocessOCRBatchRows(batchID int, progress func(done, total int)) error {
+	batch, err := loadOCRBatch(batchID)
+	if err != nil {
+		return err
+	}
+	if batch.Status != "pending" {
+		return fmt.Errorf("batch has already been %s", batch.Status)
+	}
 
-	return records, nil
-}
+	// A power batch is re-read as its recorded tab, which a reviewer may have corrected.
+	// Power batches from before kills and donations were read are power rankings.
+	batchMetric := "power"
+	if batch.Metric != nil {
+		batchMetric = *batch.Metric
+	}
 
-// Clean player name by removing alliance tags, special characters, etc
-func cleanPlayerName(name string) string {
-	// Remove common OCR artifacts
-	name = strings.ReplaceAll(name, "|", "I")
-	name = strings.ReplaceAll(name, "~", "")
-	name = strings.ReplaceAll(name, "`", "")
+	var records []OCRRecord
+	var detectedDay string
+	if batch.PageCount > 0 {
+		// Re-stitch every screenshot of a multi-image upload
+		pages, err := loadOCRBatchPages(batchID)
+		if err != nil {
+			return err
+		}
+		_, records, _ = extractStitchedPowerData(pages, batchMetric, func(done int) {
+			if progress != nil {
+				progress(done, len(pages))
+			}
+		})
+		if len(records) == 0 {
+			return fmt.Errorf("no valid records found in any screenshot")
+		}
+	} else {
+		var imageData []byte
+		if err := db.QueryRow("SELECT image FROM ocr_batches WHERE id = ?", batchID).Scan(&imageData); err != nil {
+			return err
+		}
 
-	// Remove alliance tags like [NTMs], (NTMs), etc.
-	re := regexp.MustCompile(`\[.*?\]|\(.*?\)`)
-	name = re.ReplaceAllString(name, "")
+		if progress != nil {
+			progress(0, 1)
+		}
+		if batch.Kind == "vs_points" {
+			detectedDay, records, err = extractVSPointsDataFromImage(imageData)
+		} else {
+			_, records, err = extractRankingDataFromImage(imageData, batchMetric)
+		}
+		if err != nil {
+			return err
+		}
+		if progress != nil {
+			progress(1, 1)
+		}
+	}
 
-	// Remove rank numbers at the start (1), (2), etc.
-	re = regexp.MustCompile(`^\d+\)?\s*`)
-	name = re.ReplaceAllString(name, "")
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM ocr_batch_rows WHERE batch_id = ?", batchID); err != nil {
+		return err
+	}
+	if err := insertOCRBatchRows(tx, int64(batchID), batch.Kind, records); err != nil {
+		return err
+	}
+	if detectedDay != "" {
+		if _, err := tx.Exec("UPDATE ocr_batches SET day = ? WHERE id = ?", detectedDay, batchID); err != nil {
+			return err
+		}
+	}
 
-	// Clean up whitespace
-	name = strings.TrimSpace(name)
+	return tx.Commit()
+}
 
-	return name
+// OCRJob is a queued screenshot recognition run. Uploads return the job ID straight away and
+// workers stage the recognized rows in an OCR batch in the background.
+type OCRJob struct {
+	ID            int             `json:"id"`
+	Kind          string          `json:"kind"`   // "vs_points" or "power"
+	Action        string          `json:"action"` // "upload", "stitch" or "reprocess"
+	Status        string          `json:"status"` // "queued", "running", "succeeded" or "failed"
+	Metric        *string         `json:"metric"` // requested ranking tab of a power job, detected when empty
+	WeekDate      *string         `json:"week_date"`
+	BatchID       *int            `json:"batch_id"`
+	ImageCount    int             `json:"image_count"`
+	ProgressDone  int             `json:"progress_done"`
+	ProgressTotal int             `json:"progress_total"`
+	Stage         string          `json:"stage"`
+	Error         *string         `json:"error"`
+	Result        json.RawMessage `json:"result,omitempty"`
+	Attempts      int             `json:"attempts"`
+	CreatedBy     *int            `json:"created_by"`
+	CreatedByName *string         `json:"created_by_name"`
+	CreatedAt     string          `json:"created_at"`
+	StartedAt     *string         `json:"started_at"`
+	FinishedAt    *string         `json:"finished_at"`
 }
 
-// Detect which day is selected from OCR text
-// Looks for day names and context clues to determine the active tab
-func detectSelectedDay(text string) string {
-	textLower := strings.ToLower(text)
+const (
+	maxQueuedOCRJobs      = 100              // uploads are refused while this many jobs are waiting
+	ocrJobPollInterval    = 30 * time.Second // workers also look for work this often, e.g. after a retry
+	defaultOCRWorkers     = 2
+	defaultOCRMaxClients  = 2
+	ocrJobRetentionPeriod = 30 * 24 * time.Hour // finished jobs older than this are removed
+	maxOCRJobAttempts     = 3                   // a job interrupted this many times (e.g. crashing the server) fails
+)
 
-	// Check for presence of day names and "Daily Rank" which indicates VS points screen
-	if !strings.Contains(textLower, "daily") && !strings.Contains(textLower, "rank") {
-		return "" // Not a daily rank screen
-	}
+// ocrJobSignal wakes an idle worker when a job is queued
+var ocrJobSignal = make(chan struct{}, 1)
 
-	// Count occurrences of each day name (the selected day often appears more prominently)
-	days := map[string][]string{
-		"monday":    {"monday", "mon.", "mon"},
-		"tuesday":   {"tuesday", "tues.", "tues"},
-		"wednesday": {"wednesday", "wed.", "wed"},
-		"thursday":  {"thursday", "thur.", "thur"},
-		"friday":    {"friday", "fri.", "fri"},
-		"saturday":  {"saturday", "sat.", "sat"},
+func notifyOCRWorkers() {
+	select {
+	case ocrJobSignal <- struct{}{}:
+	default:
 	}
+}
 
-	dayScores := make(map[string]int)
+// limitedOCREngine caps how many recognitions run at once, whichever worker or request starts them
+type limitedOCREngine struct {
+	OCREngine
+	slots chan struct{}
+}
 
-	for standardDay, variants := range days {
-		for _, variant := range variants {
-			// Count occurrences
-			count := strings.Count(textLower, variant)
-			dayScores[standardDay] += count
+func (e limitedOCREngine) Recognize(imageData []byte, mode OCRPageSegMode, languages []string) (OCRResult, error) {
+	e.slots <- struct{}{}
+	defer func() { <-e.slots }()
+	return e.OCREngine.Recognize(imageData, mode, languages)
+}
 
-			// Higher weight if it appears near the beginning (likely the tab)
-			if idx := strings.Index(textLower, variant); idx >= 0 && idx < 200 {
-				dayScores[standardDay] += 2
-			}
-		}
+// envInt reads a positive integer environment variable
+func envInt(name string, fallback int) int {
+	value, err := strconv.Atoi(os.Getenv(name))
+	if err != nil || value < 1 {
+		return fallback
 	}
+	return value
+}
 
-	// Find the day with the highest score
-	maxScore := 0
-	selectedDay := ""
-	for day, score := range dayScores {
-		if score > maxScore {
-			maxScore = score
-			selectedDay = day
-		}
-	}
+// startOCRWorkers limits concurrent OCR to OCR_MAX_CLIENTS and starts OCR_WORKERS job workers.
+// Jobs left running by a previous process are queued again.
+func startOCRWorkers() {
+	maxClients := envInt("OCR_MAX_CLIENTS", defaultOCRMaxClients)
+	ocrEngine = limitedOCREngine{OCREngine: ocrEngine, slots: make(chan struct{}, maxClients)}
 
-	// Only return if we have strong confidence (score >= 3)
-	if maxScore >= 3 {
-		log.Printf("Detected day from full OCR text: %s (score: %d)", selectedDay, maxScore)
-		return selectedDay
+	// Jobs that keep getting interrupted may be what brings the server down, so they aren't run again
+	result, err := db.Exec(`UPDATE ocr_jobs SET status = 'failed', stage = 'Failed', error = ?,
+		finished_at = CURRENT_TIMESTAMP WHERE status = 'running' AND attempts >= ?`,
+		fmt.Sprintf("Interrupted %d times, the server stopped while it was running", maxOCRJobAttempts), maxOCRJobAttempts)
+	if err != nil {
+		log.Printf("Failed to fail repeatedly interrupted OCR jobs: %v", err)
+	} else if count, _ := result.RowsAffected(); count > 0 {
+		log.Printf("Failed %d OCR jobs interrupted %d times", count, maxOCRJobAttempts)
 	}
 
-	// Low confidence or no detection
-	if maxScore > 0 {
-		log.Printf("Low confidence day detection from OCR: %s (score: %d)", selectedDay, maxScore)
+	result, err = db.Exec(`UPDATE ocr_jobs SET status = 'queued', stage = 'Queued (restarted)', started_at = NULL
+		WHERE status = 'running'`)
+	if err != nil {
+		log.Printf("Failed to requeue interrupted OCR jobs: %v", err)
+	} else if count, _ := result.RowsAffected(); count > 0 {
+		log.Printf("Requeued %d interrupted OCR jobs", count)
 	}
-	return ""
-}
 
-// Parse VS points text(from OCR or manual input)
-func parseVSPointsText(text string) []struct {
-	MemberName string `json:"member_name"`
-	Points     int64  `json:"points"`
-} {
-	var records []struct {
-		MemberName string `json:"member_name"`
-		Points     int64  `json:"points"`
+	_, err = db.Exec("DELETE FROM ocr_jobs WHERE status IN ('succeeded', 'failed') AND finished_at < ?",
+		time.Now().Add(-ocrJobRetentionPeriod).UTC().Format("2006-01-02 15:04:05"))
+	if err != nil {
+		log.Printf("Failed to remove old OCR jobs: %v", err)
 	}
 
-	lines := strings.Split(text, "\n")
-
-	// Pattern for VS points - similar to power rankings but with different number ranges
-	// VS points are typically 6-9 digits (100k to 999M range)
-	// Examples: "Gary6126 30598466", "Gargoland 23660312"
-	rankPattern := regexp.MustCompile(`(?:R[0-9]\s+)?([A-Za-z][A-Za-z0-9_\s]*?)\s+([0-9]{6,})`)
-	simplePattern := regexp.MustCompile(`([A-Za-z][A-Za-z0-9_]+)\s+([0-9]{6,})`)
-
-	// Track seen names to avoid duplicates
-	seenNames := make(map[string]bool)
-
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line == "" {
-			continue
-		}
+	workers := envInt("OCR_WORKERS", defaultOCRWorkers)
+	for i := 0; i < workers; i++ {
+		go ocrWorker()
+	}
+	log.Printf("OCR: %s, %d workers, at most %d concurrent recognitions", ocrEngine.Name(), workers, maxClients)
+	notifyOCRWorkers()
+}
 
-		// Skip lines that are clearly UI elements
-		if len(line) < 5 {
-			continue
+func ocrWorker() {
+	for {
+		jobID, err := claimNextOCRJob()
+		if err != nil {
+			log.Printf("Failed to claim OCR job: %v", err)
 		}
-
-		// Skip common UI text
-		lowerLine := strings.ToLower(line)
-		if strings.Contains(lowerLine, "ranking") ||
-			strings.Contains(lowerLine, "commander") ||
-			strings.Contains(lowerLine, "points") ||
-			strings.Contains(lowerLine, "daily") ||
-			strings.Contains(lowerLine, "weekly") ||
-			strings.Contains(lowerLine, "mon") ||
-			strings.Contains(lowerLine, "tues") ||
-			strings.Contains(lowerLine, "wed") ||
-			strings.Contains(lowerLine, "thur") ||
-			strings.Contains(lowerLine, "fri") ||
-			strings.Contains(lowerLine, "sat") ||
-			strings.Contains(lowerLine, "alliance") ||
-			strings.Contains(lowerLine, "your alliance") {
+		if jobID == 0 {
+			select {
+			case <-ocrJobSignal:
+			case <-time.After(ocrJobPollInterval):
+			}
 			continue
 		}
 
-		// Try patterns
-		matches := rankPattern.FindStringSubmatch(line)
-		if len(matches) == 0 {
-			matches = simplePattern.FindStringSubmatch(line)
-		}
+		// Let another idle worker check for more queued jobs
+		notifyOCRWorkers()
+		runOCRJob(jobID)
+	}
+}
 
-		if len(matches) >= 3 {
-			name := strings.TrimSpace(matches[1])
-			pointsStr := strings.ReplaceAll(matches[2], ",", "")
-			pointsStr = strings.ReplaceAll(pointsStr, " ", "")
+// claimNextOCRJob marks the oldest queued job as running and returns its ID, or 0 when the queue is empty
+func claimNextOCRJob() (int, error) {
+	var jobID int
+	err := db.QueryRow(`UPDATE ocr_jobs
+		SET status = 'running', stage = 'Starting', attempts = attempts + 1, started_at = CURRENT_TIMESTAMP
+		WHERE id = (SELECT id FROM ocr_jobs WHERE status = 'queued' ORDER BY id LIMIT 1) AND status = 'queued'
+		RETURNING id`).Scan(&jobID)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return jobID, err
+}
 
-			points, err := strconv.ParseInt(pointsStr, 10, 64)
+// setOCRJobProgress records how far a running job has got
+func setOCRJobProgress(jobID, done, total int, stage string) {
+	_, err := db.Exec("UPDATE ocr_jobs SET progress_done = ?, progress_total = ?, stage = ? WHERE id = ?",
+		done, total, stage, jobID)
+	if err != nil {
+		log.Printf("OCR job %d: failed to save progress: %v", jobID, err)
+	}
+}
 
-			// Validate: points should be realistic (10k to 999M range), name should be reasonable
-			if err == nil && points >= 10000 && points <= 999999999 &&
-				len(name) >= 3 && len(name) <= 30 && !seenNames[name] {
-				records = append(records, struct {
-					MemberName string `json:"member_name"`
-					Points     int64  `json:"points"`
-				}{
-					MemberName: name,
-					Points:     points,
-				})
-				seenNames[name] = true
-				log.Printf("Parsed VS points: %s -> %d", name, points)
-			}
+// finishOCRJob stores the outcome of a job. Failed jobs keep their screenshots so they can be retried;
+// the screenshots of successful uploads now live in the staged batch.
+func finishOCRJob(jobID int, batchID int64, result map[string]interface{}, jobErr error) {
+	resultJSON, _ := json.Marshal(result)
+	var err error
+	if jobErr != nil {
+		log.Printf("OCR job %d failed: %v", jobID, jobErr)
+		_, err = db.Exec(`UPDATE ocr_jobs SET status = 'failed', stage = 'Failed', error = ?, result = ?,
+			finished_at = CURRENT_TIMESTAMP WHERE id = ?`, jobErr.Error(), string(resultJSON), jobID)
+	} else {
+		_, err = db.Exec(`UPDATE ocr_jobs SET status = 'succeeded', stage = 'Done', error = NULL, result = ?,
+			batch_id = ?, progress_done = progress_total, finished_at = CURRENT_TIMESTAMP WHERE id = ?`,
+			string(resultJSON), batchID, jobID)
+		if err == nil {
+			_, err = db.Exec("DELETE FROM ocr_job_images WHERE job_id = ?", jobID)
 		}
 	}
-
-	return records
+	if err != nil {
+		log.Printf("OCR job %d: failed to save result: %v", jobID, err)
+	}
 }
 
-// HTTP handler to process VS points screenshot
-func processVSPointsScreenshot(w http.ResponseWriter, r *http.Request) {
-	var records []struct {
-		MemberName string `json:"member_name"`
-		Points     int64  `json:"points"`
+// runOCRJob recognizes a job's screenshots and stages the rows, recovering from panics in the OCR code
+func runOCRJob(jobID int) {
+	var batchID int64
+	var result map[string]interface{}
+	var err error
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("OCR crashed: %v", recovered)
+		}
+		finishOCRJob(jobID, batchID, result, err)
+	}()
+
+	job, err := loadOCRJob(jobID)
+	if err != nil {
+		return
+	}
+	images, err := loadOCRJobImages(jobID)
+	if err != nil {
+		return
+	}
+	createdBy := 0
+	if job.CreatedBy != nil {
+		createdBy = *job.CreatedBy
+	}
+	weekDate := ""
+	if job.WeekDate != nil {
+		weekDate = *job.WeekDate
+	}
+	metric := ""
+	if job.Metric != nil {
+		metric = *job.Metric
 	}
-	var detectedDay string
-	var weekDate string
 
-	// Check if this is a multipart form (image upload) or JSON (manual text)
-	contentType := r.Header.Get("Content-Type")
-	if strings.HasPrefix(contentType, "multipart/form-data") {
-		// Handle image upload
-		err := r.ParseMultipartForm(10 << 20) // 10 MB max
-		if err != nil {
-			http.Error(w, "Failed to parse form", http.StatusBadRequest)
+	switch job.Action {
+	case "upload":
+		if len(images) != 1 {
+			err = fmt.Errorf("expected one screenshot, found %d", len(images))
 			return
 		}
-
-		// Get the week parameter (optional, defaults to "current")
-		weekParam := r.FormValue("week")
-		if weekParam == "" {
-			weekParam = "current"
+		setOCRJobProgress(jobID, 0, 1, "Recognizing screenshot")
+		var records []OCRRecord
+		day := ""
+		if job.Kind == "vs_points" {
+			day, records, err = extractVSPointsDataFromImage(images[0].Data)
+		} else {
+			metric, records, err = extractRankingDataFromImage(images[0].Data, metric)
 		}
-
-		file, _, err := r.FormFile("image")
 		if err != nil {
-			http.Error(w, "No image file provided", http.StatusBadRequest)
 			return
 		}
-		defer file.Close()
-
-		imageData, err := io.ReadAll(file)
+		if len(records) == 0 {
+			err = fmt.Errorf("no valid records found")
+			return
+		}
+		setOCRJobProgress(jobID, 1, 1, "Staging rows for review")
+		batchID, err = createOCRBatch(job.Kind, "image", metric, weekDate, day, images[0].Data, nil, "", records, createdBy)
 		if err != nil {
-			http.Error(w, "Failed to read image", http.StatusInternalServerError)
 			return
 		}
+		result, err = stagedBatchResult(batchID)
 
-		detectedDay, records, err = extractVSPointsDataFromImage(imageData)
+	case "stitch":
+		var records []OCRRecord
+		var report StitchReport
+		metric, records, report = extractStitchedPowerData(images, metric, func(done int) {
+			setOCRJobProgress(jobID, done, len(images), fmt.Sprintf("Recognized %d of %d screenshots", done, len(images)))
+		})
+		result = map[string]interface{}{"coverage": report}
+		if len(records) == 0 {
+			err = fmt.Errorf("no valid records found in any screenshot")
+			return
+		}
+		setOCRJobProgress(jobID, len(images), len(images), "Staging rows for review")
+		batchID, err = createOCRBatch("power", "image", metric, "", "", nil, images, "", records, createdBy)
 		if err != nil {
-			http.Error(w, fmt.Sprintf("OCR processing failed: %v", err), http.StatusInternalServerError)
 			return
 		}
+		result["message"] = stitchedBatchMessage(len(images), report)
 
-		// Determine the week date based on the week parameter
-		now := time.Now()
-		if weekParam == "last" {
-			// Subtract 7 days to get last week
-			now = now.AddDate(0, 0, -7)
-		}
-		weekday := now.Weekday()
-		daysFromMonday := int(weekday) - 1
-		if weekday == time.Sunday {
-			daysFromMonday = 6
+	case "reprocess":
+		if job.BatchID == nil {
+			err = fmt.Errorf("the batch to reprocess no longer exists")
+			return
 		}
-		monday := now.AddDate(0, 0, -daysFromMonday)
-		weekDate = monday.Format("2006-01-02")
-	} else {
-		// Handle JSON (manual text or pre-parsed data)
-		var request struct {
-			Records []struct {
-				MemberName string `json:"member_name"`
-				Points     int64  `json:"points"`
-			} `json:"records"`
-			Text string `json:"text"` // Raw text to parse
-			Day  string `json:"day"`  // Optional: specify the day
-			Week string `json:"week"` // Optional: "current" or "last"
+		batchID = int64(*job.BatchID)
+		err = reprocessOCRBatchRows(int(batchID), func(done, total int) {
+			setOCRJobProgress(jobID, done, total, fmt.Sprintf("Recognized %d of %d screenshots", done, total))
+		})
+		if err != nil {
+			return
 		}
+		result, err = stagedBatchResult(batchID)
 
-		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
-			return
+	default:
+		err = fmt.Errorf("unknown OCR job action: %s", job.Action)
+	}
+}
+
+// enqueueOCRJob stores a job with its screenshots and wakes a worker.
+// It returns an error (and no job) when the queue is full.
+func enqueueOCRJob(kind, action, metric, weekDate string, batchID int, images []ocrPageImage, createdBy int) (int64, error) {
+	var queued int
+	if err := db.QueryRow("SELECT COUNT(*) FROM ocr_jobs WHERE status IN ('queued', 'running')").Scan(&queued); err != nil {
+		return 0, err
+	}
+	if queued >= maxQueuedOCRJobs {
+		return 0, errOCRQueueFull
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var createdByID, batchIDValue interface{}
+	if createdBy > 0 {
+		createdByID = createdBy
+	}
+	if batchID > 0 {
+		batchIDValue = batchID
+	}
+	result, err := tx.Exec(`INSERT INTO ocr_jobs (kind, action, metric, week_date, batch_id, progress_total, stage, created_by)
+		VALUES (?, ?, ?, ?, ?, ?, 'Queued', ?)`,
+		kind, action, sql.NullString{String: metric, Valid: metric != ""},
+		sql.NullString{String: weekDate, Valid: weekDate != ""}, batchIDValue, len(images), createdByID)
+	if err != nil {
+		return 0, err
+	}
+	jobID, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	for i, image := range images {
+		_, err := tx.Exec("INSERT INTO ocr_job_images (job_id, page_index, file_name, image) VALUES (?, ?, ?, ?)",
+			jobID, i, image.FileName, image.Data)
+		if err != nil {
+			return 0, err
 		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
 
-		if request.Text != "" {
-			// Parse raw text
-			records = parseVSPointsText(request.Text)
-			detectedDay = detectSelectedDay(request.Text)
-		} else {
-			records = request.Records
+	notifyOCRWorkers()
+	return jobID, nil
+}
+
+var errOCRQueueFull = fmt.Errorf("the OCR queue is full, please try again in a few minutes")
+
+// writeQueuedJob responds 202 Accepted with the new job, or explains why it couldn't be queued
+func writeQueuedJob(w http.ResponseWriter, jobID int64, err error) {
+	if err == errOCRQueueFull {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to queue screenshot: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	job, err := loadOCRJob(int(jobID))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", fmt.Sprintf("/api/ocr-jobs/%d", jobID))
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "Screenshot queued for recognition",
+		"job_id":  jobID,
+		"job":     job,
+	})
+}
+
+const ocrJobSelectSQL = `SELECT j.id, j.kind, j.action, j.status, j.metric, j.week_date, j.batch_id,
+	(SELECT COUNT(*) FROM ocr_job_images WHERE job_id = j.id),
+	j.progress_done, j.progress_total, COALESCE(j.stage, ''), j.error, j.result, j.attempts,
+	j.created_by, u.username, j.created_at, j.started_at, j.finished_at
+	FROM ocr_jobs j
+	LEFT JOIN users u ON j.created_by = u.id`
+
+func scanOCRJob(row interface{ Scan(...interface{}) error }) (OCRJob, error) {
+	var job OCRJob
+	var result sql.NullString
+	err := row.Scan(&job.ID, &job.Kind, &job.Action, &job.Status, &job.Metric, &job.WeekDate, &job.BatchID, &job.ImageCount,
+		&job.ProgressDone, &job.ProgressTotal, &job.Stage, &job.Error, &result, &job.Attempts,
+		&job.CreatedBy, &job.CreatedByName, &job.CreatedAt, &job.StartedAt, &job.FinishedAt)
+	if result.Valid && result.String != "" && result.String != "null" {
+		job.Result = json.RawMessage(result.String)
+	}
+	return job, err
+}
+
+// loadOCRJob returns sql.ErrNoRows when the job doesn't exist
+func loadOCRJob(jobID int) (OCRJob, error) {
+	return scanOCRJob(db.QueryRow(ocrJobSelectSQL+" WHERE j.id = ?", jobID))
+}
+
+func loadOCRJobImages(jobID int) ([]ocrPageImage, error) {
+	rows, err := db.Query("SELECT file_name, image FROM ocr_job_images WHERE job_id = ? ORDER BY page_index", jobID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	images := []ocrPageImage{}
+	for rows.Next() {
+		var image ocrPageImage
+		if err := rows.Scan(&image.FileName, &image.Data); err != nil {
+			return nil, err
 		}
+		images = append(images, image)
+	}
+	return images, rows.Err()
+}
+
+// sessionCanManageRanks reports whether the logged-in user is an admin or an R4/R5 member
+func sessionCanManageRanks(r *http.Request) bool {
+	session, _ := store.Get(r, "session")
+	if isAdmin, ok := session.Values["is_admin"].(bool); ok && isAdmin {
+		return true
+	}
+	if memberID, ok := session.Values["member_id"].(int); ok {
+		var rank string
+		err := db.QueryRow("SELECT rank FROM members WHERE id = ?", memberID).Scan(&rank)
+		return err == nil && (rank == "R4" || rank == "R5")
+	}
+	return false
+}
+
+// loadVisibleOCRJob loads the job in the URL; uploaders see their own jobs and R4/R5 see all of them
+func loadVisibleOCRJob(w http.ResponseWriter, r *http.Request) (OCRJob, bool) {
+	jobID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid job ID", http.StatusBadRequest)
+		return OCRJob{}, false
+	}
+
+	job, err := loadOCRJob(jobID)
+	if err == sql.ErrNoRows {
+		http.Error(w, "OCR job not found", http.StatusNotFound)
+		return job, false
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return job, false
+	}
+
+	session, _ := store.Get(r, "session")
+	userID, _ := session.Values["user_id"].(int)
+	if (job.CreatedBy == nil || *job.CreatedBy != userID) && !sessionCanManageRanks(r) {
+		http.Error(w, "OCR job not found", http.StatusNotFound)
+		return job, false
+	}
+	return job, true
+}
+
+// Get OCR jobs (own jobs, or everyone's for R4/R5), optionally filtered by ?status=
+func getOCRJobs(w http.ResponseWriter, r *http.Request) {
+	query := ocrJobSelectSQL + " WHERE 1 = 1"
+	args := []interface{}{}
+
+	if status := r.URL.Query().Get("status"); status != "" {
+		query += " AND j.status = ?"
+		args = append(args, status)
+	}
+	if !sessionCanManageRanks(r) {
+		session, _ := store.Get(r, "session")
+		userID, _ := session.Values["user_id"].(int)
+		query += " AND j.created_by = ?"
+		args = append(args, userID)
+	}
+	query += " ORDER BY j.id DESC LIMIT 100"
 
-		// Use provided day if available, otherwise use detected day
-		if request.Day != "" {
-			detectedDay = strings.ToLower(request.Day)
-		}
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
 
-		// Determine the week date based on the week parameter
-		weekParam := request.Week
-		if weekParam == "" {
-			weekParam = "current"
-		}
-		now := time.Now()
-		if weekParam == "last" {
-			// Subtract 7 days to get last week
-			now = now.AddDate(0, 0, -7)
-		}
-		weekday := now.Weekday()
-		daysFromMonday := int(weekday) - 1
-		if weekday == time.Sunday {
-			daysFromMonday = 6
+	jobs := []OCRJob{}
+	for rows.Next() {
+		job, err := scanOCRJob(rows)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
 		}
-		monday := now.AddDate(0, 0, -daysFromMonday)
-		weekDate = monday.Format("2006-01-02")
+		jobs = append(jobs, job)
 	}
 
-	if len(records) == 0 {
-		http.Error(w, "No valid VS point records found", http.StatusBadRequest)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(jobs)
+}
+
+// Get the status and progress of an OCR job
+func getOCRJob(w http.ResponseWriter, r *http.Request) {
+	job, ok := loadVisibleOCRJob(w, r)
+	if !ok {
 		return
 	}
 
-	if detectedDay == "" {
-		http.Error(w, "Could not determine which day these VS points are for. Please specify the day manually.", http.StatusBadRequest)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(job)
+}
+
+// Get the result of an OCR job: the staged batch once it succeeded, the error once it failed,
+// or 202 with the job while it is still queued or running
+func getOCRJobResult(w http.ResponseWriter, r *http.Request) {
+	job, ok := loadVisibleOCRJob(w, r)
+	if !ok {
 		return
 	}
 
-	// Normalize day name
-	dayColumn := detectedDay
-	validDays := []string{"monday", "tuesday", "wednesday", "thursday", "friday", "saturday"}
-	isValidDay := false
-	for _, d := range validDays {
-		if dayColumn == d {
-			isValidDay = true
-			break
+	response := map[string]interface{}{}
+	if len(job.Result) > 0 {
+		json.Unmarshal(job.Result, &response)
+	}
+	response["job"] = job
+
+	w.Header().Set("Content-Type", "application/json")
+	switch job.Status {
+	case "succeeded":
+		if job.BatchID == nil {
+			http.Error(w, "The staged batch no longer exists", http.StatusGone)
+			return
+		}
+		batch, err := loadOCRBatch(*job.BatchID)
+		if err == sql.ErrNoRows {
+			http.Error(w, "The staged batch no longer exists", http.StatusGone)
+			return
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
 		}
+		response["batch_id"] = batch.ID
+		response["batch"] = batch
+	case "failed":
+		response["error"] = job.Error
+		w.WriteHeader(http.StatusUnprocessableEntity)
+	default:
+		w.WriteHeader(http.StatusAccepted)
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// Queue a failed OCR job again with its stored screenshots
+func retryOCRJob(w http.ResponseWriter, r *http.Request) {
+	job, ok := loadVisibleOCRJob(w, r)
+	if !ok {
+		return
+	}
+	if job.Status != "failed" {
+		http.Error(w, "Only failed jobs can be retried", http.StatusConflict)
+		return
 	}
-	if !isValidDay {
-		http.Error(w, fmt.Sprintf("Invalid day: %s. Must be monday-saturday", dayColumn), http.StatusBadRequest)
+	if !requireOCR(w) {
 		return
 	}
 
-	// Start transaction
 	tx, err := db.Begin()
 	if err != nil {
-		http.Error(w, "Database error", http.StatusInternalServerError)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 	defer tx.Rollback()
 
-	successCount := 0
-	notFoundMembers := []string{}
-	updatedMembers := []string{}
+	// A retried job counts against the queue limit like a new upload
+	var queued int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM ocr_jobs WHERE status IN ('queued', 'running')").Scan(&queued); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if queued >= maxQueuedOCRJobs {
+		writeQueuedJob(w, 0, errOCRQueueFull)
+		return
+	}
 
-	for _, record := range records {
-		// Try to find member by exact name match first
-		var memberID int
-		var memberName string
-		err := tx.QueryRow("SELECT id, name FROM members WHERE LOWER(name) = LOWER(?)", record.MemberName).Scan(&memberID, &memberName)
+	result, err := tx.Exec(`UPDATE ocr_jobs SET status = 'queued', stage = 'Queued (retry)', error = NULL, result = NULL,
+		progress_done = 0, attempts = 0, started_at = NULL, finished_at = NULL WHERE id = ? AND status = 'failed'`, job.ID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if retried, _ := result.RowsAffected(); retried == 0 {
+		http.Error(w, "Only failed jobs can be retried", http.StatusConflict)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	notifyOCRWorkers()
 
-		if err == sql.ErrNoRows {
-			// Try fuzzy matching
-			rows, err := tx.Query("SELECT id, name FROM members")
-			if err != nil {
-				continue
-			}
+	writeQueuedJob(w, int64(job.ID), nil)
+}
 
-			bestMatch := ""
-			bestScore := 0
-			bestID := 0
+// OCRFixture is a labeled screenshot (or OCR text dump) used to measure recognition accuracy
+type OCRFixture struct {
+	Name         string           `json:"-"`
+	Dir          string           `json:"-"`
+	Kind         string           `json:"kind"` // "power" or "vs_points"
+	Pages        []OCRFixturePage `json:"pages"`
+	Day          string           `json:"day,omitempty"`
+	Rows         []OCRFixtureRow  `json:"rows,omitempty"`
+	MinPrecision *float64         `json:"min_precision,omitempty"`
+	MinRecall    *float64         `json:"min_recall,omitempty"`
+	Notes        string           `json:"notes,omitempty"`
+}
 
-			for rows.Next() {
-				var id int
-				var name string
-				if err := rows.Scan(&id, &name); err != nil {
-					continue
-				}
+// OCRFixturePage is one screenshot of a fixture; Text is the OCR output used when images are not run
+type OCRFixturePage struct {
+	Image string `json:"image,omitempty"`
+	Text  string `json:"text,omitempty"`
+}
 
-				score := calculateSimilarity(record.MemberName, name)
-				if score > bestScore {
-					bestScore = score
-					bestMatch = name
-					bestID = id
-				}
-			}
-			rows.Close()
+// OCRFixtureRow is one expected ranking row
+type OCRFixtureRow struct {
+	Rank  int    `json:"rank,omitempty"`
+	Name  string `json:"name"`
+	Value int64  `json:"value"`
+}
 
-			// Use fuzzy match if similarity is high enough (70%+)
-			if bestScore >= 70 {
-				memberID = bestID
-				memberName = bestMatch
-				log.Printf("Fuzzy matched '%s' to '%s' (similarity: %d%%)", record.MemberName, bestMatch, bestScore)
-			} else {
-				notFoundMembers = append(notFoundMembers, record.MemberName)
-				continue
-			}
+// OCRFieldScore counts expected, recognized and correct values of a single field
+type OCRFieldScore struct {
+	Expected int `json:"expected"`
+	Found    int `json:"found"`
+	Correct  int `json:"correct"`
+}
+
+// Precision is the share of recognized values that are correct
+func (s OCRFieldScore) Precision() float64 {
+	if s.Found == 0 {
+		if s.Expected == 0 {
+			return 1
 		}
+		return 0
+	}
+	return float64(s.Correct) / float64(s.Found)
+}
 
-		// Upsert VS points for this member
-		var existingID int
-		err = tx.QueryRow("SELECT id FROM vs_points WHERE member_id = ? AND week_date = ?",
-			memberID, weekDate).Scan(&existingID)
+// Recall is the share of expected values that were recognized correctly
+func (s OCRFieldScore) Recall() float64 {
+	if s.Expected == 0 {
+		return 1
+	}
+	return float64(s.Correct) / float64(s.Expected)
+}
 
-		if err == sql.ErrNoRows {
-			// Insert new record with the specific day's points
-			query := fmt.Sprintf(`
-				INSERT INTO vs_points (member_id, week_date, %s, updated_at)
-				VALUES (?, ?, ?, CURRENT_TIMESTAMP)`, dayColumn)
-			_, err = tx.Exec(query, memberID, weekDate, record.Points)
-		} else if err == nil {
-			// Update existing record with the specific day's points
-			query := fmt.Sprintf(`
-				UPDATE vs_points 
-				SET %s = ?, updated_at = CURRENT_TIMESTAMP
-				WHERE member_id = ? AND week_date = ?`, dayColumn)
-			_, err = tx.Exec(query, record.Points, memberID, weekDate)
-		}
+func (s *OCRFieldScore) add(other OCRFieldScore) {
+	s.Expected += other.Expected
+	s.Found += other.Found
+	s.Correct += other.Correct
+}
 
-		if err != nil {
-			log.Printf("Failed to save VS points for %s: %v", memberName, err)
-			continue
-		}
+// OCRFixtureResult is the outcome of running the pipeline on one fixture
+type OCRFixtureResult struct {
+	Fixture     *OCRFixture
+	Records     []OCRRecord
+	Name        OCRFieldScore
+	Value       OCRFieldScore
+	Rank        OCRFieldScore
+	DetectedDay string
+	Diffs       []string
+	Err         error
+}
 
-		successCount++
-		updatedMembers = append(updatedMembers, memberName)
-	}
+// DayChecked reports whether the fixture labels a day
+func (r OCRFixtureResult) DayChecked() bool {
+	return r.Fixture.Day != ""
+}
 
-	// Commit transaction
-	if err := tx.Commit(); err != nil {
-		http.Error(w, "Failed to save changes", http.StatusInternalServerError)
-		return
-	}
+// DayCorrect reports whether the detected day matches the label
+func (r OCRFixtureResult) DayCorrect() bool {
+	return r.DetectedDay == r.Fixture.Day
+}
 
-	response := map[string]interface{}{
-		"message":         fmt.Sprintf("Successfully updated VS points for %d members on %s", successCount, detectedDay),
-		"day":             detectedDay,
-		"week_date":       weekDate,
-		"success_count":   successCount,
-		"updated_members": updatedMembers,
+// Failures lists the ways the result falls short of the fixture's thresholds
+func (r OCRFixtureResult) Failures() []string {
+	if r.Err != nil {
+		return []string{r.Err.Error()}
+	}
+	minPrecision, minRecall := 1.0, 1.0
+	if r.Fixture.MinPrecision != nil {
+		minPrecision = *r.Fixture.MinPrecision
+	}
+	if r.Fixture.MinRecall != nil {
+		minRecall = *r.Fixture.MinRecall
 	}
 
-	if len(notFoundMembers) > 0 {
-		response["not_found_members"] = notFoundMembers
-		response["warning"] = fmt.Sprintf("%d members could not be matched to the database", len(notFoundMembers))
+	failures := []string{}
+	fields := []struct {
+		name  string
+		score OCRFieldScore
+	}{{"name", r.Name}, {"value", r.Value}, {"rank", r.Rank}}
+	for _, field := range fields {
+		if field.score.Precision() < minPrecision {
+			failures = append(failures, fmt.Sprintf("%s precision %.2f below %.2f", field.name, field.score.Precision(), minPrecision))
+		}
+		if field.score.Recall() < minRecall {
+			failures = append(failures, fmt.Sprintf("%s recall %.2f below %.2f", field.name, field.score.Recall(), minRecall))
+		}
 	}
+	if r.DayChecked() && !r.DayCorrect() {
+		failures = append(failures, fmt.Sprintf("day detected as %q, expected %q", r.DetectedDay, r.Fixture.Day))
+	}
+	return failures
+}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+// OCREvalSummary aggregates fixture results across a corpus
+type OCREvalSummary struct {
+	Fixtures   int           `json:"fixtures"`
+	Failed     int           `json:"failed"`
+	Name       OCRFieldScore `json:"name"`
+	Value      OCRFieldScore `json:"value"`
+	Rank       OCRFieldScore `json:"rank"`
+	DayTotal   int           `json:"day_total"`
+	DayCorrect int           `json:"day_correct"`
 }
 
-// Process screenshot data with OCR support
-func processPowerScreenshot(w http.ResponseWriter, r *http.Request) {
-	// Check if power tracking is enabled
-	var powerTrackingEnabled bool
-	err := db.QueryRow("SELECT COALESCE(power_tracking_enabled, 0) FROM settings WHERE id = 1").Scan(&powerTrackingEnabled)
-	if err != nil || !powerTrackingEnabled {
-		http.Error(w, "Power tracking is not enabled", http.StatusForbidden)
-		return
+// Add includes a fixture result in the summary
+func (s *OCREvalSummary) Add(result OCRFixtureResult) {
+	s.Fixtures++
+	if len(result.Failures()) > 0 {
+		s.Failed++
+	}
+	s.Name.add(result.Name)
+	s.Value.add(result.Value)
+	s.Rank.add(result.Rank)
+	if result.DayChecked() {
+		s.DayTotal++
+		if result.DayCorrect() {
+			s.DayCorrect++
+		}
 	}
+}
 
-	var records []struct {
-		MemberName string `json:"member_name"`
-		Power      int64  `json:"power"`
+// DayAccuracy is the share of day-labeled fixtures whose day was detected correctly
+func (s OCREvalSummary) DayAccuracy() float64 {
+	if s.DayTotal == 0 {
+		return 1
 	}
+	return float64(s.DayCorrect) / float64(s.DayTotal)
+}
 
-	// Check if this is a multipart form (image upload) or JSON (manual text)
-	contentType := r.Header.Get("Content-Type")
-	if strings.HasPrefix(contentType, "multipart/form-data") {
-		// Handle image upload
-		err := r.ParseMultipartForm(10 << 20) // 10 MB max
+// loadOCRFixtures reads every *.json label file under dir
+func loadOCRFixtures(dir string) ([]*OCRFixture, error) {
+	fixtures := []*OCRFixture{}
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
 		if err != nil {
-			http.Error(w, "Failed to parse form", http.StatusBadRequest)
-			return
+			return err
 		}
-
-		file, _, err := r.FormFile("image")
-		if err != nil {
-			http.Error(w, "No image file provided", http.StatusBadRequest)
-			return
+		if entry.IsDir() || filepath.Ext(path) != ".json" {
+			return nil
 		}
-		defer file.Close()
 
-		imageData, err := io.ReadAll(file)
+		data, err := os.ReadFile(path)
 		if err != nil {
-			http.Error(w, "Failed to read image", http.StatusInternalServerError)
-			return
+			return err
 		}
-
-		records, err = extractPowerDataFromImage(imageData)
+		fixture := &OCRFixture{}
+		if err := json.Unmarshal(data, fixture); err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+		if fixture.Kind != "power" && fixture.Kind != "vs_points" {
+			return fmt.Errorf("%s: kind must be power or vs_points", path)
+		}
+		if len(fixture.Pages) == 0 {
+			return fmt.Errorf("%s: no pages", path)
+		}
+		if fixture.Day != "" && vsDayIndex(fixture.Day) == len(vsDays) {
+			return fmt.Errorf("%s: invalid day %q", path, fixture.Day)
+		}
+		rel, err := filepath.Rel(dir, path)
 		if err != nil {
-			http.Error(w, fmt.Sprintf("OCR processing failed: %v", err), http.StatusInternalServerError)
-			return
+			rel = path
 		}
-	} else {
-		// Handle JSON (manual text or pre-parsed data)
-		var request struct {
-			Records []struct {
-				MemberName string `json:"member_name"`
-				Power      int64  `json:"power"`
-			} `json:"records"`
-			Text string `json:"text"` // Raw text to parse
+		fixture.Name = strings.TrimSuffix(rel, ".json")
+		fixture.Dir = filepath.Dir(path)
+		fixtures = append(fixtures, fixture)
+		return nil
+	})
+	return fixtures, err
+}
+
+// runOCRFixture runs the recognition pipeline on a fixture and scores it against its labels.
+// Rows are read from the screenshots when useImages is set and from the OCR text dumps otherwise;
+// the day is always taken from the screenshot when there is one.
+func runOCRFixture(fixture *OCRFixture, useImages bool) OCRFixtureResult {
+	result := OCRFixtureResult{Fixture: fixture}
+	pages := [][]OCRRecord{}
+	fileNames := []string{}
+
+	for _, page := range fixture.Pages {
+		var imageData []byte
+		if page.Image != "" {
+			data, err := os.ReadFile(filepath.Join(fixture.Dir, page.Image))
+			if err != nil {
+				result.Err = err
+				return result
+			}
+			imageData = data
 		}
 
-		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
-			return
+		var records []OCRRecord
+		day := ""
+		switch {
+		case useImages && imageData != nil && len(fixture.Rows) > 0:
+			var err error
+			if fixture.Kind == "power" {
+				records, err = extractPowerDataFromImage(imageData)
+			} else {
+				day, records, err = extractVSPointsDataFromImage(imageData)
+			}
+			if err != nil {
+				result.Diffs = append(result.Diffs, fmt.Sprintf("%s: %v", page.Image, err))
+			}
+		case page.Text != "":
+			data, err := os.ReadFile(filepath.Join(fixture.Dir, page.Text))
+			if err != nil {
+				result.Err = err
+				return result
+			}
+			text := string(data)
+			if fixture.Kind == "power" {
+				records = parsePowerRankingsText(text)
+			} else {
+				records = parseVSPointsText(text)
+				day = detectSelectedDay(text)
+			}
 		}
 
-		if request.Text != "" {
-			// Parse raw text
-			records = parsePowerRankingsText(request.Text)
-		} else {
-			records = request.Records
+		if fixture.Kind == "vs_points" && day == "" && imageData != nil {
+			day = detectDayFromTabRegion(imageData)
+		}
+		if result.DetectedDay == "" {
+			result.DetectedDay = day
 		}
-	}
 
-	if len(records) == 0 {
-		http.Error(w, "No valid records found", http.StatusBadRequest)
-		return
+		pages = append(pages, records)
+		fileNames = append(fileNames, page.Image+page.Text)
 	}
 
-	tx, err := db.Begin()
-	if err != nil {
-		http.Error(w, "Database error", http.StatusInternalServerError)
-		return
+	if fixture.Kind == "power" {
+		result.Records, _ = stitchRankedPages(pages, fileNames)
+	} else {
+		for _, records := range pages {
+			result.Records = append(result.Records, records...)
+		}
 	}
-	defer tx.Rollback()
 
-	successCount := 0
-	failedCount := 0
-	errors := []string{}
+	if len(fixture.Rows) > 0 {
+		scoreOCRRecords(&result, fixture.Rows, result.Records)
+	}
+	return result
+}
 
-	// Get all member names for fuzzy matching
-	allMembers := []struct {
-		ID   int
-		Name string
-	}{}
-	rows, err := tx.Query("SELECT id, name FROM members")
-	if err == nil {
-		defer rows.Close()
-		for rows.Next() {
-			var m struct {
-				ID   int
-				Name string
+// scoreOCRRecords pairs recognized records with expected rows and counts correct names, values and ranks.
+// Rows are paired on an exact normalized name first and on the closest similar name after that.
+func scoreOCRRecords(result *OCRFixtureResult, expected []OCRFixtureRow, got []OCRRecord) {
+	paired := make([]int, len(expected))
+	used := make([]bool, len(got))
+	for i := range paired {
+		paired[i] = -1
+	}
+	for i, row := range expected {
+		for j, record := range got {
+			if !used[j] && normalizeName(record.MemberName) == normalizeName(row.Name) {
+				paired[i], used[j] = j, true
+				break
+			}
+		}
+	}
+	for i, row := range expected {
+		if paired[i] >= 0 {
+			continue
+		}
+		best, bestScore := -1, 0
+		for j, record := range got {
+			if used[j] {
+				continue
 			}
-			if rows.Scan(&m.ID, &m.Name) == nil {
-				allMembers = append(allMembers, m)
+			if score := calculateSimilarity(row.Name, record.MemberName); score >= powerMatchThreshold && score > bestScore {
+				best, bestScore = j, score
 			}
 		}
+		if best >= 0 {
+			paired[i], used[best] = best, true
+		}
 	}
 
-	for _, record := range records {
-		// Try exact match first
-		var memberID int
-		err := tx.QueryRow("SELECT id FROM members WHERE name = ?", record.MemberName).Scan(&memberID)
-
-		if err != nil {
-			// Try case-insensitive match
-			err = tx.QueryRow("SELECT id FROM members WHERE LOWER(name) = LOWER(?)", record.MemberName).Scan(&memberID)
+	for _, row := range expected {
+		result.Name.Expected++
+		result.Value.Expected++
+		if row.Rank > 0 {
+			result.Rank.Expected++
 		}
+	}
+	for _, record := range got {
+		result.Name.Found++
+		result.Value.Found++
+		if record.Rank > 0 {
+			result.Rank.Found++
+		}
+	}
 
-		if err != nil {
-			// Try fuzzy matching with Levenshtein-like similarity
-			bestMatch := ""
-			bestMatchID := 0
-			bestScore := 0
-
-			for _, member := range allMembers {
-				score := calculateSimilarity(record.MemberName, member.Name)
-				log.Printf("Comparing '%s' with '%s': score=%d%%", record.MemberName, member.Name, score)
-				if score > bestScore {
-					bestScore = score
-					bestMatch = member.Name
-					bestMatchID = member.ID
-				}
-			}
-
-			if bestMatchID > 0 && bestScore >= 50 { // Lowered to 50% similarity threshold
-				memberID = bestMatchID
-				log.Printf("✓ Fuzzy matched '%s' to '%s' (score: %d%%)", record.MemberName, bestMatch, bestScore)
+	for i, row := range expected {
+		if paired[i] < 0 {
+			result.Diffs = append(result.Diffs, fmt.Sprintf("missing: %s %d", row.Name, row.Value))
+			continue
+		}
+		record := got[paired[i]]
+		if record.MemberName == row.Name {
+			result.Name.Correct++
+		} else {
+			result.Diffs = append(result.Diffs, fmt.Sprintf("name: expected %q got %q", row.Name, record.MemberName))
+		}
+		if record.Value == row.Value {
+			result.Value.Correct++
+		} else {
+			result.Diffs = append(result.Diffs, fmt.Sprintf("value: %s expected %d got %d", row.Name, row.Value, record.Value))
+		}
+		if row.Rank > 0 {
+			if record.Rank == row.Rank {
+				result.Rank.Correct++
 			} else {
-				failedCount++
-				if bestMatch != "" {
-					errors = append(errors, fmt.Sprintf("Member '%s' not found (closest: '%s' at %d%%, need 50%%+)", record.MemberName, bestMatch, bestScore))
-				} else {
-					errors = append(errors, fmt.Sprintf("Member '%s' not found (no members in database)", record.MemberName))
-				}
-				continue
+				result.Diffs = append(result.Diffs, fmt.Sprintf("rank: %s expected %d got %d", row.Name, row.Rank, record.Rank))
 			}
 		}
-
-		// Insert power record
-		_, err = tx.Exec("INSERT INTO power_history (member_id, power) VALUES (?, ?)",
-			memberID, record.Power)
-		if err != nil {
-			failedCount++
-			errors = append(errors, fmt.Sprintf("Failed to add record for '%s': %v", record.MemberName, err))
-			continue
+	}
+	for j, record := range got {
+		if !used[j] {
+			result.Diffs = append(result.Diffs, fmt.Sprintf("extra: %s %d", record.MemberName, record.Value))
 		}
+	}
+}
 
-		successCount++
+// runOCREvalCommand implements `ocr-eval [-text] [-quiet] [dir]`: it runs the recognition pipeline
+// on a fixtures folder, prints the differences from the labels and a summary, and returns the exit code
+func runOCREvalCommand(args []string) int {
+	flags := flag.NewFlagSet("ocr-eval", flag.ContinueOnError)
+	textOnly := flags.Bool("text", false, "parse the OCR text dumps instead of running OCR on the screenshots")
+	quiet := flags.Bool("quiet", false, "hide pipeline logging")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	dir := "testdata/ocr"
+	if flags.NArg() > 0 {
+		dir = flags.Arg(0)
+	}
+	if *quiet {
+		log.SetOutput(io.Discard)
 	}
 
-	if err := tx.Commit(); err != nil {
-		http.Error(w, "Failed to save records", http.StatusInternalServerError)
-		return
+	fixtures, err := loadOCRFixtures(dir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load fixtures: %v\n", err)
+		return 1
+	}
+	if len(fixtures) == 0 {
+		fmt.Fprintf(os.Stderr, "No fixtures found in %s\n", dir)
+		return 1
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"message":       fmt.Sprintf("Processed %d records successfully, %d failed", successCount, failedCount),
-		"success_count": successCount,
-		"failed_count":  failedCount,
-		"errors":        errors,
-	})
+	summary := OCREvalSummary{}
+	for _, fixture := range fixtures {
+		result := runOCRFixture(fixture, !*textOnly)
+		summary.Add(result)
+
+		failures := result.Failures()
+		status := "ok"
+		if len(failures) > 0 {
+			status = "FAIL"
+		}
+		fmt.Printf("%-4s %s\n", status, fixture.Name)
+		if len(fixture.Rows) > 0 {
+			fmt.Printf("     name P=%.2f R=%.2f  value P=%.2f R=%.2f  rank P=%.2f R=%.2f\n",
+				result.Name.Precision(), result.Name.Recall(),
+				result.Value.Precision(), result.Value.Recall(),
+				result.Rank.Precision(), result.Rank.Recall())
+		}
+		if result.DayChecked() {
+			fmt.Printf("     day expected %s, detected %s\n", fixture.Day, result.DetectedDay)
+		}
+		for _, diff := range result.Diffs {
+			fmt.Printf("     - %s\n", diff)
+		}
+		for _, failure := range failures {
+			fmt.Printf("     ! %s\n", failure)
+		}
+	}
+
+	fmt.Printf("\n%d fixtures, %d failed\n", summary.Fixtures, summary.Failed)
+	fmt.Printf("name:  precision %.3f  recall %.3f\n", summary.Name.Precision(), summary.Name.Recall())
+	fmt.Printf("value: precision %.3f  recall %.3f\n", summary.Value.Precision(), summary.Value.Recall())
+	fmt.Printf("rank:  precision %.3f  recall %.3f\n", summary.Rank.Precision(), summary.Rank.Recall())
+	fmt.Printf("day:   accuracy %.3f (%d/%d)\n", summary.DayAccuracy(), summary.DayCorrect, summary.DayTotal)
+
+	if summary.Failed > 0 {
+		return 1
+	}
+	return 0
 }
 
 func main() {
+	// Offline accuracy check against labeled screenshots: `lastwar-alliance ocr-eval [dir]`
+	if len(os.Args) > 1 && os.Args[1] == "ocr-eval" {
+		os.Exit(runOCREvalCommand(os.Args[2:]))
+	}
+
 	// Initialize session store first
 	initSessionStore()
 
@@ -6191,6 +16351,9 @@ func main() {
 	}
 	defer db.Close()
 
+	// Screenshot OCR runs on a bounded worker pool
+	startOCRWorkers()
+
 	router := mux.NewRouter()
 
 	// Auth routes (public)
@@ -6211,6 +16374,7 @@ func main() {
 	// API routes (protected)
 	router.HandleFunc("/api/members", authMiddleware(getMembers)).Methods("GET")
 	router.HandleFunc("/api/members/stats", authMiddleware(getMemberStats)).Methods("GET")
+	router.HandleFunc("/api/members/timezone", authMiddleware(updateMemberTimezone)).Methods("PUT")
 	router.HandleFunc("/api/members", authMiddleware(rankManagementMiddleware(createMember))).Methods("POST")
 	router.HandleFunc("/api/members/{id}", authMiddleware(rankManagementMiddleware(updateMember))).Methods("PUT")
 	router.HandleFunc("/api/members/{id}", authMiddleware(rankManagementMiddleware(deleteMember))).Methods("DELETE")
@@ -6227,9 +16391,16 @@ func main() {
 	router.HandleFunc("/api/train-schedules/{id}", authMiddleware(updateTrainSchedule)).Methods("PUT")
 	router.HandleFunc("/api/train-schedules/{id}", authMiddleware(deleteTrainSchedule)).Methods("DELETE")
 
+	// Inactivity report routes (R4/R5 decide on kicks; R5 sets the weights)
+	router.HandleFunc("/api/inactivity-report", authMiddleware(rankManagementMiddleware(getInactivityReport))).Methods("GET")
+	router.HandleFunc("/api/inactivity-report/export", authMiddleware(rankManagementMiddleware(exportInactivityReport))).Methods("GET")
+	router.HandleFunc("/api/inactivity-settings", authMiddleware(rankManagementMiddleware(getInactivitySettings))).Methods("GET")
+	router.HandleFunc("/api/inactivity-settings", authMiddleware(adminR5Middleware(updateInactivitySettings))).Methods("PUT")
+
 	// Awards routes (protected)
 	router.HandleFunc("/api/awards", authMiddleware(getAwards)).Methods("GET")
 	router.HandleFunc("/api/awards", authMiddleware(saveAwards)).Methods("POST")
+	router.HandleFunc("/api/awards/suggestions", authMiddleware(getAwardSuggestions)).Methods("GET")
 	router.HandleFunc("/api/awards/{week}", authMiddleware(deleteWeekAwards)).Methods("DELETE")
 
 	// Award types routes
@@ -6241,7 +16412,14 @@ func main() {
 	// VS points routes (protected)
 	router.HandleFunc("/api/vs-points", authMiddleware(getVSPoints)).Methods("GET")
 	router.HandleFunc("/api/vs-points", authMiddleware(saveVSPoints)).Methods("POST")
+	router.HandleFunc("/api/vs-points/compliance", authMiddleware(getVSCompliance)).Methods("GET")
+	router.HandleFunc("/api/vs-points/warning-message", authMiddleware(generateVSWarningMessage)).Methods("GET")
+	router.HandleFunc("/api/vs-points/analytics/members", authMiddleware(getVSMemberTrends)).Methods("GET")
+	router.HandleFunc("/api/vs-points/analytics/days", authMiddleware(getVSDayStats)).Methods("GET")
+	router.HandleFunc("/api/vs-points/analytics/movers", authMiddleware(getVSMovers)).Methods("GET")
 	router.HandleFunc("/api/vs-points/{week}", authMiddleware(deleteWeekVSPoints)).Methods("DELETE")
+	router.HandleFunc("/api/vs-requirements", authMiddleware(getVSRequirements)).Methods("GET")
+	router.HandleFunc("/api/vs-requirements", authMiddleware(adminR5Middleware(updateVSRequirements))).Methods("PUT")
 	router.HandleFunc("/api/vs-points/process-screenshot", authMiddleware(processVSPointsScreenshot)).Methods("POST")
 
 	// Recommendations routes (protected)
@@ -6257,20 +16435,89 @@ func main() {
 	// Settings routes (protected)
 	router.HandleFunc("/api/settings", authMiddleware(getSettings)).Methods("GET")
 	router.HandleFunc("/api/settings", authMiddleware(adminR5Middleware(updateSettings))).Methods("PUT")
+	router.HandleFunc("/api/message-templates", authMiddleware(getMessageTemplates)).Methods("GET")
+	router.HandleFunc("/api/message-templates/preview", authMiddleware(adminR5Middleware(previewMessageTemplate))).Methods("POST")
+	router.HandleFunc("/api/message-templates/{name}/versions", authMiddleware(getMessageTemplateVersions)).Methods("GET")
+	router.HandleFunc("/api/message-templates/{name}", authMiddleware(adminR5Middleware(saveMessageTemplate))).Methods("PUT")
+	router.HandleFunc("/api/message-templates/{name}", authMiddleware(adminR5Middleware(deleteMessageTemplate))).Methods("DELETE")
+	router.HandleFunc("/api/settings/ocr-languages", authMiddleware(adminR5Middleware(getOCRLanguages))).Methods("GET")
+
+	// Screenshot layout profile routes (R5/Admin only)
+	router.HandleFunc("/api/layout-profiles", authMiddleware(adminR5Middleware(getLayoutProfiles))).Methods("GET")
+	router.HandleFunc("/api/layout-profiles", authMiddleware(adminR5Middleware(createLayoutProfile))).Methods("POST")
+	router.HandleFunc("/api/layout-profiles/detect", authMiddleware(adminR5Middleware(detectLayoutProfile))).Methods("POST")
+	router.HandleFunc("/api/layout-profiles/{id}", authMiddleware(adminR5Middleware(updateLayoutProfile))).Methods("PUT")
+	router.HandleFunc("/api/layout-profiles/{id}", authMiddleware(adminR5Middleware(deleteLayoutProfile))).Methods("DELETE")
 
 	// Rankings routes (protected)
 	router.HandleFunc("/api/rankings", authMiddleware(getMemberRankings)).Methods("GET")
+	router.HandleFunc("/api/rankings/simulate", authMiddleware(adminR5Middleware(simulateRankings))).Methods("POST")
 	router.HandleFunc("/api/member-timelines", authMiddleware(getMemberTimelines)).Methods("GET")
 
+	// Alliance event routes (recurring role rotations; types editable by R5/admin, scheduling by R4/R5)
+	router.HandleFunc("/api/alliance-events", authMiddleware(getAllianceEventTypes)).Methods("GET")
+	router.HandleFunc("/api/alliance-events", authMiddleware(adminR5Middleware(createAllianceEventType))).Methods("POST")
+	router.HandleFunc("/api/alliance-events/{id}", authMiddleware(adminR5Middleware(updateAllianceEventType))).Methods("PUT")
+	router.HandleFunc("/api/alliance-events/{id}", authMiddleware(adminR5Middleware(deleteAllianceEventType))).Methods("DELETE")
+	router.HandleFunc("/api/alliance-events/{id}/schedule", authMiddleware(getAllianceEventSchedule)).Methods("GET")
+	router.HandleFunc("/api/alliance-events/{id}/auto-schedule", authMiddleware(rankManagementMiddleware(autoScheduleAllianceEvent))).Methods("POST")
+	router.HandleFunc("/api/alliance-events/{id}/assignments", authMiddleware(rankManagementMiddleware(saveAllianceEventAssignment))).Methods("PUT")
+	router.HandleFunc("/api/alliance-event-assignments/{id}", authMiddleware(rankManagementMiddleware(deleteAllianceEventAssignment))).Methods("DELETE")
+
+	// Storm buildings routes (catalogue editable by R5/admin)
+	router.HandleFunc("/api/storm-buildings", authMiddleware(getStormBuildings)).Methods("GET")
+	router.HandleFunc("/api/storm-buildings", authMiddleware(adminR5Middleware(createStormBuilding))).Methods("POST")
+	router.HandleFunc("/api/storm-buildings/{id}", authMiddleware(adminR5Middleware(updateStormBuilding))).Methods("PUT")
+	router.HandleFunc("/api/storm-buildings/{id}", authMiddleware(adminR5Middleware(deleteStormBuilding))).Methods("DELETE")
+
 	// Storm assignments routes (protected, R4/R5 only)
 	router.HandleFunc("/api/storm-assignments", authMiddleware(getStormAssignments)).Methods("GET")
 	router.HandleFunc("/api/storm-assignments", authMiddleware(r4r5Middleware(saveStormAssignments))).Methods("POST")
+	router.HandleFunc("/api/storm-assignments/message", authMiddleware(r4r5Middleware(generateStormPlanMessage))).Methods("GET")
+	router.HandleFunc("/api/storm-assignments/member-messages", authMiddleware(r4r5Middleware(generateStormMemberMessages))).Methods("GET")
+	router.HandleFunc("/api/storm-assignments/auto-assign", authMiddleware(r4r5Middleware(autoAssignStorm))).Methods("POST")
 	router.HandleFunc("/api/storm-assignments/{taskForce}", authMiddleware(r4r5Middleware(deleteStormAssignments))).Methods("DELETE")
 
+	// Storm events routes (members sign themselves up, R4/R5 manage events)
+	router.HandleFunc("/api/storm-events", authMiddleware(getStormEvents)).Methods("GET")
+	router.HandleFunc("/api/storm-events", authMiddleware(r4r5Middleware(createStormEvent))).Methods("POST")
+	router.HandleFunc("/api/storm-events/{id}", authMiddleware(getStormEvent)).Methods("GET")
+	router.HandleFunc("/api/storm-events/{id}", authMiddleware(r4r5Middleware(updateStormEvent))).Methods("PUT")
+	router.HandleFunc("/api/storm-events/{id}", authMiddleware(r4r5Middleware(deleteStormEvent))).Methods("DELETE")
+	router.HandleFunc("/api/storm-events/{id}/signup", authMiddleware(signupStormEvent)).Methods("PUT")
+	router.HandleFunc("/api/storm-events/{id}/attendance", authMiddleware(r4r5Middleware(recordStormAttendance))).Methods("PUT")
+	router.HandleFunc("/api/storm-events/{id}/results", authMiddleware(r4r5Middleware(recordStormResults))).Methods("PUT")
+
 	// Power history routes (protected)
 	router.HandleFunc("/api/power-history", authMiddleware(getPowerHistory)).Methods("GET")
 	router.HandleFunc("/api/power-history", authMiddleware(addPowerRecord)).Methods("POST")
 	router.HandleFunc("/api/power-history/process-screenshot", authMiddleware(processPowerScreenshot)).Methods("POST")
+	router.HandleFunc("/api/power-history/process-screenshots", authMiddleware(processPowerScreenshots)).Methods("POST")
+	router.HandleFunc("/api/power-history/outliers", authMiddleware(getPowerOutliers)).Methods("GET")
+	router.HandleFunc("/api/power-history/audit", authMiddleware(rankManagementMiddleware(getPowerAudit))).Methods("GET")
+	router.HandleFunc("/api/power-history/{id}", authMiddleware(rankManagementMiddleware(updatePowerRecord))).Methods("PUT")
+	router.HandleFunc("/api/power-history/{id}", authMiddleware(rankManagementMiddleware(voidPowerRecord))).Methods("DELETE")
+	router.HandleFunc("/api/power-history/analytics/growth", authMiddleware(getPowerGrowth)).Methods("GET")
+	router.HandleFunc("/api/power-history/analytics/alliance", authMiddleware(getPowerTotals)).Methods("GET")
+	router.HandleFunc("/api/power-history/analytics/stagnant", authMiddleware(getStagnantPower)).Methods("GET")
+	router.HandleFunc("/api/ranking-history/{metric}", authMiddleware(getRankingHistory)).Methods("GET")
+	router.HandleFunc("/api/ranking-history/{metric}", authMiddleware(addRankingRecord)).Methods("POST")
+	router.HandleFunc("/api/ranking-history/{metric}/weekly", authMiddleware(getRankingWeeklyDeltas)).Methods("GET")
+
+	// OCR review queue routes (protected, R4/R5 review before data is committed)
+	router.HandleFunc("/api/ocr-batches", authMiddleware(rankManagementMiddleware(getOCRBatches))).Methods("GET")
+	router.HandleFunc("/api/ocr-batches/{id}", authMiddleware(rankManagementMiddleware(getOCRBatch))).Methods("GET")
+	router.HandleFunc("/api/ocr-batches/{id}", authMiddleware(rankManagementMiddleware(updateOCRBatch))).Methods("PUT")
+	router.HandleFunc("/api/ocr-batches/{id}/image", authMiddleware(rankManagementMiddleware(getOCRBatchImage))).Methods("GET")
+	router.HandleFunc("/api/ocr-batches/{id}/approve", authMiddleware(rankManagementMiddleware(approveOCRBatch))).Methods("POST")
+	router.HandleFunc("/api/ocr-batches/{id}/reject", authMiddleware(rankManagementMiddleware(rejectOCRBatch))).Methods("POST")
+	router.HandleFunc("/api/ocr-batches/{id}/reprocess", authMiddleware(rankManagementMiddleware(reprocessOCRBatch))).Methods("POST")
+
+	// OCR job routes (protected, uploaders see their own jobs and R4/R5 see all)
+	router.HandleFunc("/api/ocr-jobs", authMiddleware(getOCRJobs)).Methods("GET")
+	router.HandleFunc("/api/ocr-jobs/{id}", authMiddleware(getOCRJob)).Methods("GET")
+	router.HandleFunc("/api/ocr-jobs/{id}/result", authMiddleware(getOCRJobResult)).Methods("GET")
+	router.HandleFunc("/api/ocr-jobs/{id}/retry", authMiddleware(retryOCRJob)).Methods("POST")
 
 	// Serve static files
 	router.PathPrefix("/").Handler(http.FileServer(http.Dir("./static")))
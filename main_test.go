@@ -0,0 +1,609 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestOCRCorpus runs the recognition pipeline over testdata/ocr and checks each fixture against its
+// labeled thresholds. Screenshots are only OCR'd when OCR_CORPUS_IMAGES=1, since that needs Tesseract
+// with trained data installed; otherwise rows come from the OCR text dumps next to each screenshot.
+func TestOCRCorpus(t *testing.T) {
+	fixtures, err := loadOCRFixtures("testdata/ocr")
+	if err != nil {
+		t.Fatalf("loading fixtures: %v", err)
+	}
+	if len(fixtures) == 0 {
+		t.Fatal("no fixtures in testdata/ocr")
+	}
+	useImages := os.Getenv("OCR_CORPUS_IMAGES") == "1"
+
+	summary := OCREvalSummary{}
+	for _, fixture := range fixtures {
+		fixture := fixture
+		t.Run(fixture.Name, func(t *testing.T) {
+			result := runOCRFixture(fixture, useImages)
+			summary.Add(result)
+			for _, diff := range result.Diffs {
+				t.Logf("diff: %s", diff)
+			}
+			for _, failure := range result.Failures() {
+				t.Error(failure)
+			}
+		})
+	}
+
+	t.Logf("name:  precision %.3f recall %.3f", summary.Name.Precision(), summary.Name.Recall())
+	t.Logf("value: precision %.3f recall %.3f", summary.Value.Precision(), summary.Value.Recall())
+	t.Logf("rank:  precision %.3f recall %.3f", summary.Rank.Precision(), summary.Rank.Recall())
+	t.Logf("day:   accuracy %.3f (%d/%d)", summary.DayAccuracy(), summary.DayCorrect, summary.DayTotal)
+}
+
+func TestScoreOCRRecords(t *testing.T) {
+	fixture := &OCRFixture{Kind: "power"}
+	result := OCRFixtureResult{Fixture: fixture}
+	expected := []OCRFixtureRow{
+		{Rank: 1, Name: "Alpha", Value: 100},
+		{Rank: 2, Name: "Bravo Six", Value: 90},
+		{Rank: 3, Name: "Charlie", Value: 80},
+	}
+	got := []OCRRecord{
+		{MemberName: "Alpha", Value: 100, Rank: 1},
+		{MemberName: "Bravo Sx", Value: 91, Rank: 2},
+		{MemberName: "Zulu", Value: 70, Rank: 4},
+	}
+	scoreOCRRecords(&result, expected, got)
+
+	if result.Name != (OCRFieldScore{Expected: 3, Found: 3, Correct: 1}) {
+		t.Errorf("name score = %+v", result.Name)
+	}
+	if result.Value != (OCRFieldScore{Expected: 3, Found: 3, Correct: 1}) {
+		t.Errorf("value score = %+v", result.Value)
+	}
+	if result.Rank != (OCRFieldScore{Expected: 3, Found: 3, Correct: 2}) {
+		t.Errorf("rank score = %+v", result.Rank)
+	}
+	if len(result.Diffs) != 4 {
+		t.Errorf("diffs = %v", result.Diffs)
+	}
+}
+
+func TestMatchMemberNameUnicode(t *testing.T) {
+	members := []Member{
+		{ID: 1, Name: "🔥Dragon🔥"},
+		{ID: 2, Name: "МАРК"},
+		{ID: 3, Name: "김철수"},
+		{ID: 4, Name: "Amélie"},
+		{ID: 5, Name: "王五"},
+	}
+	tests := []struct {
+		parsed string
+		wantID int
+	}{
+		{"Dragon", 1},     // emoji can't be read
+		{"MAPK", 2},       // Latin look-alikes of Cyrillic letters
+		{"김철주", 3},        // one misread Hangul syllable
+		{"Amelie", 4},     // accent lost by the English language pack
+		{"ＡＭＥＬＩＥ", 4},     // full-width letters
+		{"王五", 5},         // two-character name
+		{"Dragonfly", 1},  // still closest
+		{"Zzyzx Road", 0}, // nothing close
+	}
+	for _, tt := range tests {
+		id, score := matchMemberName(tt.parsed, members)
+		threshold := ocrMatchThreshold("power")
+		if score < threshold {
+			id = 0
+		}
+		if id != tt.wantID {
+			t.Errorf("matchMemberName(%q) = member %d (score %d), want %d", tt.parsed, id, score, tt.wantID)
+		}
+	}
+}
+
+func TestParseMessageTemplateSandbox(t *testing.T) {
+	tests := []struct {
+		name, kind, body string
+		wantErr          string
+	}{
+		{"call", "train_daily", `{{call .Conductor}}`, `function "call" is not allowed`},
+		{"printf", "train_daily", `{{printf "%s" .Date}}`, `function "printf" is not allowed`},
+		{"define", "train_daily", `{{define "x"}}a{{end}}b`, "{{define}} and {{block}} are not supported"},
+		{"block", "train_daily", `{{block "x" .}}a{{end}}`, "{{define}} and {{block}} are not supported"},
+		{"template", "train_daily", `{{template "x"}}`, "{{template}} is not supported"},
+		{"nested ranges", "train_weekly", `{{range .Schedules}}{{range $.Schedules}}{{range $.Schedules}}{{range $.NextInLine}}{{end}}{{end}}{{end}}{{end}}`, "nested at most 3 deep"},
+		{"range over int field", "vs_warning", `{{range .Count}}x{{end}}`, "only works over lists"},
+		{"range over int in a loop", "vs_warning", `{{range $r := .Requirements}}{{range $r.MinPoints}}{{range $r.MinPoints}}{{end}}{{end}}{{end}}`, "only works over lists"},
+		{"range over int variable", "vs_warning", `{{$n := .Count}}{{range $n}}x{{end}}`, "only works over lists"},
+		{"range over index", "vs_warning", `{{range $i, $m := .Members}}{{range $i}}x{{end}}{{end}}`, "only works over lists"},
+		{"range over literal", "vs_warning", `{{range 1000000}}x{{end}}`, "only works over lists"},
+		{"range over helper", "vs_warning", `{{range (slice .Members 0)}}x{{end}}`, "only works over lists"},
+		{"range over string", "train_daily", `{{range .Date}}x{{end}}`, "only works over lists"},
+		{"unknown kind", "nope", `x`, "unknown template kind"},
+		{"too long", "train_daily", strings.Repeat("x", maxMessageTemplateLength+1), "longer than"},
+
+		{"nested lists", "storm_plan", `{{range .Stages}}{{range .Buildings}}{{range .Members}}{{.}}{{end}}{{end}}{{end}}`, ""},
+		{"list variable", "vs_warning", `{{range $i, $m := .Members}}{{range $m.Missed}}{{.}}{{end}}{{range $.Requirements}}{{.Day}}{{end}}{{end}}`, ""},
+		{"list through with", "vs_warning", `{{with .Members}}{{range .}}{{.Name}}{{end}}{{end}}`, ""},
+		{"helpers", "vs_warning", `{{range .Requirements}}{{upper .Day}} {{number .MinPoints}} {{date "2025-01-06" "iso"}}{{end}} {{plural .Count "x" "xs"}} {{add .Count 1}}`, ""},
+	}
+	for _, tt := range tests {
+		_, err := parseMessageTemplate(tt.kind, tt.body)
+		switch {
+		case tt.wantErr == "" && err != nil:
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+		case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+			t.Errorf("%s: error = %v, want %q", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestLegacyMessageTemplates(t *testing.T) {
+	withBackup := trainDailyMessageData{
+		Date:          "2025-01-06",
+		Conductor:     templateMember{"Alice", "R3"},
+		Backup:        &templateMember{"Bob", "R4"},
+		ConductorTime: "15:00 ST",
+		BackupTime:    "16:30 ST",
+	}
+	tests := []struct {
+		kind, legacy string
+		data         interface{}
+		want         string
+	}{
+		{
+			"train_weekly",
+			`Train Schedule - Week {WEEK}\n\n{SCHEDULES}\n\nNext in line:\n{NEXT_3}`,
+			trainWeeklyMessageData{
+				Week: "2025-01-06",
+				Schedules: []trainMessageDay{
+					{Date: "2025-01-06", Conductor: templateMember{"Alice", "R3"}, Backup: &templateMember{"Bob", "R4"}},
+					{Date: "2025-01-07", Conductor: templateMember{"Carol", "R2"}, Backup: &templateMember{"Dave", "R5"}},
+				},
+				NextInLine: []templateMember{{"Erin", "R1"}, {"Frank", "R5"}},
+			},
+			"Train Schedule - Week Jan 6, 2025\n\nMonday: Alice (Backup: Bob)\nTuesday: Carol (Backup: Dave)\n\n\nNext in line:\nErin\nFrank\n",
+		},
+		{
+			"train_daily",
+			"Date: {DATE}\nConductor: {CONDUCTOR_NAME} ({CONDUCTOR_RANK})\nBackup: {BACKUP_NAME} ({BACKUP_RANK})\n{CONDUCTOR_TIME} / {BACKUP_TIME}",
+			withBackup,
+			"Date: Monday, Jan 6, 2025\nConductor: Alice (R3)\nBackup: Bob (R4)\n15:00 ST / 16:30 ST",
+		},
+		{
+			"vs_warning",
+			"VS Minimum Check - Week {WEEK}\n\nDaily minimums:\n{REQUIREMENTS}\n\n{COUNT} members missed the minimum:\n{MEMBERS}",
+			messageTemplateKinds["vs_warning"].Samples()[0],
+			"VS Minimum Check - Week Jan 6, 2025\n\nDaily minimums:\nMonday (Radar Training): 1500000\nSaturday (Enemy Buster): 2000000\n\n" +
+				"2 members missed the minimum:\nAlice - missed Mon\nBob - missed Mon, Sat (3 days in a row)",
+		},
+		{
+			"storm_plan",
+			"DESERT STORM - TASK FORCE {TASK_FORCE}\n{DATE}\n\nBuilding assignments:\n{ASSIGNMENTS}\n\n{COUNT} members",
+			messageTemplateKinds["storm_plan"].Samples()[0],
+			"DESERT STORM - TASK FORCE A\nFriday, Jan 10, 2025\n\nBuilding assignments:\nStage 1:\n- Field Hospital I: Alice, Bob\n\nStage 2:\n- Nuclear Silo: Carol\n\n3 members",
+		},
+		{
+			"storm_plan",
+			"{DATE}",
+			messageTemplateKinds["storm_plan"].Samples()[1],
+			"TBD",
+		},
+		{
+			"storm_member",
+			"Hi {NAME}! You're in Desert Storm Task Force {TASK_FORCE} on {DATE}.\n{ASSIGNMENT}\nThanks!",
+			messageTemplateKinds["storm_member"].Samples()[0],
+			"Hi Alice! You're in Desert Storm Task Force A on Friday, Jan 10, 2025.\nStage 1: Field Hospital I, position 1\nStage 2: Nuclear Silo, position 2\nThanks!",
+		},
+	}
+	for _, tt := range tests {
+		body := legacyMessageTemplate(tt.kind, tt.legacy)
+		if _, err := validateMessageTemplate(tt.kind, body); err != nil {
+			t.Errorf("%s: converted template is invalid: %v\n%s", tt.kind, err, body)
+			continue
+		}
+		tmpl, _ := parseMessageTemplate(tt.kind, body)
+		got, err := renderMessageTemplate(tmpl, tt.data)
+		if err != nil {
+			t.Errorf("%s: render: %v", tt.kind, err)
+		} else if got != tt.want {
+			t.Errorf("%s: got\n%q\nwant\n%q", tt.kind, got, tt.want)
+		}
+	}
+}
+
+func TestMessageTemplatesWithoutBackup(t *testing.T) {
+	render := func(kind, body string, data interface{}) string {
+		t.Helper()
+		tmpl, err := parseMessageTemplate(kind, body)
+		if err != nil {
+			t.Fatalf("%s: %v", kind, err)
+		}
+		out, err := renderMessageTemplate(tmpl, data)
+		if err != nil {
+			t.Fatalf("%s: %v", kind, err)
+		}
+		return out
+	}
+
+	daily := trainDailyMessageData{Date: "2025-01-07", Conductor: templateMember{"Carol", "R2"}, ConductorTime: "15:00 ST", BackupTime: "16:30 ST"}
+	if got := render("train_daily", defaultMessageTemplates["train_daily"], daily); !strings.Contains(got, "Backup Engineer: none today") || strings.Contains(got, "16:30") {
+		t.Errorf("default daily template without backup:\n%s", got)
+	}
+	legacyDaily := legacyMessageTemplate("train_daily", "Backup: {BACKUP_NAME} ({BACKUP_RANK})")
+	if got := render("train_daily", legacyDaily, daily); got != "Backup: - (-)" {
+		t.Errorf("converted daily template without backup = %q", got)
+	}
+
+	weekly := trainWeeklyMessageData{Week: "2025-01-06", Schedules: []trainMessageDay{{Date: "2025-01-07", Conductor: templateMember{"Carol", "R2"}}}}
+	if got := render("train_weekly", "{{range .Schedules}}{{date .Date \"day\"}}: {{.Conductor.Name}}{{with .Backup}} (Backup: {{.Name}}){{else}} (no backup){{end}}{{end}}", weekly); got != "Tuesday: Carol (no backup)" {
+		t.Errorf("weekly template without backup = %q", got)
+	}
+
+	// Every template shipped with the app renders both with and without a backup
+	for kind, body := range defaultMessageTemplates {
+		if _, err := validateMessageTemplate(kind, body); err != nil {
+			t.Errorf("default %s template: %v", kind, err)
+		}
+	}
+	for i, body := range defaultConductorReminders {
+		if _, err := validateMessageTemplate("conductor_reminder", body); err != nil {
+			t.Errorf("default conductor reminder %d: %v", i+1, err)
+		}
+	}
+
+	// A backup used without {{if}} or {{with}} is caught before saving
+	if _, err := validateMessageTemplate("conductor_reminder", "Backup: {{.Backup.Name}}"); err == nil {
+		t.Error("unguarded .Backup.Name was accepted")
+	}
+}
+
+func TestApplyOCRWordFieldsUnknownConfidence(t *testing.T) {
+	words := []OCRWord{
+		{Text: "Alpha", Confidence: 91, Box: image.Rect(0, 0, 50, 10), Line: 1},
+		{Text: "1,234", Confidence: 87, Box: image.Rect(60, 0, 100, 10), Line: 1},
+	}
+	records := []OCRRecord{
+		{MemberName: "Alpha", Value: 1234, RawText: "Alpha 1,234"},
+		{MemberName: "Bravo", Value: 999, RawText: "Bravo 999"}, // line not among the words
+	}
+	applyOCRWordFields(words, records, ocrTransform{Scale: 1})
+
+	if records[0].Confidence != 87 || records[0].NameConfidence != 91 || records[0].ValueConfidence != 87 {
+		t.Errorf("measured record confidence = %v/%v/%v", records[0].Confidence, records[0].NameConfidence, records[0].ValueConfidence)
+	}
+	if records[1].Confidence != ocrConfidenceUnknown || records[1].NameConfidence != ocrConfidenceUnknown || records[1].ValueConfidence != ocrConfidenceUnknown {
+		t.Errorf("unmatched record confidence = %v/%v/%v, want unknown", records[1].Confidence, records[1].NameConfidence, records[1].ValueConfidence)
+	}
+}
+
+func TestGameDay(t *testing.T) {
+	server, _ := time.LoadLocation(defaultGameTimezone)
+	london, _ := time.LoadLocation("Europe/London")
+	tests := []struct {
+		name     string
+		moment   time.Time
+		location *time.Location
+		reset    time.Duration
+		want     string
+	}{
+		{"before midnight ST", time.Date(2025, 3, 10, 1, 30, 0, 0, time.UTC), server, 0, "2025-03-09"},
+		{"after midnight ST", time.Date(2025, 3, 10, 2, 30, 0, 0, time.UTC), server, 0, "2025-03-10"},
+		{"before a 02:00 reset", time.Date(2025, 3, 10, 3, 30, 0, 0, time.UTC), server, 2 * time.Hour, "2025-03-09"},
+		{"after a 02:00 reset", time.Date(2025, 3, 10, 4, 30, 0, 0, time.UTC), server, 2 * time.Hour, "2025-03-10"},
+		{"before the clocks go forward", time.Date(2025, 3, 30, 0, 30, 0, 0, time.UTC), london, time.Hour, "2025-03-29"},
+		{"after the clocks go forward", time.Date(2025, 3, 30, 1, 30, 0, 0, time.UTC), london, time.Hour, "2025-03-30"},
+		{"summer time moves the day", time.Date(2025, 10, 25, 23, 30, 0, 0, time.UTC), london, 0, "2025-10-26"},
+	}
+	for _, tt := range tests {
+		if got := formatDateString(gameDay(tt.moment, tt.location, tt.reset)); got != tt.want {
+			t.Errorf("%s: game day = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestFormatGameTime(t *testing.T) {
+	load := func(names ...string) []*time.Location {
+		zones := []*time.Location{}
+		for _, name := range names {
+			zone, err := time.LoadLocation(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			zones = append(zones, zone)
+		}
+		return zones
+	}
+	server := load(defaultGameTimezone)[0]
+	tests := []struct {
+		name     string
+		location *time.Location
+		date     string
+		clock    string
+		zones    []*time.Location
+		want     string
+	}{
+		{"winter", server, "2025-01-07", "15:00", load("Europe/London", "Europe/Berlin"), "15:00 ST / 17:00 GMT / 18:00 CET"},
+		{"summer", server, "2025-07-01", "15:00", load("Europe/London", "Europe/Berlin"), "15:00 ST / 18:00 BST / 19:00 CEST"},
+		{"day the clocks go forward", server, "2025-03-30", "15:00", load("Europe/London", "America/New_York"), "15:00 ST / 18:00 BST / 13:00 EDT"},
+		{"next day in Tokyo", server, "2025-01-07", "23:00", load("Asia/Tokyo"), "23:00 ST / 10:00 JST Wed"},
+		{"previous day in Los Angeles", server, "2025-01-07", "00:30", load("America/Los_Angeles"), "00:30 ST / 18:30 PST Mon"},
+		{"zone without an abbreviation", server, "2025-01-07", "15:00", load("Asia/Dubai"), "15:00 ST / 21:00 UTC+04"},
+		{"server time is not repeated", server, "2025-01-07", "15:00", load(defaultGameTimezone, "Europe/London"), "15:00 ST / 17:00 GMT"},
+		{"other server zone keeps its label", load("Europe/Berlin")[0], "2025-07-01", "15:00", load("Europe/London"), "15:00 CEST / 14:00 BST"},
+		{"invalid clock", server, "2025-01-07", "3pm", nil, "3pm"},
+	}
+	for _, tt := range tests {
+		date, _ := parseDate(tt.date)
+		if got := formatGameTime(tt.location, date, tt.clock, tt.zones); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestValidateGameTimeSettings(t *testing.T) {
+	settings := Settings{DisplayTimezones: " Europe/London, ,Europe/Berlin "}
+	if msg := validateGameTimeSettings(&settings); msg != "" {
+		t.Fatalf("defaults rejected: %s", msg)
+	}
+	if settings.GameTimezone != defaultGameTimezone || settings.GameResetTime != "00:00" ||
+		settings.TrainConductorTime != "15:00" || settings.TrainBackupTime != "16:30" {
+		t.Errorf("defaults = %q %q %q %q", settings.GameTimezone, settings.GameResetTime, settings.TrainConductorTime, settings.TrainBackupTime)
+	}
+	if settings.DisplayTimezones != "Europe/London,Europe/Berlin" {
+		t.Errorf("display_timezones = %q", settings.DisplayTimezones)
+	}
+
+	tests := []struct {
+		name     string
+		settings Settings
+		want     string
+	}{
+		{"reset offset", Settings{GameResetTime: "02:00"}, ""},
+		{"unknown game zone", Settings{GameTimezone: "Mars/Olympus"}, "Unknown game_timezone: Mars/Olympus"},
+		{"invalid reset", Settings{GameResetTime: "2am"}, "Invalid game_reset_time (expected HH:MM)"},
+		{"invalid conductor time", Settings{TrainConductorTime: "25:00"}, "Invalid train_conductor_time (expected HH:MM)"},
+		{"invalid backup time", Settings{TrainBackupTime: "16.30"}, "Invalid train_backup_time (expected HH:MM)"},
+		{"unknown display zone", Settings{DisplayTimezones: "Europe/London,UK"}, "Unknown time zone in display_timezones: UK"},
+	}
+	for _, tt := range tests {
+		if got := validateGameTimeSettings(&tt.settings); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestParseRankingTextHeaderWords(t *testing.T) {
+	power, _ := findRankingMetric("power")
+	kills, _ := findRankingMetric("kills")
+	tests := []struct {
+		metric RankingMetric
+		text   string
+		want   []string
+	}{
+		{power, "Alliance Ranking\nPower Kills Donation\nRanking Commander Power\n1 PowerRanger 63785308\n2 Killswitch R4 s1926102",
+			[]string{"PowerRanger", "Killswitch"}},
+		{kills, "Ranking Commander Kills\n1 Donation Queen 125,400\n2 Commander Kyle 98211",
+			[]string{"Donation Queen", "Commander Kyle"}},
+	}
+	for _, tt := range tests {
+		records := parseRankingText(tt.text, tt.metric)
+		names := []string{}
+		for _, record := range records {
+			names = append(names, record.MemberName)
+		}
+		if strings.Join(names, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("%s: names = %v, want %v", tt.metric.Name, names, tt.want)
+		}
+	}
+}
+
+// openTestDB points db at a fresh database in a temporary directory with the full schema
+func openTestDB(t *testing.T) {
+	t.Helper()
+	t.Setenv("DATABASE_PATH", filepath.Join(t.TempDir(), "alliance.db"))
+	if err := initDB(); err != nil {
+		t.Fatalf("initDB: %v", err)
+	}
+	t.Cleanup(func() {
+		db.Close()
+		db = nil
+	})
+}
+
+func TestRecordPowerSnapshotLatestWins(t *testing.T) {
+	openTestDB(t)
+
+	type snapshot struct {
+		power int64
+		at    string
+	}
+	tests := []struct {
+		name         string
+		snapshots    []snapshot
+		wantReplaced []bool
+		want         []snapshot // stored rows in date order
+		wantAudits   int
+	}{
+		{"later reading replaces the day's earlier one",
+			[]snapshot{{100, "2026-10-01 08:00:00"}, {120, "2026-10-01 20:00:00"}},
+			[]bool{false, true}, []snapshot{{120, "2026-10-01 20:00:00"}}, 1},
+		{"earlier reading of a day is ignored",
+			[]snapshot{{120, "2026-10-01 20:00:00"}, {100, "2026-10-01 08:00:00"}},
+			[]bool{false, false}, []snapshot{{120, "2026-10-01 20:00:00"}}, 0},
+		{"same power moves the time without an audit entry",
+			[]snapshot{{100, "2026-10-01 08:00:00"}, {100, "2026-10-01 20:00:00"}},
+			[]bool{false, false}, []snapshot{{100, "2026-10-01 20:00:00"}}, 0},
+		{"readings on different days are all kept",
+			[]snapshot{{100, "2026-10-01 23:00:00"}, {90, "2026-10-02 01:00:00"}},
+			[]bool{false, false}, []snapshot{{100, "2026-10-01 23:00:00"}, {90, "2026-10-02 01:00:00"}}, 0},
+	}
+	for _, tt := range tests {
+		result, err := db.Exec("INSERT INTO members (name, rank) VALUES (?, 'R3')", tt.name)
+		if err != nil {
+			t.Fatalf("%s: adding member: %v", tt.name, err)
+		}
+		id, _ := result.LastInsertId()
+		memberID := int(id)
+
+		for i, s := range tt.snapshots {
+			tx, err := db.Begin()
+			if err != nil {
+				t.Fatalf("%s: %v", tt.name, err)
+			}
+			_, replaced, err := recordPowerSnapshot(tx, memberID, s.power, s.at, 0)
+			if err != nil {
+				tx.Rollback()
+				t.Fatalf("%s: snapshot %d: %v", tt.name, i+1, err)
+			}
+			if err := tx.Commit(); err != nil {
+				t.Fatalf("%s: %v", tt.name, err)
+			}
+			if replaced != tt.wantReplaced[i] {
+				t.Errorf("%s: snapshot %d replaced = %v, want %v", tt.name, i+1, replaced, tt.wantReplaced[i])
+			}
+		}
+
+		rows, err := db.Query("SELECT power, datetime(recorded_at) FROM power_history WHERE member_id = ? ORDER BY recorded_at", memberID)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		got := []snapshot{}
+		for rows.Next() {
+			var s snapshot
+			if err := rows.Scan(&s.power, &s.at); err != nil {
+				t.Fatalf("%s: %v", tt.name, err)
+			}
+			got = append(got, s)
+		}
+		rows.Close()
+		if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("%s: stored %v, want %v", tt.name, got, tt.want)
+		}
+
+		var audits int
+		if err := db.QueryRow("SELECT COUNT(*) FROM power_history_audit WHERE member_id = ?", memberID).Scan(&audits); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if audits != tt.wantAudits {
+			t.Errorf("%s: %d audit entries, want %d", tt.name, audits, tt.wantAudits)
+		}
+	}
+}
+
+func TestFindPowerOutliers(t *testing.T) {
+	member := func(id int, powers ...int64) powerAnalyticsMember {
+		m := powerAnalyticsMember{ID: id, Name: fmt.Sprintf("M%d", id)}
+		for i, power := range powers {
+			m.Points = append(m.Points, PowerPoint{RecordID: id*100 + i, Date: fmt.Sprintf("2026-10-%02d", i+1), Power: power})
+		}
+		return m
+	}
+	tests := []struct {
+		name    string
+		members []powerAnalyticsMember
+		want    []string // "record kind expected change"
+	}{
+		{"steady growth", []powerAnalyticsMember{member(1, 100, 110, 120, 130)}, []string{}},
+		{"drop against the median of the last three", []powerAnalyticsMember{member(1, 100, 110, 120, 130, 40)},
+			[]string{"104 drop 120 -66.7"}},
+		{"median of two readings is their mean", []powerAnalyticsMember{member(1, 100, 120, 30)},
+			[]string{"102 drop 110 -72.7"}},
+		{"an outlier stays out of the baseline", []powerAnalyticsMember{member(1, 100, 100, 100, 10, 100)},
+			[]string{"103 drop 100 -90"}},
+		{"rise", []powerAnalyticsMember{member(1, 100, 100, 250)}, []string{"102 rise 100 150"}},
+		{"below both thresholds", []powerAnalyticsMember{member(1, 100, 60, 150)}, []string{}},
+		{"newest first across members", []powerAnalyticsMember{member(1, 100, 10), member(2, 100, 100, 300)},
+			[]string{"202 rise 100 200", "101 drop 100 -90"}},
+	}
+	for _, tt := range tests {
+		got := []string{}
+		for _, o := range findPowerOutliers(tt.members, 50, 100) {
+			got = append(got, fmt.Sprintf("%d %s %d %g", o.RecordID, o.Kind, o.Expected, o.ChangePercent))
+		}
+		if strings.Join(got, ", ") != strings.Join(tt.want, ", ") {
+			t.Errorf("%s: outliers = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestProposeStormAssignments(t *testing.T) {
+	building := func(id string, stage int, priority string, capacity int) StormBuilding {
+		return StormBuilding{ID: id, Name: id, Stage: stage, Priority: priority, Capacity: capacity}
+	}
+	candidate := func(power int64, taskForce string) stormCandidate {
+		return stormCandidate{StormProposalMember: StormProposalMember{MemberID: int(power), Name: fmt.Sprintf("P%d", power), Power: power}, TaskForce: taskForce}
+	}
+	tests := []struct {
+		name       string
+		buildings  []StormBuilding
+		candidates []stormCandidate
+		want       []string // per task force: building[members]...
+		wantSubs   string
+	}{
+		{"capacity fills the highest priority first and leaves substitutes",
+			[]StormBuilding{building("b", 1, "LOW", 1), building("a", 1, "CRITICAL", 2)},
+			[]stormCandidate{candidate(10, ""), candidate(20, ""), candidate(30, ""), candidate(40, ""), candidate(50, ""), candidate(60, ""), candidate(70, "")},
+			[]string{"A: a[P70 P40] b[P30]", "B: a[P60 P50] b[P20]"}, "P10"},
+		{"task force preference is kept even when it leaves a member out",
+			[]StormBuilding{building("a", 1, "CRITICAL", 2)},
+			[]stormCandidate{candidate(90, "B"), candidate(80, "B"), candidate(70, "B"), candidate(60, ""), candidate(50, "")},
+			[]string{"A: a[P60 P50]", "B: a[P90 P80]"}, "P70"},
+		{"one building per member in each stage",
+			[]StormBuilding{building("x", 1, "CRITICAL", 1), building("y", 1, "HIGH", 1), building("z", 2, "CRITICAL", 2)},
+			[]stormCandidate{candidate(40, ""), candidate(30, ""), candidate(20, ""), candidate(10, "")},
+			[]string{"A: x[P40] y[P10] z[P40 P10]", "B: x[P30] y[P20] z[P30 P20]"}, ""},
+		{"buildings of equal priority get balanced power",
+			[]StormBuilding{building("a", 1, "HIGH", 2), building("b", 1, "HIGH", 2)},
+			[]stormCandidate{candidate(100, "A"), candidate(90, "A"), candidate(60, "A"), candidate(50, "A")},
+			[]string{"A: a[P100 P50] b[P90 P60]", "B: a[] b[]"}, ""},
+	}
+	for _, tt := range tests {
+		result := proposeStormAssignments(tt.buildings, tt.candidates)
+
+		capacity := make(map[string]int)
+		for _, b := range tt.buildings {
+			capacity[b.ID] = b.Capacity
+		}
+		got := []string{}
+		for _, tf := range result.TaskForces {
+			layout := []string{}
+			perStage := make(map[string]bool)
+			for _, b := range tf.Buildings {
+				names := []string{}
+				for i, m := range b.Members {
+					names = append(names, m.Name)
+					if m.Position != i+1 {
+						t.Errorf("%s: %s %s has %s at position %d, want %d", tt.name, tf.TaskForce, b.BuildingID, m.Name, m.Position, i+1)
+					}
+					key := fmt.Sprintf("%d/%d", b.Stage, m.MemberID)
+					if perStage[key] {
+						t.Errorf("%s: %s has %s twice in stage %d", tt.name, tf.TaskForce, m.Name, b.Stage)
+					}
+					perStage[key] = true
+				}
+				if len(b.Members) > capacity[b.BuildingID] {
+					t.Errorf("%s: %s %s has %d members, capacity %d", tt.name, tf.TaskForce, b.BuildingID, len(b.Members), capacity[b.BuildingID])
+				}
+				layout = append(layout, fmt.Sprintf("%s[%s]", b.BuildingID, strings.Join(names, " ")))
+			}
+			got = append(got, tf.TaskForce+": "+strings.Join(layout, " "))
+		}
+		if strings.Join(got, "; ") != strings.Join(tt.want, "; ") {
+			t.Errorf("%s: proposal = %v, want %v", tt.name, got, tt.want)
+		}
+
+		subs := []string{}
+		for _, m := range result.Substitutes {
+			subs = append(subs, m.Name)
+		}
+		if strings.Join(subs, " ") != tt.wantSubs {
+			t.Errorf("%s: substitutes = %v, want %s", tt.name, subs, tt.wantSubs)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+//go:build !tesseract && !noocr
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"image"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// tesseractCLIEngine runs the tesseract command for each image, so the server builds without CGO.
+// TESSERACT_CMD overrides the command (default "tesseract" on the PATH).
+type tesseractCLIEngine struct {
+	command string
+}
+
+// ocrCommandTimeout bounds a single tesseract run
+const ocrCommandTimeout = 60 * time.Second
+
+func newOCREngine() OCREngine {
+	command := os.Getenv("TESSERACT_CMD")
+	if command == "" {
+		command = "tesseract"
+	}
+	return tesseractCLIEngine{command: command}
+}
+
+func (e tesseractCLIEngine) Name() string {
+	return "tesseract (command line)"
+}
+
+func (e tesseractCLIEngine) Available() error {
+	if _, err := exec.LookPath(e.command); err != nil {
+		return fmt.Errorf("%s not found; install tesseract-ocr or set TESSERACT_CMD", e.command)
+	}
+	return nil
+}
+
+// Languages lists the installed language packs (tesseract --list-langs)
+func (e tesseractCLIEngine) Languages() ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), ocrCommandTimeout)
+	defer cancel()
+
+	output, err := exec.CommandContext(ctx, e.command, "--list-langs").CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("%s --list-langs failed: %v: %s", e.command, err, strings.TrimSpace(string(output)))
+	}
+	// The first line is a header: List of available languages in "/usr/share/tessdata/" (3):
+	languages := []string{}
+	for _, line := range strings.Split(string(output), "\n")[1:] {
+		if line = strings.TrimSpace(line); line != "" {
+			languages = append(languages, line)
+		}
+	}
+	return languages, nil
+}
+
+func (e tesseractCLIEngine) Recognize(imageData []byte, mode OCRPageSegMode, languages []string) (OCRResult, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), ocrCommandTimeout)
+	defer cancel()
+
+	// Read the image from stdin and write word-level TSV to stdout
+	cmd := exec.CommandContext(ctx, e.command, "stdin", "stdout",
+		"-l", strings.Join(languages, "+"), "--psm", strconv.Itoa(int(mode)), "tsv")
+	cmd.Stdin = bytes.NewReader(imageData)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return OCRResult{}, fmt.Errorf("%s failed: %v: %s", e.command, err, strings.TrimSpace(stderr.String()))
+	}
+
+	words, err := parseTesseractTSV(&stdout)
+	if err != nil {
+		return OCRResult{}, err
+	}
+	return OCRResult{Text: ocrTextFromWords(words), Words: words}, nil
+}
+
+// parseTesseractTSV reads the words from tesseract's TSV output
+// (level, page_num, block_num, par_num, line_num, word_num, left, top, width, height, conf, text)
+func parseTesseractTSV(r io.Reader) ([]OCRWord, error) {
+	reader := csv.NewReader(r)
+	reader.Comma = '\t'
+	reader.LazyQuotes = true
+	reader.FieldsPerRecord = -1
+
+	words := []OCRWord{}
+	header := true
+	for {
+		fields, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read tesseract output: %v", err)
+		}
+		if header {
+			header = false
+			continue
+		}
+		if len(fields) < 12 || fields[0] != "5" || strings.TrimSpace(fields[11]) == "" {
+			continue // Only word-level rows carry text
+		}
+
+		numbers := make([]int, 10)
+		for i := range numbers {
+			numbers[i], _ = strconv.Atoi(fields[i])
+		}
+		confidence, _ := strconv.ParseFloat(fields[10], 64)
+		left, top, width, height := numbers[6], numbers[7], numbers[8], numbers[9]
+		words = append(words, OCRWord{
+			Text:       fields[11],
+			Box:        image.Rect(left, top, left+width, top+height),
+			Confidence: confidence,
+			Block:      numbers[2],
+			Paragraph:  numbers[3],
+			Line:       numbers[4],
+		})
+	}
+	return words, nil
+}
+
+// ocrTextFromWords rebuilds page text from words, one text line per output line
+func ocrTextFromWords(words []OCRWord) string {
+	var text strings.Builder
+	for i, word := range words {
+		if i > 0 {
+			previous := words[i-1]
+			if previous.Block != word.Block || previous.Paragraph != word.Paragraph || previous.Line != word.Line {
+				text.WriteString("\n")
+			} else {
+				text.WriteString(" ")
+			}
+		}
+		text.WriteString(word.Text)
+	}
+	return text.String()
+}
@@ -0,0 +1,30 @@
+//go:build noocr
+
+package main
+
+import "errors"
+
+// noOCREngine is used in builds without OCR support; screenshot endpoints respond with 501
+type noOCREngine struct{}
+
+var errOCRNotBuilt = errors.New("this server was built without OCR support (-tags noocr)")
+
+func newOCREngine() OCREngine {
+	return noOCREngine{}
+}
+
+func (noOCREngine) Name() string {
+	return "none"
+}
+
+func (noOCREngine) Available() error {
+	return errOCRNotBuilt
+}
+
+func (noOCREngine) Languages() ([]string, error) {
+	return nil, errOCRNotBuilt
+}
+
+func (noOCREngine) Recognize(imageData []byte, mode OCRPageSegMode, languages []string) (OCRResult, error) {
+	return OCRResult{}, errOCRNotBuilt
+}
@@ -0,0 +1,69 @@
+//go:build tesseract && !noocr
+
+package main
+
+import (
+	"strings"
+
+	gosseract "github.com/otiai10/gosseract/v2"
+)
+
+// tesseractEngine runs Tesseract in-process through gosseract (needs CGO and libtesseract)
+type tesseractEngine struct{}
+
+func newOCREngine() OCREngine {
+	return tesseractEngine{}
+}
+
+func (tesseractEngine) Name() string {
+	return "tesseract (gosseract)"
+}
+
+func (tesseractEngine) Available() error {
+	return nil
+}
+
+func (tesseractEngine) Languages() ([]string, error) {
+	return gosseract.GetAvailableLanguages()
+}
+
+func (tesseractEngine) Recognize(imageData []byte, mode OCRPageSegMode, languages []string) (OCRResult, error) {
+	client := gosseract.NewClient()
+	defer client.Close()
+
+	if err := client.SetLanguage(languages...); err != nil {
+		return OCRResult{}, err
+	}
+	if err := client.SetImageFromBytes(imageData); err != nil {
+		return OCRResult{}, err
+	}
+	if err := client.SetPageSegMode(gosseract.PageSegMode(mode)); err != nil {
+		return OCRResult{}, err
+	}
+
+	text, err := client.Text()
+	if err != nil {
+		return OCRResult{}, err
+	}
+	result := OCRResult{Text: text}
+
+	boxes, err := client.GetBoundingBoxesVerbose()
+	if err != nil {
+		// The text is still usable without word positions
+		return result, nil
+	}
+	for _, box := range boxes {
+		if strings.TrimSpace(box.Word) == "" {
+			continue
+		}
+		result.Words = append(result.Words, OCRWord{
+			Text:       box.Word,
+			Box:        box.Box,
+			Confidence: box.Confidence,
+			Block:      box.BlockNum,
+			Paragraph:  box.ParNum,
+			Line:       box.LineNum,
+		})
+	}
+	return result, nil
+}
@@ -8,6 +8,7 @@ let allMembers = [];
 let currentAwards = {};
 let allHistory = [];
 let currentUsername = '';
+let gameToday = null; // current game day (server time), from check-auth
 let activeAwardTypes = new Set(); // All active awards
 
 // Check authentication
@@ -22,6 +23,7 @@ async function checkAuth() {
         }
         
         currentUsername = data.username;
+        gameToday = new Date(data.game_today + 'T00:00:00');
         let displayText = `👤 ${currentUsername}`;
         if (data.rank) {
             displayText += ` (${data.rank})`;
@@ -125,7 +127,7 @@ function formatDisplayDate(dateStr) {
 
 // Initialize current week
 function initializeWeek() {
-    currentWeekDate = getMostRecentMonday();
+    currentWeekDate = getMostRecentMonday(gameToday);
     updateWeekDisplay();
 }
 
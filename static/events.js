@@ -0,0 +1,465 @@
+const EVENTS_URL = '/api/alliance-events';
+const MEMBERS_URL = '/api/members';
+const WEEKDAYS = ['monday', 'tuesday', 'wednesday', 'thursday', 'friday', 'saturday', 'sunday'];
+
+let eventTypes = [];
+let allMembers = [];
+let currentTypeId = null;
+let editingTypeId = null;
+let canSchedule = false;
+let canEditTypes = false;
+let currentUsername = '';
+let gameToday = null; // current game day (server time), from check-auth
+
+// Check authentication
+async function checkAuth() {
+    try {
+        const response = await fetch('/api/check-auth');
+        const data = await response.json();
+
+        if (!data.authenticated) {
+            window.location.href = '/login.html';
+            return false;
+        }
+
+        currentUsername = data.username;
+        gameToday = new Date(data.game_today + 'T00:00:00');
+        let displayText = `👤 ${currentUsername}`;
+        if (data.rank) {
+            displayText += ` (${data.rank})`;
+        }
+        document.getElementById('username-display').textContent = displayText;
+
+        // R4/R5 schedule, R5/admin maintain the event types
+        canSchedule = data.is_admin || data.rank === 'R4' || data.rank === 'R5';
+        canEditTypes = data.is_admin || data.rank === 'R5';
+        document.querySelectorAll('.schedule-only').forEach(el => el.style.display = canSchedule ? '' : 'none');
+        document.querySelectorAll('.manage-only').forEach(el => el.style.display = canEditTypes ? '' : 'none');
+
+        return true;
+    } catch (error) {
+        console.error('Auth check error:', error);
+        window.location.href = '/login.html';
+        return false;
+    }
+}
+
+// Setup event listeners after auth check
+async function setupEventListeners() {
+    const usernameDisplay = document.getElementById('username-display');
+    const logoutBtn = document.getElementById('dropdown-logout-btn');
+    const adminLink = document.getElementById('admin-dropdown-link');
+    
+    if (usernameDisplay) {
+        usernameDisplay.addEventListener('click', toggleUserDropdown);
+    }
+    
+    if (logoutBtn) {
+        logoutBtn.addEventListener('click', handleLogout);
+    }
+    
+    // Check if user is admin to show admin link
+    try {
+        const response = await fetch('/api/check-auth');
+        const data = await response.json();
+        if (data.is_admin && adminLink) {
+            adminLink.style.display = 'block';
+        }
+    } catch (error) {
+        console.error('Error checking admin status:', error);
+    }
+    
+    // Close dropdown when clicking outside
+    document.addEventListener('click', (event) => {
+        const dropdown = document.getElementById('user-dropdown-menu');
+        const usernameBtn = document.getElementById('username-display');
+        if (dropdown && usernameBtn && !usernameBtn.contains(event.target) && !dropdown.contains(event.target)) {
+            dropdown.classList.remove('show');
+        }
+    });
+}
+
+// Toggle user dropdown menu
+function toggleUserDropdown(event) {
+    event.stopPropagation();
+    const dropdown = document.getElementById('user-dropdown-menu');
+    if (dropdown) {
+        dropdown.classList.toggle('show');
+    }
+}
+
+// Logout handler
+async function handleLogout(event) {
+    event.preventDefault();
+    if (!confirm('Are you sure you want to logout?')) {
+        return;
+    }
+    
+    try {
+        await fetch('/api/logout', { method: 'POST' });
+        window.location.href = '/login.html';
+    } catch (error) {
+        console.error('Logout error:', error);
+        alert('Error logging out. Please try again.');
+    }
+}
+
+// Load members (for manual assignments)
+async function loadMembers() {
+    try {
+        const response = await fetch(MEMBERS_URL);
+        allMembers = await response.json();
+        allMembers.sort((a, b) => a.name.toLowerCase().localeCompare(b.name.toLowerCase()));
+    } catch (error) {
+        console.error('Error loading members:', error);
+    }
+}
+
+// Format a date as YYYY-MM-DD
+function formatDate(date) {
+    const y = date.getFullYear();
+    const m = String(date.getMonth() + 1).padStart(2, '0');
+    const d = String(date.getDate()).padStart(2, '0');
+    return `${y}-${m}-${d}`;
+}
+
+// Monday of the week containing the given date
+function getMonday(date) {
+    const d = new Date(date);
+    const day = d.getDay();
+    d.setDate(d.getDate() - day + (day === 0 ? -6 : 1));
+    return d;
+}
+
+function currentType() {
+    return eventTypes.find(t => t.id === currentTypeId);
+}
+
+// Load event types into the selector
+async function loadEventTypes() {
+    try {
+        const response = await fetch(EVENTS_URL);
+        eventTypes = await response.json();
+    } catch (error) {
+        console.error('Error loading event types:', error);
+        eventTypes = [];
+    }
+
+    const select = document.getElementById('eventType');
+    if (eventTypes.length === 0) {
+        select.innerHTML = '<option value="">No event types yet</option>';
+        currentTypeId = null;
+    } else {
+        select.innerHTML = eventTypes.map(t =>
+            `<option value="${t.id}">${escapeHtml(t.name)}${t.active ? '' : ' (inactive)'}</option>`
+        ).join('');
+        if (!currentType()) {
+            currentTypeId = eventTypes[0].id;
+        }
+        select.value = currentTypeId;
+    }
+    await loadSchedule();
+}
+
+// Load and render the schedule for the selected week
+async function loadSchedule() {
+    const container = document.getElementById('schedule');
+    const type = currentType();
+
+    // The built-in train can't be deleted and is auto-scheduled from the train schedule
+    const builtIn = !!(type && type.system_key);
+    document.getElementById('delete-type-btn').style.display = canEditTypes && !builtIn ? '' : 'none';
+    document.getElementById('auto-schedule-btn').style.display = canSchedule && !builtIn ? '' : 'none';
+
+    if (!type) {
+        container.innerHTML = '<p class="help-text">Create an event type to start scheduling.</p>';
+        return;
+    }
+
+    try {
+        const start = document.getElementById('weekStart').value;
+        const response = await fetch(`${EVENTS_URL}/${type.id}/schedule?start=${start}`);
+        if (!response.ok) {
+            throw new Error(await response.text());
+        }
+        const data = await response.json();
+        renderSchedule(type, data.occurrences);
+    } catch (error) {
+        console.error('Error loading schedule:', error);
+        container.innerHTML = `<p class="help-text">Failed to load schedule: ${escapeHtml(error.message)}</p>`;
+    }
+}
+
+function renderSchedule(type, occurrences) {
+    const container = document.getElementById('schedule');
+    if (occurrences.length === 0) {
+        container.innerHTML = `<p class="help-text">${escapeHtml(type.name)} does not take place this week.</p>`;
+        return;
+    }
+
+    const time = type.start_time ? ` at ${type.start_time} ST` : '';
+    container.innerHTML = occurrences.map(occurrence => {
+        const day = new Date(occurrence.date + 'T00:00:00').toLocaleDateString(undefined, { weekday: 'long', month: 'short', day: 'numeric' });
+        const rows = type.slots.map(slot => {
+            const places = [];
+            for (let position = 1; position <= slot.count; position++) {
+                const assignment = occurrence.assignments.find(a => a.slot_id === slot.id && a.position === position);
+                places.push(renderPlace(occurrence.date, slot, position, assignment));
+            }
+            return `<tr><td><strong>${escapeHtml(slot.name)}</strong></td><td>${places.join('')}</td></tr>`;
+        }).join('');
+        return `
+            <h4>${escapeHtml(day)}${time}</h4>
+            <table class="members-table">
+                <tbody>${rows}</tbody>
+            </table>`;
+    }).join('');
+}
+
+function renderPlace(date, slot, position, assignment) {
+    if (!canSchedule) {
+        return `<div>${assignment ? escapeHtml(assignment.member_name) : '<em>Unassigned</em>'}</div>`;
+    }
+
+    const eligible = allMembers.filter(m => slot.ranks.length === 0 || slot.ranks.includes(m.rank));
+    const options = eligible.map(m =>
+        `<option value="${m.id}" ${assignment && assignment.member_id === m.id ? 'selected' : ''}>${escapeHtml(m.name)} (${m.rank})</option>`
+    ).join('');
+    const showedUp = assignment && assignment.showed_up ? 'checked' : '';
+    return `
+        <div class="form-group" data-date="${date}" data-slot="${slot.id}" data-position="${position}" data-assignment="${assignment ? assignment.id : ''}">
+            <select class="place-member" style="max-width: 250px;">
+                <option value="">-- Unassigned --</option>
+                ${options}
+            </select>
+            <label style="margin-left: 10px;"><input type="checkbox" class="place-showed-up" ${showedUp} ${assignment ? '' : 'disabled'}> Showed up</label>
+        </div>`;
+}
+
+// Save a manually changed place (member or attendance)
+async function savePlace(place) {
+    const type = currentType();
+    const memberId = parseInt(place.querySelector('.place-member').value);
+    const assignmentId = place.dataset.assignment;
+
+    try {
+        let response;
+        if (!memberId) {
+            if (!assignmentId) {
+                return;
+            }
+            response = await fetch(`/api/alliance-event-assignments/${assignmentId}`, { method: 'DELETE' });
+        } else {
+            response = await fetch(`${EVENTS_URL}/${type.id}/assignments`, {
+                method: 'PUT',
+                headers: { 'Content-Type': 'application/json' },
+                body: JSON.stringify({
+                    date: place.dataset.date,
+                    slot_id: parseInt(place.dataset.slot),
+                    position: parseInt(place.dataset.position),
+                    member_id: memberId,
+                    showed_up: place.querySelector('.place-showed-up').checked ? true : null
+                })
+            });
+        }
+        if (!response.ok) {
+            alert(await response.text());
+        }
+    } catch (error) {
+        console.error('Error saving assignment:', error);
+        alert('Failed to save assignment');
+    }
+    await loadSchedule();
+}
+
+// Fill the selected week automatically
+async function autoSchedule() {
+    const type = currentType();
+    if (!type) {
+        return;
+    }
+    if (!confirm(`Auto-schedule ${type.name} for this week? Existing assignments this week will be replaced.`)) {
+        return;
+    }
+
+    try {
+        const response = await fetch(`${EVENTS_URL}/${type.id}/auto-schedule`, {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify({ start_date: document.getElementById('weekStart').value })
+        });
+        if (!response.ok) {
+            alert(await response.text());
+            return;
+        }
+        const data = await response.json();
+        if (data.unfilled > 0) {
+            alert(`${data.unfilled} place(s) could not be filled - not enough eligible members.`);
+        }
+    } catch (error) {
+        console.error('Error auto-scheduling:', error);
+        alert('Failed to auto-schedule');
+    }
+    await loadSchedule();
+}
+
+function shiftWeek(weeks) {
+    const input = document.getElementById('weekStart');
+    const date = new Date(input.value + 'T00:00:00');
+    date.setDate(date.getDate() + weeks * 7);
+    input.value = formatDate(date);
+    loadSchedule();
+}
+
+// Event type editor
+// Slots of the built-in train can only be renamed
+function slotRow(slot, locked) {
+    const disabled = locked ? 'disabled' : '';
+    return `
+        <div class="form-group type-slot" data-id="${slot.id || ''}">
+            <input type="text" class="slot-name" placeholder="Slot name" value="${escapeHtml(slot.name || '')}" style="max-width: 200px;">
+            <input type="number" class="slot-count" min="1" max="50" value="${slot.count || 1}" style="max-width: 80px;" ${disabled}>
+            <input type="text" class="slot-ranks" placeholder="Ranks, e.g. R4,R5" value="${(slot.ranks || []).join(',')}" style="max-width: 160px;" ${disabled}>
+            <select class="slot-selection" style="max-width: 140px;" ${disabled}>
+                <option value="ranking" ${slot.selection !== 'random' ? 'selected' : ''}>ranking</option>
+                <option value="random" ${slot.selection === 'random' ? 'selected' : ''}>random</option>
+            </select>
+            ${locked ? '' : '<button class="clear-btn remove-slot-btn">✕</button>'}
+        </div>`;
+}
+
+function openTypeEditor(type) {
+    editingTypeId = type ? type.id : null;
+    document.getElementById('type-editor-title').textContent = type ? `Edit ${type.name}` : 'New Event Type';
+    document.getElementById('typeName').value = type ? type.name : '';
+    document.getElementById('typeRecurrence').value = type ? type.recurrence : 'weekly';
+    document.getElementById('typeInterval').value = type ? type.interval_weeks : 1;
+    document.getElementById('typeAnchor').value = type ? type.anchor_date : formatDate(gameToday);
+    document.getElementById('typeStartTime').value = type && type.start_time ? type.start_time : '';
+    document.getElementById('typeActive').checked = type ? type.active : true;
+    document.getElementById('typeWeekdays').innerHTML = WEEKDAYS.map(day =>
+        `<label style="margin-right: 10px;"><input type="checkbox" value="${day}" ${type && type.weekdays.includes(day) ? 'checked' : ''}> ${day.slice(0, 3)}</label>`
+    ).join('');
+    const builtIn = !!(type && type.system_key);
+    document.getElementById('typeSlots').innerHTML = (type ? type.slots : [{}]).map(slot => slotRow(slot, builtIn)).join('');
+    document.getElementById('typeRecurrence').disabled = builtIn;
+    document.getElementById('add-slot-btn').style.display = builtIn ? 'none' : '';
+    toggleWeekdays();
+    document.getElementById('type-editor').style.display = 'block';
+}
+
+function toggleWeekdays() {
+    const weekly = document.getElementById('typeRecurrence').value === 'weekly';
+    document.getElementById('weekday-group').style.display = weekly ? '' : 'none';
+}
+
+async function saveType() {
+    const body = {
+        name: document.getElementById('typeName').value,
+        recurrence: document.getElementById('typeRecurrence').value,
+        weekdays: [...document.querySelectorAll('#typeWeekdays input:checked')].map(el => el.value),
+        interval_weeks: parseInt(document.getElementById('typeInterval').value) || 1,
+        anchor_date: document.getElementById('typeAnchor').value,
+        start_time: document.getElementById('typeStartTime').value || null,
+        active: document.getElementById('typeActive').checked,
+        slots: [...document.querySelectorAll('#typeSlots .type-slot')].map(row => ({
+            id: parseInt(row.dataset.id) || 0,
+            name: row.querySelector('.slot-name').value,
+            count: parseInt(row.querySelector('.slot-count').value) || 1,
+            ranks: row.querySelector('.slot-ranks').value.split(',').map(r => r.trim()).filter(r => r),
+            selection: row.querySelector('.slot-selection').value
+        }))
+    };
+
+    try {
+        const response = await fetch(editingTypeId ? `${EVENTS_URL}/${editingTypeId}` : EVENTS_URL, {
+            method: editingTypeId ? 'PUT' : 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify(body)
+        });
+        if (!response.ok) {
+            alert(await response.text());
+            return;
+        }
+        const saved = await response.json();
+        currentTypeId = saved.id;
+        document.getElementById('type-editor').style.display = 'none';
+        await loadEventTypes();
+    } catch (error) {
+        console.error('Error saving event type:', error);
+        alert('Failed to save event type');
+    }
+}
+
+async function deleteType() {
+    const type = currentType();
+    if (!type || !confirm(`Delete ${type.name} and all of its assignments?`)) {
+        return;
+    }
+
+    try {
+        const response = await fetch(`${EVENTS_URL}/${type.id}`, { method: 'DELETE' });
+        if (!response.ok) {
+            alert(await response.text());
+            return;
+        }
+        currentTypeId = null;
+        await loadEventTypes();
+    } catch (error) {
+        console.error('Error deleting event type:', error);
+        alert('Failed to delete event type');
+    }
+}
+
+function escapeHtml(text) {
+    const div = document.createElement('div');
+    div.textContent = text;
+    return div.innerHTML;
+}
+
+// Event listeners
+document.getElementById('eventType').addEventListener('change', (e) => {
+    currentTypeId = parseInt(e.target.value) || null;
+    loadSchedule();
+});
+document.getElementById('weekStart').addEventListener('change', loadSchedule);
+document.getElementById('prev-week-btn').addEventListener('click', () => shiftWeek(-1));
+document.getElementById('next-week-btn').addEventListener('click', () => shiftWeek(1));
+document.getElementById('auto-schedule-btn').addEventListener('click', autoSchedule);
+document.getElementById('new-type-btn').addEventListener('click', () => openTypeEditor(null));
+document.getElementById('edit-type-btn').addEventListener('click', () => {
+    if (currentType()) {
+        openTypeEditor(currentType());
+    }
+});
+document.getElementById('delete-type-btn').addEventListener('click', deleteType);
+document.getElementById('typeRecurrence').addEventListener('change', toggleWeekdays);
+document.getElementById('add-slot-btn').addEventListener('click', () => {
+    document.getElementById('typeSlots').insertAdjacentHTML('beforeend', slotRow({}, false));
+});
+document.getElementById('typeSlots').addEventListener('click', (e) => {
+    if (e.target.classList.contains('remove-slot-btn')) {
+        e.target.closest('.type-slot').remove();
+    }
+});
+document.getElementById('save-type-btn').addEventListener('click', saveType);
+document.getElementById('cancel-type-btn').addEventListener('click', () => {
+    document.getElementById('type-editor').style.display = 'none';
+});
+document.getElementById('schedule').addEventListener('change', (e) => {
+    const place = e.target.closest('[data-slot]');
+    if (place) {
+        savePlace(place);
+    }
+});
+
+// Initialize
+(async () => {
+    const authenticated = await checkAuth();
+    if (authenticated) {
+        document.getElementById('weekStart').value = formatDate(getMonday(gameToday));
+        await setupEventListeners();
+        await loadMembers();
+        await loadEventTypes();
+    }
+})();
@@ -124,8 +124,122 @@ document.getElementById('password-form').addEventListener('submit', async (e) =>
     }
 });
 
+// Load upcoming Desert Storm events with the member's own signup
+async function loadStormSignups(today) {
+    try {
+        const response = await fetch(`${API_BASE}/storm-events`);
+        if (!response.ok) {
+            return;
+        }
+        
+        const events = (await response.json())
+            .filter(event => event.event_date >= today)
+            .sort((a, b) => a.event_date.localeCompare(b.event_date));
+        if (events.length === 0) {
+            return;
+        }
+        
+        const options = [
+            { value: '', label: '-- Not answered --' },
+            { value: 'A', label: 'Task Force A' },
+            { value: 'B', label: 'Task Force B' },
+            { value: 'either', label: 'Either task force' },
+            { value: 'unavailable', label: 'Unavailable' }
+        ];
+        
+        const list = document.getElementById('storm-signup-list');
+        list.innerHTML = events.map(event => `
+            <div class="form-group">
+                <label for="storm-signup-${event.id}">${event.event_date}${event.notes ? ' - ' + escapeHtml(event.notes) : ''}</label>
+                <select id="storm-signup-${event.id}" data-event-id="${event.id}" style="max-width: 400px;">
+                    ${options.map(option => `<option value="${option.value}" ${option.value === (event.my_availability || '') ? 'selected' : ''}>${option.label}</option>`).join('')}
+                </select>
+            </div>
+        `).join('');
+        
+        list.querySelectorAll('select').forEach(select => {
+            select.addEventListener('change', () => saveStormSignup(select));
+        });
+        document.getElementById('storm-signup-section').style.display = 'block';
+    } catch (error) {
+        console.error('Error loading storm events:', error);
+    }
+}
+
+// Save the member's signup for one event
+async function saveStormSignup(select) {
+    if (!select.value) {
+        return;
+    }
+    
+    try {
+        const response = await fetch(`${API_BASE}/storm-events/${select.dataset.eventId}/signup`, {
+            method: 'PUT',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify({ availability: select.value })
+        });
+        
+        if (!response.ok) {
+            throw new Error(await response.text());
+        }
+    } catch (error) {
+        console.error('Error saving storm signup:', error);
+        alert('❌ Failed to save signup: ' + error.message);
+    }
+}
+
+// Show the member's time zone, offering the zones the browser knows
+function showTimezone(timezone) {
+    document.getElementById('member-timezone').value = timezone || '';
+    if (Intl.supportedValuesOf) {
+        document.getElementById('timezone-options').innerHTML = Intl.supportedValuesOf('timeZone')
+            .map(zone => `<option value="${zone}">`).join('');
+    }
+    document.getElementById('timezone-section').style.display = 'block';
+}
+
+// Save the member's time zone
+async function saveTimezone(event) {
+    event.preventDefault();
+    
+    try {
+        const response = await fetch(`${API_BASE}/members/timezone`, {
+            method: 'PUT',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify({ timezone: document.getElementById('member-timezone').value.trim() })
+        });
+        
+        if (!response.ok) {
+            throw new Error(await response.text());
+        }
+        alert('✅ Time zone saved');
+    } catch (error) {
+        console.error('Error saving time zone:', error);
+        alert('❌ Failed to save time zone: ' + error.message);
+    }
+}
+
+document.getElementById('timezone-form').addEventListener('submit', saveTimezone);
+document.getElementById('detect-timezone').addEventListener('click', (event) => {
+    event.preventDefault();
+    document.getElementById('member-timezone').value = Intl.DateTimeFormat().resolvedOptions().timeZone;
+});
+
+// Escape HTML
+function escapeHtml(text) {
+    const div = document.createElement('div');
+    div.textContent = text;
+    return div.innerHTML;
+}
+
 // Initialize
 document.addEventListener('DOMContentLoaded', async () => {
-    await checkAuth();
+    const auth = await checkAuth();
     await setupEventListeners();
+    if (auth && auth.rank) {
+        if (!auth.is_admin) {
+            showTimezone(auth.timezone);
+        }
+        await loadStormSignups(auth.game_today);
+    }
 });
@@ -93,7 +93,9 @@ function formatDate(dateStr) {
 // Load rankings
 async function loadRankings() {
     try {
-        const response = await fetch(RANKINGS_URL);
+        const asOf = document.getElementById('as-of-date').value;
+        const url = asOf ? `${RANKINGS_URL}?as_of=${asOf}` : RANKINGS_URL;
+        const response = await fetch(url);
         if (!response.ok) throw new Error('Failed to load rankings');
         
         currentData = await response.json();
@@ -140,6 +142,14 @@ function displaySystemInfo(settings, avgCount) {
                 <span class="info-label">🎯 First Time Conductor Boost:</span>
                 <span class="info-value">+${settings.first_time_conductor_boost} pts (if never been conductor)</span>
             </div>
+            <div class="system-info-item">
+                <span class="info-label">⚔️ VS Percentile Points:</span>
+                <span class="info-value">up to +${settings.vs_percentile_points} pts (scaled by weekly VS percentile)</span>
+            </div>
+            <div class="system-info-item">
+                <span class="info-label">📅 VS Consistency Bonus:</span>
+                <span class="info-value">+${settings.vs_consistency_bonus} pts (≥${settings.vs_min_daily_points} pts every day Mon-Sat)</span>
+            </div>
             <div class="system-info-item">
                 <span class="info-label">⏱️ Recent Conductor Penalty:</span>
                 <span class="info-value">-${settings.recent_conductor_penalty_days} pts max (based on days)</span>
@@ -148,6 +158,10 @@ function displaySystemInfo(settings, avgCount) {
                 <span class="info-label">📊 Above Average Penalty:</span>
                 <span class="info-value">-${settings.above_average_conductor_penalty} pts</span>
             </div>
+            <div class="system-info-item">
+                <span class="info-label">🚫 VS Zero-Day Penalty:</span>
+                <span class="info-value">-${settings.vs_zero_day_penalty} pts per day without VS points</span>
+            </div>
         </div>
         <p class="system-note">
             <strong>Note:</strong> Awards and recommendations stack across multiple weeks until you're assigned as conductor/backup, then they expire. 
@@ -696,6 +710,14 @@ function displayRankings(rankings) {
                                 <span class="detail-label">🎯 First Timer:</span>
                                 <span class="detail-value">+${ranking.first_time_conductor_boost} pts</span>
                             </div>
+                            <div class="detail-item positive">
+                                <span class="detail-label">⚔️ VS Percentile:</span>
+                                <span class="detail-value">+${ranking.vs_percentile_points} pts (${ranking.vs_weekly_total.toLocaleString()} VS, top ${Math.round((1 - ranking.vs_percentile) * 100)}%)</span>
+                            </div>
+                            <div class="detail-item positive">
+                                <span class="detail-label">📅 VS Consistency:</span>
+                                <span class="detail-value">+${ranking.vs_consistency_bonus} pts</span>
+                            </div>
                             <div class="detail-item negative">
                                 <span class="detail-label">⏱️ Recent Conductor:</span>
                                 <span class="detail-value">-${ranking.recent_conductor_penalty} pts</span>
@@ -704,6 +726,10 @@ function displayRankings(rankings) {
                                 <span class="detail-label">📈 Above Average:</span>
                                 <span class="detail-value">-${ranking.above_average_penalty} pts</span>
                             </div>
+                            <div class="detail-item negative">
+                                <span class="detail-label">🚫 VS Zero Days:</span>
+                                <span class="detail-value">-${ranking.vs_zero_day_penalty} pts (${ranking.vs_zero_days} days)</span>
+                            </div>
                         </div>
                     </div>
                     
@@ -905,8 +931,52 @@ function escapeHtml(text) {
     return div.innerHTML;
 }
 
+// Load the inactivity report; it is R4/R5 only, so the section stays hidden for everyone else
+async function loadInactivityReport() {
+    const asOf = document.getElementById('as-of-date').value;
+    const query = asOf ? `?as_of=${asOf}` : '';
+    const section = document.getElementById('inactivity-section');
+    
+    try {
+        const response = await fetch(`${API_BASE}/inactivity-report${query}`);
+        if (!response.ok) {
+            section.style.display = 'none';
+            return;
+        }
+        const report = await response.json();
+        section.style.display = 'block';
+        
+        document.getElementById('inactivity-flagged').textContent = report.flagged_count;
+        document.getElementById('inactivity-period').textContent = `${report.from} to ${report.as_of}, flagged at ${report.settings.flag_score}+`;
+        document.getElementById('inactivity-export-link').href = `${API_BASE}/inactivity-report/export${query}`;
+        
+        const members = report.members.filter(m => m.score > 0);
+        const list = document.getElementById('inactivity-list');
+        if (members.length === 0) {
+            list.innerHTML = '<p class="loading">No inactivity signals in this period.</p>';
+            return;
+        }
+        
+        list.innerHTML = members.map((m, index) => `
+            <div class="ranking-card">
+                <h4>${index + 1}. ${escapeHtml(m.member_name)} <span class="rank-badge">${m.member_rank}</span>
+                    ${m.flagged ? '⚠️' : ''} <span style="float: right;">${m.score} pts</span></h4>
+                <ul style="margin: 6px 0 0 18px;">
+                    ${m.reasons.map(reason => `<li>${escapeHtml(reason.detail)} (+${reason.points})</li>`).join('')}
+                </ul>
+            </div>
+        `).join('');
+    } catch (error) {
+        console.error('Failed to load inactivity report:', error);
+        section.style.display = 'none';
+    }
+}
+
 // Refresh rankings
 document.getElementById('refresh-btn').addEventListener('click', loadRankings);
+document.getElementById('refresh-btn').addEventListener('click', loadInactivityReport);
+document.getElementById('as-of-date').addEventListener('change', loadInactivityReport);
+document.getElementById('as-of-date').addEventListener('change', loadRankings);
 
 // Initialize
 document.addEventListener('DOMContentLoaded', async () => {
@@ -914,6 +984,7 @@ document.addEventListener('DOMContentLoaded', async () => {
     if (auth) {
         await setupEventListeners();
         await loadRankings();
+        await loadInactivityReport();
         
         // Add filter event listeners
         document.getElementById('filter-name').addEventListener('input', filterRankings);
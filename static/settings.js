@@ -1,5 +1,6 @@
 const API_BASE = '/api';
 const SETTINGS_URL = `${API_BASE}/settings`;
+const MESSAGE_TEMPLATES_URL = `${API_BASE}/message-templates`;
 
 let isR5OrAdmin = false;
 
@@ -106,19 +107,70 @@ async function loadSettings() {
         document.getElementById('above-average-penalty').value = settings.above_average_conductor_penalty;
         document.getElementById('r4r5-rank-boost').value = settings.r4r5_rank_boost;
         document.getElementById('first-time-boost').value = settings.first_time_conductor_boost || 5;
-        document.getElementById('schedule-message-template').value = settings.schedule_message_template || 'Train Schedule - Week {WEEK}\n\n{SCHEDULES}\n\nNext in line:\n{NEXT_3}';
-        document.getElementById('daily-message-template').value = settings.daily_message_template || 'ALL ABOARD! Daily Train Assignment\n\nDate: {DATE}\n\nToday\'s Conductor: {CONDUCTOR_NAME} ({CONDUCTOR_RANK})\nBackup Engineer: {BACKUP_NAME} ({BACKUP_RANK})\n\nDEPARTURE SCHEDULE:\n- 15:00 ST (17:00 UK) - Conductor {CONDUCTOR_NAME}, please request train assignment in alliance chat\n- 16:30 ST (18:30 UK) - If conductor hasn\'t shown up, Backup {BACKUP_NAME} takes over and assigns train to themselves\n\nRemember: Communication is key! Let the alliance know if you can\'t make it.\n\nAll aboard for another successful run!';
+        document.getElementById('vs-percentile-points').value = settings.vs_percentile_points || 0;
+        document.getElementById('vs-min-daily-points').value = settings.vs_min_daily_points || 0;
+        document.getElementById('vs-consistency-bonus').value = settings.vs_consistency_bonus || 0;
+        document.getElementById('vs-zero-day-penalty').value = settings.vs_zero_day_penalty || 0;
         
         // Power tracking
         const powerTrackingEnabled = settings.power_tracking_enabled || false;
         document.getElementById('power-tracking-enabled').checked = powerTrackingEnabled;
         togglePowerUploadSection(powerTrackingEnabled);
+        document.getElementById('ocr-min-confidence').value = settings.ocr_min_confidence || 0;
+        document.getElementById('ocr-languages').value = settings.ocr_languages || 'eng';
+        loadInstalledOcrLanguages();
+        document.getElementById('game-timezone').value = settings.game_timezone || 'Etc/GMT+2';
+        document.getElementById('game-reset-time').value = settings.game_reset_time || '00:00';
+        document.getElementById('train-conductor-time').value = settings.train_conductor_time || '15:00';
+        document.getElementById('train-backup-time').value = settings.train_backup_time || '16:30';
+        document.getElementById('display-timezones').value = settings.display_timezones || '';
     } catch (error) {
         console.error('Error loading settings:', error);
         alert('Failed to load settings');
     }
 }
 
+// Read the settings form into the API payload shape
+function collectSettingsForm() {
+    return {
+        award_first_points: parseInt(document.getElementById('award-first').value),
+        award_second_points: parseInt(document.getElementById('award-second').value),
+        award_third_points: parseInt(document.getElementById('award-third').value),
+        recent_conductor_penalty_days: parseInt(document.getElementById('recent-conductor-days').value),
+        above_average_conductor_penalty: parseInt(document.getElementById('above-average-penalty').value),
+        r4r5_rank_boost: parseInt(document.getElementById('r4r5-rank-boost').value),
+        first_time_conductor_boost: parseInt(document.getElementById('first-time-boost').value),
+        vs_percentile_points: parseInt(document.getElementById('vs-percentile-points').value),
+        vs_min_daily_points: parseInt(document.getElementById('vs-min-daily-points').value),
+        vs_consistency_bonus: parseInt(document.getElementById('vs-consistency-bonus').value),
+        vs_zero_day_penalty: parseInt(document.getElementById('vs-zero-day-penalty').value),
+        power_tracking_enabled: document.getElementById('power-tracking-enabled').checked,
+        ocr_min_confidence: parseInt(document.getElementById('ocr-min-confidence').value),
+        ocr_languages: document.getElementById('ocr-languages').value.trim(),
+        game_timezone: document.getElementById('game-timezone').value.trim(),
+        game_reset_time: document.getElementById('game-reset-time').value,
+        train_conductor_time: document.getElementById('train-conductor-time').value,
+        train_backup_time: document.getElementById('train-backup-time').value,
+        display_timezones: document.getElementById('display-timezones').value.trim()
+    };
+}
+
+// Show which OCR language packs the server has installed
+async function loadInstalledOcrLanguages() {
+    const note = document.getElementById('ocr-languages-installed');
+    if (!isR5OrAdmin) return;
+    try {
+        const response = await fetch('/api/settings/ocr-languages');
+        if (!response.ok) return;
+        const languages = await response.json();
+        note.textContent = languages.error
+            ? `Installed packs could not be listed: ${languages.error}`
+            : `Installed: ${languages.installed.join(', ')}`;
+    } catch (error) {
+        console.error('Error loading OCR languages:', error);
+    }
+}
+
 // Save settings
 document.getElementById('settings-form').addEventListener('submit', async (e) => {
     e.preventDefault();
@@ -128,18 +180,7 @@ document.getElementById('settings-form').addEventListener('submit', async (e) =>
         return;
     }
     
-    const settings = {
-        award_first_points: parseInt(document.getElementById('award-first').value),
-        award_second_points: parseInt(document.getElementById('award-second').value),
-        award_third_points: parseInt(document.getElementById('award-third').value),
-        recent_conductor_penalty_days: parseInt(document.getElementById('recent-conductor-days').value),
-        above_average_conductor_penalty: parseInt(document.getElementById('above-average-penalty').value),
-        r4r5_rank_boost: parseInt(document.getElementById('r4r5-rank-boost').value),
-        first_time_conductor_boost: parseInt(document.getElementById('first-time-boost').value),
-        schedule_message_template: document.getElementById('schedule-message-template').value,
-        daily_message_template: document.getElementById('daily-message-template').value,
-        power_tracking_enabled: document.getElementById('power-tracking-enabled').checked
-    };
+    const settings = collectSettingsForm();
     
     try {
         const response = await fetch(SETTINGS_URL, {
@@ -160,6 +201,68 @@ document.getElementById('settings-form').addEventListener('submit', async (e) =>
     }
 });
 
+// Preview the ranking impact of the current form values without saving
+document.getElementById('simulate-btn').addEventListener('click', async () => {
+    if (!isR5OrAdmin) {
+        alert('Only R5 members and admins can preview settings changes.');
+        return;
+    }
+
+    try {
+        const response = await fetch(`${API_BASE}/rankings/simulate`, {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify(collectSettingsForm())
+        });
+
+        if (!response.ok) {
+            const error = await response.text();
+            throw new Error(error);
+        }
+
+        renderSimulation(await response.json());
+    } catch (error) {
+        console.error('Error simulating settings:', error);
+        alert('❌ Failed to preview settings: ' + error.message);
+    }
+});
+
+function renderSimulation(result) {
+    const section = document.getElementById('simulation-section');
+    const summary = document.getElementById('simulation-summary');
+    const container = document.getElementById('simulation-results');
+
+    const names = members => members.length ? members.map(m => escapeHtml(m.name)).join(', ') : 'none';
+    summary.textContent = `Auto-schedule for the week of ${result.week_start} with these settings:`;
+
+    const moved = result.changes.filter(c => c.rank_change !== 0 || c.score_change !== 0);
+    let html = `
+        <p><strong>Entering the conductor pool:</strong> ${names(result.entering)}</p>
+        <p><strong>Leaving the conductor pool:</strong> ${names(result.leaving)}</p>
+        <p><strong>Conductor pool:</strong> ${names(result.simulated_selection)}</p>
+    `;
+
+    if (moved.length === 0) {
+        html += '<p>No scores or ranks change.</p>';
+    } else {
+        html += '<ul>' + moved.map(c => {
+            const arrow = c.rank_change > 0 ? `▲${c.rank_change}` : c.rank_change < 0 ? `▼${-c.rank_change}` : '–';
+            const delta = c.score_change > 0 ? `+${c.score_change}` : `${c.score_change}`;
+            return `<li><strong>${escapeHtml(c.member.name)}</strong>: #${c.current_rank} → #${c.simulated_rank} (${arrow}), score ${c.current_score} → ${c.simulated_score} (${delta})</li>`;
+        }).join('') + '</ul>';
+    }
+
+    container.innerHTML = html;
+    section.style.display = 'block';
+    section.scrollIntoView({ behavior: 'smooth' });
+}
+
+function escapeHtml(text) {
+    const div = document.createElement('div');
+    div.textContent = text;
+    return div.innerHTML;
+}
+
 // Reset to defaults
 document.getElementById('reset-btn').addEventListener('click', () => {
     if (confirm('Reset all settings to default values?')) {
@@ -171,9 +274,201 @@ document.getElementById('reset-btn').addEventListener('click', () => {
         document.getElementById('above-average-penalty').value = 10;
         document.getElementById('r4r5-rank-boost').value = 5;
         document.getElementById('first-time-boost').value = 5;
-        document.getElementById('schedule-message-template').value = 'Train Schedule - Week {WEEK}\n\n{SCHEDULES}\n\nNext in line:\n{NEXT_3}';
-        document.getElementById('daily-message-template').value = 'ALL ABOARD! Daily Train Assignment\n\nDate: {DATE}\n\nToday\'s Conductor: {CONDUCTOR_NAME} ({CONDUCTOR_RANK})\nBackup Engineer: {BACKUP_NAME} ({BACKUP_RANK})\n\nDEPARTURE SCHEDULE:\n- 15:00 ST (17:00 UK) - Conductor {CONDUCTOR_NAME}, please request train assignment in alliance chat\n- 16:30 ST (18:30 UK) - If conductor hasn\'t shown up, Backup {BACKUP_NAME} takes over and assigns train to themselves\n\nRemember: Communication is key! Let the alliance know if you can\'t make it.\n\nAll aboard for another successful run!';
+        document.getElementById('vs-percentile-points').value = 0;
+        document.getElementById('vs-min-daily-points').value = 0;
+        document.getElementById('vs-consistency-bonus').value = 0;
+        document.getElementById('vs-zero-day-penalty').value = 0;
         document.getElementById('power-tracking-enabled').checked = false;
+        document.getElementById('ocr-min-confidence').value = 0;
+        document.getElementById('ocr-languages').value = 'eng';
+        document.getElementById('game-timezone').value = 'Etc/GMT+2';
+        document.getElementById('game-reset-time').value = '00:00';
+        document.getElementById('train-conductor-time').value = '15:00';
+        document.getElementById('train-backup-time').value = '16:30';
+        document.getElementById('display-timezones').value = 'Europe/London,Europe/Berlin';
+    }
+});
+
+// Message template kinds in page order, with the fields each one can use
+const MESSAGE_TEMPLATE_KINDS = {
+    train_weekly: {
+        title: '🚂 Weekly Train Schedule',
+        fields: '<code>.Week</code> (date), <code>.Schedules</code> (list of <code>.Date</code>, <code>.Conductor</code>, <code>.Backup</code>; '
+            + 'members have <code>.Name</code> and <code>.Rank</code>, <code>.Backup</code> can be empty), '
+            + '<code>.NextInLine</code> (the next 3 members by ranking)'
+    },
+    train_daily: {
+        title: '📨 Daily Train Message',
+        fields: '<code>.Date</code>, <code>.Conductor.Name</code>, <code>.Conductor.Rank</code>, <code>.Backup</code> (can be empty), '
+            + '<code>.ConductorTime</code>, <code>.BackupTime</code> (server time and the chat time zones)'
+    },
+    conductor_reminder: {
+        title: '💬 Conductor Reminders',
+        fields: 'One reminder per conductor, using the variants in turn. <code>.Name</code>, <code>.Rank</code>, <code>.Date</code>, '
+            + '<code>.Time</code> (server time and the conductor\'s own time zone), <code>.Backup</code> (can be empty)'
+    },
+    vs_warning: {
+        title: '⚠️ VS Minimum Warning',
+        fields: '<code>.Week</code>, <code>.Requirements</code> (list of <code>.Day</code>, <code>.Theme</code>, <code>.MinPoints</code>), '
+            + '<code>.Members</code> (list of <code>.Name</code>, <code>.Rank</code>, <code>.Missed</code> days, <code>.Streak</code>), <code>.Count</code>'
+    },
+    storm_plan: {
+        title: '🏜️ Desert Storm Plan',
+        fields: '<code>.TaskForce</code>, <code>.Date</code> (empty for the undated plan), <code>.Count</code>, '
+            + '<code>.Stages</code> (list of <code>.Stage</code> and <code>.Buildings</code>, each with <code>.Name</code> and <code>.Members</code>)'
+    },
+    storm_member: {
+        title: '🏜️ Desert Storm Personal Message',
+        fields: '<code>.Name</code>, <code>.Rank</code>, <code>.TaskForce</code>, <code>.Date</code> (empty for the undated plan), '
+            + '<code>.Slots</code> (list of <code>.Stage</code>, <code>.Building</code>, <code>.Position</code>)'
+    }
+};
+
+let messageTemplates = [];
+
+// Load the current message templates and render one editor per template, grouped by kind
+async function loadMessageTemplates() {
+    try {
+        const response = await fetch(MESSAGE_TEMPLATES_URL);
+        if (!response.ok) throw new Error(await response.text());
+        messageTemplates = await response.json();
+        renderMessageTemplates();
+    } catch (error) {
+        console.error('Error loading message templates:', error);
+        document.getElementById('message-templates').innerHTML = '<p class="help-text">Failed to load message templates.</p>';
+    }
+}
+
+function renderMessageTemplates() {
+    const container = document.getElementById('message-templates');
+    const disabled = isR5OrAdmin ? '' : 'disabled';
+
+    container.innerHTML = Object.entries(MESSAGE_TEMPLATE_KINDS).map(([kind, info]) => {
+        const templates = messageTemplates.filter(t => t.kind === kind);
+        const variants = templates.length > 1 || kind === 'conductor_reminder';
+        const editors = templates.map(t => `
+            <div class="form-group message-template" data-name="${escapeHtml(t.name)}" data-kind="${kind}">
+                <label for="template-${escapeHtml(t.name)}">${variants ? escapeHtml(t.name) : 'Message Template'}:</label>
+                <textarea id="template-${escapeHtml(t.name)}" rows="8" ${disabled}>${escapeHtml(t.body)}</textarea>
+                <span class="help-text">Version ${t.version}${t.created_by ? ` by ${escapeHtml(t.created_by)}` : ''}, ${escapeHtml(t.created_at)}</span>
+                <div class="button-group">
+                    <button type="button" class="primary-btn" data-action="save" ${disabled}>💾 Save</button>
+                    <button type="button" class="secondary-btn" data-action="preview" ${disabled}>👁️ Preview</button>
+                    <button type="button" class="secondary-btn" data-action="history">🕘 History</button>
+                    ${variants ? `<button type="button" class="delete-btn" data-action="delete" ${disabled}>🗑️ Delete</button>` : ''}
+                </div>
+                <div class="template-output"></div>
+            </div>
+        `).join('');
+
+        return `
+            <div class="settings-group">
+                <h4>${info.title}</h4>
+                <span class="help-text">Fields: ${info.fields}</span>
+                ${editors}
+                ${kind === 'conductor_reminder' ? `<button type="button" class="secondary-btn" data-action="add" data-kind="${kind}" ${disabled}>➕ Add Variant</button>` : ''}
+            </div>
+        `;
+    }).join('');
+}
+
+// Show a template's sample messages, or why it doesn't render
+function showTemplateOutput(editor, html) {
+    editor.querySelector('.template-output').innerHTML = html;
+}
+
+async function previewMessageTemplate(editor) {
+    const response = await fetch(`${MESSAGE_TEMPLATES_URL}/preview`, {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ kind: editor.dataset.kind, body: editor.querySelector('textarea').value })
+    });
+    if (!response.ok) throw new Error(await response.text());
+
+    const result = await response.json();
+    if (!result.valid) {
+        showTemplateOutput(editor, `<p class="template-error">❌ ${escapeHtml(result.error)}</p>`);
+        return;
+    }
+    showTemplateOutput(editor, '<p class="help-text">Rendered with sample data:</p>'
+        + result.outputs.map(output => `<pre class="template-preview">${escapeHtml(output)}</pre>`).join(''));
+}
+
+async function saveMessageTemplate(name, kind, body) {
+    const response = await fetch(`${MESSAGE_TEMPLATES_URL}/${encodeURIComponent(name)}`, {
+        method: 'PUT',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ kind, body })
+    });
+    if (!response.ok) throw new Error(await response.text());
+    return response.json();
+}
+
+async function showMessageTemplateHistory(editor) {
+    const response = await fetch(`${MESSAGE_TEMPLATES_URL}/${encodeURIComponent(editor.dataset.name)}/versions`);
+    if (!response.ok) throw new Error(await response.text());
+
+    const versions = await response.json();
+    showTemplateOutput(editor, versions.map((v, i) => `
+        <div class="template-version">
+            <p class="help-text">
+                Version ${v.version}${v.created_by ? ` by ${escapeHtml(v.created_by)}` : ''}, ${escapeHtml(v.created_at)}
+                ${i > 0 && isR5OrAdmin ? `<button type="button" class="secondary-btn" data-action="restore" data-version="${v.version}">↩️ Restore</button>` : ''}
+            </p>
+            <pre class="template-preview">${escapeHtml(v.body)}</pre>
+        </div>
+    `).join(''));
+    editor.dataset.versions = JSON.stringify(versions);
+}
+
+document.getElementById('message-templates').addEventListener('click', async (e) => {
+    const button = e.target.closest('button[data-action]');
+    if (!button) return;
+    const editor = button.closest('.message-template');
+
+    try {
+        switch (button.dataset.action) {
+            case 'save': {
+                const saved = await saveMessageTemplate(editor.dataset.name, editor.dataset.kind, editor.querySelector('textarea').value);
+                alert(`✅ ${saved.name} saved (version ${saved.version})`);
+                await loadMessageTemplates();
+                break;
+            }
+            case 'preview':
+                await previewMessageTemplate(editor);
+                break;
+            case 'history':
+                await showMessageTemplateHistory(editor);
+                break;
+            case 'restore': {
+                // Restoring saves the old text as a new version, so the history is kept
+                const version = JSON.parse(editor.dataset.versions).find(v => v.version === parseInt(button.dataset.version));
+                if (!confirm(`Restore version ${version.version} of ${editor.dataset.name}?`)) return;
+                await saveMessageTemplate(editor.dataset.name, editor.dataset.kind, version.body);
+                await loadMessageTemplates();
+                break;
+            }
+            case 'delete': {
+                if (!confirm(`Delete ${editor.dataset.name} and all its versions?`)) return;
+                const response = await fetch(`${MESSAGE_TEMPLATES_URL}/${encodeURIComponent(editor.dataset.name)}`, { method: 'DELETE' });
+                if (!response.ok) throw new Error(await response.text());
+                await loadMessageTemplates();
+                break;
+            }
+            case 'add': {
+                // New variants start as a copy of the last one, named with the next free number
+                const kind = button.dataset.kind;
+                const existing = messageTemplates.filter(t => t.kind === kind);
+                let number = existing.length + 1;
+                while (existing.some(t => t.name === `${kind}_${number}`)) number++;
+                await saveMessageTemplate(`${kind}_${number}`, kind, existing.length ? existing[existing.length - 1].body : 'Hi {{.Name}}!');
+                await loadMessageTemplates();
+                break;
+            }
+        }
+    } catch (error) {
+        console.error('Error updating message template:', error);
+        alert('❌ ' + error.message);
     }
 });
 
@@ -195,5 +490,6 @@ document.addEventListener('DOMContentLoaded', async () => {
     if (auth) {
         await setupEventListeners();
         await loadSettings();
+        await loadMessageTemplates();
     }
 });
@@ -1,27 +1,23 @@
 const API_URL = '/api/storm-assignments';
 const MEMBERS_URL = '/api/members';
 
-const BUILDINGS = [
-    // Stage 1 - Immediate (Priority: Hospitals > Oil Refineries)
-    { id: 'field_hospital_1', name: 'Field Hospital I', stage: 1, points: '30/s', priority: 'CRITICAL', boost: 'Heal 15 troops/10s' },
-    { id: 'field_hospital_2', name: 'Field Hospital II', stage: 1, points: '30/s', priority: 'CRITICAL', boost: 'Heal 15 troops/10s' },
-    { id: 'field_hospital_3', name: 'Field Hospital III', stage: 1, points: '30/s', priority: 'CRITICAL', boost: 'Heal 15 troops/10s' },
-    { id: 'field_hospital_4', name: 'Field Hospital IV', stage: 1, points: '30/s', priority: 'CRITICAL', boost: 'Heal 15 troops/10s' },
-    { id: 'oil_refinery_1', name: 'Oil Refinery I', stage: 1, points: '50/s', priority: 'HIGH' },
-    { id: 'oil_refinery_2', name: 'Oil Refinery II', stage: 1, points: '50/s', priority: 'HIGH' },
-    { id: 'science_hub', name: 'Science Hub', stage: 1, points: '10/s', priority: 'MEDIUM', boost: 'Teleport cooldown -50%' },
-    { id: 'info_center', name: 'Info Center', stage: 1, points: '10/s', priority: 'LOW', boost: '+10% all points' },
-    
-    // Stage 2 - After 10 minutes (Priority: Hospitals & Nuclear Silo)
-    { id: 'nuclear_silo', name: 'Nuclear Silo', stage: 2, points: '80/s', priority: 'CRITICAL', boost: 'HIGHEST POINTS!' },
-    { id: 'arsenal', name: 'Arsenal', stage: 2, points: '10/s', priority: 'MEDIUM', boost: '+15% ATK/DEF/HP' },
-    { id: 'mercenary_factory', name: 'Mercenary Factory', stage: 2, points: '10/s', priority: 'MEDIUM', boost: '-15% enemy stats' }
-];
+const BUILDINGS_URL = '/api/storm-buildings';
+const EVENTS_URL = '/api/storm-events';
 
+const STAGE_TITLES = {
+    1: 'Stage 1 - Immediate (0:00)',
+    2: 'Stage 2 - After 10 Minutes'
+};
+
+let BUILDINGS = [];
 let allMembers = [];
 let currentTaskForce = 'A';
 let assignments = {};
+let proposals = {};
+let stormEvents = [];
+let currentEventId = null;
 let currentUsername = '';
+let gameToday = ''; // current game day (server time), from check-auth
 
 // Check authentication
 async function checkAuth() {
@@ -35,6 +31,7 @@ async function checkAuth() {
         }
         
         currentUsername = data.username;
+        gameToday = data.game_today;
         let displayText = `👤 ${currentUsername}`;
         if (data.rank) {
             displayText += ` (${data.rank})`;
@@ -127,26 +124,215 @@ async function loadMembers() {
     }
 }
 
-// Load assignments
-async function loadAssignments() {
+// Query string selecting the current event (empty for the undated plan)
+function eventQuery(separator) {
+    return currentEventId ? `${separator}event_id=${currentEventId}` : '';
+}
+
+// Load storm events into the event selector
+async function loadEvents() {
     try {
-        const response = await fetch(`${API_URL}?task_force=${currentTaskForce}`);
-        const data = await response.json();
+        const response = await fetch(EVENTS_URL);
+        stormEvents = await response.json();
+    } catch (error) {
+        console.error('Error loading storm events:', error);
+        stormEvents = [];
+    }
+    
+    const select = document.getElementById('stormEvent');
+    select.innerHTML = '<option value="">Undated plan</option>' + stormEvents.map(event =>
+        `<option value="${event.id}">${event.event_date}${event.notes ? ' - ' + escapeHtml(event.notes) : ''}</option>`
+    ).join('');
+    select.value = currentEventId || '';
+}
+
+// Create a storm event and switch to it
+async function createEvent() {
+    const eventDate = document.getElementById('newEventDate').value;
+    if (!eventDate) {
+        alert('Please pick the event date first.');
+        return;
+    }
+    
+    try {
+        const response = await fetch(EVENTS_URL, {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify({ event_date: eventDate })
+        });
+        if (!response.ok) {
+            throw new Error(await response.text());
+        }
         
-        // Initialize empty assignments for all buildings
-        assignments = {};
-        BUILDINGS.forEach(building => {
-            assignments[building.id] = [];
+        const event = await response.json();
+        currentEventId = event.id;
+        await loadEvents();
+        await selectEvent();
+    } catch (error) {
+        console.error('Error creating storm event:', error);
+        alert('Failed to create event: ' + error.message);
+    }
+}
+
+// Show the assignments, signups and attendance of the selected event
+async function selectEvent() {
+    proposals = {};
+    await loadAssignments();
+    await renderEventDetails();
+    document.getElementById('mail-output').style.display = 'none';
+}
+
+// Render signup counts, and attendance and results once the event has taken place
+async function renderEventDetails() {
+    const signupsLine = document.getElementById('event-signups');
+    const attendanceSection = document.getElementById('attendance-section');
+    if (!currentEventId) {
+        signupsLine.style.display = 'none';
+        attendanceSection.style.display = 'none';
+        return;
+    }
+    
+    try {
+        const response = await fetch(`${EVENTS_URL}/${currentEventId}`);
+        const event = await response.json();
+        
+        const counts = event.signup_counts;
+        signupsLine.textContent = `Signups: A ${counts.A} · B ${counts.B} · either ${counts.either} · unavailable ${counts.unavailable}`;
+        signupsLine.style.display = 'block';
+        
+        if (event.event_date > gameToday) {
+            attendanceSection.style.display = 'none';
+            return;
+        }
+        
+        let resultsHtml = '';
+        ['A', 'B'].forEach(taskForce => {
+            const result = event.results.find(r => r.task_force === taskForce) || {};
+            resultsHtml += `<div class="form-group" data-result-task-force="${taskForce}">`;
+            resultsHtml += `<label>Task Force ${taskForce}:</label>`;
+            resultsHtml += `<select class="result-select" style="max-width: 150px;">`;
+            ['', 'win', 'loss', 'draw'].forEach(value => {
+                resultsHtml += `<option value="${value}" ${value === (result.result || '') ? 'selected' : ''}>${value || '-- No result --'}</option>`;
+            });
+            resultsHtml += `</select>`;
+            resultsHtml += `<input type="number" class="our-score" min="0" placeholder="Our score" value="${result.our_score ?? ''}" style="max-width: 140px;">`;
+            resultsHtml += `<input type="number" class="enemy-score" min="0" placeholder="Enemy score" value="${result.enemy_score ?? ''}" style="max-width: 140px;">`;
+            resultsHtml += `<input type="text" class="opponent" placeholder="Opponent" value="${escapeHtml(result.opponent || '')}" style="max-width: 200px;">`;
+            resultsHtml += `</div>`;
         });
+        document.getElementById('result-fields').innerHTML = resultsHtml;
         
-        // Fill in saved assignments
-        data.forEach(assignment => {
-            if (!assignments[assignment.building_id]) {
-                assignments[assignment.building_id] = [];
-            }
-            assignments[assignment.building_id].push(assignment.member_id);
+        // Everyone who signed up, was assigned or already has attendance recorded
+        const roster = event.signups.filter(s => s.availability || s.task_force || s.attended !== null);
+        let attendanceHtml = roster.length === 0 ? '<p class="help-text">Nobody signed up or was assigned.</p>' : '';
+        roster.forEach(signup => {
+            const detail = signup.task_force ? `Task Force ${signup.task_force}` : (signup.availability || 'not signed up');
+            attendanceHtml += `<label style="display: block;">`;
+            attendanceHtml += `<input type="checkbox" class="attended-checkbox" data-member-id="${signup.member_id}" ${signup.attended ? 'checked' : ''}> `;
+            attendanceHtml += `${escapeHtml(signup.member_name)} (${signup.member_rank}) - ${escapeHtml(detail)}`;
+            attendanceHtml += `</label>`;
+        });
+        document.getElementById('attendance-list').innerHTML = attendanceHtml;
+        attendanceSection.style.display = 'block';
+    } catch (error) {
+        console.error('Error loading storm event:', error);
+        signupsLine.style.display = 'none';
+        attendanceSection.style.display = 'none';
+    }
+}
+
+// Save attendance and results of the selected event
+async function saveAttendance() {
+    const attendance = [...document.querySelectorAll('.attended-checkbox')].map(checkbox => ({
+        member_id: parseInt(checkbox.dataset.memberId),
+        attended: checkbox.checked
+    }));
+    
+    const results = [];
+    document.querySelectorAll('[data-result-task-force]').forEach(row => {
+        const result = row.querySelector('.result-select').value;
+        if (!result) {
+            return;
+        }
+        const ourScore = row.querySelector('.our-score').value;
+        const enemyScore = row.querySelector('.enemy-score').value;
+        const opponent = row.querySelector('.opponent').value.trim();
+        results.push({
+            task_force: row.dataset.resultTaskForce,
+            result,
+            our_score: ourScore === '' ? null : parseInt(ourScore),
+            enemy_score: enemyScore === '' ? null : parseInt(enemyScore),
+            opponent: opponent || null
         });
+    });
+    
+    try {
+        for (const [path, body] of [['attendance', { attendance }], ['results', { results }]]) {
+            const response = await fetch(`${EVENTS_URL}/${currentEventId}/${path}`, {
+                method: 'PUT',
+                headers: { 'Content-Type': 'application/json' },
+                body: JSON.stringify(body)
+            });
+            if (!response.ok) {
+                throw new Error(await response.text());
+            }
+        }
+        alert('✓ Attendance and results saved!');
+    } catch (error) {
+        console.error('Error saving attendance:', error);
+        alert('Failed to save attendance: ' + error.message);
+    }
+}
+
+// Load building catalogue
+async function loadBuildings() {
+    try {
+        const response = await fetch(BUILDINGS_URL);
+        BUILDINGS = await response.json();
+    } catch (error) {
+        console.error('Error loading buildings:', error);
+        BUILDINGS = [];
+    }
+}
+
+// Group buildings by stage using the given titles
+function buildingsByStage(titles) {
+    const stageNums = [...new Set(BUILDINGS.map(b => b.stage))].sort((a, b) => a - b);
+    return stageNums.map(num => ({
+        num,
+        title: titles[num] || `Stage ${num}`,
+        buildings: BUILDINGS.filter(b => b.stage === num)
+    }));
+}
+
+// Fill the assignments from a list of { building_id, member_id, position }
+function applyAssignments(list) {
+    assignments = {};
+    BUILDINGS.forEach(building => {
+        assignments[building.id] = [];
+    });
+    
+    list.forEach(assignment => {
+        if (!assignments[assignment.building_id]) {
+            assignments[assignment.building_id] = [];
+        }
+        assignments[assignment.building_id][assignment.position - 1] = assignment.member_id;
+    });
+}
+
+// Load assignments (an unsaved auto-assign proposal takes precedence)
+async function loadAssignments() {
+    if (proposals[currentTaskForce]) {
+        applyAssignments(proposals[currentTaskForce].assignments);
+        renderBuildings();
+        return;
+    }
+    
+    try {
+        const response = await fetch(`${API_URL}?task_force=${currentTaskForce}${eventQuery('&')}`);
+        const data = await response.json();
         
+        applyAssignments(data);
         renderBuildings();
     } catch (error) {
         console.error('Error loading assignments:', error);
@@ -188,10 +374,7 @@ function renderBuildings() {
     let html = '';
     
     // Group buildings by stage
-    const stages = [
-        { num: 1, title: 'Stage 1 - Immediate (0:00)', buildings: BUILDINGS.filter(b => b.stage === 1) },
-        { num: 2, title: 'Stage 2 - After 10 Minutes', buildings: BUILDINGS.filter(b => b.stage === 2) }
-    ];
+    const stages = buildingsByStage(STAGE_TITLES);
     
     stages.forEach(stage => {
         html += `<div class="storm-stage">`;
@@ -204,19 +387,19 @@ function renderBuildings() {
             
             html += `<div class="storm-building ${priorityClass}">`;
             html += `<div class="building-header">`;
-            html += `<h5>${building.name}</h5>`;
+            html += `<h5>${escapeHtml(building.name)}</h5>`;
             html += `<span class="priority-badge ${priorityClass}">${building.priority}</span>`;
             html += `</div>`;
             html += `<div class="building-info">`;
-            html += `<span class="points">⚡ ${building.points}</span>`;
+            html += `<span class="points">⚡ ${building.points}/s</span>`;
             if (building.boost) {
-                html += `<span class="boost">✨ ${building.boost}</span>`;
+                html += `<span class="boost">✨ ${escapeHtml(building.boost)}</span>`;
             }
             html += `</div>`;
             
             // Member slots
             html += `<div class="member-slots">`;
-            for (let i = 0; i < 4; i++) {
+            for (let i = 0; i < building.capacity; i++) {
                 const memberId = assignedMembers[i] || '';
                 html += `<div class="slot-container">`;
                 html += `<label>Slot ${i + 1}</label>`;
@@ -345,6 +528,69 @@ function updateDropdownList(container, searchTerm) {
 }
 
 // Save assignments
+async function saveAssignments() {
+    const payload = [];
+    Object.keys(assignments).forEach(buildingId => {
+        (assignments[buildingId] || []).forEach((memberId, slot) => {
+            if (memberId) {
+                payload.push({ building_id: buildingId, member_id: memberId, position: slot + 1 });
+            }
+        });
+    });
+    
+    try {
+        const response = await fetch(API_URL, {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify({ event_id: currentEventId || 0, task_force: currentTaskForce, assignments: payload })
+        });
+        
+        if (!response.ok) {
+            throw new Error(await response.text());
+        }
+        
+        delete proposals[currentTaskForce];
+        alert(`✓ Task Force ${currentTaskForce} assignments saved!`);
+    } catch (error) {
+        console.error('Error saving assignments:', error);
+        alert('Failed to save assignments: ' + error.message);
+    }
+}
+
+// Auto-assign both task forces by latest power
+async function autoAssign() {
+    if (!confirm('Replace the assignments on screen with an auto-assigned proposal for Task Force A and B? Nothing is saved until you save each task force.')) {
+        return;
+    }
+    
+    try {
+        // A selected event proposes from its signups, otherwise from every member
+        const response = await fetch(`${API_URL}/auto-assign`, {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify(currentEventId ? { event_id: currentEventId } : {})
+        });
+        if (!response.ok) {
+            throw new Error(await response.text());
+        }
+        
+        const data = await response.json();
+        proposals = {};
+        data.task_forces.forEach(proposal => {
+            proposals[proposal.task_force] = proposal;
+        });
+        await loadAssignments();
+        
+        const summary = data.task_forces
+            .map(proposal => `Task Force ${proposal.task_force}: ${proposal.members} members, ${proposal.total_power.toLocaleString()} power`)
+            .join('\n');
+        alert(`✓ Proposal ready - review and save each task force.\n\n${summary}\nSubstitutes: ${data.substitutes.length}`);
+    } catch (error) {
+        console.error('Error auto-assigning:', error);
+        alert('Failed to auto-assign: ' + error.message);
+    }
+}
+
 // Generate mail
 function generateMail() {
     // Check if there are any assignments
@@ -432,10 +678,8 @@ function generateMail() {
     mail += `BUILDING ASSIGNMENTS:\n\n`;
     
     // Group by stage
-    const stages = [
-        { num: 1, title: 'STAGE 1 - IMMEDIATE', buildings: BUILDINGS.filter(b => b.stage === 1) },
-        { num: 2, title: 'STAGE 2 - AFTER 10 MIN', buildings: BUILDINGS.filter(b => b.stage === 2) }
-    ];
+    const stages = buildingsByStage({ 1: 'STAGE 1 - IMMEDIATE', 2: 'STAGE 2 - AFTER 10 MIN' })
+        .map(stage => ({ ...stage, title: stage.title.toUpperCase() }));
     
     stages.forEach(stage => {
         mail += `\n${stage.title}:\n`;
@@ -467,6 +711,53 @@ function generateMail() {
     document.getElementById('mail-output').scrollIntoView({ behavior: 'smooth' });
 }
 
+// Show a generated message in the mail output
+function showMessage(text) {
+    document.getElementById('mail-content').textContent = text;
+    document.getElementById('mail-output').style.display = 'block';
+    document.getElementById('mail-output').scrollIntoView({ behavior: 'smooth' });
+}
+
+// Generate the chat message for the saved plan from the template in Settings
+async function generateChatMessage() {
+    try {
+        const response = await fetch(`${API_URL}/message?task_force=${currentTaskForce}${eventQuery('&')}`);
+        if (!response.ok) {
+            throw new Error(await response.text());
+        }
+        
+        const data = await response.json();
+        if (data.count === 0) {
+            alert('No saved assignments found. Save the assignments first - messages use the saved plan.');
+            return;
+        }
+        showMessage(data.message);
+    } catch (error) {
+        console.error('Error generating chat message:', error);
+        alert('Failed to generate chat message: ' + error.message);
+    }
+}
+
+// Generate one personal message per assigned member of the saved plan
+async function generateMemberMessages() {
+    try {
+        const response = await fetch(`${API_URL}/member-messages?task_force=${currentTaskForce}${eventQuery('&')}`);
+        if (!response.ok) {
+            throw new Error(await response.text());
+        }
+        
+        const data = await response.json();
+        if (data.messages.length === 0) {
+            alert('No saved assignments found. Save the assignments first - messages use the saved plan.');
+            return;
+        }
+        showMessage(data.messages.map(m => m.message).join('\n\n───────────────────────────────\n\n'));
+    } catch (error) {
+        console.error('Error generating personal messages:', error);
+        alert('Failed to generate personal messages: ' + error.message);
+    }
+}
+
 // Copy mail to clipboard
 async function copyMail() {
     const mailText = document.getElementById('mail-content').textContent;
@@ -501,7 +792,7 @@ async function clearAssignments() {
     }
     
     try {
-        const response = await fetch(`${API_URL}/${currentTaskForce}`, {
+        const response = await fetch(`${API_URL}/${currentTaskForce}${eventQuery('?')}`, {
             method: 'DELETE'
         });
         
@@ -510,6 +801,7 @@ async function clearAssignments() {
         }
         
         // Reset local assignments
+        delete proposals[currentTaskForce];
         assignments = {};
         BUILDINGS.forEach(building => {
             assignments[building.id] = [];
@@ -532,7 +824,17 @@ function escapeHtml(text) {
 }
 
 // Event listeners
+document.getElementById('auto-assign-btn').addEventListener('click', autoAssign);
+document.getElementById('create-event-btn').addEventListener('click', createEvent);
+document.getElementById('save-attendance-btn').addEventListener('click', saveAttendance);
+document.getElementById('stormEvent').addEventListener('change', (e) => {
+    currentEventId = parseInt(e.target.value) || null;
+    selectEvent();
+});
+document.getElementById('save-assignments-btn').addEventListener('click', saveAssignments);
 document.getElementById('generate-mail-btn').addEventListener('click', generateMail);
+document.getElementById('chat-message-btn').addEventListener('click', generateChatMessage);
+document.getElementById('member-messages-btn').addEventListener('click', generateMemberMessages);
 document.getElementById('clear-assignments-btn').addEventListener('click', clearAssignments);
 document.getElementById('copy-mail-btn').addEventListener('click', copyMail);
 
@@ -551,6 +853,8 @@ document.querySelectorAll('input[name="taskForce"]').forEach(radio => {
     if (authenticated) {
         await setupEventListeners();
         await loadMembers();
+        await loadBuildings();
+        await loadEvents();
         await loadAssignments();
     }
 })();
@@ -11,6 +11,7 @@ let allHistory = [];
 let currentUsername = '';
 let currentUserRank = '';
 let isAdmin = false;
+let gameToday = null; // current game day (server time), from check-auth
 
 // Check authentication on page load
 async function checkAuth() {
@@ -24,6 +25,7 @@ async function checkAuth() {
         }
         
         currentUsername = data.username;
+        gameToday = new Date(data.game_today + 'T00:00:00');
         currentUserRank = data.rank || '';
         isAdmin = data.is_admin || false;
         
@@ -135,7 +137,7 @@ function formatDisplayDate(dateStr) {
 
 // Initialize current week
 function initializeWeek() {
-    currentWeekStart = getMondayOfWeek(new Date());
+    currentWeekStart = getMondayOfWeek(gameToday);
     updateWeekDisplay();
 }
 
@@ -219,7 +221,7 @@ function showDailyMessageSection() {
     document.getElementById('daily-message-section').style.display = 'block';
     
     // Set default date to today
-    const today = new Date();
+    const today = new Date(gameToday);
     document.getElementById('daily-message-date').value = formatDate(today);
     
     // Scroll to message section
@@ -394,7 +396,7 @@ function renderScheduleGrid() {
         date.setDate(date.getDate() + i);
         const dateStr = formatDate(date);
         const schedule = schedules[dateStr];
-        const isPast = date < new Date(new Date().setHours(0, 0, 0, 0));
+        const isPast = date < gameToday;
         
         html += `<div class="day-card ${isPast ? 'past' : ''}">`;
         html += `<div class="day-header">`;
@@ -484,8 +486,7 @@ function openScheduleModal(dateStr) {
     // Show/hide attendance group
     const attendanceGroup = document.getElementById('attendance-group');
     const dateObj = new Date(dateStr + 'T00:00:00');
-    const today = new Date(new Date().setHours(0, 0, 0, 0));
-    const isNotFuture = dateObj <= today;
+    const isNotFuture = dateObj <= gameToday;
     
     if (isNotFuture && schedule) {
         attendanceGroup.style.display = 'block';
@@ -2,6 +2,9 @@ const API_BASE = '/api';
 
 let selectedFiles = []; // Array to hold multiple files
 const MAX_FILES = 25;
+let canReview = false; // R4/R5/admin can review staged uploads
+let reviewMembers = []; // Members for the review match dropdowns
+const OCR_POLL_INTERVAL = 1500; // How often to check on a queued OCR job (ms)
 
 // Check authentication
 async function checkAuth() {
@@ -240,13 +243,21 @@ processImageBtn.addEventListener('click', async () => {
     processImageBtn.disabled = true;
     
     try {
+        // Scrolled power ranking screenshots are merged into one list by rank number
+        if (screenshotType === 'power' && selectedFiles.length > 1) {
+            await processStitchedPowerScreenshots();
+            return;
+        }
+        
         const typeLabel = screenshotType === 'power' ? 'Power Rankings' : 'VS Points';
         showResult(`🔍 Processing ${selectedFiles.length} ${typeLabel} screenshot${selectedFiles.length > 1 ? 's' : ''} with OCR...`, 'info');
         
-        let totalSuccess = 0;
+        let totalStaged = 0;
+        let totalUnmatched = 0;
         let totalFailed = 0;
         const allErrors = [];
         const detectedDays = []; // For VS points
+        const batchIds = [];
         
         // Determine API endpoint based on screenshot type
         const apiEndpoint = screenshotType === 'power' 
@@ -269,29 +280,32 @@ processImageBtn.addEventListener('click', async () => {
                     formData.append('week', week);
                 }
                 
-                const response = await fetch(apiEndpoint, {
-                    method: 'POST',
-                    body: formData
-                });
+                // Add the ranking tab for power screenshots (detected when left empty)
+                const metric = document.getElementById('ranking-metric').value;
+                if (screenshotType === 'power' && metric) {
+                    formData.append('metric', metric);
+                }
+                
+                const { ok, result } = await runOcrJob(apiEndpoint, formData,
+                    `${typeLabel} screenshot ${i + 1} of ${selectedFiles.length}: ${file.name}`);
                 
-                if (!response.ok) {
-                    const error = await response.text();
-                    throw new Error(error);
+                if (!ok) {
+                    const jobNote = result.job ? ` (job #${result.job.id} can be retried)` : '';
+                    throw new Error(result.error + jobNote);
                 }
                 
-                const result = await response.json();
-                totalSuccess += result.success_count || 0;
+                const batch = result.batch;
+                batchIds.push(result.batch_id);
+                totalStaged += batch.row_count;
+                totalUnmatched += batch.row_count - batch.matched_count;
                 
                 // Track detected day for VS points
-                if (screenshotType === 'vs-points' && result.day) {
-                    detectedDays.push(`${file.name} → ${result.day}`);
+                if (screenshotType === 'vs-points') {
+                    detectedDays.push(`${file.name} → ${batch.day || 'not detected'}`);
                 }
                 
-                if (result.errors && result.errors.length > 0) {
-                    allErrors.push(`<strong>${file.name}:</strong> ${result.errors.join(', ')}`);
-                    totalFailed++;
-                } else if (result.not_found_members && result.not_found_members.length > 0) {
-                    allErrors.push(`<strong>${file.name}:</strong> ${result.not_found_members.length} members not found in database`);
+                if (batch.row_count > batch.matched_count) {
+                    allErrors.push(`<strong>${file.name}:</strong> ${batch.row_count - batch.matched_count} rows need a member picked during review`);
                 }
             } catch (error) {
                 console.error(`Error processing ${file.name}:`, error);
@@ -304,7 +318,7 @@ processImageBtn.addEventListener('click', async () => {
         let html = `<div class="result-box result-success">
             <strong>✅ Processed ${selectedFiles.length} ${typeLabel} screenshot${selectedFiles.length > 1 ? 's' : ''}</strong><br>
             <div style="margin-top: 10px;">
-                <strong>Total Records Updated:</strong> ${totalSuccess}`;
+                <strong>Rows Staged for Review:</strong> ${totalStaged} | <strong>Unmatched:</strong> ${totalUnmatched}`;
                 
         if (totalFailed > 0) {
             html += ` | <strong>Failed:</strong> ${totalFailed}`;
@@ -321,11 +335,20 @@ processImageBtn.addEventListener('click', async () => {
             html += `<br><br><strong>Issues:</strong><br><div style="max-height: 200px; overflow-y: auto; margin-top: 5px;">${allErrors.join('<br>')}</div>`;
         }
         
+        if (!canReview && batchIds.length > 0) {
+            html += `<br><br>An R4 or R5 will review and approve ${batchIds.length > 1 ? 'these uploads' : 'this upload'} before the data is saved.`;
+        }
+        
         html += '</div>';
         document.getElementById('result-container').innerHTML = html;
         
+        if (canReview && batchIds.length > 0) {
+            await loadReviewQueue();
+            openBatch(batchIds[0]);
+        }
+        
         // Clear on success after delay
-        if (totalSuccess > 0) {
+        if (totalStaged > 0) {
             setTimeout(() => {
                 selectedFiles = [];
                 imageInput.value = '';
@@ -341,6 +364,124 @@ processImageBtn.addEventListener('click', async () => {
     }
 });
 
+// Read a JSON response, or wrap a plain-text error
+async function readResponse(response) {
+    const contentType = response.headers.get('Content-Type') || '';
+    return contentType.includes('application/json') ? await response.json() : { error: await response.text() };
+}
+
+// Upload screenshots; OCR runs as a background job, so wait for it while showing its progress
+async function runOcrJob(url, formData, label) {
+    const response = await fetch(url, { method: 'POST', body: formData });
+    const result = await readResponse(response);
+    if (response.status !== 202) {
+        return { ok: response.ok, result };
+    }
+    return waitForOcrJob(result.job_id, label);
+}
+
+// Poll an OCR job until it has succeeded or failed
+async function waitForOcrJob(jobId, label) {
+    while (true) {
+        await new Promise(resolve => setTimeout(resolve, OCR_POLL_INTERVAL));
+        const response = await fetch(`${API_BASE}/ocr-jobs/${jobId}/result`);
+        const result = await readResponse(response);
+        if (response.status !== 202) {
+            return { ok: response.ok, result };
+        }
+        showResult(`🔍 ${escapeHtml(label)}: ${describeOcrJob(result.job)}`, 'info');
+    }
+}
+
+// Describe where a queued or running OCR job is
+function describeOcrJob(job) {
+    if (job.status === 'queued') return 'waiting for a free OCR worker...';
+    if (job.progress_total > 1) {
+        return `${escapeHtml(job.stage)} (${Math.round(100 * job.progress_done / job.progress_total)}%)`;
+    }
+    return `${escapeHtml(job.stage)}...`;
+}
+
+// Re-queue a failed stitching job with the screenshots it kept
+async function retryStitchJob(jobId) {
+    const response = await fetch(`${API_BASE}/ocr-jobs/${jobId}/retry`, { method: 'POST' });
+    if (!response.ok) {
+        showResult(`❌ Retry failed: ${escapeHtml(await response.text())}`, 'error');
+        return;
+    }
+    const { ok, result } = await waitForOcrJob(jobId, 'Stitching Power Rankings screenshots');
+    showStitchResult(ok, result);
+}
+
+// Upload all power ranking screenshots in one request and show the coverage report
+async function processStitchedPowerScreenshots() {
+    showResult(`🔍 Processing and stitching ${selectedFiles.length} Power Rankings screenshots with OCR...`, 'info');
+    
+    const formData = new FormData();
+    selectedFiles.forEach(file => formData.append('images', file));
+    const metric = document.getElementById('ranking-metric').value;
+    if (metric) formData.append('metric', metric);
+    
+    const { ok, result } = await runOcrJob(`${API_BASE}/power-history/process-screenshots`, formData,
+        `Stitching ${selectedFiles.length} Power Rankings screenshots`);
+    showStitchResult(ok, result);
+    
+    if (ok) {
+        setTimeout(() => {
+            selectedFiles = [];
+            imageInput.value = '';
+            updatePreview();
+        }, 5000);
+    }
+}
+
+// Show the outcome and coverage report of a stitched upload
+async function showStitchResult(ok, result) {
+    const coverage = result.coverage;
+    
+    let html = `<div class="result-box ${ok ? 'result-success' : 'result-error'}">
+        <strong>${ok ? '✅ ' + escapeHtml(result.message) : '❌ ' + escapeHtml(result.error)}</strong>`;
+    
+    if (coverage) {
+        html += `<div style="margin-top: 10px;">
+            <strong>Rows:</strong> ${coverage.unique_rows} unique of ${coverage.total_rows} read |
+            <strong>Duplicates:</strong> ${coverage.duplicate_rows} |
+            <strong>Coverage:</strong> ${coverage.coverage_percent}% of ranks 1-${coverage.highest_rank}
+        </div>`;
+        
+        if (coverage.gaps.length > 0) {
+            const gaps = coverage.gaps.map(g => g.from === g.to ? `#${g.from}` : `#${g.from}-${g.to}`).join(', ');
+            html += `<br><strong>⚠️ Missing ranks:</strong> ${gaps} - take another screenshot covering these rows`;
+        }
+        
+        if (coverage.conflicts.length > 0) {
+            html += `<br><br><strong>Conflicts:</strong><br><div style="max-height: 150px; overflow-y: auto; margin-top: 5px; font-size: 13px;">
+                ${coverage.conflicts.map(c => `Rank ${c.rank}: ${escapeHtml(c.reason)} - kept ${escapeHtml(c.kept.member_name)}, dropped ${escapeHtml(c.discarded.member_name)}`).join('<br>')}
+            </div>`;
+        }
+        
+        html += `<br><strong>Screenshots:</strong><br><div style="max-height: 150px; overflow-y: auto; margin-top: 5px; font-size: 13px;">
+            ${coverage.pages.map(p => `${escapeHtml(p.file_name)} → ${p.error ? '❌ ' + escapeHtml(p.error) : p.max_rank ? `ranks ${p.min_rank}-${p.max_rank} (${p.row_count} rows)` : `${p.row_count} rows, no rank numbers read`}`).join('<br>')}
+        </div>`;
+    }
+    
+    if (ok && !canReview) {
+        html += `<br>An R4 or R5 will review and approve this upload before the data is saved.`;
+    }
+    
+    if (!ok && result.job && result.job.status === 'failed') {
+        html += `<br><button class="btn btn-secondary" onclick="retryStitchJob(${result.job.id})">🔁 Retry</button>`;
+    }
+    
+    html += '</div>';
+    document.getElementById('result-container').innerHTML = html;
+    
+    if (ok && canReview) {
+        await loadReviewQueue();
+        openBatch(result.batch_id);
+    }
+}
+
 // Process manual text entry
 document.getElementById('process-text-btn').addEventListener('click', async () => {
     const textInput = document.getElementById('text-input');
@@ -411,21 +552,22 @@ document.getElementById('process-text-btn').addEventListener('click', async () =
         const result = await response.json();
         
         let html = `<div class="result-box result-success">
-            <strong>✅ ${result.message}</strong><br>
-            <div style="margin-top: 10px;">
-                <strong>Successful:</strong> ${result.success_count} | 
-                <strong>Failed:</strong> ${result.failed_count}
-            </div>`;
+            <strong>✅ ${result.message}</strong>`;
         
-        if (result.errors && result.errors.length > 0) {
-            html += `<br><br><strong>Errors:</strong><br><div style="max-height: 200px; overflow-y: auto; margin-top: 5px;">${result.errors.join('<br>')}</div>`;
+        if (!canReview) {
+            html += `<br><br>An R4 or R5 will review and approve this upload before the data is saved.`;
         }
         
         html += '</div>';
         document.getElementById('result-container').innerHTML = html;
         
+        if (canReview) {
+            await loadReviewQueue();
+            openBatch(result.batch_id);
+        }
+        
         // Clear on success after delay
-        if (result.success_count > 0) {
+        if (result.batch_id) {
             setTimeout(() => {
                 textInput.value = '';
             }, 2000);
@@ -439,6 +581,287 @@ document.getElementById('process-text-btn').addEventListener('click', async () =
     }
 });
 
+// Load pending OCR batches for review
+async function loadReviewQueue() {
+    const list = document.getElementById('review-list');
+    
+    try {
+        const response = await fetch(`${API_BASE}/ocr-batches?status=pending`);
+        if (!response.ok) throw new Error(await response.text());
+        const batches = await response.json();
+        
+        if (batches.length === 0) {
+            list.innerHTML = '<p class="help-text">No uploads waiting for review.</p>';
+            return;
+        }
+        
+        list.innerHTML = batches.map(batch => `
+            <div class="batch-item" data-batch-id="${batch.id}">
+                <div>
+                    <strong>#${batch.id} ${batchKindLabel(batch)}</strong>
+                    ${batch.day ? ` - ${escapeHtml(batch.day)}` : ''}${batch.week_date ? ` (week of ${escapeHtml(batch.week_date)})` : ''}
+                    <div class="help-text" style="margin: 2px 0 0 0; font-size: 12px;">
+                        ${escapeHtml(batch.created_by_name || 'unknown')} · ${new Date(batch.created_at).toLocaleString()} · ${batch.source}
+                    </div>
+                </div>
+                <div>${batch.matched_count}/${batch.row_count} matched</div>
+            </div>
+        `).join('');
+        
+        list.querySelectorAll('.batch-item').forEach(item => {
+            item.addEventListener('click', () => openBatch(parseInt(item.dataset.batchId, 10)));
+        });
+    } catch (error) {
+        console.error('Failed to load review queue:', error);
+        list.innerHTML = `<div class="result-box result-error">Failed to load review queue: ${escapeHtml(error.message)}</div>`;
+    }
+}
+
+// Load members for the match dropdowns
+async function loadReviewMembers() {
+    try {
+        const response = await fetch(`${API_BASE}/members`);
+        if (response.ok) {
+            reviewMembers = await response.json();
+            reviewMembers.sort((a, b) => a.name.localeCompare(b.name));
+        }
+    } catch (error) {
+        console.error('Failed to load members:', error);
+    }
+}
+
+// Fields below this confidence are highlighted for the reviewer
+const UNCERTAIN_CONFIDENCE = 60;
+
+function uncertainClass(confidence, minConfidence) {
+    if (confidence < 0) return ''; // not measured
+    return confidence < Math.max(minConfidence, UNCERTAIN_CONFIDENCE) ? 'cell-uncertain' : '';
+}
+
+// Confidence as shown in the review table; negative means OCR couldn't measure it
+function formatConfidence(confidence) {
+    return confidence < 0 ? '?' : `${Math.round(confidence)}%`;
+}
+
+// Crop each field's bounding box out of the original screenshot into its canvas
+function drawFieldCrops(batch) {
+    const canvases = Array.from(document.querySelectorAll('#review-detail canvas.ocr-crop'));
+    const pages = [...new Set(canvases.map(canvas => canvas.dataset.page))];
+    
+    pages.forEach(page => {
+        const img = new Image();
+        img.onload = () => {
+            canvases.filter(canvas => canvas.dataset.page === page).forEach(canvas => {
+                const box = JSON.parse(canvas.dataset.box);
+                const scale = Math.min(1, 160 / box.width);
+                canvas.width = Math.max(1, Math.round(box.width * scale));
+                canvas.height = Math.max(1, Math.round(box.height * scale));
+                canvas.getContext('2d').drawImage(img, box.x, box.y, box.width, box.height, 0, 0, canvas.width, canvas.height);
+            });
+        };
+        img.src = batch.page_count > 0
+            ? `${API_BASE}/ocr-batches/${batch.id}/image?page=${page}`
+            : `${API_BASE}/ocr-batches/${batch.id}/image`;
+    });
+}
+
+function scoreClass(score) {
+    if (score >= 90) return 'score-high';
+    if (score >= 70) return 'score-medium';
+    return 'score-low';
+}
+
+// Show a batch with editable rows
+async function openBatch(batchId) {
+    const detail = document.getElementById('review-detail');
+    
+    try {
+        const response = await fetch(`${API_BASE}/ocr-batches/${batchId}`);
+        if (!response.ok) throw new Error(await response.text());
+        const batch = await response.json();
+        
+        const memberOptions = (selectedId) => '<option value="">— not matched —</option>' +
+            reviewMembers.map(m => `<option value="${m.id}" ${m.id === selectedId ? 'selected' : ''}>${escapeHtml(m.name)}</option>`).join('');
+        
+        let html = `<h4>Batch #${batch.id} - ${batchKindLabel(batch)}</h4>`;
+        
+        if (batch.kind === 'power') {
+            const metricOptions = Object.entries(rankingMetricLabels)
+                .map(([value, label]) => `<option value="${value}" ${(batch.metric || 'power') === value ? 'selected' : ''}>${label}</option>`).join('');
+            html += `
+                <div style="display: flex; gap: 10px; margin-bottom: 10px; flex-wrap: wrap;">
+                    <label>Ranking tab <select id="review-metric" class="form-input">${metricOptions}</select></label>
+                </div>`;
+        }
+        
+        if (batch.kind === 'vs_points') {
+            const dayOptions = ['monday', 'tuesday', 'wednesday', 'thursday', 'friday', 'saturday', 'sunday']
+                .map(d => `<option value="${d}" ${batch.day === d ? 'selected' : ''}>${d.charAt(0).toUpperCase() + d.slice(1)}</option>`).join('');
+            html += `
+                <div style="display: flex; gap: 10px; margin-bottom: 10px; flex-wrap: wrap;">
+                    <label>Week of <input type="date" id="review-week" class="form-input" value="${batch.week_date || ''}"></label>
+                    <label>Day <select id="review-day" class="form-input"><option value="">— select —</option>${dayOptions}</select></label>
+                </div>`;
+        }
+        
+        if (batch.page_count > 0) {
+            const links = Array.from({ length: batch.page_count }, (_, page) =>
+                `<a href="${API_BASE}/ocr-batches/${batch.id}/image?page=${page}" target="_blank">${page + 1}</a>`).join(' · ');
+            html += `<p>🖼️ Original screenshots: ${links}</p>`;
+        } else if (batch.has_image) {
+            html += `<p><a href="${API_BASE}/ocr-batches/${batch.id}/image" target="_blank">🖼️ View original screenshot</a></p>`;
+        }
+        
+        const showRank = batch.rows.some(row => row.rank);
+        
+        html += `
+            <div style="overflow-x: auto;">
+            <table class="review-table">
+                <thead>
+                    <tr>${showRank ? '<th>#</th>' : ''}<th>OCR Text</th><th>Member</th><th>${batch.kind === 'power' ? rankingMetricColumn(batch) : 'Points'}</th><th>Conf.</th><th>Match</th><th>Skip</th></tr>
+                </thead>
+                <tbody>
+                    ${batch.rows.map(row => `
+                        <tr data-row-id="${row.id}" class="${row.skip ? 'row-skipped' : ''}">
+                            ${showRank ? `<td>${row.rank || '?'}</td>` : ''}
+                            <td title="${escapeHtml(row.raw_text)}" class="${uncertainClass(row.name_confidence, batch.min_confidence)}">
+                                ${batch.has_image && row.name_box ? `<canvas class="ocr-crop" data-page="${row.page}" data-box='${JSON.stringify(row.name_box)}'></canvas><br>` : ''}
+                                ${escapeHtml(row.parsed_name)}${row.corrected ? ' ✏️' : ''}
+                                ${row.low_confidence ? '<br><small class="score-low">rejected: low confidence</small>' : ''}
+                            </td>
+                            <td><select class="form-input review-member">${memberOptions(row.member_id)}</select></td>
+                            <td class="${uncertainClass(row.value_confidence, batch.min_confidence)}">
+                                ${batch.has_image && row.value_box ? `<canvas class="ocr-crop" data-page="${row.page}" data-box='${JSON.stringify(row.value_box)}'></canvas><br>` : ''}
+                                <input type="number" class="form-input review-value" value="${row.value}" title="OCR: ${escapeHtml(row.value_text)}">
+                            </td>
+                            <td title="Name ${formatConfidence(row.name_confidence)} / Value ${formatConfidence(row.value_confidence)}">${formatConfidence(row.confidence)}</td>
+                            <td class="${scoreClass(row.match_score)}">${row.match_score}%</td>
+                            <td><input type="checkbox" class="review-skip" ${row.skip ? 'checked' : ''}></td>
+                        </tr>
+                    `).join('')}
+                </tbody>
+            </table>
+            </div>
+            <div class="review-actions" style="margin-top: 15px;">
+                <button id="review-save-btn" class="btn btn-secondary">💾 Save Corrections</button>
+                ${batch.has_image ? '<button id="review-reprocess-btn" class="btn btn-secondary">🔁 Re-run OCR</button>' : ''}
+                <button id="review-reject-btn" class="btn btn-secondary">🗑️ Reject</button>
+                <button id="review-approve-btn" class="btn btn-primary">✅ Approve &amp; Save</button>
+            </div>`;
+        
+        detail.innerHTML = html;
+        if (batch.has_image) {
+            drawFieldCrops(batch);
+        }
+        
+        detail.querySelectorAll('.review-skip').forEach(box => {
+            box.addEventListener('change', () => box.closest('tr').classList.toggle('row-skipped', box.checked));
+        });
+        document.getElementById('review-save-btn').addEventListener('click', () => saveBatch(batch));
+        document.getElementById('review-reject-btn').addEventListener('click', () => reviewAction(batch.id, 'reject', 'Reject this upload? Nothing will be saved.'));
+        document.getElementById('review-approve-btn').addEventListener('click', async () => {
+            if (await saveBatch(batch, true)) {
+                reviewAction(batch.id, 'approve');
+            }
+        });
+        const reprocessBtn = document.getElementById('review-reprocess-btn');
+        if (reprocessBtn) {
+            reprocessBtn.addEventListener('click', () => reviewAction(batch.id, 'reprocess', 'Re-run OCR on the original screenshot? Corrections will be lost.'));
+        }
+    } catch (error) {
+        console.error('Failed to load batch:', error);
+        detail.innerHTML = `<div class="result-box result-error">Failed to load batch: ${escapeHtml(error.message)}</div>`;
+    }
+}
+
+const rankingMetricLabels = { power: '⚡ Power', kills: '💀 Kills', donation: '🎁 Donation' };
+
+// Label of a batch in the review queue, naming the ranking tab of power batches
+function batchKindLabel(batch) {
+    if (batch.kind === 'vs_points') return '⚔️ VS Points';
+    return rankingMetricLabels[batch.metric] || rankingMetricLabels.power;
+}
+
+function rankingMetricColumn(batch) {
+    return { power: 'Power', kills: 'Kills', donation: 'Donations' }[batch.metric] || 'Power';
+}
+
+// Save reviewer corrections for a batch
+async function saveBatch(batch, quiet = false) {
+    const payload = { rows: [] };
+    
+    if (batch.kind === 'vs_points') {
+        const week = document.getElementById('review-week').value;
+        const day = document.getElementById('review-day').value;
+        if (week) payload.week_date = week;
+        if (day) payload.day = day;
+    } else {
+        payload.metric = document.getElementById('review-metric').value;
+    }
+    
+    document.querySelectorAll('#review-detail tr[data-row-id]').forEach(tr => {
+        const memberId = tr.querySelector('.review-member').value;
+        payload.rows.push({
+            id: parseInt(tr.dataset.rowId, 10),
+            member_id: memberId ? parseInt(memberId, 10) : null,
+            value: parseInt(tr.querySelector('.review-value').value, 10) || 0,
+            skip: tr.querySelector('.review-skip').checked
+        });
+    });
+    
+    try {
+        const response = await fetch(`${API_BASE}/ocr-batches/${batch.id}`, {
+            method: 'PUT',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify(payload)
+        });
+        if (!response.ok) throw new Error(await response.text());
+        
+        if (!quiet) {
+            showResult('💾 Corrections saved', 'success');
+            await loadReviewQueue();
+            openBatch(batch.id);
+        }
+        return true;
+    } catch (error) {
+        showResult(`❌ Failed to save corrections: ${error.message}`, 'error');
+        return false;
+    }
+}
+
+// Approve, reject or reprocess a batch
+async function reviewAction(batchId, action, confirmMessage) {
+    if (confirmMessage && !confirm(confirmMessage)) return;
+    
+    try {
+        const response = await fetch(`${API_BASE}/ocr-batches/${batchId}/${action}`, { method: 'POST' });
+        if (!response.ok) throw new Error(await response.text());
+        const result = await response.json();
+        
+        if (action === 'reprocess') {
+            // OCR is re-run in the background
+            const job = await waitForOcrJob(result.job_id, 'Re-running OCR');
+            if (!job.ok) throw new Error(job.result.error);
+            showResult('🔁 OCR re-run on the original screenshot', 'success');
+            openBatch(batchId);
+        } else {
+            showResult(`✅ ${escapeHtml(result.message)}`, 'success');
+            document.getElementById('review-detail').innerHTML = '';
+        }
+        await loadReviewQueue();
+    } catch (error) {
+        showResult(`❌ ${action} failed: ${error.message}`, 'error');
+    }
+}
+
+// Escape HTML
+function escapeHtml(text) {
+    if (!text) return '';
+    const div = document.createElement('div');
+    div.textContent = text;
+    return div.innerHTML;
+}
+
 function showResult(message, type) {
     const resultClass = type === 'error' ? 'result-error' : 
                        type === 'info' ? 'result-info' : 'result-success';
@@ -451,11 +874,14 @@ function updateScreenshotTypeHint() {
     const screenshotType = document.getElementById('screenshot-type').value;
     const hintElement = document.getElementById('screenshot-type-hint');
     const weekSelector = document.getElementById('week-selector');
+    const metricSelector = document.getElementById('metric-selector');
     
     if (screenshotType === 'power') {
-        hintElement.textContent = 'Upload power ranking screenshots from the alliance member list.';
+        hintElement.textContent = 'Upload strength ranking screenshots (Power, Kills or Donation tab) from the alliance member list.';
         if (weekSelector) weekSelector.style.display = 'none';
+        if (metricSelector) metricSelector.style.display = 'block';
     } else if (screenshotType === 'vs-points') {
+        if (metricSelector) metricSelector.style.display = 'none';
         hintElement.innerHTML = '<strong>⚔️ VS Points Instructions:</strong> Make sure to screenshot the "Daily Rank" tab. The system will automatically detect which day (Mon-Sat) is selected from the screenshot.';
         if (weekSelector) weekSelector.style.display = 'block';
     }
@@ -468,6 +894,14 @@ document.addEventListener('DOMContentLoaded', async () => {
     
     await setupEventListeners(auth);
     
+    // Show the review queue to R4/R5
+    canReview = auth.can_manage_ranks || false;
+    if (canReview) {
+        document.getElementById('review-section').style.display = 'block';
+        await loadReviewMembers();
+        loadReviewQueue();
+    }
+    
     // Setup screenshot type selector
     const screenshotTypeSelector = document.getElementById('screenshot-type');
     if (screenshotTypeSelector) {
@@ -1,10 +1,12 @@
 const API_URL = '/api/vs-points';
 const MEMBERS_URL = '/api/members';
+const REQUIREMENTS_URL = '/api/vs-requirements';
 
 let currentWeekDate = null;
 let allMembers = [];
 let currentVSPoints = {};
 let currentUsername = '';
+let gameToday = null; // current game day (server time), from check-auth
 
 // Check authentication
 async function checkAuth() {
@@ -18,6 +20,7 @@ async function checkAuth() {
         }
         
         currentUsername = data.username;
+        gameToday = new Date(data.game_today + 'T00:00:00');
         let displayText = `👤 ${currentUsername}`;
         if (data.rank) {
             displayText += ` (${data.rank})`;
@@ -121,7 +124,7 @@ function formatDisplayDate(dateStr) {
 
 // Initialize current week
 function initializeWeek() {
-    currentWeekDate = getMostRecentMonday();
+    currentWeekDate = getMostRecentMonday(gameToday);
     updateWeekDisplay();
 }
 
@@ -185,7 +188,8 @@ function calculateTotal(memberId) {
            (points.wednesday || 0) + 
            (points.thursday || 0) + 
            (points.friday || 0) + 
-           (points.saturday || 0);
+           (points.saturday || 0) + 
+           (points.sunday || 0);
 }
 
 // Render table
@@ -201,7 +205,7 @@ function renderTable() {
     );
     
     if (filteredMembers.length === 0) {
-        html = '<tr><td colspan="8" style="text-align: center; padding: 20px;">No members found</td></tr>';
+        html = '<tr><td colspan="9" style="text-align: center; padding: 20px;">No members found</td></tr>';
     } else {
         filteredMembers.forEach(member => {
             const points = currentVSPoints[member.id] || {
@@ -210,7 +214,8 @@ function renderTable() {
                 wednesday: 0,
                 thursday: 0,
                 friday: 0,
-                saturday: 0
+                saturday: 0,
+                sunday: 0
             };
             
             const total = calculateTotal(member.id);
@@ -227,6 +232,7 @@ function renderTable() {
                     <td><input type="number" class="vs-input" data-member="${member.id}" data-day="thursday" value="${points.thursday}" min="0"></td>
                     <td><input type="number" class="vs-input" data-member="${member.id}" data-day="friday" value="${points.friday}" min="0"></td>
                     <td><input type="number" class="vs-input" data-member="${member.id}" data-day="saturday" value="${points.saturday}" min="0"></td>
+                    <td><input type="number" class="vs-input" data-member="${member.id}" data-day="sunday" value="${points.sunday || 0}" min="0"></td>
                     <td class="total-column">${total}</td>
                 </tr>
             `;
@@ -289,7 +295,8 @@ async function saveVSPoints() {
             wednesday: parseInt(vsPoint.wednesday) || 0,
             thursday: parseInt(vsPoint.thursday) || 0,
             friday: parseInt(vsPoint.friday) || 0,
-            saturday: parseInt(vsPoint.saturday) || 0
+            saturday: parseInt(vsPoint.saturday) || 0,
+            sunday: parseInt(vsPoint.sunday) || 0
         });
     });
     
@@ -338,6 +345,99 @@ async function clearVSPoints() {
     }
 }
 
+// Load compliance report, requirements and warning message for the current week
+async function loadCompliance() {
+    const weekDate = formatDate(currentWeekDate);
+    
+    try {
+        const [requirementsResponse, complianceResponse, messageResponse] = await Promise.all([
+            fetch(REQUIREMENTS_URL),
+            fetch(`${API_URL}/compliance?week=${weekDate}`),
+            fetch(`${API_URL}/warning-message?week=${weekDate}`)
+        ]);
+        
+        if (!requirementsResponse.ok || !complianceResponse.ok || !messageResponse.ok) {
+            throw new Error('Failed to load compliance report');
+        }
+        
+        const requirements = await requirementsResponse.json();
+        const report = await complianceResponse.json();
+        const message = await messageResponse.json();
+        
+        document.getElementById('requirements-tbody').innerHTML = requirements.map(req => `
+            <tr>
+                <td>${req.day.charAt(0).toUpperCase() + req.day.slice(1)}</td>
+                <td><input type="text" class="requirement-theme" data-day="${req.day}" value="${escapeHtml(req.theme)}"></td>
+                <td><input type="number" class="vs-input requirement-min" data-day="${req.day}" value="${req.min_points}" min="0"></td>
+            </tr>
+        `).join('');
+        
+        document.getElementById('compliance-summary').textContent =
+            `${report.compliant_count} of ${report.total_members} members met every minimum this week`;
+        
+        document.getElementById('compliance-tbody').innerHTML = report.members.length === 0
+            ? '<tr><td colspan="4" style="text-align: center; padding: 20px;">Everyone met the minimums 🎉</td></tr>'
+            : report.members.map(member => `
+                <tr>
+                    <td>
+                        <span class="member-name">${escapeHtml(member.member_name)}</span>
+                        <span class="member-rank">(${escapeHtml(member.member_rank)})</span>
+                    </td>
+                    <td>${member.days.filter(d => !d.met).map(d => d.day.slice(0, 3)).join(', ')}</td>
+                    <td>${member.current_streak}</td>
+                    <td class="total-column">${member.total}</td>
+                </tr>
+            `).join('');
+        
+        document.getElementById('vs-warning-message').value = message.message;
+        document.getElementById('compliance-section').style.display = 'block';
+    } catch (error) {
+        console.error('Error loading compliance:', error);
+        alert('Failed to load compliance report: ' + error.message);
+    }
+}
+
+// Save daily minimum requirements
+async function saveRequirements() {
+    const requirements = Array.from(document.querySelectorAll('.requirement-min')).map(input => ({
+        day: input.dataset.day,
+        theme: document.querySelector(`.requirement-theme[data-day="${input.dataset.day}"]`).value,
+        min_points: parseInt(input.value) || 0
+    }));
+    
+    try {
+        const response = await fetch(REQUIREMENTS_URL, {
+            method: 'PUT',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify(requirements)
+        });
+        
+        if (!response.ok) {
+            throw new Error(await response.text());
+        }
+        
+        await loadCompliance();
+    } catch (error) {
+        console.error('Error saving requirements:', error);
+        alert('Failed to save minimums: ' + error.message);
+    }
+}
+
+// Copy warning message to clipboard
+function copyWarningMessage() {
+    const messageText = document.getElementById('vs-warning-message');
+    messageText.select();
+    document.execCommand('copy');
+    
+    // Visual feedback
+    const btn = document.getElementById('copy-warning-btn');
+    const originalText = btn.textContent;
+    btn.textContent = '✅ Copied!';
+    setTimeout(() => {
+        btn.textContent = originalText;
+    }, 2000);
+}
+
 // Escape HTML
 function escapeHtml(text) {
     if (!text) return '';
@@ -361,5 +461,8 @@ document.addEventListener('DOMContentLoaded', async () => {
         document.getElementById('save-btn').addEventListener('click', saveVSPoints);
         document.getElementById('clear-btn').addEventListener('click', clearVSPoints);
         document.getElementById('search-box').addEventListener('input', renderTable);
+        document.getElementById('compliance-btn').addEventListener('click', loadCompliance);
+        document.getElementById('save-requirements-btn').addEventListener('click', saveRequirements);
+        document.getElementById('copy-warning-btn').addEventListener('click', copyWarningMessage);
     }
 });